@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"sync"
@@ -42,8 +43,10 @@ func NewAgent(config *config.Config) (*Agent, error) {
 // │ Input │───┘
 // └───────┘
 type inputUnit struct {
-	dst    chan<- telegraf.Metric
-	inputs []*models.RunningInput
+	dst      chan<- telegraf.Metric
+	inputs   []*models.RunningInput
+	onDemand map[*models.RunningInput]*onDemand
+	pending  *pendingMetrics
 }
 
 //  ______     ┌───────────┐     ______
@@ -75,6 +78,10 @@ type aggregatorUnit struct {
 	aggC        chan<- telegraf.Metric
 	outputC     chan<- telegraf.Metric
 	aggregators []*models.RunningAggregator
+
+	// stateDir is the directory aggregator state is persisted to, empty if
+	// state is not persisted.
+	stateDir string
 }
 
 // outputUnit is a group of Outputs and their source channel.  Metrics on the
@@ -90,8 +97,13 @@ type aggregatorUnit struct {
 //                       └──▶ │ Output │
 //                            └────────┘
 type outputUnit struct {
-	src     <-chan telegraf.Metric
-	outputs []*models.RunningOutput
+	src            <-chan telegraf.Metric
+	outputs        []*models.RunningOutput
+	flushRequested []chan struct{}
+
+	// pending counts the on-demand metrics that have not yet been added to
+	// the outputs, nil without on-demand inputs.
+	pending *pendingMetrics
 }
 
 // Run starts and runs the Agent until the context is done.
@@ -101,11 +113,15 @@ func (a *Agent) Run(ctx context.Context) error {
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Quiet,
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
+	a.loadInventory()
+	a.setFeatureFlags()
+
 	log.Printf("D! [agent] Initializing plugins")
 	err := a.initPlugins()
 	if err != nil {
 		return err
 	}
+	a.saveInventory()
 
 	startTime := time.Now()
 
@@ -130,6 +146,7 @@ func (a *Agent) Run(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+		au.stateDir = a.Config.Agent.AggregatorStateDirectory
 	}
 
 	var pu []*processorUnit
@@ -144,6 +161,7 @@ func (a *Agent) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	ou.pending = iu.pending
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -186,6 +204,12 @@ func (a *Agent) Run(ctx context.Context) error {
 		}()
 	}
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.watchFlushRequests(ctx, iu, ou)
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -197,6 +221,8 @@ func (a *Agent) Run(ctx context.Context) error {
 
 	wg.Wait()
 
+	a.saveInventory()
+
 	log.Printf("D! [agent] Stopped Successfully")
 	return err
 }
@@ -217,12 +243,28 @@ func (a *Agent) initPlugins() error {
 				processor.Config.Name, err)
 		}
 	}
+	stateFiles := make(map[string]bool)
 	for _, aggregator := range a.Config.Aggregators {
 		err := aggregator.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize aggregator %s: %v",
 				aggregator.Config.Name, err)
 		}
+
+		if a.Config.Agent.AggregatorStateDirectory == "" || !aggregator.Stateful() {
+			continue
+		}
+		if stateFiles[aggregator.StateFile()] {
+			return fmt.Errorf("aggregator %s requires a unique alias to persist its state",
+				aggregator.LogName())
+		}
+		stateFiles[aggregator.StateFile()] = true
+	}
+	if len(stateFiles) > 0 {
+		err := os.MkdirAll(a.Config.Agent.AggregatorStateDirectory, 0750)
+		if err != nil {
+			return fmt.Errorf("could not create aggregator state directory: %v", err)
+		}
 	}
 	for _, processor := range a.Config.AggProcessors {
 		err := processor.Init()
@@ -238,9 +280,51 @@ func (a *Agent) initPlugins() error {
 				output.Config.Name, err)
 		}
 	}
+	a.checkTimestampResolution()
 	return nil
 }
 
+// checkTimestampResolution warns about outputs truncating the timestamps of
+// inputs collecting at a finer precision.
+func (a *Agent) checkTimestampResolution() {
+	for _, output := range a.Config.Outputs {
+		resolution := output.Config.TimestampResolution
+		if resolution <= 0 {
+			continue
+		}
+		for _, input := range a.Config.Inputs {
+			precision := a.inputPrecision(input)
+			// Metrics added by service inputs outside of Gather are only
+			// rounded if the plugin has its own precision.
+			if _, ok := input.Input.(telegraf.ServiceInput); ok {
+				precision = input.Config.Precision
+			}
+			if precision > 0 && precision < resolution {
+				log.Printf("W! [agent] %s truncates timestamps to %s, finer precision %s of %s is lost",
+					output.LogName(), resolution, precision, input.LogName())
+			}
+		}
+	}
+}
+
+// inputPrecision returns the precision the timestamps gathered by the input
+// are rounded to.
+func (a *Agent) inputPrecision(input *models.RunningInput) time.Duration {
+	// Overwrite agent interval if this plugin has its own.
+	interval := a.Config.Agent.Interval.Duration
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
+
+	// Overwrite agent precision if this plugin has its own.
+	precision := a.Config.Agent.Precision.Duration
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	return getPrecision(precision, interval)
+}
+
 func (a *Agent) startInputs(
 	dst chan<- telegraf.Metric,
 	inputs []*models.RunningInput,
@@ -248,10 +332,18 @@ func (a *Agent) startInputs(
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
-		dst: dst,
+		dst:      dst,
+		onDemand: make(map[*models.RunningInput]*onDemand),
 	}
 
 	for _, input := range inputs {
+		if a.isOnDemand(input) {
+			unit.onDemand[input] = newOnDemand()
+			if unit.pending == nil {
+				unit.pending = &pendingMetrics{}
+			}
+		}
+
 		if si, ok := input.Input.(telegraf.ServiceInput); ok {
 			// Service input plugins are not normally subject to timestamp
 			// rounding except for when precision is set on the input plugin.
@@ -297,12 +389,6 @@ func (a *Agent) runInputs(
 			interval = input.Config.Interval
 		}
 
-		// Overwrite agent precision if this plugin has its own.
-		precision := a.Config.Agent.Precision.Duration
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
-
 		// Overwrite agent collection_jitter if this plugin has its own.
 		jitter := a.Config.Agent.CollectionJitter.Duration
 		if input.Config.CollectionJitter != 0 {
@@ -317,13 +403,22 @@ func (a *Agent) runInputs(
 		}
 		defer ticker.Stop()
 
+		precision := a.inputPrecision(input)
+
 		acc := NewAccumulator(input, unit.dst)
-		acc.SetPrecision(getPrecision(precision, interval))
+		acc.SetPrecision(precision)
+
+		var odAcc telegraf.Accumulator
+		od := unit.onDemand[input]
+		if od != nil {
+			odAcc = NewAccumulator(onDemandMaker{input, unit.pending}, unit.dst)
+			odAcc.SetPrecision(precision)
+		}
 
 		wg.Add(1)
 		go func(input *models.RunningInput) {
 			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
+			a.gatherLoop(ctx, acc, input, ticker, interval, odAcc, od)
 		}(input)
 	}
 
@@ -353,12 +448,13 @@ func (a *Agent) testStartInputs(
 
 	for _, input := range inputs {
 		if si, ok := input.Input.(telegraf.ServiceInput); ok {
-			// Service input plugins are not subject to timestamp rounding.
+			// Service input plugins are not subject to timestamp rounding
+			// except for when precision is set on the input plugin.
 			// This only applies to the accumulator passed to Start(), the
 			// Gather() accumulator does apply rounding according to the
 			// precision agent setting.
 			acc := NewAccumulator(input, dst)
-			acc.SetPrecision(time.Nanosecond)
+			acc.SetPrecision(getPrecision(input.Config.Precision, 0))
 
 			err := si.Start(acc)
 			if err != nil {
@@ -393,24 +489,14 @@ func (a *Agent) testRunInputs(
 		go func(input *models.RunningInput) {
 			defer wg.Done()
 
-			// Overwrite agent interval if this plugin has its own.
-			interval := a.Config.Agent.Interval.Duration
-			if input.Config.Interval != 0 {
-				interval = input.Config.Interval
-			}
-
-			// Overwrite agent precision if this plugin has its own.
-			precision := a.Config.Agent.Precision.Duration
-			if input.Config.Precision != 0 {
-				precision = input.Config.Precision
-			}
+			precision := a.inputPrecision(input)
 
 			// Run plugins that require multiple gathers to calculate rate
 			// and delta metrics twice.
 			switch input.Config.Name {
 			case "cpu", "mongodb", "procstat":
 				nulAcc := NewAccumulator(input, nul)
-				nulAcc.SetPrecision(getPrecision(precision, interval))
+				nulAcc.SetPrecision(precision)
 				if err := input.Input.Gather(nulAcc); err != nil {
 					nulAcc.AddError(err)
 				}
@@ -419,7 +505,7 @@ func (a *Agent) testRunInputs(
 			}
 
 			acc := NewAccumulator(input, unit.dst)
-			acc.SetPrecision(getPrecision(precision, interval))
+			acc.SetPrecision(precision)
 
 			if err := input.Input.Gather(acc); err != nil {
 				acc.AddError(err)
@@ -448,16 +534,25 @@ func stopServiceInputs(inputs []*models.RunningInput) {
 }
 
 // gather runs an input's gather function periodically until the context is
-// done.
+// done.  Out-of-band gathers requested through od add their metrics to odAcc,
+// od is nil if the input is not gathered on demand.
 func (a *Agent) gatherLoop(
 	ctx context.Context,
 	acc telegraf.Accumulator,
 	input *models.RunningInput,
 	ticker Ticker,
 	interval time.Duration,
+	odAcc telegraf.Accumulator,
+	od *onDemand,
 ) {
 	defer panicRecover(input)
 
+	var odRequests <-chan *sync.WaitGroup
+	if od != nil {
+		defer close(od.stopped)
+		odRequests = od.requests
+	}
+
 	for {
 		select {
 		case <-ticker.Elapsed():
@@ -465,6 +560,12 @@ func (a *Agent) gatherLoop(
 			if err != nil {
 				acc.AddError(err)
 			}
+		case wg := <-odRequests:
+			err := a.gatherOnce(odAcc, input, ticker, interval)
+			if err != nil {
+				odAcc.AddError(err)
+			}
+			wg.Done()
 		case <-ctx.Done():
 			return
 		}
@@ -599,7 +700,16 @@ func (a *Agent) runAggregators(
 	// that any metric created after start time will be aggregated.
 	for _, agg := range a.Config.Aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
+		if agg.Config.Location != nil {
+			since, until = agg.WindowAt(startTime)
+		}
 		agg.UpdateWindow(since, until)
+
+		if path := unit.statePath(agg); path != "" {
+			if err := agg.LoadState(path); err != nil {
+				agg.Log().Errorf("Error restoring state from %s: %v", path, err)
+			}
+		}
 	}
 
 	var wg sync.WaitGroup
@@ -633,7 +743,7 @@ func (a *Agent) runAggregators(
 
 			acc := NewAccumulator(agg, unit.aggC)
 			acc.SetPrecision(getPrecision(precision, interval))
-			a.push(ctx, agg, acc)
+			a.push(ctx, agg, acc, unit.statePath(agg))
 		}(agg)
 	}
 
@@ -664,12 +774,46 @@ func updateWindow(start time.Time, roundInterval bool, period time.Duration) (ti
 	return since, until
 }
 
-// push runs the push for a single aggregator every period.
+// statePath returns the file the aggregator state is persisted to, or an
+// empty string if the state is not persisted.
+func (u *aggregatorUnit) statePath(agg *models.RunningAggregator) string {
+	if u.stateDir == "" || !agg.Stateful() {
+		return ""
+	}
+	return filepath.Join(u.stateDir, agg.StateFile())
+}
+
+// push runs the push for a single aggregator every period.  If statePath is
+// set and the aggregator is stateful the window state is saved after every
+// push and checkpoint, and on shutdown the state is saved instead of pushing
+// the incomplete window so it is completed after a restart.  All other
+// aggregators push the incomplete window on shutdown.
 func (a *Agent) push(
 	ctx context.Context,
 	aggregator *models.RunningAggregator,
 	acc telegraf.Accumulator,
+	statePath string,
 ) {
+	if !aggregator.Stateful() {
+		statePath = ""
+	}
+
+	var checkpoint <-chan time.Time
+	if statePath != "" {
+		ticker := time.NewTicker(a.Config.Agent.AggregatorCheckpointInterval.Duration)
+		defer ticker.Stop()
+		checkpoint = ticker.C
+	}
+
+	saveState := func() {
+		if statePath == "" {
+			return
+		}
+		if err := aggregator.SaveState(statePath); err != nil {
+			aggregator.Log().Errorf("Error saving state to %s: %v", statePath, err)
+		}
+	}
+
 	for {
 		// Ensures that Push will be called for each period, even if it has
 		// already elapsed before this function is called.  This is guaranteed
@@ -680,8 +824,15 @@ func (a *Agent) push(
 		select {
 		case <-time.After(until):
 			aggregator.Push(acc)
+			saveState()
 			break
+		case <-checkpoint:
+			saveState()
 		case <-ctx.Done():
+			if statePath != "" {
+				saveState()
+				return
+			}
 			aggregator.Push(acc)
 			return
 		}
@@ -706,6 +857,7 @@ func (a *Agent) startOutputs(
 		}
 
 		unit.outputs = append(unit.outputs, output)
+		unit.flushRequested = append(unit.flushRequested, make(chan struct{}, 1))
 	}
 
 	return src, unit, nil
@@ -747,7 +899,7 @@ func (a *Agent) runOutputs(
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	for _, output := range unit.outputs {
+	for i, output := range unit.outputs {
 		interval := interval
 		// Overwrite agent flush_interval if this plugin has its own.
 		if output.Config.FlushInterval != 0 {
@@ -761,17 +913,18 @@ func (a *Agent) runOutputs(
 		}
 
 		wg.Add(1)
-		go func(output *models.RunningOutput) {
+		go func(output *models.RunningOutput, flushRequested <-chan struct{}) {
 			defer wg.Done()
 
 			ticker := NewRollingTicker(interval, jitter)
 			defer ticker.Stop()
 
-			a.flushLoop(ctx, output, ticker)
-		}(output)
+			a.flushLoop(ctx, output, ticker, flushRequested)
+		}(output, unit.flushRequested[i])
 	}
 
 	for metric := range unit.src {
+		onDemand := unit.pending != nil && isOnDemandMetric(metric)
 		for i, output := range unit.outputs {
 			if i == len(a.Config.Outputs)-1 {
 				output.AddMetric(metric)
@@ -779,6 +932,9 @@ func (a *Agent) runOutputs(
 				output.AddMetric(metric.Copy())
 			}
 		}
+		if onDemand {
+			unit.pending.done()
+		}
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
@@ -788,12 +944,13 @@ func (a *Agent) runOutputs(
 	return nil
 }
 
-// flushLoop runs an output's flush function periodically until the context is
-// done.
+// flushLoop runs an output's flush function periodically, and when requested,
+// until the context is done.
 func (a *Agent) flushLoop(
 	ctx context.Context,
 	output *models.RunningOutput,
 	ticker Ticker,
+	flushRequested <-chan struct{},
 ) {
 	logError := func(err error) {
 		if err != nil {
@@ -801,11 +958,6 @@ func (a *Agent) flushLoop(
 		}
 	}
 
-	// watch for flush requests
-	flushRequested := make(chan os.Signal, 1)
-	watchForFlushSignal(flushRequested)
-	defer stopListeningForFlushSignal(flushRequested)
-
 	for {
 		// Favor shutdown over other methods.
 		select {
@@ -1,12 +1,21 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -166,3 +175,86 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestAgent_InputPrecision(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Interval = internal.Duration{Duration: 10 * time.Second}
+	c.Agent.Precision = internal.Duration{Duration: 0}
+	a, _ := NewAgent(c)
+
+	input := models.NewRunningInput(&onDemandInput{}, &models.InputConfig{Name: "ipmi_power"})
+	require.Equal(t, time.Second, a.inputPrecision(input))
+
+	input = models.NewRunningInput(&onDemandInput{}, &models.InputConfig{
+		Name:     "rapl",
+		Interval: 100 * time.Millisecond,
+	})
+	require.Equal(t, time.Millisecond, a.inputPrecision(input))
+
+	input = models.NewRunningInput(&onDemandInput{}, &models.InputConfig{
+		Name:      "ina",
+		Precision: time.Microsecond,
+	})
+	require.Equal(t, time.Microsecond, a.inputPrecision(input))
+}
+
+type countAggregator struct {
+	count int
+}
+
+func (c *countAggregator) SampleConfig() string   { return "" }
+func (c *countAggregator) Description() string    { return "" }
+func (c *countAggregator) Add(in telegraf.Metric) { c.count++ }
+func (c *countAggregator) Reset()                 { c.count = 0 }
+func (c *countAggregator) Push(acc telegraf.Accumulator) {
+	acc.AddFields("count", map[string]interface{}{"count": c.count}, nil)
+}
+
+type statefulCountAggregator struct {
+	countAggregator
+}
+
+func (c *statefulCountAggregator) GetState() (interface{}, error) { return c.count, nil }
+func (c *statefulCountAggregator) SetState(state []byte) error {
+	return json.Unmarshal(state, &c.count)
+}
+
+func TestPushOnShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aggregator-state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a, _ := NewAgent(config.NewConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		aggregator telegraf.Aggregator
+		pushed     bool
+	}{
+		{"stateless", &countAggregator{}, true},
+		{"stateful", &statefulCountAggregator{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := models.NewRunningAggregator(tt.aggregator, &models.AggregatorConfig{
+				Name:   tt.name,
+				Period: time.Hour,
+			})
+			require.NoError(t, agg.Init())
+			now := time.Now()
+			agg.UpdateWindow(now, now.Add(time.Hour))
+
+			// Both have a state directory, only the stateful aggregator
+			// keeps its incomplete window for the restart
+			path := filepath.Join(dir, agg.StateFile())
+			var acc testutil.Accumulator
+			a.push(ctx, agg, &acc, path)
+
+			require.Equal(t, tt.pushed, acc.HasMeasurement("count"))
+			_, err := os.Stat(path)
+			require.Equal(t, !tt.pushed, err == nil)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package agent
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf/featureflag"
+)
+
+// setFeatureFlags enables the configured feature flags before the plugins
+// are initialized.
+func (a *Agent) setFeatureFlags() {
+	for name, percent := range a.Config.Agent.FeatureFlags {
+		log.Printf("I! [agent] Feature flag %s enabled for %d%% of the servers", name, percent)
+		featureflag.Set(name, percent)
+	}
+}
@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf/inventory"
+)
+
+// loadInventory restores the device inventory before the plugins probe their
+// devices.  A broken inventory is not fatal, the devices are probed again.
+func (a *Agent) loadInventory() {
+	inventory.SetMaxAge(a.Config.Agent.InventoryMaxAge.Duration)
+	if a.Config.Agent.InventoryFile == "" {
+		return
+	}
+	if err := inventory.Load(a.Config.Agent.InventoryFile); err != nil {
+		log.Printf("W! [agent] Could not load device inventory: %v", err)
+	}
+}
+
+// saveInventory persists the device inventory if configured.
+func (a *Agent) saveInventory() {
+	if a.Config.Agent.InventoryFile == "" {
+		return
+	}
+	if err := inventory.Save(a.Config.Agent.InventoryFile); err != nil {
+		log.Printf("E! [agent] Could not save device inventory: %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+)
+
+// onDemandFlushTimeout is the longest time the flush waits for the metrics of
+// on-demand gathers to pass the processors and aggregators.  Metrics dropped
+// or aggregated on the way never reach the outputs.
+const onDemandFlushTimeout = 5 * time.Second
+
+// onDemand holds the requests for out-of-band gathers of an input.
+type onDemand struct {
+	requests chan *sync.WaitGroup
+	// stopped is closed when the gather loop of the input exits.
+	stopped chan struct{}
+}
+
+func newOnDemand() *onDemand {
+	return &onDemand{
+		requests: make(chan *sync.WaitGroup),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// onDemandMaker tags the metrics of out-of-band gathers and counts them as
+// pending until they reach the outputs.
+type onDemandMaker struct {
+	*models.RunningInput
+	pending *pendingMetrics
+}
+
+func (m onDemandMaker) MakeMetric(metric telegraf.Metric) telegraf.Metric {
+	metric = m.RunningInput.MakeMetric(metric)
+	if metric != nil {
+		metric.AddTag("ondemand", "true")
+		m.pending.add()
+	}
+	return metric
+}
+
+// isOnDemandMetric returns true if the metric was added by an on-demand
+// gather.
+func isOnDemandMetric(metric telegraf.Metric) bool {
+	value, ok := metric.GetTag("ondemand")
+	return ok && value == "true"
+}
+
+// pendingMetrics counts the metrics of on-demand gathers that have not yet
+// reached the outputs.
+type pendingMetrics struct {
+	mu      sync.Mutex
+	count   int
+	drained chan struct{}
+}
+
+func (p *pendingMetrics) add() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.count == 0 {
+		p.drained = make(chan struct{})
+	}
+	p.count++
+}
+
+// done marks a metric as arrived at the outputs.
+func (p *pendingMetrics) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.count == 0 {
+		return
+	}
+	p.count--
+	if p.count == 0 {
+		close(p.drained)
+	}
+}
+
+// reset forgets metrics that will never arrive.
+func (p *pendingMetrics) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.count > 0 {
+		p.count = 0
+		close(p.drained)
+	}
+}
+
+// wait waits until all pending metrics reached the outputs.  It returns false
+// if the timeout elapses or the context is done first.
+func (p *pendingMetrics) wait(ctx context.Context, timeout time.Duration) bool {
+	p.mu.Lock()
+	if p.count == 0 {
+		p.mu.Unlock()
+		return true
+	}
+	drained := p.drained
+	p.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// isOnDemand returns true if the input is gathered when the flush signal is
+// received.
+func (a *Agent) isOnDemand(input *models.RunningInput) bool {
+	for _, name := range a.Config.Agent.OnDemandInputs {
+		if name == input.Config.Name || (input.Config.Alias != "" && name == input.Config.Alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchFlushRequests gathers the on-demand inputs and flushes the outputs
+// every time the flush signal is received, until the context is done.  The
+// flush waits for the metrics of the gathers to reach the outputs.
+func (a *Agent) watchFlushRequests(ctx context.Context, iu *inputUnit, ou *outputUnit) {
+	flushRequested := make(chan os.Signal, 1)
+	watchForFlushSignal(flushRequested)
+	defer stopListeningForFlushSignal(flushRequested)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-flushRequested:
+			if len(iu.onDemand) > 0 {
+				log.Printf("D! [agent] Gathering on-demand inputs")
+				if !a.gatherOnDemand(ctx, iu) {
+					return
+				}
+				if !iu.pending.wait(ctx, onDemandFlushTimeout) {
+					if ctx.Err() != nil {
+						return
+					}
+					log.Printf("D! [agent] Not all on-demand metrics reached the outputs within %s, flushing anyway",
+						onDemandFlushTimeout)
+					iu.pending.reset()
+				}
+			}
+			ou.requestFlush()
+		}
+	}
+}
+
+// gatherOnDemand triggers a gather of all on-demand inputs and waits for them
+// to complete.  Inputs whose gather loop has stopped are skipped.  It returns
+// false if the context is done first.
+func (a *Agent) gatherOnDemand(ctx context.Context, unit *inputUnit) bool {
+	var wg sync.WaitGroup
+	for input, od := range unit.onDemand {
+		wg.Add(1)
+		select {
+		case od.requests <- &wg:
+		case <-od.stopped:
+			log.Printf("E! [agent] Cannot gather %s on demand, it is no longer running", input.LogName())
+			wg.Done()
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// requestFlush asks all outputs to flush, requests made while a flush is
+// already pending are merged.
+func (u *outputUnit) requestFlush() {
+	for _, c := range u.flushRequested {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// stoppedTicker is a Ticker that never fires.
+type stoppedTicker struct{}
+
+func (t *stoppedTicker) Elapsed() <-chan time.Time { return nil }
+func (t *stoppedTicker) Stop()                     {}
+
+type onDemandInput struct{}
+
+func (i *onDemandInput) SampleConfig() string { return "" }
+func (i *onDemandInput) Description() string  { return "" }
+func (i *onDemandInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("power", map[string]interface{}{"watts": 42.0}, nil)
+	return nil
+}
+
+func TestIsOnDemand(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OnDemandInputs = []string{"ipmi_power", "rack1"}
+	a, _ := NewAgent(c)
+
+	require.True(t, a.isOnDemand(models.NewRunningInput(&onDemandInput{}, &models.InputConfig{Name: "ipmi_power"})))
+	require.True(t, a.isOnDemand(models.NewRunningInput(&onDemandInput{}, &models.InputConfig{Name: "ipmi_sensor", Alias: "rack1"})))
+	require.False(t, a.isOnDemand(models.NewRunningInput(&onDemandInput{}, &models.InputConfig{Name: "cpu"})))
+}
+
+func TestGatherOnDemand(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OnDemandInputs = []string{"power"}
+	a, _ := NewAgent(c)
+
+	input := models.NewRunningInput(&onDemandInput{}, &models.InputConfig{Name: "power"})
+	require.NoError(t, input.Init())
+
+	dst := make(chan telegraf.Metric, 10)
+	unit, err := a.startInputs(dst, []*models.RunningInput{input})
+	require.NoError(t, err)
+	require.Contains(t, unit.onDemand, input)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The ticker never fires, metrics are only produced by on-demand gathers
+	ticker := &stoppedTicker{}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.gatherLoop(ctx, NewAccumulator(input, dst), input, ticker, time.Hour,
+			NewAccumulator(onDemandMaker{input, unit.pending}, dst), unit.onDemand[input])
+	}()
+
+	require.True(t, a.gatherOnDemand(ctx, unit))
+	require.Len(t, dst, 1)
+	require.Equal(t, 1, unit.pending.count)
+	m := <-dst
+	require.Equal(t, "power", m.Name())
+	require.Equal(t, map[string]string{"ondemand": "true"}, m.Tags())
+	require.True(t, isOnDemandMetric(m))
+
+	cancel()
+	wg.Wait()
+	require.False(t, a.gatherOnDemand(ctx, unit))
+}
+
+func TestGatherOnDemandStoppedInput(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OnDemandInputs = []string{"power"}
+	a, _ := NewAgent(c)
+
+	input := models.NewRunningInput(&onDemandInput{}, &models.InputConfig{Name: "power"})
+	require.NoError(t, input.Init())
+
+	dst := make(chan telegraf.Metric, 10)
+	unit, err := a.startInputs(dst, []*models.RunningInput{input})
+	require.NoError(t, err)
+
+	// The gather loop has exited, e.g. after a panic
+	close(unit.onDemand[input].stopped)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.True(t, a.gatherOnDemand(ctx, unit))
+	require.Len(t, dst, 0)
+}
+
+func TestPendingMetricsWait(t *testing.T) {
+	ctx := context.Background()
+	p := &pendingMetrics{}
+	require.True(t, p.wait(ctx, time.Millisecond))
+
+	p.add()
+	p.add()
+	require.False(t, p.wait(ctx, time.Millisecond))
+
+	done := make(chan bool)
+	go func() {
+		done <- p.wait(ctx, time.Minute)
+	}()
+	p.done()
+	p.done()
+	require.True(t, <-done)
+
+	// Metrics that never arrive are forgotten after the timeout
+	p.add()
+	require.False(t, p.wait(ctx, time.Millisecond))
+	p.reset()
+	require.True(t, p.wait(ctx, time.Millisecond))
+	p.done()
+	p.add()
+	p.done()
+	require.True(t, p.wait(ctx, time.Millisecond))
+
+	p.add()
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.False(t, p.wait(cctx, time.Minute))
+}
+
+func TestRequestFlushMerged(t *testing.T) {
+	unit := &outputUnit{
+		flushRequested: []chan struct{}{make(chan struct{}, 1), make(chan struct{}, 1)},
+	}
+	unit.requestFlush()
+	unit.requestFlush()
+	for _, c := range unit.flushRequested {
+		require.Len(t, c, 1)
+	}
+}
+
+func TestRunOutputsMarksOnDemandArrived(t *testing.T) {
+	a, _ := NewAgent(config.NewConfig())
+
+	src := make(chan telegraf.Metric, 2)
+	unit := &outputUnit{src: src, pending: &pendingMetrics{}}
+	unit.pending.add()
+
+	src <- testutil.MustMetric("power", map[string]string{"ondemand": "true"},
+		map[string]interface{}{"watts": 42.0}, time.Unix(0, 0))
+	src <- testutil.MustMetric("power", map[string]string{},
+		map[string]interface{}{"watts": 42.0}, time.Unix(0, 0))
+	close(src)
+
+	require.NoError(t, a.runOutputs(unit))
+	require.Equal(t, 0, unit.pending.count)
+}
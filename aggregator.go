@@ -16,3 +16,18 @@ type Aggregator interface {
 	// Reset resets the aggregators caches and aggregates.
 	Reset()
 }
+
+// StatefulAggregator is an Aggregator able to persist the state of its
+// current window, allowing the agent to snapshot it to disk and restore it
+// after a restart.
+type StatefulAggregator interface {
+	Aggregator
+
+	// GetState returns the state of the current window, the result must be
+	// serializable to JSON.
+	GetState() (interface{}, error)
+
+	// SetState restores the state of the current window from its JSON
+	// representation as produced by GetState.
+	SetState(state []byte) error
+}
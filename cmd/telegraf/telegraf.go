@@ -207,6 +207,60 @@ func runAgent(ctx context.Context,
 	return ag.Run(ctx)
 }
 
+// analyzeConfig prints the backend load projected from the configuration.
+func analyzeConfig(inputFilters []string, outputFilters []string) error {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return err
+	}
+
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return err
+		}
+	}
+
+	estimates, unknown := c.EstimateLoad()
+	config.PrintLoadReport(os.Stdout, estimates, unknown)
+	return nil
+}
+
+func pipelineGraph(args []string, inputFilters []string, outputFilters []string) error {
+	fs := flag.NewFlagSet("pipeline graph", flag.ContinueOnError)
+	format := fs.String("format", "dot", "output format, dot or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "dot" && *format != "json" {
+		return fmt.Errorf("invalid format %q, expected dot or json", *format)
+	}
+
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return err
+	}
+
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return err
+		}
+	}
+
+	g := c.PipelineGraph()
+	if *format == "json" {
+		return config.WritePipelineJSON(os.Stdout, g)
+	}
+	return config.WritePipelineDot(os.Stdout, g)
+}
+
 func usageExit(rc int) {
 	fmt.Println(internal.Usage)
 	os.Exit(rc)
@@ -292,6 +346,12 @@ func main() {
 			fmt.Println(formatFullVersion())
 			return
 		case "config":
+			if len(args) > 1 && args[1] == "analyze" {
+				if err := analyzeConfig(inputFilters, outputFilters); err != nil {
+					log.Fatal("E! " + err.Error())
+				}
+				return
+			}
 			config.PrintSampleConfig(
 				sectionFilters,
 				inputFilters,
@@ -300,6 +360,14 @@ func main() {
 				processorFilters,
 			)
 			return
+		case "pipeline":
+			if len(args) > 1 && args[1] == "graph" {
+				if err := pipelineGraph(args[2:], inputFilters, outputFilters); err != nil {
+					log.Fatal("E! " + err.Error())
+				}
+				return
+			}
+			usageExit(1)
 		}
 	}
 
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/featureflag"
+	"github.com/influxdata/telegraf/models"
+)
+
+// LoadEstimate is the projected backend load of one measurement of an input.
+type LoadEstimate struct {
+	Input       string
+	Measurement string
+	Interval    time.Duration
+	Series      int
+	Fields      int
+}
+
+// PointsPerSecond returns the rate of field values written to the backend.
+func (e *LoadEstimate) PointsPerSecond() float64 {
+	if e.Interval <= 0 {
+		return 0
+	}
+	return float64(e.Series*e.Fields) / e.Interval.Seconds()
+}
+
+// EstimateLoad statically estimates the series produced by the configured
+// inputs. Inputs not implementing telegraf.SeriesEstimator and measurements
+// whose series depend on what is found at runtime are returned as not
+// estimated.
+func (c *Config) EstimateLoad() (estimates []LoadEstimate, unknown []string) {
+	// Estimates of canaried features depend on the flags like the agent
+	for name, percent := range c.Agent.FeatureFlags {
+		featureflag.Set(name, percent)
+	}
+
+	for _, input := range c.Inputs {
+		name := input.LogName()
+		estimator, ok := input.Input.(telegraf.SeriesEstimator)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+
+		interval := input.Config.Interval
+		if interval == 0 {
+			interval = c.Agent.Interval.Duration
+		}
+
+		for _, se := range estimator.EstimateSeries() {
+			measurement := measurementName(input.Config, se.Measurement)
+			if se.Unknown {
+				unknown = append(unknown, name+" "+measurement)
+				continue
+			}
+
+			e := LoadEstimate{
+				Input:       name,
+				Measurement: measurement,
+				Interval:    interval,
+				Series:      se.Series,
+				Fields:      se.Fields,
+			}
+			if se.Interval > 0 {
+				e.Interval = se.Interval
+			}
+			estimates = append(estimates, e)
+		}
+	}
+	return estimates, unknown
+}
+
+// measurementName applies the name modifiers of the input to a measurement.
+func measurementName(cfg *models.InputConfig, name string) string {
+	if cfg.NameOverride != "" {
+		return cfg.NameOverride
+	}
+	return cfg.MeasurementPrefix + name + cfg.MeasurementSuffix
+}
+
+// PrintLoadReport writes a report of the estimated backend load per
+// measurement and in total.
+func PrintLoadReport(w io.Writer, estimates []LoadEstimate, unknown []string) {
+	sort.SliceStable(estimates, func(i, j int) bool {
+		return estimates[i].Measurement < estimates[j].Measurement
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "INPUT\tMEASUREMENT\tINTERVAL\tSERIES\tFIELDS\tPOINTS/S\tPOINTS/DAY")
+	var series int
+	var rate float64
+	for _, e := range estimates {
+		pps := e.PointsPerSecond()
+		series += e.Series * e.Fields
+		rate += pps
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%.2f\t%.0f\n",
+			e.Input, e.Measurement, e.Interval, e.Series, e.Fields, pps, pps*86400)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nTotal field series: %d\n", series)
+	fmt.Fprintf(w, "Total points/s: %.2f (%.0f points/day)\n", rate, rate*86400)
+	if len(unknown) > 0 {
+		fmt.Fprintf(w, "\nNot estimated, missing from the totals (%d):\n", len(unknown))
+		for _, name := range unknown {
+			fmt.Fprintf(w, "  %s\n", name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/stretchr/testify/require"
+)
+
+type estimatingInput struct {
+	Servers      []string          `toml:"servers"`
+	PollInterval internal.Duration `toml:"poll_interval"`
+	Supplies     bool              `toml:"supplies"`
+}
+
+func (i *estimatingInput) SampleConfig() string                  { return "" }
+func (i *estimatingInput) Description() string                   { return "" }
+func (i *estimatingInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *estimatingInput) EstimateSeries() []telegraf.SeriesEstimate {
+	estimates := []telegraf.SeriesEstimate{
+		{Measurement: "power", Series: len(i.Servers), Fields: 2, Interval: i.PollInterval.Duration},
+	}
+	if i.Supplies {
+		estimates = append(estimates, telegraf.SeriesEstimate{Measurement: "supply", Unknown: true})
+	}
+	return estimates
+}
+
+func TestConfig_EstimateLoad(t *testing.T) {
+	inputs.Add("estimating", func() telegraf.Input { return &estimatingInput{} })
+	defer delete(inputs.Inputs, "estimating")
+
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[agent]
+  interval = "10s"
+
+[[inputs.estimating]]
+  servers = ["a", "b", "c"]
+
+[[inputs.estimating]]
+  alias = "slow"
+  interval = "1m"
+  name_prefix = "slow_"
+  servers = ["a"]
+
+[[inputs.estimating]]
+  alias = "service"
+  poll_interval = "5s"
+  servers = ["a", "b"]
+  supplies = true
+
+[[inputs.memcached]]
+`))
+	require.NoError(t, err)
+
+	estimates, unknown := c.EstimateLoad()
+	require.Equal(t, []LoadEstimate{
+		{Input: "inputs.estimating", Measurement: "power", Interval: 10 * time.Second, Series: 3, Fields: 2},
+		{Input: "inputs.estimating::slow", Measurement: "slow_power", Interval: time.Minute, Series: 1, Fields: 2},
+		{Input: "inputs.estimating::service", Measurement: "power", Interval: 5 * time.Second, Series: 2, Fields: 2},
+	}, estimates)
+	require.Equal(t, []string{"inputs.estimating::service supply", "inputs.memcached"}, unknown)
+	require.Equal(t, 0.6, estimates[0].PointsPerSecond())
+
+	var buf bytes.Buffer
+	PrintLoadReport(&buf, estimates, unknown)
+	require.Contains(t, buf.String(), "Total field series: 12")
+	require.Contains(t, buf.String(), "Not estimated, missing from the totals (2):")
+	require.Contains(t, buf.String(), "inputs.memcached")
+}
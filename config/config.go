@@ -86,6 +86,9 @@ func NewConfig() *Config {
 			FlushInterval:              internal.Duration{Duration: 10 * time.Second},
 			LogTarget:                  "file",
 			LogfileRotationMaxArchives: 5,
+
+			AggregatorCheckpointInterval: internal.Duration{Duration: time.Minute},
+			InventoryMaxAge:              internal.Duration{Duration: 24 * time.Hour},
 		},
 
 		Tags:          make(map[string]string),
@@ -189,6 +192,31 @@ type AgentConfig struct {
 
 	Hostname     string
 	OmitHostname bool
+
+	// Directory aggregators able to snapshot their window state persist it
+	// to, so windows survive restarts.  When empty no state is persisted.
+	AggregatorStateDirectory string `toml:"aggregator_state_directory"`
+
+	// Interval at which the aggregator state is saved, additionally the state
+	// is saved after each push and on shutdown.
+	AggregatorCheckpointInterval internal.Duration `toml:"aggregator_checkpoint_interval"`
+
+	// Names or aliases of the inputs gathered immediately when the flush
+	// signal (SIGUSR1) is received, their metrics are tagged ondemand=true.
+	OnDemandInputs []string `toml:"ondemand_inputs"`
+
+	// File the inventory of devices discovered by plugins is persisted to,
+	// so the devices are not probed again after a restart.  When empty the
+	// inventory is only kept in memory.
+	InventoryFile string `toml:"inventory_file"`
+
+	// Time after which the discovered capabilities of a device expire and
+	// it is probed again.  0 disables expiry.
+	InventoryMaxAge internal.Duration `toml:"inventory_max_age"`
+
+	// Percentage of the servers, or other keys, new plugin implementations
+	// are enabled for by name of their feature flag.
+	FeatureFlags map[string]int `toml:"feature_flags"`
 }
 
 // InputNames returns a list of strings of the configured inputs.
@@ -360,6 +388,32 @@ var agentConfig = `
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Directory aggregators persist their window state to, allowing
+  ## aggregations to survive restarts. Only aggregators supporting state
+  ## snapshots are persisted.
+  # aggregator_state_directory = ""
+  ## Interval at which the aggregator state is saved, it is also saved after
+  ## every push and on shutdown.
+  # aggregator_checkpoint_interval = "1m"
+
+  ## Names or aliases of inputs gathered immediately when Telegraf receives
+  ## SIGUSR1, the outputs are flushed once their metrics reached them.  The
+  ## metrics of these gathers are tagged with ondemand=true.
+  # ondemand_inputs = []
+
+  ## File the inventory of devices discovered by plugins, such as BMCs and
+  ## their capabilities, is persisted to so they are not probed again after
+  ## a restart.  Discovered capabilities expire after inventory_max_age.
+  # inventory_file = ""
+  # inventory_max_age = "24h"
+
+  ## Run new implementations of plugins side-by-side with the legacy ones on
+  ## a percentage of the servers they collect from, e.g. to canary rewrites.
+  ## Metrics of both are tagged with the implementation producing them.  The
+  ## flags are listed in the documentation of the plugins.
+  # [agent.feature_flags]
+  #   ipmi_power_dcmi_raw = 10
+
 `
 
 var outputHeader = `
@@ -760,6 +814,11 @@ func (c *Config) LoadConfigData(data []byte) error {
 		if err = c.toml.UnmarshalTable(subTable, c.Agent); err != nil {
 			return fmt.Errorf("error parsing [agent]: %w", err)
 		}
+		for name, percent := range c.Agent.FeatureFlags {
+			if percent < 0 || percent > 100 {
+				return fmt.Errorf("feature flag %q: percentage must be between 0 and 100", name)
+			}
+		}
 	}
 
 	if !c.Agent.OmitHostname {
@@ -1046,19 +1105,22 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
+	var resolution time.Duration
 	switch t := output.(type) {
 	case serializers.SerializerOutput:
-		serializer, err := c.buildSerializer(name, table)
+		serializer, res, err := c.buildSerializer(name, table)
 		if err != nil {
 			return err
 		}
 		t.SetSerializer(serializer)
+		resolution = res
 	}
 
 	outputConfig, err := c.buildOutput(name, table)
 	if err != nil {
 		return err
 	}
+	outputConfig.TimestampResolution = resolution
 
 	if err := c.toml.UnmarshalTable(table, output); err != nil {
 		return err
@@ -1135,6 +1197,8 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 	c.getFieldDuration(tbl, "delay", &conf.Delay)
 	c.getFieldDuration(tbl, "grace", &conf.Grace)
 	c.getFieldBool(tbl, "drop_original", &conf.DropOriginal)
+	var timezone string
+	c.getFieldString(tbl, "timezone", &timezone)
 	c.getFieldString(tbl, "name_prefix", &conf.MeasurementPrefix)
 	c.getFieldString(tbl, "name_suffix", &conf.MeasurementSuffix)
 	c.getFieldString(tbl, "name_override", &conf.NameOverride)
@@ -1153,6 +1217,14 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 		return nil, c.firstErr()
 	}
 
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone for aggregator %s: %v", name, err)
+		}
+		conf.Location = loc
+	}
+
 	var err error
 	conf.Filter, err = c.buildFilter(tbl)
 	if err != nil {
@@ -1334,8 +1406,9 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 
 // buildSerializer grabs the necessary entries from the ast.Table for creating
 // a serializers.Serializer object, and creates it, which can then be added onto
-// an Output object.
-func (c *Config) buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error) {
+// an Output object.  The resolution of the serialized timestamps is returned
+// alongside.
+func (c *Config) buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, time.Duration, error) {
 	sc := &serializers.Config{TimestampUnits: time.Duration(1 * time.Second)}
 
 	c.getFieldString(tbl, "data_format", &sc.DataFormat)
@@ -1368,10 +1441,11 @@ func (c *Config) buildSerializer(name string, tbl *ast.Table) (serializers.Seria
 	c.getFieldBool(tbl, "prometheus_string_as_label", &sc.PrometheusStringAsLabel)
 
 	if c.hasErrs() {
-		return nil, c.firstErr()
+		return nil, 0, c.firstErr()
 	}
 
-	return serializers.NewSerializer(sc)
+	serializer, err := serializers.NewSerializer(sc)
+	return serializer, sc.TimestampResolution(), err
 }
 
 // buildOutput parses output specific items from the ast.Table,
@@ -1427,7 +1501,7 @@ func (c *Config) missingTomlField(typ reflect.Type, key string) error {
 		"prefix", "prometheus_export_timestamp", "prometheus_sort_metrics", "prometheus_string_as_label",
 		"separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
 		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "template", "templates",
-		"wavefront_source_override", "wavefront_use_strict":
+		"timezone", "wavefront_source_override", "wavefront_use_strict":
 
 		// ignore fields that are common to all plugins.
 	default:
@@ -278,3 +278,29 @@ func TestConfig_AzureMonitorNamespacePrefix(t *testing.T) {
 	assert.Equal(t, "", azureMonitor.NamespacePrefix)
 	assert.Equal(t, true, ok)
 }
+
+func TestConfig_FeatureFlags(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/feature_flags.toml")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"ipmi_power_dcmi_raw": 10, "other": 0}, c.Agent.FeatureFlags)
+	require.Equal(t, 10*time.Second, c.Agent.Interval.Duration)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte("[agent.feature_flags]\n  test = 150\n"))
+	require.EqualError(t, err, `feature flag "test": percentage must be between 0 and 100`)
+}
+
+func TestConfig_AggregatorTimezone(t *testing.T) {
+	c := NewConfig()
+	tbl, err := parseConfig([]byte("period = \"24h\"\ntimezone = \"Europe/Berlin\"\n"))
+	require.NoError(t, err)
+	conf, err := c.buildAggregator("test", tbl)
+	require.NoError(t, err)
+	require.Equal(t, "Europe/Berlin", conf.Location.String())
+
+	tbl, err = parseConfig([]byte("timezone = \"Mars/Olympus_Mons\"\n"))
+	require.NoError(t, err)
+	_, err = c.buildAggregator("test", tbl)
+	require.Error(t, err)
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf/models"
+)
+
+// PipelineGraph is the routing of metrics between the configured plugins.
+type PipelineGraph struct {
+	Nodes []PipelineNode `json:"nodes"`
+	Edges []PipelineEdge `json:"edges"`
+}
+
+// PipelineNode is a plugin of the pipeline.  Kind is one of input,
+// processor, aggregator and output.
+type PipelineNode struct {
+	ID           string          `json:"id"`
+	Kind         string          `json:"kind"`
+	Name         string          `json:"name"`
+	Filter       *PipelineFilter `json:"filter,omitempty"`
+	DropOriginal bool            `json:"drop_original,omitempty"`
+}
+
+// PipelineEdge is a path metrics take from one plugin to another.
+type PipelineEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+// PipelineFilter holds the metric filters of a plugin.
+type PipelineFilter struct {
+	NamePass   []string            `json:"namepass,omitempty"`
+	NameDrop   []string            `json:"namedrop,omitempty"`
+	FieldPass  []string            `json:"fieldpass,omitempty"`
+	FieldDrop  []string            `json:"fielddrop,omitempty"`
+	TagPass    map[string][]string `json:"tagpass,omitempty"`
+	TagDrop    map[string][]string `json:"tagdrop,omitempty"`
+	TagInclude []string            `json:"taginclude,omitempty"`
+	TagExclude []string            `json:"tagexclude,omitempty"`
+}
+
+// PipelineGraph returns the routing of metrics from the inputs through the
+// processors and aggregators to the outputs, as run by the agent.
+//
+// Every metric passes all processors in order, processors only modify the
+// metrics matching their filter.  The aggregators receive a copy of the
+// processed metrics, the originals continue to the outputs unless dropped by
+// an aggregator with drop_original.  The aggregates pass the processors
+// again before reaching the outputs.
+func (c *Config) PipelineGraph() *PipelineGraph {
+	g := &PipelineGraph{
+		Nodes: []PipelineNode{},
+		Edges: []PipelineEdge{},
+	}
+
+	var heads []string
+	for i, input := range c.Inputs {
+		id := fmt.Sprintf("input_%d", i)
+		g.addNode(id, "input", input.LogName(), &input.Config.Filter)
+		heads = append(heads, id)
+	}
+	heads = g.addProcessors("processor", c.Processors, heads)
+
+	var dropping []string
+	var aggregates []string
+	for i, aggregator := range c.Aggregators {
+		id := fmt.Sprintf("aggregator_%d", i)
+		g.addNode(id, "aggregator", aggregator.LogName(), &aggregator.Config.Filter)
+		if aggregator.Config.DropOriginal {
+			g.Nodes[len(g.Nodes)-1].DropOriginal = true
+			dropping = append(dropping, aggregator.LogName())
+		}
+		g.addEdges(heads, id, "")
+		aggregates = append(aggregates, id)
+	}
+	if len(aggregates) > 0 {
+		aggregates = g.addProcessors("aggprocessor", c.AggProcessors, aggregates)
+	}
+
+	var label string
+	if len(dropping) > 0 {
+		label = "unless dropped by " + strings.Join(dropping, ", ")
+	}
+	for i, output := range c.Outputs {
+		id := fmt.Sprintf("output_%d", i)
+		g.addNode(id, "output", output.LogName(), &output.Config.Filter)
+		g.addEdges(heads, id, label)
+		g.addEdges(aggregates, id, "aggregates")
+	}
+	return g
+}
+
+// addProcessors chains the processors in their order after the heads and
+// returns the new head.
+func (g *PipelineGraph) addProcessors(prefix string, processors models.RunningProcessors, heads []string) []string {
+	sorted := make(models.RunningProcessors, len(processors))
+	copy(sorted, processors)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Config.Order < sorted[j].Config.Order
+	})
+
+	for i, processor := range sorted {
+		id := fmt.Sprintf("%s_%d", prefix, i)
+		g.addNode(id, "processor", processor.LogName(), &processor.Config.Filter)
+		g.addEdges(heads, id, "")
+		heads = []string{id}
+	}
+	return heads
+}
+
+func (g *PipelineGraph) addNode(id, kind, name string, f *models.Filter) {
+	g.Nodes = append(g.Nodes, PipelineNode{
+		ID:     id,
+		Kind:   kind,
+		Name:   name,
+		Filter: newPipelineFilter(f),
+	})
+}
+
+func (g *PipelineGraph) addEdges(from []string, to, label string) {
+	for _, id := range from {
+		g.Edges = append(g.Edges, PipelineEdge{From: id, To: to, Label: label})
+	}
+}
+
+// newPipelineFilter returns the filters of a plugin, nil if it has none.
+func newPipelineFilter(f *models.Filter) *PipelineFilter {
+	pf := &PipelineFilter{
+		NamePass:   f.NamePass,
+		NameDrop:   f.NameDrop,
+		FieldPass:  f.FieldPass,
+		FieldDrop:  f.FieldDrop,
+		TagPass:    tagFilters(f.TagPass),
+		TagDrop:    tagFilters(f.TagDrop),
+		TagInclude: f.TagInclude,
+		TagExclude: f.TagExclude,
+	}
+	if len(pf.lines()) == 0 {
+		return nil
+	}
+	return pf
+}
+
+func tagFilters(filters []models.TagFilter) map[string][]string {
+	if len(filters) == 0 {
+		return nil
+	}
+	m := make(map[string][]string, len(filters))
+	for _, tf := range filters {
+		m[tf.Name] = tf.Filter
+	}
+	return m
+}
+
+// lines returns the filters in the syntax of the configuration file.
+func (f *PipelineFilter) lines() []string {
+	var lines []string
+	list := func(key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		quoted := make([]string, 0, len(values))
+		for _, v := range values {
+			quoted = append(quoted, fmt.Sprintf("%q", v))
+		}
+		lines = append(lines, fmt.Sprintf("%s = [%s]", key, strings.Join(quoted, ", ")))
+	}
+	tags := func(key string, filters map[string][]string) {
+		names := make([]string, 0, len(filters))
+		for name := range filters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			list(key+"."+name, filters[name])
+		}
+	}
+
+	list("namepass", f.NamePass)
+	list("namedrop", f.NameDrop)
+	list("fieldpass", f.FieldPass)
+	list("fielddrop", f.FieldDrop)
+	tags("tagpass", f.TagPass)
+	tags("tagdrop", f.TagDrop)
+	list("taginclude", f.TagInclude)
+	list("tagexclude", f.TagExclude)
+	return lines
+}
+
+var dotShapes = map[string]string{
+	"input":      "box",
+	"processor":  "ellipse",
+	"aggregator": "hexagon",
+	"output":     "box3d",
+}
+
+// WritePipelineDot writes the graph in the Graphviz DOT language, the
+// filters of the plugins are part of their labels.
+func WritePipelineDot(w io.Writer, g *PipelineGraph) error {
+	var b strings.Builder
+	b.WriteString("digraph pipeline {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		label := n.Name
+		if n.Filter != nil {
+			label += "\n" + strings.Join(n.Filter.lines(), "\n")
+		}
+		if n.DropOriginal {
+			label += "\ndrop_original = true"
+		}
+		fmt.Fprintf(&b, "  %q [shape=%s, label=%q];\n", n.ID, dotShapes[n.Kind], label)
+	}
+	for _, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.From, e.To, e.Label)
+			continue
+		}
+		fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WritePipelineJSON writes the graph as JSON document.
+func WritePipelineJSON(w io.Writer, g *PipelineGraph) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/stretchr/testify/require"
+)
+
+type graphProcessor struct{}
+
+func (p *graphProcessor) SampleConfig() string                          { return "" }
+func (p *graphProcessor) Description() string                           { return "" }
+func (p *graphProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric { return in }
+
+type graphAggregator struct{}
+
+func (a *graphAggregator) SampleConfig() string          { return "" }
+func (a *graphAggregator) Description() string           { return "" }
+func (a *graphAggregator) Add(in telegraf.Metric)        {}
+func (a *graphAggregator) Push(acc telegraf.Accumulator) {}
+func (a *graphAggregator) Reset()                        {}
+
+type graphOutput struct{}
+
+func (o *graphOutput) SampleConfig() string                  { return "" }
+func (o *graphOutput) Description() string                   { return "" }
+func (o *graphOutput) Connect() error                        { return nil }
+func (o *graphOutput) Close() error                          { return nil }
+func (o *graphOutput) Write(metrics []telegraf.Metric) error { return nil }
+
+func loadGraphConfig(t *testing.T, data string) *Config {
+	processors.Add("graph", func() telegraf.Processor { return &graphProcessor{} })
+	aggregators.Add("graph", func() telegraf.Aggregator { return &graphAggregator{} })
+	outputs.Add("graph", func() telegraf.Output { return &graphOutput{} })
+	t.Cleanup(func() {
+		delete(processors.Processors, "graph")
+		delete(aggregators.Aggregators, "graph")
+		delete(outputs.Outputs, "graph")
+	})
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(data)))
+	return c
+}
+
+func TestConfig_PipelineGraph(t *testing.T) {
+	c := loadGraphConfig(t, `
+[[inputs.memcached]]
+  alias = "a"
+  namepass = ["memcached"]
+
+[[inputs.memcached]]
+  alias = "b"
+
+[[processors.graph]]
+  alias = "second"
+  order = 2
+
+[[processors.graph]]
+  alias = "first"
+  order = 1
+  [processors.graph.tagpass]
+    rack = ["a*"]
+
+[[aggregators.graph]]
+  drop_original = true
+
+[[outputs.graph]]
+  alias = "all"
+
+[[outputs.graph]]
+  alias = "power"
+  namepass = ["ipmi_*"]
+`)
+
+	g := c.PipelineGraph()
+	require.Equal(t, []PipelineNode{
+		{ID: "input_0", Kind: "input", Name: "inputs.memcached::a", Filter: &PipelineFilter{NamePass: []string{"memcached"}}},
+		{ID: "input_1", Kind: "input", Name: "inputs.memcached::b"},
+		{ID: "processor_0", Kind: "processor", Name: "processors.graph::first", Filter: &PipelineFilter{TagPass: map[string][]string{"rack": {"a*"}}}},
+		{ID: "processor_1", Kind: "processor", Name: "processors.graph::second"},
+		{ID: "aggregator_0", Kind: "aggregator", Name: "aggregators.graph", DropOriginal: true},
+		{ID: "aggprocessor_0", Kind: "processor", Name: "processors.graph::first", Filter: &PipelineFilter{TagPass: map[string][]string{"rack": {"a*"}}}},
+		{ID: "aggprocessor_1", Kind: "processor", Name: "processors.graph::second"},
+		{ID: "output_0", Kind: "output", Name: "outputs.graph::all"},
+		{ID: "output_1", Kind: "output", Name: "outputs.graph::power", Filter: &PipelineFilter{NamePass: []string{"ipmi_*"}}},
+	}, g.Nodes)
+	require.Equal(t, []PipelineEdge{
+		{From: "input_0", To: "processor_0"},
+		{From: "input_1", To: "processor_0"},
+		{From: "processor_0", To: "processor_1"},
+		{From: "processor_1", To: "aggregator_0"},
+		{From: "aggregator_0", To: "aggprocessor_0"},
+		{From: "aggprocessor_0", To: "aggprocessor_1"},
+		{From: "processor_1", To: "output_0", Label: "unless dropped by aggregators.graph"},
+		{From: "aggprocessor_1", To: "output_0", Label: "aggregates"},
+		{From: "processor_1", To: "output_1", Label: "unless dropped by aggregators.graph"},
+		{From: "aggprocessor_1", To: "output_1", Label: "aggregates"},
+	}, g.Edges)
+}
+
+func TestConfig_PipelineGraphWithoutProcessors(t *testing.T) {
+	c := loadGraphConfig(t, `
+[[inputs.memcached]]
+
+[[outputs.graph]]
+`)
+
+	g := c.PipelineGraph()
+	require.Len(t, g.Nodes, 2)
+	require.Equal(t, []PipelineEdge{{From: "input_0", To: "output_0"}}, g.Edges)
+}
+
+func TestWritePipeline(t *testing.T) {
+	g := &PipelineGraph{
+		Nodes: []PipelineNode{
+			{ID: "input_0", Kind: "input", Name: "inputs.ipmi_power"},
+			{ID: "output_0", Kind: "output", Name: "outputs.influxdb", Filter: &PipelineFilter{
+				NamePass: []string{"ipmi_*"},
+				TagDrop:  map[string][]string{"rack": {"lab"}},
+			}},
+		},
+		Edges: []PipelineEdge{{From: "input_0", To: "output_0"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePipelineDot(&buf, g))
+	require.Equal(t, `digraph pipeline {
+  rankdir=LR;
+  "input_0" [shape=box, label="inputs.ipmi_power"];
+  "output_0" [shape=box3d, label="outputs.influxdb\nnamepass = [\"ipmi_*\"]\ntagdrop.rack = [\"lab\"]"];
+  "input_0" -> "output_0";
+}
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WritePipelineJSON(&buf, g))
+	var decoded PipelineGraph
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, *g, decoded)
+}
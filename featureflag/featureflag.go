@@ -0,0 +1,59 @@
+// featureflag is a package for rolling out new implementations of plugins
+// gradually.  A flag is enabled for a percentage of keys, such as the servers
+// a plugin collects from, so a rewrite can run side-by-side with the legacy
+// implementation on a canary set of servers before it replaces it.
+//
+// A key is assigned to the same bucket on every agent and after restarts, and
+// keys enabled at a percentage stay enabled when it is raised.
+package featureflag
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+var (
+	mu    sync.RWMutex
+	flags = make(map[string]int)
+)
+
+// Set enables the flag for the percentage of keys, 0 disables it and 100
+// enables it for all keys.
+func Set(name string, percent int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if percent <= 0 {
+		delete(flags, name)
+		return
+	}
+	flags[name] = percent
+}
+
+// Percent returns the percentage of keys the flag is enabled for.
+func Percent(name string) int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return flags[name]
+}
+
+// Enabled reports whether the flag is enabled for the key.
+func Enabled(name, key string) bool {
+	percent := Percent(name)
+	if percent <= 0 {
+		return false
+	}
+	if percent >= 100 {
+		return true
+	}
+	return bucket(name, key) < percent
+}
+
+// bucket assigns the key to one of 100 buckets.  The flag name is hashed as
+// well, so the canary keys of different flags are independent.
+func bucket(name, key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,53 @@
+package featureflag
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func enabledKeys(name string) map[string]bool {
+	keys := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		if Enabled(name, key) {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+func TestEnabled(t *testing.T) {
+	defer Set("test", 0)
+
+	require.Empty(t, enabledKeys("test"))
+
+	Set("test", 100)
+	require.Len(t, enabledKeys("test"), 1000)
+
+	Set("test", 10)
+	canary := enabledKeys("test")
+	require.InDelta(t, 100, len(canary), 40)
+
+	// Raising the percentage keeps the canary keys enabled
+	Set("test", 50)
+	raised := enabledKeys("test")
+	require.InDelta(t, 500, len(raised), 60)
+	for key := range canary {
+		require.True(t, raised[key], key)
+	}
+
+	Set("test", 0)
+	require.Empty(t, enabledKeys("test"))
+	require.Equal(t, 0, Percent("test"))
+}
+
+func TestIndependentFlags(t *testing.T) {
+	defer Set("a", 0)
+	defer Set("b", 0)
+
+	Set("a", 50)
+	Set("b", 50)
+	require.NotEqual(t, enabledKeys("a"), enabledKeys("b"))
+}
@@ -1,5 +1,7 @@
 package telegraf
 
+import "time"
+
 type Input interface {
 	PluginDescriber
 
@@ -21,3 +23,27 @@ type ServiceInput interface {
 	// to the accumulator before returning.
 	Stop()
 }
+
+// SeriesEstimator is an interface that inputs may implement to estimate,
+// from their configuration alone, the series they produce on every gather.
+type SeriesEstimator interface {
+	// EstimateSeries returns the expected series and fields per series for
+	// each measurement produced by a single gather.
+	EstimateSeries() []SeriesEstimate
+}
+
+// SeriesEstimate is the expected output of a single measurement.
+type SeriesEstimate struct {
+	Measurement string
+	Series      int
+	Fields      int
+
+	// Interval overrides the interval of the input for measurements
+	// produced on their own schedule, such as by service inputs.
+	Interval time.Duration
+
+	// Unknown marks measurements whose series depend on what is found at
+	// runtime, such as the number of power supplies, so they cannot be
+	// estimated from the configuration.
+	Unknown bool
+}
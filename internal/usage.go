@@ -11,6 +11,10 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config analyze      print the series and points/s projected from the
+                      configuration loaded with --config
+  pipeline graph      print the routing of metrics between the plugins of the
+                      configuration, --format=dot (default) or --format=json
   version             print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
@@ -47,6 +51,12 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf --input-filter cpu --output-filter influxdb config
 
+  # estimate the backend load of a config before deploying it
+  telegraf --config telegraf.conf config analyze
+
+  # render the routing of metrics through the plugins with Graphviz
+  telegraf --config telegraf.conf pipeline graph | dot -Tsvg > pipeline.svg
+
   # run a single telegraf collection, outputting metrics to stdout
   telegraf --config telegraf.conf --test
 
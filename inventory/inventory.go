@@ -0,0 +1,225 @@
+// inventory is a package for sharing the devices discovered by plugins, such
+// as BMCs, PDUs or GPUs, and their capabilities.  Plugins register what they
+// discovered while probing a device and look it up before probing it again.
+// The agent persists the inventory if configured, so thousands of devices do
+// not have to be probed again on every restart.
+package inventory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var registry = newInventory()
+
+// Device is a device and the capabilities discovered by probing it.
+type Device struct {
+	// Kind is the type of the device, e.g. "bmc", "pdu" or "gpu".
+	Kind string `json:"kind"`
+
+	// ID identifies the device among the devices of its kind, e.g. its
+	// address or serial number.
+	ID string `json:"id"`
+
+	// Capabilities holds the discovered capabilities, e.g. "dcmi": "true".
+	Capabilities map[string]string `json:"capabilities"`
+
+	// DiscoveredAt is the time the capabilities were discovered.
+	DiscoveredAt time.Time `json:"discovered_at"`
+}
+
+type key struct {
+	kind string
+	id   string
+}
+
+// Inventory holds the known devices.
+type Inventory struct {
+	sync.RWMutex
+	devices map[key]Device
+	maxAge  time.Duration
+	now     func() time.Time
+}
+
+func newInventory() *Inventory {
+	return &Inventory{
+		devices: make(map[key]Device),
+		now:     time.Now,
+	}
+}
+
+// snapshot is the file format of the persisted inventory.
+type snapshot struct {
+	Devices []Device `json:"devices"`
+}
+
+// Register adds the device to the inventory, replacing a device of the same
+// kind and ID.  DiscoveredAt defaults to the current time.
+func Register(d Device) {
+	registry.register(d)
+}
+
+// Lookup returns the device of the given kind and ID if it is known and its
+// capabilities did not expire.
+func Lookup(kind, id string) (Device, bool) {
+	return registry.lookup(kind, id)
+}
+
+// Devices returns the known devices of the given kind sorted by ID, e.g. to
+// probe all discovered BMCs.
+func Devices(kind string) []Device {
+	return registry.list(kind)
+}
+
+// Forget removes the device from the inventory, e.g. after it was replaced.
+func Forget(kind, id string) {
+	registry.forget(kind, id)
+}
+
+// SetMaxAge sets the time after which discovered capabilities expire and the
+// device has to be probed again.  0 disables expiry.
+func SetMaxAge(maxAge time.Duration) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.maxAge = maxAge
+}
+
+// Load reads the devices persisted to the file, a missing file is treated as
+// an empty inventory.
+func Load(path string) error {
+	return registry.load(path)
+}
+
+// Save persists the devices to the file.
+func Save(path string) error {
+	return registry.save(path)
+}
+
+func (i *Inventory) register(d Device) {
+	if d.DiscoveredAt.IsZero() {
+		d.DiscoveredAt = i.now()
+	}
+	caps := make(map[string]string, len(d.Capabilities))
+	for k, v := range d.Capabilities {
+		caps[k] = v
+	}
+	d.Capabilities = caps
+
+	i.Lock()
+	defer i.Unlock()
+	i.devices[key{d.Kind, d.ID}] = d
+}
+
+func (i *Inventory) lookup(kind, id string) (Device, bool) {
+	i.RLock()
+	defer i.RUnlock()
+	d, ok := i.devices[key{kind, id}]
+	if !ok || i.expired(d) {
+		return Device{}, false
+	}
+
+	caps := make(map[string]string, len(d.Capabilities))
+	for k, v := range d.Capabilities {
+		caps[k] = v
+	}
+	d.Capabilities = caps
+	return d, true
+}
+
+func (i *Inventory) list(kind string) []Device {
+	i.RLock()
+	defer i.RUnlock()
+	var devices []Device
+	for k, d := range i.devices {
+		if k.kind != kind || i.expired(d) {
+			continue
+		}
+		caps := make(map[string]string, len(d.Capabilities))
+		for k, v := range d.Capabilities {
+			caps[k] = v
+		}
+		d.Capabilities = caps
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(a, b int) bool { return devices[a].ID < devices[b].ID })
+	return devices
+}
+
+func (i *Inventory) forget(kind, id string) {
+	i.Lock()
+	defer i.Unlock()
+	delete(i.devices, key{kind, id})
+}
+
+func (i *Inventory) expired(d Device) bool {
+	return i.maxAge > 0 && i.now().Sub(d.DiscoveredAt) > i.maxAge
+}
+
+func (i *Inventory) load(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return err
+	}
+
+	i.Lock()
+	defer i.Unlock()
+	for _, d := range s.Devices {
+		k := key{d.Kind, d.ID}
+		// Devices probed since startup are more recent
+		if cur, ok := i.devices[k]; ok && cur.DiscoveredAt.After(d.DiscoveredAt) {
+			continue
+		}
+		i.devices[k] = d
+	}
+	return nil
+}
+
+func (i *Inventory) save(path string) error {
+	i.RLock()
+	s := snapshot{Devices: make([]Device, 0, len(i.devices))}
+	for _, d := range i.devices {
+		if !i.expired(d) {
+			s.Devices = append(s.Devices, d)
+		}
+	}
+	i.RUnlock()
+
+	sort.Slice(s.Devices, func(a, b int) bool {
+		if s.Devices[a].Kind != s.Devices[b].Kind {
+			return s.Devices[a].Kind < s.Devices[b].Kind
+		}
+		return s.Devices[a].ID < s.Devices[b].ID
+	})
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
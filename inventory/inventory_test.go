@@ -0,0 +1,114 @@
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterLookup(t *testing.T) {
+	i := newInventory()
+	caps := map[string]string{"dcmi": "true"}
+	i.register(Device{Kind: "bmc", ID: "10.0.0.1", Capabilities: caps})
+
+	d, ok := i.lookup("bmc", "10.0.0.1")
+	require.True(t, ok)
+	require.Equal(t, "true", d.Capabilities["dcmi"])
+	require.False(t, d.DiscoveredAt.IsZero())
+
+	// Callers can not modify the registered capabilities
+	caps["dcmi"] = "false"
+	d.Capabilities["dcmi"] = "false"
+	d, _ = i.lookup("bmc", "10.0.0.1")
+	require.Equal(t, "true", d.Capabilities["dcmi"])
+
+	_, ok = i.lookup("pdu", "10.0.0.1")
+	require.False(t, ok)
+
+	i.forget("bmc", "10.0.0.1")
+	_, ok = i.lookup("bmc", "10.0.0.1")
+	require.False(t, ok)
+}
+
+func TestMaxAge(t *testing.T) {
+	now := time.Date(2020, 12, 15, 10, 0, 0, 0, time.UTC)
+	i := newInventory()
+	i.now = func() time.Time { return now }
+	i.maxAge = time.Hour
+
+	i.register(Device{Kind: "bmc", ID: "10.0.0.1", DiscoveredAt: now.Add(-2 * time.Hour)})
+	i.register(Device{Kind: "bmc", ID: "10.0.0.2", DiscoveredAt: now.Add(-time.Minute)})
+
+	_, ok := i.lookup("bmc", "10.0.0.1")
+	require.False(t, ok)
+	_, ok = i.lookup("bmc", "10.0.0.2")
+	require.True(t, ok)
+}
+
+func TestList(t *testing.T) {
+	now := time.Date(2020, 12, 15, 10, 0, 0, 0, time.UTC)
+	i := newInventory()
+	i.now = func() time.Time { return now }
+	i.maxAge = time.Hour
+
+	i.register(Device{Kind: "bmc", ID: "10.0.0.2"})
+	i.register(Device{Kind: "bmc", ID: "10.0.0.1", Capabilities: map[string]string{"dcmi": "true"}})
+	i.register(Device{Kind: "bmc", ID: "10.0.0.3", DiscoveredAt: now.Add(-2 * time.Hour)})
+	i.register(Device{Kind: "pdu", ID: "10.0.1.1"})
+
+	devices := i.list("bmc")
+	require.Len(t, devices, 2)
+	require.Equal(t, "10.0.0.1", devices[0].ID)
+	require.Equal(t, "10.0.0.2", devices[1].ID)
+
+	// Callers can not modify the registered capabilities
+	devices[0].Capabilities["dcmi"] = "false"
+	d, _ := i.lookup("bmc", "10.0.0.1")
+	require.Equal(t, "true", d.Capabilities["dcmi"])
+
+	require.Empty(t, i.list("gpu"))
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inventory.json")
+
+	// A missing file is an empty inventory
+	i := newInventory()
+	require.NoError(t, i.load(path))
+
+	discovered := time.Date(2020, 12, 15, 10, 0, 0, 0, time.UTC)
+	i.register(Device{
+		Kind:         "bmc",
+		ID:           "10.0.0.1",
+		Capabilities: map[string]string{"dcmi": "false"},
+		DiscoveredAt: discovered,
+	})
+	require.NoError(t, i.save(path))
+
+	restored := newInventory()
+	// Devices probed before loading take precedence over older ones
+	restored.register(Device{
+		Kind:         "bmc",
+		ID:           "10.0.0.1",
+		Capabilities: map[string]string{"dcmi": "true"},
+		DiscoveredAt: discovered.Add(time.Hour),
+	})
+	require.NoError(t, restored.load(path))
+	d, ok := restored.lookup("bmc", "10.0.0.1")
+	require.True(t, ok)
+	require.Equal(t, "true", d.Capabilities["dcmi"])
+
+	restored = newInventory()
+	require.NoError(t, restored.load(path))
+	d, ok = restored.lookup("bmc", "10.0.0.1")
+	require.True(t, ok)
+	require.Equal(t, "false", d.Capabilities["dcmi"])
+	require.True(t, discovered.Equal(d.DiscoveredAt))
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -15,6 +19,7 @@ type RunningAggregator struct {
 	Config      *AggregatorConfig
 	periodStart time.Time
 	periodEnd   time.Time
+	lastPushed  time.Time
 	log         telegraf.Logger
 
 	MetricsPushed   selfstat.Stat
@@ -72,6 +77,9 @@ type AggregatorConfig struct {
 	Period       time.Duration
 	Delay        time.Duration
 	Grace        time.Duration
+	// Location aligns the windows to the local midnight of the time zone
+	// if set, see WindowAt.
+	Location *time.Location
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -102,6 +110,35 @@ func (r *RunningAggregator) EndPeriod() time.Time {
 	return r.periodEnd
 }
 
+// WindowAt returns the window containing t when the windows are aligned to
+// the local midnight of the configured time zone.  Periods of whole days
+// end at local midnight, counting days from 1970-01-01, so a daily window
+// spans 23 or 25 hours on the days daylight saving time begins or ends.
+// Shorter periods start at local midnight and the last window of a day ends
+// at the next local midnight if the period does not divide the day.
+func (r *RunningAggregator) WindowAt(t time.Time) (time.Time, time.Time) {
+	return localWindow(t, r.Config.Period, r.Config.Location)
+}
+
+func localWindow(t time.Time, period time.Duration, loc *time.Location) (time.Time, time.Time) {
+	lt := t.In(loc)
+	midnight := time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, loc)
+
+	if period%(24*time.Hour) == 0 {
+		days := int(period / (24 * time.Hour))
+		epochDay := time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		since := midnight.AddDate(0, 0, -int(epochDay%int64(days)))
+		return since, since.AddDate(0, 0, days)
+	}
+
+	since := midnight.Add(lt.Sub(midnight) / period * period)
+	until := since.Add(period)
+	if next := midnight.AddDate(0, 0, 1); until.After(next) {
+		until = next
+	}
+	return since, until
+}
+
 func (r *RunningAggregator) UpdateWindow(start, until time.Time) {
 	r.periodStart = start
 	r.periodEnd = until
@@ -165,9 +202,13 @@ func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
 
 	since := r.periodEnd
 	until := r.periodEnd.Add(r.Config.Period)
+	if r.Config.Location != nil {
+		_, until = r.WindowAt(since)
+	}
 	r.UpdateWindow(since, until)
 
 	r.push(acc)
+	r.lastPushed = since
 	r.Aggregator.Reset()
 }
 
@@ -181,3 +222,119 @@ func (r *RunningAggregator) push(acc telegraf.Accumulator) {
 func (r *RunningAggregator) Log() telegraf.Logger {
 	return r.log
 }
+
+// aggregatorState is the snapshot of a StatefulAggregator written to disk.
+type aggregatorState struct {
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+	LastPushed  time.Time       `json:"last_pushed"`
+	State       json.RawMessage `json:"state"`
+}
+
+// Stateful returns true if the aggregator can snapshot its window state.
+func (r *RunningAggregator) Stateful() bool {
+	_, ok := r.Aggregator.(telegraf.StatefulAggregator)
+	return ok
+}
+
+// StateFile returns the name of the snapshot file of the aggregator.
+func (r *RunningAggregator) StateFile() string {
+	name := r.Config.Name
+	if r.Config.Alias != "" {
+		name += "-" + r.Config.Alias
+	}
+	return name + ".json"
+}
+
+// SaveState writes a snapshot of the current window to the file.  The file
+// is replaced atomically so a crash never leaves a partial snapshot.
+func (r *RunningAggregator) SaveState(path string) error {
+	agg, ok := r.Aggregator.(telegraf.StatefulAggregator)
+	if !ok {
+		return nil
+	}
+
+	// The state may share the maps of the aggregator, it is marshalled
+	// before releasing the lock so Add cannot modify them meanwhile.
+	r.Lock()
+	snapshot := aggregatorState{
+		PeriodStart: r.periodStart,
+		PeriodEnd:   r.periodEnd,
+		LastPushed:  r.lastPushed,
+	}
+	state, err := agg.GetState()
+	if err == nil {
+		snapshot.State, err = json.Marshal(state)
+	}
+	r.Unlock()
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadState restores the window state from a snapshot file, it must be
+// called after the initial window was set and before metrics are added.
+// Windows that were already pushed are discarded, a window that ended while
+// the agent was stopped is restored so it is pushed immediately.
+func (r *RunningAggregator) LoadState(path string) error {
+	agg, ok := r.Aggregator.(telegraf.StatefulAggregator)
+	if !ok {
+		return nil
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot aggregatorState
+	if err := json.Unmarshal(buf, &snapshot); err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastPushed = snapshot.LastPushed
+	if !snapshot.LastPushed.IsZero() && !snapshot.PeriodEnd.After(snapshot.LastPushed) {
+		r.log.Debugf("Discarding state of already pushed window [%s, %s]",
+			snapshot.PeriodStart, snapshot.PeriodEnd)
+		return nil
+	}
+	if !snapshot.PeriodEnd.After(r.periodStart) || snapshot.PeriodEnd.Equal(r.periodEnd) {
+		if err := agg.SetState(snapshot.State); err != nil {
+			return err
+		}
+		r.periodStart = snapshot.PeriodStart
+		r.periodEnd = snapshot.PeriodEnd
+		r.log.Debugf("Restored state of window [%s, %s]", r.periodStart, r.periodEnd)
+		return nil
+	}
+
+	r.log.Debugf("Discarding state of overlapping window [%s, %s]",
+		snapshot.PeriodStart, snapshot.PeriodEnd)
+	return nil
+}
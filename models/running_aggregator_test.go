@@ -1,6 +1,12 @@
 package models
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -238,6 +244,241 @@ func TestAddDoesNotModifyMetric(t *testing.T) {
 	testutil.RequireMetricEqual(t, expected, m)
 }
 
+func newStatefulAggregator(t *testing.T, agg telegraf.Aggregator) *RunningAggregator {
+	ra := NewRunningAggregator(agg, &AggregatorConfig{
+		Name: "TestRunningAggregator",
+		Filter: Filter{
+			NamePass: []string{"*"},
+		},
+		Period: time.Minute,
+	})
+	require.NoError(t, ra.Config.Filter.Compile())
+	return ra
+}
+
+func TestSaveAndLoadState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aggstate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ra := newStatefulAggregator(t, &TestStatefulAggregator{})
+	require.True(t, ra.Stateful())
+	path := filepath.Join(dir, ra.StateFile())
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ra.UpdateWindow(start, start.Add(time.Minute))
+	m := testutil.MustMetric("RITest",
+		map[string]string{},
+		map[string]interface{}{"value": int64(101)},
+		start.Add(time.Second),
+	)
+	require.False(t, ra.Add(m))
+	require.NoError(t, ra.SaveState(path))
+
+	// Restarted within the same window
+	restored := &TestStatefulAggregator{}
+	ra = newStatefulAggregator(t, restored)
+	ra.UpdateWindow(start, start.Add(time.Minute))
+	require.NoError(t, ra.LoadState(path))
+	require.Equal(t, int64(101), restored.sum)
+
+	// Restarted after the window ended, the window is restored so it is
+	// pushed right away.
+	restored = &TestStatefulAggregator{}
+	ra = newStatefulAggregator(t, restored)
+	ra.UpdateWindow(start.Add(time.Hour), start.Add(time.Hour+time.Minute))
+	require.NoError(t, ra.LoadState(path))
+	require.Equal(t, int64(101), restored.sum)
+	require.Equal(t, start.Add(time.Minute), ra.EndPeriod())
+}
+
+func TestLoadStateDiscardsPushedWindow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aggstate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ra := newStatefulAggregator(t, &TestStatefulAggregator{})
+	path := filepath.Join(dir, ra.StateFile())
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ra.UpdateWindow(start, start.Add(time.Minute))
+	m := testutil.MustMetric("RITest",
+		map[string]string{},
+		map[string]interface{}{"value": int64(101)},
+		start.Add(time.Second),
+	)
+	require.False(t, ra.Add(m))
+	ra.Push(&testutil.Accumulator{})
+	require.NoError(t, ra.SaveState(path))
+
+	restored := &TestStatefulAggregator{}
+	ra = newStatefulAggregator(t, restored)
+	ra.UpdateWindow(start.Add(time.Hour), start.Add(time.Hour+time.Minute))
+	require.NoError(t, ra.LoadState(path))
+	require.Equal(t, int64(0), restored.sum)
+}
+
+// The aggregators return their maps from GetState, the snapshot must not be
+// marshalled while metrics are added.  Run with -race.
+func TestSaveStateWhileAdding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aggstate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ra := newStatefulAggregator(t, &TestMapAggregator{sums: make(map[string]int64)})
+	path := filepath.Join(dir, ra.StateFile())
+	start := time.Now()
+	ra.UpdateWindow(start, start.Add(time.Hour))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			ra.Add(testutil.MustMetric("RITest",
+				map[string]string{},
+				map[string]interface{}{"value" + strconv.Itoa(i%100): int64(i)},
+				start.Add(time.Second),
+			))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, ra.SaveState(path))
+	}
+	wg.Wait()
+}
+
+func TestLoadStateMissingFile(t *testing.T) {
+	restored := &TestStatefulAggregator{}
+	ra := newStatefulAggregator(t, restored)
+	now := time.Now()
+	ra.UpdateWindow(now, now.Add(time.Minute))
+	require.NoError(t, ra.LoadState(filepath.Join(os.TempDir(), "does-not-exist.json")))
+	require.Equal(t, int64(0), restored.sum)
+}
+
+func TestWindowAt(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+
+	local := func(loc *time.Location, month time.Month, day, hour, min int) time.Time {
+		return time.Date(2020, month, day, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name   string
+		period time.Duration
+		loc    *time.Location
+		t      time.Time
+		since  time.Time
+		until  time.Time
+	}{
+		{
+			name:   "day",
+			period: 24 * time.Hour,
+			loc:    ny,
+			t:      local(ny, time.January, 15, 13, 0),
+			since:  local(ny, time.January, 15, 0, 0),
+			until:  local(ny, time.January, 16, 0, 0),
+		},
+		{
+			name:   "day daylight saving time begins",
+			period: 24 * time.Hour,
+			loc:    ny,
+			t:      local(ny, time.March, 8, 12, 0),
+			since:  local(ny, time.March, 8, 0, 0),
+			until:  local(ny, time.March, 8, 0, 0).Add(23 * time.Hour),
+		},
+		{
+			name:   "day daylight saving time ends",
+			period: 24 * time.Hour,
+			loc:    ny,
+			t:      local(ny, time.November, 1, 23, 59),
+			since:  local(ny, time.November, 1, 0, 0),
+			until:  local(ny, time.November, 1, 0, 0).Add(25 * time.Hour),
+		},
+		{
+			name:   "week",
+			period: 7 * 24 * time.Hour,
+			loc:    ny,
+			t:      local(ny, time.March, 10, 8, 0),
+			since:  local(ny, time.March, 5, 0, 0),
+			until:  local(ny, time.March, 12, 0, 0),
+		},
+		{
+			name:   "hour daylight saving time begins",
+			period: time.Hour,
+			loc:    ny,
+			t:      local(ny, time.March, 8, 3, 30),
+			since:  local(ny, time.March, 8, 3, 0),
+			until:  local(ny, time.March, 8, 4, 0),
+		},
+		{
+			name:   "second 1am when daylight saving time ends",
+			period: time.Hour,
+			loc:    ny,
+			t:      local(ny, time.November, 1, 0, 0).Add(2*time.Hour + 30*time.Minute),
+			since:  local(ny, time.November, 1, 0, 0).Add(2 * time.Hour),
+			until:  local(ny, time.November, 1, 2, 0),
+		},
+		{
+			name:   "period not dividing the day",
+			period: 7 * time.Hour,
+			loc:    ny,
+			t:      local(ny, time.January, 15, 22, 0),
+			since:  local(ny, time.January, 15, 21, 0),
+			until:  local(ny, time.January, 16, 0, 0),
+		},
+		{
+			name:   "half hour offset",
+			period: time.Hour,
+			loc:    kolkata,
+			t:      local(kolkata, time.January, 15, 10, 45),
+			since:  local(kolkata, time.January, 15, 10, 0),
+			until:  local(kolkata, time.January, 15, 11, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+				Name:     "TestRunningAggregator",
+				Period:   tt.period,
+				Location: tt.loc,
+			})
+			since, until := ra.WindowAt(tt.t)
+			require.True(t, tt.since.Equal(since), "since %s, expected %s", since, tt.since)
+			require.True(t, tt.until.Equal(until), "until %s, expected %s", until, tt.until)
+		})
+	}
+}
+
+func TestPushLocalWindows(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+		Name:     "TestRunningAggregator",
+		Period:   24 * time.Hour,
+		Location: ny,
+	})
+	acc := testutil.Accumulator{}
+
+	ra.UpdateWindow(ra.WindowAt(time.Date(2020, time.March, 7, 12, 0, 0, 0, ny)))
+	require.Equal(t, time.Date(2020, time.March, 8, 0, 0, 0, 0, ny), ra.EndPeriod())
+
+	// Windows end at local midnight across the begin of daylight saving time
+	var durations []time.Duration
+	for i := 0; i < 3; i++ {
+		start := ra.EndPeriod()
+		ra.Push(&acc)
+		durations = append(durations, ra.EndPeriod().Sub(start))
+	}
+	require.Equal(t, []time.Duration{23 * time.Hour, 24 * time.Hour, 24 * time.Hour}, durations)
+	require.Equal(t, time.Date(2020, time.March, 11, 0, 0, 0, 0, ny), ra.EndPeriod().In(ny))
+}
+
 type TestAggregator struct {
 	sum int64
 }
@@ -262,3 +503,45 @@ func (t *TestAggregator) Add(in telegraf.Metric) {
 		}
 	}
 }
+
+type TestStatefulAggregator struct {
+	TestAggregator
+}
+
+func (t *TestStatefulAggregator) GetState() (interface{}, error) {
+	return t.sum, nil
+}
+
+func (t *TestStatefulAggregator) SetState(state []byte) error {
+	return json.Unmarshal(state, &t.sum)
+}
+
+// TestMapAggregator returns its internal map as state, like the power
+// aggregators.
+type TestMapAggregator struct {
+	sums map[string]int64
+}
+
+func (t *TestMapAggregator) Description() string  { return "" }
+func (t *TestMapAggregator) SampleConfig() string { return "" }
+func (t *TestMapAggregator) Reset() {
+	t.sums = make(map[string]int64)
+}
+
+func (t *TestMapAggregator) Push(acc telegraf.Accumulator) {}
+
+func (t *TestMapAggregator) Add(in telegraf.Metric) {
+	for k, v := range in.Fields() {
+		if vi, ok := v.(int64); ok {
+			t.sums[k] += vi
+		}
+	}
+}
+
+func (t *TestMapAggregator) GetState() (interface{}, error) {
+	return t.sums, nil
+}
+
+func (t *TestMapAggregator) SetState(state []byte) error {
+	return json.Unmarshal(state, &t.sums)
+}
@@ -31,6 +31,10 @@ type OutputConfig struct {
 	NameOverride string
 	NamePrefix   string
 	NameSuffix   string
+
+	// Resolution of the timestamps written by the output, zero if the output
+	// keeps the full precision.
+	TimestampResolution time.Duration
 }
 
 // RunningOutput contains the output configuration
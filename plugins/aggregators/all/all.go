@@ -6,5 +6,8 @@ import (
 	_ "github.com/influxdata/telegraf/plugins/aggregators/histogram"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/merge"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/minmax"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/power_balance"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/power_sla"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/rack_power"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/valuecounter"
 )
@@ -0,0 +1,190 @@
+package power_balance
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const measurement = "power_balance"
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Fields holding the power reading of a meter, the first field found on
+  ## a metric is used.
+  fields = ["instantaneous_power_reading"]
+
+  ## Tags identifying a meter, the first tag found on a metric is used.  The
+  ## ipmi_power input tags readings of remote servers with server, readings
+  ## of the local machine carry the host tag of the agent only.
+  meter_tags = ["server", "host"]
+
+  ## Mapping of child meters to their parent meter.  Any meter can be both
+  ## a child and a parent, e.g. nodes map to a PDU and PDUs map to a panel.
+  [aggregators.power_balance.topology]
+    # "192.168.1.1" = "pdu-a1"
+    # "192.168.1.2" = "pdu-a1"
+    # "pdu-a1" = "panel-1"
+`
+
+// PowerBalance compares the summed power of child meters to the reading of
+// their parent meter.
+type PowerBalance struct {
+	Fields    []string          `toml:"fields"`
+	MeterTags []string          `toml:"meter_tags"`
+	Topology  map[string]string `toml:"topology"`
+
+	children map[string][]string
+	readings map[string]*reading
+}
+
+type reading struct {
+	Sum   float64 `json:"sum"`
+	Count int     `json:"count"`
+}
+
+func (r *reading) mean() float64 {
+	return r.Sum / float64(r.Count)
+}
+
+// NewPowerBalance creates a PowerBalance aggregator with the default settings.
+func NewPowerBalance() *PowerBalance {
+	p := &PowerBalance{
+		Fields:    []string{"instantaneous_power_reading"},
+		MeterTags: []string{"server", "host"},
+	}
+	p.Reset()
+	return p
+}
+
+func (p *PowerBalance) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PowerBalance) Description() string {
+	return "Compare the summed power of child meters against their parent meter."
+}
+
+func (p *PowerBalance) Init() error {
+	p.children = make(map[string][]string)
+	for child, parent := range p.Topology {
+		p.children[parent] = append(p.children[parent], child)
+	}
+	for _, c := range p.children {
+		sort.Strings(c)
+	}
+	return nil
+}
+
+func (p *PowerBalance) Add(in telegraf.Metric) {
+	meter, ok := p.meter(in)
+	if !ok {
+		return
+	}
+
+	for _, field := range p.Fields {
+		v, ok := in.GetField(field)
+		if !ok {
+			continue
+		}
+		fv, ok := convert(v)
+		if !ok {
+			return
+		}
+		r, ok := p.readings[meter]
+		if !ok {
+			r = &reading{}
+			p.readings[meter] = r
+		}
+		r.Sum += fv
+		r.Count++
+		return
+	}
+}
+
+func (p *PowerBalance) Push(acc telegraf.Accumulator) {
+	for parent, children := range p.children {
+		pr, ok := p.readings[parent]
+		if !ok {
+			continue
+		}
+		parentPower := pr.mean()
+
+		var childrenPower float64
+		var reporting int
+		for _, child := range children {
+			if cr, ok := p.readings[child]; ok {
+				childrenPower += cr.mean()
+				reporting++
+			}
+		}
+
+		fields := map[string]interface{}{
+			"parent_power":       parentPower,
+			"children_power":     childrenPower,
+			"children_reporting": reporting,
+			"children_expected":  len(children),
+		}
+		if parentPower != 0 {
+			fields["discrepancy_percent"] = (childrenPower - parentPower) / parentPower * 100
+		}
+
+		acc.AddFields(measurement, fields, map[string]string{"parent": parent})
+	}
+}
+
+func (p *PowerBalance) Reset() {
+	p.readings = make(map[string]*reading)
+}
+
+// GetState returns the readings of the current window.
+func (p *PowerBalance) GetState() (interface{}, error) {
+	return p.readings, nil
+}
+
+// SetState restores the readings of the current window.
+func (p *PowerBalance) SetState(state []byte) error {
+	readings := make(map[string]*reading)
+	if err := json.Unmarshal(state, &readings); err != nil {
+		return err
+	}
+	p.readings = readings
+	return nil
+}
+
+// meter returns the identifier of the meter the metric was measured by.
+func (p *PowerBalance) meter(in telegraf.Metric) (string, bool) {
+	for _, tag := range p.MeterTags {
+		if v, ok := in.GetTag(tag); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("power_balance", func() telegraf.Aggregator {
+		return NewPowerBalance()
+	})
+}
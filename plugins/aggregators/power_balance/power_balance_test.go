@@ -0,0 +1,175 @@
+package power_balance
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func power(server string, watts float64) telegraf.Metric {
+	return testutil.MustMetric(
+		"ipmi_power",
+		map[string]string{"server": server},
+		map[string]interface{}{"instantaneous_power_reading": watts},
+		time.Unix(0, 0),
+	)
+}
+
+func TestPowerBalance(t *testing.T) {
+	p := NewPowerBalance()
+	p.Topology = map[string]string{
+		"node1": "pdu1",
+		"node2": "pdu1",
+		"node3": "pdu1",
+	}
+	require.NoError(t, p.Init())
+
+	p.Add(power("node1", 100))
+	p.Add(power("node1", 200))
+	p.Add(power("node2", 250))
+	p.Add(power("pdu1", 400))
+
+	acc := testutil.Accumulator{}
+	p.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "power_balance",
+		map[string]interface{}{
+			"parent_power":        float64(400),
+			"children_power":      float64(400),
+			"children_reporting":  2,
+			"children_expected":   3,
+			"discrepancy_percent": float64(0),
+		},
+		map[string]string{"parent": "pdu1"},
+	)
+}
+
+// ipmiPowerOutput is written by the ipmi_power input of a collector querying
+// the BMCs of two blades and their chassis, and by the input of a third blade
+// querying its own BMC.
+const ipmiPowerOutput = `
+ipmi_power,host=collector,server=10.0.0.1 instantaneous_power_reading=220,instantaneous_power_reading_unit="Watts",minimum_during_sampling_period=28,minimum_during_sampling_period_unit="Watts",maximum_during_sampling_period=534,maximum_during_sampling_period_unit="Watts",average_power_reading_over_sample_period=222,average_power_reading_over_sample_period_unit="Watts",sampling_period=1,sampling_period_unit="Seconds." 1608026653000000000
+ipmi_power,host=collector,server=10.0.0.2 instantaneous_power_reading=180,instantaneous_power_reading_unit="Watts",minimum_during_sampling_period=20,minimum_during_sampling_period_unit="Watts",maximum_during_sampling_period=410,maximum_during_sampling_period_unit="Watts",average_power_reading_over_sample_period=181,average_power_reading_over_sample_period_unit="Watts",sampling_period=1,sampling_period_unit="Seconds." 1608026653000000000
+ipmi_power,host=collector,oem_profile=dell,server=10.0.0.10 instantaneous_power_reading=640,instantaneous_power_reading_unit="Watts" 1608026653000000000
+ipmi_power,host=blade3 instantaneous_power_reading=200,instantaneous_power_reading_unit="Watts",minimum_during_sampling_period=30,minimum_during_sampling_period_unit="Watts",maximum_during_sampling_period=390,maximum_during_sampling_period_unit="Watts",average_power_reading_over_sample_period=199,average_power_reading_over_sample_period_unit="Watts",sampling_period=1,sampling_period_unit="Seconds." 1608026653000000000
+ipmi_power_errors,class=timeout,host=collector,server=10.0.0.3 count=1i 1608026653000000000
+`
+
+func TestPowerBalanceIPMIPower(t *testing.T) {
+	p := NewPowerBalance()
+	p.Topology = map[string]string{
+		"10.0.0.1": "10.0.0.10",
+		"10.0.0.2": "10.0.0.10",
+		"blade3":   "10.0.0.10",
+	}
+	require.NoError(t, p.Init())
+
+	parser := influx.NewParser(influx.NewMetricHandler())
+	metrics, err := parser.Parse([]byte(ipmiPowerOutput))
+	require.NoError(t, err)
+	for _, m := range metrics {
+		p.Add(m)
+	}
+
+	acc := testutil.Accumulator{}
+	p.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "power_balance",
+		map[string]interface{}{
+			"parent_power":        float64(640),
+			"children_power":      float64(600),
+			"children_reporting":  3,
+			"children_expected":   3,
+			"discrepancy_percent": -6.25,
+		},
+		map[string]string{"parent": "10.0.0.10"},
+	)
+}
+
+func TestPowerBalanceNested(t *testing.T) {
+	p := NewPowerBalance()
+	p.Topology = map[string]string{
+		"node1": "pdu1",
+		"node2": "pdu2",
+		"pdu1":  "panel",
+		"pdu2":  "panel",
+	}
+	require.NoError(t, p.Init())
+
+	p.Add(power("node1", 90))
+	p.Add(power("node2", 110))
+	p.Add(power("pdu1", 100))
+	p.Add(power("pdu2", 100))
+	p.Add(power("panel", 250))
+
+	acc := testutil.Accumulator{}
+	p.Push(&acc)
+
+	require.Len(t, acc.Metrics, 3)
+	acc.AssertContainsTaggedFields(t, "power_balance",
+		map[string]interface{}{
+			"parent_power":        float64(250),
+			"children_power":      float64(200),
+			"children_reporting":  2,
+			"children_expected":   2,
+			"discrepancy_percent": float64(-20),
+		},
+		map[string]string{"parent": "panel"},
+	)
+}
+
+func TestPowerBalanceMissingParent(t *testing.T) {
+	p := NewPowerBalance()
+	p.Topology = map[string]string{"node1": "pdu1"}
+	require.NoError(t, p.Init())
+
+	p.Add(power("node1", 100))
+
+	acc := testutil.Accumulator{}
+	p.Push(&acc)
+	require.Empty(t, acc.Metrics)
+
+	p.Reset()
+	p.Add(power("pdu1", 0))
+	p.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+	require.False(t, acc.HasField("power_balance", "discrepancy_percent"))
+}
+
+func TestPowerBalanceState(t *testing.T) {
+	p := NewPowerBalance()
+	p.Topology = map[string]string{"node1": "pdu1"}
+	require.NoError(t, p.Init())
+
+	p.Add(power("node1", 100))
+	p.Add(power("pdu1", 110))
+
+	state, err := p.GetState()
+	require.NoError(t, err)
+	buf, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	restored := NewPowerBalance()
+	restored.Topology = p.Topology
+	require.NoError(t, restored.Init())
+	require.NoError(t, restored.SetState(buf))
+	restored.Add(power("node1", 120))
+
+	acc := testutil.Accumulator{}
+	restored.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "power_balance",
+		map[string]interface{}{
+			"parent_power":        float64(110),
+			"children_power":      float64(110),
+			"children_reporting":  1,
+			"children_expected":   1,
+			"discrepancy_percent": float64(0),
+		},
+		map[string]string{"parent": "pdu1"},
+	)
+}
@@ -0,0 +1,424 @@
+package power_sla
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const measurement = "power_sla"
+
+// monthFormat formats the month tag and the name of the report files.
+const monthFormat = "2006-01"
+
+var reportHeader = []string{
+	"rack",
+	"month",
+	"energy_kwh",
+	"peak_demand_watts",
+	"contracted_power_watts",
+	"excursions",
+	"availability_percent",
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to push the month-to-date summaries.  Unlike other
+  ## aggregators the summaries accumulate over the calendar month.
+  period = "5m"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Fields holding the power reading of a meter, the first field found on
+  ## a metric is used.
+  fields = ["instantaneous_power_reading"]
+
+  ## Tags identifying a meter, the first tag found on a metric is used.  The
+  ## ipmi_power input tags readings of remote servers with server, readings
+  ## of the local machine carry the host tag of the agent only.
+  meter_tags = ["server", "host"]
+
+  ## Tag holding the rack of a meter, metrics without it are ignored.
+  rack_tag = "rack"
+
+  ## Expected interval between the readings of a meter.  The readings of the
+  ## meters of a rack are summed per interval to get its demand, the
+  ## availability is the share of intervals with readings.
+  sample_interval = "30s"
+
+  ## Time zone the calendar months are in, "Local" for the system time zone.
+  ## Also aligns the periods to local midnight.
+  timezone = "UTC"
+
+  ## Directory the reports of completed months are written to as CSV files
+  ## named power_sla_<year>-<month>.csv, empty to not write reports.
+  # report_directory = "/var/lib/telegraf/power_sla"
+
+  ## Contracted power draw of racks in Watts, excursions are only counted
+  ## for listed racks.
+  [aggregators.power_sla.contracted_power]
+    # "rack-a1" = 8000.0
+`
+
+// PowerSLA summarizes the power drawn by racks over calendar months.
+type PowerSLA struct {
+	Fields          []string           `toml:"fields"`
+	MeterTags       []string           `toml:"meter_tags"`
+	RackTag         string             `toml:"rack_tag"`
+	SampleInterval  internal.Duration  `toml:"sample_interval"`
+	Timezone        string             `toml:"timezone"`
+	ReportDirectory string             `toml:"report_directory"`
+	ContractedPower map[string]float64 `toml:"contracted_power"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	location *time.Location
+	now      func() time.Time
+	state    state
+}
+
+type state struct {
+	// Racks holds the usage of the racks in their current month.
+	Racks map[string]*usage `json:"racks"`
+	// Completed holds the usage of completed months not pushed yet.
+	Completed []*usage `json:"completed"`
+}
+
+// usage is the power drawn by a rack during a month.
+type usage struct {
+	Rack       string    `json:"rack"`
+	Month      time.Time `json:"month"`
+	EnergyWh   float64   `json:"energy_wh"`
+	PeakWatts  float64   `json:"peak_watts"`
+	Intervals  int64     `json:"intervals"`
+	Excursions int64     `json:"excursions"`
+	Above      bool      `json:"above"`
+
+	// Interval and Readings hold the readings of the meters during the
+	// current sample interval, counted once it ends.
+	Interval int64              `json:"interval"`
+	Readings map[string]float64 `json:"readings"`
+}
+
+// NewPowerSLA creates a PowerSLA aggregator with the default settings.
+func NewPowerSLA() *PowerSLA {
+	p := &PowerSLA{
+		Fields:         []string{"instantaneous_power_reading"},
+		MeterTags:      []string{"server", "host"},
+		RackTag:        "rack",
+		SampleInterval: internal.Duration{Duration: 30 * time.Second},
+		Timezone:       "UTC",
+		now:            time.Now,
+	}
+	p.state.Racks = make(map[string]*usage)
+	return p
+}
+
+func (p *PowerSLA) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PowerSLA) Description() string {
+	return "Summarize the monthly energy, peak demand and availability of rack power readings."
+}
+
+func (p *PowerSLA) Init() error {
+	if p.SampleInterval.Duration <= 0 {
+		return fmt.Errorf("sample_interval must be positive")
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %v", err)
+	}
+	p.location = location
+	if p.ReportDirectory != "" {
+		if err := os.MkdirAll(p.ReportDirectory, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PowerSLA) Add(in telegraf.Metric) {
+	rack, ok := in.GetTag(p.RackTag)
+	if !ok {
+		return
+	}
+	value, ok := p.reading(in)
+	if !ok {
+		return
+	}
+	meter := p.meter(in)
+
+	t := in.Time().In(p.location)
+	month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, p.location)
+
+	u, ok := p.state.Racks[rack]
+	if ok && month.Before(u.Month) {
+		// Late reading of a completed month
+		return
+	}
+	if ok && month.After(u.Month) {
+		p.complete(u)
+		ok = false
+	}
+	if !ok {
+		u = &usage{Rack: rack, Month: month}
+		p.state.Racks[rack] = u
+	}
+
+	interval := int64(t.Sub(month) / p.SampleInterval.Duration)
+	if len(u.Readings) > 0 && interval != u.Interval {
+		if interval < u.Interval {
+			return
+		}
+		p.closeInterval(u)
+	}
+	if u.Readings == nil {
+		u.Readings = make(map[string]float64)
+	}
+	u.Interval = interval
+	u.Readings[meter] = value
+}
+
+func (p *PowerSLA) Push(acc telegraf.Accumulator) {
+	now := p.now().In(p.location)
+
+	// Complete the months of racks which stopped reporting
+	for _, u := range p.state.Racks {
+		end := u.Month.AddDate(0, 1, 0).Add(p.SampleInterval.Duration)
+		if !now.Before(end) {
+			p.complete(u)
+		}
+	}
+
+	for _, u := range p.state.Completed {
+		acc.AddFields(measurement, p.fields(u, p.intervals(u.Month, u.Month.AddDate(0, 1, 0)), true), p.tags(u))
+	}
+	if p.ReportDirectory != "" && len(p.state.Completed) > 0 {
+		if err := p.writeReports(p.state.Completed); err != nil {
+			p.Log.Errorf("Writing reports: %v", err)
+		}
+	}
+	p.state.Completed = nil
+
+	for _, u := range p.state.Racks {
+		acc.AddFields(measurement, p.fields(u, p.intervals(u.Month, now), false), p.tags(u))
+	}
+}
+
+// Reset keeps the usage, it accumulates over the month instead of the period.
+func (p *PowerSLA) Reset() {
+}
+
+// GetState returns the usage of the current and completed months.
+func (p *PowerSLA) GetState() (interface{}, error) {
+	return p.state, nil
+}
+
+// SetState restores the usage of the current and completed months.
+func (p *PowerSLA) SetState(buf []byte) error {
+	var s state
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return err
+	}
+	if s.Racks == nil {
+		s.Racks = make(map[string]*usage)
+	}
+	p.state = s
+	return nil
+}
+
+// closeInterval accounts the readings of the current sample interval.
+func (p *PowerSLA) closeInterval(u *usage) {
+	var demand float64
+	for _, v := range u.Readings {
+		demand += v
+	}
+	u.Readings = nil
+
+	u.EnergyWh += demand * p.SampleInterval.Duration.Hours()
+	if u.Intervals == 0 || demand > u.PeakWatts {
+		u.PeakWatts = demand
+	}
+	u.Intervals++
+
+	if contracted, ok := p.ContractedPower[u.Rack]; ok {
+		above := demand > contracted
+		if above && !u.Above {
+			u.Excursions++
+		}
+		u.Above = above
+	}
+}
+
+// complete moves the usage of a rack to the completed months.
+func (p *PowerSLA) complete(u *usage) {
+	if len(u.Readings) > 0 {
+		p.closeInterval(u)
+	}
+	delete(p.state.Racks, u.Rack)
+	p.state.Completed = append(p.state.Completed, u)
+}
+
+// intervals returns the number of sample intervals between the start of the
+// month and until.
+func (p *PowerSLA) intervals(month, until time.Time) int64 {
+	if !until.After(month) {
+		return 0
+	}
+	d := p.SampleInterval.Duration
+	return int64((until.Sub(month) + d - 1) / d)
+}
+
+func (p *PowerSLA) tags(u *usage) map[string]string {
+	return map[string]string{
+		"rack":  u.Rack,
+		"month": u.Month.Format(monthFormat),
+	}
+}
+
+func (p *PowerSLA) fields(u *usage, expected int64, complete bool) map[string]interface{} {
+	if expected < u.Intervals {
+		expected = u.Intervals
+	}
+	fields := map[string]interface{}{
+		"energy_kwh":           u.EnergyWh / 1000,
+		"intervals":            u.Intervals,
+		"intervals_expected":   expected,
+		"availability_percent": availability(u.Intervals, expected),
+		"complete":             complete,
+	}
+	if u.Intervals > 0 {
+		fields["peak_demand_watts"] = u.PeakWatts
+	}
+	if contracted, ok := p.ContractedPower[u.Rack]; ok {
+		fields["contracted_power_watts"] = contracted
+		fields["excursions"] = u.Excursions
+	}
+	return fields
+}
+
+func availability(intervals, expected int64) float64 {
+	if expected == 0 {
+		return 0
+	}
+	return float64(intervals) / float64(expected) * 100
+}
+
+// writeReports appends the usage of completed months to the report of their
+// month, sorted by rack.
+func (p *PowerSLA) writeReports(completed []*usage) error {
+	byMonth := make(map[string][]*usage)
+	for _, u := range completed {
+		month := u.Month.Format(monthFormat)
+		byMonth[month] = append(byMonth[month], u)
+	}
+
+	for month, usages := range byMonth {
+		sort.Slice(usages, func(i, j int) bool { return usages[i].Rack < usages[j].Rack })
+		if err := p.writeReport(month, usages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PowerSLA) writeReport(month string, usages []*usage) error {
+	path := filepath.Join(p.ReportDirectory, measurement+"_"+month+".csv")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		if err := w.Write(reportHeader); err != nil {
+			return err
+		}
+	}
+	for _, u := range usages {
+		expected := p.intervals(u.Month, u.Month.AddDate(0, 1, 0))
+		contracted, excursions := "", ""
+		if c, ok := p.ContractedPower[u.Rack]; ok {
+			contracted = formatFloat(c)
+			excursions = strconv.FormatInt(u.Excursions, 10)
+		}
+		record := []string{
+			u.Rack,
+			month,
+			formatFloat(u.EnergyWh / 1000),
+			formatFloat(u.PeakWatts),
+			contracted,
+			excursions,
+			formatFloat(availability(u.Intervals, expected)),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// meter returns the identifier of the meter the metric was measured by.
+func (p *PowerSLA) meter(in telegraf.Metric) string {
+	for _, tag := range p.MeterTags {
+		if v, ok := in.GetTag(tag); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// reading returns the power reading of the metric.
+func (p *PowerSLA) reading(in telegraf.Metric) (float64, bool) {
+	for _, field := range p.Fields {
+		if v, ok := in.GetField(field); ok {
+			return convert(v)
+		}
+	}
+	return 0, false
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("power_sla", func() telegraf.Aggregator {
+		return NewPowerSLA()
+	})
+}
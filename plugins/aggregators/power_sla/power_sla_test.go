@@ -0,0 +1,221 @@
+package power_sla
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var monthStart = time.Date(2020, time.December, 1, 0, 0, 0, 0, time.UTC)
+
+func power(rack, server string, watts float64, t time.Time) telegraf.Metric {
+	return testutil.MustMetric(
+		"ipmi_power",
+		map[string]string{"rack": rack, "server": server},
+		map[string]interface{}{"instantaneous_power_reading": watts},
+		t,
+	)
+}
+
+func newPowerSLA(t *testing.T, now time.Time) *PowerSLA {
+	p := NewPowerSLA()
+	p.SampleInterval.Duration = time.Hour
+	p.ContractedPower = map[string]float64{"rack1": 1000}
+	p.Log = testutil.Logger{}
+	p.now = func() time.Time { return now }
+	require.NoError(t, p.Init())
+	return p
+}
+
+func TestPowerSLAMonthToDate(t *testing.T) {
+	p := newPowerSLA(t, monthStart.Add(4*time.Hour))
+
+	// Readings of two meters are summed per interval, the last reading of
+	// a meter within an interval counts
+	p.Add(power("rack1", "node1", 400, monthStart))
+	p.Add(power("rack1", "node2", 400, monthStart.Add(time.Minute)))
+	p.Add(power("rack1", "node1", 500, monthStart.Add(time.Hour)))
+	p.Add(power("rack1", "node1", 700, monthStart.Add(time.Hour+time.Minute)))
+	p.Add(power("rack1", "node2", 500, monthStart.Add(time.Hour+2*time.Minute)))
+	// No readings in the third interval
+	p.Add(power("rack1", "node1", 300, monthStart.Add(3*time.Hour)))
+	p.Add(power("rack2", "node3", 200, monthStart.Add(3*time.Hour)))
+
+	acc := testutil.Accumulator{}
+	p.Push(&acc)
+
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "power_sla",
+		map[string]interface{}{
+			"energy_kwh":             2.0,
+			"peak_demand_watts":      1200.0,
+			"intervals":              int64(2),
+			"intervals_expected":     int64(4),
+			"availability_percent":   50.0,
+			"contracted_power_watts": 1000.0,
+			"excursions":             int64(1),
+			"complete":               false,
+		},
+		map[string]string{"rack": "rack1", "month": "2020-12"},
+	)
+	acc.AssertContainsTaggedFields(t, "power_sla",
+		map[string]interface{}{
+			"energy_kwh":           0.0,
+			"intervals":            int64(0),
+			"intervals_expected":   int64(4),
+			"availability_percent": 0.0,
+			"complete":             false,
+		},
+		map[string]string{"rack": "rack2", "month": "2020-12"},
+	)
+
+	// Summaries accumulate across periods
+	p.Reset()
+	acc.ClearMetrics()
+	p.Push(&acc)
+	require.Len(t, acc.Metrics, 2)
+}
+
+func TestPowerSLAExcursions(t *testing.T) {
+	p := newPowerSLA(t, monthStart.Add(6*time.Hour))
+
+	for i, watts := range []float64{900, 1100, 1200, 800, 1100, 900} {
+		p.Add(power("rack1", "node1", watts, monthStart.Add(time.Duration(i)*time.Hour)))
+	}
+	// Closes the last interval
+	p.Add(power("rack1", "node1", 900, monthStart.Add(6*time.Hour)))
+
+	acc := testutil.Accumulator{}
+	p.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "power_sla",
+		map[string]interface{}{
+			"energy_kwh":             6.0,
+			"peak_demand_watts":      1200.0,
+			"intervals":              int64(6),
+			"intervals_expected":     int64(6),
+			"availability_percent":   100.0,
+			"contracted_power_watts": 1000.0,
+			"excursions":             int64(2),
+			"complete":               false,
+		},
+		map[string]string{"rack": "rack1", "month": "2020-12"},
+	)
+}
+
+func TestPowerSLACompleteMonth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "power_sla")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	next := monthStart.AddDate(0, 1, 0)
+	p := newPowerSLA(t, next.Add(time.Hour))
+	p.ReportDirectory = dir
+
+	p.Add(power("rack1", "node1", 1500, monthStart))
+	p.Add(power("rack2", "node2", 500, monthStart))
+	// A reading of the next month completes the month of rack1, rack2
+	// stopped reporting
+	p.Add(power("rack1", "node1", 500, next))
+	// Late readings of completed months are dropped
+	p.Add(power("rack1", "node1", 500, next.Add(-time.Hour)))
+
+	acc := testutil.Accumulator{}
+	p.Push(&acc)
+
+	// One of the 744 hours of December has readings
+	oneInterval := float64(1) / 744 * 100
+	require.Len(t, acc.Metrics, 3)
+	acc.AssertContainsTaggedFields(t, "power_sla",
+		map[string]interface{}{
+			"energy_kwh":             1.5,
+			"peak_demand_watts":      1500.0,
+			"intervals":              int64(1),
+			"intervals_expected":     int64(744),
+			"availability_percent":   oneInterval,
+			"contracted_power_watts": 1000.0,
+			"excursions":             int64(1),
+			"complete":               true,
+		},
+		map[string]string{"rack": "rack1", "month": "2020-12"},
+	)
+	acc.AssertContainsTaggedFields(t, "power_sla",
+		map[string]interface{}{
+			"energy_kwh":           0.5,
+			"peak_demand_watts":    500.0,
+			"intervals":            int64(1),
+			"intervals_expected":   int64(744),
+			"availability_percent": oneInterval,
+			"complete":             true,
+		},
+		map[string]string{"rack": "rack2", "month": "2020-12"},
+	)
+	acc.AssertContainsTaggedFields(t, "power_sla",
+		map[string]interface{}{
+			"energy_kwh":             0.0,
+			"intervals":              int64(0),
+			"intervals_expected":     int64(1),
+			"availability_percent":   0.0,
+			"contracted_power_watts": 1000.0,
+			"excursions":             int64(0),
+			"complete":               false,
+		},
+		map[string]string{"rack": "rack1", "month": "2021-01"},
+	)
+
+	report, err := ioutil.ReadFile(filepath.Join(dir, "power_sla_2020-12.csv"))
+	require.NoError(t, err)
+	require.Equal(t,
+		"rack,month,energy_kwh,peak_demand_watts,contracted_power_watts,excursions,availability_percent\n"+
+			"rack1,2020-12,1.5,1500,1000,1,0.13440860215053765\n"+
+			"rack2,2020-12,0.5,500,,,0.13440860215053765\n",
+		string(report))
+
+	// Completed months are pushed once
+	acc.ClearMetrics()
+	p.Push(&acc)
+	require.Len(t, acc.Metrics, 1)
+}
+
+func TestPowerSLAState(t *testing.T) {
+	p := newPowerSLA(t, monthStart.Add(2*time.Hour))
+	p.Add(power("rack1", "node1", 1000, monthStart))
+	p.Add(power("rack1", "node1", 500, monthStart.Add(time.Hour)))
+
+	state, err := p.GetState()
+	require.NoError(t, err)
+	buf, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	restored := newPowerSLA(t, monthStart.Add(2*time.Hour))
+	require.NoError(t, restored.SetState(buf))
+	restored.Add(power("rack1", "node1", 500, monthStart.Add(2*time.Hour)))
+
+	acc := testutil.Accumulator{}
+	restored.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "power_sla",
+		map[string]interface{}{
+			"energy_kwh":             1.5,
+			"peak_demand_watts":      1000.0,
+			"intervals":              int64(2),
+			"intervals_expected":     int64(2),
+			"availability_percent":   100.0,
+			"contracted_power_watts": 1000.0,
+			"excursions":             int64(0),
+			"complete":               false,
+		},
+		map[string]string{"rack": "rack1", "month": "2020-12"},
+	)
+}
+
+func TestPowerSLAInvalidTimezone(t *testing.T) {
+	p := NewPowerSLA()
+	p.Timezone = "Mars/Olympus_Mons"
+	require.Error(t, p.Init())
+}
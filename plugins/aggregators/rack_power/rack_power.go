@@ -0,0 +1,222 @@
+package rack_power
+
+import (
+	"encoding/json"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const measurement = "rack_power"
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Fields holding the power reading of a meter, the first field found on
+  ## a metric is used.
+  fields = ["instantaneous_power_reading", "power_watts"]
+
+  ## Tag holding the rack of a meter, metrics without it are ignored.
+  rack_tag = "rack"
+
+  ## Tag holding the row of a meter, empty to not emit row totals.  Racks
+  ## without the tag are left out of the row totals.
+  row_tag = "row"
+
+  ## Sum meters of different measurements separately, tagged with the
+  ## measurement as source.  Keeps outlet and node readings of the same
+  ## rack from being added up when both are collected.
+  # separate_sources = true
+`
+
+// RackPower sums the power of the meters of racks and rows.
+type RackPower struct {
+	Fields          []string `toml:"fields"`
+	RackTag         string   `toml:"rack_tag"`
+	RowTag          string   `toml:"row_tag"`
+	SeparateSources bool     `toml:"separate_sources"`
+
+	meters map[uint64]*meter
+}
+
+// meter holds the readings of a series within the period.
+type meter struct {
+	Rack   string  `json:"rack"`
+	Row    string  `json:"row"`
+	Source string  `json:"source"`
+	Sum    float64 `json:"sum"`
+	Count  int     `json:"count"`
+}
+
+// total is the power of the meters of a rack or row.
+type total struct {
+	row    string
+	power  float64
+	meters int
+	racks  map[string]bool
+}
+
+type totalKey struct {
+	name   string
+	source string
+}
+
+// NewRackPower creates a RackPower aggregator with the default settings.
+func NewRackPower() *RackPower {
+	r := &RackPower{
+		Fields:          []string{"instantaneous_power_reading", "power_watts"},
+		RackTag:         "rack",
+		RowTag:          "row",
+		SeparateSources: true,
+	}
+	r.Reset()
+	return r
+}
+
+func (r *RackPower) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RackPower) Description() string {
+	return "Sum the power of the meters of racks and rows using topology tags."
+}
+
+func (r *RackPower) Add(in telegraf.Metric) {
+	rack, ok := in.GetTag(r.RackTag)
+	if !ok || rack == "" {
+		return
+	}
+	v, ok := r.reading(in)
+	if !ok {
+		return
+	}
+
+	id := in.HashID()
+	m, ok := r.meters[id]
+	if !ok {
+		m = &meter{Rack: rack}
+		if r.RowTag != "" {
+			m.Row, _ = in.GetTag(r.RowTag)
+		}
+		if r.SeparateSources {
+			m.Source = in.Name()
+		}
+		r.meters[id] = m
+	}
+	m.Sum += v
+	m.Count++
+}
+
+func (r *RackPower) Push(acc telegraf.Accumulator) {
+	racks := make(map[totalKey]*total)
+	rows := make(map[totalKey]*total)
+	for _, m := range r.meters {
+		power := m.Sum / float64(m.Count)
+
+		key := totalKey{name: m.Rack, source: m.Source}
+		t, ok := racks[key]
+		if !ok {
+			t = &total{}
+			racks[key] = t
+		}
+		// Keep the row of the rack even if some of its meters lack the tag
+		if t.row == "" {
+			t.row = m.Row
+		}
+		t.power += power
+		t.meters++
+
+		if m.Row == "" {
+			continue
+		}
+		key = totalKey{name: m.Row, source: m.Source}
+		t, ok = rows[key]
+		if !ok {
+			t = &total{racks: make(map[string]bool)}
+			rows[key] = t
+		}
+		t.power += power
+		t.meters++
+		t.racks[m.Rack] = true
+	}
+
+	for key, t := range racks {
+		tags := map[string]string{"level": "rack", r.RackTag: key.name}
+		if t.row != "" {
+			tags[r.RowTag] = t.row
+		}
+		if key.source != "" {
+			tags["source"] = key.source
+		}
+		acc.AddFields(measurement, map[string]interface{}{
+			"power_watts": t.power,
+			"meters":      t.meters,
+		}, tags)
+	}
+	for key, t := range rows {
+		tags := map[string]string{"level": "row", r.RowTag: key.name}
+		if key.source != "" {
+			tags["source"] = key.source
+		}
+		acc.AddFields(measurement, map[string]interface{}{
+			"power_watts": t.power,
+			"meters":      t.meters,
+			"racks":       len(t.racks),
+		}, tags)
+	}
+}
+
+func (r *RackPower) Reset() {
+	r.meters = make(map[uint64]*meter)
+}
+
+// GetState returns the readings of the current window.
+func (r *RackPower) GetState() (interface{}, error) {
+	return r.meters, nil
+}
+
+// SetState restores the readings of the current window.
+func (r *RackPower) SetState(state []byte) error {
+	meters := make(map[uint64]*meter)
+	if err := json.Unmarshal(state, &meters); err != nil {
+		return err
+	}
+	r.meters = meters
+	return nil
+}
+
+// reading returns the power reading of the metric.
+func (r *RackPower) reading(in telegraf.Metric) (float64, bool) {
+	for _, field := range r.Fields {
+		v, ok := in.GetField(field)
+		if !ok {
+			continue
+		}
+		return convert(v)
+	}
+	return 0, false
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("rack_power", func() telegraf.Aggregator {
+		return NewRackPower()
+	})
+}
@@ -0,0 +1,162 @@
+package rack_power
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func node(server, rack, row string, watts float64) telegraf.Metric {
+	tags := map[string]string{"server": server, "rack": rack}
+	if row != "" {
+		tags["row"] = row
+	}
+	return testutil.MustMetric(
+		"ipmi_power",
+		tags,
+		map[string]interface{}{"instantaneous_power_reading": watts},
+		time.Unix(0, 0),
+	)
+}
+
+func outlet(pdu, outlet, rack, row string, watts int64) telegraf.Metric {
+	return testutil.MustMetric(
+		"pdu_outlet",
+		map[string]string{"agent_host": pdu, "outlet": outlet, "rack": rack, "row": row},
+		map[string]interface{}{"power_watts": watts},
+		time.Unix(0, 0),
+	)
+}
+
+func TestRackPower(t *testing.T) {
+	r := NewRackPower()
+
+	r.Add(node("node1", "r1", "A", 100))
+	r.Add(node("node1", "r1", "A", 200))
+	r.Add(node("node2", "r1", "A", 250))
+	r.Add(node("node3", "r2", "A", 300))
+	r.Add(node("node4", "r3", "B", 50))
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+	require.Len(t, acc.Metrics, 5)
+
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(400), "meters": 2},
+		map[string]string{"level": "rack", "rack": "r1", "row": "A", "source": "ipmi_power"},
+	)
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(300), "meters": 1},
+		map[string]string{"level": "rack", "rack": "r2", "row": "A", "source": "ipmi_power"},
+	)
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(700), "meters": 3, "racks": 2},
+		map[string]string{"level": "row", "row": "A", "source": "ipmi_power"},
+	)
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(50), "meters": 1, "racks": 1},
+		map[string]string{"level": "row", "row": "B", "source": "ipmi_power"},
+	)
+}
+
+func TestRackPowerSeparateSources(t *testing.T) {
+	r := NewRackPower()
+
+	r.Add(node("node1", "r1", "A", 180))
+	r.Add(outlet("pdu1", "1", "r1", "A", 190))
+	r.Add(outlet("pdu1", "2", "r1", "A", 10))
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(180), "meters": 1},
+		map[string]string{"level": "rack", "rack": "r1", "row": "A", "source": "ipmi_power"},
+	)
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(200), "meters": 2},
+		map[string]string{"level": "rack", "rack": "r1", "row": "A", "source": "pdu_outlet"},
+	)
+}
+
+func TestRackPowerCombinedSources(t *testing.T) {
+	r := NewRackPower()
+	r.SeparateSources = false
+	r.RowTag = ""
+
+	r.Add(node("node1", "r1", "A", 180))
+	r.Add(outlet("pdu1", "3", "r1", "A", 20))
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(200), "meters": 2},
+		map[string]string{"level": "rack", "rack": "r1"},
+	)
+}
+
+func TestRackPowerIgnoresMetricsWithoutRack(t *testing.T) {
+	r := NewRackPower()
+
+	r.Add(testutil.MustMetric(
+		"ipmi_power",
+		map[string]string{"server": "node1"},
+		map[string]interface{}{"instantaneous_power_reading": 100.0},
+		time.Unix(0, 0),
+	))
+	r.Add(testutil.MustMetric(
+		"ipmi_power",
+		map[string]string{"server": "node1", "rack": "r1"},
+		map[string]interface{}{"instantaneous_power_reading_unit": "Watts"},
+		time.Unix(0, 0),
+	))
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+	require.Empty(t, acc.Metrics)
+}
+
+func TestRackPowerReset(t *testing.T) {
+	r := NewRackPower()
+
+	r.Add(node("node1", "r1", "A", 100))
+	r.Reset()
+	r.Add(node("node1", "r1", "A", 300))
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(300), "meters": 1},
+		map[string]string{"level": "rack", "rack": "r1", "row": "A", "source": "ipmi_power"},
+	)
+}
+
+func TestRackPowerState(t *testing.T) {
+	r := NewRackPower()
+	r.Add(node("node1", "r1", "A", 100))
+	r.Add(node("node2", "r1", "A", 150))
+
+	state, err := r.GetState()
+	require.NoError(t, err)
+	buf, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	restored := NewRackPower()
+	require.NoError(t, restored.SetState(buf))
+	restored.Add(node("node1", "r1", "A", 300))
+
+	acc := testutil.Accumulator{}
+	restored.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "rack_power",
+		map[string]interface{}{"power_watts": float64(350), "meters": 2},
+		map[string]string{"level": "rack", "rack": "r1", "row": "A", "source": "ipmi_power"},
+	)
+}
@@ -0,0 +1,52 @@
+package httpconfig
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/oauth"
+	"github.com/influxdata/telegraf/plugins/common/proxy"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// HTTPClientConfig is the common configuration of HTTP clients, including
+// TLS client certificates and OAuth2 client credentials.
+type HTTPClientConfig struct {
+	Timeout         internal.Duration `toml:"timeout"`
+	IdleConnTimeout internal.Duration `toml:"idle_conn_timeout"`
+
+	proxy.HTTPProxy
+	tls.ClientConfig
+	oauth.OAuth2Config
+}
+
+// CreateClient returns an HTTP client according to the configuration.
+func (h *HTTPClientConfig) CreateClient(ctx context.Context) (*http.Client, error) {
+	tlsCfg, err := h.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	prox, err := h.HTTPProxy.Proxy()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := h.Timeout.Duration
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           prox,
+			IdleConnTimeout: h.IdleConnTimeout.Duration,
+		},
+		Timeout: timeout,
+	}
+
+	return h.OAuth2Config.CreateOauth2Client(ctx, client), nil
+}
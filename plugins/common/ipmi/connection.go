@@ -0,0 +1,256 @@
+// Package ipmi holds the connection handling shared by the IPMI plugins, such
+// as parsing the server addresses and loading the credentials, and the
+// running of ipmitool and decoding of its raw responses.
+package ipmi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// defaultPort is the RMCP port used by IPMI over LAN
+const defaultPort = 623
+
+// Interfaces are the ipmitool interfaces accepted by the interface options,
+// serial is short for serial-terminal.
+var Interfaces = []string{"lan", "lanplus", "open", "serial", "serial-terminal", "serial-basic"}
+
+// Connection properties for a Client
+type Connection struct {
+	Hostname  string
+	Username  string
+	Password  string
+	Port      int
+	Interface string
+	Privilege string
+
+	// PasswordFile is read on every query when set, overriding Password
+	PasswordFile string
+}
+
+func NewConnection(server string, privilege string) *Connection {
+	conn := &Connection{}
+	conn.Privilege = privilege
+
+	server, params := SplitParams(server)
+	conn.PasswordFile = params.Get("password_file")
+
+	inx1 := strings.LastIndex(server, "@")
+	inx2 := strings.Index(server, "(")
+
+	connstr := server
+
+	if inx1 > 0 {
+		security := server[0:inx1]
+		connstr = server[inx1+1:]
+		up := strings.SplitN(security, ":", 2)
+		conn.Username = up[0]
+		if len(up) == 2 {
+			conn.Password = up[1]
+		}
+	}
+
+	if inx2 > 0 {
+		inx2 = strings.Index(connstr, "(")
+		inx3 := strings.Index(connstr, ")")
+
+		conn.Interface = connstr[0:inx2]
+		conn.Hostname, conn.Port = SplitHostPort(connstr[inx2+1 : inx3])
+	}
+
+	if intf := params.Get("interface"); intf != "" {
+		conn.Interface = intf
+	}
+
+	return conn
+}
+
+// NewConnectionInterface returns the connection to the server using the
+// interface set by the interface option, unless the server names its own
+// with an interface parameter.  An empty option keeps the protocol.
+func NewConnectionInterface(server string, privilege string, intf string) *Connection {
+	conn := NewConnection(server, privilege)
+	if _, params := SplitParams(server); params.Get("interface") == "" && intf != "" {
+		conn.Interface = intf
+	}
+	return conn
+}
+
+// CheckInterface verifies that the interface set by the option is supported
+// by the plugins, an empty interface leaves the choice to the protocol or
+// ipmitool.
+func CheckInterface(option string, intf string) error {
+	if intf == "" {
+		return nil
+	}
+	for _, i := range Interfaces {
+		if intf == i {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s %q, must be one of %s", option, intf, strings.Join(Interfaces, ", "))
+}
+
+// CheckInterfaces verifies the interface option and the interface each of the
+// servers is queried with.
+func CheckInterfaces(servers []string, intf string) error {
+	if err := CheckInterface("interface", intf); err != nil {
+		return err
+	}
+	for _, server := range servers {
+		conn := NewConnectionInterface(server, "", intf)
+		if err := CheckInterface("interface", conn.Interface); err != nil {
+			return fmt.Errorf("server %s: %v", conn.Hostname, err)
+		}
+	}
+	return nil
+}
+
+// IpmitoolInterface returns the name ipmitool knows the interface by.
+func IpmitoolInterface(intf string) string {
+	if intf == "serial" {
+		return "serial-terminal"
+	}
+	return intf
+}
+
+// IsSerial returns true for the serial interfaces, which connect to the BMC
+// through a serial device instead of a host.
+func IsSerial(intf string) bool {
+	return strings.HasPrefix(intf, "serial")
+}
+
+// SplitHostPort splits an address of the form host, host:port, [ipv6] or
+// [ipv6]:port. Bare IPv6 literals without brackets are returned as is.
+func SplitHostPort(addr string) (string, int) {
+	if strings.HasPrefix(addr, "[") {
+		end := strings.Index(addr, "]")
+		if end < 0 {
+			return addr, 0
+		}
+		host := addr[1:end]
+		if p := strings.TrimPrefix(addr[end+1:], ":"); p != "" {
+			if port, err := strconv.Atoi(p); err == nil {
+				return host, port
+			}
+		}
+		return host, 0
+	}
+
+	if strings.Count(addr, ":") == 1 {
+		inx := strings.Index(addr, ":")
+		if port, err := strconv.Atoi(addr[inx+1:]); err == nil {
+			return addr[:inx], port
+		}
+	}
+	return addr, 0
+}
+
+// SplitParams separates the query parameters trailing the server address,
+// e.g. "?password_file=/run/secrets/bmc", from the server string.
+func SplitParams(server string) (string, url.Values) {
+	start := strings.LastIndex(server, ")")
+	if start < 0 {
+		start = strings.LastIndex(server, "@")
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	inx := strings.Index(server[start:], "?")
+	if inx < 0 {
+		return server, url.Values{}
+	}
+	inx += start
+
+	params, err := url.ParseQuery(server[inx+1:])
+	if err != nil {
+		return server, url.Values{}
+	}
+	return server[:inx], params
+}
+
+// LoadPassword reads the password from the password file, if any.
+func (t *Connection) LoadPassword() error {
+	if t.PasswordFile == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(t.PasswordFile)
+	if err != nil {
+		return fmt.Errorf("reading password file for %s: %v", t.Hostname, err)
+	}
+	t.Password = strings.TrimRight(string(b), "\r\n")
+	return nil
+}
+
+// Options returns the ipmitool options connecting to the BMC.  The serial
+// interfaces use the host of the server as device, with the optional baud
+// rate as port, e.g. serial(/dev/ttyS0:115200).
+func (t *Connection) Options() []string {
+	intf := IpmitoolInterface(t.Interface)
+	if intf == "" {
+		intf = "lan"
+	}
+
+	if IsSerial(intf) {
+		device := t.Hostname
+		if t.Port != 0 {
+			device += ":" + strconv.Itoa(t.Port)
+		}
+		options := []string{"-D", device, "-I", intf}
+		if t.Username != "" {
+			options = append(options, "-U", t.Username, "-P", t.Password)
+		}
+		if t.Privilege != "" {
+			options = append(options, "-L", t.Privilege)
+		}
+		return options
+	}
+
+	options := []string{
+		"-H", t.Hostname,
+		"-U", t.Username,
+		"-P", t.Password,
+		"-I", intf,
+	}
+
+	if t.Port != 0 {
+		options = append(options, "-p", strconv.Itoa(t.Port))
+	}
+	if t.Privilege != "" {
+		options = append(options, "-L", t.Privilege)
+	}
+	return options
+}
+
+// RemoteIP returns the remote (bmc) IP address of the Connection
+func (c *Connection) RemoteIP() string {
+	if net.ParseIP(c.Hostname) == nil {
+		addrs, err := net.LookupHost(c.Hostname)
+		if err == nil && len(addrs) > 0 {
+			return addrs[0]
+		}
+	}
+	return c.Hostname
+}
+
+// LocalIP returns the local (client) IP address of the Connection
+func (c *Connection) LocalIP() string {
+	port := c.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(c.Hostname, strconv.Itoa(port)))
+	if err != nil {
+		// don't bother returning an error, since this value will never
+		// make it to the bmc if we can't connect to it.
+		return c.Hostname
+	}
+	_ = conn.Close()
+	host, _, _ := net.SplitHostPort(conn.LocalAddr().String())
+	return host
+}
@@ -0,0 +1,248 @@
+package ipmi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type conTest struct {
+	Got  string
+	Want *Connection
+}
+
+func TestNewConnection(t *testing.T) {
+	testData := []struct {
+		addr string
+		con  *Connection
+	}{
+		{
+			"USERID:PASSW0RD@lan(192.168.1.1)",
+			&Connection{
+				Hostname:  "192.168.1.1",
+				Username:  "USERID",
+				Password:  "PASSW0RD",
+				Interface: "lan",
+				Privilege: "USER",
+			},
+		},
+		{
+			"USERID:PASS:!@#$%^&*(234)_+W0RD@lan(192.168.1.1)",
+			&Connection{
+				Hostname:  "192.168.1.1",
+				Username:  "USERID",
+				Password:  "PASS:!@#$%^&*(234)_+W0RD",
+				Interface: "lan",
+				Privilege: "USER",
+			},
+		},
+	}
+
+	for _, v := range testData {
+		assert.Equal(t, v.con, NewConnection(v.addr, "USER"))
+	}
+}
+
+func TestNewConnectionAddress(t *testing.T) {
+	testData := []struct {
+		addr string
+		con  *Connection
+	}{
+		{
+			"USERID:PASSW0RD@lan(192.168.1.1:1623)",
+			&Connection{
+				Hostname:  "192.168.1.1",
+				Username:  "USERID",
+				Password:  "PASSW0RD",
+				Port:      1623,
+				Interface: "lan",
+			},
+		},
+		{
+			"USERID:PASSW0RD@lanplus([fd00::1]:623)",
+			&Connection{
+				Hostname:  "fd00::1",
+				Username:  "USERID",
+				Password:  "PASSW0RD",
+				Port:      623,
+				Interface: "lanplus",
+			},
+		},
+		{
+			"USERID:PASSW0RD@lan([fd00::1])",
+			&Connection{
+				Hostname:  "fd00::1",
+				Username:  "USERID",
+				Password:  "PASSW0RD",
+				Interface: "lan",
+			},
+		},
+		{
+			"USERID:PASSW0RD@lan(fd00::1)",
+			&Connection{
+				Hostname:  "fd00::1",
+				Username:  "USERID",
+				Password:  "PASSW0RD",
+				Interface: "lan",
+			},
+		},
+		{
+			"USERID:PASSW0RD@lan(bmc42.example.com:623)?password_file=/run/secrets/bmc42",
+			&Connection{
+				Hostname:     "bmc42.example.com",
+				Username:     "USERID",
+				Password:     "PASSW0RD",
+				Port:         623,
+				Interface:    "lan",
+				PasswordFile: "/run/secrets/bmc42",
+			},
+		},
+	}
+
+	for _, v := range testData {
+		assert.Equal(t, v.con, NewConnection(v.addr, ""))
+	}
+}
+
+func TestNewConnectionInterface(t *testing.T) {
+	conn := NewConnection("USERID:PASSW0RD@lan(192.168.1.1)?interface=lanplus", "")
+	require.Equal(t, "lanplus", conn.Interface)
+
+	conn = NewConnection("USERID:PASSW0RD@(192.168.1.1)", "")
+	require.Equal(t, "", conn.Interface)
+	require.Equal(t, "192.168.1.1", conn.Hostname)
+	require.Contains(t, strings.Join(conn.Options(), " "), "-I lan")
+}
+
+func TestConnectionOptions(t *testing.T) {
+	conn := NewConnection("USERID:PASSW0RD@lanplus([fd00::1]:1623)", "USER")
+	require.Equal(t, []string{
+		"-H", "fd00::1",
+		"-U", "USERID",
+		"-P", "PASSW0RD",
+		"-I", "lanplus",
+		"-p", "1623",
+		"-L", "USER",
+	}, conn.Options())
+}
+
+func TestNewConnectionParams(t *testing.T) {
+	// ${VAR} references are expanded by the configuration loader, values of
+	// expanded variables are not expanded again.
+	os.Setenv("IPMI_TEST_USER", "admin")
+	defer os.Unsetenv("IPMI_TEST_USER")
+
+	testData := []struct {
+		addr string
+		con  *Connection
+	}{
+		{
+			"root:@lan(10.0.0.1)?password_file=/run/secrets/bmc42",
+			&Connection{
+				Hostname:     "10.0.0.1",
+				Username:     "root",
+				Interface:    "lan",
+				Privilege:    "USER",
+				PasswordFile: "/run/secrets/bmc42",
+			},
+		},
+		{
+			"root@lan(10.0.0.1)?password_file=/run/secrets/bmc42",
+			&Connection{
+				Hostname:     "10.0.0.1",
+				Username:     "root",
+				Interface:    "lan",
+				Privilege:    "USER",
+				PasswordFile: "/run/secrets/bmc42",
+			},
+		},
+		{
+			"root:pa${IPMI_TEST_USER}ss@lan(10.0.0.1)",
+			&Connection{
+				Hostname:  "10.0.0.1",
+				Username:  "root",
+				Password:  "pa${IPMI_TEST_USER}ss",
+				Interface: "lan",
+				Privilege: "USER",
+			},
+		},
+	}
+
+	for _, v := range testData {
+		assert.Equal(t, v.con, NewConnection(v.addr, "USER"))
+	}
+}
+
+func TestLoadPassword(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipmi")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bmc42")
+	require.NoError(t, ioutil.WriteFile(path, []byte("rotated\n"), 0600))
+
+	conn := NewConnection("root:@lan(10.0.0.1)?password_file="+path, "")
+	require.NoError(t, conn.LoadPassword())
+	require.Equal(t, "rotated", conn.Password)
+
+	conn = NewConnection("root:@lan(10.0.0.1)?password_file="+filepath.Join(dir, "missing"), "")
+	require.Error(t, conn.LoadPassword())
+}
+
+func TestNewConnectionInterfaceOption(t *testing.T) {
+	tests := []struct {
+		server   string
+		intf     string
+		expected string
+	}{
+		{"USERID:PASSW0RD@lanplus(192.168.1.1)", "", "lanplus"},
+		{"USERID:PASSW0RD@(192.168.1.1)", "", ""},
+		{"USERID:PASSW0RD@lan(192.168.1.1)", "lanplus", "lanplus"},
+		{"USERID:PASSW0RD@(192.168.1.1)", "lanplus", "lanplus"},
+		{"USERID:PASSW0RD@lan(192.168.1.1)?interface=lan", "lanplus", "lan"},
+	}
+	for _, tt := range tests {
+		conn := NewConnectionInterface(tt.server, "", tt.intf)
+		require.Equal(t, tt.expected, conn.Interface, tt.server)
+		require.Equal(t, "192.168.1.1", conn.Hostname)
+	}
+}
+
+func TestCheckInterfaces(t *testing.T) {
+	require.NoError(t, CheckInterfaces(nil, ""))
+	require.NoError(t, CheckInterfaces([]string{"USERID:PASSW0RD@(192.168.1.1)"}, "open"))
+	require.NoError(t, CheckInterfaces([]string{"USERID:PASSW0RD@lanplus(192.168.1.1)"}, ""))
+	require.NoError(t, CheckInterfaces(nil, "serial"))
+	require.NoError(t, CheckInterfaces([]string{"USERID:PASSW0RD@serial-basic(/dev/ttyS0:115200)"}, ""))
+
+	for _, tt := range []struct {
+		servers []string
+		intf    string
+	}{
+		{nil, "serial-direct"},
+		{[]string{"USERID:PASSW0RD@lanplsu(192.168.1.1)"}, ""},
+		{[]string{"USERID:PASSW0RD@lan(192.168.1.1)?interface=imb"}, "lanplus"},
+	} {
+		err := CheckInterfaces(tt.servers, tt.intf)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must be one of lan, lanplus, open, serial")
+	}
+}
+
+func TestSerialOptions(t *testing.T) {
+	conn := NewConnection("USERID:PASSW0RD@serial(/dev/ttyS0:115200)", "USER")
+	require.Equal(t, "/dev/ttyS0", conn.Hostname)
+	require.Equal(t, 115200, conn.Port)
+	require.Equal(t, []string{
+		"-D", "/dev/ttyS0:115200", "-I", "serial-terminal",
+		"-U", "USERID", "-P", "PASSW0RD", "-L", "USER",
+	}, conn.Options())
+
+	conn = NewConnectionInterface("ADMIN:@(/dev/ttyUSB0)", "", "serial-basic")
+	require.Equal(t, []string{"-D", "/dev/ttyUSB0", "-I", "serial-basic", "-U", "ADMIN", "-P", ""}, conn.Options())
+}
@@ -0,0 +1,29 @@
+package ipmi
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseRawResponse decodes the response bytes printed by "ipmitool raw".
+func ParseRawResponse(out []byte) ([]byte, error) {
+	var data []byte
+	for _, s := range strings.Fields(string(out)) {
+		b, err := strconv.ParseUint(s, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response byte %q", s)
+		}
+		data = append(data, byte(b))
+	}
+	return data, nil
+}
+
+// Linear11 decodes the PMBus LINEAR11 format, a signed 11 bit mantissa and
+// a signed 5 bit exponent.
+func Linear11(v uint16) float64 {
+	exponent := int(int16(v) >> 11)
+	mantissa := int(int16(v<<5) >> 5)
+	return float64(mantissa) * math.Pow(2, float64(exponent))
+}
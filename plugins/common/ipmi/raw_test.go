@@ -0,0 +1,25 @@
+package ipmi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRawResponse(t *testing.T) {
+	data, err := ParseRawResponse([]byte(" 57 01 00\n 0a ff\n"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x57, 0x01, 0x00, 0x0a, 0xff}, data)
+
+	_, err = ParseRawResponse([]byte("57 zz"))
+	require.Error(t, err)
+}
+
+func TestLinear11(t *testing.T) {
+	// 0x0b3f: exponent 1, mantissa 831
+	require.Equal(t, 1662.0, Linear11(0x0b3f))
+	// 0xf8d2: exponent -1, mantissa 210
+	require.Equal(t, 105.0, Linear11(0xf8d2))
+	// 0x07ff: exponent 0, mantissa -1
+	require.Equal(t, -1.0, Linear11(0x07ff))
+}
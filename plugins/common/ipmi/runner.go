@@ -0,0 +1,126 @@
+package ipmi
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// CommandPlaceholder marks the position of the ipmitool command line in
+// sudo_command.
+const CommandPlaceholder = "{command}"
+
+// DefaultSudoCommand avoids prompting the user for input of any kind.
+var DefaultSudoCommand = []string{"sudo", "-n"}
+
+// quotedPassword matches the password of a command run on a jump host
+var quotedPassword = regexp.MustCompile(`'-P' '(?:[^']|'\\'')*'`)
+
+// Runner runs ipmitool, or another BMC tool, with the path, sudo and timeout
+// options shared by the IPMI plugins.
+type Runner struct {
+	Path        string
+	UseSudo     bool
+	SudoCommand []string
+	Timeout     time.Duration
+
+	// PasswordFlag precedes the password on the command line, "-P" if empty
+	PasswordFlag string
+
+	// ExecCommand creates the command, exec.Command if nil.  Plugins pass
+	// their own function so that tests can mock the tool.
+	ExecCommand func(name string, arg ...string) *exec.Cmd
+}
+
+// CheckSudoCommand verifies that the sudo command names the privilege
+// escalation command before the ipmitool command line.
+func CheckSudoCommand(sudo []string) error {
+	if len(sudo) > 0 && sudo[0] == CommandPlaceholder {
+		return fmt.Errorf("sudo_command must start with the privilege escalation command")
+	}
+	return nil
+}
+
+// Escalate wraps the command line in the sudo command, replacing the
+// placeholder or appending it.
+func Escalate(sudo []string, name string, args []string) (string, []string) {
+	if len(sudo) == 0 {
+		sudo = DefaultSudoCommand
+	}
+	command := append([]string{name}, args...)
+
+	wrapped := make([]string, 0, len(sudo)+len(command))
+	placed := false
+	for _, arg := range sudo[1:] {
+		if arg == CommandPlaceholder {
+			wrapped = append(wrapped, command...)
+			placed = true
+			continue
+		}
+		wrapped = append(wrapped, arg)
+	}
+	if !placed {
+		wrapped = append(wrapped, command...)
+	}
+	return sudo[0], wrapped
+}
+
+// Command returns the command for the given arguments, wrapped in the sudo
+// command if configured.
+func (r *Runner) Command(args ...string) *exec.Cmd {
+	name := r.Path
+	if r.UseSudo {
+		name, args = Escalate(r.SudoCommand, name, args)
+	}
+	execCommand := r.ExecCommand
+	if execCommand == nil {
+		execCommand = exec.Command
+	}
+	return execCommand(name, args...)
+}
+
+// Run runs the command and returns its combined output, also when it fails.
+// The password is masked in the error.
+func (r *Runner) Run(args ...string) ([]byte, error) {
+	cmd := r.Command(args...)
+	out, err := internal.CombinedOutputTimeout(cmd, r.Timeout)
+	if err != nil {
+		return out, fmt.Errorf("failed to run command %s: %s - %s",
+			strings.Join(r.redact(cmd.Args), " "), err, string(out))
+	}
+	return out, nil
+}
+
+func (r *Runner) redact(args []string) []string {
+	if r.PasswordFlag == "" || r.PasswordFlag == "-P" {
+		return RedactPassword(args)
+	}
+	return redactFlag(args, r.PasswordFlag)
+}
+
+// RedactPassword returns a copy of the command line with the password
+// passed to ipmitool masked, including inside a quoted remote command line.
+func RedactPassword(args []string) []string {
+	redacted := redactFlag(args, "-P")
+	for i := range redacted {
+		redacted[i] = quotedPassword.ReplaceAllLiteralString(redacted[i], "'-P' '********'")
+	}
+	return redacted
+}
+
+// redactFlag returns a copy of the command line with the argument following
+// flag masked.
+func redactFlag(args []string, flag string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := range redacted {
+		if redacted[i] == flag && i+1 < len(redacted) {
+			redacted[i+1] = "********"
+		}
+	}
+	return redacted
+}
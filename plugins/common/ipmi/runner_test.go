@@ -0,0 +1,93 @@
+package ipmi
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		useSudo  bool
+		sudo     []string
+		expected []string
+	}{
+		{
+			name:     "no sudo",
+			expected: []string{"/usr/bin/ipmitool", "sel", "info"},
+		},
+		{
+			name:     "default",
+			useSudo:  true,
+			expected: []string{"sudo", "-n", "/usr/bin/ipmitool", "sel", "info"},
+		},
+		{
+			name:     "appended",
+			useSudo:  true,
+			sudo:     []string{"doas", "-n"},
+			expected: []string{"doas", "-n", "/usr/bin/ipmitool", "sel", "info"},
+		},
+		{
+			name:     "placeholder",
+			useSudo:  true,
+			sudo:     []string{"pbrun", "-u", "root", "{command}", "--"},
+			expected: []string{"pbrun", "-u", "root", "/usr/bin/ipmitool", "sel", "info", "--"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Runner{Path: "/usr/bin/ipmitool", UseSudo: tt.useSudo, SudoCommand: tt.sudo}
+			require.Equal(t, tt.expected, r.Command("sel", "info").Args)
+		})
+	}
+}
+
+func TestRunnerExecCommand(t *testing.T) {
+	var called []string
+	r := &Runner{
+		Path: "ipmitool",
+		ExecCommand: func(name string, arg ...string) *exec.Cmd {
+			called = append([]string{name}, arg...)
+			return exec.Command(name, arg...)
+		},
+	}
+	r.Command("mc", "info")
+	require.Equal(t, []string{"ipmitool", "mc", "info"}, called)
+}
+
+func TestCheckSudoCommand(t *testing.T) {
+	require.NoError(t, CheckSudoCommand(nil))
+	require.NoError(t, CheckSudoCommand([]string{"pbrun", "{command}"}))
+	require.Error(t, CheckSudoCommand([]string{"{command}"}))
+}
+
+func TestRunnerRedactsError(t *testing.T) {
+	r := &Runner{Path: "/nonexistent/ipmitool", Timeout: time.Second}
+	_, err := r.Run("-H", "10.0.0.1", "-U", "root", "-P", "secret", "sel", "info")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "-P ********")
+	require.NotContains(t, err.Error(), "secret")
+
+	r = &Runner{Path: "/nonexistent/racadm", Timeout: time.Second, PasswordFlag: "-p"}
+	_, err = r.Run("-r", "10.0.0.1", "-u", "root", "-p", "secret", "get", "System.Power")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "-p ********")
+	require.NotContains(t, err.Error(), "secret")
+}
+
+func TestRedactPassword(t *testing.T) {
+	args := []string{"ipmitool", "-H", "10.0.0.1", "-U", "root", "-P", "secret", "dcmi", "power", "reading"}
+	require.Equal(t,
+		"ipmitool -H 10.0.0.1 -U root -P ******** dcmi power reading",
+		strings.Join(RedactPassword(args), " "))
+	require.Equal(t, "secret", args[6])
+
+	args = []string{"ssh", "bastion", "--", `'ipmitool' '-P' 'it'\''s secret' 'dcmi'`}
+	require.Equal(t,
+		`ssh bastion -- 'ipmitool' '-P' '********' 'dcmi'`,
+		strings.Join(RedactPassword(args), " "))
+}
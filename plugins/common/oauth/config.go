@@ -0,0 +1,130 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth2Config represents the client credentials grant configuration.
+type OAuth2Config struct {
+	ClientID     string   `toml:"client_id"`
+	ClientSecret string   `toml:"client_secret"`
+	TokenURL     string   `toml:"token_url"`
+	Scopes       []string `toml:"scopes"`
+}
+
+// CreateOauth2Client wraps the client so requests carry a bearer token
+// obtained with the client credentials grant.  Tokens are cached until they
+// expire and are fetched again once rejected by the server, so long running
+// clients survive token rotations.  The client is returned unchanged if no
+// credentials are configured.
+func (o *OAuth2Config) CreateOauth2Client(ctx context.Context, client *http.Client) *http.Client {
+	if o.ClientID == "" || o.ClientSecret == "" || o.TokenURL == "" {
+		return client
+	}
+
+	cfg := &clientcredentials.Config{
+		ClientID:     o.ClientID,
+		ClientSecret: o.ClientSecret,
+		TokenURL:     o.TokenURL,
+		Scopes:       o.Scopes,
+	}
+	// the token endpoint is queried using the same transport, e.g. for mTLS
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &http.Client{
+		Transport: &transport{
+			base:   base,
+			source: &tokenSource{ctx: ctx, cfg: cfg},
+		},
+		CheckRedirect: client.CheckRedirect,
+		Jar:           client.Jar,
+		Timeout:       client.Timeout,
+	}
+}
+
+// tokenSource caches the token of the client credentials grant until it
+// expires or is invalidated.
+type tokenSource struct {
+	sync.Mutex
+	ctx   context.Context
+	cfg   *clientcredentials.Config
+	token *oauth2.Token
+}
+
+func (s *tokenSource) Token() (*oauth2.Token, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	token, err := s.cfg.Token(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// invalidate drops the cached token if it is still the given one.
+func (s *tokenSource) invalidate(token *oauth2.Token) {
+	s.Lock()
+	defer s.Unlock()
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// transport authorizes requests with the cached token.  If the server
+// rejects the token it is invalidated and the request is retried once with
+// a fresh token, as long as the request body can be replayed.
+type transport struct {
+	base   http.RoundTripper
+	source *tokenSource
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, token, err := t.roundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	t.source.invalidate(token)
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+	resp, _, err = t.roundTrip(retry)
+	return resp, err
+}
+
+func (t *transport) roundTrip(req *http.Request) (*http.Response, *oauth2.Token, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the request must not be modified, see http.RoundTripper
+	r := req.Clone(req.Context())
+	token.SetAuthHeader(r)
+	resp, err := t.base.RoundTrip(r)
+	return resp, token, err
+}
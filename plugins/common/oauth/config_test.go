@@ -0,0 +1,72 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoCredentials(t *testing.T) {
+	client := &http.Client{}
+	o := &OAuth2Config{}
+	require.True(t, client == o.CreateOauth2Client(context.Background(), client))
+}
+
+func TestTokenCachingAndRotation(t *testing.T) {
+	var issued int32
+	var valid atomic.Value
+	valid.Store("")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			token := fmt.Sprintf("token%d", atomic.AddInt32(&issued, 1))
+			valid.Store(token)
+			values := url.Values{}
+			values.Add("access_token", token)
+			values.Add("token_type", "bearer")
+			values.Add("expires_in", "3600")
+			w.Write([]byte(values.Encode()))
+		case "/write":
+			if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	o := &OAuth2Config{
+		ClientID:     "howdy",
+		ClientSecret: "secret",
+		TokenURL:     ts.URL + "/token",
+	}
+	client := o.CreateOauth2Client(context.Background(), &http.Client{})
+
+	post := func() int {
+		resp, err := client.Post(ts.URL+"/write", "text/plain", strings.NewReader("cpu value=42"))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// the token is cached between requests
+	require.Equal(t, http.StatusNoContent, post())
+	require.Equal(t, http.StatusNoContent, post())
+	require.Equal(t, int32(1), atomic.LoadInt32(&issued))
+
+	// a rejected token is fetched again and the request retried
+	valid.Store("rotated")
+	require.Equal(t, http.StatusNoContent, post())
+	require.Equal(t, int32(2), atomic.LoadInt32(&issued))
+	require.Equal(t, http.StatusNoContent, post())
+	require.Equal(t, int32(2), atomic.LoadInt32(&issued))
+}
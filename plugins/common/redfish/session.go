@@ -0,0 +1,340 @@
+// Package redfish shares the sessions of Redfish BMCs among plugins.  BMCs
+// limit the number of sessions and throttle logins, so all plugins reading
+// the same BMC with the same credentials use one session, which is renewed
+// before it times out and replaced when the BMC drops it.
+package redfish
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// sessionsURI is the collection of the sessions of the SessionService.
+const sessionsURI = "/redfish/v1/SessionService/Sessions"
+
+// refreshMargin is the share of the session timeout after which an idle
+// session is replaced instead of risking a request with an expired token.
+const refreshMargin = 0.9
+
+var registry = newPool()
+
+// pool holds the sessions by BMC and credentials, and the limiters by BMC.
+type pool struct {
+	sync.Mutex
+	sessions map[string]*Session
+	limiters map[string]*limiter
+	now      func() time.Time
+}
+
+func newPool() *pool {
+	return &pool{
+		sessions: make(map[string]*Session),
+		limiters: make(map[string]*limiter),
+		now:      time.Now,
+	}
+}
+
+// GetSession returns the session of the user on the BMC at the base URL,
+// shared with the other plugins reading the BMC with the same credentials.
+// The session is created by the first request.
+func GetSession(baseURL *url.URL, username, password string) *Session {
+	return registry.get(baseURL, username, password)
+}
+
+func (p *pool) get(baseURL *url.URL, username, password string) *Session {
+	p.Lock()
+	defer p.Unlock()
+
+	key := baseURL.Scheme + "://" + baseURL.Host + "\x00" + username + "\x00" + password
+	if s, ok := p.sessions[key]; ok {
+		return s
+	}
+
+	l, ok := p.limiters[baseURL.Host]
+	if !ok {
+		l = &limiter{}
+		l.cond = sync.NewCond(&l.mu)
+		p.limiters[baseURL.Host] = l
+	}
+	s := &Session{
+		baseURL:  &url.URL{Scheme: baseURL.Scheme, Host: baseURL.Host},
+		username: username,
+		password: password,
+		limiter:  l,
+		now:      p.now,
+		logins:   selfstat.Register("redfish_sessions", "logins", map[string]string{"address": baseURL.Host}),
+	}
+	p.sessions[key] = s
+	return s
+}
+
+// limiter limits the number of concurrent requests to a BMC.
+type limiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+func (l *limiter) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.limit > 0 && l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.cond.Signal()
+}
+
+// Session is an authenticated session on a BMC.
+type Session struct {
+	baseURL  *url.URL
+	username string
+	password string
+	limiter  *limiter
+	now      func() time.Time
+	logins   selfstat.Stat
+
+	// loginMu serializes logins, mu guards the token and its state.
+	loginMu  sync.Mutex
+	mu       sync.Mutex
+	token    string
+	location string
+	timeout  time.Duration
+	lastUsed time.Time
+}
+
+// SetMaxConcurrency limits the number of concurrent requests to the BMC
+// of all sessions.  The lowest limit set by the plugins applies, 0 does not
+// limit the requests.
+func (s *Session) SetMaxConcurrency(n int) {
+	l := s.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n > 0 && (l.limit == 0 || n < l.limit) {
+		l.limit = n
+	}
+}
+
+// Do sends the request with the session token.  It logs in if there is no
+// session yet or the session is about to time out, and once more if the BMC
+// rejects the token.  Requests with a body are only repeated if the body
+// can be replayed, as it is for requests created by http.NewRequest.
+func (s *Session) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	s.limiter.acquire()
+	resp, err := s.do(client, req)
+	if err != nil {
+		s.limiter.release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: s.limiter.release}
+	return resp, nil
+}
+
+func (s *Session) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	token, err := s.currentToken(client)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Auth-Token", token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		s.used(token)
+		return resp, nil
+	}
+
+	// The session expired or was deleted on the BMC
+	resp.Body.Close()
+	if token, err = s.login(client, token, false); err != nil {
+		return nil, err
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("X-Auth-Token", token)
+	resp, err = client.Do(retry)
+	if err != nil {
+		return nil, err
+	}
+	s.used(token)
+	return resp, nil
+}
+
+// currentToken returns the token of the session, logging in first if there
+// is none or it idled for close to the session timeout of the BMC.
+func (s *Session) currentToken(client *http.Client) (string, error) {
+	s.mu.Lock()
+	token := s.token
+	stale := token != "" && s.timeout > 0 &&
+		s.now().Sub(s.lastUsed) > time.Duration(float64(s.timeout)*refreshMargin)
+	s.mu.Unlock()
+
+	if token == "" || stale {
+		return s.login(client, token, stale)
+	}
+	return token, nil
+}
+
+// used records the use of the token, which restarts the session timeout.
+func (s *Session) used(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.lastUsed = s.now()
+	}
+}
+
+// login creates a session and returns its token, unless another request
+// replaced the stale token meanwhile.  The stale session is deleted if it
+// may still be valid, BMCs limit the number of open sessions.
+func (s *Session) login(client *http.Client, stale string, deleteStale bool) (string, error) {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+
+	s.mu.Lock()
+	token, location := s.token, s.location
+	s.mu.Unlock()
+	if token != stale {
+		return token, nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"UserName": s.username,
+		"Password": s.password,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := s.newRequest("POST", sessionsURI, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("login received status code %d (%s), expected 201",
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+	token = resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return "", fmt.Errorf("login returned no session token")
+	}
+	s.logins.Incr(1)
+
+	if deleteStale && location != "" {
+		s.logout(client, stale, location)
+	}
+
+	s.mu.Lock()
+	s.token = token
+	s.location = resp.Header.Get("Location")
+	s.lastUsed = s.now()
+	known := s.timeout > 0
+	s.mu.Unlock()
+
+	if !known {
+		timeout := s.sessionTimeout(client, token)
+		s.mu.Lock()
+		s.timeout = timeout
+		s.mu.Unlock()
+	}
+	return token, nil
+}
+
+// sessionTimeout returns the timeout of idle sessions configured in the
+// SessionService, 0 if unknown.
+func (s *Session) sessionTimeout(client *http.Client, token string) time.Duration {
+	req, err := s.newRequest("GET", "/redfish/v1/SessionService", nil)
+	if err != nil {
+		return 0
+	}
+	req.Header.Set("X-Auth-Token", token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0
+	}
+
+	var service struct {
+		SessionTimeout int64
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&service); err != nil {
+		return 0
+	}
+	return time.Duration(service.SessionTimeout) * time.Second
+}
+
+// logout deletes the session, errors are ignored as the session times out
+// anyway.
+func (s *Session) logout(client *http.Client, token, location string) {
+	req, err := s.newRequest("DELETE", location, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("X-Auth-Token", token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+func (s *Session) newRequest(method, uri string, body io.Reader) (*http.Request, error) {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, s.baseURL.ResolveReference(ref).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	return req, nil
+}
+
+// releasingBody releases the request slot of the BMC once the response was
+// read.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
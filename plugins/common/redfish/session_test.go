@@ -0,0 +1,220 @@
+package redfish
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBMC issues sessions and serves a resource to them.
+type fakeBMC struct {
+	sync.Mutex
+	tokens    map[string]bool
+	logins    int
+	deleted   []string
+	timeout   int
+	active    int
+	maxActive int
+	delay     time.Duration
+	bodies    []string
+}
+
+func newFakeBMC() *fakeBMC {
+	return &fakeBMC{tokens: make(map[string]bool)}
+}
+
+func (f *fakeBMC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	if r.URL.Path == sessionsURI && r.Method == "POST" {
+		defer f.Unlock()
+		var credentials map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil || credentials["Password"] != "secret" {
+			http.Error(w, "Unauthorized.", 401)
+			return
+		}
+		f.logins++
+		token := fmt.Sprintf("token-%d", f.logins)
+		f.tokens[token] = true
+		w.Header().Set("X-Auth-Token", token)
+		w.Header().Set("Location", fmt.Sprintf("%s/%d", sessionsURI, f.logins))
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	token := r.Header.Get("X-Auth-Token")
+	if !f.tokens[token] {
+		f.Unlock()
+		http.Error(w, "Unauthorized.", 401)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/redfish/v1/SessionService":
+		defer f.Unlock()
+		if f.timeout == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"SessionTimeout": %d}`, f.timeout)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, sessionsURI+"/"):
+		defer f.Unlock()
+		delete(f.tokens, token)
+		f.deleted = append(f.deleted, r.URL.Path)
+	default:
+		if r.Body != nil {
+			body, _ := ioutil.ReadAll(r.Body)
+			f.bodies = append(f.bodies, string(body))
+		}
+		f.active++
+		if f.active > f.maxActive {
+			f.maxActive = f.active
+		}
+		f.Unlock()
+
+		time.Sleep(f.delay)
+
+		f.Lock()
+		f.active--
+		f.Unlock()
+		fmt.Fprint(w, `{"Id": "1"}`)
+	}
+}
+
+// expire drops all sessions as a BMC does when they time out.
+func (f *fakeBMC) expire() {
+	f.Lock()
+	defer f.Unlock()
+	f.tokens = make(map[string]bool)
+}
+
+func get(t *testing.T, s *Session, baseURL string) {
+	req, err := http.NewRequest("GET", baseURL+"/redfish/v1/Chassis/1", nil)
+	require.NoError(t, err)
+	resp, err := s.Do(http.DefaultClient, req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestSharedSession(t *testing.T) {
+	bmc := newFakeBMC()
+	ts := httptest.NewServer(bmc)
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	p := newPool()
+	s := p.get(u, "telegraf", "secret")
+	require.Same(t, s, p.get(u, "telegraf", "secret"))
+	require.NotSame(t, s, p.get(u, "other", "secret"))
+
+	get(t, s, ts.URL)
+	get(t, p.get(u, "telegraf", "secret"), ts.URL)
+	require.Equal(t, 1, bmc.logins)
+}
+
+func TestSessionExpired(t *testing.T) {
+	bmc := newFakeBMC()
+	ts := httptest.NewServer(bmc)
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	s := newPool().get(u, "telegraf", "secret")
+	get(t, s, ts.URL)
+	bmc.expire()
+
+	// The body is sent again with the new token
+	req, err := http.NewRequest("PATCH", ts.URL+"/redfish/v1/Chassis/1/Power", bytes.NewReader([]byte(`{"a":1}`)))
+	require.NoError(t, err)
+	resp, err := s.Do(http.DefaultClient, req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, bmc.logins)
+	require.Equal(t, []string{"", `{"a":1}`}, bmc.bodies)
+	require.Empty(t, bmc.deleted)
+}
+
+func TestSessionRefresh(t *testing.T) {
+	bmc := newFakeBMC()
+	bmc.timeout = 60
+	ts := httptest.NewServer(bmc)
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	now := time.Date(2020, 12, 15, 10, 0, 0, 0, time.UTC)
+	p := newPool()
+	p.now = func() time.Time { return now }
+	s := p.get(u, "telegraf", "secret")
+
+	get(t, s, ts.URL)
+	require.Equal(t, time.Minute, s.timeout)
+
+	// Requests keep the session alive
+	now = now.Add(50 * time.Second)
+	get(t, s, ts.URL)
+	now = now.Add(50 * time.Second)
+	get(t, s, ts.URL)
+	require.Equal(t, 1, bmc.logins)
+
+	// Close to the timeout the session is replaced and the old one deleted
+	now = now.Add(55 * time.Second)
+	get(t, s, ts.URL)
+	require.Equal(t, 2, bmc.logins)
+	require.Equal(t, []string{sessionsURI + "/1"}, bmc.deleted)
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	bmc := newFakeBMC()
+	bmc.delay = 20 * time.Millisecond
+	ts := httptest.NewServer(bmc)
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	p := newPool()
+	s := p.get(u, "telegraf", "secret")
+	s.SetMaxConcurrency(4)
+	// The lowest limit of the sessions of the BMC applies
+	p.get(u, "other", "secret").SetMaxConcurrency(2)
+	s.SetMaxConcurrency(0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, s, ts.URL)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 2, bmc.maxActive)
+	require.Equal(t, 1, bmc.logins)
+}
+
+func TestLoginFailure(t *testing.T) {
+	ts := httptest.NewServer(newFakeBMC())
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	s := newPool().get(u, "telegraf", "wrong")
+	req, err := http.NewRequest("GET", ts.URL+"/redfish/v1/Chassis/1", nil)
+	require.NoError(t, err)
+	_, err = s.Do(http.DefaultClient, req)
+	require.EqualError(t, err, "login received status code 401 (Unauthorized), expected 201")
+
+	// The request slot was released
+	require.Equal(t, 0, s.limiter.active)
+}
@@ -0,0 +1,221 @@
+package amd_energy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// now is used to mock the time in tests.
+var now = time.Now
+
+// AMDEnergy stores the configuration values for the amd_energy input plugin
+type AMDEnergy struct {
+	HwmonPath string `toml:"hwmon_path"`
+	CPUPath   string `toml:"cpu_path"`
+	PerCore   bool   `toml:"per_core"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	// counters holds the last reading of each counter by its input file.
+	counters map[string]*counterState
+	// coreSockets caches the socket of the cores.
+	coreSockets map[string]string
+}
+
+// counterState is the last reading of an energy counter.
+type counterState struct {
+	raw       uint64
+	total     uint64
+	timestamp time.Time
+}
+
+var sampleConfig = `
+  ## Path of the hwmon class in sysfs, the device named amd_energy is read.
+  # hwmon_path = "/sys/class/hwmon"
+
+  ## Path of the CPUs in sysfs, used to find the socket of the cores.
+  # cpu_path = "/sys/devices/system/cpu"
+
+  ## Gather the energy of each core, adding one series per core.  The
+  ## energy of the sockets is always gathered.
+  # per_core = true
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (a *AMDEnergy) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (a *AMDEnergy) Description() string {
+	return "Read the core and socket energy counters of AMD processors from the amd_energy driver"
+}
+
+// Init sets the defaults.
+func (a *AMDEnergy) Init() error {
+	if a.HwmonPath == "" {
+		a.HwmonPath = "/sys/class/hwmon"
+	}
+	if a.CPUPath == "" {
+		a.CPUPath = "/sys/devices/system/cpu"
+	}
+	a.counters = make(map[string]*counterState)
+	a.coreSockets = make(map[string]string)
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (a *AMDEnergy) Gather(acc telegraf.Accumulator) error {
+	dir, err := a.findDevice()
+	if err != nil {
+		return err
+	}
+	labels, err := filepath.Glob(filepath.Join(dir, "energy*_label"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(labels)
+
+	for _, labelPath := range labels {
+		label, err := readString(labelPath)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+
+		var tags map[string]string
+		switch {
+		case strings.HasPrefix(label, "Esocket"):
+			tags = map[string]string{
+				"domain": "socket",
+				"socket": strings.TrimPrefix(label, "Esocket"),
+			}
+		case strings.HasPrefix(label, "Ecore"):
+			if !a.PerCore {
+				continue
+			}
+			core, err := strconv.Atoi(strings.TrimPrefix(label, "Ecore"))
+			if err != nil {
+				acc.AddError(fmt.Errorf("invalid core label %q", label))
+				continue
+			}
+			tags = map[string]string{
+				"domain": "core",
+				"core":   strconv.Itoa(core),
+			}
+			if socket := a.socket(core); socket != "" {
+				tags["socket"] = socket
+			}
+		default:
+			continue
+		}
+
+		input := strings.TrimSuffix(labelPath, "_label") + "_input"
+		energy, err := readUint(input)
+		if err != nil {
+			acc.AddError(fmt.Errorf("reading energy of %s: %v", label, err))
+			continue
+		}
+		timestamp := now()
+		joules, watts, ok := a.update(input, energy, timestamp)
+
+		fields := map[string]interface{}{
+			"energy_joules": joules,
+		}
+		if ok {
+			fields["power_watts"] = watts
+		}
+		acc.AddFields("amd_energy", fields, tags, timestamp)
+	}
+	return nil
+}
+
+// findDevice returns the directory of the hwmon device of the amd_energy
+// driver.
+func (a *AMDEnergy) findDevice() (string, error) {
+	dirs, err := filepath.Glob(filepath.Join(a.HwmonPath, "hwmon*"))
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range dirs {
+		if name, err := readString(filepath.Join(dir, "name")); err == nil && name == "amd_energy" {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("no amd_energy device found in %s, is the amd_energy module loaded?", a.HwmonPath)
+}
+
+// socket returns the physical package of the core, empty if unknown.
+func (a *AMDEnergy) socket(core int) string {
+	cpu := "cpu" + strconv.Itoa(core)
+	if socket, ok := a.coreSockets[cpu]; ok {
+		return socket
+	}
+	socket, err := readString(filepath.Join(a.CPUPath, cpu, "topology", "physical_package_id"))
+	if err != nil {
+		a.Log.Debugf("Unknown socket of %s: %v", cpu, err)
+		socket = ""
+	}
+	a.coreSockets[cpu] = socket
+	return socket
+}
+
+// update accumulates the counter and returns its energy in Joules and the
+// average power since the previous reading if any.  The driver accumulates
+// the 32 bit hardware counters into 64 bit ones, a decreasing counter was
+// reset by reloading the driver.
+func (a *AMDEnergy) update(input string, energy uint64, timestamp time.Time) (float64, float64, bool) {
+	last, ok := a.counters[input]
+	if !ok {
+		a.counters[input] = &counterState{raw: energy, total: energy, timestamp: timestamp}
+		return float64(energy) / 1e6, 0, false
+	}
+
+	reset := energy < last.raw
+	delta := energy - last.raw
+	if reset {
+		delta = energy
+	}
+	elapsed := timestamp.Sub(last.timestamp).Seconds()
+	last.raw = energy
+	last.total += delta
+	last.timestamp = timestamp
+
+	joules := float64(last.total) / 1e6
+	if reset || elapsed <= 0 {
+		return joules, 0, false
+	}
+	return joules, float64(delta) / 1e6 / elapsed, true
+}
+
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("amd_energy", func() telegraf.Input {
+		return &AMDEnergy{
+			PerCore: true,
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package amd_energy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile creates the file with its directories.
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content+"\n"), 0644))
+}
+
+// writeSysfs creates a hwmon device of the amd_energy driver with two cores
+// on different sockets and the socket counters.
+func writeSysfs(t *testing.T, dir string, core0, core64, socket0, socket1 string) {
+	writeFile(t, filepath.Join(dir, "hwmon", "hwmon0", "name"), "k10temp")
+	hwmon := filepath.Join(dir, "hwmon", "hwmon3")
+	writeFile(t, filepath.Join(hwmon, "name"), "amd_energy")
+	writeFile(t, filepath.Join(hwmon, "energy1_label"), "Ecore000")
+	writeFile(t, filepath.Join(hwmon, "energy1_input"), core0)
+	writeFile(t, filepath.Join(hwmon, "energy2_label"), "Ecore064")
+	writeFile(t, filepath.Join(hwmon, "energy2_input"), core64)
+	writeFile(t, filepath.Join(hwmon, "energy3_label"), "Esocket0")
+	writeFile(t, filepath.Join(hwmon, "energy3_input"), socket0)
+	writeFile(t, filepath.Join(hwmon, "energy4_label"), "Esocket1")
+	writeFile(t, filepath.Join(hwmon, "energy4_input"), socket1)
+
+	writeFile(t, filepath.Join(dir, "cpu", "cpu0", "topology", "physical_package_id"), "0")
+	writeFile(t, filepath.Join(dir, "cpu", "cpu64", "topology", "physical_package_id"), "1")
+}
+
+func TestGather(t *testing.T) {
+	dir, err := ioutil.TempDir("", "amd_energy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	start := time.Unix(1602756000, 0)
+	defer func() { now = time.Now }()
+	now = func() time.Time { return start }
+
+	writeSysfs(t, dir, "1000000", "2000000", "100000000", "200000000")
+
+	a := &AMDEnergy{
+		HwmonPath: filepath.Join(dir, "hwmon"),
+		CPUPath:   filepath.Join(dir, "cpu"),
+		PerCore:   true,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, a.Init())
+
+	// The first reading has no power
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4)
+	acc.AssertContainsTaggedFields(t, "amd_energy",
+		map[string]interface{}{"energy_joules": 1.0},
+		map[string]string{"domain": "core", "core": "0", "socket": "0"})
+
+	now = func() time.Time { return start.Add(10 * time.Second) }
+	// The counter of socket 1 was reset by reloading the driver
+	writeSysfs(t, dir, "31000000", "12000000", "1100000000", "50000000")
+
+	acc.ClearMetrics()
+	require.NoError(t, a.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4)
+	acc.AssertContainsTaggedFields(t, "amd_energy",
+		map[string]interface{}{"energy_joules": 31.0, "power_watts": 3.0},
+		map[string]string{"domain": "core", "core": "0", "socket": "0"})
+	acc.AssertContainsTaggedFields(t, "amd_energy",
+		map[string]interface{}{"energy_joules": 12.0, "power_watts": 1.0},
+		map[string]string{"domain": "core", "core": "64", "socket": "1"})
+	acc.AssertContainsTaggedFields(t, "amd_energy",
+		map[string]interface{}{"energy_joules": 1100.0, "power_watts": 100.0},
+		map[string]string{"domain": "socket", "socket": "0"})
+	acc.AssertContainsTaggedFields(t, "amd_energy",
+		map[string]interface{}{"energy_joules": 250.0},
+		map[string]string{"domain": "socket", "socket": "1"})
+}
+
+func TestGatherSocketsOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "amd_energy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeSysfs(t, dir, "1000000", "2000000", "100000000", "200000000")
+
+	a := &AMDEnergy{
+		HwmonPath: filepath.Join(dir, "hwmon"),
+		CPUPath:   filepath.Join(dir, "cpu"),
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, a.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+	for _, m := range acc.Metrics {
+		require.Equal(t, "socket", m.Tags["domain"])
+	}
+}
+
+func TestGatherNoDevice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "amd_energy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "hwmon0", "name"), "k10temp")
+
+	a := &AMDEnergy{HwmonPath: dir, Log: testutil.Logger{}}
+	require.NoError(t, a.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, a.Gather(&acc))
+}
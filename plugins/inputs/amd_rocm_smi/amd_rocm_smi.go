@@ -0,0 +1,243 @@
+package amd_rocm_smi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = "amd_rocm_smi"
+
+// ROCmSMI holds the methods for this plugin
+type ROCmSMI struct {
+	BinPath        string            `toml:"bin_path"`
+	Timeout        internal.Duration `toml:"timeout"`
+	XGMIThroughput bool              `toml:"xgmi_throughput"`
+}
+
+// Description returns the description of the ROCmSMI plugin
+func (rsmi *ROCmSMI) Description() string {
+	return "Pulls statistics from AMD GPUs attached to the host through rocm-smi"
+}
+
+// SampleConfig returns the sample configuration for the ROCmSMI plugin
+func (rsmi *ROCmSMI) SampleConfig() string {
+	return `
+  ## Optional: path to rocm-smi binary
+  # bin_path = "/opt/rocm/bin/rocm-smi"
+
+  ## Optional: timeout for GPU polling
+  # timeout = "5s"
+
+  ## Optional: read the data counters of the XGMI links from the GPU metrics
+  ## table, requires rocm-smi of ROCm 6.0 or later.
+  # xgmi_throughput = false
+`
+}
+
+// Gather implements the telegraf interface
+func (rsmi *ROCmSMI) Gather(acc telegraf.Accumulator) error {
+	if _, err := os.Stat(rsmi.BinPath); os.IsNotExist(err) {
+		return fmt.Errorf("rocm-smi binary not at path %s, cannot gather GPU data", rsmi.BinPath)
+	}
+
+	data, err := rsmi.pollROCmSMI()
+	if err != nil {
+		return err
+	}
+	return gatherROCmSMI(data, acc)
+}
+
+func (rsmi *ROCmSMI) pollROCmSMI() ([]byte, error) {
+	args := []string{
+		"--showid", "--showproductname", "--showuniqueid", "--showbus",
+		"--showtemp", "--showpower", "--showuse", "--showmemuse",
+		"--showmeminfo", "vram", "--showxgmierr", "--showdriverversion",
+	}
+	if rsmi.XGMIThroughput {
+		args = append(args, "--showmetrics")
+	}
+	args = append(args, "--json")
+	return internal.StdOutputTimeout(exec.Command(rsmi.BinPath, args...), rsmi.Timeout.Duration)
+}
+
+type fieldKind int
+
+const (
+	floatField fieldKind = iota
+	intField
+	mebibytesField
+)
+
+// fields maps the keys printed by rocm-smi to field names.  The keys
+// changed between ROCm releases, several keys map to the same field.
+var fields = map[string]struct {
+	name string
+	kind fieldKind
+}{
+	"Temperature (Sensor edge) (C)":             {"temperature_edge", floatField},
+	"Temperature (Sensor junction) (C)":         {"temperature_junction", floatField},
+	"Temperature (Sensor memory) (C)":           {"temperature_memory", floatField},
+	"Average Graphics Package Power (W)":        {"power_draw", floatField},
+	"Current Socket Graphics Package Power (W)": {"power_draw", floatField},
+	"GPU use (%)":                  {"utilization_gpu", intField},
+	"GPU memory use (%)":           {"utilization_memory", intField},
+	"GPU Memory Allocated (VRAM%)": {"utilization_memory", intField},
+	"VRAM Total Memory (B)":        {"memory_total", mebibytesField},
+	"VRAM Total Used Memory (B)":   {"memory_used", mebibytesField},
+	"XGMI Error count":             {"xgmi_error_status", intField},
+}
+
+// tags maps the keys printed by rocm-smi to tag names.
+var tags = map[string]string{
+	"Device ID":   "gpu_id",
+	"Unique ID":   "gpu_unique_id",
+	"PCI Bus":     "pci_bus",
+	"Card series": "name",
+}
+
+// xgmiCounters maps the XGMI data counters of the GPU metrics table, in KB,
+// to field names.
+var xgmiCounters = map[string]string{
+	"xgmi_read_data_acc":  "read_bytes",
+	"xgmi_write_data_acc": "write_bytes",
+}
+
+func gatherROCmSMI(data []byte, acc telegraf.Accumulator) error {
+	var devices map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &devices); err != nil {
+		return fmt.Errorf("parsing the output of rocm-smi failed: %v", err)
+	}
+
+	var driverVersion string
+	if system, ok := devices["system"]; ok {
+		driverVersion = stringValue(system["Driver version"])
+	}
+
+	cards := make([]string, 0, len(devices))
+	for card := range devices {
+		if strings.HasPrefix(card, "card") {
+			cards = append(cards, card)
+		}
+	}
+	sort.Strings(cards)
+
+	for _, card := range cards {
+		device := devices[card]
+		gpuTags := map[string]string{"index": strings.TrimPrefix(card, "card")}
+		gpuFields := make(map[string]interface{})
+		setIfUsed(gpuFields, "driver_version", driverVersion)
+		links := make(map[int]map[string]interface{})
+
+		for key, raw := range device {
+			value := stringValue(raw)
+			if value == "" || value == "N/A" {
+				continue
+			}
+			if tag, ok := tags[key]; ok {
+				gpuTags[tag] = value
+				continue
+			}
+			if f, ok := fields[key]; ok {
+				addField(gpuFields, f.name, f.kind, value)
+				continue
+			}
+			name := strings.SplitN(key, " (", 2)[0]
+			if field, ok := xgmiCounters[name]; ok {
+				for link, kb := range parseList(value) {
+					if links[link] == nil {
+						links[link] = make(map[string]interface{})
+					}
+					links[link][field] = int64(kb * 1024)
+				}
+			}
+		}
+
+		if total, ok := gpuFields["memory_total"].(int64); ok {
+			if used, ok := gpuFields["memory_used"].(int64); ok {
+				gpuFields["memory_free"] = total - used
+			}
+		}
+		acc.AddFields(measurement, gpuFields, gpuTags)
+
+		for link, linkFields := range links {
+			linkTags := map[string]string{
+				"index": gpuTags["index"],
+				"link":  strconv.Itoa(link),
+			}
+			acc.AddFields(measurement+"_xgmi", linkFields, linkTags)
+		}
+	}
+	return nil
+}
+
+// addField parses the value of a field, values that do not parse are
+// ignored.
+func addField(m map[string]interface{}, name string, kind fieldKind, value string) {
+	switch kind {
+	case floatField:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			m[name] = f
+		}
+	case intField:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			m[name] = i
+		}
+	case mebibytesField:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			m[name] = i >> 20
+		}
+	}
+}
+
+// parseList parses the per link values of the GPU metrics table, printed
+// as "[1, 2, 3]".  The links not in use report the maximum value and are
+// left out.
+func parseList(value string) map[int]uint64 {
+	result := make(map[int]uint64)
+	value = strings.Trim(value, "[]")
+	for i, item := range strings.Split(value, ",") {
+		v, err := strconv.ParseUint(strings.TrimSpace(item), 0, 64)
+		if err != nil || v == ^uint64(0) {
+			continue
+		}
+		result[i] = v
+	}
+	return result
+}
+
+// stringValue returns the value printed by rocm-smi, usually a string but
+// a number for some keys.
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func setIfUsed(m map[string]interface{}, k, v string) {
+	if v != "" {
+		m[k] = v
+	}
+}
+
+func init() {
+	inputs.Add("amd_rocm_smi", func() telegraf.Input {
+		return &ROCmSMI{
+			BinPath: "/opt/rocm/bin/rocm-smi",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package amd_rocm_smi
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherValidJSON(t *testing.T) {
+	expected := []telegraf.Metric{
+		testutil.MustMetric("amd_rocm_smi",
+			map[string]string{
+				"index":         "0",
+				"gpu_id":        "0x740c",
+				"gpu_unique_id": "0x3d6a0ab2e4e39b3c",
+				"pci_bus":       "0000:C1:00.0",
+				"name":          "AMD INSTINCT MI250X / MI250",
+			},
+			map[string]interface{}{
+				"driver_version":       "6.2.4",
+				"temperature_edge":     38.0,
+				"temperature_junction": 42.0,
+				"temperature_memory":   51.0,
+				"power_draw":           91.0,
+				"utilization_gpu":      int64(37),
+				"utilization_memory":   int64(12),
+				"memory_total":         int64(65520),
+				"memory_used":          int64(10240),
+				"memory_free":          int64(55280),
+				"xgmi_error_status":    int64(0),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("amd_rocm_smi_xgmi",
+			map[string]string{
+				"index": "0",
+				"link":  "0",
+			},
+			map[string]interface{}{
+				"read_bytes":  int64(1048576),
+				"write_bytes": int64(524288),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("amd_rocm_smi_xgmi",
+			map[string]string{
+				"index": "0",
+				"link":  "1",
+			},
+			map[string]interface{}{
+				"read_bytes":  int64(2097152),
+				"write_bytes": int64(4194304),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("amd_rocm_smi",
+			map[string]string{
+				"index":         "1",
+				"gpu_id":        "0x740c",
+				"gpu_unique_id": "0x6a9f1c3a4e1b7d20",
+				"pci_bus":       "0000:C6:00.0",
+				"name":          "AMD INSTINCT MI250X / MI250",
+			},
+			map[string]interface{}{
+				"driver_version":       "6.2.4",
+				"temperature_junction": 40.0,
+				"temperature_memory":   49.0,
+				"utilization_gpu":      int64(0),
+				"utilization_memory":   int64(0),
+				"memory_total":         int64(65520),
+				"memory_used":          int64(10),
+				"memory_free":          int64(65510),
+				"xgmi_error_status":    int64(1),
+			},
+			time.Unix(0, 0)),
+	}
+
+	octets, err := ioutil.ReadFile(filepath.Join("testdata", "mi250x.json"))
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	require.NoError(t, gatherROCmSMI(octets, &acc))
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherInvalidJSON(t *testing.T) {
+	var acc testutil.Accumulator
+	require.Error(t, gatherROCmSMI([]byte("WARNING: No AMD GPUs specified"), &acc))
+}
@@ -0,0 +1,306 @@
+package beegfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+type BeeGFS struct {
+	BeegfsCtlPath string            `toml:"beegfs_ctl_path"`
+	UseSudo       bool              `toml:"use_sudo"`
+	Timeout       internal.Duration `toml:"timeout"`
+	Mount         string            `toml:"mount"`
+	NodeTypes     []string          `toml:"node_types"`
+	ServerStats   bool              `toml:"server_stats"`
+}
+
+var sampleConfig = `
+  ## Path to the beegfs-ctl executable.
+  # beegfs_ctl_path = "/usr/bin/beegfs-ctl"
+
+  ## Use sudo to run beegfs-ctl, which requires access to the
+  ## authentication file of the file system.  Sudo must be configured to
+  ## allow the telegraf user to run beegfs-ctl without a password.
+  # use_sudo = false
+
+  ## Timeout of each run of beegfs-ctl.
+  # timeout = "10s"
+
+  ## Mount point of the file system to query, by default the file system
+  ## of the client configuration.
+  # mount = "/mnt/beegfs"
+
+  ## Types of the servers to query, "meta" and "storage".
+  # node_types = ["meta", "storage"]
+
+  ## Query the work requests, queue length and busy workers of the servers.
+  # server_stats = true
+`
+
+func (b *BeeGFS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BeeGFS) Description() string {
+	return "Read the capacity of the targets and the load of the servers of BeeGFS through beegfs-ctl"
+}
+
+func (b *BeeGFS) Init() error {
+	if b.BeegfsCtlPath == "" {
+		b.BeegfsCtlPath = "/usr/bin/beegfs-ctl"
+	}
+	for _, nodeType := range b.NodeTypes {
+		if nodeType != "meta" && nodeType != "storage" {
+			return fmt.Errorf("invalid node type %q", nodeType)
+		}
+	}
+	return nil
+}
+
+func (b *BeeGFS) Gather(acc telegraf.Accumulator) error {
+	for _, nodeType := range b.NodeTypes {
+		if err := b.gatherTargets(acc, nodeType); err != nil {
+			acc.AddError(err)
+		}
+		if !b.ServerStats {
+			continue
+		}
+		if err := b.gatherServerStats(acc, nodeType); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func (b *BeeGFS) gatherTargets(acc telegraf.Accumulator, nodeType string) error {
+	out, err := b.run("--listtargets", "--nodetype="+nodeType, "--spaceinfo", "--state", "--pools")
+	if err != nil {
+		return err
+	}
+	header, rows := parseTable(out)
+	if len(rows) == 0 {
+		return fmt.Errorf("no %s target in the output of beegfs-ctl", nodeType)
+	}
+
+	now := time.Now()
+	for _, row := range rows {
+		tags := map[string]string{"node_type": nodeType}
+		fields := make(map[string]interface{})
+		for i, column := range header {
+			value := row[i]
+			switch column {
+			case "TargetID":
+				tags["target_id"] = value
+			case "NodeID":
+				tags["node_id"] = value
+			case "Cap. Pool":
+				tags["capacity_pool"] = value
+			case "Reachability", "Consistency":
+				fields[strings.ToLower(column)] = strings.ToLower(value)
+			case "Total", "Free":
+				if v, err := parseBytes(value); err == nil {
+					fields[strings.ToLower(column)+"_bytes"] = v
+				}
+			case "ITotal", "IFree":
+				if v, err := parseCount(value); err == nil {
+					fields["inodes_"+strings.ToLower(column[1:])] = v
+				}
+			}
+		}
+		if _, ok := tags["target_id"]; !ok || len(fields) == 0 {
+			continue
+		}
+		acc.AddFields("beegfs_target", fields, tags, now)
+	}
+	return nil
+}
+
+func (b *BeeGFS) gatherServerStats(acc telegraf.Accumulator, nodeType string) error {
+	out, err := b.run("--serverstats", "--perserver", "--names", "--nodetype="+nodeType, "--history=1")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, server := range parseServerStats(out) {
+		tags := map[string]string{
+			"node_type": nodeType,
+			"node":      server.node,
+		}
+		if server.nodeID != "" {
+			tags["node_id"] = server.nodeID
+		}
+		acc.AddFields("beegfs_server", server.fields, tags, now)
+	}
+	return nil
+}
+
+func (b *BeeGFS) run(args ...string) ([]byte, error) {
+	if b.Mount != "" {
+		args = append(args, "--mount="+b.Mount)
+	}
+
+	name := b.BeegfsCtlPath
+	if b.UseSudo {
+		// -n - avoid prompting the user for input of any kind
+		args = append([]string{"-n", name}, args...)
+		name = "sudo"
+	}
+	cmd := execCommand(name, args...)
+	out, err := internal.StdOutputTimeout(cmd, b.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	return out, nil
+}
+
+// parseTable parses the tables of beegfs-ctl, a header, a line of "=" under
+// each column and a row per target.  The columns are separated by spaces,
+// "Cap. Pool" is the only header with a space.
+func parseTable(out []byte) ([]string, [][]string) {
+	var header []string
+	var rows [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "TargetID"):
+			header = strings.Fields(strings.Replace(line, "Cap. Pool", "Cap.Pool", 1))
+			for i := range header {
+				if header[i] == "Cap.Pool" {
+					header[i] = "Cap. Pool"
+				}
+			}
+		case strings.HasPrefix(line, "="):
+			continue
+		case header != nil:
+			row := strings.Fields(line)
+			if len(row) == len(header) {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return header, rows
+}
+
+type serverStats struct {
+	node   string
+	nodeID string
+	fields map[string]interface{}
+}
+
+// parseServerStats parses the statistics of the servers, a header naming
+// the columns of the values and a line per server ending with the values,
+// e.g. "storage01 [ID: 1]  10240  20480  300  2  1".  The sum of the
+// servers and the statistics of the earlier intervals are skipped.
+func parseServerStats(out []byte) []serverStats {
+	var header []string
+	var servers []serverStats
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "=") {
+			if len(servers) > 0 {
+				break
+			}
+			continue
+		}
+		parts := strings.Fields(line)
+		if header == nil {
+			if _, err := strconv.ParseFloat(parts[len(parts)-1], 64); err != nil {
+				header = parts
+			}
+			continue
+		}
+		if len(parts) <= len(header) || strings.HasPrefix(line, "Sum:") {
+			continue
+		}
+
+		values := parts[len(parts)-len(header):]
+		fields := make(map[string]interface{})
+		for i, column := range header {
+			if v, err := strconv.ParseUint(values[i], 10, 64); err == nil {
+				fields[strings.ToLower(column)] = v
+			}
+		}
+		server := serverStats{node: strings.Join(parts[:len(parts)-len(header)], " "), fields: fields}
+		// "<name> [ID: <id>]" with --names
+		if i := strings.Index(server.node, "[ID:"); i >= 0 {
+			server.nodeID = strings.TrimSpace(strings.TrimSuffix(server.node[i+4:], "]"))
+			server.node = strings.TrimSpace(server.node[:i])
+		}
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+var byteUnits = map[string]float64{
+	"B":   1,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+	"PiB": 1 << 50,
+}
+
+// parseBytes parses the sizes of beegfs-ctl, e.g. 43717.3GiB.
+func parseBytes(s string) (uint64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		return strconv.ParseUint(s, 10, 64)
+	}
+	unit, ok := byteUnits[s[i:]]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit in %q", s)
+	}
+	v, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(v * unit), nil
+}
+
+// parseCount parses the inode counts of beegfs-ctl, e.g. 4375.0M.
+func parseCount(s string) (uint64, error) {
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier = 1e3
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1e6
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1e9
+	}
+	v, err := strconv.ParseFloat(strings.TrimRight(s, "kMG"), 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(v * multiplier), nil
+}
+
+func init() {
+	inputs.Add("beegfs", func() telegraf.Input {
+		return &BeeGFS{
+			Timeout:     internal.Duration{Duration: 10 * time.Second},
+			NodeTypes:   []string{"meta", "storage"},
+			ServerStats: true,
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package beegfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	b := &BeeGFS{
+		Timeout:     internal.Duration{Duration: 5 * time.Second},
+		Mount:       "/mnt/beegfs",
+		NodeTypes:   []string{"meta", "storage"},
+		ServerStats: true,
+	}
+	require.NoError(t, b.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 6)
+
+	acc.AssertContainsTaggedFields(t, "beegfs_target",
+		map[string]interface{}{
+			"reachability": "online",
+			"consistency":  "good",
+			"total_bytes":  uint64(959495693926),
+			"free_bytes":   uint64(908707705651),
+			"inodes_total": uint64(59600000),
+			"inodes_free":  uint64(58900000),
+		},
+		map[string]string{"node_type": "meta", "target_id": "1", "node_id": "1", "capacity_pool": "normal"})
+	acc.AssertContainsTaggedFields(t, "beegfs_target",
+		map[string]interface{}{
+			"reachability": "probably-offline",
+			"consistency":  "needs-resync",
+			"total_bytes":  uint64(46941093442355),
+			"free_bytes":   uint64(2199023255552),
+			"inodes_total": uint64(4375000000),
+			"inodes_free":  uint64(4366300000),
+		},
+		map[string]string{"node_type": "storage", "target_id": "201", "node_id": "2", "capacity_pool": "low"})
+	acc.AssertContainsTaggedFields(t, "beegfs_server",
+		map[string]interface{}{
+			"write_kib": uint64(10240),
+			"read_kib":  uint64(20480),
+			"reqs":      uint64(300),
+			"qlen":      uint64(2),
+			"bsy":       uint64(1),
+		},
+		map[string]string{"node_type": "storage", "node": "storage01", "node_id": "1"})
+	acc.AssertContainsTaggedFields(t, "beegfs_server",
+		map[string]interface{}{
+			"reqs": uint64(1200),
+			"qlen": uint64(5),
+			"bsy":  uint64(8),
+		},
+		map[string]string{"node_type": "meta", "node": "meta01", "node_id": "1"})
+}
+
+func TestInitInvalidNodeType(t *testing.T) {
+	b := &BeeGFS{NodeTypes: []string{"mgmt"}}
+	require.Error(t, b.Init())
+}
+
+func TestParseBytes(t *testing.T) {
+	v, err := parseBytes("1.5KiB")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1536), v)
+
+	v, err = parseBytes("512B")
+	require.NoError(t, err)
+	require.Equal(t, uint64(512), v)
+
+	_, err = parseBytes("3.0XB")
+	require.Error(t, err)
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the output of beegfs-ctl for the queried node type.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := strings.Join(os.Args, " ")
+	var file string
+	switch {
+	case strings.Contains(args, "--listtargets --nodetype=meta --spaceinfo --state --pools --mount=/mnt/beegfs"):
+		file = "targets_meta.txt"
+	case strings.Contains(args, "--listtargets --nodetype=storage --spaceinfo --state --pools --mount=/mnt/beegfs"):
+		file = "targets_storage.txt"
+	case strings.Contains(args, "--serverstats --perserver --names --nodetype=meta --history=1 --mount=/mnt/beegfs"):
+		file = "serverstats_meta.txt"
+	case strings.Contains(args, "--serverstats --perserver --names --nodetype=storage --history=1 --mount=/mnt/beegfs"):
+		file = "serverstats_storage.txt"
+	default:
+		fmt.Fprintf(os.Stderr, "unexpected arguments %q", args)
+		os.Exit(1)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
@@ -0,0 +1,269 @@
+package bmc_probe
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/inventory"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// inventoryKind is the kind of the BMCs in the device inventory.
+const inventoryKind = "bmc"
+
+// Result codes of a probe.
+const (
+	resultSuccess = iota
+	resultTimeout
+	resultError
+)
+
+// asfIANA is the IANA enterprise number of the ASF, 4542.
+var asfIANA = []byte{0x00, 0x00, 0x11, 0xbe}
+
+// probes are the supported probe methods.
+var probes = map[string]func(p *BMCProbe, host string) (int, time.Duration, error){
+	"rmcp":  (*BMCProbe).pingRMCP,
+	"https": (*BMCProbe).connectHTTPS,
+}
+
+var sampleConfig = `
+  ## BMCs to probe, either host names or addresses or servers in the url
+  ## syntax of the IPMI inputs,
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## credentials are not used.
+  # servers = ["192.168.1.1", "USERID:PASSW0RD@lan(192.168.1.2)"]
+
+  ## Also probe the BMCs in the device inventory, which holds the BMCs
+  ## discovered by the ipmi_power input.
+  # include_inventory = true
+
+  ## Probe methods, "rmcp" sends an RMCP presence ping to the IPMI port,
+  ## "https" opens a TCP connection to the Redfish and web interface port.
+  # methods = ["rmcp", "https"]
+
+  ## Ports of the probes.
+  # rmcp_port = 623
+  # https_port = 443
+
+  ## Probes are cheap, they may run more often than the power readings so
+  ## BMCs are known to be unreachable before their readings go stale.
+  interval = "10s"
+
+  ## Timeout of each probe.
+  # timeout = "2s"
+
+  ## Maximum number of probes running at the same time.
+  # max_parallel = 64
+`
+
+// BMCProbe checks that BMCs answer on their management ports.
+type BMCProbe struct {
+	Servers          []string          `toml:"servers"`
+	IncludeInventory bool              `toml:"include_inventory"`
+	Methods          []string          `toml:"methods"`
+	RMCPPort         int               `toml:"rmcp_port"`
+	HTTPSPort        int               `toml:"https_port"`
+	Timeout          internal.Duration `toml:"timeout"`
+	MaxParallel      int               `toml:"max_parallel"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	hosts []string
+}
+
+// SampleConfig returns the documentation about the sample configuration
+func (p *BMCProbe) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (p *BMCProbe) Description() string {
+	return "Check that BMCs answer RMCP pings and HTTPS connections"
+}
+
+// Init checks the methods and parses the servers.
+func (p *BMCProbe) Init() error {
+	for _, method := range p.Methods {
+		if _, ok := probes[method]; !ok {
+			return fmt.Errorf("invalid method %q, expected rmcp or https", method)
+		}
+	}
+	if len(p.Methods) == 0 {
+		return fmt.Errorf("no methods configured")
+	}
+	if p.MaxParallel < 1 {
+		return fmt.Errorf("max_parallel must be at least 1")
+	}
+
+	p.hosts = nil
+	for _, server := range p.Servers {
+		host := server
+		if strings.Contains(server, "(") {
+			host = ipmi.NewConnection(server, "").Hostname
+		}
+		if host == "" {
+			return fmt.Errorf("no host in server %q", server)
+		}
+		p.hosts = append(p.hosts, host)
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (p *BMCProbe) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, p.MaxParallel)
+	for _, host := range p.targets() {
+		for _, method := range p.Methods {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(host, method string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				p.probe(acc, host, method)
+			}(host, method)
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+// targets returns the configured hosts and those of the inventory, without
+// duplicates.
+func (p *BMCProbe) targets() []string {
+	if !p.IncludeInventory {
+		return p.hosts
+	}
+
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, host := range p.hosts {
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	var discovered []string
+	for _, d := range inventory.Devices(inventoryKind) {
+		if !seen[d.ID] {
+			seen[d.ID] = true
+			discovered = append(discovered, d.ID)
+		}
+	}
+	sort.Strings(discovered)
+	return append(hosts, discovered...)
+}
+
+func (p *BMCProbe) probe(acc telegraf.Accumulator, host, method string) {
+	result, rtt, err := probes[method](p, host)
+	if err != nil {
+		p.Log.Debugf("Probing %s with %s failed: %v", host, method, err)
+	}
+
+	fields := map[string]interface{}{
+		"reachable":   result == resultSuccess,
+		"result_code": result,
+	}
+	if result == resultSuccess {
+		fields["response_time_ms"] = float64(rtt) / float64(time.Millisecond)
+	}
+	acc.AddFields("bmc_probe", fields, map[string]string{"server": host, "method": method})
+}
+
+// pingRMCP sends an ASF presence ping to the RMCP port and waits for the
+// presence pong, which BMCs answer without a session.
+func (p *BMCProbe) pingRMCP(host string) (int, time.Duration, error) {
+	tag := make([]byte, 1)
+	if _, err := rand.Read(tag); err != nil {
+		return resultError, 0, err
+	}
+	// RMCP header: version 1.0, reserved, no acknowledge, class ASF, then
+	// the ASF presence ping message
+	ping := []byte{0x06, 0x00, 0xff, 0x06}
+	ping = append(ping, asfIANA...)
+	ping = append(ping, 0x80, tag[0], 0x00, 0x00)
+
+	address := net.JoinHostPort(host, strconv.Itoa(p.RMCPPort))
+	conn, err := net.DialTimeout("udp", address, p.Timeout.Duration)
+	if err != nil {
+		return resultError, 0, err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	if err := conn.SetDeadline(start.Add(p.Timeout.Duration)); err != nil {
+		return resultError, 0, err
+	}
+	if _, err := conn.Write(ping); err != nil {
+		return resultError, 0, err
+	}
+
+	buf := make([]byte, 512)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return classify(err), 0, err
+		}
+		if isPong(buf[:n], tag[0]) {
+			return resultSuccess, time.Since(start), nil
+		}
+	}
+}
+
+// isPong returns true if the message is the presence pong answering the
+// ping with the tag.
+func isPong(msg []byte, tag byte) bool {
+	return len(msg) >= 12 &&
+		msg[3] == 0x06 &&
+		bytes.Equal(msg[4:8], asfIANA) &&
+		msg[8] == 0x40 &&
+		msg[9] == tag
+}
+
+// connectHTTPS opens a TCP connection to the HTTPS port.  The TLS handshake
+// is skipped, a listening port shows the web server of the BMC is up.
+func (p *BMCProbe) connectHTTPS(host string) (int, time.Duration, error) {
+	address := net.JoinHostPort(host, strconv.Itoa(p.HTTPSPort))
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, p.Timeout.Duration)
+	if err != nil {
+		return classify(err), 0, err
+	}
+	rtt := time.Since(start)
+	conn.Close()
+	return resultSuccess, rtt, nil
+}
+
+// classify returns the result code of a failed probe.
+func classify(err error) int {
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return resultTimeout
+	}
+	return resultError
+}
+
+func init() {
+	inputs.Add("bmc_probe", func() telegraf.Input {
+		return &BMCProbe{
+			IncludeInventory: true,
+			Methods:          []string{"rmcp", "https"},
+			RMCPPort:         623,
+			HTTPSPort:        443,
+			Timeout:          internal.Duration{Duration: 2 * time.Second},
+			MaxParallel:      64,
+		}
+	})
+}
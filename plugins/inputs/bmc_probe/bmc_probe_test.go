@@ -0,0 +1,153 @@
+package bmc_probe
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/inventory"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// fakeRMCP answers presence pings, or ignores them if mute is set.
+func fakeRMCP(t *testing.T, mute bool) int {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if mute || n < 12 || buf[8] != 0x80 {
+				continue
+			}
+			// An unrelated message before the pong is skipped
+			conn.WriteTo([]byte{0x06, 0x00, 0xff, 0x07}, addr)
+			pong := []byte{0x06, 0x00, 0xff, 0x06, 0x00, 0x00, 0x11, 0xbe, 0x40, buf[9], 0x00, 0x10,
+				0x00, 0x00, 0x11, 0xbe, 0x00, 0x00, 0x00, 0x00, 0x81, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+			conn.WriteTo(pong, addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// fakeHTTPS accepts connections.
+func fakeHTTPS(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// closedPort returns a TCP port nothing listens on.
+func closedPort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+func newProbe(rmcpPort, httpsPort int) *BMCProbe {
+	return &BMCProbe{
+		Servers:     []string{"127.0.0.1"},
+		Methods:     []string{"rmcp", "https"},
+		RMCPPort:    rmcpPort,
+		HTTPSPort:   httpsPort,
+		Timeout:     internal.Duration{Duration: 200 * time.Millisecond},
+		MaxParallel: 4,
+		Log:         testutil.Logger{},
+	}
+}
+
+func TestGatherReachable(t *testing.T) {
+	p := newProbe(fakeRMCP(t, false), fakeHTTPS(t))
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+
+	for _, method := range []string{"rmcp", "https"} {
+		var found bool
+		for _, m := range acc.Metrics {
+			if m.Tags["method"] != method {
+				continue
+			}
+			found = true
+			require.Equal(t, "127.0.0.1", m.Tags["server"])
+			require.Equal(t, true, m.Fields["reachable"])
+			require.Equal(t, resultSuccess, m.Fields["result_code"])
+			require.Contains(t, m.Fields, "response_time_ms")
+		}
+		require.True(t, found, method)
+	}
+}
+
+func TestGatherUnreachable(t *testing.T) {
+	p := newProbe(fakeRMCP(t, true), closedPort(t))
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "bmc_probe",
+		map[string]interface{}{"reachable": false, "result_code": resultTimeout},
+		map[string]string{"server": "127.0.0.1", "method": "rmcp"})
+	acc.AssertContainsTaggedFields(t, "bmc_probe",
+		map[string]interface{}{"reachable": false, "result_code": resultError},
+		map[string]string{"server": "127.0.0.1", "method": "https"})
+}
+
+func TestTargets(t *testing.T) {
+	inventory.Register(inventory.Device{Kind: "bmc", ID: "10.0.0.9"})
+	inventory.Register(inventory.Device{Kind: "bmc", ID: "10.0.0.1"})
+	inventory.Register(inventory.Device{Kind: "pdu", ID: "10.0.1.1"})
+	defer func() {
+		inventory.Forget("bmc", "10.0.0.9")
+		inventory.Forget("bmc", "10.0.0.1")
+		inventory.Forget("pdu", "10.0.1.1")
+	}()
+
+	p := newProbe(623, 443)
+	p.Servers = []string{"bmc-a.example.com", "USERID:PASSW0RD@lanplus(10.0.0.1)"}
+	require.NoError(t, p.Init())
+	require.Equal(t, []string{"bmc-a.example.com", "10.0.0.1"}, p.targets())
+
+	p.IncludeInventory = true
+	require.Equal(t, []string{"bmc-a.example.com", "10.0.0.1", "10.0.0.9"}, p.targets())
+}
+
+func TestInitErrors(t *testing.T) {
+	p := newProbe(623, 443)
+	p.Methods = []string{"icmp"}
+	require.Error(t, p.Init())
+
+	p = newProbe(623, 443)
+	p.Servers = []string{"USERID:PASSW0RD@lan()"}
+	require.Error(t, p.Init())
+}
+
+func TestIsPong(t *testing.T) {
+	pong := []byte{0x06, 0x00, 0xff, 0x06, 0x00, 0x00, 0x11, 0xbe, 0x40, 0x2a, 0x00, 0x10}
+	require.True(t, isPong(pong, 0x2a))
+	require.False(t, isPong(pong, 0x2b))
+	require.False(t, isPong(pong[:8], 0x2a))
+}
@@ -0,0 +1,221 @@
+package buddyinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	defaultHostProc = "/proc"
+	envProc         = "HOST_PROC"
+)
+
+type Buddyinfo struct {
+	HostProc     string `toml:"host_proc"`
+	Pagetypeinfo bool   `toml:"pagetypeinfo"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// zone identifies a memory zone of a NUMA node, and a migrate type of the
+// pages of the zone in pagetypeinfo.
+type zone struct {
+	node     string
+	name     string
+	pageType string
+}
+
+var sampleConfig = `
+  ## Sets 'proc' directory path, if not specified the default is /proc
+  # host_proc = "/proc"
+
+  ## Read the free pages and the page blocks of each migrate type from
+  ## /proc/pagetypeinfo, which is only readable by root.
+  # pagetypeinfo = false
+`
+
+func (b *Buddyinfo) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *Buddyinfo) Description() string {
+	return "Read the free pages of each order of the memory zones from /proc/buddyinfo"
+}
+
+func (b *Buddyinfo) Init() error {
+	if b.HostProc == "" {
+		b.HostProc = os.Getenv(envProc)
+	}
+	if b.HostProc == "" {
+		b.HostProc = defaultHostProc
+	}
+	return nil
+}
+
+func (b *Buddyinfo) Gather(acc telegraf.Accumulator) error {
+	file, err := os.Open(filepath.Join(b.HostProc, "buddyinfo"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zones, err := parseBuddyinfo(file)
+	if err != nil {
+		return err
+	}
+	for _, z := range zones {
+		acc.AddFields("buddyinfo", z.fields, map[string]string{"node": z.zone.node, "zone": z.zone.name})
+	}
+
+	if b.Pagetypeinfo {
+		if err := b.gatherPagetypeinfo(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func (b *Buddyinfo) gatherPagetypeinfo(acc telegraf.Accumulator) error {
+	file, err := os.Open(filepath.Join(b.HostProc, "pagetypeinfo"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zones, err := parsePagetypeinfo(file)
+	if err != nil {
+		return err
+	}
+	for _, z := range zones {
+		tags := map[string]string{"node": z.zone.node, "zone": z.zone.name, "type": z.zone.pageType}
+		acc.AddFields("buddyinfo_pagetype", z.fields, tags)
+	}
+	return nil
+}
+
+type zoneFields struct {
+	zone   zone
+	fields map[string]interface{}
+}
+
+// parseBuddyinfo parses the number of free blocks of each order, blocks of
+// 2^order pages, of the zones, e.g.
+//
+//	Node 0, zone   Normal   1394   1123    845    649    468 ...
+func parseBuddyinfo(r io.Reader) ([]zoneFields, error) {
+	var zones []zoneFields
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 5 || parts[0] != "Node" || parts[2] != "zone" {
+			continue
+		}
+		z := zoneFields{
+			zone:   zone{node: strings.TrimSuffix(parts[1], ","), name: parts[3]},
+			fields: make(map[string]interface{}),
+		}
+		var freePages int64
+		for order, s := range parts[4:] {
+			count, err := parseCount(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid free blocks %q in zone %s of node %s", s, z.zone.name, z.zone.node)
+			}
+			z.fields["order_"+strconv.Itoa(order)] = count
+			freePages += count << uint(order)
+		}
+		z.fields["free_pages"] = freePages
+		zones = append(zones, z)
+	}
+	return zones, scanner.Err()
+}
+
+// parsePagetypeinfo parses the free blocks of each order per migrate type,
+// and the number of page blocks of each migrate type, e.g.
+//
+//	Free pages count per migrate type at order       0      1 ...
+//	Node    0, zone   Normal, type    Unmovable    120     51 ...
+//	...
+//	Number of blocks type     Unmovable      Movable  Reclaimable ...
+//	Node 0, zone   Normal          190        15966          100 ...
+func parsePagetypeinfo(r io.Reader) ([]zoneFields, error) {
+	var zones []zoneFields
+	index := make(map[zone]int)
+	var blockTypes []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Number of blocks type") {
+			blockTypes = strings.Fields(strings.TrimPrefix(line, "Number of blocks type"))
+			continue
+		}
+		if !strings.HasPrefix(line, "Node") {
+			continue
+		}
+
+		// "Node 0" and the zone and type are separated by commas
+		sections := strings.Split(line, ",")
+		node := strings.TrimSpace(strings.TrimPrefix(sections[0], "Node"))
+		if len(sections) == 3 && blockTypes == nil {
+			zoneName := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(sections[1]), "zone"))
+			parts := strings.Fields(strings.TrimSpace(sections[2]))
+			if len(parts) < 2 || parts[0] != "type" {
+				continue
+			}
+			z := zoneFields{
+				zone:   zone{node: node, name: zoneName, pageType: parts[1]},
+				fields: make(map[string]interface{}),
+			}
+			for order, s := range parts[2:] {
+				count, err := parseCount(s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid free blocks %q in zone %s of node %s", s, zoneName, node)
+				}
+				z.fields["order_"+strconv.Itoa(order)] = count
+			}
+			index[z.zone] = len(zones)
+			zones = append(zones, z)
+		} else if len(sections) == 2 && blockTypes != nil {
+			parts := strings.Fields(strings.TrimSpace(sections[1]))
+			if len(parts) != len(blockTypes)+2 || parts[0] != "zone" {
+				continue
+			}
+			for i, pageType := range blockTypes {
+				count, err := parseCount(parts[i+2])
+				if err != nil {
+					return nil, fmt.Errorf("invalid number of blocks %q in zone %s of node %s", parts[i+2], parts[1], node)
+				}
+				// Types without free pages are not listed in the first table
+				z := zone{node: node, name: parts[1], pageType: pageType}
+				i, ok := index[z]
+				if !ok {
+					index[z] = len(zones)
+					zones = append(zones, zoneFields{zone: z, fields: make(map[string]interface{})})
+					i = index[z]
+				}
+				zones[i].fields["blocks"] = count
+			}
+		}
+	}
+	return zones, scanner.Err()
+}
+
+// parseCount parses a count, the free blocks are capped to ">100000" in
+// pagetypeinfo since Linux 5.9.
+func parseCount(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(s, ">"), 10, 64)
+}
+
+func init() {
+	inputs.Add("buddyinfo", func() telegraf.Input {
+		return &Buddyinfo{}
+	})
+}
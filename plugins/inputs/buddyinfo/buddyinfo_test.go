@@ -0,0 +1,67 @@
+package buddyinfo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	b := &Buddyinfo{HostProc: "testdata", Pagetypeinfo: true}
+	require.NoError(t, b.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4+10)
+
+	acc.AssertContainsTaggedFields(t, "buddyinfo",
+		map[string]interface{}{
+			"order_0":    int64(20417),
+			"order_1":    int64(8312),
+			"order_2":    int64(3021),
+			"order_3":    int64(402),
+			"order_4":    int64(21),
+			"order_5":    int64(3),
+			"order_6":    int64(0),
+			"order_7":    int64(0),
+			"order_8":    int64(0),
+			"order_9":    int64(0),
+			"order_10":   int64(0),
+			"free_pages": int64(20417 + 8312*2 + 3021*4 + 402*8 + 21*16 + 3*32),
+		},
+		map[string]string{"node": "1", "zone": "Normal"})
+
+	acc.AssertContainsTaggedFields(t, "buddyinfo_pagetype",
+		map[string]interface{}{
+			"order_0":  int64(100000),
+			"order_1":  int64(1072),
+			"order_2":  int64(825),
+			"order_3":  int64(640),
+			"order_4":  int64(465),
+			"order_5":  int64(277),
+			"order_6":  int64(156),
+			"order_7":  int64(73),
+			"order_8":  int64(32),
+			"order_9":  int64(12),
+			"order_10": int64(9873),
+			"blocks":   int64(15966),
+		},
+		map[string]string{"node": "0", "zone": "Normal", "type": "Movable"})
+}
+
+func TestGatherWithoutPagetypeinfo(t *testing.T) {
+	b := &Buddyinfo{HostProc: "testdata"}
+	require.NoError(t, b.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, b.Gather(&acc))
+	require.Len(t, acc.Metrics, 4)
+}
+
+func TestParseBuddyinfoInvalid(t *testing.T) {
+	_, err := parseBuddyinfo(strings.NewReader("Node 0, zone   Normal   1394   x\n"))
+	require.Error(t, err)
+}
@@ -0,0 +1,246 @@
+package cpufreq
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// CPUFreq stores the configuration values for the cpufreq input plugin
+type CPUFreq struct {
+	Path      string `toml:"path"`
+	PerCPU    bool   `toml:"per_cpu"`
+	PerSocket bool   `toml:"per_socket"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// cpuState is the frequency state of a CPU.
+type cpuState struct {
+	cpu    string
+	socket string
+	fields map[string]interface{}
+}
+
+var sampleConfig = `
+  ## Path of the CPUs in sysfs.
+  # path = "/sys/devices/system/cpu"
+
+  ## Emit a metric per CPU.
+  # per_cpu = true
+
+  ## Emit the minimum, average and maximum frequency of the CPUs of each
+  ## socket, disable per_cpu to keep the number of series low on nodes with
+  ## many cores.
+  # per_socket = false
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (c *CPUFreq) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (c *CPUFreq) Description() string {
+	return "Read the frequency, governor and thermal throttling of the CPUs from sysfs"
+}
+
+// Init sets the defaults.
+func (c *CPUFreq) Init() error {
+	if c.Path == "" {
+		c.Path = "/sys/devices/system/cpu"
+	}
+	if !c.PerCPU && !c.PerSocket {
+		return fmt.Errorf("per_cpu or per_socket must be enabled")
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (c *CPUFreq) Gather(acc telegraf.Accumulator) error {
+	dirs, err := filepath.Glob(filepath.Join(c.Path, "cpu[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	var cpus []*cpuState
+	for _, dir := range dirs {
+		// Offline CPUs and CPUs without frequency scaling have no cpufreq
+		// directory
+		if _, err := os.Stat(filepath.Join(dir, "cpufreq")); err != nil {
+			continue
+		}
+		s, err := readCPU(dir)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		cpus = append(cpus, s)
+	}
+	if len(cpus) == 0 {
+		return fmt.Errorf("no CPU with frequency scaling found in %s", c.Path)
+	}
+
+	if c.PerCPU {
+		for _, s := range cpus {
+			tags := map[string]string{"cpu": s.cpu}
+			if s.socket != "" {
+				tags["socket"] = s.socket
+			}
+			acc.AddFields("cpufreq", s.fields, tags)
+		}
+	}
+	if c.PerSocket {
+		for socket, fields := range summarize(cpus) {
+			tags := map[string]string{}
+			if socket != "" {
+				tags["socket"] = socket
+			}
+			acc.AddFields("cpufreq_socket", fields, tags)
+		}
+	}
+	return nil
+}
+
+// readCPU reads the frequency state of the CPU in the directory.  Only the
+// current frequency is required, the other files depend on the driver.
+func readCPU(dir string) (*cpuState, error) {
+	s := &cpuState{
+		cpu:    strings.TrimPrefix(filepath.Base(dir), "cpu"),
+		fields: make(map[string]interface{}),
+	}
+
+	cur, err := readUint(filepath.Join(dir, "cpufreq", "scaling_cur_freq"))
+	if err != nil {
+		return nil, fmt.Errorf("reading frequency of cpu%s: %v", s.cpu, err)
+	}
+	// The frequencies are in kHz
+	s.fields["current_mhz"] = float64(cur) / 1000
+	for file, field := range map[string]string{
+		"scaling_min_freq": "scaling_min_mhz",
+		"scaling_max_freq": "scaling_max_mhz",
+		"cpuinfo_min_freq": "hardware_min_mhz",
+		"cpuinfo_max_freq": "hardware_max_mhz",
+	} {
+		if v, err := readUint(filepath.Join(dir, "cpufreq", file)); err == nil {
+			s.fields[field] = float64(v) / 1000
+		}
+	}
+	if governor, err := readString(filepath.Join(dir, "cpufreq", "scaling_governor")); err == nil {
+		s.fields["governor"] = governor
+	}
+
+	// Thermal throttling events counted by Intel processors
+	for _, name := range []string{"core_throttle_count", "package_throttle_count"} {
+		if v, err := readUint(filepath.Join(dir, "thermal_throttle", name)); err == nil {
+			s.fields[name] = int64(v)
+		}
+	}
+
+	if socket, err := readString(filepath.Join(dir, "topology", "physical_package_id")); err == nil {
+		s.socket = socket
+	}
+	return s, nil
+}
+
+// summarize returns the fields of the sockets: the minimum, average and
+// maximum current frequency, the governor if all CPUs share it, and the
+// throttling events.  The package throttling events are counted by each
+// CPU of the socket, their maximum is used.
+func summarize(cpus []*cpuState) map[string]map[string]interface{} {
+	bySocket := make(map[string][]*cpuState)
+	for _, s := range cpus {
+		bySocket[s.socket] = append(bySocket[s.socket], s)
+	}
+
+	summaries := make(map[string]map[string]interface{}, len(bySocket))
+	for socket, states := range bySocket {
+		var min, max, sum float64
+		var coreThrottles, packageThrottles int64
+		var hasCore, hasPackage bool
+		governors := make(map[string]bool)
+		for i, s := range states {
+			cur := s.fields["current_mhz"].(float64)
+			if i == 0 || cur < min {
+				min = cur
+			}
+			if cur > max {
+				max = cur
+			}
+			sum += cur
+			if g, ok := s.fields["governor"].(string); ok {
+				governors[g] = true
+			}
+			if v, ok := s.fields["core_throttle_count"].(int64); ok {
+				coreThrottles += v
+				hasCore = true
+			}
+			if v, ok := s.fields["package_throttle_count"].(int64); ok {
+				if v > packageThrottles {
+					packageThrottles = v
+				}
+				hasPackage = true
+			}
+		}
+
+		fields := map[string]interface{}{
+			"current_mhz_min": min,
+			"current_mhz_avg": sum / float64(len(states)),
+			"current_mhz_max": max,
+			"cpus":            int64(len(states)),
+		}
+		switch len(governors) {
+		case 0:
+		case 1:
+			for g := range governors {
+				fields["governor"] = g
+			}
+		default:
+			names := make([]string, 0, len(governors))
+			for g := range governors {
+				names = append(names, g)
+			}
+			sort.Strings(names)
+			fields["governor"] = strings.Join(names, ",")
+		}
+		if hasCore {
+			fields["core_throttle_count"] = coreThrottles
+		}
+		if hasPackage {
+			fields["package_throttle_count"] = packageThrottles
+		}
+		summaries[socket] = fields
+	}
+	return summaries
+}
+
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("cpufreq", func() telegraf.Input {
+		return &CPUFreq{
+			PerCPU: true,
+		}
+	})
+}
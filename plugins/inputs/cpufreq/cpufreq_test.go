@@ -0,0 +1,151 @@
+package cpufreq
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCPU creates the sysfs files of a CPU.
+func writeCPU(t *testing.T, dir, cpu string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, "cpu"+cpu, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content+"\n"), 0644))
+	}
+}
+
+func newSysfs(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cpufreq")
+	require.NoError(t, err)
+
+	cpu := func(cur, socket, coreThrottles, packageThrottles, governor string) map[string]string {
+		return map[string]string{
+			"cpufreq/scaling_cur_freq":                cur,
+			"cpufreq/scaling_min_freq":                "1000000",
+			"cpufreq/scaling_max_freq":                "3700000",
+			"cpufreq/cpuinfo_min_freq":                "1000000",
+			"cpufreq/cpuinfo_max_freq":                "3700000",
+			"cpufreq/scaling_governor":                governor,
+			"thermal_throttle/core_throttle_count":    coreThrottles,
+			"thermal_throttle/package_throttle_count": packageThrottles,
+			"topology/physical_package_id":            socket,
+		}
+	}
+	writeCPU(t, dir, "0", cpu("2400000", "0", "3", "12", "performance"))
+	writeCPU(t, dir, "1", cpu("3600000", "0", "0", "12", "performance"))
+	writeCPU(t, dir, "2", cpu("1200000", "1", "0", "0", "powersave"))
+	writeCPU(t, dir, "3", cpu("1800000", "1", "1", "2", "performance"))
+	// Offline CPU
+	writeCPU(t, dir, "4", map[string]string{"online": "0"})
+	// Not a CPU
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "cpufreq"), 0755))
+	return dir
+}
+
+func TestGatherPerCPU(t *testing.T) {
+	dir := newSysfs(t)
+	defer os.RemoveAll(dir)
+
+	c := &CPUFreq{Path: dir, PerCPU: true, Log: testutil.Logger{}}
+	require.NoError(t, c.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4)
+	acc.AssertContainsTaggedFields(t, "cpufreq",
+		map[string]interface{}{
+			"current_mhz":            2400.0,
+			"scaling_min_mhz":        1000.0,
+			"scaling_max_mhz":        3700.0,
+			"hardware_min_mhz":       1000.0,
+			"hardware_max_mhz":       3700.0,
+			"governor":               "performance",
+			"core_throttle_count":    int64(3),
+			"package_throttle_count": int64(12),
+		},
+		map[string]string{"cpu": "0", "socket": "0"})
+}
+
+func TestGatherPerSocket(t *testing.T) {
+	dir := newSysfs(t)
+	defer os.RemoveAll(dir)
+
+	c := &CPUFreq{Path: dir, PerSocket: true, Log: testutil.Logger{}}
+	require.NoError(t, c.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "cpufreq_socket",
+		map[string]interface{}{
+			"current_mhz_min":        2400.0,
+			"current_mhz_avg":        3000.0,
+			"current_mhz_max":        3600.0,
+			"cpus":                   int64(2),
+			"governor":               "performance",
+			"core_throttle_count":    int64(3),
+			"package_throttle_count": int64(12),
+		},
+		map[string]string{"socket": "0"})
+	acc.AssertContainsTaggedFields(t, "cpufreq_socket",
+		map[string]interface{}{
+			"current_mhz_min":        1200.0,
+			"current_mhz_avg":        1500.0,
+			"current_mhz_max":        1800.0,
+			"cpus":                   int64(2),
+			"governor":               "performance,powersave",
+			"core_throttle_count":    int64(1),
+			"package_throttle_count": int64(2),
+		},
+		map[string]string{"socket": "1"})
+}
+
+func TestGatherMinimal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpufreq")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Virtual machines often lack everything but the current frequency
+	writeCPU(t, dir, "0", map[string]string{"cpufreq/scaling_cur_freq": "2000000"})
+
+	c := &CPUFreq{Path: dir, PerCPU: true, PerSocket: true, Log: testutil.Logger{}}
+	require.NoError(t, c.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "cpufreq",
+		map[string]interface{}{"current_mhz": 2000.0},
+		map[string]string{"cpu": "0"})
+	acc.AssertContainsFields(t, "cpufreq_socket",
+		map[string]interface{}{
+			"current_mhz_min": 2000.0,
+			"current_mhz_avg": 2000.0,
+			"current_mhz_max": 2000.0,
+			"cpus":            int64(1),
+		})
+}
+
+func TestGatherNoCPUs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpufreq")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := &CPUFreq{Path: dir, PerCPU: true}
+	require.NoError(t, c.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, c.Gather(&acc))
+}
+
+func TestInitNothingEnabled(t *testing.T) {
+	c := &CPUFreq{}
+	require.Error(t, c.Init())
+}
@@ -0,0 +1,122 @@
+package daos
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+type DAOS struct {
+	DmgPath       string            `toml:"dmg_path"`
+	DmgConfig     string            `toml:"dmg_config"`
+	UseSudo       bool              `toml:"use_sudo"`
+	Pools         bool              `toml:"pools"`
+	TelemetryURLs []string          `toml:"telemetry_urls"`
+	Metrics       []string          `toml:"metrics"`
+	Timeout       internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	metricFilter filter.Filter
+	client       *http.Client
+}
+
+var sampleConfig = `
+  ## Query the space, targets and rebuild state of the pools with dmg, from
+  ## a node with the administrative certificates of the system.
+  # pools = true
+
+  ## Path to the dmg executable and its configuration file.
+  # dmg_path = "/usr/bin/dmg"
+  # dmg_config = "/etc/daos/daos_control.yml"
+
+  ## Use sudo to run dmg.  Sudo must be configured to allow the telegraf
+  ## user to run dmg without a password.
+  # use_sudo = false
+
+  ## URLs of the telemetry of the engines, exported by daos_server when
+  ## telemetry_port is set in its configuration.
+  # telemetry_urls = ["http://daos-server01:9191/metrics"]
+
+  ## Telemetry metrics to report, the engines export several thousand.  All
+  ## metrics are reported when empty.
+  # metrics = ["engine_io_latency_*", "engine_pool_*"]
+
+  ## Timeout of dmg and of the requests to the engines.
+  # timeout = "10s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (d *DAOS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DAOS) Description() string {
+	return "Read the pools of DAOS through dmg and the telemetry of its engines"
+}
+
+func (d *DAOS) Init() error {
+	if d.DmgPath == "" {
+		d.DmgPath = "/usr/bin/dmg"
+	}
+	if !d.Pools && len(d.TelemetryURLs) == 0 {
+		return fmt.Errorf("neither pools nor telemetry_urls are set")
+	}
+
+	var err error
+	if d.metricFilter, err = filter.Compile(d.Metrics); err != nil {
+		return fmt.Errorf("invalid metric filter: %v", err)
+	}
+	tlsCfg, err := d.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	d.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: d.Timeout.Duration,
+	}
+	return nil
+}
+
+func (d *DAOS) Gather(acc telegraf.Accumulator) error {
+	if d.Pools {
+		if err := d.gatherPools(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	for _, u := range d.TelemetryURLs {
+		if err := d.gatherTelemetry(acc, u); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("daos", func() telegraf.Input {
+		return &DAOS{
+			Pools:   true,
+			Metrics: []string{"engine_io_latency_*", "engine_pool_*"},
+			Timeout: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package daos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherPools(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	d := &DAOS{
+		DmgConfig: "/etc/daos/daos_control.yml",
+		Pools:     true,
+		Timeout:   internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "DER_TIMEDOUT")
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "daos_pool",
+		map[string]interface{}{
+			"total_targets":    int64(32),
+			"active_targets":   int64(30),
+			"disabled_targets": int64(2),
+			"scm_total_bytes":  uint64(100000000000),
+			"scm_free_bytes":   uint64(60000000000),
+			"nvme_total_bytes": uint64(8000000000000),
+			"nvme_free_bytes":  uint64(5000000000000),
+			"rebuild_state":    "busy",
+			"rebuild_status":   int64(0),
+			"rebuild_objects":  int64(1200),
+			"rebuild_records":  int64(53000),
+		},
+		map[string]string{"pool": "tank", "pool_uuid": "3a4b6e0c-1b1f-4bd1-9a49-1f2cb45c5e61"})
+}
+
+func TestGatherTelemetry(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/metrics", r.URL.Path)
+		http.ServeFile(w, r, filepath.Join("testdata", "metrics.txt"))
+	}))
+	defer ts.Close()
+
+	u := ts.URL + "/metrics"
+	d := &DAOS{
+		TelemetryURLs: []string{u},
+		Metrics:       []string{"engine_io_latency_*", "engine_pool_*"},
+		Timeout:       internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "daos_engine",
+		map[string]interface{}{
+			"io_latency_update_mean": 35.5,
+			"io_latency_update_max":  120.0,
+		},
+		map[string]string{"url": u, "rank": "0", "size": "4KB", "target": "0"})
+	acc.AssertContainsTaggedFields(t, "daos_engine",
+		map[string]interface{}{
+			"io_latency_update_mean": 41.0,
+			"io_latency_update_max":  98.0,
+		},
+		map[string]string{"url": u, "rank": "0", "size": "4KB", "target": "1"})
+	acc.AssertContainsTaggedFields(t, "daos_engine",
+		map[string]interface{}{"pool_ops_fetch": 5000.0},
+		map[string]string{"url": u, "rank": "0", "pool": "3a4b6e0c-1b1f-4bd1-9a49-1f2cb45c5e61"})
+}
+
+func TestInitNothingToGather(t *testing.T) {
+	d := &DAOS{}
+	require.Error(t, d.Init())
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the JSON output of dmg, failing like dmg for the scratch pool.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := strings.Join(os.Args, " ")
+	var file string
+	status := 0
+	switch {
+	case strings.HasSuffix(args, "dmg -o /etc/daos/daos_control.yml -j pool list"):
+		file = "pool_list.json"
+	case strings.HasSuffix(args, "dmg -o /etc/daos/daos_control.yml -j pool query tank"):
+		file = "pool_query_tank.json"
+	case strings.HasSuffix(args, "dmg -o /etc/daos/daos_control.yml -j pool query scratch"):
+		file = "pool_query_scratch.json"
+		status = 1
+	default:
+		fmt.Fprintf(os.Stderr, "unexpected arguments %q", args)
+		os.Exit(1)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(status)
+}
@@ -0,0 +1,121 @@
+package daos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// dmgResponse is the envelope of the JSON output of dmg.
+type dmgResponse struct {
+	Response json.RawMessage `json:"response"`
+	Error    *string         `json:"error"`
+	Status   int             `json:"status"`
+}
+
+type dmgPoolList struct {
+	Pools []struct {
+		UUID  string `json:"uuid"`
+		Label string `json:"label"`
+	} `json:"pools"`
+}
+
+type dmgPoolQuery struct {
+	UUID            string `json:"uuid"`
+	Label           string `json:"label"`
+	TotalTargets    int64  `json:"total_targets"`
+	ActiveTargets   int64  `json:"active_targets"`
+	DisabledTargets int64  `json:"disabled_targets"`
+	Rebuild         *struct {
+		Status  int64  `json:"status"`
+		State   string `json:"state"`
+		Objects int64  `json:"objects"`
+		Records int64  `json:"records"`
+	} `json:"rebuild"`
+	TierStats []struct {
+		Total     uint64 `json:"total"`
+		Free      uint64 `json:"free"`
+		MediaType string `json:"media_type"`
+	} `json:"tier_stats"`
+}
+
+// gatherPools adds the space, targets and rebuild state of each pool of the
+// system.
+func (d *DAOS) gatherPools(acc telegraf.Accumulator) error {
+	var list dmgPoolList
+	if err := d.dmg(&list, "pool", "list"); err != nil {
+		return err
+	}
+
+	for _, pool := range list.Pools {
+		id := pool.Label
+		if id == "" {
+			id = pool.UUID
+		}
+		var query dmgPoolQuery
+		if err := d.dmg(&query, "pool", "query", id); err != nil {
+			acc.AddError(err)
+			continue
+		}
+
+		tags := map[string]string{"pool_uuid": pool.UUID}
+		if pool.Label != "" {
+			tags["pool"] = pool.Label
+		}
+		fields := map[string]interface{}{
+			"total_targets":    query.TotalTargets,
+			"active_targets":   query.ActiveTargets,
+			"disabled_targets": query.DisabledTargets,
+		}
+		for _, tier := range query.TierStats {
+			media := strings.ToLower(tier.MediaType)
+			fields[media+"_total_bytes"] = tier.Total
+			fields[media+"_free_bytes"] = tier.Free
+		}
+		if r := query.Rebuild; r != nil {
+			fields["rebuild_state"] = r.State
+			fields["rebuild_status"] = r.Status
+			fields["rebuild_objects"] = r.Objects
+			fields["rebuild_records"] = r.Records
+		}
+		acc.AddFields("daos_pool", fields, tags, time.Now())
+	}
+	return nil
+}
+
+// dmg runs a dmg command with JSON output and decodes its response.
+func (d *DAOS) dmg(v interface{}, args ...string) error {
+	args = append([]string{"-j"}, args...)
+	if d.DmgConfig != "" {
+		args = append([]string{"-o", d.DmgConfig}, args...)
+	}
+
+	name := d.DmgPath
+	if d.UseSudo {
+		// -n - avoid prompting the user for input of any kind
+		args = append([]string{"-n", name}, args...)
+		name = "sudo"
+	}
+	cmd := execCommand(name, args...)
+	// dmg exits with an error status along with the error in the JSON
+	out, err := internal.StdOutputTimeout(cmd, d.Timeout.Duration)
+
+	var resp dmgResponse
+	if jerr := json.Unmarshal(out, &resp); jerr != nil {
+		if err != nil {
+			return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+		}
+		return fmt.Errorf("parsing the output of %s failed: %v", strings.Join(cmd.Args, " "), jerr)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s failed: %s", strings.Join(cmd.Args, " "), *resp.Error)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %s", strings.Join(cmd.Args, " "), err)
+	}
+	return json.Unmarshal(resp.Response, v)
+}
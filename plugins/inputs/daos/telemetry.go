@@ -0,0 +1,72 @@
+package daos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
+)
+
+// gatherTelemetry reads the Prometheus metrics of the engines, the metrics
+// with the same labels, e.g. rank and target, are grouped in one metric
+// with the name of the metrics, without their engine_ prefix, as fields.
+func (d *DAOS) gatherTelemetry(acc telegraf.Accumulator, u string) error {
+	resp, err := d.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status code %d (%s)", u, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	parser := &prometheus.Parser{}
+	metrics, err := parser.Parse(body)
+	if err != nil {
+		return fmt.Errorf("parsing %s failed: %v", u, err)
+	}
+
+	type group struct {
+		tags   map[string]string
+		fields map[string]interface{}
+	}
+	groups := make(map[string]*group)
+	for _, m := range metrics {
+		tags := m.Tags()
+		tags["url"] = u
+		key := tagKey(tags)
+		for name, value := range m.Fields() {
+			if d.metricFilter != nil && !d.metricFilter.Match(name) {
+				continue
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &group{tags: tags, fields: make(map[string]interface{})}
+				groups[key] = g
+			}
+			g.fields[strings.TrimPrefix(name, "engine_")] = value
+		}
+	}
+
+	for _, g := range groups {
+		acc.AddFields("daos_engine", g.fields, g.tags)
+	}
+	return nil
+}
+
+func tagKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		keys = append(keys, k+"="+v)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,282 @@
+package dcgm
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// fieldKind is the type of the value of a DCGM field.
+type fieldKind int
+
+const (
+	floatField fieldKind = iota
+	intField
+	bitmaskField
+)
+
+type field struct {
+	name string
+	kind fieldKind
+}
+
+// fields maps the DCGM fields exported by dcgm-exporter to field names,
+// other DCGM fields are named after their identifier.
+var fields = map[string]field{
+	"DCGM_FI_DEV_SM_CLOCK":                 {name: "sm_clock_mhz"},
+	"DCGM_FI_DEV_MEM_CLOCK":                {name: "memory_clock_mhz"},
+	"DCGM_FI_DEV_GPU_TEMP":                 {name: "temperature_celsius"},
+	"DCGM_FI_DEV_MEMORY_TEMP":              {name: "memory_temperature_celsius"},
+	"DCGM_FI_DEV_POWER_USAGE":              {name: "power_watts"},
+	"DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION": {name: "energy_millijoules", kind: intField},
+	"DCGM_FI_DEV_GPU_UTIL":                 {name: "utilization_percent"},
+	"DCGM_FI_DEV_MEM_COPY_UTIL":            {name: "memory_copy_utilization_percent"},
+	"DCGM_FI_DEV_ENC_UTIL":                 {name: "encoder_utilization_percent"},
+	"DCGM_FI_DEV_DEC_UTIL":                 {name: "decoder_utilization_percent"},
+	"DCGM_FI_DEV_FB_FREE":                  {name: "memory_free_mib"},
+	"DCGM_FI_DEV_FB_USED":                  {name: "memory_used_mib"},
+	"DCGM_FI_DEV_PCIE_REPLAY_COUNTER":      {name: "pcie_replay_count", kind: intField},
+	"DCGM_FI_DEV_XID_ERRORS":               {name: "xid_error", kind: intField},
+	"DCGM_FI_DEV_CLOCK_THROTTLE_REASONS":   {name: "throttle_reasons", kind: bitmaskField},
+	"DCGM_FI_DEV_ECC_SBE_VOL_TOTAL":        {name: "ecc_sbe_volatile", kind: intField},
+	"DCGM_FI_DEV_ECC_DBE_VOL_TOTAL":        {name: "ecc_dbe_volatile", kind: intField},
+	"DCGM_FI_DEV_ECC_SBE_AGG_TOTAL":        {name: "ecc_sbe_aggregate", kind: intField},
+	"DCGM_FI_DEV_ECC_DBE_AGG_TOTAL":        {name: "ecc_dbe_aggregate", kind: intField},
+	"DCGM_FI_DEV_RETIRED_SBE":              {name: "retired_pages_sbe", kind: intField},
+	"DCGM_FI_DEV_RETIRED_DBE":              {name: "retired_pages_dbe", kind: intField},
+	"DCGM_FI_DEV_RETIRED_PENDING":          {name: "retired_pages_pending", kind: intField},
+	"DCGM_FI_DEV_ROW_REMAP_FAILURE":        {name: "row_remap_failure", kind: intField},
+	"DCGM_FI_DEV_NVLINK_BANDWIDTH_TOTAL":   {name: "nvlink_bandwidth_total", kind: intField},
+	"DCGM_FI_PROF_GR_ENGINE_ACTIVE":        {name: "graphics_engine_active_ratio"},
+	"DCGM_FI_PROF_SM_ACTIVE":               {name: "sm_active_ratio"},
+	"DCGM_FI_PROF_SM_OCCUPANCY":            {name: "sm_occupancy_ratio"},
+	"DCGM_FI_PROF_PIPE_TENSOR_ACTIVE":      {name: "tensor_active_ratio"},
+	"DCGM_FI_PROF_PIPE_FP64_ACTIVE":        {name: "fp64_active_ratio"},
+	"DCGM_FI_PROF_PIPE_FP32_ACTIVE":        {name: "fp32_active_ratio"},
+	"DCGM_FI_PROF_PIPE_FP16_ACTIVE":        {name: "fp16_active_ratio"},
+	"DCGM_FI_PROF_DRAM_ACTIVE":             {name: "dram_active_ratio"},
+	"DCGM_FI_PROF_PCIE_TX_BYTES":           {name: "pcie_tx_bytes_per_second"},
+	"DCGM_FI_PROF_PCIE_RX_BYTES":           {name: "pcie_rx_bytes_per_second"},
+	"DCGM_FI_PROF_NVLINK_TX_BYTES":         {name: "nvlink_tx_bytes_per_second"},
+	"DCGM_FI_PROF_NVLINK_RX_BYTES":         {name: "nvlink_rx_bytes_per_second"},
+}
+
+// throttleReasons are the bits of DCGM_FI_DEV_CLOCK_THROTTLE_REASONS.
+var throttleReasons = []struct {
+	bit  uint64
+	name string
+}{
+	{0x1, "throttle_gpu_idle"},
+	{0x2, "throttle_applications_clocks"},
+	{0x4, "throttle_sw_power_cap"},
+	{0x8, "throttle_hw_slowdown"},
+	{0x10, "throttle_sync_boost"},
+	{0x20, "throttle_sw_thermal"},
+	{0x40, "throttle_hw_thermal"},
+	{0x80, "throttle_hw_power_brake"},
+	{0x100, "throttle_display_clocks"},
+}
+
+// labels maps the labels of dcgm-exporter to tags, the other labels, such
+// as those of the Kubernetes pods, are ignored.
+var labels = map[string]string{
+	"gpu":           "gpu",
+	"UUID":          "uuid",
+	"device":        "device",
+	"modelName":     "model",
+	"Hostname":      "source",
+	"GPU_I_ID":      "gpu_instance",
+	"GPU_I_PROFILE": "gpu_instance_profile",
+}
+
+// DCGM stores the configuration values for the dcgm input plugin
+type DCGM struct {
+	URLs    []string          `toml:"urls"`
+	Timeout internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  ## URLs of the metrics endpoints of dcgm-exporter.
+  # urls = ["http://localhost:9400/metrics"]
+
+  ## Maximum time to receive the response.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (d *DCGM) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (d *DCGM) Description() string {
+	return "Read the GPU metrics of NVIDIA DCGM from dcgm-exporter"
+}
+
+// Init creates the HTTP client.
+func (d *DCGM) Init() error {
+	if len(d.URLs) == 0 {
+		d.URLs = []string{"http://localhost:9400/metrics"}
+	}
+	if d.Timeout.Duration == 0 {
+		d.Timeout.Duration = 5 * time.Second
+	}
+	tlsCfg, err := d.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	d.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+		},
+		Timeout: d.Timeout.Duration,
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (d *DCGM) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, u := range d.URLs {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			if err := d.gatherURL(acc, u); err != nil {
+				acc.AddError(fmt.Errorf("%s: %v", u, err))
+			}
+		}(u)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (d *DCGM) gatherURL(acc telegraf.Accumulator, u string) error {
+	resp, err := d.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return fmt.Errorf("parsing metrics failed: %v", err)
+	}
+
+	now := time.Now()
+	for _, g := range groupByGPU(families) {
+		acc.AddFields("dcgm", g.fields, g.tags, now)
+	}
+	return nil
+}
+
+// gpuMetric is the metric of a GPU or of a MIG instance.
+type gpuMetric struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// groupByGPU merges the DCGM fields of each GPU, which dcgm-exporter
+// exports as one metric family per field, into one metric.
+func groupByGPU(families map[string]*dto.MetricFamily) []*gpuMetric {
+	gpus := make(map[string]*gpuMetric)
+	var keys []string
+	for name, family := range families {
+		if !strings.HasPrefix(name, "DCGM_FI_") {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			value, ok := sampleValue(m)
+			if !ok {
+				continue
+			}
+
+			tags := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				if tag, ok := labels[l.GetName()]; ok && l.GetValue() != "" {
+					tags[tag] = l.GetValue()
+				}
+			}
+			key := tags["source"] + "/" + tags["gpu"] + "/" + tags["uuid"] + "/" + tags["gpu_instance"]
+			g, ok := gpus[key]
+			if !ok {
+				g = &gpuMetric{tags: tags, fields: make(map[string]interface{})}
+				gpus[key] = g
+				keys = append(keys, key)
+			}
+			addField(g.fields, name, value)
+		}
+	}
+
+	sort.Strings(keys)
+	result := make([]*gpuMetric, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, gpus[key])
+	}
+	return result
+}
+
+// addField adds the value of the DCGM field, decoding the throttle reasons.
+func addField(result map[string]interface{}, name string, value float64) {
+	f, ok := fields[name]
+	if !ok {
+		result[strings.ToLower(strings.TrimPrefix(name, "DCGM_FI_"))] = value
+		return
+	}
+	switch f.kind {
+	case intField:
+		result[f.name] = int64(value)
+	case bitmaskField:
+		mask := uint64(value)
+		result[f.name] = mask
+		for _, reason := range throttleReasons {
+			result[reason.name] = mask&reason.bit != 0
+		}
+	default:
+		result[f.name] = value
+	}
+}
+
+// sampleValue returns the value of a gauge or counter sample.
+func sampleValue(m *dto.Metric) (float64, bool) {
+	switch {
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue(), true
+	case m.Counter != nil:
+		return m.GetCounter().GetValue(), true
+	case m.Untyped != nil:
+		return m.GetUntyped().GetValue(), true
+	}
+	return 0, false
+}
+
+func init() {
+	inputs.Add("dcgm", func() telegraf.Input {
+		return &DCGM{}
+	})
+}
@@ -0,0 +1,134 @@
+package dcgm
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", "metrics.txt"))
+	require.NoError(t, err)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/metrics", r.URL.Path)
+		w.Write(content)
+	}))
+	defer ts.Close()
+
+	d := &DCGM{URLs: []string{ts.URL + "/metrics"}, Log: testutil.Logger{}}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("dcgm",
+			map[string]string{
+				"gpu":    "0",
+				"uuid":   "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"device": "nvidia0",
+				"model":  "NVIDIA A100-SXM4-40GB",
+				"source": "node01",
+			},
+			map[string]interface{}{
+				"sm_clock_mhz":                 1410.0,
+				"temperature_celsius":          61.0,
+				"power_watts":                  312.5,
+				"energy_millijoules":           int64(8021937461),
+				"throttle_reasons":             uint64(36),
+				"throttle_gpu_idle":            false,
+				"throttle_applications_clocks": false,
+				"throttle_sw_power_cap":        true,
+				"throttle_hw_slowdown":         false,
+				"throttle_sync_boost":          false,
+				"throttle_sw_thermal":          true,
+				"throttle_hw_thermal":          false,
+				"throttle_hw_power_brake":      false,
+				"throttle_display_clocks":      false,
+				"ecc_dbe_volatile":             int64(0),
+				"ecc_sbe_volatile":             int64(3),
+				"nvlink_bandwidth_total":       int64(128734),
+				"sm_active_ratio":              0.875,
+				"dram_active_ratio":            0.5,
+				"nvlink_tx_bytes_per_second":   1048576.0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("dcgm",
+			map[string]string{
+				"gpu":    "1",
+				"uuid":   "GPU-1f4b2c7e-6a8d-4d3c-9b51-0e2a7c9d1f33",
+				"device": "nvidia1",
+				"model":  "NVIDIA A100-SXM4-40GB",
+				"source": "node01",
+			},
+			map[string]interface{}{
+				"sm_clock_mhz":                 210.0,
+				"temperature_celsius":          33.0,
+				"power_watts":                  52.25,
+				"throttle_reasons":             uint64(1),
+				"throttle_gpu_idle":            true,
+				"throttle_applications_clocks": false,
+				"throttle_sw_power_cap":        false,
+				"throttle_hw_slowdown":         false,
+				"throttle_sync_boost":          false,
+				"throttle_sw_thermal":          false,
+				"throttle_hw_thermal":          false,
+				"throttle_hw_power_brake":      false,
+				"throttle_display_clocks":      false,
+				"dev_vgpu_license_status":      0.0,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherMIG(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`# TYPE DCGM_FI_PROF_GR_ENGINE_ACTIVE gauge
+DCGM_FI_PROF_GR_ENGINE_ACTIVE{gpu="0",UUID="GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",device="nvidia0",modelName="NVIDIA A100-SXM4-40GB",GPU_I_PROFILE="1g.5gb",GPU_I_ID="7"} 0.25
+DCGM_FI_PROF_GR_ENGINE_ACTIVE{gpu="0",UUID="GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",device="nvidia0",modelName="NVIDIA A100-SXM4-40GB",GPU_I_PROFILE="3g.20gb",GPU_I_ID="1"} 0.75
+`))
+	}))
+	defer ts.Close()
+
+	d := &DCGM{URLs: []string{ts.URL}, Log: testutil.Logger{}}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "dcgm",
+		map[string]interface{}{"graphics_engine_active_ratio": 0.25},
+		map[string]string{
+			"gpu":                  "0",
+			"uuid":                 "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+			"device":               "nvidia0",
+			"model":                "NVIDIA A100-SXM4-40GB",
+			"gpu_instance":         "7",
+			"gpu_instance_profile": "1g.5gb",
+		})
+}
+
+func TestGatherError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	d := &DCGM{URLs: []string{ts.URL}, Log: testutil.Logger{}}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "503")
+}
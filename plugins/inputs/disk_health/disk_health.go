@@ -0,0 +1,333 @@
+package disk_health
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// ataAttributes are the fields of the raw values of the ATA SMART attributes.
+var ataAttributes = map[int]string{
+	5:   "reallocated_sectors",
+	196: "reallocation_events",
+	197: "pending_sectors",
+	198: "offline_uncorrectable",
+	199: "udma_crc_errors",
+}
+
+type DiskHealth struct {
+	PathSmartctl string            `toml:"path_smartctl"`
+	UseSudo      bool              `toml:"use_sudo"`
+	Nocheck      string            `toml:"nocheck"`
+	Devices      []string          `toml:"devices"`
+	Excludes     []string          `toml:"excludes"`
+	Timeout      internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// smartctlOutput is the part of the JSON output of smartctl 7.0 and later
+// used by the plugin.
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String string `json:"string"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	Device struct {
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName    string `json:"model_name"`
+	ScsiModel    string `json:"scsi_model_name"`
+	SerialNumber string `json:"serial_number"`
+	WWN          *struct {
+		NAA uint64 `json:"naa"`
+		OUI uint64 `json:"oui"`
+		ID  uint64 `json:"id"`
+	} `json:"wwn"`
+	LogicalUnitID  string `json:"logical_unit_id"`
+	NVMeNamespaces []struct {
+		EUI64 *struct {
+			OUI   uint64 `json:"oui"`
+			ExtID uint64 `json:"ext_id"`
+		} `json:"eui64"`
+	} `json:"nvme_namespaces"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature *struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime *struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	AtaSmartAttributes struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	ScsiGrownDefectList *int64 `json:"scsi_grown_defect_list"`
+	ScsiErrorCounterLog map[string]struct {
+		TotalUncorrectedErrors int64 `json:"total_uncorrected_errors"`
+	} `json:"scsi_error_counter_log"`
+	NVMeSmartHealth *struct {
+		MediaErrors    int64 `json:"media_errors"`
+		PercentageUsed int64 `json:"percentage_used"`
+		AvailableSpare int64 `json:"available_spare"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+var sampleConfig = `
+  ## Path to the smartctl executable, version 7.0 or later.
+  # path_smartctl = "/usr/sbin/smartctl"
+
+  ## Use sudo to run smartctl, which requires access to the devices.  Sudo
+  ## must be configured to allow the telegraf user to run smartctl without
+  ## a password.
+  # use_sudo = false
+
+  ## Skip the disks in this power mode, "standby" does not wake up the
+  ## disks that have stopped rotating.  See --nocheck of smartctl.
+  # nocheck = "standby"
+
+  ## Devices to read with their type, by default the devices found by
+  ## "smartctl --scan".
+  # devices = ["/dev/sda", "/dev/bus/0 -d megaraid,4"]
+
+  ## Devices to skip when scanning.
+  # excludes = ["/dev/sdz"]
+
+  ## Timeout of each run of smartctl.
+  # timeout = "30s"
+`
+
+func (d *DiskHealth) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DiskHealth) Description() string {
+	return "Read the health of ATA, SCSI and NVMe disks from the JSON output of smartctl"
+}
+
+func (d *DiskHealth) Init() error {
+	if d.PathSmartctl == "" {
+		path, err := exec.LookPath("smartctl")
+		if err != nil {
+			return fmt.Errorf("smartctl not found: verify that smartctl is installed and in your PATH (or specified in config): %v", err)
+		}
+		d.PathSmartctl = path
+	}
+	if d.Nocheck == "" {
+		d.Nocheck = "standby"
+	}
+	return nil
+}
+
+func (d *DiskHealth) Gather(acc telegraf.Accumulator) error {
+	devices := d.Devices
+	if len(devices) == 0 {
+		var err error
+		if devices, err = d.scan(); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		wg.Add(1)
+		go func(device string) {
+			defer wg.Done()
+			if err := d.gatherDevice(acc, device); err != nil {
+				acc.AddError(err)
+			}
+		}(device)
+	}
+	wg.Wait()
+	return nil
+}
+
+// scan lists the devices found by smartctl with their type, such as
+// "/dev/bus/0 -d megaraid,4" for the disks behind a RAID controller.
+func (d *DiskHealth) scan() ([]string, error) {
+	out, err := d.run("--scan", "--json")
+	if err != nil {
+		return nil, err
+	}
+	var scan struct {
+		Devices []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"devices"`
+	}
+	if err := json.Unmarshal(out, &scan); err != nil {
+		return nil, fmt.Errorf("parsing the scan of smartctl failed: %v", err)
+	}
+
+	var devices []string
+	for _, device := range scan.Devices {
+		if excluded(d.Excludes, device.Name) {
+			continue
+		}
+		if device.Type != "" {
+			devices = append(devices, device.Name+" -d "+device.Type)
+		} else {
+			devices = append(devices, device.Name)
+		}
+	}
+	return devices, nil
+}
+
+func (d *DiskHealth) gatherDevice(acc telegraf.Accumulator, device string) error {
+	args := []string{"--json", "--info", "--health", "--attributes", "--log=error", "-n", d.Nocheck}
+	args = append(args, strings.Fields(device)...)
+	out, err := d.run(args...)
+	if err != nil {
+		return err
+	}
+
+	var info smartctlOutput
+	if err := json.Unmarshal(out, &info); err != nil {
+		return fmt.Errorf("parsing the output of smartctl for %s failed: %v", device, err)
+	}
+	// The bit 1 is set for the disks skipped in a low power mode, as well as
+	// for those that could not be opened
+	if info.Smartctl.ExitStatus&0x02 != 0 {
+		var messages []string
+		for _, message := range info.Smartctl.Messages {
+			if strings.Contains(message.String, " mode, exit(") {
+				return nil
+			}
+			messages = append(messages, message.String)
+		}
+		return fmt.Errorf("smartctl failed to read %s: %s", device, strings.Join(messages, "; "))
+	}
+
+	tags, fields := parseDevice(&info)
+	acc.AddFields("disk_health", fields, tags)
+	return nil
+}
+
+// run runs smartctl.  Its exit status is a bit mask, only the bit 0 is an
+// error of the command line; the other bits report the failures to read the
+// device and the health of the disk, also given in the output.
+func (d *DiskHealth) run(args ...string) ([]byte, error) {
+	name := d.PathSmartctl
+	if d.UseSudo {
+		// -n - avoid prompting the user for input of any kind
+		args = append([]string{"-n", name}, args...)
+		name = "sudo"
+	}
+	cmd := execCommand(name, args...)
+	out, err := internal.StdOutputTimeout(cmd, d.Timeout.Duration)
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode()&0x01 == 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	return out, nil
+}
+
+func parseDevice(info *smartctlOutput) (map[string]string, map[string]interface{}) {
+	tags := map[string]string{
+		"device":   filepath.Base(info.Device.Name),
+		"protocol": info.Device.Protocol,
+	}
+	if model := info.ModelName; model != "" {
+		tags["model"] = model
+	} else if info.ScsiModel != "" {
+		tags["model"] = info.ScsiModel
+	}
+	if info.SerialNumber != "" {
+		tags["serial_no"] = info.SerialNumber
+	}
+	if wwn := deviceWWN(info); wwn != "" {
+		tags["wwn"] = wwn
+	}
+
+	fields := map[string]interface{}{
+		"exit_status": info.Smartctl.ExitStatus,
+	}
+	if info.SmartStatus != nil {
+		fields["health_ok"] = info.SmartStatus.Passed
+	}
+	if info.Temperature != nil {
+		fields["temperature"] = info.Temperature.Current
+	}
+	if info.PowerOnTime != nil {
+		fields["power_on_hours"] = info.PowerOnTime.Hours
+	}
+	for _, attr := range info.AtaSmartAttributes.Table {
+		if name, ok := ataAttributes[attr.ID]; ok {
+			fields[name] = attr.Raw.Value
+		}
+	}
+	if info.ScsiGrownDefectList != nil {
+		fields["grown_defects"] = *info.ScsiGrownDefectList
+	}
+	for _, op := range []string{"read", "write", "verify"} {
+		if counters, ok := info.ScsiErrorCounterLog[op]; ok {
+			fields[op+"_uncorrected_errors"] = counters.TotalUncorrectedErrors
+		}
+	}
+	if nvme := info.NVMeSmartHealth; nvme != nil {
+		fields["media_errors"] = nvme.MediaErrors
+		fields["percentage_used"] = nvme.PercentageUsed
+		fields["available_spare"] = nvme.AvailableSpare
+	}
+	return tags, fields
+}
+
+// deviceWWN returns the world wide name of the disk, as printed by smartctl
+// without spaces such as "5002538655584d30".  It is stable across reboots
+// unlike the device names.  NVMe drives have none, the EUI-64 of their
+// first namespace is used as in /dev/disk/by-id.
+func deviceWWN(info *smartctlOutput) string {
+	if wwn := info.WWN; wwn != nil {
+		return fmt.Sprintf("%x%06x%09x", wwn.NAA, wwn.OUI, wwn.ID)
+	}
+	if id := info.LogicalUnitID; id != "" {
+		return strings.TrimPrefix(strings.ToLower(id), "0x")
+	}
+	for _, ns := range info.NVMeNamespaces {
+		if ns.EUI64 != nil {
+			return fmt.Sprintf("eui.%06x%010x", ns.EUI64.OUI, ns.EUI64.ExtID)
+		}
+	}
+	return ""
+}
+
+func excluded(excludes []string, device string) bool {
+	for _, exclude := range excludes {
+		if device == exclude {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	inputs.Add("disk_health", func() telegraf.Input {
+		return &DiskHealth{
+			Nocheck: "standby",
+			Timeout: internal.Duration{Duration: 30 * time.Second},
+		}
+	})
+}
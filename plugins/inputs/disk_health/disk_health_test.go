@@ -0,0 +1,169 @@
+package disk_health
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	d := &DiskHealth{
+		PathSmartctl: "/usr/sbin/smartctl",
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		Excludes:     []string{"/dev/nvme0"},
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "disk_health",
+		map[string]interface{}{
+			"exit_status":           0,
+			"health_ok":             true,
+			"temperature":           int64(34),
+			"power_on_hours":        int64(26832),
+			"reallocated_sectors":   int64(8),
+			"pending_sectors":       int64(2),
+			"offline_uncorrectable": int64(1),
+			"udma_crc_errors":       int64(0),
+		},
+		map[string]string{
+			"device":    "sda",
+			"protocol":  "ATA",
+			"model":     "ST8000NM0055-1RM112",
+			"serial_no": "ZA1234AB",
+			"wwn":       "5000c500ad28be44",
+		})
+	acc.AssertContainsTaggedFields(t, "disk_health",
+		map[string]interface{}{
+			"exit_status":               32,
+			"health_ok":                 true,
+			"temperature":               int64(29),
+			"power_on_hours":            int64(17544),
+			"grown_defects":             int64(12),
+			"read_uncorrected_errors":   int64(0),
+			"write_uncorrected_errors":  int64(1),
+			"verify_uncorrected_errors": int64(0),
+		},
+		map[string]string{
+			"device":    "sdb",
+			"protocol":  "SCSI",
+			"model":     "HGST HUH721212AL5204",
+			"serial_no": "8HJ1ABCD",
+			"wwn":       "5000cca2a1b2c3d4",
+		})
+}
+
+func TestGatherNVMe(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	d := &DiskHealth{
+		PathSmartctl: "/usr/sbin/smartctl",
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		Devices:      []string{"/dev/nvme0 -d nvme"},
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "disk_health",
+		map[string]interface{}{
+			"exit_status":     0,
+			"health_ok":       true,
+			"temperature":     int64(37),
+			"power_on_hours":  int64(1234),
+			"media_errors":    int64(0),
+			"percentage_used": int64(3),
+			"available_spare": int64(100),
+		},
+		map[string]string{
+			"device":    "nvme0",
+			"protocol":  "NVMe",
+			"model":     "Samsung SSD 970 EVO Plus 1TB",
+			"serial_no": "S4EWNX0N123456",
+			"wwn":       "eui.0025385bd3fa89d0",
+		})
+}
+
+func TestGatherOpenFailed(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	d := &DiskHealth{
+		PathSmartctl: "/usr/sbin/smartctl",
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+		Devices:      []string{"/dev/sdz"},
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "No such device")
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the JSON output of smartctl for the queried device, exiting with
+// its exit status.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := strings.Join(os.Args, " ")
+	var file string
+	var status int
+	switch {
+	case strings.HasSuffix(args, "/usr/sbin/smartctl --scan --json"):
+		file = "scan.json"
+	case strings.HasSuffix(args, "--json --info --health --attributes --log=error -n standby /dev/sda -d sat"):
+		file = "sda.json"
+	case strings.HasSuffix(args, "--json --info --health --attributes --log=error -n standby /dev/sdb -d scsi"):
+		file, status = "sdb.json", 32
+	case strings.HasSuffix(args, "--json --info --health --attributes --log=error -n standby /dev/sdc -d sat"):
+		file, status = "sdc.json", 2
+	case strings.HasSuffix(args, "--json --info --health --attributes --log=error -n standby /dev/nvme0 -d nvme"):
+		file = "nvme0.json"
+	case strings.HasSuffix(args, "-n standby /dev/sdz"):
+		fmt.Fprint(os.Stdout, `{"smartctl": {"exit_status": 2, "messages": [{"string": "Smartctl open device: /dev/sdz failed: No such device", "severity": "error"}]}}`)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "unexpected arguments %q", args)
+		os.Exit(1)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(status)
+}
@@ -0,0 +1,267 @@
+package edac
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// locationPart matches the components of the location of a DIMM, e.g.
+// "channel 1 slot 0" or "csrow 2 channel 0".
+var locationPart = regexp.MustCompile(`([a-z]+) (\d+)`)
+
+// csrowChannel matches the per channel counters of the legacy csrow layout.
+var csrowChannel = regexp.MustCompile(`^ch(\d+)_ce_count$`)
+
+// EDAC stores the configuration values for the edac input plugin
+type EDAC struct {
+	Path               string `toml:"path"`
+	CEWarningThreshold uint64 `toml:"ce_warning_threshold"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// counts are the error counters of a channel.
+type counts struct {
+	ce, ue uint64
+	hasUE  bool
+}
+
+var sampleConfig = `
+  ## Path of the EDAC subsystem in sysfs.
+  # path = "/sys/devices/system/edac"
+
+  ## Number of corrected errors from which a DIMM is flagged with the
+  ## ce_warning field, e.g. the threshold of the replacement policy of the
+  ## vendor.  0 disables the flag.
+  # ce_warning_threshold = 0
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (e *EDAC) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (e *EDAC) Description() string {
+	return "Read the memory error counters of the memory controllers, channels and DIMMs from EDAC"
+}
+
+// Init sets the defaults.
+func (e *EDAC) Init() error {
+	if e.Path == "" {
+		e.Path = "/sys/devices/system/edac"
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (e *EDAC) Gather(acc telegraf.Accumulator) error {
+	mcs, err := filepath.Glob(filepath.Join(e.Path, "mc", "mc[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(mcs) == 0 {
+		return fmt.Errorf("no memory controller found in %s, is an EDAC driver loaded?", e.Path)
+	}
+	sort.Strings(mcs)
+
+	for _, dir := range mcs {
+		if err := e.gatherController(acc, dir); err != nil {
+			acc.AddError(fmt.Errorf("reading %s: %v", dir, err))
+		}
+	}
+	return nil
+}
+
+// gatherController adds the counters of the memory controller, of its
+// channels and of its DIMMs.
+func (e *EDAC) gatherController(acc telegraf.Accumulator, dir string) error {
+	tags := map[string]string{
+		"mc": strings.TrimPrefix(filepath.Base(dir), "mc"),
+	}
+	if name, err := readString(filepath.Join(dir, "mc_name")); err == nil {
+		tags["mc_name"] = name
+	}
+
+	fields := make(map[string]interface{})
+	for _, counter := range []string{"ce_count", "ue_count", "ce_noinfo_count", "ue_noinfo_count", "seconds_since_reset"} {
+		value, err := readUint(filepath.Join(dir, counter))
+		if err != nil {
+			if counter == "ce_count" || counter == "ue_count" {
+				return err
+			}
+			continue
+		}
+		fields[counter] = value
+	}
+	acc.AddFields("edac_mc", fields, tags)
+
+	// Current kernels list the DIMMs, or the ranks with drivers unaware of
+	// the DIMMs, while older ones only have the chip select rows.
+	dimms, err := filepath.Glob(filepath.Join(dir, "dimm[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(dimms) == 0 {
+		if dimms, err = filepath.Glob(filepath.Join(dir, "rank[0-9]*")); err != nil {
+			return err
+		}
+	}
+	if len(dimms) == 0 {
+		return e.gatherCSRows(acc, dir, tags)
+	}
+	sort.Strings(dimms)
+
+	channels := make(map[string]*counts)
+	for _, dimm := range dimms {
+		ch, err := e.gatherDIMM(acc, dimm, tags)
+		if err != nil {
+			acc.AddError(fmt.Errorf("reading %s: %v", dimm, err))
+			continue
+		}
+		if ch.channel == "" {
+			continue
+		}
+		c, ok := channels[ch.channel]
+		if !ok {
+			c = &counts{hasUE: true}
+			channels[ch.channel] = c
+		}
+		c.ce += ch.ce
+		c.ue += ch.ue
+	}
+	addChannels(acc, channels, tags)
+	return nil
+}
+
+// dimmCounts are the counters of a DIMM and its channel.
+type dimmCounts struct {
+	channel string
+	ce, ue  uint64
+}
+
+// gatherDIMM adds the counters of a DIMM, tagged with its label and the
+// components of its location.  The attributes of the ranks are named like
+// those of the DIMMs.
+func (e *EDAC) gatherDIMM(acc telegraf.Accumulator, dir string, mcTags map[string]string) (dimmCounts, error) {
+	var result dimmCounts
+	name := filepath.Base(dir)
+	ce, err := readUint(filepath.Join(dir, "dimm_ce_count"))
+	if err != nil {
+		return result, err
+	}
+	ue, err := readUint(filepath.Join(dir, "dimm_ue_count"))
+	if err != nil {
+		return result, err
+	}
+
+	tags := map[string]string{"dimm": name}
+	for k, v := range mcTags {
+		tags[k] = v
+	}
+	if label, err := readString(filepath.Join(dir, "dimm_label")); err == nil && label != "" {
+		tags["label"] = label
+	}
+	if location, err := readString(filepath.Join(dir, "dimm_location")); err == nil {
+		for _, m := range locationPart.FindAllStringSubmatch(location, -1) {
+			tags[m[1]] = m[2]
+		}
+	}
+
+	fields := map[string]interface{}{
+		"ce_count": ce,
+		"ue_count": ue,
+	}
+	if size, err := readUint(filepath.Join(dir, "size")); err == nil {
+		fields["size_mb"] = size
+	}
+	if e.CEWarningThreshold > 0 {
+		fields["ce_warning"] = ce >= e.CEWarningThreshold
+	}
+	acc.AddFields("edac_dimm", fields, tags)
+
+	return dimmCounts{channel: tags["channel"], ce: ce, ue: ue}, nil
+}
+
+// gatherCSRows adds the corrected errors of the channels from the legacy
+// chip select rows, which do not count the uncorrected errors per channel.
+func (e *EDAC) gatherCSRows(acc telegraf.Accumulator, dir string, mcTags map[string]string) error {
+	csrows, err := filepath.Glob(filepath.Join(dir, "csrow[0-9]*"))
+	if err != nil {
+		return err
+	}
+	channels := make(map[string]*counts)
+	for _, csrow := range csrows {
+		files, err := ioutil.ReadDir(csrow)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			m := csrowChannel.FindStringSubmatch(f.Name())
+			if m == nil {
+				continue
+			}
+			ce, err := readUint(filepath.Join(csrow, f.Name()))
+			if err != nil {
+				if !os.IsNotExist(err) {
+					e.Log.Debugf("Skipping %s: %v", f.Name(), err)
+				}
+				continue
+			}
+			c, ok := channels[m[1]]
+			if !ok {
+				c = &counts{}
+				channels[m[1]] = c
+			}
+			c.ce += ce
+		}
+	}
+	addChannels(acc, channels, mcTags)
+	return nil
+}
+
+// addChannels adds the counters summed per channel of a memory controller.
+func addChannels(acc telegraf.Accumulator, channels map[string]*counts, mcTags map[string]string) {
+	for channel, c := range channels {
+		tags := map[string]string{"channel": channel}
+		for k, v := range mcTags {
+			tags[k] = v
+		}
+		fields := map[string]interface{}{"ce_count": c.ce}
+		if c.hasUE {
+			fields["ue_count"] = c.ue
+		}
+		acc.AddFields("edac_channel", fields, tags)
+	}
+}
+
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("edac", func() telegraf.Input {
+		return &EDAC{}
+	})
+}
@@ -0,0 +1,98 @@
+package edac
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	e := &EDAC{Path: "testdata/dimms", CEWarningThreshold: 100, Log: testutil.Logger{}}
+	require.NoError(t, e.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 6)
+
+	acc.AssertContainsTaggedFields(t, "edac_mc",
+		map[string]interface{}{
+			"ce_count":            uint64(131),
+			"ue_count":            uint64(0),
+			"ce_noinfo_count":     uint64(1),
+			"ue_noinfo_count":     uint64(0),
+			"seconds_since_reset": uint64(86400),
+		},
+		map[string]string{"mc": "0", "mc_name": "Skylake Socket#0 IMC#0"})
+	acc.AssertContainsTaggedFields(t, "edac_dimm",
+		map[string]interface{}{
+			"ce_count":   uint64(120),
+			"ue_count":   uint64(0),
+			"size_mb":    uint64(32768),
+			"ce_warning": true,
+		},
+		map[string]string{
+			"mc":      "0",
+			"mc_name": "Skylake Socket#0 IMC#0",
+			"dimm":    "dimm0",
+			"label":   "CPU_SrcID#0_MC#0_Chan#0_DIMM#0",
+			"channel": "0",
+			"slot":    "0",
+		})
+	acc.AssertContainsTaggedFields(t, "edac_dimm",
+		map[string]interface{}{
+			"ce_count":   uint64(10),
+			"ue_count":   uint64(0),
+			"size_mb":    uint64(32768),
+			"ce_warning": false,
+		},
+		map[string]string{
+			"mc":      "0",
+			"mc_name": "Skylake Socket#0 IMC#0",
+			"dimm":    "dimm1",
+			"label":   "CPU_SrcID#0_MC#0_Chan#0_DIMM#1",
+			"channel": "0",
+			"slot":    "1",
+		})
+	acc.AssertContainsTaggedFields(t, "edac_channel",
+		map[string]interface{}{"ce_count": uint64(130), "ue_count": uint64(0)},
+		map[string]string{"mc": "0", "mc_name": "Skylake Socket#0 IMC#0", "channel": "0"})
+	acc.AssertContainsTaggedFields(t, "edac_channel",
+		map[string]interface{}{"ce_count": uint64(0), "ue_count": uint64(0)},
+		map[string]string{"mc": "0", "mc_name": "Skylake Socket#0 IMC#0", "channel": "1"})
+}
+
+func TestGatherCSRows(t *testing.T) {
+	e := &EDAC{Path: "testdata/csrows", Log: testutil.Logger{}}
+	require.NoError(t, e.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "edac_mc",
+		map[string]interface{}{"ce_count": uint64(7), "ue_count": uint64(1)},
+		map[string]string{"mc": "1", "mc_name": "i5000"})
+	acc.AssertContainsTaggedFields(t, "edac_channel",
+		map[string]interface{}{"ce_count": uint64(6)},
+		map[string]string{"mc": "1", "mc_name": "i5000", "channel": "0"})
+	acc.AssertContainsTaggedFields(t, "edac_channel",
+		map[string]interface{}{"ce_count": uint64(1)},
+		map[string]string{"mc": "1", "mc_name": "i5000", "channel": "1"})
+}
+
+func TestGatherNoController(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edac")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := &EDAC{Path: dir, Log: testutil.Logger{}}
+	require.NoError(t, e.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, e.Gather(&acc))
+}
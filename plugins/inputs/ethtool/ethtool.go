@@ -2,6 +2,7 @@ package ethtool
 
 import (
 	"net"
+	"regexp"
 
 	"github.com/influxdata/telegraf"
 )
@@ -20,6 +21,9 @@ type Ethtool struct {
 	// This is the list of interface names to ignore
 	InterfaceExclude []string `toml:"interface_exclude"`
 
+	// Report the counters of each priority in a separate measurement
+	PriorityStats bool `toml:"priority_stats"`
+
 	Log telegraf.Logger `toml:"-"`
 
 	// the ethtool command
@@ -30,6 +34,7 @@ const (
 	pluginName    = "ethtool"
 	tagInterface  = "interface"
 	tagDriverName = "driver"
+	tagPriority   = "priority"
 
 	sampleConfig = `
   ## List of interfaces to pull metrics for
@@ -37,6 +42,10 @@ const (
 
   ## List of interfaces to ignore when pulling metrics.
   # interface_exclude = ["eth1"]
+
+  ## Report the counters of each priority, such as the PFC pause frames and
+  ## the ECN marks of RoCE, in the ethtool_priority measurement.
+  # priority_stats = false
 `
 )
 
@@ -48,3 +57,55 @@ func (e *Ethtool) SampleConfig() string {
 func (e *Ethtool) Description() string {
 	return "Returns ethtool statistics for given interfaces"
 }
+
+// priorityPatterns match the per priority statistics of the drivers, with
+// the direction, priority and counter in the dir, prio and counter groups:
+// rx_prio3_pause of mlx5, rx_pfc_ena_frames_pri3 of bnxt_en and
+// rx_priority_3_xoff.nic of ice or port.rx_priority_3_xoff_rx of i40e.
+var priorityPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(?P<dir>rx|tx)_prio(?P<prio>[0-7])_(?P<counter>[a-z_]+)$`),
+	regexp.MustCompile(`^(?P<dir>rx|tx)_(?P<counter>[a-z_]+)_pri(?P<prio>[0-7])$`),
+	regexp.MustCompile(`^(?:port\.)?(?P<dir>rx|tx)_priority_(?P<prio>[0-7])_(?P<counter>xon|xoff)(?:_rx|_tx)?(?:\.nic)?$`),
+}
+
+// priorityCounters renames the counters of the drivers to common names.
+var priorityCounters = map[string]string{
+	"pfc_ena_frames": "pause",
+	"buf_discard":    "buf_discards",
+	"cong_discard":   "cong_discards",
+	"marked":         "ecn_marked",
+}
+
+// priorityStats groups the per priority statistics of an interface by
+// priority, with fields named after the direction and counter.
+func priorityStats(stats map[string]uint64) map[string]map[string]interface{} {
+	priorities := make(map[string]map[string]interface{})
+	for name, value := range stats {
+		for _, re := range priorityPatterns {
+			match := re.FindStringSubmatch(name)
+			if match == nil {
+				continue
+			}
+			var dir, prio, counter string
+			for i, group := range re.SubexpNames() {
+				switch group {
+				case "dir":
+					dir = match[i]
+				case "prio":
+					prio = match[i]
+				case "counter":
+					counter = match[i]
+				}
+			}
+			if renamed, ok := priorityCounters[counter]; ok {
+				counter = renamed
+			}
+			if priorities[prio] == nil {
+				priorities[prio] = make(map[string]interface{})
+			}
+			priorities[prio][dir+"_"+counter] = value
+			break
+		}
+	}
+	return priorities
+}
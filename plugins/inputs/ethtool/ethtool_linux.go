@@ -85,6 +85,18 @@ func (e *Ethtool) gatherEthtoolStats(iface net.Interface, acc telegraf.Accumulat
 	}
 
 	acc.AddFields(pluginName, fields, tags)
+
+	if !e.PriorityStats {
+		return
+	}
+	for priority, fields := range priorityStats(stats) {
+		ptags := map[string]string{
+			tagInterface:  iface.Name,
+			tagDriverName: driverName,
+			tagPriority:   priority,
+		}
+		acc.AddFields(pluginName+"_priority", fields, ptags)
+	}
 }
 
 func NewCommandEthtool() *CommandEthtool {
@@ -379,3 +379,68 @@ func TestGatherIgnoreInterfaces(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, pluginName, expectedFieldsEth2, expectedTagsEth2)
 
 }
+
+func TestGatherPriorityStats(t *testing.T) {
+
+	c := &CommandEthtoolMock{map[string]*InterfaceMock{
+		"ens1f0": {
+			Name:       "ens1f0",
+			DriverName: "mlx5_core",
+			Stat: map[string]uint64{
+				"rx_bytes":                  123456,
+				"rx_prio3_bytes":            1000,
+				"tx_prio3_bytes":            2000,
+				"rx_prio3_pause":            5,
+				"tx_prio3_pause":            7,
+				"rx_prio3_pause_duration":   12,
+				"rx_prio3_pause_transition": 2,
+				"rx_prio3_marked":           42,
+				"rx_prio3_cong_discard":     1,
+				"rx_prio0_bytes":            10,
+			},
+		},
+		"ens2f0np0": {
+			Name:       "ens2f0np0",
+			DriverName: "bnxt_en",
+			Stat: map[string]uint64{
+				"rx_bytes_pri3":          300,
+				"tx_pfc_ena_frames_pri3": 4,
+			},
+		},
+		"ens3f0": {
+			Name:       "ens3f0",
+			DriverName: "ice",
+			Stat: map[string]uint64{
+				"rx_priority_3_xoff.nic": 9,
+				"tx_priority_3_xon.nic":  8,
+			},
+		},
+	}}
+	e := &Ethtool{PriorityStats: true, command: c}
+
+	var acc testutil.Accumulator
+	assert.NoError(t, e.Gather(&acc))
+	assert.Len(t, acc.Metrics, 7)
+
+	acc.AssertContainsTaggedFields(t, "ethtool_priority",
+		map[string]interface{}{
+			"rx_bytes":            uint64(1000),
+			"tx_bytes":            uint64(2000),
+			"rx_pause":            uint64(5),
+			"tx_pause":            uint64(7),
+			"rx_pause_duration":   uint64(12),
+			"rx_pause_transition": uint64(2),
+			"rx_ecn_marked":       uint64(42),
+			"rx_cong_discards":    uint64(1),
+		},
+		map[string]string{"interface": "ens1f0", "driver": "mlx5_core", "priority": "3"})
+	acc.AssertContainsTaggedFields(t, "ethtool_priority",
+		map[string]interface{}{"rx_bytes": uint64(10)},
+		map[string]string{"interface": "ens1f0", "driver": "mlx5_core", "priority": "0"})
+	acc.AssertContainsTaggedFields(t, "ethtool_priority",
+		map[string]interface{}{"rx_bytes": uint64(300), "tx_pause": uint64(4)},
+		map[string]string{"interface": "ens2f0np0", "driver": "bnxt_en", "priority": "3"})
+	acc.AssertContainsTaggedFields(t, "ethtool_priority",
+		map[string]interface{}{"rx_xoff": uint64(9), "tx_xon": uint64(8)},
+		map[string]string{"interface": "ens3f0", "driver": "ice", "priority": "3"})
+}
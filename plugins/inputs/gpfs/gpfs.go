@@ -0,0 +1,236 @@
+package gpfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// ioFields maps the keywords of the counters of fs_io_s and io_s to field
+// names.
+var ioFields = map[string]string{
+	"_br_":  "bytes_read",
+	"_bw_":  "bytes_written",
+	"_oc_":  "opens",
+	"_cc_":  "closes",
+	"_rdc_": "reads",
+	"_wc_":  "writes",
+	"_dir_": "readdirs",
+	"_iu_":  "inode_updates",
+	"_d_":   "disks",
+}
+
+// 14:25:22.169738  R        data   1:1251507968      2048    0.883       cli  C0A80101:5C1B6E5B 192.168.1.2
+var iohistRegexp = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d+\s+([RW])\s+(\S+)\s+\S+\s+(\d+)\s+([\d.]+)\s`)
+
+type GPFS struct {
+	MmpmonPath string            `toml:"mmpmon_path"`
+	MmdiagPath string            `toml:"mmdiag_path"`
+	UseSudo    bool              `toml:"use_sudo"`
+	Timeout    internal.Duration `toml:"timeout"`
+	IOHist     bool              `toml:"iohist"`
+}
+
+var sampleConfig = `
+  ## Paths to the mmpmon and mmdiag executables.
+  # mmpmon_path = "/usr/lpp/mmfs/bin/mmpmon"
+  # mmdiag_path = "/usr/lpp/mmfs/bin/mmdiag"
+
+  ## Use sudo to run the commands, which require root.  Sudo must be
+  ## configured to allow the telegraf user to run them without a password.
+  # use_sudo = false
+
+  ## Timeout of each command.
+  # timeout = "10s"
+
+  ## Report the service time of the recent I/Os of the node from the I/O
+  ## history of mmdiag.
+  # iohist = false
+`
+
+func (g *GPFS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GPFS) Description() string {
+	return "Read the I/O statistics of IBM Spectrum Scale (GPFS) file systems through mmpmon"
+}
+
+func (g *GPFS) Init() error {
+	if g.MmpmonPath == "" {
+		g.MmpmonPath = "/usr/lpp/mmfs/bin/mmpmon"
+	}
+	if g.MmdiagPath == "" {
+		g.MmdiagPath = "/usr/lpp/mmfs/bin/mmdiag"
+	}
+	return nil
+}
+
+func (g *GPFS) Gather(acc telegraf.Accumulator) error {
+	if err := g.gatherMmpmon(acc); err != nil {
+		acc.AddError(err)
+	}
+	if g.IOHist {
+		if err := g.gatherIOHist(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func (g *GPFS) gatherMmpmon(acc telegraf.Accumulator) error {
+	// -p prints the keywords and values, -s suppresses the prompt
+	cmd := g.command(g.MmpmonPath, "-p", "-s")
+	cmd.Stdin = strings.NewReader("fs_io_s\nio_s\n")
+	out, err := internal.StdOutputTimeout(cmd, g.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+		var measurement string
+		switch parts[0] {
+		case "_fs_io_s_":
+			measurement = "gpfs_fs"
+		case "_io_s_":
+			measurement = "gpfs_io"
+		default:
+			continue
+		}
+
+		values := make(map[string]string)
+		for i := 1; i+1 < len(parts); i += 2 {
+			values[parts[i]] = parts[i+1]
+		}
+		// A non-zero return code, e.g. when no file system is mounted
+		if values["_rc_"] != "0" {
+			continue
+		}
+
+		tags := make(map[string]string)
+		if node := values["_nn_"]; node != "" {
+			tags["node"] = node
+		}
+		if measurement == "gpfs_fs" {
+			tags["cluster"] = values["_cl_"]
+			tags["filesystem"] = values["_fs_"]
+		}
+		fields := make(map[string]interface{})
+		for keyword, name := range ioFields {
+			if v, err := strconv.ParseUint(values[keyword], 10, 64); err == nil {
+				fields[name] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		tm := time.Now()
+		if sec, err := strconv.ParseInt(values["_t_"], 10, 64); err == nil {
+			usec, _ := strconv.ParseInt(values["_tu_"], 10, 64)
+			tm = time.Unix(sec, usec*1000)
+		}
+		acc.AddFields(measurement, fields, tags, tm)
+	}
+	return nil
+}
+
+type ioStats struct {
+	count   int
+	sectors uint64
+	sum     float64
+	max     float64
+}
+
+// gatherIOHist summarizes the I/O history of mmdiag, the last I/Os of the
+// node to the disks with their service time, by direction and buffer type.
+func (g *GPFS) gatherIOHist(acc telegraf.Accumulator) error {
+	cmd := g.command(g.MmdiagPath, "--iohist")
+	out, err := internal.StdOutputTimeout(cmd, g.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+
+	stats := make(map[[2]string]*ioStats)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		match := iohistRegexp.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		sectors, err := strconv.ParseUint(match[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		ms, err := strconv.ParseFloat(match[4], 64)
+		if err != nil {
+			continue
+		}
+		key := [2]string{match[1], match[2]}
+		s, ok := stats[key]
+		if !ok {
+			s = &ioStats{}
+			stats[key] = s
+		}
+		s.count++
+		s.sectors += sectors
+		s.sum += ms
+		if ms > s.max {
+			s.max = ms
+		}
+	}
+
+	now := time.Now()
+	for key, s := range stats {
+		direction := "read"
+		if key[0] == "W" {
+			direction = "write"
+		}
+		tags := map[string]string{
+			"direction": direction,
+			"buf_type":  key[1],
+		}
+		fields := map[string]interface{}{
+			"ios":         s.count,
+			"bytes":       s.sectors * 512,
+			"avg_time_ms": s.sum / float64(s.count),
+			"max_time_ms": s.max,
+		}
+		acc.AddFields("gpfs_iohist", fields, tags, now)
+	}
+	return nil
+}
+
+func (g *GPFS) command(name string, args ...string) *exec.Cmd {
+	if g.UseSudo {
+		// -n - avoid prompting the user for input of any kind
+		args = append([]string{"-n", name}, args...)
+		name = "sudo"
+	}
+	return execCommand(name, args...)
+}
+
+func init() {
+	inputs.Add("gpfs", func() telegraf.Input {
+		return &GPFS{
+			Timeout: internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
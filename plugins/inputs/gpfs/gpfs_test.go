@@ -0,0 +1,119 @@
+package gpfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	g := &GPFS{
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+		IOHist:  true,
+	}
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 6)
+
+	acc.AssertContainsTaggedFields(t, "gpfs_fs",
+		map[string]interface{}{
+			"bytes_read":    uint64(1073741824),
+			"bytes_written": uint64(536870912),
+			"opens":         uint64(1200),
+			"closes":        uint64(1190),
+			"reads":         uint64(4096),
+			"writes":        uint64(2048),
+			"readdirs":      uint64(30),
+			"inode_updates": uint64(400),
+			"disks":         uint64(12),
+		},
+		map[string]string{"node": "node01", "cluster": "hpc.example.org", "filesystem": "gpfs01"})
+	acc.AssertContainsTaggedFields(t, "gpfs_io",
+		map[string]interface{}{
+			"bytes_read":    uint64(1073743872),
+			"bytes_written": uint64(536870912),
+			"opens":         uint64(1203),
+			"closes":        uint64(1193),
+			"reads":         uint64(4098),
+			"writes":        uint64(2048),
+			"readdirs":      uint64(31),
+			"inode_updates": uint64(400),
+		},
+		map[string]string{"node": "node01"})
+	acc.AssertContainsTaggedFields(t, "gpfs_iohist",
+		map[string]interface{}{
+			"ios":         2,
+			"bytes":       uint64(2097152),
+			"avg_time_ms": 2.0,
+			"max_time_ms": 3.0,
+		},
+		map[string]string{"direction": "read", "buf_type": "data"})
+	acc.AssertContainsTaggedFields(t, "gpfs_iohist",
+		map[string]interface{}{
+			"ios":         1,
+			"bytes":       uint64(512),
+			"avg_time_ms": 0.25,
+			"max_time_ms": 0.25,
+		},
+		map[string]string{"direction": "write", "buf_type": "inode"})
+
+	m, ok := acc.Get("gpfs_fs")
+	require.True(t, ok)
+	require.Equal(t, time.Unix(1608026653, 250000000), m.Time)
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the output of mmpmon for the requests read on stdin or of mmdiag.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := strings.Join(os.Args, " ")
+	var file string
+	switch {
+	case strings.HasSuffix(args, "mmpmon -p -s"):
+		requests, _ := ioutil.ReadAll(os.Stdin)
+		if string(requests) != "fs_io_s\nio_s\n" {
+			fmt.Fprintf(os.Stderr, "unexpected requests %q", requests)
+			os.Exit(1)
+		}
+		file = "mmpmon.txt"
+	case strings.HasSuffix(args, "mmdiag --iohist"):
+		file = "iohist.txt"
+	default:
+		fmt.Fprintf(os.Stderr, "unexpected arguments %q", args)
+		os.Exit(1)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
@@ -0,0 +1,260 @@
+package gpu_jobs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+const (
+	defaultHostProc = "/proc"
+	envProc         = "HOST_PROC"
+)
+
+type GPUJobs struct {
+	Source       string            `toml:"source"`
+	NodeName     string            `toml:"node_name"`
+	ScontrolPath string            `toml:"scontrol_path"`
+	Timeout      internal.Duration `toml:"timeout"`
+	HostProc     string            `toml:"host_proc"`
+	JobVariable  string            `toml:"job_variable"`
+	GPUVariables []string          `toml:"gpu_variables"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// allocation is a GPU allocated to a job.
+type allocation struct {
+	gpu   string
+	jobID string
+	user  string
+}
+
+var sampleConfig = `
+  ## Source of the GPU allocations, one of
+  ##   slurm:   GRES allocations of running jobs reported by "scontrol"
+  ##   environ: environment of the running processes, requires access to
+  ##            /proc/<pid>/environ of all users
+  # source = "slurm"
+
+  ## Name of this node in Slurm, defaults to the short hostname
+  # node_name = ""
+
+  ## Path to the scontrol executable and timeout of the command
+  # scontrol_path = "/usr/bin/scontrol"
+  # timeout = "5s"
+
+  ## Sets 'proc' directory path, if not specified the default is /proc
+  # host_proc = "/proc"
+
+  ## Environment variable holding the job id of a process
+  # job_variable = "SLURM_JOB_ID"
+
+  ## Environment variables holding the GPU indices of a process, the first
+  ## one set is used.  Slurm renumbers CUDA_VISIBLE_DEVICES inside jobs
+  ## constrained to their devices, SLURM_JOB_GPUS holds the node's indices.
+  # gpu_variables = ["SLURM_JOB_GPUS", "CUDA_VISIBLE_DEVICES"]
+`
+
+func (g *GPUJobs) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GPUJobs) Description() string {
+	return "Map GPUs to the jobs they are allocated to"
+}
+
+func (g *GPUJobs) Init() error {
+	switch g.Source {
+	case "slurm":
+		if g.NodeName == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return err
+			}
+			g.NodeName = strings.SplitN(hostname, ".", 2)[0]
+		}
+		if g.ScontrolPath == "" {
+			path, err := exec.LookPath("scontrol")
+			if err != nil {
+				return fmt.Errorf("scontrol not found: verify that scontrol is installed and in your PATH (or specified in config): %v", err)
+			}
+			g.ScontrolPath = path
+		}
+	case "environ":
+		if g.HostProc == "" {
+			g.HostProc = os.Getenv(envProc)
+		}
+		if g.HostProc == "" {
+			g.HostProc = defaultHostProc
+		}
+		if g.JobVariable == "" {
+			return fmt.Errorf("job_variable must not be empty")
+		}
+		if len(g.GPUVariables) == 0 {
+			return fmt.Errorf("gpu_variables must not be empty")
+		}
+	default:
+		return fmt.Errorf("invalid source %q", g.Source)
+	}
+	return nil
+}
+
+func (g *GPUJobs) Gather(acc telegraf.Accumulator) error {
+	var allocations []allocation
+	var err error
+	switch g.Source {
+	case "slurm":
+		allocations, err = g.gatherSlurm()
+	case "environ":
+		allocations, err = g.gatherEnviron()
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, a := range allocations {
+		tags := gpuTags(a.gpu)
+		fields := map[string]interface{}{"job_id": a.jobID}
+		if a.user != "" {
+			fields["user"] = a.user
+		}
+		acc.AddFields("gpu_job", fields, tags, now)
+	}
+	return nil
+}
+
+func (g *GPUJobs) gatherSlurm() ([]allocation, error) {
+	cmd := execCommand(g.ScontrolPath, "show", "job", "--details", "--oneliner")
+	out, err := internal.CombinedOutputTimeout(cmd, g.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	return parseScontrol(out, g.NodeName), nil
+}
+
+// gpuTags returns the tags identifying the GPU or MIG device of an
+// allocation, listed by index, by GPU UUID, as MIG-GPU-<uuid>/<gi>/<ci> for
+// the MIG devices or by MIG device UUID since driver R470.
+func gpuTags(gpu string) map[string]string {
+	switch {
+	case strings.HasPrefix(gpu, "GPU-"):
+		return map[string]string{"uuid": gpu}
+	case strings.HasPrefix(gpu, "MIG-GPU-"):
+		parts := strings.Split(strings.TrimPrefix(gpu, "MIG-"), "/")
+		if len(parts) != 3 {
+			return map[string]string{"mig_uuid": gpu}
+		}
+		return map[string]string{
+			"uuid":             parts[0],
+			"gpu_instance":     parts[1],
+			"compute_instance": parts[2],
+		}
+	case strings.HasPrefix(gpu, "MIG-"):
+		return map[string]string{"mig_uuid": gpu}
+	}
+	return map[string]string{"index": gpu}
+}
+
+// gatherEnviron reads the job and GPUs of every process from its
+// environment, processes not belonging to a job are ignored.
+func (g *GPUJobs) gatherEnviron() ([]allocation, error) {
+	files, err := filepath.Glob(filepath.Join(g.HostProc, "[0-9]*", "environ"))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[allocation]bool)
+	var allocations []allocation
+	for _, file := range files {
+		// Processes may exit or deny access, skip them
+		environ, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		env := parseEnviron(environ)
+		jobID := env[g.JobVariable]
+		if jobID == "" {
+			continue
+		}
+		var gpus string
+		for _, name := range g.GPUVariables {
+			if gpus = env[name]; gpus != "" {
+				break
+			}
+		}
+		if gpus == "" || gpus == "NoDevFiles" {
+			continue
+		}
+
+		for _, gpu := range strings.Split(gpus, ",") {
+			a := allocation{gpu: strings.TrimSpace(gpu), jobID: jobID, user: env["SLURM_JOB_USER"]}
+			if a.gpu == "" || seen[a] {
+				continue
+			}
+			seen[a] = true
+			allocations = append(allocations, a)
+		}
+	}
+	return allocations, nil
+}
+
+// parseEnviron parses the NUL separated content of /proc/<pid>/environ.
+func parseEnviron(environ []byte) map[string]string {
+	env := make(map[string]string)
+	for _, kv := range bytes.Split(environ, []byte{0}) {
+		parts := strings.SplitN(string(kv), "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env
+}
+
+// expandIndices expands a list of indices and ranges, e.g. "0-2,5".
+func expandIndices(list string) ([]string, error) {
+	var indices []string
+	for _, part := range strings.Split(list, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		for i := first; i <= last; i++ {
+			indices = append(indices, strconv.Itoa(i))
+		}
+	}
+	return indices, nil
+}
+
+func init() {
+	inputs.Add("gpu_jobs", func() telegraf.Input {
+		return &GPUJobs{
+			Source:       "slurm",
+			Timeout:      internal.Duration{Duration: 5 * time.Second},
+			JobVariable:  "SLURM_JOB_ID",
+			GPUVariables: []string{"SLURM_JOB_GPUS", "CUDA_VISIBLE_DEVICES"},
+		}
+	})
+}
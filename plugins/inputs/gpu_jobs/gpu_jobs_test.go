@@ -0,0 +1,148 @@
+package gpu_jobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherSlurm(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	g := &GPUJobs{
+		Source:       "slurm",
+		NodeName:     "gpu01",
+		ScontrolPath: "scontrol",
+		Timeout:      internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(g.Gather))
+	require.Equal(t, uint64(3), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "gpu_job",
+		map[string]interface{}{"job_id": "1001", "user": "alice"},
+		map[string]string{"index": "0"})
+	acc.AssertContainsTaggedFields(t, "gpu_job",
+		map[string]interface{}{"job_id": "1001", "user": "alice"},
+		map[string]string{"index": "1"})
+	acc.AssertContainsTaggedFields(t, "gpu_job",
+		map[string]interface{}{"job_id": "1002", "user": "bob"},
+		map[string]string{"index": "3"})
+}
+
+func TestGatherEnviron(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpu_jobs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	processes := map[string][]string{
+		// Two processes of the same job
+		"100": {"SLURM_JOB_ID=2001", "SLURM_JOB_USER=alice", "SLURM_JOB_GPUS=0,1", "CUDA_VISIBLE_DEVICES=0,1"},
+		"101": {"SLURM_JOB_ID=2001", "SLURM_JOB_USER=alice", "SLURM_JOB_GPUS=0,1", "CUDA_VISIBLE_DEVICES=0"},
+		// Devices renumbered by the cgroup constraint
+		"200": {"SLURM_JOB_ID=2002", "SLURM_JOB_USER=bob", "SLURM_JOB_GPUS=3", "CUDA_VISIBLE_DEVICES=0"},
+		"300": {"CUDA_VISIBLE_DEVICES=2"},
+		"400": {"SLURM_JOB_ID=2003", "CUDA_VISIBLE_DEVICES=NoDevFiles"},
+		// MIG devices
+		"500": {"SLURM_JOB_ID=2004", "SLURM_JOB_USER=carol", "CUDA_VISIBLE_DEVICES=MIG-GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52/1/0"},
+		"600": {"SLURM_JOB_ID=2005", "SLURM_JOB_USER=dave", "CUDA_VISIBLE_DEVICES=MIG-c6d8e1b5-6d70-5ae6-a4a1-2a1e6a3bd8f1"},
+	}
+	for pid, env := range processes {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, pid), 0755))
+		environ := []byte(strings.Join(env, "\x00") + "\x00")
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, pid, "environ"), environ, 0644))
+	}
+
+	g := &GPUJobs{
+		Source:       "environ",
+		HostProc:     dir,
+		JobVariable:  "SLURM_JOB_ID",
+		GPUVariables: []string{"SLURM_JOB_GPUS", "CUDA_VISIBLE_DEVICES"},
+	}
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(g.Gather))
+	require.Equal(t, uint64(5), acc.NMetrics())
+	acc.AssertContainsTaggedFields(t, "gpu_job",
+		map[string]interface{}{"job_id": "2001", "user": "alice"},
+		map[string]string{"index": "0"})
+	acc.AssertContainsTaggedFields(t, "gpu_job",
+		map[string]interface{}{"job_id": "2001", "user": "alice"},
+		map[string]string{"index": "1"})
+	acc.AssertContainsTaggedFields(t, "gpu_job",
+		map[string]interface{}{"job_id": "2002", "user": "bob"},
+		map[string]string{"index": "3"})
+	acc.AssertContainsTaggedFields(t, "gpu_job",
+		map[string]interface{}{"job_id": "2004", "user": "carol"},
+		map[string]string{
+			"uuid":             "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+			"gpu_instance":     "1",
+			"compute_instance": "0",
+		})
+	acc.AssertContainsTaggedFields(t, "gpu_job",
+		map[string]interface{}{"job_id": "2005", "user": "dave"},
+		map[string]string{"mig_uuid": "MIG-c6d8e1b5-6d70-5ae6-a4a1-2a1e6a3bd8f1"})
+}
+
+func TestInitInvalidSource(t *testing.T) {
+	g := &GPUJobs{Source: "dcgm"}
+	require.Error(t, g.Init())
+}
+
+func TestContainsHost(t *testing.T) {
+	tests := []struct {
+		hostlist string
+		host     string
+		expected bool
+	}{
+		{"gpu01", "gpu01", true},
+		{"gpu01,gpu03", "gpu03", true},
+		{"gpu[01-04]", "gpu03", true},
+		{"gpu[01-04]", "gpu3", false},
+		{"gpu[01-04,08]", "gpu08", true},
+		{"gpu[01-04,08],cpu[1-2]", "cpu2", true},
+		{"gpu[01-04,08],cpu[1-2]", "gpu05", false},
+		{"(null)", "gpu01", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostlist+"/"+tt.host, func(t *testing.T) {
+			require.Equal(t, tt.expected, containsHost(tt.hostlist, tt.host))
+		})
+	}
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the output of scontrol.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	out, err := ioutil.ReadFile("testdata/scontrol.txt")
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
@@ -0,0 +1,122 @@
+package gpu_jobs
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gresGPURe matches the indices of the GPUs of a GRES allocation, e.g.
+// "gpu:a100:2(IDX:0-1)" or "gpu(IDX:0,2)".
+var gresGPURe = regexp.MustCompile(`gpu[^(,]*\(IDX:([0-9,\-]+)\)`)
+
+// parseScontrol returns the GPUs allocated on the node to running jobs from
+// the output of "scontrol show job --details --oneliner".  The details of a
+// job list the allocations per set of nodes:
+//
+//	Nodes=node[01-02] CPU_IDs=0-7 Mem=0 GRES=gpu:2(IDX:0-1)
+func parseScontrol(out []byte, node string) []allocation {
+	var allocations []allocation
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var jobID, user, state string
+		var onNode bool
+		for _, token := range strings.Fields(scanner.Text()) {
+			parts := strings.SplitN(token, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			key, value := parts[0], parts[1]
+			switch key {
+			case "JobId":
+				jobID = value
+			case "UserId":
+				user = strings.SplitN(value, "(", 2)[0]
+			case "JobState":
+				state = value
+			case "Nodes":
+				onNode = containsHost(value, node)
+			case "GRES", "GRES_IDX":
+				if !onNode || state != "RUNNING" {
+					continue
+				}
+				for _, match := range gresGPURe.FindAllStringSubmatch(value, -1) {
+					indices, err := expandIndices(match[1])
+					if err != nil {
+						continue
+					}
+					for _, gpu := range indices {
+						allocations = append(allocations, allocation{gpu: gpu, jobID: jobID, user: user})
+					}
+				}
+			}
+		}
+	}
+	return allocations
+}
+
+// containsHost returns true if the Slurm hostlist, e.g. "node[01-04,08],gpu1",
+// contains the host.
+func containsHost(hostlist, host string) bool {
+	for _, entry := range splitHostlist(hostlist) {
+		start := strings.Index(entry, "[")
+		if start < 0 || !strings.HasSuffix(entry, "]") {
+			if entry == host {
+				return true
+			}
+			continue
+		}
+
+		prefix := entry[:start]
+		if !strings.HasPrefix(host, prefix) {
+			continue
+		}
+		suffix := host[len(prefix):]
+		for _, r := range strings.Split(entry[start+1:len(entry)-1], ",") {
+			bounds := strings.SplitN(r, "-", 2)
+			if len(bounds) == 1 {
+				if suffix == bounds[0] {
+					return true
+				}
+				continue
+			}
+			// Ranges keep the zero padding of their bounds
+			if len(suffix) != len(bounds[0]) {
+				continue
+			}
+			n, err := strconv.Atoi(suffix)
+			if err != nil {
+				continue
+			}
+			first, err1 := strconv.Atoi(bounds[0])
+			last, err2 := strconv.Atoi(bounds[1])
+			if err1 == nil && err2 == nil && first <= n && n <= last {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitHostlist splits a hostlist at the commas outside of brackets.
+func splitHostlist(hostlist string) []string {
+	var entries []string
+	depth, start := 0, 0
+	for i, c := range hostlist {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				entries = append(entries, hostlist[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(entries, hostlist[start:])
+}
@@ -0,0 +1,301 @@
+package gpu_xid
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/shirou/gopsutil/host"
+)
+
+// bootTime is used to mock the boot time in tests.
+var bootTime = host.BootTime
+
+var (
+	// NVRM: Xid (PCI:0000:3b:00): 79, pid=1234, name=python3, GPU has fallen off the bus.
+	xidRegexp = regexp.MustCompile(`NVRM: Xid \(PCI:([0-9a-fA-F:.]+)\): (\d+), (.*)$`)
+	// NVRM: GPU at PCI:0000:3b:00: GPU-b850f46d-d5ea-c752-ddf3-c4453e44d3f7
+	gpuRegexp = regexp.MustCompile(`NVRM: GPU at PCI:([0-9a-fA-F:.]+): (GPU-[0-9a-fA-F-]+)`)
+	pidRegexp = regexp.MustCompile(`^pid=([^,]*), name=([^,]*), (.*)$`)
+)
+
+// xidCategory is a class of XID errors, with whether the GPU has to be
+// reset before it can run jobs again.
+type xidCategory struct {
+	name          string
+	resetRequired bool
+}
+
+// categories follows the XID catalog of the NVIDIA GPU deployment guide,
+// the other XIDs are in the "other" category.
+var categories = map[int]xidCategory{
+	13:  {"application", false},
+	31:  {"application", false},
+	43:  {"application", false},
+	45:  {"application", false},
+	48:  {"ecc_double_bit", true},
+	61:  {"microcontroller", true},
+	62:  {"microcontroller", true},
+	63:  {"row_remap", true},
+	64:  {"row_remap_failure", true},
+	74:  {"nvlink", true},
+	79:  {"fallen_off_bus", true},
+	92:  {"ecc_single_bit_rate", false},
+	94:  {"contained_ecc", false},
+	95:  {"uncontained_ecc", true},
+	119: {"gsp", true},
+	120: {"gsp", true},
+}
+
+// gpu is a GPU of the NVIDIA driver.
+type gpu struct {
+	uuid  string
+	model string
+	minor string
+}
+
+type GPUXid struct {
+	Path          string `toml:"path"`
+	DriverPath    string `toml:"driver_path"`
+	FromBeginning bool   `toml:"from_beginning"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	file *os.File
+	boot time.Time
+	gpus map[string]gpu
+	wg   sync.WaitGroup
+}
+
+var sampleConfig = `
+  ## Kernel log device, the records are read as they are logged.
+  # path = "/dev/kmsg"
+
+  ## Directory of the GPUs of the NVIDIA driver, used to find the UUID of the
+  ## GPUs by their PCI address.
+  # driver_path = "/proc/driver/nvidia/gpus"
+
+  ## Read the records logged since boot instead of only the new ones.
+  # from_beginning = false
+`
+
+func (g *GPUXid) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GPUXid) Description() string {
+	return "Emit an event for each XID error of the NVIDIA GPUs in the kernel log"
+}
+
+func (g *GPUXid) Init() error {
+	if g.Path == "" {
+		g.Path = "/dev/kmsg"
+	}
+	if g.DriverPath == "" {
+		g.DriverPath = "/proc/driver/nvidia/gpus"
+	}
+	return nil
+}
+
+func (g *GPUXid) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (g *GPUXid) Start(acc telegraf.Accumulator) error {
+	boot, err := bootTime()
+	if err != nil {
+		return fmt.Errorf("reading the boot time failed: %v", err)
+	}
+	g.boot = time.Unix(int64(boot), 0)
+	g.gpus = readGPUs(g.DriverPath)
+
+	g.file, err = os.Open(g.Path)
+	if err != nil {
+		return err
+	}
+	if !g.FromBeginning {
+		if _, err := g.file.Seek(0, io.SeekEnd); err != nil {
+			g.file.Close()
+			return err
+		}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.read(acc)
+	}()
+	return nil
+}
+
+func (g *GPUXid) Stop() {
+	if g.file == nil {
+		return
+	}
+	g.file.Close()
+	g.wg.Wait()
+	g.file = nil
+}
+
+// read reads the records until the file is closed, each read of /dev/kmsg
+// returns one record.  Reading fails with EPIPE when records were
+// overwritten before being read, the reading goes on with the oldest record
+// still available.
+func (g *GPUXid) read(acc telegraf.Accumulator) {
+	buf := make([]byte, 8192)
+	for {
+		n, err := g.file.Read(buf)
+		switch {
+		case errors.Is(err, syscall.EPIPE):
+			g.Log.Warnf("Kernel log records were overwritten before being read")
+			continue
+		case err == io.EOF || errors.Is(err, os.ErrClosed):
+			return
+		case err != nil:
+			acc.AddError(fmt.Errorf("reading %s failed: %v", g.Path, err))
+			return
+		}
+		for _, record := range strings.Split(string(buf[:n]), "\n") {
+			g.parse(acc, record)
+		}
+	}
+}
+
+// parse parses a record of /dev/kmsg, "<priority>,<sequence>,<timestamp in
+// microseconds since boot>,<flags>;<message>", the continuation lines of the
+// records start with a space and are ignored.
+func (g *GPUXid) parse(acc telegraf.Accumulator, record string) {
+	parts := strings.SplitN(record, ";", 2)
+	if len(parts) != 2 || strings.HasPrefix(record, " ") {
+		return
+	}
+	header := strings.Split(parts[0], ",")
+	if len(header) < 3 {
+		return
+	}
+	timestamp := time.Now()
+	if us, err := strconv.ParseInt(header[2], 10, 64); err == nil {
+		timestamp = g.boot.Add(time.Duration(us) * time.Microsecond)
+	}
+	message := parts[1]
+
+	// The driver logs the UUID of the GPUs after some XIDs, when the GPU
+	// may have fallen off the bus and is missing from the driver.
+	if m := gpuRegexp.FindStringSubmatch(message); m != nil {
+		bus := normalizeBus(m[1])
+		info := g.gpus[bus]
+		info.uuid = m[2]
+		g.gpus[bus] = info
+		return
+	}
+
+	m := xidRegexp.FindStringSubmatch(message)
+	if m == nil {
+		return
+	}
+	bus := normalizeBus(m[1])
+	xid, err := strconv.Atoi(m[2])
+	if err != nil {
+		return
+	}
+	category, ok := categories[xid]
+	if !ok {
+		category = xidCategory{name: "other"}
+	}
+
+	tags := map[string]string{
+		"pci_bus":  bus,
+		"xid":      m[2],
+		"category": category.name,
+	}
+	info, ok := g.gpus[bus]
+	if !ok || info.uuid == "" {
+		// The GPU may have been added since the start
+		g.gpus = readGPUs(g.DriverPath)
+		info = g.gpus[bus]
+	}
+	setTagIfUsed(tags, "uuid", info.uuid)
+	setTagIfUsed(tags, "name", info.model)
+	setTagIfUsed(tags, "index", info.minor)
+
+	fields := map[string]interface{}{
+		"reset_required": category.resetRequired,
+	}
+	detail := m[3]
+	if pm := pidRegexp.FindStringSubmatch(detail); pm != nil {
+		if pid, err := strconv.Atoi(pm[1]); err == nil {
+			fields["pid"] = pid
+		}
+		if pm[2] != "" && pm[2] != "<unknown>" {
+			fields["process_name"] = pm[2]
+		}
+		detail = pm[3]
+	}
+	fields["message"] = detail
+
+	acc.AddFields("gpu_xid", fields, tags, timestamp)
+}
+
+// readGPUs reads the UUID, model and minor number of the GPUs of the driver,
+// by PCI bus.
+func readGPUs(path string) map[string]gpu {
+	gpus := make(map[string]gpu)
+	files, _ := filepath.Glob(filepath.Join(path, "*", "information"))
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var info gpu
+		for _, line := range strings.Split(string(content), "\n") {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value := strings.TrimSpace(parts[1])
+			switch strings.TrimSpace(parts[0]) {
+			case "Model":
+				info.model = value
+			case "GPU UUID":
+				info.uuid = value
+			case "Device Minor":
+				info.minor = value
+			}
+		}
+		gpus[normalizeBus(filepath.Base(filepath.Dir(file)))] = info
+	}
+	return gpus
+}
+
+// normalizeBus returns the PCI bus of a GPU as printed in the XIDs, e.g.
+// "0000:3b:00", without the function of the driver's directories.
+func normalizeBus(bus string) string {
+	bus = strings.ToLower(bus)
+	if i := strings.LastIndex(bus, "."); i > 0 {
+		bus = bus[:i]
+	}
+	return bus
+}
+
+func setTagIfUsed(m map[string]string, k, v string) {
+	if v != "" {
+		m[k] = v
+	}
+}
+
+func init() {
+	inputs.Add("gpu_xid", func() telegraf.Input {
+		return &GPUXid{}
+	})
+}
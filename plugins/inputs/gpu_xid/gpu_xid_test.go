@@ -0,0 +1,138 @@
+package gpu_xid
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const information = `Model: 		 A100-SXM4-40GB
+IRQ:   		 130
+GPU UUID: 	 GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52
+Video BIOS: 	 92.00.19.00.01
+Bus Type: 	 PCIe
+DMA Size: 	 47 bits
+DMA Mask: 	 0x7fffffffffff
+Bus Location: 	 0000:07:00.0
+Device Minor: 	 0
+GPU Excluded:	 No
+`
+
+var records = []string{
+	"6,1000,50000000,-;usb 1-1: new high-speed USB device number 2 using xhci_hcd",
+	"4,1001,100000000,-;NVRM: Xid (PCI:0000:07:00): 63, pid='<unknown>', name=<unknown>, Row Remapper: New row (0x0000000000001234) marked for remapping, reset gpu to activate.",
+	" SUBSYSTEM=pci",
+	" DEVICE=+pci:0000:07:00.0",
+	"4,1002,200000000,-;NVRM: Xid (PCI:0000:86:00): 79, pid=2811, name=python3, GPU has fallen off the bus.",
+	"4,1003,200000500,-;NVRM: GPU at PCI:0000:86:00: GPU-b850f46d-d5ea-c752-ddf3-c4453e44d3f7",
+	"4,1004,300000000,-;NVRM: Xid (PCI:0000:86:00): 13, pid=2903, name=a.out, Graphics SM Warp Exception on (GPC 0, TPC 1, SM 0): Out Of Range Address",
+}
+
+func TestStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpu_xid")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kmsg := filepath.Join(dir, "kmsg")
+	require.NoError(t, ioutil.WriteFile(kmsg, []byte(strings.Join(records, "\n")+"\n"), 0644))
+	gpus := filepath.Join(dir, "gpus")
+	require.NoError(t, os.MkdirAll(filepath.Join(gpus, "0000:07:00.0"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(gpus, "0000:07:00.0", "information"), []byte(information), 0644))
+
+	defer func(f func() (uint64, error)) { bootTime = f }(bootTime)
+	bootTime = func() (uint64, error) { return 1600000000, nil }
+
+	g := &GPUXid{
+		Path:          kmsg,
+		DriverPath:    gpus,
+		FromBeginning: true,
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Start(&acc))
+	acc.Wait(3)
+	g.Stop()
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("gpu_xid",
+			map[string]string{
+				"pci_bus":  "0000:07:00",
+				"xid":      "63",
+				"category": "row_remap",
+				"uuid":     "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"name":     "A100-SXM4-40GB",
+				"index":    "0",
+			},
+			map[string]interface{}{
+				"reset_required": true,
+				"message":        "Row Remapper: New row (0x0000000000001234) marked for remapping, reset gpu to activate.",
+			},
+			time.Unix(1600000100, 0)),
+		testutil.MustMetric("gpu_xid",
+			map[string]string{
+				"pci_bus":  "0000:86:00",
+				"xid":      "79",
+				"category": "fallen_off_bus",
+			},
+			map[string]interface{}{
+				"reset_required": true,
+				"pid":            2811,
+				"process_name":   "python3",
+				"message":        "GPU has fallen off the bus.",
+			},
+			time.Unix(1600000200, 0)),
+		testutil.MustMetric("gpu_xid",
+			map[string]string{
+				"pci_bus":  "0000:86:00",
+				"xid":      "13",
+				"category": "application",
+				"uuid":     "GPU-b850f46d-d5ea-c752-ddf3-c4453e44d3f7",
+			},
+			map[string]interface{}{
+				"reset_required": false,
+				"pid":            2903,
+				"process_name":   "a.out",
+				"message":        "Graphics SM Warp Exception on (GPC 0, TPC 1, SM 0): Out Of Range Address",
+			},
+			time.Unix(1600000300, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestStartNewRecordsOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpu_xid")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kmsg := filepath.Join(dir, "kmsg")
+	require.NoError(t, ioutil.WriteFile(kmsg, []byte(strings.Join(records, "\n")+"\n"), 0644))
+
+	g := &GPUXid{
+		Path:       kmsg,
+		DriverPath: filepath.Join(dir, "gpus"),
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, g.Start(&acc))
+	g.Stop()
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestStartMissingDevice(t *testing.T) {
+	g := &GPUXid{Path: "/nonexistent/kmsg"}
+	require.NoError(t, g.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, g.Start(&acc))
+}
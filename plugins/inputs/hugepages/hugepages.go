@@ -0,0 +1,231 @@
+package hugepages
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	defaultHostProc = "/proc"
+	defaultHostSys  = "/sys"
+	envProc         = "HOST_PROC"
+	envSys          = "HOST_SYS"
+)
+
+// poolFields are the fields of the files of a hugetlb pool.
+var poolFields = map[string]string{
+	"nr_hugepages":            "total",
+	"free_hugepages":          "free",
+	"resv_hugepages":          "reserved",
+	"surplus_hugepages":       "surplus",
+	"nr_overcommit_hugepages": "overcommit",
+}
+
+// meminfoFields are the sizes of the transparent huge pages in meminfo.
+var meminfoFields = map[string]string{
+	"AnonHugePages":  "anon_huge_pages",
+	"ShmemHugePages": "shmem_huge_pages",
+	"FileHugePages":  "file_huge_pages",
+}
+
+type Hugepages struct {
+	HostProc string `toml:"host_proc"`
+	HostSys  string `toml:"host_sys"`
+	PerNode  bool   `toml:"per_node"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## Sets 'proc' directory path, if not specified the default is /proc
+  # host_proc = "/proc"
+
+  ## Sets 'sys' directory path, if not specified the default is /sys
+  # host_sys = "/sys"
+
+  ## Report the hugetlb pools of each NUMA node.
+  # per_node = false
+`
+
+func (h *Hugepages) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *Hugepages) Description() string {
+	return "Read the usage of the hugetlbfs pools and the transparent huge page counters"
+}
+
+func (h *Hugepages) Init() error {
+	if h.HostProc == "" {
+		h.HostProc = os.Getenv(envProc)
+	}
+	if h.HostProc == "" {
+		h.HostProc = defaultHostProc
+	}
+	if h.HostSys == "" {
+		h.HostSys = os.Getenv(envSys)
+	}
+	if h.HostSys == "" {
+		h.HostSys = defaultHostSys
+	}
+	return nil
+}
+
+func (h *Hugepages) Gather(acc telegraf.Accumulator) error {
+	if err := h.gatherPools(acc, filepath.Join(h.HostSys, "kernel", "mm", "hugepages"), nil); err != nil {
+		acc.AddError(err)
+	}
+	if h.PerNode {
+		nodes, err := filepath.Glob(filepath.Join(h.HostSys, "devices", "system", "node", "node[0-9]*"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(nodes)
+		for _, node := range nodes {
+			tags := map[string]string{"node": strings.TrimPrefix(filepath.Base(node), "node")}
+			if err := h.gatherPools(acc, filepath.Join(node, "hugepages"), tags); err != nil {
+				acc.AddError(err)
+			}
+		}
+	}
+	if err := h.gatherTHP(acc); err != nil {
+		acc.AddError(err)
+	}
+	return nil
+}
+
+// gatherPools reads the pools of each huge page size in a hugepages
+// directory, of the system or of a node when tags are given.
+func (h *Hugepages) gatherPools(acc telegraf.Accumulator, dir string, nodeTags map[string]string) error {
+	pools, err := filepath.Glob(filepath.Join(dir, "hugepages-*kB"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(pools)
+
+	for _, pool := range pools {
+		size := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(pool), "hugepages-"), "kB")
+		fields := make(map[string]interface{})
+		for file, name := range poolFields {
+			// The nodes only have the total, free and surplus pages
+			if v, err := readInt(filepath.Join(pool, file)); err == nil {
+				fields[name] = v
+			}
+		}
+		total, ok1 := fields["total"].(int64)
+		free, ok2 := fields["free"].(int64)
+		if !ok1 || !ok2 {
+			return fmt.Errorf("reading %s: missing nr_hugepages or free_hugepages", pool)
+		}
+		fields["used"] = total - free
+
+		tags := map[string]string{"size_kb": size}
+		measurement := "hugepages"
+		if nodeTags != nil {
+			for k, v := range nodeTags {
+				tags[k] = v
+			}
+			measurement = "hugepages_per_node"
+		}
+		acc.AddFields(measurement, fields, tags)
+	}
+	return nil
+}
+
+// gatherTHP reads the settings of the transparent huge pages, the activity
+// of khugepaged collapsing small pages into huge pages, the thp_* counters
+// of /proc/vmstat and the sizes of the huge pages in /proc/meminfo.
+func (h *Hugepages) gatherTHP(acc telegraf.Accumulator) error {
+	dir := filepath.Join(h.HostSys, "kernel", "mm", "transparent_hugepage")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		// Kernel built without transparent huge pages
+		return nil
+	}
+
+	fields := make(map[string]interface{})
+	for _, name := range []string{"enabled", "defrag", "shmem_enabled"} {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, name)); err == nil {
+			fields[name] = selected(string(data))
+		}
+	}
+	for _, name := range []string{"pages_collapsed", "full_scans"} {
+		if v, err := readInt(filepath.Join(dir, "khugepaged", name)); err == nil {
+			fields["khugepaged_"+name] = v
+		}
+	}
+
+	err := readPairs(filepath.Join(h.HostProc, "vmstat"), func(name string, value int64) {
+		if strings.HasPrefix(name, "thp_") {
+			fields[name] = value
+		}
+	})
+	if err != nil {
+		return err
+	}
+	err = readPairs(filepath.Join(h.HostProc, "meminfo"), func(name string, value int64) {
+		if field, ok := meminfoFields[strings.TrimSuffix(name, ":")]; ok {
+			fields[field] = value * 1024
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	acc.AddFields("hugepages_thp", fields, map[string]string{})
+	return nil
+}
+
+// selected returns the setting selected among the choices of a file of the
+// transparent huge pages, e.g. "madvise" for "always [madvise] never".
+func selected(choices string) string {
+	for _, choice := range strings.Fields(choices) {
+		if strings.HasPrefix(choice, "[") && strings.HasSuffix(choice, "]") {
+			return strings.Trim(choice, "[]")
+		}
+	}
+	return strings.TrimSpace(choices)
+}
+
+// readPairs calls fn with the name and value of each line of a file
+// starting with a name followed by an integer, such as /proc/vmstat.
+func readPairs(file string, fn func(string, int64)) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 {
+			continue
+		}
+		if value, err := strconv.ParseInt(parts[1], 10, 64); err == nil {
+			fn(parts[0], value)
+		}
+	}
+	return scanner.Err()
+}
+
+func readInt(file string) (int64, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func init() {
+	inputs.Add("hugepages", func() telegraf.Input {
+		return &Hugepages{}
+	})
+}
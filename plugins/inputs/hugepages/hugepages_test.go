@@ -0,0 +1,84 @@
+package hugepages
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	h := &Hugepages{
+		HostProc: "testdata/proc",
+		HostSys:  "testdata/sys",
+		PerNode:  true,
+	}
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 5)
+
+	acc.AssertContainsTaggedFields(t, "hugepages",
+		map[string]interface{}{
+			"total":      int64(1024),
+			"free":       int64(256),
+			"reserved":   int64(16),
+			"surplus":    int64(0),
+			"overcommit": int64(0),
+			"used":       int64(768),
+		},
+		map[string]string{"size_kb": "2048"})
+	acc.AssertContainsTaggedFields(t, "hugepages",
+		map[string]interface{}{
+			"total":      int64(4),
+			"free":       int64(4),
+			"reserved":   int64(0),
+			"surplus":    int64(0),
+			"overcommit": int64(0),
+			"used":       int64(0),
+		},
+		map[string]string{"size_kb": "1048576"})
+	acc.AssertContainsTaggedFields(t, "hugepages_per_node",
+		map[string]interface{}{
+			"total":   int64(512),
+			"free":    int64(64),
+			"surplus": int64(0),
+			"used":    int64(448),
+		},
+		map[string]string{"size_kb": "2048", "node": "0"})
+	acc.AssertContainsTaggedFields(t, "hugepages_thp",
+		map[string]interface{}{
+			"enabled":                    "madvise",
+			"defrag":                     "madvise",
+			"shmem_enabled":              "never",
+			"khugepaged_pages_collapsed": int64(1874),
+			"khugepaged_full_scans":      int64(312),
+			"thp_fault_alloc":            int64(48213),
+			"thp_fault_fallback":         int64(1320),
+			"thp_collapse_alloc":         int64(1874),
+			"thp_collapse_alloc_failed":  int64(12),
+			"thp_split_page":             int64(204),
+			"anon_huge_pages":            int64(8388608 * 1024),
+			"shmem_huge_pages":           int64(0),
+			"file_huge_pages":            int64(63488 * 1024),
+		},
+		map[string]string{})
+}
+
+func TestGatherWithoutPerNode(t *testing.T) {
+	h := &Hugepages{HostProc: "testdata/proc", HostSys: "testdata/sys"}
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+}
+
+func TestSelected(t *testing.T) {
+	require.Equal(t, "madvise", selected("always [madvise] never\n"))
+	require.Equal(t, "defer+madvise", selected("always defer [defer+madvise] madvise never"))
+	require.Equal(t, "always", selected("always\n"))
+}
@@ -0,0 +1,266 @@
+package hwmon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// sensorAttr matches the attribute files of the sensors, e.g. temp1_input.
+var sensorAttr = regexp.MustCompile(`^([a-z]+)(\d+)_([a-z_]+)$`)
+
+// sensorType is a type of hwmon sensor with the divisor converting its raw
+// values to the unit of its fields.
+type sensorType struct {
+	unit    string
+	divisor float64
+}
+
+var sensorTypes = map[string]sensorType{
+	"temp":     {unit: "celsius", divisor: 1000},
+	"in":       {unit: "volts", divisor: 1000},
+	"curr":     {unit: "amperes", divisor: 1000},
+	"power":    {unit: "watts", divisor: 1000000},
+	"energy":   {unit: "joules", divisor: 1000000},
+	"humidity": {unit: "percent", divisor: 1000},
+	"fan":      {unit: "rpm", divisor: 1},
+}
+
+// thresholds are the limit attributes reported besides the input.
+var thresholds = map[string]bool{
+	"min":       true,
+	"max":       true,
+	"lcrit":     true,
+	"crit":      true,
+	"emergency": true,
+}
+
+// Hwmon stores the configuration values for the hwmon input plugin
+type Hwmon struct {
+	Path         string   `toml:"path"`
+	ChipsInclude []string `toml:"chips_include"`
+	ChipsExclude []string `toml:"chips_exclude"`
+	Thresholds   bool     `toml:"thresholds"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	chipFilter filter.Filter
+}
+
+// sensor is a sensor of a chip with its attributes by name.
+type sensor struct {
+	kind  string
+	index int
+	attrs map[string]string
+}
+
+var sampleConfig = `
+  ## Path of the hwmon class in sysfs.
+  # path = "/sys/class/hwmon"
+
+  ## Chips to gather by the name of their driver, e.g. "coretemp",
+  ## "k10temp" or "nct6775", by default all.
+  # chips_include = []
+  # chips_exclude = ["acpitz"]
+
+  ## Add the min, max, lcrit, crit and emergency limits of the sensors.
+  # thresholds = false
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (h *Hwmon) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (h *Hwmon) Description() string {
+	return "Read the hardware monitoring sensors from sysfs, named by their labels"
+}
+
+// Init compiles the chip filter.
+func (h *Hwmon) Init() error {
+	if h.Path == "" {
+		h.Path = "/sys/class/hwmon"
+	}
+	var err error
+	if h.chipFilter, err = filter.NewIncludeExcludeFilter(h.ChipsInclude, h.ChipsExclude); err != nil {
+		return fmt.Errorf("invalid chip filter: %v", err)
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (h *Hwmon) Gather(acc telegraf.Accumulator) error {
+	dirs, err := filepath.Glob(filepath.Join(h.Path, "hwmon*"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		name, err := readString(filepath.Join(dir, "name"))
+		if err != nil {
+			// Older drivers keep their attributes in the device directory
+			if name, err = readString(filepath.Join(dir, "device", "name")); err != nil {
+				h.Log.Debugf("Skipping %s without a name", dir)
+				continue
+			}
+			dir = filepath.Join(dir, "device")
+		}
+		if !h.chipFilter.Match(name) {
+			continue
+		}
+
+		fields, err := h.readChip(dir)
+		if err != nil {
+			acc.AddError(fmt.Errorf("reading chip %s: %v", name, err))
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		tags := map[string]string{"chip": name}
+		if device, err := filepath.EvalSymlinks(filepath.Join(dir, "device")); err == nil {
+			tags["device"] = filepath.Base(device)
+		}
+		acc.AddFields("hwmon", fields, tags)
+	}
+	return nil
+}
+
+// readChip reads the sensors of a chip into fields named by their labels.
+// Sensors without a label, and the later sensors sharing a label with
+// another one, are named after their attribute files, e.g. temp1.
+func (h *Hwmon) readChip(dir string) (map[string]interface{}, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sensors := make(map[string]*sensor)
+	for _, f := range files {
+		m := sensorAttr.FindStringSubmatch(f.Name())
+		if m == nil {
+			continue
+		}
+		if _, ok := sensorTypes[m[1]]; !ok {
+			continue
+		}
+		id := m[1] + m[2]
+		s, ok := sensors[id]
+		if !ok {
+			index, _ := strconv.Atoi(m[2])
+			s = &sensor{kind: m[1], index: index, attrs: make(map[string]string)}
+			sensors[id] = s
+		}
+		s.attrs[m[3]] = filepath.Join(dir, f.Name())
+	}
+
+	ids := make([]string, 0, len(sensors))
+	for id := range sensors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := sensors[ids[i]], sensors[ids[j]]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.index < b.index
+	})
+
+	fields := make(map[string]interface{})
+	used := make(map[string]bool)
+	for _, id := range ids {
+		s := sensors[id]
+		name := id
+		if path, ok := s.attrs["label"]; ok {
+			if label, err := readString(path); err == nil && sanitize(label) != "" && !used[sanitize(label)] {
+				name = sanitize(label)
+			}
+		}
+		used[name] = true
+		h.readSensor(s, name, fields)
+	}
+	return fields, nil
+}
+
+// readSensor adds the value of the sensor, and its limits if enabled, scaled
+// to the unit of its type.
+func (h *Hwmon) readSensor(s *sensor, name string, fields map[string]interface{}) {
+	st := sensorTypes[s.kind]
+	for attr, path := range s.attrs {
+		var field string
+		switch {
+		case attr == "input":
+			field = name + "_" + st.unit
+		case attr == "alarm":
+			if value, err := readInt(path); err == nil {
+				fields[name+"_alarm"] = value != 0
+			}
+			continue
+		case h.Thresholds && thresholds[attr]:
+			field = name + "_" + attr + "_" + st.unit
+		default:
+			continue
+		}
+
+		value, err := readInt(path)
+		if err != nil {
+			// Sensors of disabled inputs fail to read
+			if !os.IsNotExist(err) {
+				h.Log.Debugf("Skipping %s: %v", path, err)
+			}
+			continue
+		}
+		fields[field] = float64(value) / st.divisor
+	}
+}
+
+// sanitize turns a sensor label into a field name, e.g. "Package id 0"
+// becomes "package_id_0".
+func sanitize(label string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(label) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			underscore = false
+			b.WriteRune(r)
+			continue
+		}
+		underscore = true
+	}
+	return b.String()
+}
+
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readInt(path string) (int64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("hwmon", func() telegraf.Input {
+		return &Hwmon{}
+	})
+}
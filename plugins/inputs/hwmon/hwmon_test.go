@@ -0,0 +1,100 @@
+package hwmon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	h := &Hwmon{
+		Path:         "testdata/hwmon",
+		ChipsExclude: []string{"acpi*"},
+		Thresholds:   true,
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "hwmon",
+		map[string]interface{}{
+			"package_id_0_celsius":      45.0,
+			"package_id_0_max_celsius":  80.0,
+			"package_id_0_crit_celsius": 100.0,
+			"core_0_celsius":            43.0,
+			"temp3_celsius":             -1.5,
+		},
+		map[string]string{"chip": "coretemp"})
+	acc.AssertContainsTaggedFields(t, "hwmon",
+		map[string]interface{}{
+			"in0_volts":     1.2,
+			"cpu_fan_rpm":   1500.0,
+			"cpu_fan_alarm": true,
+			"power1_watts":  95.5,
+		},
+		map[string]string{"chip": "nct6775"})
+	acc.AssertContainsTaggedFields(t, "hwmon",
+		map[string]interface{}{"temp1_celsius": 30.0},
+		map[string]string{"chip": "w83627hf"})
+}
+
+func TestGatherInclude(t *testing.T) {
+	h := &Hwmon{
+		Path:         "testdata/hwmon",
+		ChipsInclude: []string{"coretemp"},
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "hwmon",
+		map[string]interface{}{
+			"package_id_0_celsius": 45.0,
+			"core_0_celsius":       43.0,
+			"temp3_celsius":        -1.5,
+		},
+		map[string]string{"chip": "coretemp"})
+}
+
+// Symbolic links are not checked in, they break checkouts on Windows
+func TestGatherDeviceTag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hwmon")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	device := filepath.Join(dir, "devices", "coretemp.0")
+	chip := filepath.Join(dir, "hwmon", "hwmon0")
+	require.NoError(t, os.MkdirAll(device, 0755))
+	require.NoError(t, os.MkdirAll(chip, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(chip, "name"), []byte("coretemp\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(chip, "temp1_input"), []byte("45000\n"), 0644))
+	require.NoError(t, os.Symlink(device, filepath.Join(chip, "device")))
+
+	h := &Hwmon{Path: filepath.Join(dir, "hwmon"), Log: testutil.Logger{}}
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "hwmon",
+		map[string]interface{}{"temp1_celsius": 45.0},
+		map[string]string{"chip": "coretemp", "device": "coretemp.0"})
+}
+
+func TestSanitize(t *testing.T) {
+	require.Equal(t, "package_id_0", sanitize("Package id 0"))
+	require.Equal(t, "vcore", sanitize(" Vcore "))
+	require.Equal(t, "cpu_fan_1", sanitize("CPU-Fan #1"))
+	require.Equal(t, "", sanitize("--"))
+}
@@ -0,0 +1,252 @@
+package ib_fabric
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+type IBFabric struct {
+	Source string `toml:"source"`
+
+	SminfoPath     string            `toml:"sminfo_path"`
+	IblinkinfoPath string            `toml:"iblinkinfo_path"`
+	UseSudo        bool              `toml:"use_sudo"`
+	Timeout        internal.Duration `toml:"timeout"`
+
+	URL      string `toml:"url"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+	// links holds the links up in the previous gathers by switch port, the
+	// links no longer up are missing.
+	links    map[string]*link
+	gathered bool
+	changes  uint64
+}
+
+// link is a link of a switch port.
+type link struct {
+	switchName string
+	switchGUID string
+	port       string
+	peer       string
+	peerPort   string
+	up         bool
+	degraded   bool
+}
+
+// fabric is the state of the fabric read from the subnet manager.
+type fabric struct {
+	sm       map[string]interface{}
+	smTags   map[string]string
+	switches map[string]*fabricSwitch
+	links    map[string]*link
+}
+
+// fabricSwitch holds the fields of a switch, those read from the subnet
+// manager and the link counts.
+type fabricSwitch struct {
+	name   string
+	guid   string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+var sampleConfig = `
+  ## Source of the fabric state, one of
+  ##   opensm: the SM and the links of the switches queried with sminfo and
+  ##           iblinkinfo of infiniband-diags
+  ##   ufm:    the switches and links of the NVIDIA UFM REST API
+  # source = "opensm"
+
+  ## Paths to the executables of infiniband-diags for the opensm source.
+  # sminfo_path = "/usr/sbin/sminfo"
+  # iblinkinfo_path = "/usr/sbin/iblinkinfo"
+
+  ## Timeout of each command or request to UFM.
+  # timeout = "30s"
+
+  ## Use sudo to run the infiniband-diags, which require access to the umad
+  ## devices.  Sudo must be configured to allow the telegraf user to run
+  ## them without a password.
+  # use_sudo = false
+
+  ## URL and credentials of UFM for the ufm source.
+  # url = "https://ufm.example.org"
+  # username = "admin"
+  # password = "123456"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (f *IBFabric) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *IBFabric) Description() string {
+	return "Read the health of the InfiniBand fabric from the subnet manager or UFM"
+}
+
+func (f *IBFabric) Init() error {
+	switch f.Source {
+	case "opensm":
+		if f.SminfoPath == "" {
+			f.SminfoPath = "/usr/sbin/sminfo"
+		}
+		if f.IblinkinfoPath == "" {
+			f.IblinkinfoPath = "/usr/sbin/iblinkinfo"
+		}
+	case "ufm":
+		if f.URL == "" {
+			return fmt.Errorf("url must not be empty")
+		}
+		tlsCfg, err := f.ClientConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		f.client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsCfg,
+			},
+			Timeout: f.Timeout.Duration,
+		}
+	default:
+		return fmt.Errorf("invalid source %q", f.Source)
+	}
+	f.links = make(map[string]*link)
+	return nil
+}
+
+func (f *IBFabric) Gather(acc telegraf.Accumulator) error {
+	var state *fabric
+	var err error
+	switch f.Source {
+	case "opensm":
+		state, err = f.gatherOpenSM()
+	case "ufm":
+		state, err = f.gatherUFM()
+	}
+	if err != nil {
+		return err
+	}
+
+	missing := f.updateLinks(state.links)
+	now := time.Now()
+
+	fields := map[string]interface{}{
+		"switches":         len(state.switches),
+		"links_up":         0,
+		"links_degraded":   0,
+		"links_missing":    len(missing),
+		"topology_changes": f.changes,
+	}
+	for k, v := range state.sm {
+		fields[k] = v
+	}
+	for _, l := range state.links {
+		if !l.up {
+			continue
+		}
+		fields["links_up"] = fields["links_up"].(int) + 1
+		if l.degraded {
+			fields["links_degraded"] = fields["links_degraded"].(int) + 1
+		}
+	}
+	acc.AddFields("ib_fabric", fields, state.smTags, now)
+
+	for _, sw := range state.switches {
+		tags := map[string]string{"switch": sw.name, "switch_guid": sw.guid}
+		for k, v := range sw.tags {
+			tags[k] = v
+		}
+		acc.AddFields("ib_fabric_switch", sw.fields, tags, now)
+	}
+
+	for _, l := range missing {
+		tags := map[string]string{
+			"switch":      l.switchName,
+			"switch_guid": l.switchGUID,
+			"port":        l.port,
+		}
+		fields := map[string]interface{}{"peer": l.peer}
+		if port, err := strconv.Atoi(l.peerPort); err == nil {
+			fields["peer_port"] = port
+		}
+		acc.AddFields("ib_fabric_missing_link", fields, tags, now)
+	}
+	return nil
+}
+
+// updateLinks compares the links with those of the previous gathers and
+// returns the missing links, up in a previous gather but no longer up.  The
+// links going down or coming up count as topology changes.
+func (f *IBFabric) updateLinks(links map[string]*link) []*link {
+	for key, l := range links {
+		previous, ok := f.links[key]
+		switch {
+		case l.up && !ok:
+			if f.gathered {
+				f.changes++
+			}
+			f.links[key] = l
+		case l.up && ok:
+			if !previous.up {
+				f.changes++
+			}
+			f.links[key] = l
+		case !l.up && ok && previous.up:
+			f.changes++
+			previous.up = false
+		}
+	}
+	for key, previous := range f.links {
+		if _, ok := links[key]; !ok && previous.up {
+			f.changes++
+			previous.up = false
+		}
+	}
+	f.gathered = true
+
+	var missing []*link
+	keys := make([]string, 0, len(f.links))
+	for key := range f.links {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !f.links[key].up {
+			missing = append(missing, f.links[key])
+		}
+	}
+	return missing
+}
+
+func init() {
+	inputs.Add("ib_fabric", func() telegraf.Input {
+		return &IBFabric{
+			Source:  "opensm",
+			Timeout: internal.Duration{Duration: 30 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package ib_fabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// iblinkinfoFile is the output of iblinkinfo printed by the helper process.
+var iblinkinfoFile = "iblinkinfo.txt"
+
+func TestGatherOpenSM(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+	defer func() { iblinkinfoFile = "iblinkinfo.txt" }()
+
+	f := &IBFabric{
+		Source:  "opensm",
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, f.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(f.Gather))
+
+	smTags := map[string]string{
+		"sm_lid":  "1",
+		"sm_guid": "0x248a070300f8f6e0",
+	}
+	sw01 := map[string]string{
+		"switch":      "MF0;sw01:MQM8700/U1",
+		"switch_guid": "0x248a070300f8f6e0",
+	}
+	sw02 := map[string]string{
+		"switch":      "MF0;sw02:MQM8700/U1",
+		"switch_guid": "0x248a070300f8f7a0",
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("ib_fabric", smTags,
+			map[string]interface{}{
+				"switches":          2,
+				"links_up":          5,
+				"links_degraded":    1,
+				"links_missing":     0,
+				"topology_changes":  uint64(0),
+				"sm_activity_count": uint64(5532177),
+				"sm_priority":       15,
+				"sm_state":          "master",
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ib_fabric_switch", sw01,
+			map[string]interface{}{
+				"links_up":       3,
+				"links_degraded": 1,
+				"ports_down":     1,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ib_fabric_switch", sw02,
+			map[string]interface{}{
+				"links_up":       2,
+				"links_degraded": 0,
+				"ports_down":     0,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+
+	// The link of node02 goes down
+	iblinkinfoFile = "iblinkinfo_down.txt"
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(f.Gather))
+
+	expected = []telegraf.Metric{
+		testutil.MustMetric("ib_fabric", smTags,
+			map[string]interface{}{
+				"switches":          2,
+				"links_up":          4,
+				"links_degraded":    0,
+				"links_missing":     1,
+				"topology_changes":  uint64(1),
+				"sm_activity_count": uint64(5532177),
+				"sm_priority":       15,
+				"sm_state":          "master",
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ib_fabric_switch", sw01,
+			map[string]interface{}{
+				"links_up":       2,
+				"links_degraded": 0,
+				"ports_down":     2,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ib_fabric_switch", sw02,
+			map[string]interface{}{
+				"links_up":       2,
+				"links_degraded": 0,
+				"ports_down":     0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ib_fabric_missing_link",
+			map[string]string{
+				"switch":      "MF0;sw01:MQM8700/U1",
+				"switch_guid": "0x248a070300f8f6e0",
+				"port":        "2",
+			},
+			map[string]interface{}{
+				"peer":      "node02 mlx5_0",
+				"peer_port": 1,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestGatherUFM(t *testing.T) {
+	systems, err := ioutil.ReadFile(filepath.Join("testdata", "systems.json"))
+	require.NoError(t, err)
+	links, err := ioutil.ReadFile(filepath.Join("testdata", "links.json"))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/ufmRest/resources/systems":
+			require.Equal(t, "switch", r.URL.Query().Get("type"))
+			w.Write(systems)
+		case "/ufmRest/resources/links":
+			w.Write(links)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	f := &IBFabric{
+		Source:   "ufm",
+		URL:      ts.URL,
+		Username: "admin",
+		Password: "secret",
+		Timeout:  internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, f.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(f.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("ib_fabric", map[string]string{},
+			map[string]interface{}{
+				"switches":         2,
+				"links_up":         3,
+				"links_degraded":   1,
+				"links_missing":    0,
+				"topology_changes": uint64(0),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ib_fabric_switch",
+			map[string]string{
+				"switch":      "sw01",
+				"switch_guid": "0x248a070300f8f6e0",
+				"model":       "MQM8700",
+			},
+			map[string]interface{}{
+				"links_up":       3,
+				"links_degraded": 1,
+				"state":          "active",
+				"severity":       "info",
+				"temperature":    45.0,
+				"power":          312.5,
+				"alarms":         int64(0),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("ib_fabric_switch",
+			map[string]string{
+				"switch":      "sw02",
+				"switch_guid": "0x248a070300f8f7a0",
+				"model":       "MQM8700",
+			},
+			map[string]interface{}{
+				"links_up":       0,
+				"links_degraded": 0,
+				"state":          "active",
+				"severity":       "warning",
+				"alarms":         int64(2),
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+
+	// UFM no longer lists the link of node01
+	var list []map[string]interface{}
+	require.NoError(t, json.Unmarshal(links, &list))
+	links, err = json.Marshal(list[1:])
+	require.NoError(t, err)
+
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(f.Gather))
+	acc.AssertContainsTaggedFields(t, "ib_fabric_missing_link",
+		map[string]interface{}{
+			"peer":      "node01/mlx5_0/1",
+			"peer_port": 1,
+		},
+		map[string]string{
+			"switch":      "sw01",
+			"switch_guid": "0x248a070300f8f6e0",
+			"port":        "1",
+		})
+}
+
+func TestInitInvalidSource(t *testing.T) {
+	f := &IBFabric{Source: "ibdiagnet"}
+	require.Error(t, f.Init())
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "IBLINKINFO_FILE=" + iblinkinfoFile}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the output of sminfo and iblinkinfo.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	file := "sminfo.txt"
+	if strings.Contains(strings.Join(os.Args, " "), "iblinkinfo") {
+		file = os.Getenv("IBLINKINFO_FILE")
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
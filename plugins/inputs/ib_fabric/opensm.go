@@ -0,0 +1,120 @@
+package ib_fabric
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+var (
+	// sminfo: sm lid 1 sm guid 0x248a070300f8f6e0, activity count 5532177 priority 15 state 3 SMINFO_MASTER
+	sminfoRegexp = regexp.MustCompile(`sm lid (\d+) sm guid (0x[0-9a-fA-F]+), activity count (\d+) priority (\d+) state (\d+) SMINFO_(\w+)`)
+	// Switch: 0x248a070300f8f6e0 MF0;sw01:MQM8700/U1:
+	switchRegexp = regexp.MustCompile(`^Switch:\s+(0x[0-9a-fA-F]+)\s+(.*):$`)
+	//    3    1[  ] ==( 4X      53.125 Gbps Active/  LinkUp)==>      12    1[  ] "node01 mlx5_0" ( )
+	portRegexp = regexp.MustCompile(`^\s*\d+\s+(\d+)\[[^\]]*\]\s+==\((.*)\)==>\s*(?:\d+\s+(\d+))?\[[^\]]*\]\s+"(.*)"\s+\((.*)\)\s*$`)
+)
+
+// gatherOpenSM reads the state of the master SM with sminfo and the links
+// of the switches with iblinkinfo.
+func (f *IBFabric) gatherOpenSM() (*fabric, error) {
+	state := &fabric{
+		sm:       make(map[string]interface{}),
+		smTags:   make(map[string]string),
+		switches: make(map[string]*fabricSwitch),
+		links:    make(map[string]*link),
+	}
+
+	out, err := f.run(f.SminfoPath)
+	if err != nil {
+		return nil, err
+	}
+	m := sminfoRegexp.FindStringSubmatch(string(out))
+	if m == nil {
+		return nil, fmt.Errorf("unexpected output of sminfo: %s", strings.TrimSpace(string(out)))
+	}
+	state.smTags["sm_lid"] = m[1]
+	state.smTags["sm_guid"] = m[2]
+	state.sm["sm_activity_count"], _ = strconv.ParseUint(m[3], 10, 64)
+	state.sm["sm_priority"], _ = strconv.Atoi(m[4])
+	state.sm["sm_state"] = strings.ToLower(m[6])
+
+	out, err = f.run(f.IblinkinfoPath, "--switches-only")
+	if err != nil {
+		return nil, err
+	}
+	parseIblinkinfo(out, state)
+	return state, nil
+}
+
+// parseIblinkinfo reads the ports of the switches, a port is up when its
+// physical state is LinkUp and degraded when iblinkinfo reports the speed
+// or width it could run at.
+func parseIblinkinfo(out []byte, state *fabric) {
+	var sw *fabricSwitch
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := switchRegexp.FindStringSubmatch(line); m != nil {
+			sw = &fabricSwitch{
+				name: m[2],
+				guid: m[1],
+				fields: map[string]interface{}{
+					"links_up":       0,
+					"links_degraded": 0,
+					"ports_down":     0,
+				},
+			}
+			state.switches[sw.guid] = sw
+			continue
+		}
+		m := portRegexp.FindStringSubmatch(line)
+		if m == nil || sw == nil {
+			continue
+		}
+
+		l := &link{
+			switchName: sw.name,
+			switchGUID: sw.guid,
+			port:       m[1],
+			peer:       m[4],
+			peerPort:   m[3],
+			up:         strings.HasSuffix(strings.TrimSpace(m[2]), "LinkUp"),
+			degraded:   strings.HasPrefix(strings.TrimSpace(m[5]), "Could be"),
+		}
+		state.links[l.switchGUID+"/"+l.port] = l
+		countLink(sw, l)
+	}
+}
+
+// countLink counts the link in the fields of its switch.
+func countLink(sw *fabricSwitch, l *link) {
+	if !l.up {
+		sw.fields["ports_down"] = sw.fields["ports_down"].(int) + 1
+		return
+	}
+	sw.fields["links_up"] = sw.fields["links_up"].(int) + 1
+	if l.degraded {
+		sw.fields["links_degraded"] = sw.fields["links_degraded"].(int) + 1
+	}
+}
+
+func (f *IBFabric) run(path string, args ...string) ([]byte, error) {
+	name := path
+	if f.UseSudo {
+		// -n - avoid prompting the user for input of any kind
+		args = append([]string{"-n", name}, args...)
+		name = "sudo"
+	}
+	cmd := execCommand(name, args...)
+	out, err := internal.CombinedOutputTimeout(cmd, f.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	return out, nil
+}
@@ -0,0 +1,155 @@
+package ib_fabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ufmSystem is a system of the /ufmRest/resources/systems resource.
+type ufmSystem struct {
+	Name        string      `json:"system_name"`
+	GUID        string      `json:"guid"`
+	Model       string      `json:"model"`
+	State       string      `json:"state"`
+	Severity    string      `json:"severity"`
+	Temperature interface{} `json:"temperature"`
+	Power       interface{} `json:"power"`
+	TotalAlarms interface{} `json:"total_alarms"`
+}
+
+// ufmLink is a link of the /ufmRest/resources/links resource, UFM only
+// lists the links that are up.
+type ufmLink struct {
+	SourceGUID      string      `json:"source_guid"`
+	SourcePort      interface{} `json:"source_port"`
+	SourceName      string      `json:"source_port_dname"`
+	DestinationGUID string      `json:"destination_guid"`
+	DestinationPort interface{} `json:"destination_port"`
+	DestinationName string      `json:"destination_port_dname"`
+	Severity        string      `json:"severity"`
+}
+
+// gatherUFM reads the switches and their links from UFM, links with a
+// severity other than Info are degraded.
+func (f *IBFabric) gatherUFM() (*fabric, error) {
+	state := &fabric{
+		sm:       make(map[string]interface{}),
+		smTags:   make(map[string]string),
+		switches: make(map[string]*fabricSwitch),
+		links:    make(map[string]*link),
+	}
+
+	var systems []ufmSystem
+	if err := f.getUFM("/ufmRest/resources/systems?type=switch", &systems); err != nil {
+		return nil, err
+	}
+	for _, s := range systems {
+		sw := &fabricSwitch{
+			name: s.Name,
+			guid: normalizeGUID(s.GUID),
+			tags: make(map[string]string),
+			fields: map[string]interface{}{
+				"links_up":       0,
+				"links_degraded": 0,
+			},
+		}
+		if s.Model != "" {
+			sw.tags["model"] = s.Model
+		}
+		if s.State != "" {
+			sw.fields["state"] = strings.ToLower(s.State)
+		}
+		if s.Severity != "" {
+			sw.fields["severity"] = strings.ToLower(s.Severity)
+		}
+		if v, ok := number(s.Temperature); ok {
+			sw.fields["temperature"] = v
+		}
+		if v, ok := number(s.Power); ok {
+			sw.fields["power"] = v
+		}
+		if v, ok := number(s.TotalAlarms); ok {
+			sw.fields["alarms"] = int64(v)
+		}
+		state.switches[sw.guid] = sw
+	}
+
+	var links []ufmLink
+	if err := f.getUFM("/ufmRest/resources/links", &links); err != nil {
+		return nil, err
+	}
+	for _, ul := range links {
+		// The links are keyed by their switch port, the source port for the
+		// links between switches.
+		sourceGUID := normalizeGUID(ul.SourceGUID)
+		destinationGUID := normalizeGUID(ul.DestinationGUID)
+		sw, port, peer, peerPort := state.switches[sourceGUID], ul.SourcePort, ul.DestinationName, ul.DestinationPort
+		if sw == nil {
+			sw, port, peer, peerPort = state.switches[destinationGUID], ul.DestinationPort, ul.SourceName, ul.SourcePort
+		}
+		if sw == nil {
+			continue
+		}
+		l := &link{
+			switchName: sw.name,
+			switchGUID: sw.guid,
+			port:       fmt.Sprint(port),
+			peer:       peer,
+			peerPort:   fmt.Sprint(peerPort),
+			up:         true,
+			degraded:   ul.Severity != "" && !strings.EqualFold(ul.Severity, "Info"),
+		}
+		state.links[l.switchGUID+"/"+l.port] = l
+		countLink(sw, l)
+	}
+	return state, nil
+}
+
+func (f *IBFabric) getUFM(path string, v interface{}) error {
+	u := strings.TrimSuffix(f.URL, "/") + path
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	if f.Username != "" || f.Password != "" {
+		req.SetBasicAuth(f.Username, f.Password)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status code %d (%s)", u, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("parsing %s failed: %v", u, err)
+	}
+	return nil
+}
+
+// normalizeGUID returns the GUID as printed by infiniband-diags, UFM prints
+// it without the 0x prefix.
+func normalizeGUID(guid string) string {
+	guid = strings.ToLower(guid)
+	if guid != "" && !strings.HasPrefix(guid, "0x") {
+		guid = "0x" + guid
+	}
+	return guid
+}
+
+// number returns the value of an attribute UFM prints as a number or as a
+// string, "N/A" when the switch does not report it.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,317 @@
+package idrac_power
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// powerAttributes are the attributes of the System.Power group reported and
+// their fields.
+var powerAttributes = map[string]string{
+	"Realtime.Power":    "power_watts",
+	"Realtime.Amps":     "current_amps",
+	"Avg.LastHour":      "avg_last_hour_watts",
+	"Avg.LastDay":       "avg_last_day_watts",
+	"Avg.LastWeek":      "avg_last_week_watts",
+	"Max.LastHour":      "max_last_hour_watts",
+	"Max.LastDay":       "max_last_day_watts",
+	"Max.LastWeek":      "max_last_week_watts",
+	"Min.LastHour":      "min_last_hour_watts",
+	"Min.LastDay":       "min_last_day_watts",
+	"Min.LastWeek":      "min_last_week_watts",
+	"Max.Power":         "peak_watts",
+	"Max.Amps":          "peak_amps",
+	"Cap.Watts":         "power_cap_watts",
+	"EnergyConsumption": "energy_kwh",
+}
+
+// psuSensorRe matches the power supply rows of "racadm getsensorinfo" with
+// a reading, such as "PS1 Current 1   Ok   0.6 Amps   N/A   N/A".
+var psuSensorRe = regexp.MustCompile(`^(PS\d+)\s+(.*?)\s{2,}(\S+)\s+([\d.]+)\s+(Watts|Amps|Volts)\b`)
+
+// psuStatusRe matches the presence rows of the power supplies, such as
+// "PS1 Status   Present   AC".
+var psuStatusRe = regexp.MustCompile(`^(PS\d+)\s+Status\s{2,}(.+?)(?:\s{2,}(\S+))?\s*$`)
+
+// IdracPower stores the configuration values for the idrac_power input
+// plugin
+type IdracPower struct {
+	Path        string            `toml:"path"`
+	UseSudo     bool              `toml:"use_sudo"`
+	SudoCommand []string          `toml:"sudo_command"`
+	Servers     []string          `toml:"servers"`
+	Timeout     internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	servers []*server
+}
+
+// server is a remote iDRAC.
+type server struct {
+	host     string
+	username string
+	password string
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the racadm executable
+  # path = "/opt/dell/srvadmin/bin/idracadm7"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run racadm.
+  ## Sudo must be configured to allow the telegraf user to run racadm
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The racadm command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally specify one or more remote iDRACs as
+  ##  username:password@host
+  ## if no servers are specified, the local iDRAC will be queried
+  # servers = ["root:calvin@192.168.1.120"]
+
+  ## racadm is slow, an interval of at least a minute is recommended
+  interval = "1m"
+
+  ## Timeout for each racadm command to complete
+  timeout = "30s"
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (d *IdracPower) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (d *IdracPower) Description() string {
+	return "Read the system and power supply power of Dell servers via racadm"
+}
+
+// Init locates racadm and parses the servers.
+func (d *IdracPower) Init() error {
+	if d.UseSudo {
+		if err := ipmi.CheckSudoCommand(d.SudoCommand); err != nil {
+			return err
+		}
+	}
+
+	if len(d.Path) == 0 {
+		d.Path = "racadm"
+	}
+	path, err := exec.LookPath(d.Path)
+	if err != nil {
+		return fmt.Errorf("racadm not found: verify that racadm is installed and that racadm is in your PATH (or specified in config): %v", err)
+	}
+	d.Path = path
+
+	d.servers = nil
+	for _, s := range d.Servers {
+		srv, err := parseServer(s)
+		if err != nil {
+			return err
+		}
+		d.servers = append(d.servers, srv)
+	}
+	return nil
+}
+
+// parseServer parses a server given as username:password@host.
+func parseServer(s string) (*server, error) {
+	i := strings.LastIndex(s, "@")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid server %q, expected username:password@host", redactServer(s))
+	}
+	credentials := strings.SplitN(s[:i], ":", 2)
+	if len(credentials) != 2 || credentials[0] == "" || s[i+1:] == "" {
+		return nil, fmt.Errorf("invalid server %q, expected username:password@host", redactServer(s))
+	}
+	return &server{
+		host:     s[i+1:],
+		username: credentials[0],
+		password: credentials[1],
+	}, nil
+}
+
+// Gather is the main execution function for the plugin
+func (d *IdracPower) Gather(acc telegraf.Accumulator) error {
+	if len(d.servers) == 0 {
+		return d.gatherServer(acc, nil)
+	}
+
+	var wg sync.WaitGroup
+	for _, srv := range d.servers {
+		wg.Add(1)
+		go func(srv *server) {
+			defer wg.Done()
+			if err := d.gatherServer(acc, srv); err != nil {
+				acc.AddError(err)
+			}
+		}(srv)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (d *IdracPower) gatherServer(acc telegraf.Accumulator, srv *server) error {
+	tags := map[string]string{}
+	if srv != nil {
+		tags["server"] = srv.host
+	}
+
+	out, err := d.run(srv, "get", "System.Power")
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	fields := parsePower(out)
+	if len(fields) == 0 {
+		return fmt.Errorf("no power readings found in output: %s", string(out))
+	}
+	acc.AddFields("idrac_power", fields, tags, timestamp)
+
+	out, err = d.run(srv, "getsensorinfo")
+	if err != nil {
+		return err
+	}
+	timestamp = time.Now()
+	for psu, fields := range parseSupplies(out) {
+		psuTags := map[string]string{"psu": psu}
+		for k, v := range tags {
+			psuTags[k] = v
+		}
+		acc.AddFields("idrac_power_supply", fields, psuTags, timestamp)
+	}
+	return nil
+}
+
+// run runs racadm with the arguments, against the iDRAC of the server if
+// not nil.
+func (d *IdracPower) run(srv *server, args ...string) ([]byte, error) {
+	if srv != nil {
+		args = append([]string{"-r", srv.host, "-u", srv.username, "-p", srv.password, "--nocertwarn"}, args...)
+	}
+	r := &ipmi.Runner{
+		Path:         d.Path,
+		UseSudo:      d.UseSudo,
+		SudoCommand:  d.SudoCommand,
+		Timeout:      d.Timeout.Duration,
+		PasswordFlag: "-p",
+		ExecCommand:  execCommand,
+	}
+	return r.Run(args...)
+}
+
+// parsePower parses the output of "racadm get System.Power", with lines like
+// "#Realtime.Power=212 W", read-only attributes are prefixed with "#".
+func parsePower(out []byte) map[string]interface{} {
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "#"), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		if key == "Cap.Enable" {
+			fields["power_cap_enabled"] = value == "Enabled"
+			continue
+		}
+		field, ok := powerAttributes[key]
+		if !ok {
+			continue
+		}
+		// Values carry their unit, such as "212 W" or "1235.5 KWh | 4216047 btu"
+		tokens := strings.Fields(value)
+		if len(tokens) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(tokens[0], 64); err == nil {
+			fields[field] = v
+		}
+	}
+	return fields
+}
+
+// parseSupplies parses the power supply rows of "racadm getsensorinfo" and
+// returns the fields per power supply.
+func parseSupplies(out []byte) map[string]map[string]interface{} {
+	supplies := make(map[string]map[string]interface{})
+	supply := func(name string) map[string]interface{} {
+		if supplies[name] == nil {
+			supplies[name] = make(map[string]interface{})
+		}
+		return supplies[name]
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := psuSensorRe.FindStringSubmatch(line); m != nil {
+			v, err := strconv.ParseFloat(m[4], 64)
+			if err != nil {
+				continue
+			}
+			name := strings.ToLower(m[2])
+			fields := supply(m[1])
+			switch m[5] {
+			case "Watts":
+				if strings.Contains(name, "output") {
+					fields["output_watts"] = v
+				} else {
+					fields["input_watts"] = v
+				}
+			case "Amps":
+				fields["input_current_amps"] = v
+			case "Volts":
+				fields["input_voltage_volts"] = v
+			}
+			continue
+		}
+		if m := psuStatusRe.FindStringSubmatch(line); m != nil {
+			fields := supply(m[1])
+			fields["present"] = m[2] == "Present"
+			if m[3] != "" {
+				fields["type"] = m[3]
+			}
+		}
+	}
+	return supplies
+}
+
+// redactServer masks the password of a server given as
+// username:password@host.
+func redactServer(s string) string {
+	i := strings.LastIndex(s, "@")
+	j := strings.Index(s, ":")
+	if i < 0 || j < 0 || j > i {
+		return s
+	}
+	return s[:j+1] + "********" + s[i:]
+}
+
+func init() {
+	inputs.Add("idrac_power", func() telegraf.Input {
+		return &IdracPower{
+			Timeout: internal.Duration{Duration: time.Second * 30},
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package idrac_power
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	d := &IdracPower{
+		Path:    os.Args[0],
+		Servers: []string{"root:calvin@192.168.1.120"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "idrac_power",
+		map[string]interface{}{
+			"power_watts":         212.0,
+			"current_amps":        1.0,
+			"avg_last_hour_watts": 212.0,
+			"avg_last_day_watts":  214.0,
+			"avg_last_week_watts": 215.0,
+			"max_last_hour_watts": 220.0,
+			"max_last_day_watts":  240.0,
+			"max_last_week_watts": 311.0,
+			"min_last_hour_watts": 204.0,
+			"min_last_day_watts":  198.0,
+			"min_last_week_watts": 191.0,
+			"peak_watts":          487.0,
+			"peak_amps":           1.8,
+			"power_cap_enabled":   false,
+			"power_cap_watts":     714.0,
+			"energy_kwh":          1235.5,
+		},
+		map[string]string{
+			"server": "192.168.1.120",
+		})
+	acc.AssertContainsTaggedFields(t, "idrac_power_supply",
+		map[string]interface{}{
+			"present":             true,
+			"type":                "AC",
+			"input_watts":         218.0,
+			"output_watts":        196.0,
+			"input_current_amps":  1.0,
+			"input_voltage_volts": 230.0,
+		},
+		map[string]string{
+			"server": "192.168.1.120",
+			"psu":    "PS1",
+		})
+	acc.AssertContainsTaggedFields(t, "idrac_power_supply",
+		map[string]interface{}{
+			"present": false,
+		},
+		map[string]string{
+			"server": "192.168.1.120",
+			"psu":    "PS2",
+		})
+}
+
+func TestGatherError(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_RACADM_FAILURE=ERROR: Unable to connect to RAC at specified IP address.")
+		return cmd
+	}
+
+	d := &IdracPower{
+		Path:    os.Args[0],
+		Servers: []string{"root:calvin@192.168.1.120"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, d.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "Unable to connect")
+	require.NotContains(t, acc.Errors[0].Error(), "calvin")
+}
+
+func TestParseServer(t *testing.T) {
+	srv, err := parseServer("root:p@ss:word@idrac-node01.example.com")
+	require.NoError(t, err)
+	require.Equal(t, &server{host: "idrac-node01.example.com", username: "root", password: "p@ss:word"}, srv)
+
+	_, err = parseServer("root:calvin")
+	require.Error(t, err)
+
+	_, err = parseServer("root@192.168.1.120")
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "calvin")
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the captures in testdata for "get System.Power" and
+// "getsensorinfo".
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	if failure, ok := os.LookupEnv("FAKE_RACADM_FAILURE"); ok {
+		fmt.Fprint(os.Stdout, failure)
+		os.Exit(1)
+	}
+
+	var capture string
+	cmdline := strings.Join(os.Args, " ")
+	switch {
+	case strings.HasSuffix(cmdline, "get System.Power"):
+		capture = "get_system_power.txt"
+	case strings.HasSuffix(cmdline, "getsensorinfo"):
+		capture = "getsensorinfo.txt"
+	default:
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", capture))
+	if err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
@@ -0,0 +1,291 @@
+package ilo_power
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/common/redfish"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = "ilo_power"
+
+// meterLinks are the names of the links to the power meters in the OEM
+// section of the Power resource.
+var meterLinks = map[string]string{
+	"fast":    "FastPowerMeter",
+	"history": "PowerMeter",
+}
+
+var sampleConfig = `
+  ## URL of the iLO
+  address = "https://127.0.0.1"
+
+  ## Credentials of a user allowed to read the power meters
+  username = "telegraf"
+  password = ""
+
+  ## Id of the chassis
+  # chassis_id = "1"
+
+  ## Power meters read, "fast" holds 20 second samples of the last 5
+  ## minutes, "history" holds 5 minute samples of the last 24 hours.  Each
+  ## sample is added once with its time.
+  # meters = ["fast", "history"]
+
+  ## Maximum number of concurrent requests to the BMC.  The session and the
+  ## limit are shared with the other Redfish plugins reading the BMC, the
+  ## lowest limit configured applies.
+  # max_concurrent_requests = 4
+
+  ## Timeout for HTTP requests
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## The fast power meter covers 5 minutes, gather more often to not miss
+  ## samples.
+  interval = "1m"
+`
+
+// IloPower gathers the power meter samples recorded by HPE iLO.
+type IloPower struct {
+	Address   string   `toml:"address"`
+	Username  string   `toml:"username"`
+	Password  string   `toml:"password"`
+	ChassisID string   `toml:"chassis_id"`
+	Meters    []string `toml:"meters"`
+
+	MaxConcurrentRequests int `toml:"max_concurrent_requests"`
+	httpconfig.HTTPClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	baseURL *url.URL
+	host    string
+	client  *http.Client
+	session *redfish.Session
+
+	// refs are the URIs of the meters, discovered on the first gather.
+	refs map[string]string
+	// last is the time of the newest sample added of each meter.
+	last map[string]time.Time
+}
+
+// power is the Power resource, the links to the meters are in the Hpe
+// section on iLO 5 and later and the Hp section on iLO 4.
+type power struct {
+	Oem map[string]struct {
+		Links map[string]struct {
+			Ref string `json:"@odata.id"`
+		}
+	}
+}
+
+// meter is a power meter resource.
+type meter struct {
+	PowerDetail []struct {
+		Time       string
+		Average    *float64
+		Minimum    *float64
+		Peak       *float64
+		AmbTemp    *float64
+		CpuUtil    *float64
+		CpuAvgFreq *float64
+	}
+}
+
+func (i *IloPower) SampleConfig() string {
+	return sampleConfig
+}
+
+func (i *IloPower) Description() string {
+	return "Read the power meter samples recorded by HPE iLO"
+}
+
+func (i *IloPower) Init() error {
+	if i.Address == "" {
+		return fmt.Errorf("no address configured")
+	}
+	if i.ChassisID == "" {
+		i.ChassisID = "1"
+	}
+	for _, m := range i.Meters {
+		if _, ok := meterLinks[m]; !ok {
+			return fmt.Errorf("invalid meter %q, expected fast or history", m)
+		}
+	}
+
+	var err error
+	i.baseURL, err = url.Parse(i.Address)
+	if err != nil {
+		return err
+	}
+	i.host, _, err = net.SplitHostPort(i.baseURL.Host)
+	if err != nil {
+		i.host = i.baseURL.Host
+	}
+
+	i.session = redfish.GetSession(i.baseURL, i.Username, i.Password)
+	i.session.SetMaxConcurrency(i.MaxConcurrentRequests)
+
+	i.client, err = i.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	i.last = make(map[string]time.Time)
+	return nil
+}
+
+func (i *IloPower) Gather(acc telegraf.Accumulator) error {
+	if i.refs == nil {
+		refs, err := i.discover()
+		if err != nil {
+			return err
+		}
+		i.refs = refs
+	}
+
+	for _, m := range i.Meters {
+		if err := i.gatherMeter(acc, m); err != nil {
+			acc.AddError(fmt.Errorf("%s meter of %s: %v", m, i.host, err))
+		}
+	}
+	return nil
+}
+
+// discover returns the URIs of the meters linked by the Power resource,
+// falling back to their default location.
+func (i *IloPower) discover() (map[string]string, error) {
+	powerURI := path.Join("/redfish/v1/Chassis", i.ChassisID, "Power")
+	var p power
+	if err := i.get(powerURI, &p); err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]string, len(meterLinks))
+	for m, link := range meterLinks {
+		refs[m] = path.Join(powerURI, link)
+		for _, vendor := range []string{"Hpe", "Hp"} {
+			if l, ok := p.Oem[vendor].Links[link]; ok && l.Ref != "" {
+				refs[m] = l.Ref
+				break
+			}
+		}
+	}
+	return refs, nil
+}
+
+func (i *IloPower) gatherMeter(acc telegraf.Accumulator, m string) error {
+	var samples meter
+	if err := i.get(i.refs[m], &samples); err != nil {
+		return err
+	}
+
+	tags := map[string]string{
+		"address": i.host,
+		"meter":   m,
+	}
+	last := i.last[m]
+	newest := last
+	for _, s := range samples.PowerDetail {
+		t, err := time.Parse(time.RFC3339, s.Time)
+		if err != nil {
+			i.Log.Debugf("Skipping sample of %s with invalid time %q", i.host, s.Time)
+			continue
+		}
+		if !t.After(last) {
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		addField(fields, "average_watts", s.Average)
+		addField(fields, "minimum_watts", s.Minimum)
+		addField(fields, "peak_watts", s.Peak)
+		addField(fields, "ambient_temperature_celsius", s.AmbTemp)
+		addField(fields, "cpu_utilization_percent", s.CpuUtil)
+		addField(fields, "cpu_average_frequency_mhz", s.CpuAvgFreq)
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields(measurement, fields, tags, t)
+
+		if t.After(newest) {
+			newest = t
+		}
+	}
+	i.last[m] = newest
+	return nil
+}
+
+func addField(fields map[string]interface{}, key string, value *float64) {
+	if value != nil {
+		fields[key] = *value
+	}
+}
+
+// get decodes the resource at the URI, requested with the session shared
+// with the other Redfish plugins reading the iLO.
+func (i *IloPower) get(uri string, v interface{}) error {
+	req, err := i.newRequest("GET", uri)
+	if err != nil {
+		return err
+	}
+	resp, err := i.session.Do(i.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s received status code %d (%s), expected 200",
+			uri,
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (i *IloPower) newRequest(method, uri string) (*http.Request, error) {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, i.baseURL.ResolveReference(ref).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	return req, nil
+}
+
+func init() {
+	inputs.Add("ilo_power", func() telegraf.Input {
+		return &IloPower{
+			ChassisID: "1",
+			Meters:    []string{"fast", "history"},
+
+			MaxConcurrentRequests: 4,
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+			},
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package ilo_power
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeILO serves the power meters from testdata to a session.
+type fakeILO struct {
+	sync.Mutex
+	token  string
+	logins int
+	files  map[string]string
+}
+
+func (f *fakeILO) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.URL.Path == "/redfish/v1/SessionService/Sessions" && r.Method == "POST" {
+		var credentials map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil || credentials["Password"] != "secret" {
+			http.Error(w, "Unauthorized.", 401)
+			return
+		}
+		f.logins++
+		f.token = fmt.Sprintf("token-%d", f.logins)
+		w.Header().Set("X-Auth-Token", f.token)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	if f.token == "" || r.Header.Get("X-Auth-Token") != f.token {
+		http.Error(w, "Unauthorized.", 401)
+		return
+	}
+
+	file, ok := f.files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Write(data)
+}
+
+func newFakeILO() *fakeILO {
+	return &fakeILO{
+		files: map[string]string{
+			"/redfish/v1/Chassis/1/Power":                "power.json",
+			"/redfish/v1/Chassis/1/Power/FastPowerMeter": "fast_power_meter.json",
+			"/redfish/v1/Chassis/1/Power/PowerMeter":     "power_meter.json",
+		},
+	}
+}
+
+func newIloPower(address string) *IloPower {
+	return &IloPower{
+		Address:  address,
+		Username: "telegraf",
+		Password: "secret",
+		Meters:   []string{"fast", "history"},
+		Log:      testutil.Logger{},
+	}
+}
+
+func TestGather(t *testing.T) {
+	ilo := newFakeILO()
+	ts := httptest.NewServer(ilo)
+	defer ts.Close()
+
+	plugin := newIloPower(ts.URL)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	fast := map[string]string{"address": "127.0.0.1", "meter": "fast"}
+	history := map[string]string{"address": "127.0.0.1", "meter": "history"}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("ilo_power", fast,
+			map[string]interface{}{
+				"average_watts":               212.0,
+				"minimum_watts":               208.0,
+				"peak_watts":                  231.0,
+				"ambient_temperature_celsius": 22.0,
+				"cpu_utilization_percent":     31.0,
+				"cpu_average_frequency_mhz":   2294.0,
+			},
+			time.Date(2020, 10, 14, 12, 0, 0, 0, time.UTC)),
+		testutil.MustMetric("ilo_power", fast,
+			map[string]interface{}{
+				"average_watts":               215.0,
+				"minimum_watts":               209.0,
+				"peak_watts":                  240.0,
+				"ambient_temperature_celsius": 22.0,
+				"cpu_utilization_percent":     33.0,
+				"cpu_average_frequency_mhz":   2301.0,
+			},
+			time.Date(2020, 10, 14, 12, 0, 20, 0, time.UTC)),
+		testutil.MustMetric("ilo_power", history,
+			map[string]interface{}{
+				"average_watts":               205.0,
+				"minimum_watts":               188.0,
+				"peak_watts":                  262.0,
+				"ambient_temperature_celsius": 22.0,
+				"cpu_utilization_percent":     27.0,
+				"cpu_average_frequency_mhz":   2187.0,
+			},
+			time.Date(2020, 10, 14, 11, 55, 0, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// The iLO recorded a new fast sample, only it is added.
+	ilo.Lock()
+	ilo.files["/redfish/v1/Chassis/1/Power/FastPowerMeter"] = "fast_power_meter_next.json"
+	ilo.Unlock()
+
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	expected = []telegraf.Metric{
+		testutil.MustMetric("ilo_power", fast,
+			map[string]interface{}{
+				"average_watts":               220.0,
+				"minimum_watts":               214.0,
+				"peak_watts":                  236.0,
+				"ambient_temperature_celsius": 22.0,
+				"cpu_utilization_percent":     35.0,
+			},
+			time.Date(2020, 10, 14, 12, 0, 40, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestSessionExpired(t *testing.T) {
+	ilo := newFakeILO()
+	ts := httptest.NewServer(ilo)
+	defer ts.Close()
+
+	plugin := newIloPower(ts.URL)
+	plugin.Meters = []string{"history"}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	ilo.Lock()
+	ilo.token = ""
+	ilo.Unlock()
+
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, 2, ilo.logins)
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+}
+
+func TestLoginFailure(t *testing.T) {
+	ts := httptest.NewServer(newFakeILO())
+	defer ts.Close()
+
+	plugin := newIloPower(ts.URL)
+	plugin.Password = "wrong"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.EqualError(t, plugin.Gather(&acc), "login received status code 401 (Unauthorized), expected 201")
+}
+
+func TestMissingMeter(t *testing.T) {
+	ilo := newFakeILO()
+	delete(ilo.files, "/redfish/v1/Chassis/1/Power/FastPowerMeter")
+	ts := httptest.NewServer(ilo)
+	defer ts.Close()
+
+	plugin := newIloPower(ts.URL)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "fast meter of 127.0.0.1")
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+}
+
+func TestInvalidMeter(t *testing.T) {
+	plugin := newIloPower("https://127.0.0.1")
+	plugin.Meters = []string{"slow"}
+	require.EqualError(t, plugin.Init(), `invalid meter "slow", expected fast or history`)
+}
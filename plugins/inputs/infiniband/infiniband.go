@@ -4,14 +4,23 @@ import (
 	"github.com/influxdata/telegraf"
 )
 
-// Stores the configuration values for the infiniband plugin - as there are no
-// config values, this is intentionally empty
+// Stores the configuration values for the infiniband plugin
 type Infiniband struct {
+	ErrorDeltas bool `toml:"error_deltas"`
+
 	Log telegraf.Logger `toml:"-"`
+
+	// lastErrors holds the error counters of the previous gather by device,
+	// port and counter.
+	lastErrors map[string]uint64
 }
 
 // Sample configuration for plugin
-var InfinibandConfig = ``
+var InfinibandConfig = `
+  ## Emit the increase of the error counters of each port since the previous
+  ## interval in the infiniband_errors measurement.
+  # error_deltas = false
+`
 
 func (_ *Infiniband) SampleConfig() string {
 	return InfinibandConfig
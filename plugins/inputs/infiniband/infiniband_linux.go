@@ -10,8 +10,25 @@ import (
 	"strconv"
 )
 
+// errorCounterMax holds the maximum values of the error counters, they
+// saturate at their maximum instead of wrapping.
+var errorCounterMax = map[string]uint64{
+	"symbol_error":                    0xffff,
+	"link_error_recovery":             0xff,
+	"link_downed":                     0xff,
+	"port_rcv_errors":                 0xffff,
+	"port_rcv_remote_physical_errors": 0xffff,
+	"port_rcv_switch_relay_errors":    0xffff,
+	"port_xmit_discards":              0xffff,
+	"port_xmit_constraint_errors":     0xff,
+	"port_rcv_constraint_errors":      0xff,
+	"local_link_integrity_errors":     0xf,
+	"excessive_buffer_overrun_errors": 0xf,
+	"VL15_dropped":                    0xffff,
+}
+
 // Gather statistics from our infiniband cards
-func (_ *Infiniband) Gather(acc telegraf.Accumulator) error {
+func (i *Infiniband) Gather(acc telegraf.Accumulator) error {
 
 	rdmaDevices := rdmamap.GetRdmaDeviceList()
 
@@ -33,6 +50,9 @@ func (_ *Infiniband) Gather(acc telegraf.Accumulator) error {
 			}
 
 			addStats(dev, port, stats, acc)
+			if i.ErrorDeltas {
+				i.addErrorDeltas(dev, port, stats, acc)
+			}
 		}
 	}
 
@@ -53,6 +73,50 @@ func addStats(dev string, port string, stats []rdmamap.RdmaStatEntry, acc telegr
 	acc.AddFields("infiniband", fields, tags)
 }
 
+// addErrorDeltas adds the increase of the error counters since the previous
+// gather, nothing is added for the first gather of a port.  A counter lower
+// than before was reset, by perfquery -R for example, and its increase is
+// its value.  A saturated counter no longer increases and is reported in the
+// saturated field until it is reset.
+func (i *Infiniband) addErrorDeltas(dev string, port string, stats []rdmamap.RdmaStatEntry, acc telegraf.Accumulator) {
+	if i.lastErrors == nil {
+		i.lastErrors = make(map[string]uint64)
+	}
+
+	fields := make(map[string]interface{})
+	saturated := false
+	first := false
+	for _, entry := range stats {
+		max, ok := errorCounterMax[entry.Name]
+		if !ok {
+			continue
+		}
+		key := dev + "/" + port + "/" + entry.Name
+		last, ok := i.lastErrors[key]
+		i.lastErrors[key] = entry.Value
+		if !ok {
+			first = true
+			continue
+		}
+
+		delta := entry.Value
+		if entry.Value >= last {
+			delta = entry.Value - last
+		}
+		fields[entry.Name] = delta
+		if entry.Value >= max {
+			saturated = true
+		}
+	}
+	if first || len(fields) == 0 {
+		return
+	}
+	fields["saturated"] = saturated
+
+	tags := map[string]string{"device": dev, "port": port}
+	acc.AddFields("infiniband_errors", fields, tags)
+}
+
 // Initialise plugin
 func init() {
 	inputs.Add("infiniband", func() telegraf.Input { return &Infiniband{} })
@@ -5,6 +5,7 @@ package infiniband
 import (
 	"github.com/Mellanox/rdmamap"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
 	"testing"
 )
 
@@ -132,3 +133,43 @@ func TestInfiniband(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, "infiniband", fields, tags)
 
 }
+
+func TestErrorDeltas(t *testing.T) {
+	stats := func(symbolErrors, linkDowned, integrityErrors uint64) []rdmamap.RdmaStatEntry {
+		return []rdmamap.RdmaStatEntry{
+			{Name: "symbol_error", Value: symbolErrors},
+			{Name: "link_downed", Value: linkDowned},
+			{Name: "local_link_integrity_errors", Value: integrityErrors},
+			{Name: "port_rcv_data", Value: 237159415345822},
+		}
+	}
+	tags := map[string]string{
+		"device": "mlx5_0",
+		"port":   "1",
+	}
+
+	i := &Infiniband{ErrorDeltas: true}
+	var acc testutil.Accumulator
+
+	// Nothing before the second gather
+	i.addErrorDeltas("mlx5_0", "1", stats(10, 1, 0), &acc)
+	require.Equal(t, uint64(0), acc.NMetrics())
+
+	i.addErrorDeltas("mlx5_0", "1", stats(25, 1, 3), &acc)
+	acc.AssertContainsTaggedFields(t, "infiniband_errors", map[string]interface{}{
+		"symbol_error":                uint64(15),
+		"link_downed":                 uint64(0),
+		"local_link_integrity_errors": uint64(3),
+		"saturated":                   false,
+	}, tags)
+
+	// Counters reset, then saturated
+	acc.ClearMetrics()
+	i.addErrorDeltas("mlx5_0", "1", stats(4, 0, 15), &acc)
+	acc.AssertContainsTaggedFields(t, "infiniband_errors", map[string]interface{}{
+		"symbol_error":                uint64(4),
+		"link_downed":                 uint64(0),
+		"local_link_integrity_errors": uint64(12),
+		"saturated":                   true,
+	}, tags)
+}
@@ -0,0 +1,263 @@
+package infiniband_perfquery
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// # Port extended counters: Lid 12 port 3 (CapMask: 0x5A00)
+var headerRegexp = regexp.MustCompile(`^# Port (?:extended )?counters: Lid (\d+) port (\d+)`)
+
+// counters maps the counters of perfquery to the names of the counters of
+// the infiniband input, the other counters are converted to snake case.
+var counters = map[string]string{
+	"PortXmitPkts":                 "port_xmit_packets",
+	"PortRcvPkts":                  "port_rcv_packets",
+	"PortUnicastXmitPkts":          "unicast_xmit_packets",
+	"PortUnicastRcvPkts":           "unicast_rcv_packets",
+	"PortMulticastXmitPkts":        "multicast_xmit_packets",
+	"PortMulticastRcvPkts":         "multicast_rcv_packets",
+	"SymbolErrorCounter":           "symbol_error",
+	"LinkErrorRecoveryCounter":     "link_error_recovery",
+	"LinkDownedCounter":            "link_downed",
+	"ExcessiveBufferOverrunErrors": "excessive_buffer_overrun_errors",
+	"VL15Dropped":                  "VL15_dropped",
+}
+
+// ignored are the fields of the queries which are not counters.
+var ignored = map[string]bool{
+	"PortSelect":     true,
+	"CounterSelect":  true,
+	"CounterSelect2": true,
+}
+
+type Perfquery struct {
+	PerfqueryPath string            `toml:"perfquery_path"`
+	UseSudo       bool              `toml:"use_sudo"`
+	Timeout       internal.Duration `toml:"timeout"`
+	CA            string            `toml:"ca"`
+	CAPort        int               `toml:"ca_port"`
+	Ports         []string          `toml:"ports"`
+	ErrorCounters bool              `toml:"error_counters"`
+
+	targets []target
+}
+
+// target is a port queried by LID, the local port has no LID.
+type target struct {
+	lid  string
+	port string
+}
+
+var sampleConfig = `
+  ## Path to the perfquery executable of infiniband-diags.
+  # perfquery_path = "/usr/sbin/perfquery"
+
+  ## Use sudo to run perfquery, which requires access to the umad devices.
+  ## Sudo must be configured to allow the telegraf user to run perfquery
+  ## without a password.
+  # use_sudo = false
+
+  ## Timeout of each run of perfquery.
+  # timeout = "5s"
+
+  ## Local HCA and port sending the queries, by default the first active
+  ## port.
+  # ca = "mlx5_0"
+  # ca_port = 1
+
+  ## Ports to query, as "<lid>:<port>" or "<lid>:<first port>-<last port>",
+  ## the ports of the switches are reachable by their LID, or "local" for
+  ## the local port.  The local port is queried when empty.
+  # ports = ["local", "12:1-40"]
+
+  ## Query the error counters and PortXmitWait with the basic counters for
+  ## the ports whose extended counters lack them.
+  # error_counters = true
+`
+
+func (p *Perfquery) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Perfquery) Description() string {
+	return "Read the extended InfiniBand port counters of local and remote ports through perfquery"
+}
+
+func (p *Perfquery) Init() error {
+	if p.PerfqueryPath == "" {
+		p.PerfqueryPath = "/usr/sbin/perfquery"
+	}
+
+	p.targets = nil
+	if len(p.Ports) == 0 {
+		p.targets = append(p.targets, target{})
+	}
+	for _, spec := range p.Ports {
+		if spec == "local" {
+			p.targets = append(p.targets, target{})
+			continue
+		}
+		parts := strings.SplitN(spec, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid port %q, expected <lid>:<port>", spec)
+		}
+		if _, err := strconv.ParseUint(parts[0], 0, 16); err != nil {
+			return fmt.Errorf("invalid LID in port %q: %v", spec, err)
+		}
+		bounds := strings.SplitN(parts[1], "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return fmt.Errorf("invalid port %q: %v", spec, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return fmt.Errorf("invalid port %q: %v", spec, err)
+			}
+		}
+		if first < 0 || last < first || last > 254 {
+			return fmt.Errorf("invalid port range in %q", spec)
+		}
+		for port := first; port <= last; port++ {
+			p.targets = append(p.targets, target{lid: parts[0], port: strconv.Itoa(port)})
+		}
+	}
+	return nil
+}
+
+func (p *Perfquery) Gather(acc telegraf.Accumulator) error {
+	for _, t := range p.targets {
+		if err := p.gatherTarget(acc, t); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+func (p *Perfquery) gatherTarget(acc telegraf.Accumulator, t target) error {
+	out, err := p.run(t, "-x")
+	if err != nil {
+		return err
+	}
+	tags, fields := parse(out)
+	if len(fields) == 0 {
+		return fmt.Errorf("no counters in the output of perfquery for port %s:%s", t.lid, t.port)
+	}
+
+	if _, ok := fields["symbol_error"]; p.ErrorCounters && !ok {
+		out, err := p.run(t)
+		if err != nil {
+			return err
+		}
+		// The data counters of the basic counters are 32 bit and saturate,
+		// only the counters missing from the extended counters are added.
+		_, basic := parse(out)
+		for name, value := range basic {
+			if _, ok := fields[name]; !ok {
+				fields[name] = value
+			}
+		}
+	}
+
+	if p.CA != "" {
+		tags["ca"] = p.CA
+	}
+	acc.AddFields("infiniband_perfquery", fields, tags)
+	return nil
+}
+
+func (p *Perfquery) run(t target, args ...string) ([]byte, error) {
+	if p.CA != "" {
+		args = append(args, "-C", p.CA)
+	}
+	if p.CAPort != 0 {
+		args = append(args, "-P", strconv.Itoa(p.CAPort))
+	}
+	if t.lid != "" {
+		args = append(args, t.lid, t.port)
+	}
+
+	name := p.PerfqueryPath
+	if p.UseSudo {
+		// -n - avoid prompting the user for input of any kind
+		args = append([]string{"-n", name}, args...)
+		name = "sudo"
+	}
+	cmd := execCommand(name, args...)
+	out, err := internal.CombinedOutputTimeout(cmd, p.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	return out, nil
+}
+
+// parse parses the output of perfquery, the lines of the counters are
+// "<name>:.....<value>".
+func parse(out []byte) (map[string]string, map[string]interface{}) {
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := headerRegexp.FindStringSubmatch(line); m != nil {
+			tags["lid"] = m[1]
+			tags["port"] = m[2]
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || ignored[parts[0]] {
+			continue
+		}
+		value, err := strconv.ParseUint(strings.TrimLeft(parts[1], "."), 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[fieldName(parts[0])] = value
+	}
+	return tags, fields
+}
+
+// fieldName converts a counter name, e.g. PortXmitData to port_xmit_data.
+func fieldName(counter string) string {
+	if name, ok := counters[counter]; ok {
+		return name
+	}
+	var b strings.Builder
+	runes := []rune(counter)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Acronyms such as VL stay in one word
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func init() {
+	inputs.Add("infiniband_perfquery", func() telegraf.Input {
+		return &Perfquery{
+			Timeout:       internal.Duration{Duration: 5 * time.Second},
+			ErrorCounters: true,
+		}
+	})
+}
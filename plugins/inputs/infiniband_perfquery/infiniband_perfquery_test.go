@@ -0,0 +1,159 @@
+package infiniband_perfquery
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	p := &Perfquery{
+		Timeout:       internal.Duration{Duration: 5 * time.Second},
+		CA:            "mlx5_0",
+		Ports:         []string{"local", "12:7"},
+		ErrorCounters: true,
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("infiniband_perfquery",
+			map[string]string{
+				"ca":   "mlx5_0",
+				"lid":  "3",
+				"port": "1",
+			},
+			map[string]interface{}{
+				"port_xmit_data":                  uint64(1288343214312),
+				"port_rcv_data":                   uint64(1177452337720),
+				"port_xmit_packets":               uint64(4123417123),
+				"port_rcv_packets":                uint64(3912731221),
+				"unicast_xmit_packets":            uint64(4123412221),
+				"unicast_rcv_packets":             uint64(3912729901),
+				"multicast_xmit_packets":          uint64(4902),
+				"multicast_rcv_packets":           uint64(1320),
+				"symbol_error":                    uint64(0),
+				"link_error_recovery":             uint64(0),
+				"link_downed":                     uint64(1),
+				"port_rcv_errors":                 uint64(0),
+				"port_rcv_remote_physical_errors": uint64(0),
+				"port_rcv_switch_relay_errors":    uint64(0),
+				"port_xmit_discards":              uint64(0),
+				"port_xmit_constraint_errors":     uint64(0),
+				"port_rcv_constraint_errors":      uint64(0),
+				"local_link_integrity_errors":     uint64(0),
+				"excessive_buffer_overrun_errors": uint64(0),
+				"VL15_dropped":                    uint64(0),
+				"port_xmit_wait":                  uint64(1182),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("infiniband_perfquery",
+			map[string]string{
+				"ca":   "mlx5_0",
+				"lid":  "12",
+				"port": "7",
+			},
+			map[string]interface{}{
+				"port_xmit_data":                  uint64(98123400123),
+				"port_rcv_data":                   uint64(97412301298),
+				"port_xmit_packets":               uint64(301234098),
+				"port_rcv_packets":                uint64(299812371),
+				"unicast_xmit_packets":            uint64(301230012),
+				"unicast_rcv_packets":             uint64(299808822),
+				"multicast_xmit_packets":          uint64(4086),
+				"multicast_rcv_packets":           uint64(3549),
+				"symbol_error":                    uint64(12),
+				"link_error_recovery":             uint64(0),
+				"link_downed":                     uint64(0),
+				"port_rcv_errors":                 uint64(3),
+				"port_rcv_remote_physical_errors": uint64(0),
+				"port_rcv_switch_relay_errors":    uint64(0),
+				"port_xmit_discards":              uint64(17),
+				"port_xmit_constraint_errors":     uint64(0),
+				"port_rcv_constraint_errors":      uint64(0),
+				"local_link_integrity_errors":     uint64(0),
+				"excessive_buffer_overrun_errors": uint64(0),
+				"VL15_dropped":                    uint64(0),
+				"port_xmit_wait":                  uint64(918234),
+				"qp1_dropped":                     uint64(0),
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestInitPorts(t *testing.T) {
+	p := &Perfquery{Ports: []string{"12:1-3", "0x1f:5", "local"}}
+	require.NoError(t, p.Init())
+	require.Equal(t, []target{
+		{lid: "12", port: "1"},
+		{lid: "12", port: "2"},
+		{lid: "12", port: "3"},
+		{lid: "0x1f", port: "5"},
+		{},
+	}, p.targets)
+
+	for _, port := range []string{"12", "x:1", "12:a", "12:5-3", "12:1-255"} {
+		p := &Perfquery{Ports: []string{port}}
+		require.Error(t, p.Init(), port)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	require.Equal(t, "port_rcv_remote_physical_errors", fieldName("PortRcvRemotePhysicalErrors"))
+	require.Equal(t, "port_vl_xmit_wait", fieldName("PortVLXmitWait"))
+	require.Equal(t, "port_xmit_packets", fieldName("PortXmitPkts"))
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the output of perfquery for the queried port.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := strings.Join(os.Args, " ")
+	var file string
+	switch {
+	case strings.HasSuffix(args, " -x -C mlx5_0 12 7"):
+		file = "switch_extended.txt"
+	case strings.HasSuffix(args, " -x -C mlx5_0"):
+		file = "local_extended.txt"
+	case strings.HasSuffix(args, "perfquery -C mlx5_0"):
+		file = "local_basic.txt"
+	default:
+		fmt.Fprintf(os.Stderr, "unexpected arguments %q", args)
+		os.Exit(1)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
@@ -0,0 +1,339 @@
+package intel_nm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// intelIANA is the manufacturer id of Intel leading the Node Manager
+// requests and responses.
+var intelIANA = []string{"0x57", "0x01", "0x00"}
+
+const (
+	netFn        = "0x2e"
+	getStats     = "0xc8"
+	getPolicy    = "0xc2"
+	globalPower  = "0x01"
+	statsLength  = 20
+	policyLength = 16
+)
+
+// domains are the Node Manager domains by name.
+var domains = map[string]byte{
+	"platform": 0x00,
+	"cpu":      0x01,
+	"memory":   0x02,
+	"hw":       0x03,
+	"io":       0x04,
+}
+
+// triggers are the names of the policy trigger types.
+var triggers = map[byte]string{
+	0x00: "none",
+	0x01: "inlet_temperature",
+	0x02: "missing_power_reading",
+	0x03: "time_after_reset",
+	0x04: "boot_time",
+}
+
+// IntelNM stores the configuration values for the intel_nm input plugin
+type IntelNM struct {
+	Path          string            `toml:"path"`
+	UseSudo       bool              `toml:"use_sudo"`
+	SudoCommand   []string          `toml:"sudo_command"`
+	Privilege     string            `toml:"privilege"`
+	Servers       []string          `toml:"servers"`
+	Interface     string            `toml:"interface"`
+	Timeout       internal.Duration `toml:"timeout"`
+	BridgeChannel string            `toml:"bridge_channel"`
+	TargetAddress string            `toml:"target_address"`
+	Domains       []string          `toml:"domains"`
+	PolicyIDs     []int             `toml:"policy_ids"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## optionally specify one or more servers via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, the local Node Manager will be queried
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## Recommended: use metric 'interval' that is a multiple of 'timeout' to avoid
+  ## gaps or overlap in pulled data
+  interval = "30s"
+
+  ## Timeout for each ipmitool command to complete
+  timeout = "20s"
+
+  ## Channel and address of the Management Engine running Node Manager, the
+  ## requests are bridged to it by the BMC.  Leave empty for BMCs handling
+  ## Node Manager requests themselves.
+  # bridge_channel = "0x06"
+  # target_address = "0x2c"
+
+  ## Domains to read the power statistics of, platform, cpu, memory, hw or
+  ## io.  Not all platforms support all domains.
+  # domains = ["platform", "cpu", "memory"]
+
+  ## Ids of the policies to read the power limit, trigger and state of.  The
+  ## policies are looked up in the platform domain.
+  # policy_ids = []
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *IntelNM) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *IntelNM) Description() string {
+	return "Read the power statistics and policies of Intel Node Manager via IPMI"
+}
+
+// Init locates ipmitool and checks the domains and policies.
+func (m *IntelNM) Init() error {
+	for _, d := range m.Domains {
+		if _, ok := domains[d]; !ok {
+			return fmt.Errorf("invalid domain %q, expected platform, cpu, memory, hw or io", d)
+		}
+	}
+	for _, id := range m.PolicyIDs {
+		if id < 0 || id > 255 {
+			return fmt.Errorf("invalid policy id %d", id)
+		}
+	}
+	if (m.BridgeChannel == "") != (m.TargetAddress == "") {
+		return fmt.Errorf("bridge_channel and target_address must be set together")
+	}
+
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *IntelNM) Gather(acc telegraf.Accumulator) error {
+	if len(m.Servers) == 0 {
+		m.gatherServer(acc, "")
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			m.gatherServer(acc, s)
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+// gatherServer reads the statistics of every domain and the policies, the
+// errors are added per request as platforms lack some domains.
+func (m *IntelNM) gatherServer(acc telegraf.Accumulator, server string) {
+	var opts []string
+	hostname := ""
+	if server != "" {
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			acc.AddError(err)
+			return
+		}
+		hostname = conn.Hostname
+		opts = conn.Options()
+	}
+	if m.BridgeChannel != "" {
+		opts = append(opts, "-b", m.BridgeChannel, "-t", m.TargetAddress)
+	}
+
+	newTags := func(domain string) map[string]string {
+		tags := map[string]string{"domain": domain}
+		if hostname != "" {
+			tags["server"] = hostname
+		}
+		return tags
+	}
+
+	for _, domain := range m.Domains {
+		data, err := m.request(opts, getStats, globalPower, fmt.Sprintf("0x%02x", domains[domain]), "0x00")
+		timestamp := time.Now()
+		if err != nil {
+			acc.AddError(fmt.Errorf("statistics of %s domain: %v", domain, err))
+			continue
+		}
+		fields, err := parseStatistics(data)
+		if err != nil {
+			acc.AddError(fmt.Errorf("statistics of %s domain of %s: %v", domain, hostname, err))
+			continue
+		}
+		acc.AddFields("intel_nm", fields, newTags(domain), timestamp)
+	}
+
+	for _, id := range m.PolicyIDs {
+		data, err := m.request(opts, getPolicy, "0x00", fmt.Sprintf("0x%02x", id))
+		timestamp := time.Now()
+		if err != nil {
+			acc.AddError(fmt.Errorf("policy %d: %v", id, err))
+			continue
+		}
+		domain, fields, err := parsePolicy(data)
+		if err != nil {
+			acc.AddError(fmt.Errorf("policy %d of %s: %v", id, hostname, err))
+			continue
+		}
+		tags := newTags(domain)
+		tags["policy_id"] = strconv.Itoa(id)
+		acc.AddFields("intel_nm_policy", fields, tags, timestamp)
+	}
+}
+
+// request sends the Node Manager command with the Intel manufacturer id
+// and returns the response bytes following it.
+func (m *IntelNM) request(opts []string, command string, data ...string) ([]byte, error) {
+	args := append(append([]string{}, opts...), "raw", netFn, command)
+	args = append(append(args, intelIANA...), data...)
+	out, err := m.run(args...)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ipmi.ParseRawResponse(out)
+	if err != nil {
+		return nil, fmt.Errorf("%v in output: %s", err, string(out))
+	}
+	if len(resp) < 3 || resp[0] != 0x57 || resp[1] != 0x01 || resp[2] != 0x00 {
+		return nil, fmt.Errorf("response without the Intel manufacturer id: % x", resp)
+	}
+	return resp[3:], nil
+}
+
+// run runs ipmitool with the arguments.
+func (m *IntelNM) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        m.Path,
+		UseSudo:     m.UseSudo,
+		SudoCommand: m.SudoCommand,
+		Timeout:     m.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// parseStatistics decodes the response to Get Node Manager Statistics in
+// global power mode: the current, minimum, maximum and average power, the
+// timestamp, the statistics reporting period and the state of the domain.
+func parseStatistics(data []byte) (map[string]interface{}, error) {
+	if len(data) < statsLength-3 {
+		return nil, fmt.Errorf("short statistics response: % x", data)
+	}
+	state := data[16]
+	return map[string]interface{}{
+		"current_watts":             float64(binary.LittleEndian.Uint16(data[0:2])),
+		"minimum_watts":             float64(binary.LittleEndian.Uint16(data[2:4])),
+		"maximum_watts":             float64(binary.LittleEndian.Uint16(data[4:6])),
+		"average_watts":             float64(binary.LittleEndian.Uint16(data[6:8])),
+		"statistics_period_seconds": int64(binary.LittleEndian.Uint32(data[12:16])),
+		"policy_enabled":            state&0x10 != 0,
+		"policy_active":             state&0x20 != 0,
+		"measurements_active":       state&0x40 != 0,
+		"limiting":                  state&0x80 != 0,
+	}, nil
+}
+
+// parsePolicy decodes the response to Get Node Manager Policy and returns
+// the domain of the policy and its fields.
+func parsePolicy(data []byte) (string, map[string]interface{}, error) {
+	if len(data) < policyLength-3 {
+		return "", nil, fmt.Errorf("short policy response: % x", data)
+	}
+
+	domain := fmt.Sprintf("0x%02x", data[0]&0x0f)
+	for name, id := range domains {
+		if id == data[0]&0x0f {
+			domain = name
+		}
+	}
+	trigger, ok := triggers[data[1]&0x0f]
+	if !ok {
+		trigger = fmt.Sprintf("0x%02x", data[1]&0x0f)
+	}
+
+	return domain, map[string]interface{}{
+		"enabled":                   data[0]&0x10 != 0,
+		"trigger":                   trigger,
+		"trigger_limit":             int64(binary.LittleEndian.Uint16(data[9:11])),
+		"alert":                     data[2]&0x01 != 0,
+		"shutdown":                  data[2]&0x02 != 0,
+		"power_limit_watts":         float64(binary.LittleEndian.Uint16(data[3:5])),
+		"correction_time_ms":        int64(binary.LittleEndian.Uint32(data[5:9])),
+		"statistics_period_seconds": int64(binary.LittleEndian.Uint16(data[11:13])),
+	}, nil
+}
+
+func init() {
+	inputs.Add("intel_nm", func() telegraf.Input {
+		return &IntelNM{
+			Timeout:       internal.Duration{Duration: time.Second * 20},
+			BridgeChannel: "0x06",
+			TargetAddress: "0x2c",
+			Domains:       []string{"platform", "cpu", "memory"},
+		}
+	})
+}
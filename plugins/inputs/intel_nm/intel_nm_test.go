@@ -0,0 +1,165 @@
+package intel_nm
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IntelNM{
+		Path:          os.Args[0],
+		Servers:       []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:       internal.Duration{Duration: time.Second * 5},
+		BridgeChannel: "0x06",
+		TargetAddress: "0x2c",
+		Domains:       []string{"platform", "cpu", "memory"},
+		PolicyIDs:     []int{1},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+
+	// The memory domain is not supported by the platform
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "statistics of memory domain")
+	require.NotContains(t, acc.Errors[0].Error(), "PASSW0RD")
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "intel_nm",
+		map[string]interface{}{
+			"current_watts":             162.0,
+			"minimum_watts":             64.0,
+			"maximum_watts":             300.0,
+			"average_watts":             180.0,
+			"statistics_period_seconds": int64(3600),
+			"policy_enabled":            true,
+			"policy_active":             false,
+			"measurements_active":       true,
+			"limiting":                  false,
+		},
+		map[string]string{
+			"server": "192.168.1.1",
+			"domain": "platform",
+		})
+	acc.AssertContainsTaggedFields(t, "intel_nm",
+		map[string]interface{}{
+			"current_watts":             85.0,
+			"minimum_watts":             10.0,
+			"maximum_watts":             200.0,
+			"average_watts":             90.0,
+			"statistics_period_seconds": int64(3600),
+			"policy_enabled":            true,
+			"policy_active":             true,
+			"measurements_active":       true,
+			"limiting":                  true,
+		},
+		map[string]string{
+			"server": "192.168.1.1",
+			"domain": "cpu",
+		})
+	acc.AssertContainsTaggedFields(t, "intel_nm_policy",
+		map[string]interface{}{
+			"enabled":                   true,
+			"trigger":                   "none",
+			"trigger_limit":             int64(0),
+			"alert":                     true,
+			"shutdown":                  false,
+			"power_limit_watts":         500.0,
+			"correction_time_ms":        int64(1000),
+			"statistics_period_seconds": int64(60),
+		},
+		map[string]string{
+			"server":    "192.168.1.1",
+			"domain":    "platform",
+			"policy_id": "1",
+		})
+}
+
+func TestInitInvalidDomain(t *testing.T) {
+	i := &IntelNM{
+		Path:    os.Args[0],
+		Domains: []string{"gpu"},
+	}
+	require.Error(t, i.Init())
+}
+
+func TestParseStatisticsShort(t *testing.T) {
+	_, err := parseStatistics([]byte{0xa2, 0x00})
+	require.Error(t, err)
+}
+
+func TestRequestWithoutIANA(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_RESPONSE=00 00 00 a2 00")
+		return cmd
+	}
+
+	i := &IntelNM{
+		Path:    os.Args[0],
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Domains: []string{"platform"},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "without the Intel manufacturer id")
+	require.Empty(t, acc.Metrics)
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the responses of the Node Manager commands.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	if response, ok := os.LookupEnv("FAKE_IPMI_RESPONSE"); ok {
+		fmt.Fprintln(os.Stdout, response)
+		os.Exit(0)
+	}
+
+	cmd := strings.Join(os.Args, " ")
+	if !strings.Contains(cmd, "-b 0x06 -t 0x2c raw") {
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	switch {
+	case strings.HasSuffix(cmd, "raw 0x2e 0xc8 0x57 0x01 0x00 0x01 0x00 0x00"):
+		fmt.Fprintln(os.Stdout, " 57 01 00 a2 00 40 00 2c 01 b4 00 5f 8a 3c 60 10 0e 00 00\n 50")
+	case strings.HasSuffix(cmd, "raw 0x2e 0xc8 0x57 0x01 0x00 0x01 0x01 0x00"):
+		fmt.Fprintln(os.Stdout, " 57 01 00 55 00 0a 00 c8 00 5a 00 5f 8a 3c 60 10 0e 00 00\n f1")
+	case strings.HasSuffix(cmd, "raw 0x2e 0xc2 0x57 0x01 0x00 0x00 0x01"):
+		fmt.Fprintln(os.Stdout, " 57 01 00 10 10 01 f4 01 e8 03 00 00 00 00 3c 00")
+	default:
+		fmt.Fprint(os.Stdout, "Unable to send RAW command (channel=0x6 netfn=0x2e lun=0x0 cmd=0xc8 rsp=0x80): Unknown (0x80)")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,258 @@
+package intel_rapl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// now is used to mock the time in tests.
+var now = time.Now
+
+// IntelRAPL stores the configuration values for the intel_rapl input plugin
+type IntelRAPL struct {
+	Path    string   `toml:"path"`
+	Domains []string `toml:"domains"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	// zones holds the last reading of each zone by its directory name.
+	zones map[string]*zoneState
+}
+
+// zoneState is the last reading of the energy counter of a zone.
+type zoneState struct {
+	raw       uint64
+	total     uint64
+	timestamp time.Time
+}
+
+// zone is a power zone of the powercap framework.
+type zone struct {
+	id     string
+	domain string
+	socket string
+	energy uint64
+	max    uint64
+}
+
+var sampleConfig = `
+  ## Path of the powercap class in sysfs.
+  # path = "/sys/class/powercap"
+
+  ## Domains to gather, package, core, uncore, dram or psys, by default all
+  ## domains are gathered.
+  # domains = []
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (r *IntelRAPL) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (r *IntelRAPL) Description() string {
+	return "Read the energy counters of the Intel RAPL power zones"
+}
+
+// Init checks the domains.
+func (r *IntelRAPL) Init() error {
+	if r.Path == "" {
+		r.Path = "/sys/class/powercap"
+	}
+	for _, d := range r.Domains {
+		switch d {
+		case "package", "core", "uncore", "dram", "psys":
+		default:
+			return fmt.Errorf("invalid domain %q, must be package, core, uncore, dram or psys", d)
+		}
+	}
+	r.zones = make(map[string]*zoneState)
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (r *IntelRAPL) Gather(acc telegraf.Accumulator) error {
+	dirs, err := filepath.Glob(filepath.Join(r.Path, "intel-rapl:*"))
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("no RAPL zones found in %s, is the intel_rapl module loaded?", r.Path)
+	}
+	sort.Strings(dirs)
+
+	type socketTotal struct {
+		energy float64
+		power  float64
+		// powerKnown is false until every zone of the socket has a power
+		// reading.
+		powerKnown bool
+	}
+	sockets := make(map[string]*socketTotal)
+
+	for _, dir := range dirs {
+		z, err := r.readZone(dir)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		if !r.wanted(z.domain) {
+			continue
+		}
+		timestamp := now()
+		joules, watts, ok := r.update(z, timestamp)
+
+		tags := map[string]string{
+			"zone":   z.id,
+			"domain": z.domain,
+		}
+		if z.socket != "" {
+			tags["socket"] = z.socket
+		}
+		fields := map[string]interface{}{
+			"energy_joules": joules,
+		}
+		if ok {
+			fields["power_watts"] = watts
+		}
+		acc.AddFields("intel_rapl", fields, tags, timestamp)
+
+		// The core and uncore zones are part of the package zone
+		if z.socket == "" || (z.domain != "package" && z.domain != "dram") {
+			continue
+		}
+		s, found := sockets[z.socket]
+		if !found {
+			s = &socketTotal{powerKnown: true}
+			sockets[z.socket] = s
+		}
+		s.energy += joules
+		s.power += watts
+		s.powerKnown = s.powerKnown && ok
+	}
+
+	for socket, s := range sockets {
+		fields := map[string]interface{}{
+			"energy_joules": s.energy,
+		}
+		if s.powerKnown {
+			fields["power_watts"] = s.power
+		}
+		acc.AddFields("intel_rapl_socket", fields, map[string]string{"socket": socket})
+	}
+	return nil
+}
+
+// wanted returns whether the domain is gathered.
+func (r *IntelRAPL) wanted(domain string) bool {
+	if len(r.Domains) == 0 {
+		return true
+	}
+	for _, d := range r.Domains {
+		if d == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// update accumulates the energy counter of the zone across wraparounds and
+// returns the energy in Joules since the first reading plus the initial
+// counter value, and the average power since the previous reading if any.
+func (r *IntelRAPL) update(z *zone, timestamp time.Time) (float64, float64, bool) {
+	last, ok := r.zones[z.id]
+	if !ok {
+		r.zones[z.id] = &zoneState{raw: z.energy, total: z.energy, timestamp: timestamp}
+		return float64(z.energy) / 1e6, 0, false
+	}
+
+	delta := z.energy - last.raw
+	if z.energy < last.raw {
+		// The counter wrapped at max_energy_range_uj
+		delta = z.max - last.raw + z.energy
+	}
+	elapsed := timestamp.Sub(last.timestamp).Seconds()
+	last.raw = z.energy
+	last.total += delta
+	last.timestamp = timestamp
+
+	joules := float64(last.total) / 1e6
+	if elapsed <= 0 {
+		return joules, 0, false
+	}
+	return joules, float64(delta) / 1e6 / elapsed, true
+}
+
+// readZone reads the name and energy counter of the zone in the directory.
+// Sub-zones are named intel-rapl:<package>:<index> and belong to the socket
+// of their parent.
+func (r *IntelRAPL) readZone(dir string) (*zone, error) {
+	id := filepath.Base(dir)
+	name, err := readString(filepath.Join(dir, "name"))
+	if err != nil {
+		return nil, err
+	}
+	energy, err := readUint(filepath.Join(dir, "energy_uj"))
+	if err != nil {
+		// The counters are only readable by root since Linux 5.10
+		return nil, fmt.Errorf("reading energy of zone %s: %v", id, err)
+	}
+	max, err := readUint(filepath.Join(dir, "max_energy_range_uj"))
+	if err != nil {
+		return nil, err
+	}
+
+	z := &zone{id: id, domain: name, energy: energy, max: max}
+	if strings.Count(id, ":") > 1 {
+		parent, err := readString(filepath.Join(r.Path, id[:strings.LastIndex(id, ":")], "name"))
+		if err != nil {
+			return nil, err
+		}
+		z.socket = packageSocket(parent)
+	} else {
+		z.socket = packageSocket(name)
+	}
+	if strings.HasPrefix(name, "package-") {
+		z.domain = "package"
+	}
+	return z, nil
+}
+
+// packageSocket returns the socket of a package zone named package-<socket>,
+// empty for other zones.
+func packageSocket(name string) string {
+	if !strings.HasPrefix(name, "package-") {
+		return ""
+	}
+	return strings.TrimPrefix(name, "package-")
+}
+
+func readString(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("intel_rapl", func() telegraf.Input {
+		return &IntelRAPL{}
+	})
+}
@@ -0,0 +1,119 @@
+package intel_rapl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// writeZone creates the files of a power zone in the powercap directory.
+func writeZone(t *testing.T, dir, id, name, energy string) {
+	path := filepath.Join(dir, id)
+	require.NoError(t, os.MkdirAll(path, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "name"), []byte(name+"\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "energy_uj"), []byte(energy+"\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path, "max_energy_range_uj"), []byte("262143328850\n"), 0644))
+}
+
+func TestGather(t *testing.T) {
+	dir, err := ioutil.TempDir("", "intel_rapl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	start := time.Unix(1602756000, 0)
+	defer func() { now = time.Now }()
+	now = func() time.Time { return start }
+
+	writeZone(t, dir, "intel-rapl:0", "package-0", "1000000000")
+	writeZone(t, dir, "intel-rapl:0:0", "core", "262133328850")
+	writeZone(t, dir, "intel-rapl:0:1", "dram", "500000000")
+	writeZone(t, dir, "intel-rapl:1", "psys", "2000000000")
+
+	r := &IntelRAPL{Path: dir, Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	// The first reading has no power
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 5)
+	acc.AssertContainsTaggedFields(t, "intel_rapl",
+		map[string]interface{}{"energy_joules": 1000.0},
+		map[string]string{"zone": "intel-rapl:0", "domain": "package", "socket": "0"})
+	acc.AssertContainsTaggedFields(t, "intel_rapl_socket",
+		map[string]interface{}{"energy_joules": 1500.0},
+		map[string]string{"socket": "0"})
+
+	now = func() time.Time { return start.Add(10 * time.Second) }
+	writeZone(t, dir, "intel-rapl:0", "package-0", "2500000000")
+	// The core counter wraps
+	writeZone(t, dir, "intel-rapl:0:0", "core", "40000000")
+	writeZone(t, dir, "intel-rapl:0:1", "dram", "700000000")
+	writeZone(t, dir, "intel-rapl:1", "psys", "5000000000")
+
+	acc.ClearMetrics()
+	require.NoError(t, r.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 5)
+	acc.AssertContainsTaggedFields(t, "intel_rapl",
+		map[string]interface{}{"energy_joules": 2500.0, "power_watts": 150.0},
+		map[string]string{"zone": "intel-rapl:0", "domain": "package", "socket": "0"})
+	acc.AssertContainsTaggedFields(t, "intel_rapl",
+		map[string]interface{}{"energy_joules": 262183.32885, "power_watts": 5.0},
+		map[string]string{"zone": "intel-rapl:0:0", "domain": "core", "socket": "0"})
+	acc.AssertContainsTaggedFields(t, "intel_rapl",
+		map[string]interface{}{"energy_joules": 700.0, "power_watts": 20.0},
+		map[string]string{"zone": "intel-rapl:0:1", "domain": "dram", "socket": "0"})
+	acc.AssertContainsTaggedFields(t, "intel_rapl",
+		map[string]interface{}{"energy_joules": 5000.0, "power_watts": 300.0},
+		map[string]string{"zone": "intel-rapl:1", "domain": "psys"})
+	acc.AssertContainsTaggedFields(t, "intel_rapl_socket",
+		map[string]interface{}{"energy_joules": 3200.0, "power_watts": 170.0},
+		map[string]string{"socket": "0"})
+}
+
+func TestGatherDomains(t *testing.T) {
+	dir, err := ioutil.TempDir("", "intel_rapl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeZone(t, dir, "intel-rapl:0", "package-0", "1000000000")
+	writeZone(t, dir, "intel-rapl:0:0", "core", "1000")
+	writeZone(t, dir, "intel-rapl:1", "package-1", "3000000000")
+
+	r := &IntelRAPL{Path: dir, Domains: []string{"package"}, Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4)
+	acc.AssertContainsTaggedFields(t, "intel_rapl",
+		map[string]interface{}{"energy_joules": 3000.0},
+		map[string]string{"zone": "intel-rapl:1", "domain": "package", "socket": "1"})
+	acc.AssertDoesNotContainsTaggedFields(t, "intel_rapl",
+		map[string]interface{}{"energy_joules": 0.001},
+		map[string]string{"zone": "intel-rapl:0:0", "domain": "core", "socket": "0"})
+}
+
+func TestGatherNoZones(t *testing.T) {
+	dir, err := ioutil.TempDir("", "intel_rapl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r := &IntelRAPL{Path: dir, Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, r.Gather(&acc))
+}
+
+func TestInitInvalidDomain(t *testing.T) {
+	r := &IntelRAPL{Domains: []string{"gpu"}}
+	require.Error(t, r.Init())
+}
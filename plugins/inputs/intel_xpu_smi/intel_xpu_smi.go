@@ -0,0 +1,196 @@
+package intel_xpu_smi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+const measurement = "intel_xpu_smi"
+
+// fields maps the statistics of xpu-smi to field names with their units,
+// other statistics are named after their type.
+var fields = map[string]string{
+	"XPUM_STATS_POWER":                                    "power_watts",
+	"XPUM_STATS_ENERGY":                                   "energy_millijoules",
+	"XPUM_STATS_GPU_FREQUENCY":                            "frequency_mhz",
+	"XPUM_STATS_MEDIA_ENGINE_FREQUENCY":                   "media_frequency_mhz",
+	"XPUM_STATS_GPU_CORE_TEMPERATURE":                     "temperature_core_celsius",
+	"XPUM_STATS_MEMORY_TEMPERATURE":                       "temperature_memory_celsius",
+	"XPUM_STATS_GPU_UTILIZATION":                          "utilization_percent",
+	"XPUM_STATS_EU_ACTIVE":                                "eu_active_percent",
+	"XPUM_STATS_EU_STALL":                                 "eu_stall_percent",
+	"XPUM_STATS_EU_IDLE":                                  "eu_idle_percent",
+	"XPUM_STATS_MEMORY_USED":                              "memory_used_mib",
+	"XPUM_STATS_MEMORY_UTILIZATION":                       "memory_utilization_percent",
+	"XPUM_STATS_MEMORY_BANDWIDTH":                         "memory_bandwidth_percent",
+	"XPUM_STATS_MEMORY_READ":                              "memory_read_bytes",
+	"XPUM_STATS_MEMORY_WRITE":                             "memory_write_bytes",
+	"XPUM_STATS_MEMORY_READ_THROUGHPUT":                   "memory_read_kilobytes_per_second",
+	"XPUM_STATS_MEMORY_WRITE_THROUGHPUT":                  "memory_write_kilobytes_per_second",
+	"XPUM_STATS_PCIE_READ_THROUGHPUT":                     "pcie_read_kilobytes_per_second",
+	"XPUM_STATS_PCIE_WRITE_THROUGHPUT":                    "pcie_write_kilobytes_per_second",
+	"XPUM_STATS_RAS_ERROR_CAT_RESET":                      "ras_reset",
+	"XPUM_STATS_RAS_ERROR_CAT_CACHE_ERRORS_CORRECTABLE":   "ras_cache_errors_correctable",
+	"XPUM_STATS_RAS_ERROR_CAT_CACHE_ERRORS_UNCORRECTABLE": "ras_cache_errors_uncorrectable",
+}
+
+// XPUSMI holds the methods for this plugin
+type XPUSMI struct {
+	BinPath string            `toml:"bin_path"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+// Description returns the description of the XPUSMI plugin
+func (x *XPUSMI) Description() string {
+	return "Pulls statistics from Intel data center GPUs attached to the host through xpu-smi"
+}
+
+// SampleConfig returns the sample configuration for the XPUSMI plugin
+func (x *XPUSMI) SampleConfig() string {
+	return `
+  ## Optional: path to xpu-smi binary
+  # bin_path = "/usr/bin/xpu-smi"
+
+  ## Optional: timeout for each run of xpu-smi
+  # timeout = "5s"
+`
+}
+
+// discovery is the list of GPUs printed by "xpu-smi discovery -j".
+type discovery struct {
+	Devices []struct {
+		ID            int    `json:"device_id"`
+		Name          string `json:"device_name"`
+		UUID          string `json:"uuid"`
+		PCIBDFAddress string `json:"pci_bdf_address"`
+	} `json:"device_list"`
+}
+
+// stat is a statistic of a GPU or of a tile.
+type stat struct {
+	Type  string   `json:"metrics_type"`
+	Value *float64 `json:"value"`
+}
+
+// stats are the statistics of a GPU printed by "xpu-smi stats -d <id> -j".
+type stats struct {
+	DeviceID    int    `json:"device_id"`
+	DeviceLevel []stat `json:"device_level"`
+	TileLevel   []struct {
+		TileID   int    `json:"tile_id"`
+		DataList []stat `json:"data_list"`
+	} `json:"tile_level"`
+}
+
+// Gather implements the telegraf interface
+func (x *XPUSMI) Gather(acc telegraf.Accumulator) error {
+	if _, err := os.Stat(x.BinPath); os.IsNotExist(err) {
+		return fmt.Errorf("xpu-smi binary not at path %s, cannot gather GPU data", x.BinPath)
+	}
+
+	out, err := x.run("discovery", "-j")
+	if err != nil {
+		return err
+	}
+	var d discovery
+	if err := json.Unmarshal(out, &d); err != nil {
+		return fmt.Errorf("parsing the GPUs failed: %v", err)
+	}
+
+	for _, device := range d.Devices {
+		tags := map[string]string{"index": strconv.Itoa(device.ID)}
+		setTagIfUsed(tags, "name", device.Name)
+		setTagIfUsed(tags, "uuid", device.UUID)
+		setTagIfUsed(tags, "pci_bus", device.PCIBDFAddress)
+
+		out, err := x.run("stats", "-d", strconv.Itoa(device.ID), "-j")
+		if err != nil {
+			acc.AddError(fmt.Errorf("GPU %d: %v", device.ID, err))
+			continue
+		}
+		if err := gatherStats(out, tags, acc); err != nil {
+			acc.AddError(fmt.Errorf("GPU %d: %v", device.ID, err))
+		}
+	}
+	return nil
+}
+
+func (x *XPUSMI) run(args ...string) ([]byte, error) {
+	cmd := execCommand(x.BinPath, args...)
+	out, err := internal.StdOutputTimeout(cmd, x.Timeout.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	return out, nil
+}
+
+// gatherStats adds the statistics of a GPU and of each of its tiles, the
+// tiles of a multi-tile GPU such as the Data Center GPU Max 1550 have their
+// own power, frequency and memory.
+func gatherStats(data []byte, tags map[string]string, acc telegraf.Accumulator) error {
+	var s stats
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("parsing the statistics failed: %v", err)
+	}
+
+	if f := statFields(s.DeviceLevel); len(f) > 0 {
+		acc.AddFields(measurement, f, tags)
+	}
+	for _, tile := range s.TileLevel {
+		f := statFields(tile.DataList)
+		if len(f) == 0 {
+			continue
+		}
+		tileTags := make(map[string]string, len(tags)+1)
+		for k, v := range tags {
+			tileTags[k] = v
+		}
+		tileTags["tile"] = strconv.Itoa(tile.TileID)
+		acc.AddFields(measurement+"_tile", f, tileTags)
+	}
+	return nil
+}
+
+// statFields converts the statistics to fields, the statistics the GPU
+// does not support have no value.
+func statFields(list []stat) map[string]interface{} {
+	result := make(map[string]interface{}, len(list))
+	for _, s := range list {
+		if s.Value == nil {
+			continue
+		}
+		name, ok := fields[s.Type]
+		if !ok {
+			name = strings.ToLower(strings.TrimPrefix(s.Type, "XPUM_STATS_"))
+		}
+		result[name] = *s.Value
+	}
+	return result
+}
+
+func setTagIfUsed(m map[string]string, k, v string) {
+	if v != "" {
+		m[k] = v
+	}
+}
+
+func init() {
+	inputs.Add("intel_xpu_smi", func() telegraf.Input {
+		return &XPUSMI{
+			BinPath: "/usr/bin/xpu-smi",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
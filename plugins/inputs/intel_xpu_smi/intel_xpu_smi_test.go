@@ -0,0 +1,125 @@
+package intel_xpu_smi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	execCommand = fakeExecCommand
+	defer func() { execCommand = exec.Command }()
+
+	x := &XPUSMI{
+		BinPath: os.Args[0],
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(x.Gather))
+
+	tags := map[string]string{
+		"index":   "0",
+		"name":    "Intel(R) Data Center GPU Max 1550",
+		"uuid":    "01000000-0000-0000-0000-000000180000",
+		"pci_bus": "0000:18:00.0",
+	}
+	tileTags := func(tile string) map[string]string {
+		result := map[string]string{"tile": tile}
+		for k, v := range tags {
+			result[k] = v
+		}
+		return result
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("intel_xpu_smi", tags,
+			map[string]interface{}{
+				"power_watts":                       412.5,
+				"energy_millijoules":                1536000.0,
+				"frequency_mhz":                     1600.0,
+				"memory_used_mib":                   24576.0,
+				"memory_read_kilobytes_per_second":  104857600.0,
+				"memory_write_kilobytes_per_second": 52428800.0,
+				"temperature_core_celsius":          48.0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("intel_xpu_smi_tile", tileTags("0"),
+			map[string]interface{}{
+				"power_watts":                       210.25,
+				"frequency_mhz":                     1600.0,
+				"memory_read_kilobytes_per_second":  62914560.0,
+				"memory_write_kilobytes_per_second": 31457280.0,
+				"eu_active_percent":                 87.5,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("intel_xpu_smi_tile", tileTags("1"),
+			map[string]interface{}{
+				"power_watts":                       202.25,
+				"frequency_mhz":                     1550.0,
+				"memory_read_kilobytes_per_second":  41943040.0,
+				"memory_write_kilobytes_per_second": 20971520.0,
+				"eu_active_percent":                 62.5,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherMissingBinary(t *testing.T) {
+	x := &XPUSMI{BinPath: "/nonexistent/xpu-smi"}
+
+	var acc testutil.Accumulator
+	require.Error(t, x.Gather(&acc))
+}
+
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the output of xpu-smi for the subcommand.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	// Skip "--" and the command
+	args = args[2:]
+
+	var file string
+	switch args[0] {
+	case "discovery":
+		file = "discovery.json"
+	case "stats":
+		file = fmt.Sprintf("stats-%s.json", args[2])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q", strings.Join(args, " "))
+		os.Exit(1)
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
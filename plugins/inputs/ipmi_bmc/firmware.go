@@ -0,0 +1,126 @@
+package ipmi_bmc
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// firmwareSchedule tracks when the firmware inventory of the BMCs was last
+// read, as it only changes with updates.
+type firmwareSchedule struct {
+	sync.Mutex
+	last map[string]time.Time
+}
+
+// due returns true if the inventory of the server was not read within the
+// interval.
+func (s *firmwareSchedule) due(server string, interval time.Duration, now time.Time) bool {
+	s.Lock()
+	defer s.Unlock()
+	last, ok := s.last[server]
+	return !ok || now.Sub(last) >= interval
+}
+
+// done records that the inventory of the server was read.
+func (s *firmwareSchedule) done(server string, now time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	if s.last == nil {
+		s.last = make(map[string]time.Time)
+	}
+	s.last[server] = now
+}
+
+// gatherFirmware adds the firmware inventory of the BMC, read from the
+// output of "ipmitool mc info", and of the BIOS, read with "ipmitool mc
+// getsysinfo system_fw_version".  BMCs without the system info parameters
+// are inventoried without BIOS.
+func (m *IpmiBmc) gatherFirmware(acc telegraf.Accumulator, server string, opts []string, tags map[string]string, info []byte, timestamp time.Time) {
+	if !m.firmware.due(server, m.FirmwareInterval.Duration, timestamp) {
+		return
+	}
+
+	acc.AddFields("ipmi_bmc_firmware", parseFirmware(info), firmwareTags(tags, "bmc"), timestamp)
+
+	out, err := m.run(append(opts, "mc", "getsysinfo", "system_fw_version")...)
+	if err != nil {
+		m.Log.Debugf("Skipping the BIOS version of %q: %v", tags["server"], err)
+	} else if version := strings.TrimSpace(string(out)); version != "" {
+		acc.AddFields("ipmi_bmc_firmware", map[string]interface{}{"version": version},
+			firmwareTags(tags, "bios"), timestamp)
+	}
+
+	m.firmware.done(server, timestamp)
+}
+
+func firmwareTags(tags map[string]string, component string) map[string]string {
+	t := map[string]string{"component": component}
+	for k, v := range tags {
+		t[k] = v
+	}
+	return t
+}
+
+// parseFirmware returns the inventory fields of the BMC from the output of
+// "ipmitool mc info".  The auxiliary revision follows on separate lines,
+// some BMCs change it with firmware builds of the same revision:
+//
+//	Firmware Revision         : 2.61
+//	...
+//	Aux Firmware Rev Info     :
+//	    0x00
+//	    0x1c
+func parseFirmware(out []byte) map[string]interface{} {
+	fields := make(map[string]interface{})
+	var aux []string
+	inAux := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if inAux && strings.HasPrefix(line, "0x") {
+			aux = append(aux, strings.TrimPrefix(line, "0x"))
+			continue
+		}
+		inAux = false
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "Firmware Revision":
+			fields["version"] = value
+		case "Aux Firmware Rev Info":
+			inAux = true
+		case "Manufacturer ID":
+			if v, err := parseID(value); err == nil {
+				fields["manufacturer_id"] = v
+			}
+		case "Manufacturer Name":
+			fields["manufacturer"] = value
+		case "Product ID":
+			if v, err := parseID(value); err == nil {
+				fields["product_id"] = v
+			}
+		}
+	}
+	if len(aux) > 0 {
+		fields["aux_revision"] = strings.Join(aux, "")
+	}
+	return fields
+}
+
+// parseID parses ids like "6929 (0x1b11)".
+func parseID(value string) (int64, error) {
+	if i := strings.IndexByte(value, ' '); i >= 0 {
+		value = value[:i]
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
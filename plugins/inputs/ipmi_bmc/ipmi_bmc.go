@@ -0,0 +1,326 @@
+package ipmi_bmc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// codeRe matches the code printed after the watchdog timer use and action,
+// such as "Hard Reset (0x01)".
+var codeRe = regexp.MustCompile(`\s*\(0x[0-9a-fA-F]+\)$`)
+
+// IpmiBmc stores the configuration values for the ipmi_bmc input plugin
+type IpmiBmc struct {
+	Path        string            `toml:"path"`
+	UseSudo     bool              `toml:"use_sudo"`
+	SudoCommand []string          `toml:"sudo_command"`
+	Privilege   string            `toml:"privilege"`
+	Servers     []string          `toml:"servers"`
+	Interface   string            `toml:"interface"`
+	Timeout     internal.Duration `toml:"timeout"`
+	SelfTest    bool              `toml:"selftest"`
+	Watchdog    bool              `toml:"watchdog"`
+
+	FirmwareInventory bool              `toml:"firmware_inventory"`
+	FirmwareInterval  internal.Duration `toml:"firmware_interval"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	firmware firmwareSchedule
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## optionally specify one or more servers via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, the local BMC will be queried
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## BMC health changes slowly, there is no need to query it often
+  interval = "5m"
+
+  ## Timeout for each ipmitool command to complete
+  timeout = "20s"
+
+  ## Read the result of the BMC self test with "ipmitool mc selftest"
+  # selftest = true
+
+  ## Read the state of the watchdog timer with "ipmitool mc watchdog get"
+  # watchdog = true
+
+  ## Add the firmware versions of the BMC and BIOS as ipmi_bmc_firmware
+  ## metrics, read with "ipmitool mc info" and "ipmitool mc getsysinfo
+  ## system_fw_version".  They only change with updates and are read once per
+  ## firmware_interval.
+  # firmware_inventory = false
+  # firmware_interval = "1h"
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *IpmiBmc) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *IpmiBmc) Description() string {
+	return "Read the health, self test result and watchdog state of BMCs via IPMI"
+}
+
+// Init locates ipmitool.
+func (m *IpmiBmc) Init() error {
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *IpmiBmc) Gather(acc telegraf.Accumulator) error {
+	if len(m.Servers) == 0 {
+		return m.gatherServer(acc, "")
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			if err := m.gatherServer(acc, s); err != nil {
+				acc.AddError(err)
+			}
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (m *IpmiBmc) gatherServer(acc telegraf.Accumulator, server string) error {
+	var opts []string
+	tags := map[string]string{}
+	if server != "" {
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			return err
+		}
+		tags["server"] = conn.Hostname
+		opts = conn.Options()
+	}
+
+	// A BMC not answering is reported, as it is about to drop the readings
+	// of the other inputs
+	out, err := m.run(append(opts, "mc", "info")...)
+	timestamp := time.Now()
+	if err != nil {
+		acc.AddFields("ipmi_bmc", map[string]interface{}{"responding": false}, tags, timestamp)
+		return err
+	}
+	fields, err := parseInfo(out)
+	if err != nil {
+		return fmt.Errorf("parsing mc info of %s: %v", tags["server"], err)
+	}
+	fields["responding"] = true
+
+	if m.FirmwareInventory {
+		m.gatherFirmware(acc, server, opts, tags, out, timestamp)
+	}
+
+	if m.SelfTest {
+		out, err := m.run(append(opts, "mc", "selftest")...)
+		if err != nil {
+			acc.AddError(err)
+		} else if err := parseSelfTest(out, fields); err != nil {
+			acc.AddError(fmt.Errorf("parsing mc selftest of %s: %v", tags["server"], err))
+		}
+	}
+
+	if m.Watchdog {
+		out, err := m.run(append(opts, "mc", "watchdog", "get")...)
+		if err != nil {
+			acc.AddError(err)
+		} else if err := parseWatchdog(out, fields); err != nil {
+			acc.AddError(fmt.Errorf("parsing mc watchdog get of %s: %v", tags["server"], err))
+		}
+	}
+
+	acc.AddFields("ipmi_bmc", fields, tags, timestamp)
+	return nil
+}
+
+// run runs ipmitool with the arguments.
+func (m *IpmiBmc) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        m.Path,
+		UseSudo:     m.UseSudo,
+		SudoCommand: m.SudoCommand,
+		Timeout:     m.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// parseInfo parses the output of "ipmitool mc info", with lines like
+// "Firmware Revision         : 2.61".
+func parseInfo(out []byte) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	for key, value := range parseColumns(out) {
+		switch key {
+		case "Firmware Revision":
+			fields["firmware_revision"] = value
+		case "IPMI Version":
+			fields["ipmi_version"] = value
+		case "Device Available":
+			fields["device_available"] = value == "yes"
+		}
+	}
+	if _, ok := fields["firmware_revision"]; !ok {
+		return nil, fmt.Errorf("no firmware revision found in output: %s", string(out))
+	}
+	return fields, nil
+}
+
+// parseSelfTest parses the output of "ipmitool mc selftest", the result
+// followed by the failed devices:
+//
+//	Selftest: device error
+//	 -> SEL device not accessible
+func parseSelfTest(out []byte, fields map[string]interface{}) error {
+	var result string
+	var failures []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "Selftest:") {
+			result = strings.TrimSpace(strings.TrimPrefix(line, "Selftest:"))
+		} else if strings.HasPrefix(line, "->") {
+			failures = append(failures, strings.TrimSpace(strings.TrimPrefix(line, "->")))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if result == "" {
+		return fmt.Errorf("no self test result found in output: %s", string(out))
+	}
+
+	fields["selftest_result"] = result
+	fields["selftest_passed"] = result == "passed"
+	fields["selftest_errors"] = strings.Join(failures, ", ")
+	return nil
+}
+
+// parseWatchdog parses the output of "ipmitool mc watchdog get", with lines
+// like "Watchdog Timer Is:      Started/Running".
+func parseWatchdog(out []byte, fields map[string]interface{}) error {
+	found := false
+	for key, value := range parseColumns(out) {
+		switch key {
+		case "Watchdog Timer Is":
+			fields["watchdog_running"] = value == "Started/Running"
+			found = true
+		case "Watchdog Timer Use":
+			fields["watchdog_timer_use"] = codeRe.ReplaceAllString(value, "")
+		case "Watchdog Timer Action", "Watchdog Timer Actions":
+			fields["watchdog_action"] = codeRe.ReplaceAllString(value, "")
+		case "Initial Countdown":
+			if v, err := parseSeconds(value); err == nil {
+				fields["watchdog_initial_countdown_seconds"] = v
+			}
+		case "Present Countdown":
+			if v, err := parseSeconds(value); err == nil {
+				fields["watchdog_present_countdown_seconds"] = v
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("no watchdog state found in output: %s", string(out))
+	}
+	return nil
+}
+
+// parseSeconds parses countdowns like "587.3 sec".
+func parseSeconds(value string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "sec")), 64)
+}
+
+// parseColumns returns the values of lines like "key : value" by their
+// key.
+func parseColumns(out []byte) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return values
+}
+
+func init() {
+	inputs.Add("ipmi_bmc", func() telegraf.Input {
+		return &IpmiBmc{
+			Timeout:  internal.Duration{Duration: time.Second * 20},
+			SelfTest: true,
+			Watchdog: true,
+
+			FirmwareInterval: internal.Duration{Duration: time.Hour},
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package ipmi_bmc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiBmc{
+		Path:     os.Args[0],
+		Servers:  []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:  internal.Duration{Duration: time.Second * 5},
+		SelfTest: true,
+		Watchdog: true,
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_bmc",
+		map[string]interface{}{
+			"responding":                         true,
+			"firmware_revision":                  "2.61",
+			"ipmi_version":                       "2.0",
+			"device_available":                   true,
+			"selftest_result":                    "device error",
+			"selftest_passed":                    false,
+			"selftest_errors":                    "SEL device not accessible, FRU device not accessible",
+			"watchdog_running":                   true,
+			"watchdog_timer_use":                 "SMS/OS",
+			"watchdog_action":                    "Hard Reset",
+			"watchdog_initial_countdown_seconds": 600.0,
+			"watchdog_present_countdown_seconds": 587.3,
+		},
+		map[string]string{
+			"server": "192.168.1.1",
+		})
+}
+
+func TestGatherNotResponding(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_FAILURE=Error: Unable to establish IPMI v2 / RMCP+ session")
+		return cmd
+	}
+
+	i := &IpmiBmc{
+		Path:     os.Args[0],
+		Servers:  []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:  internal.Duration{Duration: time.Second * 5},
+		SelfTest: true,
+		Watchdog: true,
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "Unable to establish")
+	require.NotContains(t, acc.Errors[0].Error(), "PASSW0RD")
+	acc.AssertContainsTaggedFields(t, "ipmi_bmc",
+		map[string]interface{}{"responding": false},
+		map[string]string{"server": "192.168.1.1"})
+}
+
+func TestGatherFirmware(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiBmc{
+		Path:              os.Args[0],
+		Servers:           []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:           internal.Duration{Duration: time.Second * 5},
+		FirmwareInventory: true,
+		FirmwareInterval:  internal.Duration{Duration: time.Hour},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_bmc_firmware",
+		map[string]interface{}{
+			"version":         "2.61",
+			"aux_revision":    "00000000",
+			"manufacturer_id": int64(10876),
+			"manufacturer":    "Super Micro Computer Inc.",
+			"product_id":      int64(6929),
+		},
+		map[string]string{"server": "192.168.1.1", "component": "bmc"})
+	acc.AssertContainsTaggedFields(t, "ipmi_bmc_firmware",
+		map[string]interface{}{"version": "3.4a"},
+		map[string]string{"server": "192.168.1.1", "component": "bios"})
+
+	// The inventory is only read again after the interval
+	acc.ClearMetrics()
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	require.False(t, acc.HasMeasurement("ipmi_bmc_firmware"))
+}
+
+func TestGatherFirmwareWithoutSysInfo(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_NO_SYSINFO=1")
+		return cmd
+	}
+
+	i := &IpmiBmc{
+		Path:              os.Args[0],
+		Timeout:           internal.Duration{Duration: time.Second * 5},
+		FirmwareInventory: true,
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_bmc_firmware",
+		map[string]interface{}{
+			"version":         "2.61",
+			"aux_revision":    "00000000",
+			"manufacturer_id": int64(10876),
+			"manufacturer":    "Super Micro Computer Inc.",
+			"product_id":      int64(6929),
+		},
+		map[string]string{"component": "bmc"})
+	require.Len(t, acc.Metrics, 2)
+}
+
+func TestParseSelfTest(t *testing.T) {
+	fields := make(map[string]interface{})
+	require.NoError(t, parseSelfTest([]byte("Selftest: passed\n"), fields))
+	require.Equal(t, map[string]interface{}{
+		"selftest_result": "passed",
+		"selftest_passed": true,
+		"selftest_errors": "",
+	}, fields)
+
+	require.Error(t, parseSelfTest([]byte("Could not open device at /dev/ipmi0\n"), fields))
+}
+
+func TestParseWatchdog(t *testing.T) {
+	// Older ipmitool versions
+	fields := make(map[string]interface{})
+	require.NoError(t, parseWatchdog([]byte(`Watchdog Timer Use:     Reserved (0x00)
+Watchdog Timer Is:      Stopped
+Watchdog Timer Actions: No action (0x00)
+Pre-timeout interval:   0 seconds
+Timer Expiration Flags: 0x00
+Initial Countdown:      0 sec
+Present Countdown:      0 sec
+`), fields))
+	require.Equal(t, map[string]interface{}{
+		"watchdog_running":                   false,
+		"watchdog_timer_use":                 "Reserved",
+		"watchdog_action":                    "No action",
+		"watchdog_initial_countdown_seconds": 0.0,
+		"watchdog_present_countdown_seconds": 0.0,
+	}, fields)
+
+	require.Error(t, parseWatchdog([]byte("Invalid command\n"), fields))
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the captures in testdata for the "mc" commands.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	if failure, ok := os.LookupEnv("FAKE_IPMI_FAILURE"); ok {
+		fmt.Fprint(os.Stdout, failure)
+		os.Exit(1)
+	}
+
+	var capture string
+	cmd := strings.Join(os.Args, " ")
+	switch {
+	case strings.HasSuffix(cmd, "mc info"):
+		capture = "mc_info.txt"
+	case strings.HasSuffix(cmd, "mc selftest"):
+		capture = "mc_selftest.txt"
+	case strings.HasSuffix(cmd, "mc watchdog get"):
+		capture = "mc_watchdog_get.txt"
+	case strings.HasSuffix(cmd, "mc getsysinfo system_fw_version"):
+		if os.Getenv("FAKE_IPMI_NO_SYSINFO") == "1" {
+			fmt.Fprint(os.Stdout, "Invalid command")
+			os.Exit(1)
+		}
+		capture = "mc_getsysinfo_system_fw_version.txt"
+	default:
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", capture))
+	if err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
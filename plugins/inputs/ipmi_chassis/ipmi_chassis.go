@@ -0,0 +1,288 @@
+package ipmi_chassis
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// flags are the states printed by "ipmitool chassis status" reported as
+// boolean fields, and the value of the state when set.
+var flags = map[string]struct {
+	field string
+	set   string
+}{
+	"System Power":        {"power_on", "on"},
+	"Power Overload":      {"power_overload", "true"},
+	"Power Interlock":     {"power_interlock", "active"},
+	"Main Power Fault":    {"main_power_fault", "true"},
+	"Power Control Fault": {"power_control_fault", "true"},
+	"Chassis Intrusion":   {"chassis_intrusion", "active"},
+	"Front-Panel Lockout": {"front_panel_lockout", "active"},
+	"Drive Fault":         {"drive_fault", "true"},
+	"Cooling/Fan Fault":   {"cooling_fault", "true"},
+}
+
+// faults are the fields counted as faults.
+var faults = []string{
+	"power_overload",
+	"power_interlock",
+	"main_power_fault",
+	"power_control_fault",
+	"drive_fault",
+	"cooling_fault",
+}
+
+// identifyStates are the states of the chassis identify LED in bits 4 and 5
+// of the misc chassis state returned by Get Chassis Status.
+var identifyStates = []string{"off", "temporary", "indefinite", "reserved"}
+
+// IpmiChassis stores the configuration values for the ipmi_chassis input
+// plugin
+type IpmiChassis struct {
+	Path        string            `toml:"path"`
+	UseSudo     bool              `toml:"use_sudo"`
+	SudoCommand []string          `toml:"sudo_command"`
+	Privilege   string            `toml:"privilege"`
+	Servers     []string          `toml:"servers"`
+	Interface   string            `toml:"interface"`
+	Timeout     internal.Duration `toml:"timeout"`
+
+	IdentifyLED bool `toml:"identify_led"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## optionally specify one or more servers via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, the local chassis will be queried
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## Recommended: use metric 'interval' that is a multiple of 'timeout' to avoid
+  ## gaps or overlap in pulled data
+  interval = "30s"
+
+  ## Timeout for each ipmitool command to complete
+  timeout = "20s"
+
+  ## Read the state of the chassis identify LED with an additional raw Get
+  ## Chassis Status command, ipmitool does not print it.
+  # identify_led = false
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *IpmiChassis) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *IpmiChassis) Description() string {
+	return "Read the chassis power state and faults of bare metal servers via IPMI"
+}
+
+// Init locates ipmitool.
+func (m *IpmiChassis) Init() error {
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *IpmiChassis) Gather(acc telegraf.Accumulator) error {
+	if len(m.Servers) == 0 {
+		return m.gatherServer(acc, "")
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			if err := m.gatherServer(acc, s); err != nil {
+				acc.AddError(err)
+			}
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (m *IpmiChassis) gatherServer(acc telegraf.Accumulator, server string) error {
+	var opts []string
+	hostname := ""
+	if server != "" {
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			return err
+		}
+		hostname = conn.Hostname
+		opts = conn.Options()
+	}
+
+	out, err := m.run(append(opts, "chassis", "status")...)
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	fields, err := parseStatus(out)
+	if err != nil {
+		return fmt.Errorf("parsing chassis status of %s: %v", hostname, err)
+	}
+
+	// The chassis status is still reported if the LED state is unavailable
+	if m.IdentifyLED {
+		if err := m.gatherIdentify(opts, fields); err != nil {
+			acc.AddError(fmt.Errorf("identify LED of %s: %v", hostname, err))
+		}
+	}
+
+	tags := map[string]string{}
+	if hostname != "" {
+		tags["server"] = hostname
+	}
+	acc.AddFields("ipmi_chassis", fields, tags, timestamp)
+	return nil
+}
+
+// gatherIdentify adds the state of the identify LED to the fields, it is
+// skipped if the BMC does not report it.
+func (m *IpmiChassis) gatherIdentify(opts []string, fields map[string]interface{}) error {
+	out, err := m.run(append(opts, "raw", "0x00", "0x01")...)
+	if err != nil {
+		return err
+	}
+	data, err := ipmi.ParseRawResponse(out)
+	if err != nil {
+		return fmt.Errorf("%v in output: %s", err, string(out))
+	}
+	if len(data) < 3 {
+		return fmt.Errorf("short chassis status response: % x", data)
+	}
+	// Bit 6 tells whether the state is reported
+	if data[2]&0x40 == 0 {
+		m.Log.Debug("Identify LED state not supported")
+		return nil
+	}
+	state := identifyStates[data[2]>>4&0x03]
+	fields["identify_led"] = state
+	fields["identify_led_on"] = state == "temporary" || state == "indefinite"
+	return nil
+}
+
+// run runs ipmitool with the arguments.
+func (m *IpmiChassis) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        m.Path,
+		UseSudo:     m.UseSudo,
+		SudoCommand: m.SudoCommand,
+		Timeout:     m.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// parseStatus parses the output of "ipmitool chassis status", with lines
+// like "Drive Fault          : false".
+func parseStatus(out []byte) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		if flag, ok := flags[key]; ok {
+			fields[flag.field] = value == flag.set
+			continue
+		}
+		switch key {
+		case "Power Restore Policy":
+			fields["power_restore_policy"] = value
+		case "Last Power Event":
+			// Empty if the last power event was not caused by a fault
+			fields["last_power_event"] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["power_on"]; !ok {
+		return nil, fmt.Errorf("no power state found in output: %s", string(out))
+	}
+
+	var count int64
+	for _, f := range faults {
+		if set, _ := fields[f].(bool); set {
+			count++
+		}
+	}
+	fields["faults"] = count
+	return fields, nil
+}
+
+func init() {
+	inputs.Add("ipmi_chassis", func() telegraf.Input {
+		return &IpmiChassis{
+			Timeout: internal.Duration{Duration: time.Second * 20},
+		}
+	})
+}
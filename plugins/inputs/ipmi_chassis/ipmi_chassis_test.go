@@ -0,0 +1,183 @@
+package ipmi_chassis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiChassis{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_chassis",
+		map[string]interface{}{
+			"power_on":             true,
+			"power_overload":       false,
+			"power_interlock":      false,
+			"main_power_fault":     false,
+			"power_control_fault":  false,
+			"chassis_intrusion":    false,
+			"front_panel_lockout":  false,
+			"drive_fault":          true,
+			"cooling_fault":        false,
+			"power_restore_policy": "previous",
+			"last_power_event":     "ac-failed",
+			"faults":               int64(1),
+		},
+		map[string]string{
+			"server": "192.168.1.1",
+		})
+}
+
+func TestGatherIdentifyLED(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiChassis{
+		Path:        os.Args[0],
+		Servers:     []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:     internal.Duration{Duration: time.Second * 5},
+		IdentifyLED: true,
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+
+	fields := acc.Metrics[0].Fields
+	require.Equal(t, "temporary", fields["identify_led"])
+	require.Equal(t, true, fields["identify_led_on"])
+	require.Equal(t, true, fields["power_on"])
+}
+
+func TestGatherIdentifyLEDUnsupported(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_NO_IDENTIFY=1")
+		return cmd
+	}
+
+	i := &IpmiChassis{
+		Path:        os.Args[0],
+		Timeout:     internal.Duration{Duration: time.Second * 5},
+		IdentifyLED: true,
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	require.NotContains(t, acc.Metrics[0].Fields, "identify_led")
+	require.NotContains(t, acc.Metrics[0].Fields, "identify_led_on")
+}
+
+func TestGatherError(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_FAILURE=Error: Unable to establish IPMI v2 / RMCP+ session")
+		return cmd
+	}
+
+	i := &IpmiChassis{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "Unable to establish")
+	require.NotContains(t, acc.Errors[0].Error(), "PASSW0RD")
+}
+
+func TestParseStatus(t *testing.T) {
+	fields, err := parseStatus([]byte("System Power         : off\nLast Power Event     : \nCooling/Fan Fault    : true\n"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"power_on":         false,
+		"last_power_event": "",
+		"cooling_fault":    true,
+		"faults":           int64(1),
+	}, fields)
+
+	_, err = parseStatus([]byte("Could not open device at /dev/ipmi0\n"))
+	require.Error(t, err)
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the capture in testdata for "chassis status" and the response of
+// the raw Get Chassis Status command.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	if failure, ok := os.LookupEnv("FAKE_IPMI_FAILURE"); ok {
+		fmt.Fprint(os.Stdout, failure)
+		os.Exit(1)
+	}
+
+	cmd := strings.Join(os.Args, " ")
+	if strings.HasSuffix(cmd, "raw 0x00 0x01") {
+		if os.Getenv("FAKE_IPMI_NO_IDENTIFY") == "1" {
+			fmt.Fprint(os.Stdout, " 01 10 00 00\n")
+		} else {
+			fmt.Fprint(os.Stdout, " 01 10 50 00\n")
+		}
+		os.Exit(0)
+	}
+	if !strings.HasSuffix(cmd, "chassis status") {
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", "chassis_status.txt"))
+	if err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
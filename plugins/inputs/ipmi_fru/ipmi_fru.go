@@ -0,0 +1,265 @@
+package ipmi_fru
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// deviceHeader matches the line starting a FRU device in the output of
+// "ipmitool fru print", e.g. "FRU Device Description : PS1 (ID 1)".
+var deviceHeader = regexp.MustCompile(`^FRU Device Description\s*:\s*(.*?)\s*(?:\(ID (\d+)\))?$`)
+
+// IpmiFru stores the configuration values for the ipmi_fru input plugin
+type IpmiFru struct {
+	Path        string            `toml:"path"`
+	UseSudo     bool              `toml:"use_sudo"`
+	SudoCommand []string          `toml:"sudo_command"`
+	Privilege   string            `toml:"privilege"`
+	Servers     []string          `toml:"servers"`
+	Interface   string            `toml:"interface"`
+	Timeout     internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// device is a FRU device listed by "ipmitool fru print".
+type device struct {
+	description string
+	id          string
+	present     bool
+	info        map[string]string
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## optionally specify one or more servers via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, the local FRU inventory will be queried
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## The inventory rarely changes, gather it infrequently.
+  interval = "1h"
+
+  ## Timeout for each ipmitool command to complete
+  timeout = "20s"
+
+  ## Use taginclude to limit the inventory keys reported as tags, e.g.
+  # taginclude = ["server", "fru", "fru_id", "product_*", "board_serial"]
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *IpmiFru) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *IpmiFru) Description() string {
+	return "Read the FRU inventory of bare metal servers via IPMI"
+}
+
+// Init locates ipmitool.
+func (m *IpmiFru) Init() error {
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *IpmiFru) Gather(acc telegraf.Accumulator) error {
+	if len(m.Servers) == 0 {
+		return m.gatherServer(acc, "")
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			if err := m.gatherServer(acc, s); err != nil {
+				acc.AddError(err)
+			}
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (m *IpmiFru) gatherServer(acc telegraf.Accumulator, server string) error {
+	var opts []string
+	hostname := ""
+	if server != "" {
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			return err
+		}
+		hostname = conn.Hostname
+		opts = conn.Options()
+	}
+
+	out, err := m.run(append(opts, "fru", "print")...)
+	if err != nil {
+		// ipmitool fails if any device is not present but prints the others
+		if bytes.Contains(out, []byte("FRU Device Description")) {
+			m.Log.Debugf("Listing FRU devices of %q: %v", hostname, err)
+		} else {
+			return err
+		}
+	}
+	timestamp := time.Now()
+	devices, err := parseDevices(out)
+	if err != nil {
+		return fmt.Errorf("parsing FRU devices of %s: %v", hostname, err)
+	}
+
+	for _, d := range devices {
+		tags := map[string]string{
+			"fru": d.description,
+		}
+		if d.id != "" {
+			tags["fru_id"] = d.id
+		}
+		for key, value := range d.info {
+			tags[key] = value
+		}
+		if hostname != "" {
+			tags["server"] = hostname
+		}
+		acc.AddFields("ipmi_fru", map[string]interface{}{"present": d.present}, tags, timestamp)
+	}
+	return nil
+}
+
+// run runs ipmitool with the arguments and returns the output even if
+// ipmitool failed.
+func (m *IpmiFru) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        m.Path,
+		UseSudo:     m.UseSudo,
+		SudoCommand: m.SudoCommand,
+		Timeout:     m.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// parseDevices parses the devices printed by "ipmitool fru print", each
+// device starts with its description followed by indented lines like
+// " Product Serial : S123456X8C12345".  Repeated keys, such as the extra
+// product information, are joined with commas.
+func parseDevices(out []byte) ([]*device, error) {
+	var devices []*device
+	var d *device
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := deviceHeader.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			d = &device{
+				description: match[1],
+				id:          match[2],
+				present:     true,
+				info:        make(map[string]string),
+			}
+			devices = append(devices, d)
+			continue
+		}
+		if d == nil {
+			continue
+		}
+
+		text := strings.TrimSpace(line)
+		if strings.HasPrefix(text, "Device not present") {
+			d.present = false
+			continue
+		}
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := transform(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if key == "" || value == "" {
+			continue
+		}
+		if v, ok := d.info[key]; ok {
+			value = v + "," + value
+		}
+		d.info[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no FRU devices found in output: %s", string(out))
+	}
+	return devices, nil
+}
+
+func transform(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ToLower(s)
+	return strings.Replace(s, " ", "_", -1)
+}
+
+func init() {
+	inputs.Add("ipmi_fru", func() telegraf.Input {
+		return &IpmiFru{
+			Timeout: internal.Duration{Duration: time.Second * 20},
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package ipmi_fru
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiFru{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_fru",
+		map[string]interface{}{"present": true},
+		map[string]string{
+			"server":               "192.168.1.1",
+			"fru":                  "Builtin FRU Device",
+			"fru_id":               "0",
+			"chassis_type":         "Rack Mount Chassis",
+			"chassis_part_number":  "CSE-819UTS-R1K02P-T",
+			"chassis_serial":       "C8190LH51NA0123",
+			"board_mfg_date":       "Mon Jan  1 00:00:00 1996",
+			"board_mfg":            "Supermicro",
+			"board_product":        "X11DPU",
+			"board_serial":         "ZM18AS012345",
+			"board_part_number":    "X11DPU",
+			"product_manufacturer": "Supermicro",
+			"product_name":         "SYS-1029U-TRTP",
+			"product_part_number":  "SYS-1029U-TRTP",
+			"product_version":      "0123456789",
+			"product_serial":       "S123456X8C12345",
+			"product_extra":        "rev 1.02,BIOS 3.4",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_fru",
+		map[string]interface{}{"present": true},
+		map[string]string{
+			"server":            "192.168.1.1",
+			"fru":               "PS1",
+			"fru_id":            "1",
+			"board_mfg":         "SUPERMICRO",
+			"board_product":     "PWS-1K02A-1R",
+			"board_serial":      "P1K02AJ12A3456",
+			"board_part_number": "PWS-1K02A-1R",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_fru",
+		map[string]interface{}{"present": false},
+		map[string]string{
+			"server": "192.168.1.1",
+			"fru":    "PS2",
+			"fru_id": "2",
+		})
+}
+
+func TestGatherPartialFailure(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_EXIT_CODE=1")
+		return cmd
+	}
+
+	i := &IpmiFru{
+		Path:    os.Args[0],
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Metrics, 3)
+}
+
+func TestGatherError(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_FAILURE=Error: Unable to establish IPMI v2 / RMCP+ session")
+		return cmd
+	}
+
+	i := &IpmiFru{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "Unable to establish")
+	require.NotContains(t, acc.Errors[0].Error(), "PASSW0RD")
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the capture in testdata for "fru print".
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	if failure, ok := os.LookupEnv("FAKE_IPMI_FAILURE"); ok {
+		fmt.Fprint(os.Stdout, failure)
+		os.Exit(1)
+	}
+
+	if !strings.HasSuffix(strings.Join(os.Args, " "), "fru print") {
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", "fru_print.txt"))
+	if err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	if os.Getenv("FAKE_IPMI_EXIT_CODE") == "1" {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
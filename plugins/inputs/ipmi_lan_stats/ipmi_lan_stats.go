@@ -0,0 +1,234 @@
+package ipmi_lan_stats
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// counters maps the statistics printed by "ipmitool lan stats get" to
+// fields.
+var counters = map[string]string{
+	"IP Rx Packet":              "ip_rx_packets",
+	"IP Rx Header Errors":       "ip_rx_header_errors",
+	"IP Rx Address Errors":      "ip_rx_address_errors",
+	"IP Rx Fragmented":          "ip_rx_fragmented",
+	"IP Tx Packet":              "ip_tx_packets",
+	"UDP Rx Packet":             "udp_rx_packets",
+	"RMCP Rx Valid":             "rmcp_rx_valid",
+	"UDP Proxy Packet Received": "udp_proxy_rx_packets",
+	"UDP Proxy Packet Dropped":  "udp_proxy_dropped_packets",
+}
+
+// IpmiLanStats stores the configuration values for the ipmi_lan_stats
+// input plugin
+type IpmiLanStats struct {
+	Path        string            `toml:"path"`
+	UseSudo     bool              `toml:"use_sudo"`
+	SudoCommand []string          `toml:"sudo_command"`
+	Privilege   string            `toml:"privilege"`
+	Servers     []string          `toml:"servers"`
+	Interface   string            `toml:"interface"`
+	Timeout     internal.Duration `toml:"timeout"`
+	Channels    []int             `toml:"channels"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## optionally specify one or more servers via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, the local BMC will be queried
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## LAN channels of the BMCs to read the statistics of.  Most BMCs have
+  ## their dedicated or shared NIC on channel 1, some use 2, 3 or 8.
+  # channels = [1]
+
+  ## Timeout for each ipmitool command to complete
+  timeout = "20s"
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *IpmiLanStats) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *IpmiLanStats) Description() string {
+	return "Read the packet and error counters of the LAN channels of BMCs via IPMI"
+}
+
+// Init locates ipmitool and checks the channels.
+func (m *IpmiLanStats) Init() error {
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+
+	if len(m.Channels) == 0 {
+		m.Channels = []int{1}
+	}
+	for _, c := range m.Channels {
+		if c < 0 || c > 15 {
+			return fmt.Errorf("invalid channel %d, must be between 0 and 15", c)
+		}
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *IpmiLanStats) Gather(acc telegraf.Accumulator) error {
+	if len(m.Servers) == 0 {
+		m.gatherServer(acc, "")
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			m.gatherServer(acc, s)
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (m *IpmiLanStats) gatherServer(acc telegraf.Accumulator, server string) {
+	var opts []string
+	hostname := ""
+	if server != "" {
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			acc.AddError(err)
+			return
+		}
+		hostname = conn.Hostname
+		opts = conn.Options()
+	}
+
+	for _, channel := range m.Channels {
+		out, err := m.run(append(opts, "lan", "stats", "get", strconv.Itoa(channel))...)
+		timestamp := time.Now()
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		fields, err := parseStats(out)
+		if err != nil {
+			acc.AddError(fmt.Errorf("parsing lan stats of channel %d of %s: %v", channel, hostname, err))
+			continue
+		}
+
+		tags := map[string]string{"channel": strconv.Itoa(channel)}
+		if hostname != "" {
+			tags["server"] = hostname
+		}
+		acc.AddCounter("ipmi_lan_stats", fields, tags, timestamp)
+	}
+}
+
+// run runs ipmitool with the arguments.
+func (m *IpmiLanStats) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        m.Path,
+		UseSudo:     m.UseSudo,
+		SudoCommand: m.SudoCommand,
+		Timeout:     m.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// parseStats parses the output of "ipmitool lan stats get", with lines like
+// "IP Rx Packet              : 1536".
+func parseStats(out []byte) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		field, ok := counters[strings.TrimSpace(parts[0])]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %v", field, err)
+		}
+		fields[field] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no statistics found in output: %s", string(out))
+	}
+	return fields, nil
+}
+
+func init() {
+	inputs.Add("ipmi_lan_stats", func() telegraf.Input {
+		return &IpmiLanStats{
+			Timeout: internal.Duration{Duration: time.Second * 20},
+		}
+	})
+}
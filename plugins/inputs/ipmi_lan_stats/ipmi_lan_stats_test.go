@@ -0,0 +1,127 @@
+package ipmi_lan_stats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiLanStats{
+		Path:     os.Args[0],
+		Servers:  []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:  internal.Duration{Duration: time.Second * 5},
+		Channels: []int{1, 2},
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+
+	// Channel 2 is not a LAN channel of the fake BMC
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "not a LAN channel")
+	require.NotContains(t, acc.Errors[0].Error(), "PASSW0RD")
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_lan_stats",
+		map[string]interface{}{
+			"ip_rx_packets":             uint64(48213),
+			"ip_rx_header_errors":       uint64(12),
+			"ip_rx_address_errors":      uint64(0),
+			"ip_rx_fragmented":          uint64(0),
+			"ip_tx_packets":             uint64(47980),
+			"udp_rx_packets":            uint64(48002),
+			"rmcp_rx_valid":             uint64(47917),
+			"udp_proxy_rx_packets":      uint64(0),
+			"udp_proxy_dropped_packets": uint64(0),
+		},
+		map[string]string{
+			"server":  "192.168.1.1",
+			"channel": "1",
+		})
+}
+
+func TestGatherLocal(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiLanStats{
+		Path:    os.Args[0],
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+	require.Equal(t, []int{1}, i.Channels)
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, map[string]string{"channel": "1"}, acc.Metrics[0].Tags)
+}
+
+func TestInitInvalidChannel(t *testing.T) {
+	i := &IpmiLanStats{
+		Path:     os.Args[0],
+		Channels: []int{16},
+	}
+	require.Error(t, i.Init())
+}
+
+func TestParseStats(t *testing.T) {
+	_, err := parseStats([]byte("Get LAN Stats command failed\n"))
+	require.Error(t, err)
+
+	_, err = parseStats([]byte("IP Rx Packet              : many\n"))
+	require.Error(t, err)
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the capture in testdata for "lan stats get" of channel 1.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	cmd := strings.Join(os.Args, " ")
+	switch {
+	case strings.HasSuffix(cmd, "lan stats get 1"):
+	case strings.Contains(cmd, "lan stats get"):
+		fmt.Fprint(os.Stdout, "Channel 2 is not a LAN channel")
+		os.Exit(1)
+	default:
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", "lan_stats_get.txt"))
+	if err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
@@ -0,0 +1,64 @@
+package ipmi_power
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+)
+
+const defaultRawOutputMaxLength = 1024
+
+// unsafeFilename matches the characters replaced in capture file names.
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// missingReadings returns the DCMI readings not found in the parsed fields.
+func missingReadings(fields map[string]interface{}) []string {
+	var missing []string
+	for _, reading := range dcmiReadings {
+		if _, ok := fields[reading]; !ok {
+			missing = append(missing, reading)
+		}
+	}
+	return missing
+}
+
+// truncateOutput returns the output cut to at most max bytes, 0 disables
+// truncation.
+func truncateOutput(out []byte, max int) string {
+	if max > 0 && len(out) > max {
+		return string(out[:max])
+	}
+	return string(out)
+}
+
+// captureOutput writes the output of a query lacking readings to a file in
+// the capture directory, if configured.
+func (m *Ipmi) captureOutput(hostname string, args []string, out []byte, missing []string, t time.Time) {
+	if m.RawOutputDir == "" {
+		return
+	}
+
+	if hostname == "" {
+		hostname = "local"
+	}
+	name := fmt.Sprintf("ipmi_power-%s-%s.txt",
+		unsafeFilename.ReplaceAllString(hostname, "_"), t.UTC().Format("20060102T150405.000000000Z"))
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# Command: %s\n", strings.Join(ipmi.RedactPassword(args), " "))
+	fmt.Fprintf(&buf, "# Time: %s\n", t.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "# Missing: %s\n", strings.Join(missing, ", "))
+	buf.WriteString(truncateOutput(out, m.RawOutputMaxLength))
+
+	path := filepath.Join(m.RawOutputDir, name)
+	if err := ioutil.WriteFile(path, []byte(buf.String()), 0640); err != nil {
+		m.Log.Errorf("Capturing output of %s failed: %v", hostname, err)
+		return
+	}
+	m.Log.Debugf("Captured output of %s lacking %s to %s", hostname, strings.Join(missing, ", "), path)
+}
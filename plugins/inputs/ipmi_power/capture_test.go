@@ -0,0 +1,101 @@
+package ipmi_power
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const partialOutput = `
+    Instantaneous power reading:                   220 Watts
+    Power reading state is:                   activated
+`
+
+func newCaptureIpmi(t *testing.T, dir string) *Ipmi {
+	i := &Ipmi{
+		Path:               os.Args[0],
+		Servers:            []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:            internal.Duration{Duration: time.Second * 5},
+		RawOutputField:     true,
+		RawOutputDir:       dir,
+		RawOutputMaxLength: 64,
+		Log:                testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+	return i
+}
+
+func TestCaptureMissingReadings(t *testing.T) {
+	execCommand = fakeOutputExecCommand(partialOutput)
+	defer func() { execCommand = exec.Command }()
+
+	dir, err := ioutil.TempDir("", "ipmi_power")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	i := newCaptureIpmi(t, dir)
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	metric, ok := acc.Get("ipmi_power")
+	require.True(t, ok)
+	require.Equal(t, float64(220), metric.Fields["instantaneous_power_reading"])
+	require.Equal(t, partialOutput[:64], metric.Fields["raw_output"])
+
+	files, err := filepath.Glob(filepath.Join(dir, "ipmi_power-192.168.1.1-*.txt"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	capture, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(capture), "-P ********")
+	require.NotContains(t, string(capture), "PASSW0RD")
+	require.Contains(t, string(capture), "# Missing: minimum_during_sampling_period, maximum_during_sampling_period")
+}
+
+func TestCaptureParseError(t *testing.T) {
+	execCommand = fakeOutputExecCommand("Power reading state is: deactivated\n")
+	defer func() { execCommand = exec.Command }()
+
+	dir, err := ioutil.TempDir("", "ipmi_power")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	i := newCaptureIpmi(t, dir)
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestCaptureCompleteOutput(t *testing.T) {
+	execCommand = fakeExecCommand(false)
+	defer func() { execCommand = exec.Command }()
+
+	dir, err := ioutil.TempDir("", "ipmi_power")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	i := newCaptureIpmi(t, dir)
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	metric, ok := acc.Get("ipmi_power")
+	require.True(t, ok)
+	require.NotContains(t, metric.Fields, "raw_output")
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
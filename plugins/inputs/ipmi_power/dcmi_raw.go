@@ -0,0 +1,108 @@
+package ipmi_power
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/featureflag"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+)
+
+// rawReadingFlag is the feature flag querying the power readings with the
+// raw DCMI command side-by-side with "dcmi power reading".
+const rawReadingFlag = "ipmi_power_dcmi_raw"
+
+// Values of the implementation tag added to the metrics of servers the
+// raw DCMI command is canaried on.
+const (
+	implementationTag    = "implementation"
+	implementationLegacy = "legacy"
+	implementationRaw    = "dcmi_raw"
+)
+
+// rollingAverages are the rolling average time periods of the enhanced
+// system power statistics, see table 6-16 of the DCMI specification.  The
+// upper two bits hold the unit and the lower six the duration.
+var rollingAverages = map[string]string{
+	"5_sec":  "0x05",
+	"15_sec": "0x0f",
+	"30_sec": "0x1e",
+	"1_min":  "0x41",
+	"3_min":  "0x43",
+	"7_min":  "0x47",
+	"15_min": "0x4f",
+	"30_min": "0x5e",
+	"1_hour": "0x81",
+}
+
+// canary reports whether the raw DCMI command is canaried on the server, the
+// local machine is identified by its hostname.
+func canary(hostname string) bool {
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	return featureflag.Enabled(rawReadingFlag, hostname)
+}
+
+// queryRaw queries the readings with the raw DCMI command.  Its failures
+// are reported but neither counted nor recorded in the device inventory, so
+// the canary does not affect the legacy implementation.
+func (m *Ipmi) queryRaw(acc telegraf.Accumulator, hostname string, opts []string) {
+	raw, err := rawReadingArgs(m.SamplePeriod)
+	if err == nil {
+		args := append(append([]string{}, opts...), raw...)
+		err = m.query(acc, hostname, args, parseRawReadings, implementationRaw)
+	}
+	if err != nil {
+		acc.AddError(fmt.Errorf("%s implementation: %v", implementationRaw, err))
+	}
+}
+
+// rawReadingArgs returns the ipmitool arguments of the DCMI Get Power
+// Reading request, mode 1 for the system power statistics or mode 2 for
+// the enhanced statistics over the sample period.
+func rawReadingArgs(samplePeriod string) ([]string, error) {
+	args := []string{"raw", "0x2c", "0x02", "0xdc"}
+	if samplePeriod == "" {
+		return append(args, "0x01", "0x00", "0x00"), nil
+	}
+	period, ok := rollingAverages[samplePeriod]
+	if !ok {
+		return nil, fmt.Errorf("unknown sample period %q", samplePeriod)
+	}
+	return append(args, "0x02", period, "0x00"), nil
+}
+
+// parseRawReadings decodes the response to the DCMI Get Power Reading
+// request printed by "ipmitool raw" into the fields of "dcmi power
+// reading", so the metrics of both are comparable.
+func parseRawReadings(hostname string, cmdOut []byte) (map[string]interface{}, error) {
+	data, err := ipmi.ParseRawResponse(cmdOut)
+	if err != nil {
+		return nil, newBMCError(hostname, errorParseError, "%v in output: %s", err, string(cmdOut))
+	}
+
+	// Group extension, the readings, timestamp, statistics period and the
+	// power reading state
+	if len(data) < 18 || data[0] != 0xdc {
+		return nil, newBMCError(hostname, errorParseError, "no power readings found in output: %s", string(cmdOut))
+	}
+
+	fields := make(map[string]interface{})
+	readings := []string{
+		"instantaneous_power_reading",
+		"minimum_during_sampling_period",
+		"maximum_during_sampling_period",
+		"average_power_reading_over_sample_period",
+	}
+	for i, key := range readings {
+		fields[key] = float64(binary.LittleEndian.Uint16(data[1+2*i:]))
+		fields[key+"_unit"] = "Watts"
+	}
+	// ipmitool reports the period in whole seconds
+	fields["sampling_period"] = float64(binary.LittleEndian.Uint32(data[13:]) / 1000)
+	fields["sampling_period_unit"] = "Seconds."
+	return fields, nil
+}
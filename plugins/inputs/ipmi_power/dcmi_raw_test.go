@@ -0,0 +1,88 @@
+package ipmi_power
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/featureflag"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRawReadings(t *testing.T) {
+	// 312 W current, 96 W minimum, 498 W maximum and 305 W average over
+	// 300 s in the enhanced mode
+	out := []byte(" dc 38 01 60 00 f2 01 31 01 5d 89 d8 5f e0 93 04\n 00 40\n")
+	fields, err := parseRawReadings("", out)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"instantaneous_power_reading":                   float64(312),
+		"instantaneous_power_reading_unit":              "Watts",
+		"minimum_during_sampling_period":                float64(96),
+		"minimum_during_sampling_period_unit":           "Watts",
+		"maximum_during_sampling_period":                float64(498),
+		"maximum_during_sampling_period_unit":           "Watts",
+		"average_power_reading_over_sample_period":      float64(305),
+		"average_power_reading_over_sample_period_unit": "Watts",
+		"sampling_period":                               float64(300),
+		"sampling_period_unit":                          "Seconds.",
+	}, fields)
+
+	for _, out := range []string{"", " dc 38 01\n", " c1\n", " zz\n"} {
+		_, err := parseRawReadings("", []byte(out))
+		require.Error(t, err, out)
+		require.Equal(t, errorParseError, err.(*bmcError).class)
+	}
+}
+
+func TestRawReadingArgs(t *testing.T) {
+	args, err := rawReadingArgs("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"raw", "0x2c", "0x02", "0xdc", "0x01", "0x00", "0x00"}, args)
+
+	_, err = rawReadingArgs("5_min")
+	require.Error(t, err)
+
+	args, err = rawReadingArgs("15_min")
+	require.NoError(t, err)
+	require.Equal(t, []string{"raw", "0x2c", "0x02", "0xdc", "0x02", "0x4f", "0x00"}, args)
+}
+
+func TestRawReadingCanary(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand(false)
+
+	i := &Ipmi{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.11.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	// Without the flag the metrics are not tagged with the implementation
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, map[string]string{"server": "192.168.11.1"}, acc.Metrics[0].Tags)
+
+	featureflag.Set(rawReadingFlag, 100)
+	defer featureflag.Set(rawReadingFlag, 0)
+
+	acc.ClearMetrics()
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+
+	byImplementation := make(map[string]map[string]interface{})
+	for _, m := range acc.Metrics {
+		byImplementation[m.Tags[implementationTag]] = m.Fields
+	}
+	require.Contains(t, byImplementation, implementationLegacy)
+	require.Contains(t, byImplementation, implementationRaw)
+	require.Equal(t, byImplementation[implementationLegacy], byImplementation[implementationRaw])
+}
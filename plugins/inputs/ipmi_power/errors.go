@@ -0,0 +1,111 @@
+package ipmi_power
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Classes of errors returned by a BMC.
+const (
+	errorAuthFailure        = "auth_failure"
+	errorTimeout            = "timeout"
+	errorUnsupportedCommand = "unsupported_command"
+	errorParseError         = "parse_error"
+	errorUnknown            = "unknown"
+)
+
+// errorPatterns maps messages printed by ipmitool to the class of the error,
+// the first match wins.
+var errorPatterns = []struct {
+	substr string
+	class  string
+}{
+	{"unauthorized name", errorAuthFailure},
+	{"invalid user name", errorAuthFailure},
+	{"rakp", errorAuthFailure},
+	{"password", errorAuthFailure},
+	{"authentication", errorAuthFailure},
+	{"privilege level", errorAuthFailure},
+	{"activate session", errorAuthFailure},
+	{"insufficient privilege", errorAuthFailure},
+	{"no response", errorTimeout},
+	{"timeout", errorTimeout},
+	{"timed out", errorTimeout},
+	{"unable to establish", errorTimeout},
+	{"invalid command", errorUnsupportedCommand},
+	{"not supported", errorUnsupportedCommand},
+	{"unsupported", errorUnsupportedCommand},
+	{"dcmi request failed", errorUnsupportedCommand},
+	{"invalid data field", errorUnsupportedCommand},
+}
+
+// bmcError is an error returned by a BMC or ipmitool while querying it.
+type bmcError struct {
+	server string
+	class  string
+	err    error
+}
+
+func (e *bmcError) Error() string {
+	return e.err.Error()
+}
+
+// classify returns the class of a failed ipmitool command from its error
+// and output.
+func classify(err error, out []byte) string {
+	if err == internal.TimeoutErr {
+		return errorTimeout
+	}
+	msg := strings.ToLower(string(out))
+	for _, p := range errorPatterns {
+		if strings.Contains(msg, p.substr) {
+			return p.class
+		}
+	}
+	return errorUnknown
+}
+
+// errorCounter counts the errors of every server by class.
+type errorCounter struct {
+	sync.Mutex
+	counts map[string]map[string]int64
+}
+
+// add counts the error if it was returned by a BMC.
+func (c *errorCounter) add(err error) {
+	e, ok := err.(*bmcError)
+	if !ok {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]map[string]int64)
+	}
+	if c.counts[e.server] == nil {
+		c.counts[e.server] = make(map[string]int64)
+	}
+	c.counts[e.server][e.class]++
+}
+
+// emit adds the error counts of the server to the accumulator.
+func (c *errorCounter) emit(acc telegraf.Accumulator, server string) {
+	c.Lock()
+	defer c.Unlock()
+	for class, count := range c.counts[server] {
+		tags := map[string]string{"class": class}
+		if server != "" {
+			tags["server"] = server
+		}
+		acc.AddCounter("ipmi_power_errors", map[string]interface{}{"count": count}, tags)
+	}
+}
+
+func newBMCError(server, class string, format string, a ...interface{}) error {
+	return &bmcError{server: server, class: class, err: fmt.Errorf(format, a...)}
+}
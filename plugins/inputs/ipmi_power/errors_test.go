@@ -0,0 +1,78 @@
+package ipmi_power
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err   error
+		out   string
+		class string
+	}{
+		{errors.New("exit status 1"), "Error: Unable to establish IPMI v2 / RMCP+ session\nRAKP 2 message indicates an error : unauthorized name", errorAuthFailure},
+		{errors.New("exit status 1"), "Activate Session error:\tInvalid user name", errorAuthFailure},
+		{errors.New("exit status 1"), "Error: Unable to establish LAN session", errorTimeout},
+		{internal.TimeoutErr, "", errorTimeout},
+		{errors.New("exit status 1"), "DCMI request failed because: Invalid command (c1)", errorUnsupportedCommand},
+		{errors.New("exit status 1"), "something went wrong", errorUnknown},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.class, classify(tt.err, []byte(tt.out)), tt.out)
+	}
+}
+
+func TestErrorCounters(t *testing.T) {
+	execCommand = fakeFailingExecCommand("Error: Unable to establish IPMI v2 / RMCP+ session\nRAKP 2 message indicates an error : unauthorized name")
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 2)
+	for _, err := range acc.Errors {
+		require.NotContains(t, err.Error(), "PASSW0RD")
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("ipmi_power_errors",
+			map[string]string{"server": "192.168.1.1", "class": errorAuthFailure},
+			map[string]interface{}{"count": int64(1)},
+			time.Unix(0, 0),
+			telegraf.Counter),
+		testutil.MustMetric("ipmi_power_errors",
+			map[string]string{"server": "192.168.1.1", "class": errorAuthFailure},
+			map[string]interface{}{"count": int64(2)},
+			time.Unix(0, 0),
+			telegraf.Counter),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestParseErrorCounted(t *testing.T) {
+	var c errorCounter
+	var acc testutil.Accumulator
+	_, err := parseReadings("192.168.1.1", []byte("Power reading state is: deactivated\n"))
+	require.Error(t, err)
+	c.add(err)
+	c.emit(&acc, "192.168.1.1")
+	acc.AssertContainsTaggedFields(t, "ipmi_power_errors",
+		map[string]interface{}{"count": int64(1)},
+		map[string]string{"server": "192.168.1.1", "class": errorParseError})
+}
@@ -0,0 +1,56 @@
+package ipmi_power
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestGolden parses the captured ipmitool outputs in testdata/*.txt and
+// compares the result with the respective .golden file, holding the metrics
+// in line protocol or the class of the error.  To validate the output of a
+// new firmware add its capture and run "go test -update", then review the
+// generated golden file.
+func TestGolden(t *testing.T) {
+	captures, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	require.NoError(t, err)
+	require.NotEmpty(t, captures)
+
+	serializer := influx.NewSerializer()
+	serializer.SetFieldSortOrder(influx.SortFields)
+
+	for _, capture := range captures {
+		name := strings.TrimSuffix(filepath.Base(capture), ".txt")
+		t.Run(name, func(t *testing.T) {
+			out, err := ioutil.ReadFile(capture)
+			require.NoError(t, err)
+
+			var acc testutil.Accumulator
+			var actual []byte
+			if fields, err := parseReadings("", out); err != nil {
+				actual = []byte("error: " + err.(*bmcError).class + "\n")
+			} else {
+				acc.AddFields("ipmi_power", fields, nil, time.Unix(0, 0))
+				actual, err = serializer.SerializeBatch(acc.GetTelegrafMetrics())
+				require.NoError(t, err)
+			}
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				require.NoError(t, ioutil.WriteFile(golden, actual, 0644))
+			}
+			expected, err := ioutil.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), string(actual))
+		})
+	}
+}
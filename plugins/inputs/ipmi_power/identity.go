@@ -0,0 +1,121 @@
+package ipmi_power
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// identityQueries are the DCMI commands identifying a BMC, the label of
+// their output line and the tag it is added as.
+var identityQueries = []struct {
+	args  []string
+	label string
+	tag   string
+}{
+	{[]string{"dcmi", "asset_tag"}, "Asset tag", "asset_tag"},
+	{[]string{"dcmi", "get_mc_id_string"}, "Get Management Controller Identifier String", "mc_id"},
+}
+
+// identityCache holds the identity tags of every server, queried once per
+// server for the lifetime of the plugin.
+type identityCache struct {
+	sync.Mutex
+	tags map[string]map[string]string
+}
+
+func (c *identityCache) get(hostname string) (map[string]string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	tags, ok := c.tags[hostname]
+	return tags, ok
+}
+
+func (c *identityCache) set(hostname string, tags map[string]string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.tags == nil {
+		c.tags = make(map[string]map[string]string)
+	}
+	c.tags[hostname] = tags
+}
+
+// identify queries the asset tag and management controller id of the server
+// unless they are known.  BMCs not supporting a command lack its tag, other
+// failures are returned and the identity is queried again next time.
+func (m *Ipmi) identify(hostname string, opts []string) error {
+	if _, ok := m.identities.get(hostname); ok {
+		return nil
+	}
+
+	tags := make(map[string]string)
+	for _, q := range identityQueries {
+		args := append(append([]string{}, opts...), q.args...)
+		_, out, err := m.run(hostname, args)
+		if e, ok := err.(*bmcError); ok && e.class == errorUnsupportedCommand {
+			m.Log.Debugf("Not tagging %s with %s, it is not supported", hostname, q.tag)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if v := parseIdentity(out, q.label); v != "" {
+			tags[q.tag] = v
+		}
+	}
+	m.identities.set(hostname, tags)
+	return nil
+}
+
+// parseIdentity returns the value of the line of the output with the label,
+// such as "Asset tag: 4U-1234".
+func parseIdentity(out []byte, label string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == label {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// identityAccumulator adds the identity tags of the server to its metrics.
+// The tags are looked up when adding, as they are queried along with the
+// first readings.
+type identityAccumulator struct {
+	telegraf.Accumulator
+	cache    *identityCache
+	hostname string
+}
+
+func (a *identityAccumulator) withTags(tags map[string]string) map[string]string {
+	identity, _ := a.cache.get(a.hostname)
+	if len(identity) == 0 {
+		return tags
+	}
+	merged := make(map[string]string, len(tags)+len(identity))
+	for k, v := range identity {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return merged
+}
+
+func (a *identityAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddFields(measurement, fields, a.withTags(tags), t...)
+}
+
+func (a *identityAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddGauge(measurement, fields, a.withTags(tags), t...)
+}
+
+func (a *identityAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddCounter(measurement, fields, a.withTags(tags), t...)
+}
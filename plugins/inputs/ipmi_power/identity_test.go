@@ -0,0 +1,88 @@
+package ipmi_power
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// assetTagOutput and mcIDOutput are the outputs of "ipmitool dcmi
+// asset_tag" and "ipmitool dcmi get_mc_id_string".
+const (
+	assetTagOutput = "\n Asset tag: 4U-1234\n"
+	mcIDOutput     = "\n Get Management Controller Identifier String: bmc-r12-u3\n"
+)
+
+func TestParseIdentity(t *testing.T) {
+	require.Equal(t, "4U-1234", parseIdentity([]byte(assetTagOutput), "Asset tag"))
+	require.Equal(t, "bmc-r12-u3", parseIdentity([]byte(mcIDOutput), "Get Management Controller Identifier String"))
+	require.Equal(t, "", parseIdentity([]byte("\n Asset tag: \n"), "Asset tag"))
+}
+
+func TestGatherIdentityTags(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand(false)
+
+	i := &Ipmi{
+		Path:         os.Args[0],
+		Servers:      []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:      internal.Duration{Duration: time.Second * 5},
+		PSUReadings:  true,
+		IdentityTags: true,
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.NotEmpty(t, acc.Metrics)
+	for _, m := range acc.Metrics {
+		require.Equal(t, "4U-1234", m.Tags["asset_tag"], m.Measurement)
+		require.Equal(t, "bmc-r12-u3", m.Tags["mc_id"], m.Measurement)
+	}
+
+	// The identity is only queried once
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(false)(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_NO_MC_ID=1")
+		return cmd
+	}
+	acc.ClearMetrics()
+	require.NoError(t, i.Gather(&acc))
+	require.True(t, acc.HasMeasurement("ipmi_power"))
+	for _, m := range acc.Metrics {
+		require.Equal(t, "bmc-r12-u3", m.Tags["mc_id"], m.Measurement)
+	}
+}
+
+func TestGatherIdentityTagsUnsupported(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(false)(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_NO_MC_ID=1")
+		return cmd
+	}
+
+	i := &Ipmi{
+		Path:          os.Args[0],
+		Servers:       []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:       internal.Duration{Duration: time.Second * 5},
+		IdentityTags:  true,
+		FieldsInclude: []string{"instantaneous_power_reading"},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "ipmi_power",
+		map[string]interface{}{"instantaneous_power_reading": 220.0},
+		map[string]string{"server": "192.168.1.1", "asset_tag": "4U-1234"})
+}
@@ -0,0 +1,184 @@
+package ipmi_power
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/inventory"
+)
+
+const (
+	// inventoryKind is the kind of the BMCs in the device inventory.
+	inventoryKind = "bmc"
+
+	// capabilityDCMIPower tells whether the BMC supports "dcmi power
+	// reading".
+	capabilityDCMIPower = "dcmi_power"
+
+	// capabilityDCMIPowerProbed is the time a BMC lacking DCMI power
+	// readings was last probed, in RFC3339 format.
+	capabilityDCMIPowerProbed = "dcmi_power_probed_at"
+
+	// unsupportedThreshold is the number of consecutive queries failing
+	// with an unsupported command before a BMC is recorded as lacking DCMI
+	// power readings.  BMCs may reject commands for a while, e.g. when
+	// rebooting.
+	unsupportedThreshold = 3
+
+	// unsupportedBackoff is the time after which a BMC recorded as lacking
+	// DCMI power readings is probed again, in case it was recorded after
+	// transient failures.  Every probe confirming it doubles the time up to
+	// maxUnsupportedBackoff.
+	unsupportedBackoff    = 10 * time.Minute
+	maxUnsupportedBackoff = 6 * time.Hour
+)
+
+// dcmiProbes tracks the DCMI queries of every BMC failing with an
+// unsupported command: the consecutive failures of BMCs not recorded yet and
+// the time until recorded BMCs are probed again.
+var dcmiProbes = struct {
+	sync.Mutex
+	streaks  map[string]int
+	backoffs map[string]time.Duration
+}{
+	streaks:  make(map[string]int),
+	backoffs: make(map[string]time.Duration),
+}
+
+// dcmiSupported returns false if the BMC is known not to support DCMI power
+// readings and is not due to be probed again.  Unknown BMCs are assumed to
+// support them.
+func dcmiSupported(hostname string) bool {
+	d, ok := inventory.Lookup(inventoryKind, hostname)
+	if !ok || d.Capabilities[capabilityDCMIPower] != "false" {
+		return true
+	}
+	probed, err := time.Parse(time.RFC3339, d.Capabilities[capabilityDCMIPowerProbed])
+	if err != nil {
+		probed = d.DiscoveredAt
+	}
+
+	dcmiProbes.Lock()
+	backoff := dcmiProbes.backoffs[hostname]
+	dcmiProbes.Unlock()
+	if backoff == 0 {
+		backoff = unsupportedBackoff
+	}
+	return time.Since(probed) >= backoff
+}
+
+// recordDCMISupport registers whether the BMC supports DCMI power readings
+// in the device inventory.
+func recordDCMISupport(hostname string, supported bool) {
+	recordCapability(hostname, capabilityDCMIPower, strconv.FormatBool(supported))
+}
+
+// lookupCapability returns the capability of the BMC registered in the
+// device inventory, empty if unknown.
+func lookupCapability(hostname, name string) string {
+	d, ok := inventory.Lookup(inventoryKind, hostname)
+	if !ok {
+		return ""
+	}
+	return d.Capabilities[name]
+}
+
+// recordCapability registers the capability of the BMC in the device
+// inventory, keeping the capabilities registered by others.
+func recordCapability(hostname, name, value string) {
+	d, ok := inventory.Lookup(inventoryKind, hostname)
+	if ok && d.Capabilities[name] == value {
+		return
+	}
+	if !ok {
+		d = inventory.Device{Kind: inventoryKind, ID: hostname, Capabilities: map[string]string{}}
+	}
+	d.Capabilities[name] = value
+	d.DiscoveredAt = time.Time{}
+	inventory.Register(d)
+}
+
+// recordQuery registers the DCMI support revealed by the result of a "dcmi
+// power reading" query of a remote BMC.  It returns true when the BMC is
+// newly recorded as not supporting DCMI power readings.
+func recordQuery(hostname string, err error) bool {
+	if hostname == "" {
+		return false
+	}
+
+	dcmiProbes.Lock()
+	defer dcmiProbes.Unlock()
+	if err == nil {
+		recordSupported(hostname)
+		return false
+	}
+	e, ok := err.(*bmcError)
+	if !ok {
+		return false
+	}
+	switch e.class {
+	case errorUnsupportedCommand:
+		// A probe of a recorded BMC confirmed it lacks DCMI
+		if lookupCapability(hostname, capabilityDCMIPower) == "false" {
+			backoff := 2 * dcmiProbes.backoffs[hostname]
+			if backoff == 0 {
+				backoff = 2 * unsupportedBackoff
+			}
+			if backoff > maxUnsupportedBackoff {
+				backoff = maxUnsupportedBackoff
+			}
+			dcmiProbes.backoffs[hostname] = backoff
+			recordUnsupported(hostname)
+			return false
+		}
+
+		dcmiProbes.streaks[hostname]++
+		if dcmiProbes.streaks[hostname] < unsupportedThreshold {
+			return false
+		}
+		delete(dcmiProbes.streaks, hostname)
+		dcmiProbes.backoffs[hostname] = unsupportedBackoff
+		recordUnsupported(hostname)
+		return true
+	case errorParseError:
+		recordSupported(hostname)
+	}
+	return false
+}
+
+// recordSupported records the BMC as supporting DCMI power readings, the
+// caller must hold the lock of dcmiProbes.
+func recordSupported(hostname string) {
+	delete(dcmiProbes.streaks, hostname)
+	delete(dcmiProbes.backoffs, hostname)
+	recordDCMISupport(hostname, true)
+}
+
+// recordUnsupported records the BMC as lacking DCMI power readings as of
+// now.
+func recordUnsupported(hostname string) {
+	recordDCMISupport(hostname, false)
+	recordCapability(hostname, capabilityDCMIPowerProbed, time.Now().UTC().Format(time.RFC3339))
+}
+
+// skippedHosts holds the BMCs skipped for lacking DCMI power readings, so
+// skipping them is logged once.
+type skippedHosts struct {
+	sync.Mutex
+	m map[string]bool
+}
+
+// set records whether the BMC is skipped and returns true if that changed.
+func (s *skippedHosts) set(hostname string, skipped bool) bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.m[hostname] == skipped {
+		return false
+	}
+	if s.m == nil {
+		s.m = make(map[string]bool)
+	}
+	s.m[hostname] = skipped
+	return true
+}
@@ -0,0 +1,123 @@
+package ipmi_power
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/inventory"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipUnsupportedBMC(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	defer inventory.Forget(inventoryKind, "192.168.10.1")
+	defer inventory.Forget(inventoryKind, "192.168.10.2")
+
+	i := &Ipmi{
+		Path: os.Args[0],
+		Servers: []string{
+			"USERID:PASSW0RD@lan(192.168.10.1)",
+			"USERID:PASSW0RD@lan(192.168.10.2)",
+		},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	// A single failure, e.g. while the BMC reboots, is not recorded
+	execCommand = fakeFailingExecCommand("DCMI request failed because: Invalid command (c1)")
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 2)
+	require.True(t, dcmiSupported("192.168.10.1"))
+
+	for n := 1; n < unsupportedThreshold; n++ {
+		require.NoError(t, i.Gather(&acc))
+	}
+	d, ok := inventory.Lookup(inventoryKind, "192.168.10.1")
+	require.True(t, ok)
+	require.Equal(t, "false", d.Capabilities[capabilityDCMIPower])
+
+	// Known unsupported BMCs are not queried again
+	inventory.Forget(inventoryKind, "192.168.10.2")
+	execCommand = fakeExecCommand(false)
+	acc.ClearMetrics()
+	acc.Errors = nil
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	var readings int
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "ipmi_power" {
+			readings++
+		}
+	}
+	require.Equal(t, 1, readings)
+
+	d, ok = inventory.Lookup(inventoryKind, "192.168.10.2")
+	require.True(t, ok)
+	require.Equal(t, "true", d.Capabilities[capabilityDCMIPower])
+}
+
+func TestRecordQueryResetsStreak(t *testing.T) {
+	defer inventory.Forget(inventoryKind, "192.168.10.3")
+
+	unsupported := newBMCError("192.168.10.3", errorUnsupportedCommand, "Invalid data field")
+	for n := 1; n < unsupportedThreshold; n++ {
+		require.False(t, recordQuery("192.168.10.3", unsupported))
+	}
+	require.False(t, recordQuery("192.168.10.3", nil))
+	for n := 1; n < unsupportedThreshold; n++ {
+		require.False(t, recordQuery("192.168.10.3", unsupported))
+	}
+	require.True(t, dcmiSupported("192.168.10.3"))
+	require.True(t, recordQuery("192.168.10.3", unsupported))
+	require.False(t, dcmiSupported("192.168.10.3"))
+}
+
+func TestProbeUnsupportedBMCAgain(t *testing.T) {
+	defer inventory.Forget(inventoryKind, "192.168.10.4")
+
+	probedAgo := func(d time.Duration) {
+		recordCapability("192.168.10.4", capabilityDCMIPowerProbed,
+			time.Now().Add(-d).UTC().Format(time.RFC3339))
+	}
+
+	unsupported := newBMCError("192.168.10.4", errorUnsupportedCommand, "Invalid data field")
+	for n := 1; n < unsupportedThreshold; n++ {
+		require.False(t, recordQuery("192.168.10.4", unsupported))
+	}
+	require.True(t, recordQuery("192.168.10.4", unsupported))
+	require.False(t, dcmiSupported("192.168.10.4"))
+
+	// The BMC is probed again after the backoff, a single failure confirms
+	// it lacks DCMI and doubles the backoff
+	probedAgo(unsupportedBackoff)
+	require.True(t, dcmiSupported("192.168.10.4"))
+	require.False(t, recordQuery("192.168.10.4", unsupported))
+	require.False(t, dcmiSupported("192.168.10.4"))
+	probedAgo(unsupportedBackoff)
+	require.False(t, dcmiSupported("192.168.10.4"))
+	probedAgo(2 * unsupportedBackoff)
+	require.True(t, dcmiSupported("192.168.10.4"))
+
+	// A successful probe records the support and resets the backoff
+	require.False(t, recordQuery("192.168.10.4", nil))
+	require.True(t, dcmiSupported("192.168.10.4"))
+	for n := 0; n < unsupportedThreshold; n++ {
+		recordQuery("192.168.10.4", unsupported)
+	}
+	probedAgo(unsupportedBackoff)
+	require.True(t, dcmiSupported("192.168.10.4"))
+}
+
+func TestSkippedHostsChanged(t *testing.T) {
+	var s skippedHosts
+	require.False(t, s.set("192.168.10.5", false))
+	require.True(t, s.set("192.168.10.5", true))
+	require.False(t, s.set("192.168.10.5", true))
+	require.True(t, s.set("192.168.10.5", false))
+}
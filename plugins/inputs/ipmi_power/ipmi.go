@@ -3,33 +3,101 @@ package ipmi_power
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 var (
-	execCommand             = exec.Command // execCommand is used to mock commands in tests.
-	re_parse_line        = regexp.MustCompile(`^\s+(?P<name>[^:]*):\s+(?P<value>\S+)\s+(?P<unit>\S+)`)
+	execCommand   = exec.Command // execCommand is used to mock commands in tests.
+	re_parse_line = regexp.MustCompile(`^\s+(?P<name>[^:]*):\s+(?P<value>\S+)\s+(?P<unit>\S+)`)
 )
 
+// dcmiReadings are the statistics reported by "dcmi power reading".
+var dcmiReadings = []string{
+	"instantaneous_power_reading",
+	"minimum_during_sampling_period",
+	"maximum_during_sampling_period",
+	"average_power_reading_over_sample_period",
+	"sampling_period",
+}
+
+const defaultPollInterval = 30 * time.Second
+
 // Ipmi stores the configuration values for the ipmi_power input plugin
 type Ipmi struct {
-	Path          string
-	Privilege     string
-	Servers       []string
-	Timeout       internal.Duration
-	UseSudo       bool
-	SamplePeriod  string
+	Path         string
+	Privilege    string
+	Servers      []string
+	Timeout      internal.Duration
+	UseSudo      bool
+	SudoCommand  []string
+	SamplePeriod string
+
+	Interface      string
+	LocalInterface string
+
+	RateLimit      int
+	RateLimitBurst int
+
+	MaintenanceFile string
+
+	OEMProfile string `toml:"oem_profile"`
+	OEMSensor  string `toml:"oem_sensor"`
+
+	PSUReadings bool `toml:"psu_readings"`
+
+	IdentityTags bool `toml:"identity_tags"`
+
+	ServiceMode  bool
+	PollInterval internal.Duration
+
+	DryRun bool
+
+	MinValidWatts float64 `toml:"min_valid_watts"`
+	MaxValidWatts float64 `toml:"max_valid_watts"`
+
+	FieldsInclude []string `toml:"fields_include"`
+	FieldsExclude []string `toml:"fields_exclude"`
+
+	RawOutputField     bool   `toml:"raw_output_field"`
+	RawOutputDir       string `toml:"raw_output_dir"`
+	RawOutputMaxLength int    `toml:"raw_output_max_length"`
+
+	SSHHost           string `toml:"ssh_host"`
+	SSHUser           string `toml:"ssh_user"`
+	SSHKeyFile        string `toml:"ssh_key_file"`
+	SSHKnownHostsFile string `toml:"ssh_known_hosts_file"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	maintenance *maintenanceList
+	fieldFilter filter.Filter
+	errors      errorCounter
+	rejected    rejectedCounter
+	skipped     skippedHosts
+	identities  identityCache
+	sshPath     string
+
+	// localManufacturer is the manufacturer id of the local BMC detected
+	// by the auto profile.
+	localManufacturer string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 var sampleConfig = `
@@ -41,18 +109,46 @@ var sampleConfig = `
   ## without a password.
   # use_sudo = false
   ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
   ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
   # privilege = "ADMINISTRATOR"
   ##
   ## optionally specify one or more servers via a url matching
-  ##  [username[:password]@][protocol[(address)]]
+  ##  [username[:password]@][protocol[(address[:port])]]
   ##  e.g.
   ##    root:passwd@lan(127.0.0.1)
+  ##    root:passwd@lan([fd00::1]:623)
+  ##
+  ## the interface passed to ipmitool's -I flag is taken from the protocol,
+  ## the 'interface' option overrides it and a per server 'interface'
+  ## parameter overrides both
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)?interface=lanplus
+  ##
+  ## the password can be read from a file on every query by appending a
+  ## 'password_file' parameter, or taken from the environment like any other
+  ## setting
+  ##  e.g.
+  ##    root:@lan(127.0.0.1)?password_file=/run/secrets/bmc
+  ##    ${BMC_USER}:${BMC_PASSWORD}@lan(127.0.0.1)
   ##
   ## if no servers are specified, local machine sensor stats will be queried
   ##
   # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
 
+  ## Interface used for all servers and the local machine, one of lan,
+  ## lanplus, open or serial. By default servers use the protocol of their
+  ## url, or lan without one, and ipmitool picks the interface of the local
+  ## machine. Serial servers name their device and baud rate, e.g.
+  ## "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## Interface used to query the local machine, overriding 'interface'.
+  # local_interface = ""
+
   ## Recommended: use metric 'interval' that is a multiple of 'timeout' to avoid
   ## gaps or overlap in pulled data
   interval = "30s"
@@ -62,6 +158,87 @@ var sampleConfig = `
 
   ## Sample Period, can be 5_sec/15_sec/30_sec/1_min/3_min/7_min/15_min/30_min/1_hour
   # sample_period = ""
+
+  ## Power readings outside of this range are dropped as implausible and
+  ## counted in the ipmi_power_rejected measurement, e.g. for BMCs
+  ## intermittently reporting 0 or 65535 Watts.  0 disables the bound.
+  # min_valid_watts = 0.0
+  # max_valid_watts = 0.0
+
+  ## Fields to emit, glob patterns are supported.  By default all parsed
+  ## fields are emitted, e.g. use fields_exclude = ["*_unit"] to drop the
+  ## units.
+  # fields_include = []
+  # fields_exclude = []
+
+  ## Profile reading the power consumption of BMCs lacking DCMI power
+  ## readings with vendor specific raw commands, one of supermicro,
+  ## supermicro_node, dell or hpe, or auto to select the profile from the
+  ## manufacturer reported by "ipmitool mc info".  It can be set per server
+  ## with an 'oem_profile' parameter, e.g.
+  ##   root:passwd@lan(192.168.1.1)?oem_profile=dell
+  ## Only the instantaneous power reading is available.  The hpe profile
+  ## needs the number of the power meter sensor in 'oem_sensor', or the
+  ## 'oem_sensor' parameter of the server.  The supermicro_node profile reads
+  ## the power of the node of multi-node chassis instead of DCMI, which
+  ## reports the power of the whole chassis.
+  # oem_profile = ""
+  # oem_sensor = "0x0e"
+
+  ## Read the input and output power, current, voltage and status of every
+  ## power supply from its sensor data records with "ipmitool sdr entity 10"
+  ## and emit them per supply in the ipmi_power_supply measurement.
+  # psu_readings = false
+
+  ## Tag all metrics of a server with its DCMI asset tag and management
+  ## controller id, read once with "ipmitool dcmi asset_tag" and "ipmitool
+  ## dcmi get_mc_id_string".
+  # identity_tags = false
+
+  ## Maximum number of queries per minute sent to a single BMC, shared by
+  ## all ipmi_power instances. Every ipmitool command counts, including the
+  ## identity, power supply and OEM queries. Queries that would have to wait
+  ## longer than 'timeout' for their turn are skipped. 0 disables rate
+  ## limiting.
+  # rate_limit = 0
+  ## Number of queries allowed in a burst before rate limiting kicks in
+  # rate_limit_burst = 1
+
+  ## File listing BMCs to exclude from collection, e.g. during firmware
+  ## updates. The file is re-read whenever it changes, one server per line
+  ## with an optional RFC3339 time at which the maintenance ends:
+  ##   192.168.1.1
+  ##   192.168.1.2 2020-12-15T18:00:00Z
+  # maintenance_file = "/etc/telegraf/ipmi_maintenance"
+
+  ## Poll every BMC in the background on its own schedule instead of on
+  ## every gather, readings are pushed as soon as they complete so slow
+  ## BMCs do not delay the others.  'interval' is ignored in this mode.
+  # service_mode = false
+  ## Time between two queries of the same BMC in service mode
+  # poll_interval = "30s"
+
+  ## Only query the first server and log the exact command line, its output
+  ## and the parsed fields instead of emitting metrics.  Use it together with
+  ## "telegraf --test" to validate new BMC firmware.
+  # dry_run = false
+
+  ## Keep the raw ipmitool output when it lacks some of the DCMI readings,
+  ## to report output variations of vendors.  'raw_output_field' adds it to
+  ## the metric as "raw_output" field truncated to 'raw_output_max_length'
+  ## bytes, 'raw_output_dir' writes it to a file per query in the directory.
+  # raw_output_field = false
+  # raw_output_dir = ""
+  # raw_output_max_length = 1024
+
+  ## Run ipmitool on a jump host over SSH, for BMCs only reachable from a
+  ## bastion.  Use one plugin instance per jump host.  'path' and 'use_sudo'
+  ## then apply to the jump host.  Authentication must not be interactive.
+  ## BMC passwords are passed to ipmitool on stdin, not on the command line.
+  # ssh_host = "bastion.example.com:22"
+  # ssh_user = "telegraf"
+  # ssh_key_file = "/etc/telegraf/id_ed25519"
+  # ssh_known_hosts_file = "/etc/telegraf/known_hosts"
 `
 
 // SampleConfig returns the documentation about the sample configuration
@@ -74,19 +251,100 @@ func (m *Ipmi) Description() string {
 	return "Read metrics from the bare metal servers via IPMI"
 }
 
+// Init locates ipmitool and, when collecting from the local machine, checks
+// that the local BMC can be reached so misconfiguration is reported once at
+// startup.
+func (m *Ipmi) Init() error {
+	if m.MaintenanceFile != "" {
+		m.maintenance = &maintenanceList{path: m.MaintenanceFile}
+	}
+
+	var err error
+	m.fieldFilter, err = filter.NewIncludeExcludeFilter(m.FieldsInclude, m.FieldsExclude)
+	if err != nil {
+		return fmt.Errorf("invalid field filter: %v", err)
+	}
+
+	if err := m.checkOEMProfile(m.OEMProfile, m.OEMSensor); err != nil {
+		return err
+	}
+	for _, server := range m.Servers {
+		conn := ipmi.NewConnection(server, m.Privilege)
+		profile, sensor := m.oemProfile(server)
+		if err := m.checkOEMProfile(profile, sensor); err != nil {
+			return fmt.Errorf("server %s: %v", conn.Hostname, err)
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+	if err := ipmi.CheckInterface("local_interface", m.LocalInterface); err != nil {
+		return err
+	}
+	if len(m.Servers) == 0 && ipmi.IsSerial(m.localInterface()) {
+		return fmt.Errorf("interface %q requires a server naming the serial device", m.localInterface())
+	}
+
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+
+	if m.SSHHost != "" {
+		return m.initSSH()
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+
+	if len(m.Servers) > 0 {
+		return nil
+	}
+
+	opts := make([]string, 0)
+	if intf := m.localInterface(); intf != "" {
+		opts = append(opts, "-I", ipmi.IpmitoolInterface(intf))
+	}
+	opts = append(opts, "dcmi", "discover")
+	cmd := m.command(opts...)
+	out, err := internal.CombinedOutputTimeout(cmd, m.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("local IPMI access check %q failed, verify that the ipmi device exists and that telegraf may access it (or enable use_sudo): %s - %s",
+			strings.Join(cmd.Args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 // Gather is the main execution function for the plugin
 func (m *Ipmi) Gather(acc telegraf.Accumulator) error {
-	if len(m.Path) == 0 {
-		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH")
+	if m.DryRun {
+		server := ""
+		if len(m.Servers) > 0 {
+			server = m.Servers[0]
+		}
+		return m.parse(acc, server)
 	}
 
+	if m.ServiceMode {
+		return nil
+	}
+
+	m.loadMaintenance(acc)
+
 	if len(m.Servers) > 0 {
 		wg := sync.WaitGroup{}
 		for _, server := range m.Servers {
 			wg.Add(1)
 			go func(a telegraf.Accumulator, s string) {
 				defer wg.Done()
-				err := m.parse(a, s)
+				err := m.gatherServer(a, s)
 				if err != nil {
 					a.AddError(err)
 				}
@@ -94,7 +352,7 @@ func (m *Ipmi) Gather(acc telegraf.Accumulator) error {
 		}
 		wg.Wait()
 	} else {
-		err := m.parse(acc, "")
+		err := m.gatherServer(acc, "")
 		if err != nil {
 			return err
 		}
@@ -103,38 +361,315 @@ func (m *Ipmi) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// loadMaintenance refreshes the maintenance list if one is configured.
+func (m *Ipmi) loadMaintenance(acc telegraf.Accumulator) {
+	if m.maintenance == nil {
+		return
+	}
+	if err := m.maintenance.load(); err != nil {
+		acc.AddError(err)
+	}
+}
+
+// EstimateSeries returns one series per server holding the reading and unit
+// of every DCMI power statistic, or the instantaneous reading only for
+// servers whose OEM profile replaces DCMI.  Canary servers are queried with
+// both implementations and report two series.  The power supplies depend on
+// the hardware and the error counters on failures, they are not estimated.
+func (m *Ipmi) EstimateSeries() []telegraf.SeriesEstimate {
+	// The estimate is taken from the configuration before Init, which
+	// reports invalid filters.
+	fieldFilter, _ := filter.NewIncludeExcludeFilter(m.FieldsInclude, m.FieldsExclude)
+	countFields := func(readings []string) int {
+		fields := 0
+		for _, reading := range readings {
+			for _, key := range []string{reading, reading + "_unit"} {
+				if fieldFilter == nil || fieldFilter.Match(key) {
+					fields++
+				}
+			}
+		}
+		return fields
+	}
+
+	servers := m.Servers
+	if len(servers) == 0 {
+		servers = []string{""}
+	}
+	var dcmiSeries, oemSeries int
+	for _, server := range servers {
+		hostname := ""
+		if server != "" {
+			hostname = ipmi.NewConnection(server, m.Privilege).Hostname
+		}
+		profile, _ := m.oemProfile(server)
+		switch {
+		case oemProfiles[profile].replacesDCMI:
+			oemSeries++
+		case canary(hostname):
+			dcmiSeries += 2
+		default:
+			dcmiSeries++
+		}
+	}
+
+	var interval time.Duration
+	if m.ServiceMode {
+		interval = m.PollInterval.Duration
+		if interval <= 0 {
+			interval = defaultPollInterval
+		}
+	}
+
+	var estimates []telegraf.SeriesEstimate
+	if dcmiSeries > 0 {
+		estimates = append(estimates, telegraf.SeriesEstimate{
+			Measurement: "ipmi_power", Series: dcmiSeries, Fields: countFields(dcmiReadings), Interval: interval,
+		})
+	}
+	if oemSeries > 0 {
+		estimates = append(estimates, telegraf.SeriesEstimate{
+			Measurement: "ipmi_power", Series: oemSeries, Fields: countFields(dcmiReadings[:1]), Interval: interval,
+		})
+	}
+	if m.PSUReadings {
+		estimates = append(estimates, telegraf.SeriesEstimate{Measurement: "ipmi_power_supply", Unknown: true})
+	}
+	return estimates
+}
+
+// gatherServer queries a single server and reports its error counters.
+func (m *Ipmi) gatherServer(acc telegraf.Accumulator, server string) error {
+	hostname := ""
+	if server != "" {
+		hostname = ipmi.NewConnection(server, m.Privilege).Hostname
+	}
+	if m.IdentityTags {
+		acc = &identityAccumulator{Accumulator: acc, cache: &m.identities, hostname: hostname}
+	}
+
+	err := m.parse(acc, server)
+	m.errors.add(err)
+	m.errors.emit(acc, hostname)
+	m.rejected.emit(acc, hostname)
+	return err
+}
+
 func (m *Ipmi) parse(acc telegraf.Accumulator, server string) error {
 	opts := make([]string, 0)
 	hostname := ""
+	profile, sensor := m.OEMProfile, m.OEMSensor
+	useOEM := false
 	if server != "" {
-		conn := NewConnection(server, m.Privilege)
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
 		hostname = conn.Hostname
-		opts = conn.options()
+		profile, sensor = m.oemProfile(server)
+
+		if m.maintenance != nil && m.maintenance.contains(conn.Hostname, time.Now()) {
+			m.Log.Debugf("Skipping %s, it is in maintenance", conn.Hostname)
+			return nil
+		}
+
+		if !m.DryRun && !dcmiSupported(conn.Hostname) {
+			if profile == "" {
+				if m.skipped.set(conn.Hostname, true) {
+					m.Log.Infof("Skipping %s, it is recorded in the inventory as not supporting DCMI power readings", conn.Hostname)
+				}
+				return nil
+			}
+			useOEM = true
+		} else if m.skipped.set(conn.Hostname, false) {
+			m.Log.Infof("Probing %s again for DCMI power readings", conn.Hostname)
+		}
+
+		if err := conn.LoadPassword(); err != nil {
+			return err
+		}
+		opts = conn.Options()
+	} else if intf := m.localInterface(); intf != "" {
+		opts = append(opts, "-I", ipmi.IpmitoolInterface(intf))
+	}
+	if profile == autoProfile {
+		var err error
+		profile, err = m.detectProfile(hostname, opts, sensor)
+		if err != nil {
+			return err
+		}
+		if useOEM && profile == "" {
+			m.Log.Debugf("Skipping %s, it does not support DCMI power readings and has no OEM profile", hostname)
+			return nil
+		}
+	}
+	if m.IdentityTags {
+		if err := m.identify(hostname, opts); err != nil {
+			return err
+		}
+	}
+	if m.PSUReadings {
+		if err := m.queryPSU(acc, hostname, opts); err != nil {
+			acc.AddError(fmt.Errorf("power supplies: %v", err))
+		}
+	}
+
+	if oemProfiles[profile].replacesDCMI {
+		useOEM = true
+	}
+	if useOEM {
+		return m.queryOEM(acc, hostname, opts, profile, sensor)
 	}
-	opts = append(opts, "dcmi", "power", "reading")
 
+	args := append(append([]string{}, opts...), "dcmi", "power", "reading")
 	if m.SamplePeriod != "" {
-		opts = append(opts, m.SamplePeriod)
+		args = append(args, m.SamplePeriod)
 	}
 
-	name := m.Path
-	if m.UseSudo {
-		// -n - avoid prompting the user for input of any kind
-		opts = append([]string{"-n", name}, opts...)
-		name = "sudo"
+	var err error
+	if m.DryRun || !canary(hostname) {
+		err = m.query(acc, hostname, args, parseReadings, "")
+	} else {
+		// Canary servers are queried with both implementations
+		err = m.query(acc, hostname, args, parseReadings, implementationLegacy)
+		m.queryRaw(acc, hostname, opts)
 	}
-	cmd := execCommand(name, opts...)
-	out, err := internal.CombinedOutputTimeout(cmd, m.Timeout.Duration)
+	if recordQuery(hostname, err) {
+		m.Log.Warnf("Recorded %s as not supporting DCMI power readings after %d failed queries, it is probed again in %s: %v",
+			hostname, unsupportedThreshold, unsupportedBackoff, err)
+	}
+
+	// BMCs found to lack DCMI are queried with the OEM profile right away
+	if e, ok := err.(*bmcError); ok && e.class == errorUnsupportedCommand && profile != "" {
+		m.Log.Debugf("Querying %s with the %s profile, it does not support DCMI power readings", hostname, profile)
+		return m.queryOEM(acc, hostname, opts, profile, sensor)
+	}
+	return err
+}
+
+// query runs the ipmitool command and adds the readings parsed from its
+// output.  On canary servers the metric is tagged with the implementation.
+func (m *Ipmi) query(acc telegraf.Accumulator, hostname string, args []string,
+	parse func(string, []byte) (map[string]interface{}, error), implementation string) error {
+	cmd, out, err := m.run(hostname, args)
 	timestamp := time.Now()
 	if err != nil {
-		return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+		return err
+	}
+
+	fields, err := parse(hostname, out)
+	missing := missingReadings(fields)
+	if len(missing) > 0 && !m.DryRun && implementation != implementationRaw {
+		m.captureOutput(hostname, cmd.Args, out, missing, timestamp)
+	}
+	if err != nil {
+		return err
+	}
+	// Readings with all power readings rejected are dropped
+	rejected := m.rejectImplausible(fields)
+	dropped := len(rejected) > 0 && !hasPowerReading(fields)
+	if len(rejected) > 0 && m.DryRun {
+		m.Log.Infof("Rejected implausible readings: %s", strings.Join(rejected, ", "))
+	} else if len(rejected) > 0 {
+		m.Log.Debugf("Rejected implausible readings %s of %s", strings.Join(rejected, ", "), hostname)
+		if implementation != implementationRaw {
+			m.rejected.add(hostname, rejected)
+		}
+	}
+	m.filterFields(fields)
+	if m.DryRun {
+		m.Log.Infof("Parsed fields:\n%s", formatFields(fields))
+		return nil
 	}
-	return parseInner(acc, hostname, out, timestamp)
+
+	if len(missing) > 0 && m.RawOutputField {
+		fields["raw_output"] = truncateOutput(out, m.RawOutputMaxLength)
+	}
+	if len(fields) == 0 || dropped {
+		return nil
+	}
+	tags := make(map[string]string)
+	if hostname != "" {
+		tags["server"] = hostname
+	}
+	if implementation != "" {
+		tags[implementationTag] = implementation
+	}
+	acc.AddFields("ipmi_power", fields, tags, timestamp)
+	return nil
 }
 
-func parseInner(acc telegraf.Accumulator, hostname string, cmdOut []byte, measured_at time.Time) error {
+// localInterface returns the interface used to query the local machine,
+// local_interface overrides the interface option.
+func (m *Ipmi) localInterface() string {
+	if m.LocalInterface != "" {
+		return m.LocalInterface
+	}
+	return m.Interface
+}
+
+// run runs the ipmitool command against the BMC of hostname, waiting for a
+// token of its rate limit first, and logs the command and its output in dry
+// run mode.  Failed commands return a bmcError classified from the output.
+func (m *Ipmi) run(hostname string, args []string) (*exec.Cmd, []byte, error) {
+	if err := m.reserve(hostname); err != nil {
+		return nil, nil, err
+	}
+	cmd := m.command(args...)
+	out, err := internal.CombinedOutputTimeout(cmd, m.Timeout.Duration)
+	if m.DryRun {
+		m.Log.Infof("Command: %s", strings.Join(ipmi.RedactPassword(cmd.Args), " "))
+		m.Log.Infof("Output:\n%s", string(out))
+	}
+	if err != nil {
+		return cmd, out, newBMCError(hostname, classify(err, out),
+			"failed to run command %s: %s - %s", strings.Join(ipmi.RedactPassword(cmd.Args), " "), err, string(out))
+	}
+	return cmd, out, nil
+}
+
+// reserve waits for a token of the rate limit of the BMC, every command sent
+// to it takes one.  The local BMC is not limited.
+func (m *Ipmi) reserve(hostname string) error {
+	if m.RateLimit <= 0 || hostname == "" {
+		return nil
+	}
+	b := getBucket(hostname, m.RateLimit, m.RateLimitBurst)
+	wait, ok := b.reserve(time.Now(), m.Timeout.Duration)
+	if !ok {
+		return fmt.Errorf("rate limit of %d queries per minute exceeded for %s", m.RateLimit, hostname)
+	}
+	time.Sleep(wait)
+	return nil
+}
+
+// command returns the ipmitool command for the given arguments, wrapped in
+// the sudo command and run on the jump host if configured.
+func (m *Ipmi) command(opts ...string) *exec.Cmd {
+	if m.SSHHost != "" {
+		return m.sshCommand(opts)
+	}
+	name := m.Path
+	if m.UseSudo {
+		name, opts = ipmi.Escalate(m.SudoCommand, name, opts)
+	}
+	return execCommand(name, opts...)
+}
+
+// filterFields removes the fields not selected by fields_include and
+// fields_exclude.
+func (m *Ipmi) filterFields(fields map[string]interface{}) {
+	if m.fieldFilter == nil {
+		return
+	}
+	for key := range fields {
+		if !m.fieldFilter.Match(key) {
+			delete(fields, key)
+		}
+	}
+}
+
+// parseReadings returns the readings and units printed by ipmitool.
+func parseReadings(hostname string, cmdOut []byte) (map[string]interface{}, error) {
 	// each line will look something like
-	// Planar VBAT      | 3.05 Volts        | ok
+	//     Instantaneous power reading:                   220 Watts
 
 	fields := make(map[string]interface{})
 	scanner := bufio.NewScanner(bytes.NewReader(cmdOut))
@@ -150,13 +685,31 @@ func parseInner(acc telegraf.Accumulator, hostname string, cmdOut []byte, measur
 			continue
 		}
 		fields[key] = floatval
-		fields[key + "_unit"] = ipmiFields["unit"]
+		fields[key+"_unit"] = ipmiFields["unit"]
 
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, newBMCError(hostname, errorParseError, "reading output: %v", err)
+	}
+	if len(fields) == 0 {
+		return nil, newBMCError(hostname, errorParseError, "no power readings found in output: %s", string(cmdOut))
+	}
+	return fields, nil
+}
 
-	acc.AddFields("ipmi_power", fields, nil, measured_at)
+// formatFields returns the fields sorted by name, one per line.
+func formatFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-	return scanner.Err()
+	var buf strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "  %s = %v (%T)\n", k, fields[k], fields[k])
+	}
+	return buf.String()
 }
 
 // extractFieldsFromRegex consumes a regex with named capture groups and returns a kvp map of strings with the results
@@ -196,14 +749,11 @@ func transform(s string) string {
 }
 
 func init() {
-	m := Ipmi{}
-	path, _ := exec.LookPath("ipmitool")
-	if len(path) > 0 {
-		m.Path = path
-	}
-	m.Timeout = internal.Duration{Duration: time.Second * 20}
 	inputs.Add("ipmi_power", func() telegraf.Input {
-		m := m
-		return &m
+		return &Ipmi{
+			Timeout:            internal.Duration{Duration: time.Second * 20},
+			PollInterval:       internal.Duration{Duration: defaultPollInterval},
+			RawOutputMaxLength: defaultRawOutputMaxLength,
+		}
 	})
 }
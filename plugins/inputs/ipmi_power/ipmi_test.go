@@ -0,0 +1,470 @@
+package ipmi_power
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/featureflag"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitPathNotFound(t *testing.T) {
+	i := &Ipmi{
+		Path:    "/does/not/exist/ipmitool",
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+	}
+	err := i.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ipmitool not found")
+}
+
+func TestInitRemoteSkipsLocalCheck(t *testing.T) {
+	execCommand = fakeExecCommand(true)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+	}
+	require.NoError(t, i.Init())
+}
+
+func TestInitLocalAccess(t *testing.T) {
+	execCommand = fakeExecCommand(false)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:           os.Args[0],
+		LocalInterface: "open",
+		Timeout:        internal.Duration{Duration: time.Second * 5},
+	}
+	require.NoError(t, i.Init())
+}
+
+func TestInitLocalAccessDenied(t *testing.T) {
+	execCommand = fakeExecCommand(true)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:    os.Args[0],
+		Timeout: internal.Duration{Duration: time.Second * 5},
+	}
+	err := i.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "local IPMI access check")
+	require.Contains(t, err.Error(), "/dev/ipmi0")
+}
+
+func TestGather(t *testing.T) {
+	execCommand = fakeExecCommand(false)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:    os.Args[0],
+		Timeout: internal.Duration{Duration: time.Second * 5},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(i.Gather))
+	acc.AssertContainsFields(t, "ipmi_power", map[string]interface{}{
+		"instantaneous_power_reading":                   float64(220),
+		"instantaneous_power_reading_unit":              "Watts",
+		"minimum_during_sampling_period":                float64(28),
+		"minimum_during_sampling_period_unit":           "Watts",
+		"maximum_during_sampling_period":                float64(534),
+		"maximum_during_sampling_period_unit":           "Watts",
+		"average_power_reading_over_sample_period":      float64(222),
+		"average_power_reading_over_sample_period_unit": "Watts",
+		"sampling_period":                               float64(1),
+		"sampling_period_unit":                          "Seconds.",
+	})
+}
+
+func TestServiceMode(t *testing.T) {
+	execCommand = fakeExecCommand(false)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path: os.Args[0],
+		Servers: []string{
+			"USERID:PASSW0RD@lan(192.168.1.1)",
+			"USERID:PASSW0RD@lan(192.168.1.2)",
+		},
+		Timeout:      internal.Duration{Duration: time.Second * 5},
+		ServiceMode:  true,
+		PollInterval: internal.Duration{Duration: time.Millisecond * 50},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Start(&acc))
+	defer i.Stop()
+
+	// Gather does not query the BMCs in service mode
+	require.NoError(t, i.Gather(&acc))
+
+	acc.Wait(4)
+	i.Stop()
+	require.Empty(t, acc.Errors)
+	require.Equal(t, float64(220), acc.Metrics[0].Fields["instantaneous_power_reading"])
+
+	// No metrics are added after Stop returns
+	n := acc.NMetrics()
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, n, acc.NMetrics())
+}
+
+func TestPlausibility(t *testing.T) {
+	execCommand = fakeOutputExecCommand(`
+    Instantaneous power reading:                 65535 Watts
+    Minimum during sampling period:                  0 Watts
+    Maximum during sampling period:              65535 Watts
+    Average power reading over sample period:      222 Watts
+    Sampling period:                          00000001 Seconds.
+`)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:          os.Args[0],
+		Servers:       []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:       internal.Duration{Duration: time.Second * 5},
+		MinValidWatts: 1,
+		MaxValidWatts: 5000,
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(i.Gather))
+	require.NoError(t, acc.GatherError(i.Gather))
+
+	metric, ok := acc.Get("ipmi_power")
+	require.True(t, ok)
+	require.Equal(t, map[string]interface{}{
+		"average_power_reading_over_sample_period":      float64(222),
+		"average_power_reading_over_sample_period_unit": "Watts",
+		"sampling_period":                               float64(1),
+		"sampling_period_unit":                          "Seconds.",
+	}, metric.Fields)
+
+	for _, reading := range []string{"instantaneous_power_reading", "minimum_during_sampling_period", "maximum_during_sampling_period"} {
+		acc.AssertContainsTaggedFields(t, "ipmi_power_rejected",
+			map[string]interface{}{"count": int64(2)},
+			map[string]string{"server": "192.168.1.1", "reading": reading})
+	}
+}
+
+func TestPlausibilityAllRejected(t *testing.T) {
+	execCommand = fakeOutputExecCommand(`
+    Instantaneous power reading:                     0 Watts
+    Sampling period:                          00000001 Seconds.
+`)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:          os.Args[0],
+		Servers:       []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:       internal.Duration{Duration: time.Second * 5},
+		MinValidWatts: 1,
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(i.Gather))
+	require.False(t, acc.HasMeasurement("ipmi_power"))
+	require.True(t, acc.HasMeasurement("ipmi_power_rejected"))
+}
+
+func TestFieldFilter(t *testing.T) {
+	execCommand = fakeExecCommand(false)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:          os.Args[0],
+		Servers:       []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:       internal.Duration{Duration: time.Second * 5},
+		FieldsInclude: []string{"*_power_reading*"},
+		FieldsExclude: []string{"*_unit"},
+	}
+	require.NoError(t, i.Init())
+	require.Equal(t, 2, i.EstimateSeries()[0].Fields)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(i.Gather))
+	metric, ok := acc.Get("ipmi_power")
+	require.True(t, ok)
+	require.Equal(t, map[string]interface{}{
+		"instantaneous_power_reading":              float64(220),
+		"average_power_reading_over_sample_period": float64(222),
+	}, metric.Fields)
+}
+
+func TestSudoCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		sudo     []string
+		expected []string
+	}{
+		{
+			name:     "default",
+			expected: []string{"sudo", "-n", "/usr/bin/ipmitool", "dcmi", "power", "reading"},
+		},
+		{
+			name:     "appended",
+			sudo:     []string{"doas", "-n"},
+			expected: []string{"doas", "-n", "/usr/bin/ipmitool", "dcmi", "power", "reading"},
+		},
+		{
+			name:     "placeholder",
+			sudo:     []string{"pbrun", "-u", "root", "{command}", "--"},
+			expected: []string{"pbrun", "-u", "root", "/usr/bin/ipmitool", "dcmi", "power", "reading", "--"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Ipmi{Path: "/usr/bin/ipmitool", UseSudo: true, SudoCommand: tt.sudo}
+			cmd := i.command("dcmi", "power", "reading")
+			require.Equal(t, tt.expected, cmd.Args)
+		})
+	}
+}
+
+func TestLocalInterface(t *testing.T) {
+	require.Equal(t, "", (&Ipmi{}).localInterface())
+	require.Equal(t, "open", (&Ipmi{Interface: "open"}).localInterface())
+	require.Equal(t, "open", (&Ipmi{Interface: "lanplus", LocalInterface: "open"}).localInterface())
+}
+
+func TestInitInvalidInterface(t *testing.T) {
+	tests := []struct {
+		name string
+		i    *Ipmi
+	}{
+		{"option", &Ipmi{Interface: "serial-direct", Servers: []string{"USERID:PASSW0RD@(192.168.1.1)"}}},
+		{"local", &Ipmi{LocalInterface: "lanplus2"}},
+		{"protocol", &Ipmi{Servers: []string{"USERID:PASSW0RD@lanplsu(192.168.1.1)"}}},
+		{"parameter", &Ipmi{Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)?interface=imb"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.i.Init()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "must be one of lan, lanplus, open")
+		})
+	}
+}
+
+func TestInitSerialInterface(t *testing.T) {
+	i := &Ipmi{LocalInterface: "serial"}
+	err := i.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "serial device")
+
+	i = &Ipmi{
+		Path:      os.Args[0],
+		Interface: "serial",
+		Servers:   []string{"USERID:PASSW0RD@(/dev/ttyS0:115200)"},
+	}
+	require.NoError(t, i.Init())
+}
+
+func TestInitSudoCommandPlaceholderFirst(t *testing.T) {
+	i := &Ipmi{
+		UseSudo:     true,
+		SudoCommand: []string{"{command}"},
+		Servers:     []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+	}
+	require.Error(t, i.Init())
+}
+
+func TestDryRun(t *testing.T) {
+	execCommand = fakeExecCommand(false)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path: os.Args[0],
+		Servers: []string{
+			"USERID:PASSW0RD@lan(192.168.1.1)",
+			"USERID:PASSW0RD@lan(192.168.1.2)",
+		},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		DryRun:  true,
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Zero(t, acc.NMetrics())
+}
+
+// fakeExecCommand returns a mock of the exec.Command call calling the test
+// binary, denyLocal simulates a machine without access to its local BMC.
+func fakeExecCommand(denyLocal bool) func(string, ...string) *exec.Cmd {
+	return func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+		if denyLocal {
+			cmd.Env = append(cmd.Env, "DENY_LOCAL_IPMI=1")
+		}
+		return cmd
+	}
+}
+
+// fakeFailingExecCommand returns a mock of the exec.Command call printing
+// the given output and failing.
+func fakeFailingExecCommand(output string) func(string, ...string) *exec.Cmd {
+	return func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(false)(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_FAILURE="+output)
+		return cmd
+	}
+}
+
+// fakeOutputExecCommand returns a mock of the exec.Command call printing
+// the given output for power readings.
+func fakeOutputExecCommand(output string) func(string, ...string) *exec.Cmd {
+	return func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(false)(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_OUTPUT="+output)
+		return cmd
+	}
+}
+
+// fakeNoDCMIExecCommand returns a mock of the exec.Command call of a BMC
+// not supporting DCMI.
+func fakeNoDCMIExecCommand() func(string, ...string) *exec.Cmd {
+	return func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(false)(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_NO_DCMI=1")
+		return cmd
+	}
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command
+// For example, if you run:
+// GO_WANT_HELPER_PROCESS=1 go test -test.run=TestHelperProcess -- ipmitool dcmi power reading
+// it returns below mockData.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	// Commands run over ssh are quoted for the remote shell
+	cmd := strings.Replace(strings.Join(args[2:], " "), "'", "", -1)
+
+	// Passwords of commands run over ssh are read from stdin
+	if strings.Contains(cmd, " -f /dev/stdin ") {
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil || password != "PASSW0RD\n" {
+			fmt.Fprint(os.Stdout, "Unable to read password from file /dev/stdin\n")
+			os.Exit(1)
+		}
+	}
+
+	if failure, ok := os.LookupEnv("FAKE_IPMI_FAILURE"); ok {
+		fmt.Fprint(os.Stdout, failure)
+		os.Exit(1)
+	}
+
+	switch {
+	case strings.Contains(cmd, " dcmi ") && os.Getenv("FAKE_IPMI_NO_DCMI") == "1":
+		fmt.Fprint(os.Stdout, "DCMI request failed because: Invalid command (c1)\n")
+		os.Exit(1)
+	case strings.HasSuffix(cmd, "dcmi discover"):
+		if os.Getenv("DENY_LOCAL_IPMI") == "1" {
+			fmt.Fprint(os.Stdout, "Could not open device at /dev/ipmi0 or /dev/ipmi/0 or /dev/ipmidev/0: No such file or directory\n")
+			os.Exit(1)
+		}
+		fmt.Fprint(os.Stdout, "\n    DCMI capabilities\n    -----------------\n")
+	case strings.HasSuffix(cmd, "dcmi power reading") && os.Getenv("FAKE_IPMI_OUTPUT") != "":
+		fmt.Fprint(os.Stdout, os.Getenv("FAKE_IPMI_OUTPUT"))
+	case strings.HasSuffix(cmd, "dcmi power reading"):
+		fmt.Fprint(os.Stdout, `
+    Instantaneous power reading:                   220 Watts
+    Minimum during sampling period:                 28 Watts
+    Maximum during sampling period:                534 Watts
+    Average power reading over sample period:      222 Watts
+    IPMI timestamp:                           Tue Dec 15 10:04:13 2020
+    Sampling period:                          00000001 Seconds.
+    Power reading state is:                   activated
+`)
+	case strings.HasSuffix(cmd, "raw 0x2e 0xc8 0x57 0x01 0x00 0x01 0x00 0x00"):
+		fmt.Fprint(os.Stdout, " 57 01 00 a5 01 50 00 10 03 9e 01 5d 89 d8 5f 10\n 0e 00 00 50\n")
+	case strings.HasSuffix(cmd, "raw 0x30 0xc9"):
+		fmt.Fprint(os.Stdout, " 01\n")
+	case strings.HasSuffix(cmd, "sdr entity 10"):
+		fmt.Fprint(os.Stdout, psuSDR)
+	case strings.HasSuffix(cmd, "mc info"):
+		manufacturer := os.Getenv("FAKE_IPMI_MANUFACTURER")
+		if manufacturer == "" {
+			manufacturer = "10876"
+		}
+		fmt.Fprintf(os.Stdout, mcInfoOutput, manufacturer)
+	case strings.HasSuffix(cmd, "dcmi asset_tag"):
+		fmt.Fprint(os.Stdout, assetTagOutput)
+	case strings.HasSuffix(cmd, "dcmi get_mc_id_string") && os.Getenv("FAKE_IPMI_NO_MC_ID") == "1":
+		fmt.Fprint(os.Stdout, "Get Management Controller Identifier String failed: Invalid command\n")
+		os.Exit(1)
+	case strings.HasSuffix(cmd, "dcmi get_mc_id_string"):
+		fmt.Fprint(os.Stdout, mcIDOutput)
+	case strings.HasSuffix(cmd, "raw 0x30 0xb3 0x0a 0x00"):
+		fmt.Fprint(os.Stdout, " 38 01 1c 00 00 00 00\n")
+	case strings.HasSuffix(cmd, "raw 0x2c 0x02 0xdc 0x01 0x00 0x00"):
+		fmt.Fprint(os.Stdout, " dc dc 00 1c 00 16 02 de 00 5d 89 d8 5f e8 03 00\n 00 40\n")
+	default:
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestEstimateSeries(t *testing.T) {
+	i := &Ipmi{
+		Servers: []string{
+			"USERID:PASSW0RD@lan(192.168.1.1)",
+			"USERID:PASSW0RD@lan(192.168.1.2)",
+			"USERID:PASSW0RD@lan(192.168.1.3)?oem_profile=supermicro_node",
+		},
+		FieldsExclude: []string{"*_unit"},
+		PSUReadings:   true,
+	}
+	require.Equal(t, []telegraf.SeriesEstimate{
+		{Measurement: "ipmi_power", Series: 2, Fields: 5},
+		{Measurement: "ipmi_power", Series: 1, Fields: 1},
+		{Measurement: "ipmi_power_supply", Unknown: true},
+	}, i.EstimateSeries())
+
+	// Canary servers report the legacy and the raw implementation
+	featureflag.Set(rawReadingFlag, 100)
+	defer featureflag.Set(rawReadingFlag, 0)
+	require.Equal(t, 4, i.EstimateSeries()[0].Series)
+}
+
+func TestEstimateSeriesServiceMode(t *testing.T) {
+	i := &Ipmi{
+		ServiceMode:  true,
+		PollInterval: internal.Duration{Duration: 10 * time.Second},
+	}
+	require.Equal(t, []telegraf.SeriesEstimate{
+		{Measurement: "ipmi_power", Series: 1, Fields: 10, Interval: 10 * time.Second},
+	}, i.EstimateSeries())
+}
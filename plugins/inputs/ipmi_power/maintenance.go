@@ -0,0 +1,99 @@
+package ipmi_power
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maintenanceList holds the BMCs excluded from collection, it is re-read
+// whenever the backing file changes.
+type maintenanceList struct {
+	sync.RWMutex
+	path    string
+	modTime time.Time
+	until   map[string]time.Time
+}
+
+// load re-reads the maintenance file if it was modified since the last
+// load. A missing file is treated as an empty list.
+func (l *maintenanceList) load() error {
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) {
+		l.Lock()
+		l.modTime = time.Time{}
+		l.until = nil
+		l.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	l.RLock()
+	unchanged := info.ModTime().Equal(l.modTime)
+	l.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	until, err := parseMaintenance(f)
+	if err != nil {
+		return fmt.Errorf("parsing maintenance file %s: %v", l.path, err)
+	}
+
+	l.Lock()
+	l.modTime = info.ModTime()
+	l.until = until
+	l.Unlock()
+	return nil
+}
+
+// contains returns true if the host is in maintenance at the given time.
+func (l *maintenanceList) contains(host string, now time.Time) bool {
+	l.RLock()
+	defer l.RUnlock()
+	until, ok := l.until[host]
+	if !ok {
+		return false
+	}
+	return until.IsZero() || now.Before(until)
+}
+
+// parseMaintenance reads lines holding a host and an optional RFC3339 end
+// of maintenance, ignoring blank lines and comments starting with '#'.
+func parseMaintenance(f *os.File) (map[string]time.Time, error) {
+	until := make(map[string]time.Time)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		parts := strings.Fields(line)
+		switch len(parts) {
+		case 0:
+			continue
+		case 1:
+			until[parts[0]] = time.Time{}
+		case 2:
+			t, err := time.Parse(time.RFC3339, parts[1])
+			if err != nil {
+				return nil, err
+			}
+			until[parts[0]] = t
+		default:
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+	}
+	return until, scanner.Err()
+}
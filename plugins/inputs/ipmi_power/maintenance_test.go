@@ -0,0 +1,55 @@
+package ipmi_power
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipmi_power")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "maintenance")
+	l := &maintenanceList{path: path}
+	now := time.Date(2020, 12, 15, 12, 0, 0, 0, time.UTC)
+
+	// a missing file excludes nothing
+	require.NoError(t, l.load())
+	require.False(t, l.contains("192.168.1.1", now))
+
+	content := `# firmware updates
+192.168.1.1
+192.168.1.2 2020-12-15T13:00:00Z
+192.168.1.3 2020-12-15T11:00:00Z # already done
+`
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0640))
+	require.NoError(t, l.load())
+	require.True(t, l.contains("192.168.1.1", now))
+	require.True(t, l.contains("192.168.1.2", now))
+	require.False(t, l.contains("192.168.1.2", now.Add(time.Hour)))
+	require.False(t, l.contains("192.168.1.3", now))
+	require.False(t, l.contains("192.168.1.4", now))
+
+	// removing the file lifts the maintenance
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, l.load())
+	require.False(t, l.contains("192.168.1.1", now))
+}
+
+func TestMaintenanceListInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipmi_power")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "maintenance")
+	require.NoError(t, ioutil.WriteFile(path, []byte("192.168.1.1 tomorrow\n"), 0640))
+
+	l := &maintenanceList{path: path}
+	require.Error(t, l.load())
+}
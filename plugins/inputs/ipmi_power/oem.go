@@ -0,0 +1,262 @@
+package ipmi_power
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+)
+
+// oemProfileTag tags the readings of BMCs queried with an OEM profile.
+const oemProfileTag = "oem_profile"
+
+// rawRunner runs "ipmitool raw" with the request bytes and returns the
+// response bytes.
+type rawRunner func(request ...string) ([]byte, error)
+
+// oemProfile reads the power consumption of a BMC lacking DCMI power
+// readings with vendor specific raw commands.
+type oemProfile struct {
+	// read returns the power consumption in Watts.
+	read func(run rawRunner, sensor string) (float64, error)
+	// tags optionally returns tags identifying the reading.
+	tags func(run rawRunner) (map[string]string, error)
+	// replacesDCMI is set if the DCMI readings are not the ones of the
+	// server, the profile is then used even if the BMC supports DCMI.
+	replacesDCMI bool
+}
+
+var oemProfiles = map[string]oemProfile{
+	"supermicro":      {read: readSupermicro},
+	"supermicro_node": {read: readSupermicroNode, tags: readSupermicroNodeSlot, replacesDCMI: true},
+	"dell":            {read: readDell},
+	"hpe":             {read: readHPE},
+}
+
+// supermicroSupplies are the PMBus addresses of the power supplies on the
+// I2C bus 7 of Supermicro boards.
+var supermicroSupplies = []string{"0x78", "0x7a"}
+
+// readSupermicro sums the input power of the power supplies read with the
+// PMBus READ_PIN command through the Master Write-Read command.  Missing
+// supplies are skipped.
+func readSupermicro(run rawRunner, _ string) (float64, error) {
+	var total float64
+	var found bool
+	var lastErr error
+	for _, addr := range supermicroSupplies {
+		data, err := run("0x06", "0x52", "0x07", addr, "0x02", "0x97")
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(data) < 2 {
+			return 0, fmt.Errorf("short READ_PIN response of power supply %s: % x", addr, data)
+		}
+		total += ipmi.Linear11(binary.LittleEndian.Uint16(data))
+		found = true
+	}
+	if !found {
+		return 0, lastErr
+	}
+	return total, nil
+}
+
+// readSupermicroNode reads the power of the node of a multi-node chassis,
+// such as a BigTwin, where DCMI reports the power of the whole chassis.  The
+// current platform power is read with the Get Node Manager Statistics
+// command of Intel Node Manager, which the node BMC measures at the node.
+func readSupermicroNode(run rawRunner, _ string) (float64, error) {
+	data, err := run("0x2e", "0xc8", "0x57", "0x01", "0x00", "0x01", "0x00", "0x00")
+	if err != nil {
+		return 0, err
+	}
+	// The response starts with the Intel manufacturer id
+	if len(data) < 5 {
+		return 0, fmt.Errorf("short node manager statistics response: % x", data)
+	}
+	return float64(binary.LittleEndian.Uint16(data[3:5])), nil
+}
+
+// readSupermicroNodeSlot reads the slot of the node in a multi-node chassis
+// with the Supermicro OEM Get Node ID command, 0 being node A.
+func readSupermicroNodeSlot(run rawRunner) (map[string]string, error) {
+	data, err := run("0x30", "0xc9")
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 1 {
+		return nil, fmt.Errorf("short node id response: % x", data)
+	}
+	if data[0] > 25 {
+		return nil, fmt.Errorf("invalid node id %d", data[0])
+	}
+	return map[string]string{"node_slot": string(rune('A' + data[0]))}, nil
+}
+
+// readDell reads the instantaneous power consumption with the Dell OEM Get
+// Power Consumption Data command, as "ipmitool delloem powermonitor" does.
+func readDell(run rawRunner, _ string) (float64, error) {
+	data, err := run("0x30", "0xb3", "0x0a", "0x00")
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < 2 {
+		return 0, fmt.Errorf("short power consumption response: % x", data)
+	}
+	return float64(binary.LittleEndian.Uint16(data)), nil
+}
+
+// readHPE reads the power meter sensor of older iLO generations with the
+// Get Sensor Reading command and converts it with the factors returned by
+// Get Sensor Reading Factors.  The sensor number differs between models.
+func readHPE(run rawRunner, sensor string) (float64, error) {
+	if sensor == "" {
+		return 0, fmt.Errorf("the hpe profile requires oem_sensor, the number of the power meter sensor")
+	}
+	data, err := run("0x04", "0x2d", sensor)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < 2 {
+		return 0, fmt.Errorf("short sensor reading response: % x", data)
+	}
+	if data[1]&0x20 != 0 {
+		return 0, fmt.Errorf("reading of sensor %s unavailable", sensor)
+	}
+	reading := data[0]
+
+	factors, err := run("0x04", "0x23", sensor, fmt.Sprintf("0x%02x", reading))
+	if err != nil {
+		return 0, err
+	}
+	if len(factors) < 7 {
+		return 0, fmt.Errorf("short sensor reading factors response: % x", factors)
+	}
+	m := signExtend(int(factors[1])|int(factors[2]&0xc0)<<2, 10)
+	b := signExtend(int(factors[3])|int(factors[4]&0xc0)<<2, 10)
+	rExp := signExtend(int(factors[6]>>4), 4)
+	bExp := signExtend(int(factors[6]&0x0f), 4)
+	return (float64(m)*float64(reading) + float64(b)*math.Pow10(bExp)) * math.Pow10(rExp), nil
+}
+
+// signExtend interprets the lower bits of v as two's complement.
+func signExtend(v int, bits uint) int {
+	shift := 64 - bits
+	return int(int64(v) << shift >> shift)
+}
+
+// oemProfile returns the OEM profile and sensor of the server given by its
+// 'oem_profile' and 'oem_sensor' parameters, falling back to the ones of the
+// plugin.
+func (m *Ipmi) oemProfile(server string) (string, string) {
+	profile, sensor := m.OEMProfile, m.OEMSensor
+	_, params := ipmi.SplitParams(server)
+	if p := params.Get("oem_profile"); p != "" {
+		profile = p
+	}
+	if s := params.Get("oem_sensor"); s != "" {
+		sensor = s
+	}
+	return profile, sensor
+}
+
+// checkOEMProfile validates the OEM profile and sensor.
+func (m *Ipmi) checkOEMProfile(profile, sensor string) error {
+	if profile == "" {
+		return nil
+	}
+	if _, ok := oemProfiles[profile]; !ok && profile != autoProfile {
+		return fmt.Errorf("unknown oem_profile %q, must be one of %s", profile, strings.Join(append(oemProfileNames(), autoProfile), ", "))
+	}
+	if profile == "hpe" && sensor == "" {
+		return fmt.Errorf("the hpe oem_profile requires oem_sensor")
+	}
+	return nil
+}
+
+// oemProfileNames returns the names of the built-in profiles.
+func oemProfileNames() []string {
+	names := make([]string, 0, len(oemProfiles))
+	for name := range oemProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// queryOEM reads the power consumption of a BMC lacking DCMI power readings
+// with the OEM profile.
+func (m *Ipmi) queryOEM(acc telegraf.Accumulator, hostname string, opts []string, profile, sensor string) error {
+	p, ok := oemProfiles[profile]
+	if !ok {
+		return fmt.Errorf("unknown OEM profile %q", profile)
+	}
+
+	run := func(request ...string) ([]byte, error) {
+		args := append(append(append([]string{}, opts...), "raw"), request...)
+		_, out, err := m.run(hostname, args)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ipmi.ParseRawResponse(out)
+		if err != nil {
+			return nil, newBMCError(hostname, errorParseError, "%v in output: %s", err, string(out))
+		}
+		return data, nil
+	}
+
+	tags := map[string]string{oemProfileTag: profile}
+	if hostname != "" {
+		tags["server"] = hostname
+	}
+	if p.tags != nil {
+		profileTags, err := p.tags(run)
+		if err != nil {
+			return oemError(hostname, profile, err)
+		}
+		for k, v := range profileTags {
+			tags[k] = v
+		}
+	}
+
+	watts, err := p.read(run, sensor)
+	timestamp := time.Now()
+	if err != nil {
+		return oemError(hostname, profile, err)
+	}
+
+	fields := map[string]interface{}{
+		"instantaneous_power_reading":      watts,
+		"instantaneous_power_reading_unit": "Watts",
+	}
+	rejected := m.rejectImplausible(fields)
+	if len(rejected) > 0 {
+		m.Log.Debugf("Rejected implausible readings %s of %s", strings.Join(rejected, ", "), hostname)
+		m.rejected.add(hostname, rejected)
+	}
+	m.filterFields(fields)
+	if m.DryRun {
+		m.Log.Infof("Parsed fields:\n%s", formatFields(fields))
+		return nil
+	}
+	if len(rejected) > 0 || len(fields) == 0 {
+		return nil
+	}
+	acc.AddFields("ipmi_power", fields, tags, timestamp)
+	return nil
+}
+
+// oemError classifies errors of OEM profiles not failing with a BMC error
+// as parse errors.
+func oemError(hostname, profile string, err error) error {
+	if _, ok := err.(*bmcError); !ok {
+		err = newBMCError(hostname, errorParseError, "%s profile: %v", profile, err)
+	}
+	return err
+}
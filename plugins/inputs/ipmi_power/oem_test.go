@@ -0,0 +1,188 @@
+package ipmi_power
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/inventory"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRunner returns the responses by request, unknown requests fail.
+func fakeRunner(responses map[string][]byte) rawRunner {
+	return func(request ...string) ([]byte, error) {
+		data, ok := responses[strings.Join(request, " ")]
+		if !ok {
+			return nil, errors.New("invalid command")
+		}
+		return data, nil
+	}
+}
+
+func TestReadSupermicro(t *testing.T) {
+	run := fakeRunner(map[string][]byte{
+		"0x06 0x52 0x07 0x78 0x02 0x97": {0xd2, 0xf8},
+		"0x06 0x52 0x07 0x7a 0x02 0x97": {0xc8, 0xf8},
+	})
+	watts, err := readSupermicro(run, "")
+	require.NoError(t, err)
+	require.Equal(t, 205.0, watts)
+
+	// A single power supply is enough
+	run = fakeRunner(map[string][]byte{
+		"0x06 0x52 0x07 0x78 0x02 0x97": {0xd2, 0xf8},
+	})
+	watts, err = readSupermicro(run, "")
+	require.NoError(t, err)
+	require.Equal(t, 105.0, watts)
+
+	_, err = readSupermicro(fakeRunner(nil), "")
+	require.Error(t, err)
+}
+
+func TestReadSupermicroNode(t *testing.T) {
+	run := fakeRunner(map[string][]byte{
+		"0x2e 0xc8 0x57 0x01 0x00 0x01 0x00 0x00": {0x57, 0x01, 0x00, 0xa5, 0x01, 0x50, 0x00, 0x10, 0x03, 0x9e, 0x01},
+		"0x30 0xc9": {0x02},
+	})
+	watts, err := readSupermicroNode(run, "")
+	require.NoError(t, err)
+	require.Equal(t, 421.0, watts)
+
+	tags, err := readSupermicroNodeSlot(run)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"node_slot": "C"}, tags)
+
+	run = fakeRunner(map[string][]byte{
+		"0x2e 0xc8 0x57 0x01 0x00 0x01 0x00 0x00": {0x57, 0x01, 0x00},
+		"0x30 0xc9": {0xff},
+	})
+	_, err = readSupermicroNode(run, "")
+	require.Error(t, err)
+	_, err = readSupermicroNodeSlot(run)
+	require.Error(t, err)
+}
+
+func TestReadDell(t *testing.T) {
+	run := fakeRunner(map[string][]byte{
+		"0x30 0xb3 0x0a 0x00": {0x38, 0x01, 0x1c, 0x00, 0x00, 0x00, 0x00},
+	})
+	watts, err := readDell(run, "")
+	require.NoError(t, err)
+	require.Equal(t, 312.0, watts)
+}
+
+func TestReadHPE(t *testing.T) {
+	// Reading 0x74 with M = 2, B = 0 and no exponents
+	run := fakeRunner(map[string][]byte{
+		"0x04 0x2d 0x0e":      {0x74, 0xc0, 0xc0},
+		"0x04 0x23 0x0e 0x74": {0x75, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00},
+	})
+	watts, err := readHPE(run, "0x0e")
+	require.NoError(t, err)
+	require.Equal(t, 232.0, watts)
+
+	// Reading unavailable
+	run = fakeRunner(map[string][]byte{
+		"0x04 0x2d 0x0e": {0x00, 0xe0, 0xc0},
+	})
+	_, err = readHPE(run, "0x0e")
+	require.Error(t, err)
+
+	_, err = readHPE(run, "")
+	require.Error(t, err)
+}
+
+func TestInitOEMProfile(t *testing.T) {
+	for _, tt := range []struct {
+		profile string
+		sensor  string
+		servers []string
+		ok      bool
+	}{
+		{profile: "dell", ok: true},
+		{profile: "hpe", sensor: "0x0e", ok: true},
+		{profile: "hpe"},
+		{profile: "ibm"},
+		{servers: []string{"USERID:PASSW0RD@lan(192.168.12.1)?oem_profile=hpe&oem_sensor=0x0e"}, ok: true},
+		{servers: []string{"USERID:PASSW0RD@lan(192.168.12.1)?oem_profile=hpe"}},
+	} {
+		i := &Ipmi{
+			Path:       os.Args[0],
+			Servers:    tt.servers,
+			OEMProfile: tt.profile,
+			OEMSensor:  tt.sensor,
+			Timeout:    internal.Duration{Duration: time.Second * 5},
+			Log:        testutil.Logger{},
+		}
+		if tt.servers == nil {
+			i.Servers = []string{"USERID:PASSW0RD@lan(192.168.12.1)"}
+		}
+		err := i.Init()
+		if tt.ok {
+			require.NoError(t, err, tt)
+		} else {
+			require.Error(t, err, tt)
+		}
+	}
+}
+
+func TestOEMFallback(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	defer inventory.Forget(inventoryKind, "192.168.12.2")
+	execCommand = fakeNoDCMIExecCommand()
+
+	i := &Ipmi{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.12.2)?oem_profile=dell"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	// The OEM profile is used as soon as DCMI turns out to be unsupported
+	// and right away once the BMC is known to lack it
+	for n := 0; n < unsupportedThreshold+1; n++ {
+		var acc testutil.Accumulator
+		require.NoError(t, i.Gather(&acc))
+		require.Empty(t, acc.Errors)
+		acc.AssertContainsTaggedFields(t, "ipmi_power",
+			map[string]interface{}{
+				"instantaneous_power_reading":      312.0,
+				"instantaneous_power_reading_unit": "Watts",
+			},
+			map[string]string{"server": "192.168.12.2", oemProfileTag: "dell"})
+	}
+	require.False(t, dcmiSupported("192.168.12.2"))
+}
+
+func TestOEMReplacingDCMI(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand(false)
+
+	i := &Ipmi{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.12.3)?oem_profile=supermicro_node"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	// The node power is read even though the BMC supports DCMI
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "ipmi_power",
+		map[string]interface{}{
+			"instantaneous_power_reading":      421.0,
+			"instantaneous_power_reading_unit": "Watts",
+		},
+		map[string]string{"server": "192.168.12.3", oemProfileTag: "supermicro_node", "node_slot": "B"})
+	require.Len(t, acc.Metrics, 1)
+}
@@ -0,0 +1,74 @@
+package ipmi_power
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// rejectImplausible removes the power readings outside of the valid range
+// along with their units and returns the names of the removed readings.
+func (m *Ipmi) rejectImplausible(fields map[string]interface{}) []string {
+	if m.MinValidWatts == 0 && m.MaxValidWatts == 0 {
+		return nil
+	}
+
+	var rejected []string
+	for _, reading := range dcmiReadings {
+		unit, _ := fields[reading+"_unit"].(string)
+		value, ok := fields[reading].(float64)
+		if !ok || !strings.EqualFold(unit, "Watts") {
+			continue
+		}
+		if value < m.MinValidWatts || (m.MaxValidWatts > 0 && value > m.MaxValidWatts) {
+			delete(fields, reading)
+			delete(fields, reading+"_unit")
+			rejected = append(rejected, reading)
+		}
+	}
+	return rejected
+}
+
+// hasPowerReading returns true if any of the power readings is left.
+func hasPowerReading(fields map[string]interface{}) bool {
+	for _, reading := range dcmiReadings {
+		if unit, _ := fields[reading+"_unit"].(string); strings.EqualFold(unit, "Watts") {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectedCounter counts the rejected readings per server and reading.
+type rejectedCounter struct {
+	sync.Mutex
+	counts map[string]map[string]int64
+}
+
+func (c *rejectedCounter) add(server string, readings []string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]map[string]int64)
+	}
+	if c.counts[server] == nil {
+		c.counts[server] = make(map[string]int64)
+	}
+	for _, reading := range readings {
+		c.counts[server][reading]++
+	}
+}
+
+// emit adds the rejected reading counts of the server to the accumulator.
+func (c *rejectedCounter) emit(acc telegraf.Accumulator, server string) {
+	c.Lock()
+	defer c.Unlock()
+	for reading, count := range c.counts[server] {
+		tags := map[string]string{"reading": reading}
+		if server != "" {
+			tags["server"] = server
+		}
+		acc.AddCounter("ipmi_power_rejected", map[string]interface{}{"count": count}, tags)
+	}
+}
@@ -0,0 +1,140 @@
+package ipmi_power
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// psuEntityID is the IPMI entity id of power supplies, their sensors are
+// listed by "ipmitool sdr entity 10" with the instance of the supply as
+// entity instance.
+const psuEntityID = "10"
+
+// psuUnits maps the units of the analog power supply sensors to the
+// suffix of their fields.
+var psuUnits = map[string]string{
+	"Watts":     "watts",
+	"Amps":      "current_amps",
+	"Volts":     "voltage_volts",
+	"degrees C": "temperature_celsius",
+}
+
+// psuFailures are the discrete states of the power supply status sensors
+// indicating a failed supply.
+var psuFailures = []string{
+	"Failure detected",
+	"Predictive failure",
+	"Power Supply AC lost",
+	"AC lost or out-of-range",
+	"AC out-of-range, but present",
+}
+
+// queryPSU reads the sensors of the power supplies and adds their readings
+// per supply.
+func (m *Ipmi) queryPSU(acc telegraf.Accumulator, hostname string, opts []string) error {
+	args := append(append([]string{}, opts...), "sdr", "entity", psuEntityID)
+	_, out, err := m.run(hostname, args)
+	timestamp := time.Now()
+	if err != nil {
+		return err
+	}
+
+	supplies, err := parsePSU(out)
+	if err != nil {
+		return newBMCError(hostname, errorParseError, "%v", err)
+	}
+	for psu, fields := range supplies {
+		if m.DryRun {
+			m.Log.Infof("Parsed fields of power supply %s:\n%s", psu, formatFields(fields))
+			continue
+		}
+		tags := map[string]string{"psu": psu}
+		if hostname != "" {
+			tags["server"] = hostname
+		}
+		acc.AddFields("ipmi_power_supply", fields, tags, timestamp)
+	}
+	return nil
+}
+
+// parsePSU parses the sensors listed by "ipmitool sdr entity 10" and
+// returns the fields per power supply instance.  The lines are like
+//
+//	PS1 Input Power  | 70h | ok  | 10.1 | 220 Watts
+//	PS1 Status       | C8h | ok  | 10.1 | Presence detected
+func parsePSU(out []byte) (map[string]map[string]interface{}, error) {
+	supplies := make(map[string]map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		columns := strings.Split(scanner.Text(), "|")
+		if len(columns) != 5 {
+			continue
+		}
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+		}
+		name, status, entity, reading := columns[0], columns[2], columns[3], columns[4]
+		parts := strings.SplitN(entity, ".", 2)
+		if len(parts) != 2 || parts[0] != psuEntityID || status == "ns" {
+			continue
+		}
+		psu := parts[1]
+		fields, ok := supplies[psu]
+		if !ok {
+			fields = make(map[string]interface{})
+			supplies[psu] = fields
+		}
+
+		// Analog readings consist of the value and unit, discrete ones
+		// list the asserted states
+		if value := strings.SplitN(reading, " ", 2); len(value) == 2 {
+			if v, err := strconv.ParseFloat(value[0], 64); err == nil {
+				if suffix, ok := psuUnits[value[1]]; ok {
+					fields[psuDirection(name, suffix)+suffix] = v
+				}
+				continue
+			}
+		}
+
+		if _, ok := fields["status"]; ok {
+			fields["status"] = fields["status"].(string) + ", " + reading
+		} else {
+			fields["status"] = reading
+		}
+		state := fields["status"].(string)
+		fields["present"] = strings.Contains(state, "Presence detected")
+		failed := false
+		for _, failure := range psuFailures {
+			if strings.Contains(state, failure) {
+				failed = true
+			}
+		}
+		fields["failed"] = failed
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(supplies) == 0 {
+		return nil, fmt.Errorf("no power supply sensors found in output: %s", string(out))
+	}
+	return supplies, nil
+}
+
+// psuDirection returns the prefix of the field of the sensor, the readings
+// are of the input unless the sensor name says otherwise.  Temperatures
+// have none.
+func psuDirection(name, suffix string) string {
+	if suffix == "temperature_celsius" {
+		return ""
+	}
+	if strings.Contains(strings.ToLower(name), "out") {
+		return "output_"
+	}
+	return "input_"
+}
@@ -0,0 +1,90 @@
+package ipmi_power
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// psuSDR is the output of "ipmitool sdr entity 10" of a server with a
+// failed second power supply.
+const psuSDR = `PS1 Status       | C8h | ok  | 10.1 | Presence detected
+PS1 Input Power  | 70h | ok  | 10.1 | 232 Watts
+PS1 Output Power | 71h | ok  | 10.1 | 210 Watts
+PS1 Current In   | 72h | ok  | 10.1 | 1 Amps
+PS1 Voltage In   | 73h | ok  | 10.1 | 230 Volts
+PS1 Temperature  | 74h | ok  | 10.1 | 38 degrees C
+PS1 Curr Out %   | 75h | ok  | 10.1 | 18 percent
+PS2 Status       | C9h | ok  | 10.2 | Presence detected, Power Supply AC lost
+PS2 Input Power  | 76h | ok  | 10.2 | 0 Watts
+PS2 Output Power | 77h | ns  | 10.2 | No Reading
+`
+
+func TestParsePSU(t *testing.T) {
+	supplies, err := parsePSU([]byte(psuSDR))
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]interface{}{
+		"1": {
+			"status":              "Presence detected",
+			"present":             true,
+			"failed":              false,
+			"input_watts":         232.0,
+			"output_watts":        210.0,
+			"input_current_amps":  1.0,
+			"input_voltage_volts": 230.0,
+			"temperature_celsius": 38.0,
+		},
+		"2": {
+			"status":      "Presence detected, Power Supply AC lost",
+			"present":     true,
+			"failed":      true,
+			"input_watts": 0.0,
+		},
+	}, supplies)
+
+	_, err = parsePSU([]byte("Unable to find sensor id 10\n"))
+	require.Error(t, err)
+}
+
+func TestGatherPSU(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand(false)
+
+	i := &Ipmi{
+		Path:        os.Args[0],
+		Servers:     []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:     internal.Duration{Duration: time.Second * 5},
+		PSUReadings: true,
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.True(t, acc.HasMeasurement("ipmi_power"))
+	acc.AssertContainsTaggedFields(t, "ipmi_power_supply",
+		map[string]interface{}{
+			"status":      "Presence detected, Power Supply AC lost",
+			"present":     true,
+			"failed":      true,
+			"input_watts": 0.0,
+		},
+		map[string]string{"server": "192.168.1.1", "psu": "2"})
+	require.Equal(t, 2, countMeasurement(&acc, "ipmi_power_supply"))
+}
+
+func countMeasurement(acc *testutil.Accumulator, measurement string) int {
+	var n int
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == measurement {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,68 @@
+package ipmi_power
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// buckets holds the token bucket of every BMC, it is shared between all
+// plugin instances so overlapping intervals are limited together.
+var buckets = struct {
+	sync.Mutex
+	m map[string]*bucket
+}{m: make(map[string]*bucket)}
+
+// bucket is a token bucket limiting the queries sent to a single BMC.
+type bucket struct {
+	sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// getBucket returns the bucket for the given BMC address, creating it as
+// needed. The most recent limits configured for an address win.
+func getBucket(address string, perMinute int, burst int) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	buckets.Lock()
+	defer buckets.Unlock()
+	b, ok := buckets.m[address]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: time.Now()}
+		buckets.m[address] = b
+	}
+
+	b.Lock()
+	b.rate = float64(perMinute) / 60
+	b.burst = float64(burst)
+	b.Unlock()
+	return b
+}
+
+// reserve takes a token from the bucket and returns how long the caller has
+// to wait before using it. If the wait would exceed maxWait no token is
+// taken and false is returned.
+func (b *bucket) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+
+	var wait time.Duration
+	if b.tokens < 1 {
+		wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
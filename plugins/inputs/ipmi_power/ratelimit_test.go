@@ -0,0 +1,71 @@
+package ipmi_power
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketReserve(t *testing.T) {
+	b := getBucket("bucket-reserve", 6, 2)
+	now := b.last
+
+	// the burst is available immediately
+	for i := 0; i < 2; i++ {
+		wait, ok := b.reserve(now, 0)
+		require.True(t, ok)
+		require.Equal(t, time.Duration(0), wait)
+	}
+
+	// the next token is refilled after 10 seconds
+	_, ok := b.reserve(now, 5*time.Second)
+	require.False(t, ok)
+	wait, ok := b.reserve(now, 10*time.Second)
+	require.True(t, ok)
+	require.Equal(t, 10*time.Second, wait)
+
+	// the reserved token is already spent once it becomes available
+	_, ok = b.reserve(now.Add(10*time.Second), 5*time.Second)
+	require.False(t, ok)
+	wait, ok = b.reserve(now.Add(20*time.Second), time.Second)
+	require.True(t, ok)
+	require.Equal(t, time.Duration(0), wait)
+}
+
+func TestBucketShared(t *testing.T) {
+	a := getBucket("bucket-shared", 60, 1)
+	b := getBucket("bucket-shared", 30, 1)
+	require.True(t, a == b)
+	require.Equal(t, 0.5, b.rate)
+}
+
+func TestRateLimitEveryCommand(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand(false)
+
+	// The asset tag, management controller id and power supply queries take
+	// the burst, leaving no token for the power reading.
+	i := &Ipmi{
+		Path:           os.Args[0],
+		Servers:        []string{"USERID:PASSW0RD@lan(192.168.1.42)"},
+		Timeout:        internal.Duration{Duration: time.Second * 5},
+		PSUReadings:    true,
+		IdentityTags:   true,
+		RateLimit:      1,
+		RateLimitBurst: 3,
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "rate limit of 1 queries per minute exceeded for 192.168.1.42")
+	require.True(t, acc.HasMeasurement("ipmi_power_supply"))
+	require.False(t, acc.HasMeasurement("ipmi_power"))
+}
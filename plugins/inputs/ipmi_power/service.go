@@ -0,0 +1,74 @@
+package ipmi_power
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Start launches one poller per server when running in service mode, each
+// querying its BMC every poll_interval independent of the agent's gather
+// cycle.  Without service mode it does nothing and Gather does the work.
+func (m *Ipmi) Start(acc telegraf.Accumulator) error {
+	if !m.ServiceMode {
+		return nil
+	}
+
+	interval := m.PollInterval.Duration
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+
+	servers := m.Servers
+	if len(servers) == 0 {
+		servers = []string{""}
+	}
+	for i, server := range servers {
+		// Spread the first queries over the interval so the BMCs are not
+		// all polled at once.
+		offset := interval * time.Duration(i) / time.Duration(len(servers))
+		m.wg.Add(1)
+		go func(server string) {
+			defer m.wg.Done()
+			m.poll(ctx, acc, server, offset, interval)
+		}(server)
+	}
+	return nil
+}
+
+// Stop cancels the pollers and waits for in-flight queries to finish.
+func (m *Ipmi) Stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	m.wg.Wait()
+	m.cancel = nil
+}
+
+func (m *Ipmi) poll(ctx context.Context, acc telegraf.Accumulator, server string, offset, interval time.Duration) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(offset):
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.loadMaintenance(acc)
+		if err := m.gatherServer(acc, server); err != nil {
+			acc.AddError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
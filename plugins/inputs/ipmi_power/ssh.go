@@ -0,0 +1,97 @@
+package ipmi_power
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+)
+
+// initSSH locates the ssh client used to run ipmitool on the jump host.
+func (m *Ipmi) initSSH() error {
+	if len(m.Servers) == 0 {
+		return fmt.Errorf("ssh_host requires servers to be set")
+	}
+
+	path, err := exec.LookPath("ssh")
+	if err != nil {
+		return fmt.Errorf("ssh not found: verify that the ssh client is installed and in your PATH: %v", err)
+	}
+	m.sshPath = path
+
+	// ipmitool is looked up on the jump host
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	return nil
+}
+
+// sshCommand returns the ssh command running ipmitool on the jump host.  The
+// password is written to its stdin and read by ipmitool from /dev/stdin, the
+// remote command line is visible to every user of the jump host.
+func (m *Ipmi) sshCommand(opts []string) *exec.Cmd {
+	opts, password, ok := stdinPassword(opts)
+	name := m.Path
+	if m.UseSudo {
+		name, opts = ipmi.Escalate(m.SudoCommand, name, opts)
+	}
+	cmd := execCommand(m.sshPath, m.sshArgs(append([]string{name}, opts...))...)
+	if ok {
+		cmd.Stdin = strings.NewReader(password + "\n")
+	}
+	return cmd
+}
+
+// stdinPassword replaces the password passed to ipmitool with -P by the
+// password file /dev/stdin and returns the password.
+func stdinPassword(opts []string) ([]string, string, bool) {
+	for i, opt := range opts {
+		if opt == "-P" && i+1 < len(opts) {
+			replaced := make([]string, 0, len(opts))
+			replaced = append(replaced, opts[:i]...)
+			replaced = append(replaced, "-f", "/dev/stdin")
+			replaced = append(replaced, opts[i+2:]...)
+			return replaced, opts[i+1], true
+		}
+	}
+	return opts, "", false
+}
+
+// sshArgs returns the arguments of the ssh client running the command on
+// the jump host.
+func (m *Ipmi) sshArgs(command []string) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if m.Timeout.Duration > 0 {
+		timeout := int(m.Timeout.Duration.Seconds())
+		if timeout < 1 {
+			timeout = 1
+		}
+		args = append(args, "-o", "ConnectTimeout="+strconv.Itoa(timeout))
+	}
+	if m.SSHKnownHostsFile != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+m.SSHKnownHostsFile)
+	}
+	if m.SSHKeyFile != "" {
+		args = append(args, "-i", m.SSHKeyFile)
+	}
+	host, port := ipmi.SplitHostPort(m.SSHHost)
+	if port != 0 {
+		args = append(args, "-p", strconv.Itoa(port))
+	}
+	if m.SSHUser != "" {
+		args = append(args, "-l", m.SSHUser)
+	}
+
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return append(args, host, "--", strings.Join(quoted, " "))
+}
+
+// shellQuote quotes the argument for the remote shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
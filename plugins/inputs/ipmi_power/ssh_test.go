@@ -0,0 +1,85 @@
+package ipmi_power
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHRequiresServers(t *testing.T) {
+	i := &Ipmi{SSHHost: "bastion"}
+	require.Error(t, i.Init())
+}
+
+func TestSSHCommand(t *testing.T) {
+	i := &Ipmi{
+		Path:              "/usr/bin/ipmitool",
+		UseSudo:           true,
+		Timeout:           internal.Duration{Duration: time.Second * 20},
+		SSHHost:           "bastion.example.com:2222",
+		SSHUser:           "telegraf",
+		SSHKeyFile:        "/etc/telegraf/id_ed25519",
+		SSHKnownHostsFile: "/etc/telegraf/known_hosts",
+		sshPath:           "/usr/bin/ssh",
+	}
+
+	cmd := i.command("-H", "10.0.0.1", "-P", "it's secret", "dcmi", "power", "reading")
+	require.Equal(t, []string{
+		"/usr/bin/ssh",
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout=20",
+		"-o", "UserKnownHostsFile=/etc/telegraf/known_hosts",
+		"-i", "/etc/telegraf/id_ed25519",
+		"-p", "2222",
+		"-l", "telegraf",
+		"bastion.example.com",
+		"--",
+		`'sudo' '-n' '/usr/bin/ipmitool' '-H' '10.0.0.1' '-f' '/dev/stdin' 'dcmi' 'power' 'reading'`,
+	}, cmd.Args)
+
+	// The password is passed on stdin, out of the remote command line
+	stdin, err := ioutil.ReadAll(cmd.Stdin)
+	require.NoError(t, err)
+	require.Equal(t, "it's secret\n", string(stdin))
+}
+
+func TestSSHCommandWithoutPassword(t *testing.T) {
+	i := &Ipmi{
+		Path:    "ipmitool",
+		SSHHost: "bastion",
+		sshPath: "ssh",
+	}
+
+	cmd := i.command("-I", "open", "dcmi", "power", "reading")
+	require.Equal(t, []string{
+		"ssh",
+		"-o", "BatchMode=yes",
+		"bastion",
+		"--",
+		`'ipmitool' '-I' 'open' 'dcmi' 'power' 'reading'`,
+	}, cmd.Args)
+	require.Nil(t, cmd.Stdin)
+}
+
+func TestSSHGather(t *testing.T) {
+	execCommand = fakeExecCommand(false)
+	defer func() { execCommand = exec.Command }()
+
+	i := &Ipmi{
+		Path:    "ipmitool",
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		SSHHost: "bastion",
+		sshPath: "ssh",
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.True(t, acc.HasField("ipmi_power", "instantaneous_power_reading"))
+}
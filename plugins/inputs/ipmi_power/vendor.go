@@ -0,0 +1,85 @@
+package ipmi_power
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// autoProfile selects the OEM profile from the manufacturer of the BMC.
+const autoProfile = "auto"
+
+// capabilityManufacturer is the IANA enterprise number of the manufacturer
+// of the BMC reported by "ipmitool mc info".
+const capabilityManufacturer = "manufacturer_id"
+
+// vendorProfiles maps the IANA enterprise numbers of BMC manufacturers to
+// their OEM profile.  Other manufacturers are only queried with DCMI.
+var vendorProfiles = map[string]string{
+	"10876": "supermicro",
+	"674":   "dell",
+	"11":    "hpe",
+	"47196": "hpe",
+}
+
+// detectProfile returns the OEM profile of the manufacturer of the BMC, read
+// with "ipmitool mc info" once and kept in the device inventory.  The hpe
+// profile is only selected if the sensor of the power meter is known.
+func (m *Ipmi) detectProfile(hostname string, opts []string, sensor string) (string, error) {
+	manufacturer, err := m.manufacturer(hostname, opts)
+	if err != nil {
+		return "", err
+	}
+	profile := vendorProfiles[manufacturer]
+	if profile == "hpe" && sensor == "" {
+		m.Log.Debugf("Not using the hpe profile for %s, oem_sensor is not set", hostname)
+		profile = ""
+	}
+	if m.DryRun {
+		m.Log.Infof("Detected manufacturer %s, OEM profile %q", manufacturer, profile)
+	}
+	return profile, nil
+}
+
+// manufacturer returns the manufacturer id of the BMC.  The local BMC is
+// kept in the plugin, remote ones in the device inventory.
+func (m *Ipmi) manufacturer(hostname string, opts []string) (string, error) {
+	if hostname == "" && m.localManufacturer != "" {
+		return m.localManufacturer, nil
+	}
+	if hostname != "" {
+		if id := lookupCapability(hostname, capabilityManufacturer); id != "" {
+			return id, nil
+		}
+	}
+
+	args := append(append([]string{}, opts...), "mc", "info")
+	_, out, err := m.run(hostname, args)
+	if err != nil {
+		return "", err
+	}
+	id := parseManufacturer(out)
+	if id == "" {
+		return "", newBMCError(hostname, errorParseError, "no manufacturer id found in output: %s", string(out))
+	}
+
+	if hostname == "" {
+		m.localManufacturer = id
+	} else {
+		recordCapability(hostname, capabilityManufacturer, id)
+	}
+	return id, nil
+}
+
+// parseManufacturer returns the manufacturer id printed by "ipmitool mc
+// info", such as "Manufacturer ID : 10876".
+func parseManufacturer(out []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "Manufacturer ID" {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
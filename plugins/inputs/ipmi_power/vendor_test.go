@@ -0,0 +1,104 @@
+package ipmi_power
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/inventory"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// mcInfoOutput is the output of "ipmitool mc info" with the manufacturer
+// id left to fill in.
+const mcInfoOutput = `Device ID                 : 32
+Device Revision           : 1
+Firmware Revision         : 2.61
+IPMI Version              : 2.0
+Manufacturer ID           : %s
+Manufacturer Name         : Unknown
+Product ID                : 6929 (0x1b11)
+Product Name              : Unknown (0x1B11)
+Device Available          : yes
+`
+
+func TestParseManufacturer(t *testing.T) {
+	require.Equal(t, "674", parseManufacturer([]byte("Device ID : 32\nManufacturer ID           : 674\n")))
+	require.Equal(t, "", parseManufacturer([]byte("Could not open device\n")))
+}
+
+// fakeVendorExecCommand returns a mock of the exec.Command call of a BMC of
+// the manufacturer, optionally not supporting DCMI.
+func fakeVendorExecCommand(manufacturer string, noDCMI bool) func(string, ...string) *exec.Cmd {
+	return func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(false)(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_MANUFACTURER="+manufacturer)
+		if noDCMI {
+			cmd.Env = append(cmd.Env, "FAKE_IPMI_NO_DCMI=1")
+		}
+		return cmd
+	}
+}
+
+func TestAutoProfile(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	defer inventory.Forget(inventoryKind, "192.168.13.1")
+	execCommand = fakeVendorExecCommand("674", true)
+
+	i := &Ipmi{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.13.1)?oem_profile=auto"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "ipmi_power",
+		map[string]interface{}{
+			"instantaneous_power_reading":      312.0,
+			"instantaneous_power_reading_unit": "Watts",
+		},
+		map[string]string{"server": "192.168.13.1", oemProfileTag: "dell"})
+	require.Equal(t, "674", lookupCapability("192.168.13.1", capabilityManufacturer))
+
+	// The manufacturer is kept in the inventory and not read again
+	execCommand = fakeVendorExecCommand("10876", true)
+	acc.ClearMetrics()
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "ipmi_power",
+		map[string]interface{}{
+			"instantaneous_power_reading":      312.0,
+			"instantaneous_power_reading_unit": "Watts",
+		},
+		map[string]string{"server": "192.168.13.1", oemProfileTag: "dell"})
+}
+
+func TestAutoProfileHPEWithoutSensor(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	defer inventory.Forget(inventoryKind, "192.168.13.2")
+	execCommand = fakeVendorExecCommand("47196", false)
+
+	i := &Ipmi{
+		Path:          os.Args[0],
+		Servers:       []string{"USERID:PASSW0RD@lan(192.168.13.2)"},
+		Timeout:       internal.Duration{Duration: time.Second * 5},
+		OEMProfile:    autoProfile,
+		FieldsInclude: []string{"instantaneous_power_reading"},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "ipmi_power",
+		map[string]interface{}{"instantaneous_power_reading": 220.0},
+		map[string]string{"server": "192.168.13.2"})
+}
@@ -0,0 +1,426 @@
+package ipmi_raw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// IpmiRaw stores the configuration values for the ipmi_raw input plugin
+type IpmiRaw struct {
+	Path        string            `toml:"path"`
+	UseSudo     bool              `toml:"use_sudo"`
+	SudoCommand []string          `toml:"sudo_command"`
+	Privilege   string            `toml:"privilege"`
+	Servers     []string          `toml:"servers"`
+	Interface   string            `toml:"interface"`
+	Timeout     internal.Duration `toml:"timeout"`
+	Commands    []*Command        `toml:"command"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// Command is a raw request and the layout of its response.
+type Command struct {
+	Name           string            `toml:"name"`
+	Request        []string          `toml:"request"`
+	BridgeChannel  string            `toml:"bridge_channel"`
+	TargetAddress  string            `toml:"target_address"`
+	ResponsePrefix []string          `toml:"response_prefix"`
+	Tags           map[string]string `toml:"tags"`
+	Fields         []*Field          `toml:"field"`
+
+	prefix []byte
+}
+
+// Field is a value decoded from the response bytes.
+type Field struct {
+	Name      string  `toml:"name"`
+	Offset    int     `toml:"offset"`
+	Width     int     `toml:"width"`
+	ByteOrder string  `toml:"byte_order"`
+	DataType  string  `toml:"data_type"`
+	Mask      uint64  `toml:"mask"`
+	Scale     float64 `toml:"scale"`
+	Bias      float64 `toml:"bias"`
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## optionally specify one or more servers via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, the local BMC will be queried
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## Timeout for each ipmitool command to complete
+  timeout = "20s"
+
+  ## Raw commands, each adds an ipmi_raw metric tagged with its name.
+  # [[inputs.ipmi_raw.command]]
+  #   name = "psu1"
+  #   ## Request bytes passed to "ipmitool raw", starting with the network
+  #   ## function and command.  This one reads the input power of the first
+  #   ## power supply of Supermicro boards over PMBus.
+  #   request = ["0x06", "0x52", "0x07", "0x78", "0x02", "0x97"]
+  #
+  #   ## Bridge the request to a satellite controller such as the Intel ME.
+  #   # bridge_channel = "0x06"
+  #   # target_address = "0x2c"
+  #
+  #   ## Bytes the response must start with, e.g. the IANA number of OEM
+  #   ## commands.  They are stripped before the fields are decoded.
+  #   # response_prefix = []
+  #
+  #   ## Additional tags of the metric.
+  #   # [inputs.ipmi_raw.command.tags]
+  #   #   psu = "1"
+  #
+  #   [[inputs.ipmi_raw.command.field]]
+  #     name = "input_watts"
+  #     ## Position and number of the bytes of the value in the response,
+  #     ## the completion code is not part of it.
+  #     offset = 0
+  #     width = 2
+  #     ## Byte order, IPMI uses little endian.
+  #     # byte_order = "little"
+  #     ## uint, int, float (IEEE 754, width 4 or 8), bool or linear11 (the
+  #     ## PMBus format, width 2).
+  #     data_type = "linear11"
+  #     ## Bits of the value used by uint and bool fields, shifted down to
+  #     ## the lowest set bit, 0 uses all bits.
+  #     # mask = 0
+  #     ## Numeric values are converted to float with value * scale + bias
+  #     ## if either is set.
+  #     # scale = 1.0
+  #     # bias = 0.0
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *IpmiRaw) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *IpmiRaw) Description() string {
+	return "Read vendor specific sensors with raw IPMI commands and decode their responses"
+}
+
+// Init locates ipmitool and checks the commands.
+func (m *IpmiRaw) Init() error {
+	if len(m.Commands) == 0 {
+		return fmt.Errorf("no commands configured")
+	}
+	for _, c := range m.Commands {
+		if err := c.init(); err != nil {
+			return fmt.Errorf("command %q: %v", c.Name, err)
+		}
+	}
+
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+	return nil
+}
+
+func (c *Command) init() error {
+	if c.Name == "" {
+		return fmt.Errorf("no name")
+	}
+	if len(c.Request) < 2 {
+		return fmt.Errorf("request must hold at least the network function and command")
+	}
+	for _, b := range c.Request {
+		if _, err := parseByte(b); err != nil {
+			return fmt.Errorf("invalid request byte %q", b)
+		}
+	}
+	if (c.BridgeChannel == "") != (c.TargetAddress == "") {
+		return fmt.Errorf("bridge_channel and target_address must be set together")
+	}
+	c.prefix = nil
+	for _, s := range c.ResponsePrefix {
+		b, err := parseByte(s)
+		if err != nil {
+			return fmt.Errorf("invalid response_prefix byte %q", s)
+		}
+		c.prefix = append(c.prefix, b)
+	}
+	if len(c.Fields) == 0 {
+		return fmt.Errorf("no fields")
+	}
+	for _, f := range c.Fields {
+		if err := f.init(); err != nil {
+			return fmt.Errorf("field %q: %v", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func (f *Field) init() error {
+	if f.Name == "" {
+		return fmt.Errorf("no name")
+	}
+	if f.Offset < 0 {
+		return fmt.Errorf("negative offset")
+	}
+	if f.Width == 0 {
+		f.Width = 1
+	}
+	if f.DataType == "" {
+		f.DataType = "uint"
+	}
+	switch f.ByteOrder {
+	case "":
+		f.ByteOrder = "little"
+	case "little", "big":
+	default:
+		return fmt.Errorf("invalid byte_order %q, expected little or big", f.ByteOrder)
+	}
+
+	switch f.DataType {
+	case "uint", "int", "bool":
+		if f.Width < 1 || f.Width > 8 {
+			return fmt.Errorf("invalid width %d, expected 1 to 8", f.Width)
+		}
+	case "float":
+		if f.Width != 4 && f.Width != 8 {
+			return fmt.Errorf("invalid width %d of float, expected 4 or 8", f.Width)
+		}
+	case "linear11":
+		if f.Width != 2 {
+			return fmt.Errorf("invalid width %d of linear11, expected 2", f.Width)
+		}
+	default:
+		return fmt.Errorf("invalid data_type %q, expected uint, int, float, bool or linear11", f.DataType)
+	}
+	if f.Mask != 0 && f.DataType != "uint" && f.DataType != "bool" {
+		return fmt.Errorf("mask requires a uint or bool data_type")
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *IpmiRaw) Gather(acc telegraf.Accumulator) error {
+	if len(m.Servers) == 0 {
+		m.gatherServer(acc, "")
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			m.gatherServer(acc, s)
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+// gatherServer runs every command, the errors are added per command as
+// BMCs may lack some of them.
+func (m *IpmiRaw) gatherServer(acc telegraf.Accumulator, server string) {
+	var opts []string
+	hostname := ""
+	if server != "" {
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			acc.AddError(err)
+			return
+		}
+		hostname = conn.Hostname
+		opts = conn.Options()
+	}
+
+	for _, c := range m.Commands {
+		args := append([]string{}, opts...)
+		if c.BridgeChannel != "" {
+			args = append(args, "-b", c.BridgeChannel, "-t", c.TargetAddress)
+		}
+		args = append(append(args, "raw"), c.Request...)
+
+		out, err := m.run(args...)
+		timestamp := time.Now()
+		if err != nil {
+			acc.AddError(fmt.Errorf("command %s: %v", c.Name, err))
+			continue
+		}
+		fields, err := c.decode(out)
+		if err != nil {
+			acc.AddError(fmt.Errorf("command %s of %s: %v", c.Name, hostname, err))
+			continue
+		}
+
+		tags := map[string]string{"command": c.Name}
+		for k, v := range c.Tags {
+			tags[k] = v
+		}
+		if hostname != "" {
+			tags["server"] = hostname
+		}
+		acc.AddFields("ipmi_raw", fields, tags, timestamp)
+	}
+}
+
+// run runs ipmitool with the arguments.
+func (m *IpmiRaw) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        m.Path,
+		UseSudo:     m.UseSudo,
+		SudoCommand: m.SudoCommand,
+		Timeout:     m.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// decode checks and strips the response prefix and decodes the fields.
+func (c *Command) decode(out []byte) (map[string]interface{}, error) {
+	data, err := ipmi.ParseRawResponse(out)
+	if err != nil {
+		return nil, fmt.Errorf("%v in output: %s", err, string(out))
+	}
+	if len(data) < len(c.prefix) || string(data[:len(c.prefix)]) != string(c.prefix) {
+		return nil, fmt.Errorf("response does not start with % x: % x", c.prefix, data)
+	}
+	data = data[len(c.prefix):]
+
+	fields := make(map[string]interface{}, len(c.Fields))
+	for _, f := range c.Fields {
+		v, err := f.decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", f.Name, err)
+		}
+		fields[f.Name] = v
+	}
+	return fields, nil
+}
+
+// decode decodes the value of the field from the response bytes.
+func (f *Field) decode(data []byte) (interface{}, error) {
+	if f.Offset+f.Width > len(data) {
+		return nil, fmt.Errorf("short response % x, expected %d bytes", data, f.Offset+f.Width)
+	}
+	raw := make([]byte, f.Width)
+	copy(raw, data[f.Offset:f.Offset+f.Width])
+	if f.ByteOrder == "big" {
+		for i, j := 0, len(raw)-1; i < j; i, j = i+1, j-1 {
+			raw[i], raw[j] = raw[j], raw[i]
+		}
+	}
+	// raw is little endian from here on
+	var u uint64
+	for i := len(raw) - 1; i >= 0; i-- {
+		u = u<<8 | uint64(raw[i])
+	}
+
+	switch f.DataType {
+	case "bool":
+		if f.Mask != 0 {
+			u &= f.Mask
+		}
+		return u != 0, nil
+	case "uint":
+		if f.Mask != 0 {
+			u = (u & f.Mask) >> uint(bits.TrailingZeros64(f.Mask))
+		}
+		return f.scale(float64(u), u), nil
+	case "int":
+		shift := uint(64 - 8*f.Width)
+		i := int64(u<<shift) >> shift
+		return f.scale(float64(i), i), nil
+	case "float":
+		if f.Width == 4 {
+			return f.scale(float64(math.Float32frombits(binary.LittleEndian.Uint32(raw))), nil), nil
+		}
+		return f.scale(math.Float64frombits(binary.LittleEndian.Uint64(raw)), nil), nil
+	default: // linear11
+		return f.scale(ipmi.Linear11(uint16(u)), nil), nil
+	}
+}
+
+// scale returns value * scale + bias if either is set, the integer value
+// otherwise.  Floating point values are always returned as float.
+func (f *Field) scale(value float64, integer interface{}) interface{} {
+	if f.Scale == 0 && f.Bias == 0 {
+		if integer != nil {
+			return integer
+		}
+		return value
+	}
+	scale := f.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return value*scale + f.Bias
+}
+
+// parseByte parses a request byte as ipmitool does, in hex with 0x prefix
+// or decimal.
+func parseByte(s string) (byte, error) {
+	v, err := strconv.ParseUint(s, 0, 8)
+	return byte(v), err
+}
+
+func init() {
+	inputs.Add("ipmi_raw", func() telegraf.Input {
+		return &IpmiRaw{
+			Timeout: internal.Duration{Duration: time.Second * 20},
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package ipmi_raw
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiRaw{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Commands: []*Command{
+			{
+				Name:    "psu1",
+				Request: []string{"0x06", "0x52", "0x07", "0x78", "0x02", "0x97"},
+				Tags:    map[string]string{"psu": "1"},
+				Fields: []*Field{
+					{Name: "input_watts", Width: 2, DataType: "linear11"},
+				},
+			},
+			{
+				Name:           "node_manager",
+				Request:        []string{"0x2e", "0xc8", "0x57", "0x01", "0x00", "0x01", "0x00", "0x00"},
+				BridgeChannel:  "0x06",
+				TargetAddress:  "0x2c",
+				ResponsePrefix: []string{"0x57", "0x01", "0x00"},
+				Fields: []*Field{
+					{Name: "power_watts", Offset: 0, Width: 2},
+					{Name: "power_half", Offset: 0, Width: 2, Scale: 0.5},
+					{Name: "active", Offset: 16, DataType: "bool", Mask: 0x80},
+					{Name: "state", Offset: 16, Mask: 0x60},
+				},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_raw",
+		map[string]interface{}{"input_watts": 184.0},
+		map[string]string{"server": "192.168.1.1", "command": "psu1", "psu": "1"})
+	acc.AssertContainsTaggedFields(t, "ipmi_raw",
+		map[string]interface{}{
+			"power_watts": uint64(412),
+			"power_half":  206.0,
+			"active":      true,
+			"state":       uint64(2),
+		},
+		map[string]string{"server": "192.168.1.1", "command": "node_manager"})
+}
+
+func TestGatherCommandError(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiRaw{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Commands: []*Command{
+			{
+				Name:    "unsupported",
+				Request: []string{"0x30", "0xff"},
+				Fields:  []*Field{{Name: "value"}},
+			},
+			{
+				Name:    "short",
+				Request: []string{"0x06", "0x52", "0x07", "0x78", "0x02", "0x97"},
+				Fields:  []*Field{{Name: "value", Offset: 1, Width: 4}},
+			},
+			{
+				Name:    "psu1",
+				Request: []string{"0x06", "0x52", "0x07", "0x78", "0x02", "0x97"},
+				Fields:  []*Field{{Name: "raw", Width: 2, ByteOrder: "big"}},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 2)
+	for _, err := range acc.Errors {
+		require.NotContains(t, err.Error(), "PASSW0RD")
+	}
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "ipmi_raw",
+		map[string]interface{}{"raw": uint64(0xe0f2)},
+		map[string]string{"server": "192.168.1.1", "command": "psu1"})
+}
+
+func TestInitInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *Command
+	}{
+		{"no request", &Command{Name: "a", Request: []string{"0x06"}, Fields: []*Field{{Name: "v"}}}},
+		{"invalid byte", &Command{Name: "a", Request: []string{"0x06", "0x100"}, Fields: []*Field{{Name: "v"}}}},
+		{"no fields", &Command{Name: "a", Request: []string{"0x06", "0x01"}}},
+		{"bridge", &Command{Name: "a", Request: []string{"0x06", "0x01"}, BridgeChannel: "6", Fields: []*Field{{Name: "v"}}}},
+		{"width", &Command{Name: "a", Request: []string{"0x06", "0x01"}, Fields: []*Field{{Name: "v", Width: 9}}}},
+		{"float width", &Command{Name: "a", Request: []string{"0x06", "0x01"}, Fields: []*Field{{Name: "v", Width: 2, DataType: "float"}}}},
+		{"mask", &Command{Name: "a", Request: []string{"0x06", "0x01"}, Fields: []*Field{{Name: "v", DataType: "int", Mask: 1}}}},
+		{"byte order", &Command{Name: "a", Request: []string{"0x06", "0x01"}, Fields: []*Field{{Name: "v", ByteOrder: "middle"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &IpmiRaw{Path: os.Args[0], Commands: []*Command{tt.command}}
+			require.Error(t, i.Init())
+		})
+	}
+}
+
+func TestFieldDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    Field
+		data     []byte
+		expected interface{}
+	}{
+		{"uint little", Field{Width: 2}, []byte{0x34, 0x12}, uint64(0x1234)},
+		{"uint big", Field{Width: 2, ByteOrder: "big"}, []byte{0x12, 0x34}, uint64(0x1234)},
+		{"uint three bytes", Field{Width: 3, Offset: 1}, []byte{0xff, 0x01, 0x02, 0x03}, uint64(0x030201)},
+		{"int negative", Field{Width: 2, DataType: "int"}, []byte{0xfe, 0xff}, int64(-2)},
+		{"int scaled", Field{Width: 1, DataType: "int", Scale: 0.5, Bias: 10}, []byte{0xfc}, 8.0},
+		{"float32", Field{Width: 4, DataType: "float"}, []byte{0x00, 0x00, 0x48, 0x42}, 50.0},
+		{"float64 big", Field{Width: 8, DataType: "float", ByteOrder: "big"}, []byte{0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}, 3.141592653589793},
+		{"bool", Field{DataType: "bool", Mask: 0x04}, []byte{0x03}, false},
+		{"linear11", Field{Width: 2, DataType: "linear11"}, []byte{0xe0, 0xf2}, 184.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.field
+			f.Name = "v"
+			require.NoError(t, f.init())
+			v, err := f.decode(tt.data)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the responses of the raw commands used in the tests.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	cmd := strings.Join(os.Args, " ")
+	switch {
+	case strings.HasSuffix(cmd, "raw 0x06 0x52 0x07 0x78 0x02 0x97"):
+		fmt.Fprint(os.Stdout, " e0 f2\n")
+	case strings.HasSuffix(cmd, "-b 0x06 -t 0x2c raw 0x2e 0xc8 0x57 0x01 0x00 0x01 0x00 0x00"):
+		fmt.Fprint(os.Stdout, " 57 01 00 9c 01 70 01 c0 01 80 01 c4 8b 6b 5f 88\n 0d 00 00 c0\n")
+	default:
+		fmt.Fprint(os.Stdout, "Unable to send RAW command (channel=0x0 netfn=0x30 lun=0x0 cmd=0xff rsp=0xc1): Invalid command")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,527 @@
+package ipmi_sel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// sensorTypes are the sensor types of the IPMI specification as printed by
+// ipmitool, the sensor column of "ipmitool sel elist" starts with one.
+var sensorTypes = []string{
+	"Temperature",
+	"Voltage",
+	"Current",
+	"Fan",
+	"Physical Security",
+	"Platform Security",
+	"Processor",
+	"Power Supply",
+	"Power Unit",
+	"Cooling Device",
+	"Other",
+	"Memory",
+	"Drive Slot / Bay",
+	"POST Memory Resize",
+	"System Firmwares",
+	"Event Logging Disabled",
+	"Watchdog1",
+	"Watchdog2",
+	"System Event",
+	"Critical Interrupt",
+	"Button",
+	"Module / Board",
+	"Microcontroller",
+	"Add-in Card",
+	"Chassis",
+	"Chip Set",
+	"Other FRU",
+	"Cable / Interconnect",
+	"Terminator",
+	"System Boot Initiated",
+	"Boot Error",
+	"OS Boot",
+	"OS Critical Stop",
+	"Slot / Connector",
+	"System ACPI Power State",
+	"Platform Alert",
+	"Entity Presence",
+	"Monitor ASIC",
+	"LAN",
+	"Management Subsys Health",
+	"Battery",
+	"Session Audit",
+	"Version Change",
+	"FRU State",
+}
+
+// categories group the sensor types for routing events, e.g. to the team
+// handling cooling or power issues.  Unlisted sensor types are "other".
+var categories = map[string]string{
+	"Temperature":              "thermal",
+	"Fan":                      "cooling",
+	"Cooling Device":           "cooling",
+	"Voltage":                  "power",
+	"Current":                  "power",
+	"Power Supply":             "power",
+	"Power Unit":               "power",
+	"Battery":                  "power",
+	"System ACPI Power State":  "power",
+	"Processor":                "processor",
+	"Critical Interrupt":       "processor",
+	"Chip Set":                 "processor",
+	"Memory":                   "memory",
+	"POST Memory Resize":       "memory",
+	"Drive Slot / Bay":         "storage",
+	"Physical Security":        "security",
+	"Platform Security":        "security",
+	"Session Audit":            "security",
+	"System Firmwares":         "firmware",
+	"Version Change":           "firmware",
+	"Watchdog1":                "os",
+	"Watchdog2":                "os",
+	"System Boot Initiated":    "os",
+	"Boot Error":               "os",
+	"OS Boot":                  "os",
+	"OS Critical Stop":         "os",
+	"Event Logging Disabled":   "bmc",
+	"Microcontroller":          "bmc",
+	"Management Subsys Health": "bmc",
+	"LAN":                      "network",
+	"Cable / Interconnect":     "network",
+	"Add-in Card":              "hardware",
+	"Module / Board":           "hardware",
+	"Slot / Connector":         "hardware",
+	"Chassis":                  "hardware",
+	"Entity Presence":          "hardware",
+	"Other FRU":                "hardware",
+	"FRU State":                "hardware",
+}
+
+// severities classify the description of asserted events, the first
+// matching keyword wins.
+var severities = []struct {
+	keyword  string
+	severity string
+}{
+	{"uncorrectable", "critical"},
+	{"non-recoverable", "critical"},
+	{"failure", "critical"},
+	{"fault", "critical"},
+	{"ac lost", "critical"},
+	{"power off", "critical"},
+	{"non-critical", "warning"},
+	{"critical", "critical"},
+	{"correctable", "warning"},
+	{"predictive", "warning"},
+	{"degraded", "warning"},
+	{"limit reached", "warning"},
+	{"throttled", "warning"},
+}
+
+var severityCodes = map[string]int64{
+	"info":     0,
+	"warning":  1,
+	"critical": 2,
+}
+
+// IpmiSel stores the configuration values for the ipmi_sel input plugin
+type IpmiSel struct {
+	Path          string            `toml:"path"`
+	UseSudo       bool              `toml:"use_sudo"`
+	SudoCommand   []string          `toml:"sudo_command"`
+	Privilege     string            `toml:"privilege"`
+	Servers       []string          `toml:"servers"`
+	Interface     string            `toml:"interface"`
+	Timeout       internal.Duration `toml:"timeout"`
+	FromBeginning bool              `toml:"from_beginning"`
+	Timezone      string            `toml:"timezone"`
+
+	SeverityOverrides map[string]string `toml:"severity_overrides"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	location *time.Location
+
+	mu sync.Mutex
+	// seen holds the entries of the last listing of each server by record
+	// ID, an entry is new if its record ID is unseen or was reused after
+	// the log was cleared.
+	seen map[string]map[string]string
+}
+
+// event is an entry of the System Event Log.
+type event struct {
+	recordID    string
+	timestamp   time.Time
+	sensorType  string
+	sensor      string
+	description string
+	direction   string
+	details     string
+	line        string
+
+	// assertedAt is the time of the assertion a deasserted event clears,
+	// zero if unknown.
+	assertedAt time.Time
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## optionally specify one or more servers via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, the local event log will be queried
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## Recommended: use metric 'interval' that is a multiple of 'timeout' to avoid
+  ## gaps or overlap in pulled data
+  interval = "60s"
+
+  ## Timeout for each ipmitool command to complete
+  timeout = "20s"
+
+  ## Emit the events already in the log when telegraf starts, by default
+  ## only events logged afterwards are emitted.
+  # from_beginning = false
+
+  ## Time zone of the event timestamps printed by ipmitool, "Local" for the
+  ## system time zone.
+  # timezone = "Local"
+
+  ## Severity of asserted events whose description contains the keyword,
+  ## case insensitive, taking precedence over the built-in classification.
+  ## The longest matching keyword wins.
+  ## Severities are info, warning or critical.
+  # [inputs.ipmi_sel.severity_overrides]
+  #   "Correctable ECC logging limit reached" = "critical"
+  #   "Presence detected" = "info"
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *IpmiSel) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *IpmiSel) Description() string {
+	return "Emit the events of the System Event Log of bare metal servers via IPMI"
+}
+
+// Init locates ipmitool.
+func (m *IpmiSel) Init() error {
+	location, err := time.LoadLocation(m.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %v", err)
+	}
+	m.location = location
+
+	for keyword, severity := range m.SeverityOverrides {
+		if _, ok := severityCodes[severity]; !ok {
+			return fmt.Errorf("invalid severity %q of %q, must be info, warning or critical", severity, keyword)
+		}
+	}
+
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+	m.seen = make(map[string]map[string]string)
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *IpmiSel) Gather(acc telegraf.Accumulator) error {
+	if len(m.Servers) == 0 {
+		return m.gatherServer(acc, "")
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			if err := m.gatherServer(acc, s); err != nil {
+				acc.AddError(err)
+			}
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (m *IpmiSel) gatherServer(acc telegraf.Accumulator, server string) error {
+	var opts []string
+	hostname := ""
+	if server != "" {
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			return err
+		}
+		hostname = conn.Hostname
+		opts = conn.Options()
+	}
+
+	out, err := m.run(append(opts, "sel", "elist")...)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	events := parseEvents(out, m.location)
+	pairAssertions(events)
+
+	for _, e := range m.unseen(server, events) {
+		severity := m.classify(e)
+		tags := map[string]string{
+			"sensor_type": e.sensorType,
+			"category":    category(e.sensorType),
+			"description": e.description,
+			"severity":    severity,
+		}
+		if e.sensor != "" {
+			tags["sensor"] = e.sensor
+		}
+		if e.direction != "" {
+			tags["direction"] = strings.ToLower(e.direction)
+		}
+		if hostname != "" {
+			tags["server"] = hostname
+		}
+
+		fields := map[string]interface{}{
+			"severity_code": severityCodes[severity],
+		}
+		if id, err := strconv.ParseInt(e.recordID, 16, 64); err == nil {
+			fields["record_id"] = id
+		}
+		if e.details != "" {
+			fields["details"] = e.details
+		}
+		switch strings.ToLower(e.direction) {
+		case "asserted":
+			fields["active"] = true
+		case "deasserted":
+			fields["active"] = false
+			fields["cleared_severity"] = m.conditionSeverity(e)
+			if !e.assertedAt.IsZero() && !e.timestamp.IsZero() {
+				fields["active_seconds"] = e.timestamp.Sub(e.assertedAt).Seconds()
+			}
+		}
+
+		timestamp := e.timestamp
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+		acc.AddFields("ipmi_sel", fields, tags, timestamp)
+	}
+	return nil
+}
+
+// unseen returns the events not seen in the last listing of the server and
+// remembers the listing.  All events of the first listing are unseen only if
+// from_beginning is set.
+func (m *IpmiSel) unseen(server string, events []*event) []*event {
+	listing := make(map[string]string, len(events))
+	for _, e := range events {
+		listing[e.recordID] = e.line
+	}
+
+	m.mu.Lock()
+	seen, ok := m.seen[server]
+	m.seen[server] = listing
+	m.mu.Unlock()
+
+	if !ok && !m.FromBeginning {
+		return nil
+	}
+
+	var unseen []*event
+	for _, e := range events {
+		if line, ok := seen[e.recordID]; !ok || line != e.line {
+			unseen = append(unseen, e)
+		}
+	}
+	return unseen
+}
+
+// run runs ipmitool with the arguments.
+func (m *IpmiSel) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        m.Path,
+		UseSudo:     m.UseSudo,
+		SudoCommand: m.SudoCommand,
+		Timeout:     m.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// parseEvents parses the entries listed by "ipmitool sel elist", with lines
+// like "2f | 12/16/2020 | 14:02:11 | Memory #0x87 | Correctable ECC | Asserted".
+// Entries logged before the BMC clock was set have no date and time.
+func parseEvents(out []byte, location *time.Location) []*event {
+	var events []*event
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		columns := strings.Split(line, "|")
+		if len(columns) < 5 {
+			continue
+		}
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+		}
+		if _, err := strconv.ParseUint(columns[0], 16, 64); err != nil {
+			continue
+		}
+
+		e := &event{recordID: columns[0], line: line}
+		if t, err := time.ParseInLocation("01/02/2006 15:04:05", columns[1]+" "+columns[2], location); err == nil {
+			e.timestamp = t
+		}
+		e.sensorType, e.sensor = splitSensor(columns[3])
+		e.description = columns[4]
+		if len(columns) > 5 {
+			e.direction = columns[5]
+		}
+		if len(columns) > 6 {
+			e.details = strings.Join(columns[6:], " | ")
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// splitSensor splits the sensor column into the sensor type and the name or
+// number of the sensor.
+func splitSensor(s string) (string, string) {
+	var sensorType string
+	for _, t := range sensorTypes {
+		if len(t) > len(sensorType) && (s == t || strings.HasPrefix(s, t+" ")) {
+			sensorType = t
+		}
+	}
+	if sensorType == "" {
+		return s, ""
+	}
+	return sensorType, strings.TrimSpace(s[len(sensorType):])
+}
+
+// pairAssertions sets the time of the assertion cleared by each deasserted
+// event of the listing.  Both are logged with the same sensor and
+// description.
+func pairAssertions(events []*event) {
+	asserted := make(map[string]time.Time)
+	for _, e := range events {
+		key := e.sensorType + "|" + e.sensor + "|" + e.description
+		switch strings.ToLower(e.direction) {
+		case "asserted":
+			asserted[key] = e.timestamp
+		case "deasserted":
+			if t, ok := asserted[key]; ok {
+				e.assertedAt = t
+				delete(asserted, key)
+			}
+		}
+	}
+}
+
+// category returns the category of the sensor type.
+func category(sensorType string) string {
+	if c, ok := categories[sensorType]; ok {
+		return c
+	}
+	return "other"
+}
+
+// classify returns the severity of the event, deasserted events clear a
+// condition and are informational.
+func (m *IpmiSel) classify(e *event) string {
+	if strings.EqualFold(e.direction, "Deasserted") {
+		return "info"
+	}
+	return m.conditionSeverity(e)
+}
+
+// conditionSeverity returns the severity of the condition reported by the
+// event regardless of its direction.
+func (m *IpmiSel) conditionSeverity(e *event) string {
+	description := strings.ToLower(e.description)
+	// The longest matching override wins
+	var override string
+	for keyword := range m.SeverityOverrides {
+		if len(keyword) > len(override) && strings.Contains(description, strings.ToLower(keyword)) {
+			override = keyword
+		}
+	}
+	if override != "" {
+		return m.SeverityOverrides[override]
+	}
+	for _, s := range severities {
+		if strings.Contains(description, s.keyword) {
+			return s.severity
+		}
+	}
+	return "info"
+}
+
+func init() {
+	inputs.Add("ipmi_sel", func() telegraf.Input {
+		return &IpmiSel{
+			Timeout:  internal.Duration{Duration: time.Second * 20},
+			Timezone: "Local",
+		}
+	})
+}
@@ -0,0 +1,298 @@
+package ipmi_sel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newIpmiSel(t *testing.T) *IpmiSel {
+	i := &IpmiSel{
+		Path:     os.Args[0],
+		Servers:  []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:  internal.Duration{Duration: time.Second * 5},
+		Timezone: "UTC",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+	return i
+}
+
+func TestGatherFromBeginning(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand("sel_elist.txt")
+
+	i := newIpmiSel(t)
+	i.FromBeginning = true
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 5)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_sel",
+		map[string]interface{}{
+			"record_id":     int64(2),
+			"severity_code": int64(2),
+			"active":        true,
+		},
+		map[string]string{
+			"server":      "192.168.1.1",
+			"sensor_type": "Power Supply",
+			"category":    "power",
+			"sensor":      "PS2 Status",
+			"description": "Power Supply AC lost",
+			"direction":   "asserted",
+			"severity":    "critical",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_sel",
+		map[string]interface{}{
+			"record_id":        int64(3),
+			"severity_code":    int64(0),
+			"active":           false,
+			"cleared_severity": "critical",
+			"active_seconds":   79.0,
+		},
+		map[string]string{
+			"server":      "192.168.1.1",
+			"sensor_type": "Power Supply",
+			"category":    "power",
+			"sensor":      "PS2 Status",
+			"description": "Power Supply AC lost",
+			"direction":   "deasserted",
+			"severity":    "info",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_sel",
+		map[string]interface{}{
+			"record_id":     int64(4),
+			"severity_code": int64(1),
+			"active":        true,
+			"details":       "DIMM A2",
+		},
+		map[string]string{
+			"server":      "192.168.1.1",
+			"sensor_type": "Memory",
+			"category":    "memory",
+			"sensor":      "#0x87",
+			"description": "Correctable ECC",
+			"direction":   "asserted",
+			"severity":    "warning",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_sel",
+		map[string]interface{}{
+			"record_id":     int64(5),
+			"severity_code": int64(1),
+			"active":        true,
+			"details":       "Reading 72 > Threshold 70 degrees C",
+		},
+		map[string]string{
+			"server":      "192.168.1.1",
+			"sensor_type": "Temperature",
+			"category":    "thermal",
+			"sensor":      "Exhaust Temp",
+			"description": "Upper Non-critical going high",
+			"direction":   "asserted",
+			"severity":    "warning",
+		})
+
+	for _, m := range acc.Metrics {
+		if m.Fields["record_id"] == int64(4) {
+			require.Equal(t, time.Date(2020, time.December, 16, 14, 2, 11, 0, time.UTC), m.Time.UTC())
+		}
+	}
+}
+
+func TestGatherNewEvents(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand("sel_elist.txt")
+
+	i := newIpmiSel(t)
+
+	// Events logged before the first listing are skipped
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Empty(t, acc.Metrics)
+
+	execCommand = fakeExecCommand("sel_elist_next.txt")
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "ipmi_sel",
+		map[string]interface{}{
+			"record_id":     int64(6),
+			"severity_code": int64(2),
+			"active":        true,
+			"details":       "DIMM A2",
+		},
+		map[string]string{
+			"server":      "192.168.1.1",
+			"sensor_type": "Memory",
+			"category":    "memory",
+			"sensor":      "#0x87",
+			"description": "Uncorrectable ECC",
+			"direction":   "asserted",
+			"severity":    "critical",
+		})
+
+	// Unchanged log
+	acc.ClearMetrics()
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Metrics)
+
+	// Record IDs are reused after the log was cleared
+	execCommand = fakeExecCommand("sel_elist_cleared.txt")
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "ipmi_sel",
+		map[string]interface{}{
+			"record_id":     int64(2),
+			"severity_code": int64(2),
+			"active":        true,
+			"details":       "Reading 600 < Threshold 800 RPM",
+		},
+		map[string]string{
+			"server":      "192.168.1.1",
+			"sensor_type": "Fan",
+			"category":    "cooling",
+			"sensor":      "FAN3",
+			"description": "Lower Critical going low",
+			"direction":   "asserted",
+			"severity":    "critical",
+		})
+}
+
+func TestGatherError(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand("sel_elist.txt")(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_FAILURE=Error: Unable to establish IPMI v2 / RMCP+ session")
+		return cmd
+	}
+
+	i := newIpmiSel(t)
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "Unable to establish")
+	require.NotContains(t, acc.Errors[0].Error(), "PASSW0RD")
+}
+
+func TestSeverityOverrides(t *testing.T) {
+	i := newIpmiSel(t)
+	i.SeverityOverrides = map[string]string{
+		"correctable ecc": "critical",
+		"ecc":             "info",
+		"Presence":        "info",
+	}
+	require.NoError(t, i.Init())
+
+	tests := []struct {
+		description string
+		direction   string
+		severity    string
+	}{
+		{"Correctable ECC", "Asserted", "critical"},
+		{"Uncorrectable ECC", "Asserted", "critical"},
+		{"Correctable ECC logging limit reached", "Asserted", "critical"},
+		{"Presence detected", "Asserted", "info"},
+		{"Power Supply AC lost", "Asserted", "critical"},
+		{"Correctable ECC", "Deasserted", "info"},
+	}
+	for _, tt := range tests {
+		e := &event{description: tt.description, direction: tt.direction}
+		require.Equal(t, tt.severity, i.classify(e), tt.description)
+	}
+	require.Equal(t, "critical", i.conditionSeverity(&event{description: "Correctable ECC", direction: "Deasserted"}))
+
+	i.SeverityOverrides = map[string]string{"Presence": "fatal"}
+	require.Error(t, i.Init())
+}
+
+func TestPairAssertions(t *testing.T) {
+	events := parseEvents([]byte(`   1 | 12/16/2020 | 13:58:42 | Power Supply PS2 Status | Power Supply AC lost | Asserted
+   2 | 12/16/2020 | 13:59:00 | Power Supply PS1 Status | Power Supply AC lost | Deasserted
+   3 | 12/16/2020 | 14:00:01 | Power Supply PS2 Status | Power Supply AC lost | Deasserted
+   4 | 12/16/2020 | 14:01:00 | Power Supply PS2 Status | Power Supply AC lost | Deasserted
+`), time.UTC)
+	pairAssertions(events)
+	require.True(t, events[1].assertedAt.IsZero())
+	require.Equal(t, events[0].timestamp, events[2].assertedAt)
+	require.True(t, events[3].assertedAt.IsZero())
+}
+
+func TestCategory(t *testing.T) {
+	require.Equal(t, "thermal", category("Temperature"))
+	require.Equal(t, "power", category("Power Supply"))
+	require.Equal(t, "other", category("OEM record c0"))
+	for _, sensorType := range sensorTypes {
+		require.NotEmpty(t, category(sensorType))
+	}
+}
+
+func TestSplitSensor(t *testing.T) {
+	tests := []struct {
+		column     string
+		sensorType string
+		sensor     string
+	}{
+		{"Power Supply PS1 Status", "Power Supply", "PS1 Status"},
+		{"Power Unit #0x01", "Power Unit", "#0x01"},
+		{"Other FRU #0x12", "Other FRU", "#0x12"},
+		{"Drive Slot / Bay Drive 3", "Drive Slot / Bay", "Drive 3"},
+		{"Memory", "Memory", ""},
+		{"OEM record c0", "OEM record c0", ""},
+	}
+	for _, tt := range tests {
+		sensorType, sensor := splitSensor(tt.column)
+		require.Equal(t, tt.sensorType, sensorType, tt.column)
+		require.Equal(t, tt.sensor, sensor, tt.column)
+	}
+}
+
+// fakeExecCommand returns a helper function that mock the exec.Command call
+// (and call the test binary) printing the capture in testdata.
+func fakeExecCommand(capture string) func(command string, args ...string) *exec.Cmd {
+	return func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "FAKE_SEL_CAPTURE=" + capture}
+		return cmd
+	}
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the capture of "sel elist" given by FAKE_SEL_CAPTURE.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	if failure, ok := os.LookupEnv("FAKE_IPMI_FAILURE"); ok {
+		fmt.Fprint(os.Stdout, failure)
+		os.Exit(1)
+	}
+
+	args := os.Args
+	if len(args) < 2 || args[len(args)-2] != "sel" || args[len(args)-1] != "elist" {
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", os.Getenv("FAKE_SEL_CAPTURE")))
+	if err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
@@ -0,0 +1,100 @@
+package ipmi_sensors
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// unsafeChars are the characters replaced in the names of the cache files.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+// sdrCache returns the SDR cache file of the BMC, dumping the SDR repository
+// with "ipmitool sdr dump" if there is no cache of its current firmware.
+// Caches of other firmware revisions of the BMC are removed.
+func (m *IpmiSensors) sdrCache(hostname string, opts []string) (string, error) {
+	out, err := m.run(append(opts, "mc", "info")...)
+	if err != nil {
+		return "", err
+	}
+	firmware := parseFirmware(out)
+	if firmware == "" {
+		return "", fmt.Errorf("no firmware revision found in output: %s", string(out))
+	}
+
+	host := hostname
+	if host == "" {
+		host = "local"
+	}
+	prefix := "ipmi_sensors_" + unsafeChars.ReplaceAllString(host, "_") + "_"
+	file := filepath.Join(m.CachePath, prefix+unsafeChars.ReplaceAllString(firmware, "_")+".sdr")
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+
+	m.Log.Debugf("Dumping the SDR repository of %s to %s", host, file)
+	tmp := file + ".tmp"
+	if _, err := m.run(append(opts, "sdr", "dump", tmp)...); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	stale, err := filepath.Glob(filepath.Join(m.CachePath, prefix+"*.sdr"))
+	if err != nil {
+		return file, nil
+	}
+	for _, f := range stale {
+		if f != file {
+			m.Log.Debugf("Removing the SDR cache %s of an earlier firmware", f)
+			os.Remove(f)
+		}
+	}
+	return file, nil
+}
+
+// parseFirmware returns the firmware revision reported by "ipmitool mc
+// info" including the auxiliary revision, which changes with firmware
+// builds of the same revision on some BMCs:
+//
+//	Firmware Revision         : 2.61
+//	...
+//	Aux Firmware Rev Info     :
+//	    0x00
+//	    0x1c
+func parseFirmware(out []byte) string {
+	var revision string
+	var aux []string
+	inAux := false
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if inAux && strings.HasPrefix(line, "0x") {
+			aux = append(aux, strings.TrimPrefix(line, "0x"))
+			continue
+		}
+		inAux = false
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch strings.TrimSpace(parts[0]) {
+		case "Firmware Revision":
+			revision = strings.TrimSpace(parts[1])
+		case "Aux Firmware Rev Info":
+			inAux = true
+		}
+	}
+	if revision == "" || len(aux) == 0 {
+		return revision
+	}
+	return revision + "-" + strings.Join(aux, "")
+}
@@ -0,0 +1,359 @@
+package ipmi_sensors
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// statusCodes maps the sensor status printed by ipmitool to a severity
+// which can be alerted on, sensors without reading have no code.
+var statusCodes = map[string]int64{
+	"ok": 0,
+	"nc": 1,
+	"cr": 2,
+	"nr": 3,
+}
+
+// thresholds are the threshold columns of "ipmitool sensor" in order.
+var thresholds = []string{
+	"lower_non_recoverable",
+	"lower_critical",
+	"lower_non_critical",
+	"upper_non_critical",
+	"upper_critical",
+	"upper_non_recoverable",
+}
+
+// IpmiSensors stores the configuration values for the ipmi_sensors input
+// plugin
+type IpmiSensors struct {
+	Path        string            `toml:"path"`
+	UseSudo     bool              `toml:"use_sudo"`
+	SudoCommand []string          `toml:"sudo_command"`
+	Privilege   string            `toml:"privilege"`
+	Servers     []string          `toml:"servers"`
+	Interface   string            `toml:"interface"`
+	Timeout     internal.Duration `toml:"timeout"`
+	Thresholds  bool              `toml:"thresholds"`
+	UseCache    bool              `toml:"use_cache"`
+	CachePath   string            `toml:"cache_path"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// sensor is a sensor listed by "ipmitool sdr elist" and its thresholds
+// listed by "ipmitool sensor".
+type sensor struct {
+	name     string
+	entityID string
+	status   string
+	value    *float64
+	unit     string
+	limits   map[string]float64
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## optionally force session privilege level. Can be CALLBACK, USER, OPERATOR, ADMINISTRATOR
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## optionally specify one or more servers via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, local machine sensor stats will be queried
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## Recommended: use metric 'interval' that is a multiple of 'timeout' to avoid
+  ## gaps or overlap in pulled data
+  interval = "30s"
+
+  ## Timeout for each ipmitool command to complete
+  timeout = "20s"
+
+  ## Read the thresholds of the sensors with "ipmitool sensor" in addition
+  ## to the readings of "ipmitool sdr elist".  Some BMCs take long to list
+  ## the thresholds of all sensors.
+  # thresholds = true
+
+  ## Read the sensor data records from a cache file instead of walking the
+  ## SDR repository of the BMC on every gather.  The cache of each BMC is
+  ## dumped with "ipmitool sdr dump" and dumped again once "ipmitool mc info"
+  ## reports a different firmware revision.
+  # use_cache = false
+  ## Directory of the cache files, defaults to the temporary directory.
+  # cache_path = ""
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *IpmiSensors) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *IpmiSensors) Description() string {
+	return "Read the sensors of bare metal servers and their thresholds via IPMI"
+}
+
+// Init locates ipmitool and checks the cache directory.
+func (m *IpmiSensors) Init() error {
+	if m.UseSudo {
+		if err := ipmi.CheckSudoCommand(m.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(m.Servers, m.Interface); err != nil {
+		return err
+	}
+
+	if len(m.Path) == 0 {
+		m.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(m.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	m.Path = path
+
+	if m.UseCache {
+		if m.CachePath == "" {
+			m.CachePath = os.TempDir()
+		}
+		info, err := os.Stat(m.CachePath)
+		if err != nil {
+			return fmt.Errorf("invalid cache_path: %v", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid cache_path: %s is not a directory", m.CachePath)
+		}
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *IpmiSensors) Gather(acc telegraf.Accumulator) error {
+	if len(m.Servers) == 0 {
+		return m.gatherServer(acc, "")
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			if err := m.gatherServer(acc, s); err != nil {
+				acc.AddError(err)
+			}
+		}(server)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (m *IpmiSensors) gatherServer(acc telegraf.Accumulator, server string) error {
+	var opts []string
+	hostname := ""
+	if server != "" {
+		conn := ipmi.NewConnectionInterface(server, m.Privilege, m.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			return err
+		}
+		hostname = conn.Hostname
+		opts = conn.Options()
+	}
+
+	if m.UseCache {
+		file, err := m.sdrCache(hostname, opts)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, "-S", file)
+	}
+
+	out, err := m.run(append(opts, "sdr", "elist")...)
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now()
+	sensors, err := parseSDR(out)
+	if err != nil {
+		return fmt.Errorf("parsing sensors of %s: %v", hostname, err)
+	}
+
+	if m.Thresholds {
+		out, err := m.run(append(opts, "sensor")...)
+		if err != nil {
+			return err
+		}
+		if err := parseThresholds(out, sensors); err != nil {
+			return fmt.Errorf("parsing thresholds of %s: %v", hostname, err)
+		}
+	}
+
+	for _, s := range sensors {
+		tags := map[string]string{
+			"name":      transform(s.name),
+			"entity_id": s.entityID,
+		}
+		if s.unit != "" {
+			tags["unit"] = transform(s.unit)
+		}
+		if hostname != "" {
+			tags["server"] = hostname
+		}
+
+		fields := map[string]interface{}{"status": s.status}
+		if code, ok := statusCodes[s.status]; ok {
+			fields["status_code"] = code
+		}
+		if s.value != nil {
+			fields["value"] = *s.value
+		}
+		for name, limit := range s.limits {
+			fields[name] = limit
+		}
+		acc.AddFields("ipmi_sensors", fields, tags, timestamp)
+	}
+	return nil
+}
+
+// run runs ipmitool with the arguments.
+func (m *IpmiSensors) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        m.Path,
+		UseSudo:     m.UseSudo,
+		SudoCommand: m.SudoCommand,
+		Timeout:     m.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// parseSDR parses the sensors listed by "ipmitool sdr elist", with lines
+// like "Inlet Temp | 04h | ok | 7.1 | 21 degrees C".
+func parseSDR(out []byte) ([]*sensor, error) {
+	var sensors []*sensor
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		columns := splitColumns(scanner.Text())
+		if len(columns) != 5 || columns[0] == "" {
+			continue
+		}
+
+		s := &sensor{
+			name:     columns[0],
+			status:   columns[2],
+			entityID: columns[3],
+		}
+		// Analog readings consist of the value and unit, discrete ones
+		// describe the state
+		if parts := strings.SplitN(columns[4], " ", 2); len(parts) == 2 {
+			if v, err := strconv.ParseFloat(parts[0], 64); err == nil {
+				s.value = &v
+				s.unit = parts[1]
+			}
+		}
+		sensors = append(sensors, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(sensors) == 0 {
+		return nil, fmt.Errorf("no sensors found in output: %s", string(out))
+	}
+	return sensors, nil
+}
+
+// parseThresholds adds the thresholds listed by "ipmitool sensor" to the
+// sensors of the same name.  The lines hold the name, reading, unit and
+// status followed by the thresholds, "na" if unset.
+func parseThresholds(out []byte, sensors []*sensor) error {
+	byName := make(map[string]*sensor, len(sensors))
+	for _, s := range sensors {
+		if _, ok := byName[s.name]; !ok {
+			byName[s.name] = s
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		columns := splitColumns(scanner.Text())
+		if len(columns) != 4+len(thresholds) {
+			continue
+		}
+		s, ok := byName[columns[0]]
+		if !ok {
+			continue
+		}
+		for i, name := range thresholds {
+			if v, err := strconv.ParseFloat(columns[4+i], 64); err == nil {
+				if s.limits == nil {
+					s.limits = make(map[string]float64)
+				}
+				s.limits[name] = v
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// splitColumns splits a line of the tables printed by ipmitool.
+func splitColumns(line string) []string {
+	columns := strings.Split(line, "|")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	return columns
+}
+
+func transform(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ToLower(s)
+	return strings.Replace(s, " ", "_", -1)
+}
+
+func init() {
+	inputs.Add("ipmi_sensors", func() telegraf.Input {
+		return &IpmiSensors{
+			Timeout:    internal.Duration{Duration: time.Second * 20},
+			Thresholds: true,
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package ipmi_sensors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiSensors{
+		Path:       os.Args[0],
+		Servers:    []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:    internal.Duration{Duration: time.Second * 5},
+		Thresholds: true,
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 8)
+
+	acc.AssertContainsTaggedFields(t, "ipmi_sensors",
+		map[string]interface{}{
+			"status":             "ok",
+			"status_code":        int64(0),
+			"value":              21.0,
+			"lower_critical":     -7.0,
+			"lower_non_critical": 3.0,
+			"upper_non_critical": 38.0,
+			"upper_critical":     42.0,
+		},
+		map[string]string{
+			"server":    "192.168.1.1",
+			"name":      "inlet_temp",
+			"entity_id": "7.1",
+			"unit":      "degrees_c",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_sensors",
+		map[string]interface{}{
+			"status":         "cr",
+			"status_code":    int64(2),
+			"value":          252.0,
+			"upper_critical": 250.0,
+		},
+		map[string]string{
+			"server":    "192.168.1.1",
+			"name":      "voltage_1",
+			"entity_id": "10.1",
+			"unit":      "volts",
+		})
+	// Discrete sensors and sensors without reading
+	acc.AssertContainsTaggedFields(t, "ipmi_sensors",
+		map[string]interface{}{
+			"status":      "ok",
+			"status_code": int64(0),
+		},
+		map[string]string{
+			"server":    "192.168.1.1",
+			"name":      "ps1_status",
+			"entity_id": "10.1",
+		})
+	acc.AssertContainsTaggedFields(t, "ipmi_sensors",
+		map[string]interface{}{
+			"status": "ns",
+		},
+		map[string]string{
+			"server":    "192.168.1.1",
+			"name":      "sel",
+			"entity_id": "7.1",
+		})
+}
+
+func TestGatherWithoutThresholds(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	i := &IpmiSensors{
+		Path:    os.Args[0],
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "ipmi_sensors",
+		map[string]interface{}{
+			"status":      "nc",
+			"status_code": int64(1),
+			"value":       72.0,
+		},
+		map[string]string{
+			"name":      "exhaust_temp",
+			"entity_id": "7.1",
+			"unit":      "degrees_c",
+		})
+}
+
+func TestGatherError(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_FAILURE=Error: Unable to establish IPMI v2 / RMCP+ session")
+		return cmd
+	}
+
+	i := &IpmiSensors{
+		Path:    os.Args[0],
+		Servers: []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout: internal.Duration{Duration: time.Second * 5},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "Unable to establish")
+	require.NotContains(t, acc.Errors[0].Error(), "PASSW0RD")
+}
+
+func TestParseSDR(t *testing.T) {
+	_, err := parseSDR([]byte("Could not open device at /dev/ipmi0\n"))
+	require.Error(t, err)
+}
+
+func TestGatherCache(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	var env []string
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, env...)
+		return cmd
+	}
+
+	dir, err := ioutil.TempDir("", "ipmi_sensors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	i := &IpmiSensors{
+		Path:      os.Args[0],
+		Servers:   []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Timeout:   internal.Duration{Duration: time.Second * 5},
+		UseCache:  true,
+		CachePath: dir,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, i.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 8)
+
+	cache := filepath.Join(dir, "ipmi_sensors_192.168.1.1_2.61-00000000.sdr")
+	require.FileExists(t, cache)
+
+	// The cache is reused while the firmware is unchanged
+	require.NoError(t, ioutil.WriteFile(cache, []byte("cached"), 0644))
+	acc.ClearMetrics()
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	data, err := ioutil.ReadFile(cache)
+	require.NoError(t, err)
+	require.Equal(t, "cached", string(data))
+
+	// A firmware update replaces the cache
+	env = []string{"FAKE_IPMI_FIRMWARE=2.62"}
+	acc.ClearMetrics()
+	require.NoError(t, i.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "ipmi_sensors_192.168.1.1_2.62-00000000.sdr")}, files)
+}
+
+func TestParseFirmware(t *testing.T) {
+	out, err := ioutil.ReadFile("testdata/mc_info.txt")
+	require.NoError(t, err)
+	require.Equal(t, "2.61-00000000", parseFirmware(out))
+	require.Equal(t, "1.10", parseFirmware([]byte("Firmware Revision         : 1.10\n")))
+	require.Equal(t, "", parseFirmware([]byte("Could not open device\n")))
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the captures in testdata for "sdr elist" and "sensor".
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	cmd := strings.Join(args[2:], " ")
+
+	if failure, ok := os.LookupEnv("FAKE_IPMI_FAILURE"); ok {
+		fmt.Fprint(os.Stdout, failure)
+		os.Exit(1)
+	}
+
+	// Commands reading the SDR cache require it to be dumped before
+	if i := strings.Index(cmd, " -S "); i >= 0 {
+		file := strings.Fields(cmd[i+4:])[0]
+		if _, err := os.Stat(file); err != nil {
+			fmt.Fprint(os.Stdout, err)
+			os.Exit(1)
+		}
+	}
+
+	var capture string
+	switch {
+	case strings.HasSuffix(cmd, "mc info"):
+		out, err := ioutil.ReadFile(filepath.Join("testdata", "mc_info.txt"))
+		if err != nil {
+			fmt.Fprint(os.Stdout, err)
+			os.Exit(1)
+		}
+		if fw, ok := os.LookupEnv("FAKE_IPMI_FIRMWARE"); ok {
+			out = []byte(strings.Replace(string(out), "2.61", fw, 1))
+		}
+		fmt.Fprint(os.Stdout, string(out))
+		os.Exit(0)
+	case strings.Contains(cmd, "sdr dump "):
+		file := cmd[strings.Index(cmd, "sdr dump ")+9:]
+		if err := ioutil.WriteFile(file, []byte("sdr"), 0644); err != nil {
+			fmt.Fprint(os.Stdout, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stdout, "Dumping Sensor Data Repository to '%s'\n", file)
+		os.Exit(0)
+	case strings.HasSuffix(cmd, "sdr elist"):
+		capture = "sdr_elist.txt"
+	case strings.HasSuffix(cmd, "sensor"):
+		capture = "sensor.txt"
+	default:
+		fmt.Fprint(os.Stdout, "invalid command")
+		os.Exit(1)
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", capture))
+	if err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(os.Stdout, string(out))
+	os.Exit(0)
+}
@@ -23,14 +23,15 @@ import (
 )
 
 type tags struct {
-	name, job string
+	name, job, client string
 }
 
 // Lustre proc files can change between versions, so we want to future-proof
 // by letting people choose what to look at.
 type Lustre2 struct {
-	Ost_procfiles []string `toml:"ost_procfiles"`
-	Mds_procfiles []string `toml:"mds_procfiles"`
+	Ost_procfiles    []string `toml:"ost_procfiles"`
+	Mds_procfiles    []string `toml:"mds_procfiles"`
+	Client_procfiles []string `toml:"client_procfiles"`
 
 	// allFields maps and OST name to the metric fields associated with that OST
 	allFields map[tags]map[string]interface{}
@@ -49,6 +50,22 @@ var sampleConfig = `
   #   "/proc/fs/lustre/mdt/*/md_stats",
   #   "/proc/fs/lustre/mdt/*/job_stats",
   # ]
+  ## The brw_stats histograms, num_exports and lock_count files are also
+  ## read, e.g. "/proc/fs/lustre/osd-ldiskfs/*/brw_stats".  The traffic of
+  ## each client is read from the stats of the exports, which are not read
+  ## by default as there is one per client and target, e.g.
+  ## "/proc/fs/lustre/obdfilter/*/exports/*/stats" in ost_procfiles and
+  ## "/proc/fs/lustre/mdt/*/exports/*/stats" in mds_procfiles.
+  ##
+  ## Client statistics of the mounts and of the connections to the OSTs,
+  ## in debugfs since Lustre 2.12
+  # client_procfiles = [
+  #   "/proc/fs/lustre/llite/*/stats",
+  #   "/sys/kernel/debug/lustre/llite/*/stats",
+  #   "/proc/fs/lustre/osc/*/stats",
+  #   "/sys/kernel/debug/lustre/osc/*/stats",
+  #   "/proc/fs/lustre/osc/*/rpc_stats",
+  # ]
 `
 
 /* The wanted fields would be a []string if not for the
@@ -361,6 +378,83 @@ var wanted_mdt_jobstats_fields = []*mapping{
 	},
 }
 
+var wanted_client_fields = []*mapping{
+	{
+		inProc:   "write_bytes",
+		field:    6,
+		reportAs: "write_bytes",
+	},
+	{
+		inProc:   "write_bytes",
+		field:    1,
+		reportAs: "write_calls",
+	},
+	{
+		inProc:   "read_bytes",
+		field:    6,
+		reportAs: "read_bytes",
+	},
+	{
+		inProc:   "read_bytes",
+		field:    1,
+		reportAs: "read_calls",
+	},
+	{
+		inProc: "open",
+	},
+	{
+		inProc: "close",
+	},
+	{
+		inProc: "getattr",
+	},
+	{
+		inProc: "setattr",
+	},
+	{
+		inProc: "fsync",
+	},
+	{
+		inProc: "statfs",
+	},
+	{
+		inProc: "mkdir",
+	},
+	{
+		inProc: "rmdir",
+	},
+	{
+		inProc: "unlink",
+	},
+	{
+		inProc: "rename",
+	},
+	{ // RPCs of the osc with the sum of their latency in the sixth column
+		inProc:   "req_waittime",
+		field:    1,
+		reportAs: "rpc_calls",
+	},
+	{
+		inProc:   "req_waittime",
+		field:    6,
+		reportAs: "rpc_wait_usecs",
+	},
+	{
+		inProc:   "ost_read",
+		field:    6,
+		reportAs: "ost_read_usecs",
+	},
+	{
+		inProc:   "ost_write",
+		field:    6,
+		reportAs: "ost_write_usecs",
+	},
+}
+
+// Clients record job_stats for both the data and metadata operations
+var wanted_client_jobstats_fields = append(append([]*mapping{},
+	wanted_ost_jobstats_fields...), wanted_mdt_jobstats_fields...)
+
 func (l *Lustre2) GetLustreProcStats(fileglob string, wantedFields []*mapping, acc telegraf.Accumulator) error {
 	files, err := filepath.Glob(fileglob)
 	if err != nil {
@@ -368,13 +462,7 @@ func (l *Lustre2) GetLustreProcStats(fileglob string, wantedFields []*mapping, a
 	}
 
 	for _, file := range files {
-		/* Turn /proc/fs/lustre/obdfilter/<ost_name>/stats and similar
-		 * into just the object store target name
-		 * Assumption: the target name is always second to last,
-		 * which is true in Lustre 2.1->2.8
-		 */
-		path := strings.Split(file, "/")
-		name := path[len(path)-2]
+		name, client := targetName(file)
 
 		//lines, err := internal.ReadLines(file)
 		wholeFile, err := ioutil.ReadFile(file)
@@ -400,10 +488,10 @@ func (l *Lustre2) GetLustreProcStats(fileglob string, wantedFields []*mapping, a
 				parts := strings.Fields(line)
 
 				var fields map[string]interface{}
-				fields, ok := l.allFields[tags{name, jobid}]
+				fields, ok := l.allFields[tags{name, jobid, client}]
 				if !ok {
 					fields = make(map[string]interface{})
-					l.allFields[tags{name, jobid}] = fields
+					l.allFields[tags{name, jobid, client}] = fields
 				}
 
 				for _, wanted := range wantedFields {
@@ -432,6 +520,169 @@ func (l *Lustre2) GetLustreProcStats(fileglob string, wantedFields []*mapping, a
 	return nil
 }
 
+// GetLustreHistograms reads the histograms of rpc_stats and brw_stats files,
+// each bucket is added as a metric with the read and write counts.
+func (l *Lustre2) GetLustreHistograms(fileglob string, acc telegraf.Accumulator) error {
+	files, err := filepath.Glob(fileglob)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name, _ := targetName(file)
+
+		wholeFile, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		for _, bucket := range parseHistograms(string(wholeFile)) {
+			tags := map[string]string{
+				"name":      name,
+				"histogram": bucket.histogram,
+				"bucket":    bucket.bucket,
+			}
+			fields := map[string]interface{}{
+				"read":  bucket.read,
+				"write": bucket.write,
+			}
+			acc.AddFields("lustre2_histogram", fields, tags)
+		}
+	}
+	return nil
+}
+
+// GetLustreValue reads the files holding a single value, such as the
+// num_exports of the targets and the lock_count of the lock namespaces.
+func (l *Lustre2) GetLustreValue(fileglob string, reportAs string, acc telegraf.Accumulator) error {
+	files, err := filepath.Glob(fileglob)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name, _ := targetName(file)
+
+		wholeFile, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		data, err := strconv.ParseUint(strings.TrimSpace(string(wholeFile)), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		fields, ok := l.allFields[tags{name: name}]
+		if !ok {
+			fields = make(map[string]interface{})
+			l.allFields[tags{name: name}] = fields
+		}
+		fields[reportAs] = data
+	}
+	return nil
+}
+
+/* Turn /proc/fs/lustre/obdfilter/<ost_name>/stats and similar
+ * into just the object store target name
+ * Assumption: the target name is always second to last,
+ * which is true in Lustre 2.1->2.8, except for the statistics of the
+ * clients in exports/<nid>/stats and for the lock namespaces of the
+ * targets, such as ldlm/namespaces/filter-<ost_name>_UUID.
+ */
+func targetName(file string) (name, client string) {
+	path := strings.Split(file, "/")
+	name = path[len(path)-2]
+	if len(path) >= 4 && path[len(path)-3] == "exports" {
+		return path[len(path)-4], name
+	}
+	if len(path) >= 3 && path[len(path)-3] == "namespaces" {
+		for _, prefix := range []string{"filter-", "mdt-"} {
+			if strings.HasPrefix(name, prefix) {
+				name = strings.TrimSuffix(strings.TrimPrefix(name, prefix), "_UUID")
+			}
+		}
+	}
+	return name, ""
+}
+
+// getProcfile reads a configured file according to its name.
+func (l *Lustre2) getProcfile(procfile string, wantedFields, wantedJobstatsFields []*mapping, acc telegraf.Accumulator) error {
+	switch {
+	case strings.HasSuffix(procfile, "job_stats"):
+		return l.GetLustreProcStats(procfile, wantedJobstatsFields, acc)
+	case strings.HasSuffix(procfile, "brw_stats"), strings.HasSuffix(procfile, "rpc_stats"):
+		return l.GetLustreHistograms(procfile, acc)
+	case strings.HasSuffix(procfile, "num_exports"):
+		return l.GetLustreValue(procfile, "exports", acc)
+	case strings.HasSuffix(procfile, "lock_count"):
+		return l.GetLustreValue(procfile, "lock_count", acc)
+	}
+	return l.GetLustreProcStats(procfile, wantedFields, acc)
+}
+
+type histogramBucket struct {
+	histogram, bucket string
+	read, write       uint64
+}
+
+// parseHistograms parses the tables of the histogram files, where the name
+// of each histogram precedes the name of the counted column, e.g.
+//
+//	                        read                    write
+//	pages per rpc         rpcs   % cum % |       rpcs   % cum %
+//	1:                       5  50  50   |          0   0   0
+func parseHistograms(content string) []histogramBucket {
+	var buckets []histogramBucket
+	histogram := ""
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 3 {
+			continue
+		}
+		if i := indexOf(parts, "%"); i > 1 && strings.HasSuffix(parts[i+1], "cum") {
+			histogram = histogramName(strings.Join(parts[:i-1], " "))
+			continue
+		}
+		if histogram == "" || !strings.HasSuffix(parts[0], ":") || len(parts) < 6 || parts[4] != "|" {
+			continue
+		}
+		read, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		write, err := strconv.ParseUint(parts[5], 10, 64)
+		if err != nil {
+			continue
+		}
+		buckets = append(buckets, histogramBucket{
+			histogram: histogram,
+			bucket:    strings.TrimSuffix(parts[0], ":"),
+			read:      read,
+			write:     write,
+		})
+	}
+	return buckets
+}
+
+func indexOf(parts []string, s string) int {
+	for i, part := range parts {
+		if part == s && i+1 < len(parts) {
+			return i
+		}
+	}
+	return -1
+}
+
+// histogramName converts a title such as "I/O time (1/1000s)" to io_time
+// and "pages per bulk r/w" to pages_per_bulk_rw.
+func histogramName(title string) string {
+	if i := strings.Index(title, "("); i >= 0 {
+		title = title[:i]
+	}
+	title = strings.Replace(title, "I/O", "io", -1)
+	title = strings.Replace(title, "/", "", -1)
+	return strings.Join(strings.Fields(strings.ToLower(title)), "_")
+}
+
 // SampleConfig returns sample configuration message
 func (l *Lustre2) SampleConfig() string {
 	return sampleConfig
@@ -466,6 +717,29 @@ func (l *Lustre2) Gather(acc telegraf.Accumulator) error {
 		if err != nil {
 			return err
 		}
+		// I/O size and time histograms, in osd-ldiskfs since Lustre 2.12
+		for _, procfile := range []string{
+			"/proc/fs/lustre/obdfilter/*/brw_stats",
+			"/proc/fs/lustre/osd-ldiskfs/*/brw_stats",
+		} {
+			err = l.GetLustreHistograms(procfile, acc)
+			if err != nil {
+				return err
+			}
+		}
+		// the exports and locks of the OSTs and MDTs, in sysfs since 2.12
+		for _, root := range []string{"/proc/fs/lustre", "/sys/fs/lustre"} {
+			for _, target := range []string{"obdfilter", "mdt"} {
+				err = l.GetLustreValue(root+"/"+target+"/*/num_exports", "exports", acc)
+				if err != nil {
+					return err
+				}
+			}
+			err = l.GetLustreValue(root+"/ldlm/namespaces/*/lock_count", "lock_count", acc)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	if len(l.Mds_procfiles) == 0 {
@@ -484,22 +758,41 @@ func (l *Lustre2) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 
-	for _, procfile := range l.Ost_procfiles {
-		ost_fields := wanted_ost_fields
-		if strings.HasSuffix(procfile, "job_stats") {
-			ost_fields = wanted_ost_jobstats_fields
+	if len(l.Client_procfiles) == 0 {
+		// Statistics of the client mounts, moved to debugfs in Lustre 2.12
+		for _, root := range []string{"/proc/fs/lustre", "/sys/kernel/debug/lustre"} {
+			err := l.GetLustreProcStats(root+"/llite/*/stats",
+				wanted_client_fields, acc)
+			if err != nil {
+				return err
+			}
+			// RPCs of the connections to the OSTs
+			err = l.GetLustreProcStats(root+"/osc/*/stats",
+				wanted_client_fields, acc)
+			if err != nil {
+				return err
+			}
+		}
+		err := l.GetLustreHistograms("/proc/fs/lustre/osc/*/rpc_stats", acc)
+		if err != nil {
+			return err
 		}
-		err := l.GetLustreProcStats(procfile, ost_fields, acc)
+	}
+
+	for _, procfile := range l.Ost_procfiles {
+		err := l.getProcfile(procfile, wanted_ost_fields, wanted_ost_jobstats_fields, acc)
 		if err != nil {
 			return err
 		}
 	}
 	for _, procfile := range l.Mds_procfiles {
-		mdt_fields := wanted_mds_fields
-		if strings.HasSuffix(procfile, "job_stats") {
-			mdt_fields = wanted_mdt_jobstats_fields
+		err := l.getProcfile(procfile, wanted_mds_fields, wanted_mdt_jobstats_fields, acc)
+		if err != nil {
+			return err
 		}
-		err := l.GetLustreProcStats(procfile, mdt_fields, acc)
+	}
+	for _, procfile := range l.Client_procfiles {
+		err := l.getProcfile(procfile, wanted_client_fields, wanted_client_jobstats_fields, acc)
 		if err != nil {
 			return err
 		}
@@ -513,6 +806,9 @@ func (l *Lustre2) Gather(acc telegraf.Accumulator) error {
 		if len(tgs.job) > 0 {
 			tags["jobid"] = tgs.job
 		}
+		if len(tgs.client) > 0 {
+			tags["client"] = tgs.client
+		}
 		acc.AddFields("lustre2", fields, tags)
 	}
 
@@ -132,6 +132,73 @@ const mdtJobStatsContents = `job_stats:
   crossdir_rename: { samples:         201, unit:  reqs }
 `
 
+const lliteProcContents = `snapshot_time             1608026653.123456 secs.usecs
+read_bytes                1024 samples [bytes] 0 4194304 2147483648
+write_bytes               512 samples [bytes] 1 4194304 1073741824
+open                      300 samples [usecs] 5 900 12000
+close                     299 samples [usecs] 2 100 3000
+getattr                   1200 samples [usecs] 1 50 6000
+fsync                     3 samples [usecs] 100 2000 3500
+statfs                    10 samples [usecs] 20 80 400
+`
+
+const oscProcContents = `snapshot_time             1608026653.123456 secs.usecs
+req_waittime              1540 samples [usec] 38 95345 5987654 293745849327
+req_active                1540 samples [reqs] 1 8 2380 4890
+ost_read                  1024 samples [usec] 120 95000 3456789 234567890123
+ost_write                 512 samples [usec] 200 80000 2345678 123456789012
+`
+
+const oscRpcStatsContents = `snapshot_time:         1608026653.123456 (secs.usecs)
+read RPCs in flight:  0
+write RPCs in flight: 0
+pending write pages:  0
+pending read pages:   0
+
+			read			write
+pages per rpc         rpcs   % cum % |       rpcs   % cum %
+1:		         4   0   0   |          2   0   0
+256:		       100  96 100   |         10  83 100
+
+			read			write
+rpcs in flight        rpcs   % cum % |       rpcs   % cum %
+1:		        90  86  86   |         12 100 100
+2:		        14  13 100   |          0   0 100
+`
+
+const clientJobStatsContents = `job_stats:
+- job_id:          1234@alice
+  snapshot_time:   1608026653
+  read_bytes:      { samples:          10, unit: bytes, min:    4096, max: 1048576, sum:         5246976 }
+  write_bytes:     { samples:           2, unit: bytes, min:    4096, max:    4096, sum:            8192 }
+  open:            { samples:           3, unit:  usecs, min: 10, max: 30, sum: 60 }
+  close:           { samples:           3, unit:  usecs, min: 1, max: 3, sum: 6 }
+`
+
+const osdldiskfsBrwStatsContents = `snapshot_time:         1608026653.123456 (secs.usecs)
+
+                           read      |     write
+pages per bulk r/w     rpcs  % cum % |  rpcs        % cum %
+1:		         5   4   4   |    0   0   0
+256:		       120  95 100   |   80 100 100
+
+                           read      |     write
+disk I/Os in flight    ios   % cum % |  ios         % cum %
+1:		        60  48  48   |   70  87  87
+2:		        65  52 100   |   10  12 100
+
+                           read      |     write
+I/O time (1/1000s)     ios   % cum % |  ios         % cum %
+1:		       100  80  80   |   50  62  62
+16:		        25  20 100   |   30  37 100
+`
+
+const exportStatsContents = `snapshot_time             1608026653.123456 secs.usecs
+read_bytes                120 samples [bytes] 4096 1048576 125829120
+write_bytes               80 samples [bytes] 1048576 1048576 83886080
+punch                     2 samples [reqs]
+`
+
 func TestLustre2GeneratesMetrics(t *testing.T) {
 
 	tempdir := os.TempDir() + "/telegraf/proc/fs/lustre/"
@@ -336,6 +403,188 @@ func TestLustre2GeneratesJobstatsMetrics(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestLustre2GeneratesClientMetrics(t *testing.T) {
+
+	tempdir := os.TempDir() + "/telegraf/proc/fs/lustre/"
+	mount_name := "scratch-ffff8f0b5c4d7000"
+	osc_name := "scratch-OST0000-osc-ffff8f0b5c4d7000"
+
+	llitedir := tempdir + "/llite/"
+	err := os.MkdirAll(llitedir+"/"+mount_name, 0755)
+	require.NoError(t, err)
+
+	oscdir := tempdir + "/osc/"
+	err = os.MkdirAll(oscdir+"/"+osc_name, 0755)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(llitedir+"/"+mount_name+"/stats", []byte(lliteProcContents), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(llitedir+"/"+mount_name+"/job_stats", []byte(clientJobStatsContents), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(oscdir+"/"+osc_name+"/stats", []byte(oscProcContents), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(oscdir+"/"+osc_name+"/rpc_stats", []byte(oscRpcStatsContents), 0644)
+	require.NoError(t, err)
+
+	m := &Lustre2{
+		Ost_procfiles: []string{tempdir + "/obdfilter/*/stats"},
+		Mds_procfiles: []string{tempdir + "/mdt/*/md_stats"},
+		Client_procfiles: []string{
+			llitedir + "/*/stats",
+			llitedir + "/*/job_stats",
+			oscdir + "/*/stats",
+			oscdir + "/*/rpc_stats",
+		},
+	}
+
+	var acc testutil.Accumulator
+
+	err = m.Gather(&acc)
+	require.NoError(t, err)
+
+	acc.AssertContainsTaggedFields(t, "lustre2",
+		map[string]interface{}{
+			"read_bytes":  uint64(2147483648),
+			"read_calls":  uint64(1024),
+			"write_bytes": uint64(1073741824),
+			"write_calls": uint64(512),
+			"open":        uint64(300),
+			"close":       uint64(299),
+			"getattr":     uint64(1200),
+			"fsync":       uint64(3),
+			"statfs":      uint64(10),
+		},
+		map[string]string{"name": mount_name})
+
+	acc.AssertContainsTaggedFields(t, "lustre2",
+		map[string]interface{}{
+			"jobstats_read_calls":     uint64(10),
+			"jobstats_read_min_size":  uint64(4096),
+			"jobstats_read_max_size":  uint64(1048576),
+			"jobstats_read_bytes":     uint64(5246976),
+			"jobstats_write_calls":    uint64(2),
+			"jobstats_write_min_size": uint64(4096),
+			"jobstats_write_max_size": uint64(4096),
+			"jobstats_write_bytes":    uint64(8192),
+			"jobstats_open":           uint64(3),
+			"jobstats_close":          uint64(3),
+		},
+		map[string]string{"name": mount_name, "jobid": "1234@alice"})
+
+	acc.AssertContainsTaggedFields(t, "lustre2",
+		map[string]interface{}{
+			"rpc_calls":       uint64(1540),
+			"rpc_wait_usecs":  uint64(5987654),
+			"ost_read_usecs":  uint64(3456789),
+			"ost_write_usecs": uint64(2345678),
+		},
+		map[string]string{"name": osc_name})
+
+	histograms := []struct {
+		histogram, bucket string
+		read, write       uint64
+	}{
+		{"pages_per_rpc", "1", 4, 2},
+		{"pages_per_rpc", "256", 100, 10},
+		{"rpcs_in_flight", "1", 90, 12},
+		{"rpcs_in_flight", "2", 14, 0},
+	}
+	for _, h := range histograms {
+		acc.AssertContainsTaggedFields(t, "lustre2_histogram",
+			map[string]interface{}{"read": h.read, "write": h.write},
+			map[string]string{"name": osc_name, "histogram": h.histogram, "bucket": h.bucket})
+	}
+
+	err = os.RemoveAll(os.TempDir() + "/telegraf")
+	require.NoError(t, err)
+}
+
+func TestLustre2GeneratesServerMetrics(t *testing.T) {
+
+	tempdir := os.TempDir() + "/telegraf/proc/fs/lustre/"
+	ost_name := "scratch-OST0000"
+	client := "10.1.0.1@o2ib"
+
+	osddir := tempdir + "/osd-ldiskfs/"
+	err := os.MkdirAll(osddir+"/"+ost_name, 0755)
+	require.NoError(t, err)
+
+	obddir := tempdir + "/obdfilter/"
+	err = os.MkdirAll(obddir+"/"+ost_name+"/exports/"+client, 0755)
+	require.NoError(t, err)
+
+	nsdir := tempdir + "/ldlm/namespaces/"
+	err = os.MkdirAll(nsdir+"/filter-"+ost_name+"_UUID", 0755)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(osddir+"/"+ost_name+"/brw_stats", []byte(osdldiskfsBrwStatsContents), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(obddir+"/"+ost_name+"/num_exports", []byte("42\n"), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(obddir+"/"+ost_name+"/exports/"+client+"/stats", []byte(exportStatsContents), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(nsdir+"/filter-"+ost_name+"_UUID/lock_count", []byte("1337\n"), 0644)
+	require.NoError(t, err)
+
+	m := &Lustre2{
+		Ost_procfiles: []string{
+			osddir + "/*/brw_stats",
+			obddir + "/*/num_exports",
+			obddir + "/*/exports/*/stats",
+			nsdir + "/*/lock_count",
+		},
+		Mds_procfiles:    []string{tempdir + "/mdt/*/md_stats"},
+		Client_procfiles: []string{tempdir + "/llite/*/stats"},
+	}
+
+	var acc testutil.Accumulator
+
+	err = m.Gather(&acc)
+	require.NoError(t, err)
+
+	acc.AssertContainsTaggedFields(t, "lustre2",
+		map[string]interface{}{
+			"exports":    uint64(42),
+			"lock_count": uint64(1337),
+		},
+		map[string]string{"name": ost_name})
+
+	acc.AssertContainsTaggedFields(t, "lustre2",
+		map[string]interface{}{
+			"read_bytes":  uint64(125829120),
+			"read_calls":  uint64(120),
+			"write_bytes": uint64(83886080),
+			"write_calls": uint64(80),
+		},
+		map[string]string{"name": ost_name, "client": client})
+
+	histograms := []struct {
+		histogram, bucket string
+		read, write       uint64
+	}{
+		{"pages_per_bulk_rw", "1", 5, 0},
+		{"pages_per_bulk_rw", "256", 120, 80},
+		{"disk_ios_in_flight", "1", 60, 70},
+		{"disk_ios_in_flight", "2", 65, 10},
+		{"io_time", "1", 100, 50},
+		{"io_time", "16", 25, 30},
+	}
+	for _, h := range histograms {
+		acc.AssertContainsTaggedFields(t, "lustre2_histogram",
+			map[string]interface{}{"read": h.read, "write": h.write},
+			map[string]string{"name": ost_name, "histogram": h.histogram, "bucket": h.bucket})
+	}
+
+	err = os.RemoveAll(os.TempDir() + "/telegraf")
+	require.NoError(t, err)
+}
+
 func TestLustre2CanParseConfiguration(t *testing.T) {
 	config := []byte(`
 [[inputs.lustre2]]
@@ -0,0 +1,263 @@
+package modbus_cdu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mb "github.com/goburrow/modbus"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// registerSizes are the number of registers of the data types.
+var registerSizes = map[string]uint16{
+	"INT16":   1,
+	"UINT16":  1,
+	"INT32":   2,
+	"UINT32":  2,
+	"FLOAT32": 2,
+}
+
+var sampleConfig = `
+  ## Modbus/TCP addresses of the CDUs
+  controllers = ["tcp://127.0.0.1:502"]
+
+  ## Slave ID of the CDUs
+  # slave_id = 1
+
+  ## Register map of the CDUs, one of coolit_chx, vertiv_xdu or custom.
+  ## Use one plugin instance per model.
+  model = "coolit_chx"
+
+  ## Registers read in addition to the ones of the model, or instead of
+  ## them with the custom model.  Registers of a field of the model replace
+  ## its register.
+  ## field     - the field name
+  ## type      - holding or input
+  ## address   - the zero based address of the (first) register
+  ## data_type - INT16, UINT16, INT32, UINT32 or FLOAT32, multi-register
+  ##             values are big endian
+  ## scale     - factor the value is multiplied with
+  # registers = [
+  #   { field = "leak_detected", type = "input", address = 20, data_type = "UINT16", scale = 1.0 },
+  # ]
+
+  ## Timeout for each request
+  # timeout = "5s"
+`
+
+// Register is a register of a CDU read into a field.
+type Register struct {
+	Field    string  `toml:"field"`
+	Type     string  `toml:"type"`
+	Address  uint16  `toml:"address"`
+	DataType string  `toml:"data_type"`
+	Scale    float64 `toml:"scale"`
+}
+
+// ModbusCDU gathers the cooling telemetry of liquid cooling distribution
+// units via Modbus/TCP.
+type ModbusCDU struct {
+	Controllers []string          `toml:"controllers"`
+	SlaveID     int               `toml:"slave_id"`
+	Model       string            `toml:"model"`
+	Registers   []Register        `toml:"registers"`
+	Timeout     internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	registers []Register
+	cdus      []*cdu
+}
+
+// cdu is the connection to a CDU, kept open between gathers.
+type cdu struct {
+	address string
+	handler *mb.TCPClientHandler
+	client  mb.Client
+}
+
+// SampleConfig returns the documentation about the sample configuration
+func (m *ModbusCDU) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (m *ModbusCDU) Description() string {
+	return "Read the flow, temperatures, pump speed and valve position of liquid cooling CDUs via Modbus/TCP"
+}
+
+// Init builds the register map and parses the controllers.
+func (m *ModbusCDU) Init() error {
+	if m.SlaveID < 0 || m.SlaveID > 247 {
+		return fmt.Errorf("invalid slave_id %d", m.SlaveID)
+	}
+
+	var base []Register
+	if m.Model != "custom" {
+		var ok bool
+		base, ok = models[m.Model]
+		if !ok {
+			names := []string{"custom"}
+			for name := range models {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown model %q, must be one of %s", m.Model, strings.Join(names, ", "))
+		}
+	}
+
+	overridden := make(map[string]bool)
+	for _, r := range m.Registers {
+		if r.Field == "" {
+			return fmt.Errorf("register at address %d without field", r.Address)
+		}
+		if r.Type != "holding" && r.Type != "input" {
+			return fmt.Errorf("invalid type %q of %s, expected holding or input", r.Type, r.Field)
+		}
+		if _, ok := registerSizes[r.DataType]; !ok {
+			return fmt.Errorf("invalid data_type %q of %s", r.DataType, r.Field)
+		}
+		overridden[r.Field] = true
+	}
+	m.registers = nil
+	for _, r := range base {
+		if !overridden[r.Field] {
+			m.registers = append(m.registers, r)
+		}
+	}
+	m.registers = append(m.registers, m.Registers...)
+	if len(m.registers) == 0 {
+		return fmt.Errorf("no registers configured")
+	}
+	for i := range m.registers {
+		if m.registers[i].Scale == 0 {
+			m.registers[i].Scale = 1
+		}
+	}
+
+	m.cdus = nil
+	for _, c := range m.Controllers {
+		u, err := url.Parse(c)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "tcp" {
+			return fmt.Errorf("invalid controller %q, expected tcp://host:port", c)
+		}
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return fmt.Errorf("invalid controller %q: %v", c, err)
+		}
+		m.cdus = append(m.cdus, &cdu{address: u.Host})
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (m *ModbusCDU) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, c := range m.cdus {
+		wg.Add(1)
+		go func(c *cdu) {
+			defer wg.Done()
+			if err := m.gatherCDU(acc, c); err != nil {
+				acc.AddError(fmt.Errorf("CDU %s: %v", c.address, err))
+			}
+		}(c)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (m *ModbusCDU) gatherCDU(acc telegraf.Accumulator, c *cdu) error {
+	if c.handler == nil {
+		handler := mb.NewTCPClientHandler(c.address)
+		handler.Timeout = m.Timeout.Duration
+		handler.SlaveId = byte(m.SlaveID)
+		if err := handler.Connect(); err != nil {
+			return err
+		}
+		c.handler = handler
+		c.client = mb.NewClient(handler)
+	}
+
+	fields := make(map[string]interface{}, len(m.registers))
+	for _, r := range m.registers {
+		v, err := read(c.client, r)
+		if err != nil {
+			// Reconnect on the next gather, the connection may be broken
+			c.handler.Close()
+			c.handler = nil
+			return fmt.Errorf("reading %s: %v", r.Field, err)
+		}
+		fields[r.Field] = v
+	}
+
+	acc.AddFields("cdu", fields, map[string]string{"controller": c.address, "model": m.Model})
+	return nil
+}
+
+// read reads the register and returns its scaled value.
+func read(client mb.Client, r Register) (float64, error) {
+	size := registerSizes[r.DataType]
+	var data []byte
+	var err error
+	if r.Type == "input" {
+		data, err = client.ReadInputRegisters(r.Address, size)
+	} else {
+		data, err = client.ReadHoldingRegisters(r.Address, size)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < int(size)*2 {
+		return 0, fmt.Errorf("short response % x", data)
+	}
+
+	var v float64
+	switch r.DataType {
+	case "INT16":
+		v = float64(int16(binary.BigEndian.Uint16(data)))
+	case "UINT16":
+		v = float64(binary.BigEndian.Uint16(data))
+	case "INT32":
+		v = float64(int32(binary.BigEndian.Uint32(data)))
+	case "UINT32":
+		v = float64(binary.BigEndian.Uint32(data))
+	case "FLOAT32":
+		v = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	}
+	if r.Scale < 1 {
+		// Dividing keeps decimal readings such as 215 tenths of degrees
+		// exact
+		return v / math.Round(1/r.Scale), nil
+	}
+	return v * r.Scale, nil
+}
+
+// Stop closes the connections to the CDUs.
+func (m *ModbusCDU) Stop() {
+	for _, c := range m.cdus {
+		if c.handler != nil {
+			c.handler.Close()
+			c.handler = nil
+		}
+	}
+}
+
+func init() {
+	inputs.Add("modbus_cdu", func() telegraf.Input {
+		return &ModbusCDU{
+			SlaveID: 1,
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
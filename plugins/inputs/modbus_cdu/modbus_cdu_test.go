@@ -0,0 +1,184 @@
+package modbus_cdu
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tbrandon/mbserver"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newServer(t *testing.T) *mbserver.Server {
+	serv := mbserver.NewServer()
+	require.NoError(t, serv.ListenTCP("localhost:1512"))
+	t.Cleanup(serv.Close)
+	return serv
+}
+
+func newPlugin(model string) *ModbusCDU {
+	return &ModbusCDU{
+		Controllers: []string{"tcp://localhost:1512"},
+		SlaveID:     1,
+		Model:       model,
+		Timeout:     internal.Duration{Duration: time.Second},
+		Log:         testutil.Logger{},
+	}
+}
+
+func TestGatherCoolIT(t *testing.T) {
+	serv := newServer(t)
+	copy(serv.InputRegisters, []uint16{425, 215, 287, 180, 245, 1005, 72, 55})
+
+	m := newPlugin("coolit_chx")
+	require.NoError(t, m.Init())
+	defer m.Stop()
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "cdu",
+		map[string]interface{}{
+			"flow_rate_lpm":                       42.5,
+			"supply_temperature_celsius":          21.5,
+			"return_temperature_celsius":          28.7,
+			"facility_supply_temperature_celsius": 18.0,
+			"facility_return_temperature_celsius": 24.5,
+			"differential_pressure_kpa":           100.5,
+			"pump_speed_percent":                  72.0,
+			"valve_position_percent":              55.0,
+		},
+		map[string]string{"controller": "localhost:1512", "model": "coolit_chx"})
+}
+
+func TestGatherVertiv(t *testing.T) {
+	serv := newServer(t)
+	for i, v := range []float32{120.5, 18.25, 26.75, 85, 64, 40} {
+		bits := math.Float32bits(v)
+		serv.HoldingRegisters[100+2*i] = uint16(bits >> 16)
+		serv.HoldingRegisters[101+2*i] = uint16(bits)
+	}
+
+	m := newPlugin("vertiv_xdu")
+	require.NoError(t, m.Init())
+	defer m.Stop()
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "cdu",
+		map[string]interface{}{
+			"flow_rate_lpm":              120.5,
+			"supply_temperature_celsius": 18.25,
+			"return_temperature_celsius": 26.75,
+			"differential_pressure_kpa":  85.0,
+			"pump_speed_percent":         64.0,
+			"valve_position_percent":     40.0,
+		},
+		map[string]string{"controller": "localhost:1512", "model": "vertiv_xdu"})
+}
+
+func TestGatherCustomRegisters(t *testing.T) {
+	serv := newServer(t)
+	serv.HoldingRegisters[10] = 0xffff
+	serv.HoldingRegisters[11] = 0xfff6
+	serv.HoldingRegisters[12] = 0x0001
+	serv.HoldingRegisters[13] = 0x0000
+
+	m := newPlugin("custom")
+	m.Registers = []Register{
+		{Field: "supply_temperature_celsius", Type: "holding", Address: 10, DataType: "INT32", Scale: 0.1},
+		{Field: "runtime_hours", Type: "holding", Address: 12, DataType: "UINT32"},
+	}
+	require.NoError(t, m.Init())
+	defer m.Stop()
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsFields(t, "cdu", map[string]interface{}{
+		"supply_temperature_celsius": -1.0,
+		"runtime_hours":              65536.0,
+	})
+}
+
+func TestRegistersOverrideModel(t *testing.T) {
+	m := newPlugin("coolit_chx")
+	m.Registers = []Register{
+		{Field: "flow_rate_lpm", Type: "holding", Address: 40, DataType: "UINT16", Scale: 0.01},
+		{Field: "leak_detected", Type: "input", Address: 20, DataType: "UINT16"},
+	}
+	require.NoError(t, m.Init())
+
+	var flow []Register
+	for _, r := range m.registers {
+		if r.Field == "flow_rate_lpm" {
+			flow = append(flow, r)
+		}
+	}
+	require.Equal(t, []Register{m.Registers[0]}, flow)
+	require.Len(t, m.registers, len(models["coolit_chx"])+1)
+	require.Equal(t, 1.0, m.registers[len(m.registers)-1].Scale)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		registers []Register
+		servers   []string
+	}{
+		{name: "unknown model", model: "acme"},
+		{name: "custom without registers", model: "custom"},
+		{
+			name:      "invalid type",
+			model:     "custom",
+			registers: []Register{{Field: "x", Type: "coil", DataType: "UINT16"}},
+		},
+		{
+			name:      "invalid data type",
+			model:     "custom",
+			registers: []Register{{Field: "x", Type: "input", DataType: "FLOAT64"}},
+		},
+		{name: "invalid scheme", model: "coolit_chx", servers: []string{"udp://localhost:502"}},
+		{name: "missing port", model: "coolit_chx", servers: []string{"tcp://localhost"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newPlugin(tt.model)
+			m.Registers = tt.registers
+			if tt.servers != nil {
+				m.Controllers = tt.servers
+			}
+			require.Error(t, m.Init())
+		})
+	}
+}
+
+func TestGatherReconnects(t *testing.T) {
+	m := newPlugin("coolit_chx")
+	require.NoError(t, m.Init())
+	defer m.Stop()
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Empty(t, acc.Metrics)
+
+	serv := newServer(t)
+	serv.InputRegisters[0] = 100
+
+	acc.ClearMetrics()
+	acc.Errors = nil
+	require.NoError(t, m.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	v, ok := acc.FloatField("cdu", "flow_rate_lpm")
+	require.True(t, ok)
+	require.Equal(t, 10.0, v)
+}
@@ -0,0 +1,27 @@
+package modbus_cdu
+
+// models are the register maps of the CDUs by model.  The addresses are the
+// zero based protocol addresses, not the 3xxxx/4xxxx register numbers of the
+// vendor documentation.
+var models = map[string][]Register{
+	// CoolIT CHx750 and CHx80 rack CDUs, input registers
+	"coolit_chx": {
+		{Field: "flow_rate_lpm", Type: "input", Address: 0, DataType: "UINT16", Scale: 0.1},
+		{Field: "supply_temperature_celsius", Type: "input", Address: 1, DataType: "INT16", Scale: 0.1},
+		{Field: "return_temperature_celsius", Type: "input", Address: 2, DataType: "INT16", Scale: 0.1},
+		{Field: "facility_supply_temperature_celsius", Type: "input", Address: 3, DataType: "INT16", Scale: 0.1},
+		{Field: "facility_return_temperature_celsius", Type: "input", Address: 4, DataType: "INT16", Scale: 0.1},
+		{Field: "differential_pressure_kpa", Type: "input", Address: 5, DataType: "UINT16", Scale: 0.1},
+		{Field: "pump_speed_percent", Type: "input", Address: 6, DataType: "UINT16", Scale: 1},
+		{Field: "valve_position_percent", Type: "input", Address: 7, DataType: "UINT16", Scale: 1},
+	},
+	// Vertiv Liebert XDU, holding registers holding IEEE 754 floats
+	"vertiv_xdu": {
+		{Field: "flow_rate_lpm", Type: "holding", Address: 100, DataType: "FLOAT32", Scale: 1},
+		{Field: "supply_temperature_celsius", Type: "holding", Address: 102, DataType: "FLOAT32", Scale: 1},
+		{Field: "return_temperature_celsius", Type: "holding", Address: 104, DataType: "FLOAT32", Scale: 1},
+		{Field: "differential_pressure_kpa", Type: "holding", Address: 106, DataType: "FLOAT32", Scale: 1},
+		{Field: "pump_speed_percent", Type: "holding", Address: 108, DataType: "FLOAT32", Scale: 1},
+		{Field: "valve_position_percent", Type: "holding", Address: 110, DataType: "FLOAT32", Scale: 1},
+	},
+}
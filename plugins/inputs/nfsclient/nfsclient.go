@@ -0,0 +1,210 @@
+package nfsclient
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	defaultHostProc = "/proc"
+	envProc         = "HOST_PROC"
+)
+
+// bytesFields are the counters of the bytes line, in order.
+var bytesFields = []string{
+	"normal_read_bytes",
+	"normal_write_bytes",
+	"direct_read_bytes",
+	"direct_write_bytes",
+	"server_read_bytes",
+	"server_write_bytes",
+	"read_pages",
+	"write_pages",
+}
+
+// xprtFields are the counters of the xprt line by transport, in order after
+// the transport name, empty names are skipped.
+var xprtFields = map[string][]string{
+	"tcp": {"", "bind_count", "connect_count", "connect_time", "idle_time", "sends", "receives", "bad_xids", "requests_u", "backlog_u"},
+	"udp": {"", "bind_count", "sends", "receives", "bad_xids", "requests_u", "backlog_u"},
+}
+
+// opFields are the counters of the per-op statistics, in order, the errors
+// are counted since kernel 5.3.
+var opFields = []string{
+	"ops",
+	"transmissions",
+	"major_timeouts",
+	"bytes_sent",
+	"bytes_received",
+	"queue_time_ms",
+	"rtt_ms",
+	"execute_time_ms",
+	"errors",
+}
+
+type NFSClient struct {
+	HostProc          string   `toml:"host_proc"`
+	IncludeMounts     []string `toml:"include_mounts"`
+	ExcludeMounts     []string `toml:"exclude_mounts"`
+	IncludeOperations []string `toml:"include_operations"`
+	ExcludeOperations []string `toml:"exclude_operations"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	mountFilter     filter.Filter
+	operationFilter filter.Filter
+}
+
+var sampleConfig = `
+  ## Sets 'proc' directory path, if not specified the default is /proc
+  # host_proc = "/proc"
+
+  ## Mount points to report, by default all the NFS mounts.
+  # include_mounts = []
+  # exclude_mounts = []
+
+  ## Operations to report the RPC statistics of, e.g. "READ", "WRITE" and
+  ## "GETATTR", by default all of them.
+  # include_operations = []
+  # exclude_operations = []
+`
+
+func (n *NFSClient) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NFSClient) Description() string {
+	return "Read per-mount NFS client statistics from /proc/self/mountstats"
+}
+
+func (n *NFSClient) Init() error {
+	if n.HostProc == "" {
+		n.HostProc = os.Getenv(envProc)
+	}
+	if n.HostProc == "" {
+		n.HostProc = defaultHostProc
+	}
+
+	var err error
+	if n.mountFilter, err = filter.NewIncludeExcludeFilter(n.IncludeMounts, n.ExcludeMounts); err != nil {
+		return fmt.Errorf("invalid mount filter: %v", err)
+	}
+	if n.operationFilter, err = filter.NewIncludeExcludeFilter(n.IncludeOperations, n.ExcludeOperations); err != nil {
+		return fmt.Errorf("invalid operation filter: %v", err)
+	}
+	return nil
+}
+
+func (n *NFSClient) Gather(acc telegraf.Accumulator) error {
+	file, err := os.Open(filepath.Join(n.HostProc, "self", "mountstats"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var tags map[string]string
+	var fields map[string]interface{}
+	flush := func() {
+		if tags != nil && len(fields) > 0 {
+			acc.AddFields("nfsclient", fields, tags)
+		}
+		tags, fields = nil, nil
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+
+		// device 10.0.0.1:/export/home mounted on /home with fstype nfs4 statvers=1.1
+		if parts[0] == "device" {
+			flush()
+			if len(parts) < 8 || !strings.HasPrefix(parts[7], "nfs") || !n.mountFilter.Match(parts[4]) {
+				continue
+			}
+			tags = map[string]string{
+				"mountpoint":   parts[4],
+				"serverexport": parts[1],
+			}
+			if i := strings.LastIndex(parts[1], ":/"); i > 0 {
+				tags["server"] = strings.Trim(parts[1][:i], "[]")
+			}
+			fields = make(map[string]interface{})
+			continue
+		}
+		if tags == nil {
+			continue
+		}
+
+		switch {
+		case parts[0] == "age:" && len(parts) == 2:
+			if v, err := strconv.ParseUint(parts[1], 10, 64); err == nil {
+				fields["age"] = v
+			}
+		case parts[0] == "bytes:":
+			setCounters(fields, "", bytesFields, parts[1:])
+		case parts[0] == "xprt:" && len(parts) > 1:
+			if names, ok := xprtFields[parts[1]]; ok {
+				setCounters(fields, "xprt_", names, parts[2:])
+			}
+		case strings.HasSuffix(parts[0], ":") && len(parts) >= 9 && isUpper(parts[0]):
+			op := strings.TrimSuffix(parts[0], ":")
+			if !n.operationFilter.Match(op) {
+				continue
+			}
+			opFieldsValues := make(map[string]interface{})
+			setCounters(opFieldsValues, "", opFields, parts[1:])
+			ops, ok := opFieldsValues["ops"].(uint64)
+			if !ok {
+				continue
+			}
+			if trans, ok := opFieldsValues["transmissions"].(uint64); ok && trans >= ops {
+				opFieldsValues["retransmissions"] = trans - ops
+			}
+			opTags := map[string]string{"operation": op}
+			for k, v := range tags {
+				opTags[k] = v
+			}
+			acc.AddFields("nfsclient_ops", opFieldsValues, opTags)
+		}
+	}
+	flush()
+	return scanner.Err()
+}
+
+func setCounters(fields map[string]interface{}, prefix string, names []string, values []string) {
+	for i, name := range names {
+		if i >= len(values) {
+			break
+		}
+		if name == "" {
+			continue
+		}
+		if v, err := strconv.ParseUint(values[i], 10, 64); err == nil {
+			fields[prefix+name] = v
+		}
+	}
+}
+
+// isUpper tells whether the name of an operation, e.g. READ or
+// TEST_STATEID, is in upper case.
+func isUpper(s string) bool {
+	return strings.ToUpper(s) == s && strings.ToLower(s) != s
+}
+
+func init() {
+	inputs.Add("nfsclient", func() telegraf.Input {
+		return &NFSClient{}
+	})
+}
@@ -0,0 +1,109 @@
+package nfsclient
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	n := &NFSClient{HostProc: "testdata", Log: testutil.Logger{}}
+	require.NoError(t, n.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Len(t, acc.Metrics, 8)
+
+	home := map[string]string{
+		"mountpoint":   "/home",
+		"serverexport": "10.0.0.1:/export/home",
+		"server":       "10.0.0.1",
+	}
+	acc.AssertContainsTaggedFields(t, "nfsclient",
+		map[string]interface{}{
+			"age":                uint64(86400),
+			"normal_read_bytes":  uint64(1048576),
+			"normal_write_bytes": uint64(524288),
+			"direct_read_bytes":  uint64(0),
+			"direct_write_bytes": uint64(0),
+			"server_read_bytes":  uint64(2097152),
+			"server_write_bytes": uint64(1048576),
+			"read_pages":         uint64(512),
+			"write_pages":        uint64(128),
+			"xprt_bind_count":    uint64(1),
+			"xprt_connect_count": uint64(2),
+			"xprt_connect_time":  uint64(0),
+			"xprt_idle_time":     uint64(12),
+			"xprt_sends":         uint64(1500),
+			"xprt_receives":      uint64(1490),
+			"xprt_bad_xids":      uint64(0),
+			"xprt_requests_u":    uint64(3000),
+			"xprt_backlog_u":     uint64(0),
+		},
+		home)
+
+	read := map[string]string{"operation": "READ"}
+	for k, v := range home {
+		read[k] = v
+	}
+	acc.AssertContainsTaggedFields(t, "nfsclient_ops",
+		map[string]interface{}{
+			"ops":             uint64(100),
+			"transmissions":   uint64(103),
+			"retransmissions": uint64(3),
+			"major_timeouts":  uint64(1),
+			"bytes_sent":      uint64(14800),
+			"bytes_received":  uint64(2110000),
+			"queue_time_ms":   uint64(50),
+			"rtt_ms":          uint64(4000),
+			"execute_time_ms": uint64(4200),
+			"errors":          uint64(0),
+		},
+		read)
+
+	acc.AssertContainsTaggedFields(t, "nfsclient",
+		map[string]interface{}{
+			"age":                uint64(3600),
+			"normal_read_bytes":  uint64(10),
+			"normal_write_bytes": uint64(20),
+			"direct_read_bytes":  uint64(0),
+			"direct_write_bytes": uint64(0),
+			"server_read_bytes":  uint64(10),
+			"server_write_bytes": uint64(20),
+			"read_pages":         uint64(1),
+			"write_pages":        uint64(1),
+			"xprt_bind_count":    uint64(0),
+			"xprt_sends":         uint64(40),
+			"xprt_receives":      uint64(38),
+			"xprt_bad_xids":      uint64(0),
+			"xprt_requests_u":    uint64(40),
+			"xprt_backlog_u":     uint64(0),
+		},
+		map[string]string{
+			"mountpoint":   "/scratch",
+			"serverexport": "10.0.0.2:/export/scratch",
+			"server":       "10.0.0.2",
+		})
+}
+
+func TestGatherFilters(t *testing.T) {
+	n := &NFSClient{
+		HostProc:          "testdata",
+		ExcludeMounts:     []string{"/scratch"},
+		IncludeOperations: []string{"READ", "WRITE"},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, n.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Len(t, acc.Metrics, 3)
+
+	for _, m := range acc.Metrics {
+		require.Equal(t, "/home", m.Tags["mountpoint"])
+		if m.Measurement == "nfsclient_ops" {
+			require.Contains(t, []string{"READ", "WRITE"}, m.Tags["operation"])
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package numa
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// NUMA stores the configuration values for the numa input plugin
+type NUMA struct {
+	Path string `toml:"path"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## Path of the NUMA nodes in sysfs.
+  # path = "/sys/devices/system/node"
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (n *NUMA) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (n *NUMA) Description() string {
+	return "Read the memory usage and allocation statistics of each NUMA node"
+}
+
+// Init sets the defaults.
+func (n *NUMA) Init() error {
+	if n.Path == "" {
+		n.Path = "/sys/devices/system/node"
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (n *NUMA) Gather(acc telegraf.Accumulator) error {
+	nodes, err := filepath.Glob(filepath.Join(n.Path, "node[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no NUMA node found in %s", n.Path)
+	}
+	sort.Strings(nodes)
+
+	for _, dir := range nodes {
+		fields := make(map[string]interface{})
+		if err := readMeminfo(filepath.Join(dir, "meminfo"), fields); err != nil {
+			acc.AddError(fmt.Errorf("reading %s: %v", dir, err))
+			continue
+		}
+		if err := readNumastat(filepath.Join(dir, "numastat"), fields); err != nil {
+			acc.AddError(fmt.Errorf("reading %s: %v", dir, err))
+			continue
+		}
+		tags := map[string]string{
+			"node": strings.TrimPrefix(filepath.Base(dir), "node"),
+		}
+		acc.AddFields("numa_node", fields, tags)
+	}
+	return nil
+}
+
+// readMeminfo reads the meminfo of a node, in the format of /proc/meminfo
+// with the node as prefix, e.g. "Node 0 MemFree:  52023700 kB".  The sizes
+// are converted to bytes, the huge pages are counted in pages.
+func readMeminfo(file string, fields map[string]interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 4 || parts[0] != "Node" {
+			continue
+		}
+		value, err := strconv.ParseUint(parts[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(parts) == 5 && parts[4] == "kB" {
+			value *= 1024
+		}
+		fields[fieldName(strings.TrimSuffix(parts[2], ":"))] = value
+	}
+	return scanner.Err()
+}
+
+// readNumastat reads the allocation counters of a node, e.g. "numa_miss 12".
+func readNumastat(file string, fields map[string]interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[parts[0]] = value
+	}
+	return scanner.Err()
+}
+
+// fieldName converts the names of meminfo to snake case, e.g. "MemFree" to
+// "mem_free", "HugePages_Total" to "huge_pages_total" and "Active(anon)" to
+// "active_anon".  Acronyms stay a single word, "SReclaimable" is
+// "sreclaimable" as in the mem input.
+func fieldName(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '(' || r == '_':
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteRune('_')
+			}
+		case r == ')':
+		case unicode.IsUpper(r):
+			if i > 0 && unicode.IsLower(runes[i-1]) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func init() {
+	inputs.Add("numa", func() telegraf.Input {
+		return &NUMA{}
+	})
+}
@@ -0,0 +1,68 @@
+package numa
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	n := &NUMA{Path: "testdata"}
+	require.NoError(t, n.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "numa_node",
+		map[string]interface{}{
+			"mem_total":        uint64(65842620 * 1024),
+			"mem_free":         uint64(52023700 * 1024),
+			"mem_used":         uint64(13818920 * 1024),
+			"active_anon":      uint64(1203452 * 1024),
+			"file_pages":       uint64(8231040 * 1024),
+			"sreclaimable":     uint64(612304 * 1024),
+			"anon_huge_pages":  uint64(923648 * 1024),
+			"nfs_unstable":     uint64(0),
+			"huge_pages_total": uint64(16),
+			"huge_pages_free":  uint64(8),
+			"numa_hit":         uint64(81654213),
+			"numa_miss":        uint64(0),
+			"numa_foreign":     uint64(1234),
+			"interleave_hit":   uint64(32021),
+			"local_node":       uint64(81623101),
+			"other_node":       uint64(31112),
+		},
+		map[string]string{"node": "0"})
+
+	for _, m := range acc.Metrics {
+		if m.Tags["node"] == "1" {
+			require.Equal(t, uint64(40960000*1024), m.Fields["mem_free"])
+			require.Equal(t, uint64(1234), m.Fields["numa_miss"])
+		}
+	}
+}
+
+func TestGatherNoNode(t *testing.T) {
+	n := &NUMA{Path: "testdata/node0"}
+	require.NoError(t, n.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, n.Gather(&acc))
+}
+
+func TestFieldName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"MemFree":         "mem_free",
+		"HugePages_Total": "huge_pages_total",
+		"Active(anon)":    "active_anon",
+		"Inactive(file)":  "inactive_file",
+		"SUnreclaim":      "sunreclaim",
+		"NFS_Unstable":    "nfs_unstable",
+		"ShmemPmdMapped":  "shmem_pmd_mapped",
+	} {
+		require.Equal(t, expected, fieldName(name), name)
+	}
+}
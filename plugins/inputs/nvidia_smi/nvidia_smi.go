@@ -20,6 +20,7 @@ const measurement = "nvidia_smi"
 type NvidiaSMI struct {
 	BinPath string
 	Timeout internal.Duration
+	UseNVML bool `toml:"use_nvml"`
 }
 
 // Description returns the description of the NvidiaSMI plugin
@@ -35,11 +36,21 @@ func (smi *NvidiaSMI) SampleConfig() string {
 
   ## Optional: timeout for GPU polling
   # timeout = "5s"
+
+  ## Optional: read the GPUs through the NVML library instead of running
+  ## nvidia-smi, which is faster on hosts with many GPUs and adds the clock
+  ## throttle reasons, the energy, the ECC errors and the memory of the
+  ## processes.  Requires a Linux build of Telegraf with cgo.
+  # use_nvml = false
 `
 }
 
 // Gather implements the telegraf interface
 func (smi *NvidiaSMI) Gather(acc telegraf.Accumulator) error {
+	if smi.UseNVML {
+		return smi.gatherNVML(acc)
+	}
+
 	if _, err := os.Stat(smi.BinPath); os.IsNotExist(err) {
 		return fmt.Errorf("nvidia-smi binary not at path %s, cannot gather GPU data", smi.BinPath)
 	}
@@ -85,15 +96,16 @@ func gatherNvidiaSMI(ret []byte, acc telegraf.Accumulator) error {
 	metrics := smi.genTagsFields()
 
 	for _, metric := range metrics {
-		acc.AddFields(measurement, metric.fields, metric.tags)
+		acc.AddFields(metric.measurement, metric.fields, metric.tags)
 	}
 
 	return nil
 }
 
 type metric struct {
-	tags   map[string]string
-	fields map[string]interface{}
+	measurement string
+	tags        map[string]string
+	fields      map[string]interface{}
 }
 
 func (s *SMI) genTagsFields() []metric {
@@ -134,11 +146,53 @@ func (s *SMI) genTagsFields() []metric {
 		setIfUsed("int", fields, "clocks_current_video", gpu.Clocks.Video)
 
 		setIfUsed("float", fields, "power_draw", gpu.Power.PowerDraw)
-		metrics = append(metrics, metric{tags, fields})
+		metrics = append(metrics, metric{measurement, tags, fields})
+
+		for _, mig := range gpu.MIGDevices {
+			migTags := map[string]string{
+				"index":     tags["index"],
+				"mig_index": mig.Index,
+			}
+			setTagIfUsed(migTags, "name", gpu.ProdName)
+			setTagIfUsed(migTags, "uuid", gpu.UUID)
+			setInstanceTags(migTags, mig.GPUInstanceID, mig.ComputeInstanceID)
+			migFields := map[string]interface{}{}
+			setIfUsed("int", migFields, "memory_total", mig.Memory.Total)
+			setIfUsed("int", migFields, "memory_used", mig.Memory.Used)
+			setIfUsed("int", migFields, "memory_free", mig.Memory.Free)
+			setIfUsed("int", migFields, "multiprocessor_count", mig.MultiprocessorCount)
+			metrics = append(metrics, metric{measurement + "_mig", migTags, migFields})
+		}
+
+		for _, p := range gpu.Processes {
+			processTags := map[string]string{
+				"index": tags["index"],
+				"pid":   p.PID,
+			}
+			setTagIfUsed(processTags, "uuid", gpu.UUID)
+			setTagIfUsed(processTags, "process_name", p.ProcessName)
+			setInstanceTags(processTags, p.GPUInstanceID, p.ComputeInstanceID)
+			processFields := map[string]interface{}{}
+			setIfUsed("int", processFields, "used_memory", p.UsedMemory)
+			if len(processFields) > 0 {
+				metrics = append(metrics, metric{measurement + "_process", processTags, processFields})
+			}
+		}
 	}
 	return metrics
 }
 
+// setInstanceTags sets the MIG GPU and compute instance tags, nvidia-smi
+// reports them as N/A for GPUs without MIG.
+func setInstanceTags(tags map[string]string, gpuInstance, computeInstance string) {
+	if gpuInstance != "N/A" {
+		setTagIfUsed(tags, "gpu_instance", gpuInstance)
+	}
+	if computeInstance != "N/A" {
+		setTagIfUsed(tags, "compute_instance", computeInstance)
+	}
+}
+
 func setTagIfUsed(m map[string]string, k, v string) {
 	if v != "" {
 		m[k] = v
@@ -200,6 +254,28 @@ type GPU []struct {
 	Encoder     EncoderStats     `xml:"encoder_stats"`
 	FBC         FBCStats         `xml:"fbc_stats"`
 	Clocks      ClockStats       `xml:"clocks"`
+	MIGDevices  []MIGDevice      `xml:"mig_devices>mig_device"`
+	Processes   []ProcessInfo    `xml:"processes>process_info"`
+}
+
+// MIGDevice defines the structure of a MIG device in the mig_devices portion
+// of the smi output.
+type MIGDevice struct {
+	Index               string      `xml:"index"`
+	GPUInstanceID       string      `xml:"gpu_instance_id"`
+	ComputeInstanceID   string      `xml:"compute_instance_id"`
+	MultiprocessorCount string      `xml:"device_attributes>shared>multiprocessor_count"` // int
+	Memory              MemoryStats `xml:"fb_memory_usage"`
+}
+
+// ProcessInfo defines the structure of a process in the processes portion of
+// the smi output.
+type ProcessInfo struct {
+	GPUInstanceID     string `xml:"gpu_instance_id"`
+	ComputeInstanceID string `xml:"compute_instance_id"`
+	PID               string `xml:"pid"`
+	ProcessName       string `xml:"process_name"`
+	UsedMemory        string `xml:"used_memory"` // int
 }
 
 // MemoryStats defines the structure of the memory portions in the smi output.
@@ -134,6 +134,91 @@ func TestGatherValidXML(t *testing.T) {
 					time.Unix(0, 0)),
 			},
 		},
+		{
+			name:     "A100 SXM4 in MIG mode",
+			filename: "a100-sxm4-mig.xml",
+			expected: []telegraf.Metric{
+				testutil.MustMetric(
+					"nvidia_smi",
+					map[string]string{
+						"compute_mode": "Default",
+						"index":        "0",
+						"name":         "A100-SXM4-40GB",
+						"pstate":       "P0",
+						"uuid":         "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+					},
+					map[string]interface{}{
+						"clocks_current_graphics":       1410,
+						"clocks_current_memory":         1215,
+						"clocks_current_sm":             1410,
+						"clocks_current_video":          1275,
+						"cuda_version":                  "11.0",
+						"driver_version":                "450.80.02",
+						"encoder_stats_average_fps":     0,
+						"encoder_stats_average_latency": 0,
+						"encoder_stats_session_count":   0,
+						"fbc_stats_average_fps":         0,
+						"fbc_stats_average_latency":     0,
+						"fbc_stats_session_count":       0,
+						"memory_free":                   36893,
+						"memory_total":                  40537,
+						"memory_used":                   3644,
+						"pcie_link_gen_current":         4,
+						"pcie_link_width_current":       16,
+						"power_draw":                    93.42,
+						"temperature_gpu":               47,
+					},
+					time.Unix(0, 0)),
+				testutil.MustMetric(
+					"nvidia_smi_mig",
+					map[string]string{
+						"index":            "0",
+						"mig_index":        "0",
+						"name":             "A100-SXM4-40GB",
+						"uuid":             "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+						"gpu_instance":     "1",
+						"compute_instance": "0",
+					},
+					map[string]interface{}{
+						"memory_free":          16455,
+						"memory_total":         20096,
+						"memory_used":          3641,
+						"multiprocessor_count": 42,
+					},
+					time.Unix(0, 0)),
+				testutil.MustMetric(
+					"nvidia_smi_mig",
+					map[string]string{
+						"index":            "0",
+						"mig_index":        "1",
+						"name":             "A100-SXM4-40GB",
+						"uuid":             "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+						"gpu_instance":     "9",
+						"compute_instance": "0",
+					},
+					map[string]interface{}{
+						"memory_free":          4861,
+						"memory_total":         4864,
+						"memory_used":          3,
+						"multiprocessor_count": 14,
+					},
+					time.Unix(0, 0)),
+				testutil.MustMetric(
+					"nvidia_smi_process",
+					map[string]string{
+						"index":            "0",
+						"uuid":             "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+						"pid":              "28177",
+						"process_name":     "python3",
+						"gpu_instance":     "1",
+						"compute_instance": "0",
+					},
+					map[string]interface{}{
+						"used_memory": 3637,
+					},
+					time.Unix(0, 0)),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,190 @@
+package nvidia_smi
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Names of the raw NVML readings of a GPU.
+const (
+	nvmlFanSpeed           = "fan_speed"
+	nvmlMemoryTotal        = "memory_total"
+	nvmlMemoryUsed         = "memory_used"
+	nvmlMemoryFree         = "memory_free"
+	nvmlTemperature        = "temperature_gpu"
+	nvmlUtilizationGPU     = "utilization_gpu"
+	nvmlUtilizationMemory  = "utilization_memory"
+	nvmlUtilizationEncoder = "utilization_encoder"
+	nvmlUtilizationDecoder = "utilization_decoder"
+	nvmlPCIeGen            = "pcie_link_gen_current"
+	nvmlPCIeWidth          = "pcie_link_width_current"
+	nvmlClockGraphics      = "clocks_current_graphics"
+	nvmlClockSM            = "clocks_current_sm"
+	nvmlClockMemory        = "clocks_current_memory"
+	nvmlClockVideo         = "clocks_current_video"
+	nvmlPowerUsage         = "power_usage"
+	nvmlEnergy             = "energy_consumption"
+	nvmlThrottleReasons    = "clocks_throttle_reasons"
+	nvmlECCCorrected       = "ecc_errors_corrected_volatile"
+	nvmlECCUncorrected     = "ecc_errors_uncorrected_volatile"
+)
+
+// nvmlValueNotAvailable is returned by NVML for unavailable readings.
+const nvmlValueNotAvailable = ^uint64(0)
+
+// throttleReasons are the bits of the clock throttle reasons of NVML.
+var throttleReasons = []struct {
+	bit  uint64
+	name string
+}{
+	{0x1, "gpu_idle"},
+	{0x2, "applications_clocks_setting"},
+	{0x4, "sw_power_cap"},
+	{0x8, "hw_slowdown"},
+	{0x10, "sync_boost"},
+	{0x20, "sw_thermal_slowdown"},
+	{0x40, "hw_thermal_slowdown"},
+	{0x80, "hw_power_brake_slowdown"},
+	{0x100, "display_clock_setting"},
+}
+
+// nvmlSystem holds the readings of the GPUs of the host through NVML.
+type nvmlSystem struct {
+	driverVersion string
+	cudaVersion   int
+	gpus          []nvmlGPU
+}
+
+// nvmlGPU holds the readings of a GPU, the readings not supported by the GPU
+// are absent from values.
+type nvmlGPU struct {
+	index     int
+	uuid      string
+	name      string
+	pstate    int
+	values    map[string]uint64
+	processes []nvmlProcess
+	migs      []nvmlMIGDevice
+}
+
+// nvmlMIGDevice is a MIG device, a compute instance of a GPU instance of a
+// GPU in MIG mode.
+type nvmlMIGDevice struct {
+	index           int
+	uuid            string
+	gpuInstance     int
+	computeInstance int
+	values          map[string]uint64
+}
+
+// nvmlProcess is a compute process running on a GPU, the instances are -1
+// unless the GPU is in MIG mode.
+type nvmlProcess struct {
+	pid             uint32
+	name            string
+	usedMemory      uint64
+	gpuInstance     int
+	computeInstance int
+}
+
+// gatherNVML reads the GPUs through the NVML library, without running
+// nvidia-smi.
+func (smi *NvidiaSMI) gatherNVML(acc telegraf.Accumulator) error {
+	system, err := readNVML()
+	if err != nil {
+		return err
+	}
+
+	for _, gpu := range system.gpus {
+		tags, fields := system.tagsFields(gpu)
+		acc.AddFields(measurement, fields, tags)
+
+		for _, p := range gpu.processes {
+			// The memory of the processes is not available in MIG mode
+			if p.usedMemory == nvmlValueNotAvailable {
+				continue
+			}
+			ptags := map[string]string{
+				"index": tags["index"],
+				"pid":   strconv.FormatUint(uint64(p.pid), 10),
+			}
+			setTagIfUsed(ptags, "uuid", gpu.uuid)
+			setTagIfUsed(ptags, "process_name", p.name)
+			setNVMLInstanceTags(ptags, p.gpuInstance, p.computeInstance)
+			acc.AddFields(measurement+"_process", map[string]interface{}{
+				"used_memory": int(p.usedMemory >> 20),
+			}, ptags)
+		}
+
+		for _, mig := range gpu.migs {
+			mtags := map[string]string{
+				"index":     tags["index"],
+				"mig_index": strconv.Itoa(mig.index),
+			}
+			setTagIfUsed(mtags, "name", gpu.name)
+			setTagIfUsed(mtags, "uuid", gpu.uuid)
+			setTagIfUsed(mtags, "mig_uuid", mig.uuid)
+			setNVMLInstanceTags(mtags, mig.gpuInstance, mig.computeInstance)
+			mfields := map[string]interface{}{}
+			for name, value := range mig.values {
+				mfields[name] = int(value >> 20)
+			}
+			if len(mfields) > 0 {
+				acc.AddFields(measurement+"_mig", mfields, mtags)
+			}
+		}
+	}
+	return nil
+}
+
+func setNVMLInstanceTags(tags map[string]string, gpuInstance, computeInstance int) {
+	if gpuInstance >= 0 {
+		tags["gpu_instance"] = strconv.Itoa(gpuInstance)
+	}
+	if computeInstance >= 0 {
+		tags["compute_instance"] = strconv.Itoa(computeInstance)
+	}
+}
+
+// tagsFields converts the readings of a GPU to the tags and fields of
+// nvidia-smi, adding those nvidia-smi does not print.
+func (s *nvmlSystem) tagsFields(gpu nvmlGPU) (map[string]string, map[string]interface{}) {
+	tags := map[string]string{
+		"index": strconv.Itoa(gpu.index),
+	}
+	setTagIfUsed(tags, "name", gpu.name)
+	setTagIfUsed(tags, "uuid", gpu.uuid)
+	if gpu.pstate >= 0 {
+		tags["pstate"] = fmt.Sprintf("P%d", gpu.pstate)
+	}
+
+	fields := map[string]interface{}{}
+	setIfUsed("str", fields, "driver_version", s.driverVersion)
+	if s.cudaVersion > 0 {
+		fields["cuda_version"] = fmt.Sprintf("%d.%d", s.cudaVersion/1000, s.cudaVersion%1000/10)
+	}
+
+	for name, value := range gpu.values {
+		switch name {
+		case nvmlMemoryTotal, nvmlMemoryUsed, nvmlMemoryFree:
+			// nvidia-smi reports MiB
+			fields[name] = int(value >> 20)
+		case nvmlPowerUsage:
+			fields["power_draw"] = float64(value) / 1000
+		case nvmlEnergy:
+			fields["energy_consumption_joules"] = float64(value) / 1000
+		case nvmlThrottleReasons:
+			fields[name] = int64(value)
+			for _, reason := range throttleReasons {
+				fields[name+"_"+reason.name] = value&reason.bit != 0
+			}
+		case nvmlECCCorrected, nvmlECCUncorrected:
+			fields[name] = int64(value)
+		default:
+			fields[name] = int(value)
+		}
+	}
+	return tags, fields
+}
@@ -0,0 +1,191 @@
+// +build linux,cgo
+
+package nvidia_smi
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// readNVML is used to mock the library in tests.
+var readNVML = readNVMLSystem
+
+var (
+	nvmlOnce    sync.Once
+	nvmlInitErr error
+)
+
+// initNVML loads and initializes the library once, it stays loaded for the
+// following gathers.
+func initNVML() error {
+	nvmlOnce.Do(func() {
+		// The bindings panic if libnvidia-ml.so.1 cannot be loaded
+		defer func() {
+			if r := recover(); r != nil {
+				nvmlInitErr = fmt.Errorf("loading the NVML library failed: %v", r)
+			}
+		}()
+		if ret := nvml.Init(); ret != nvml.SUCCESS {
+			nvmlInitErr = fmt.Errorf("initializing NVML failed: %s", nvml.ErrorString(ret))
+		}
+	})
+	return nvmlInitErr
+}
+
+func readNVMLSystem() (*nvmlSystem, error) {
+	if err := initNVML(); err != nil {
+		return nil, err
+	}
+
+	count, ret := nvml.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("getting the number of GPUs failed: %s", nvml.ErrorString(ret))
+	}
+
+	system := &nvmlSystem{}
+	if version, ret := nvml.SystemGetDriverVersion(); ret == nvml.SUCCESS {
+		system.driverVersion = version
+	}
+	if version, ret := nvml.SystemGetCudaDriverVersion(); ret == nvml.SUCCESS {
+		system.cudaVersion = version
+	}
+
+	for i := 0; i < count; i++ {
+		device, ret := nvml.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("getting GPU %d failed: %s", i, nvml.ErrorString(ret))
+		}
+		system.gpus = append(system.gpus, readNVMLDevice(i, device))
+	}
+	return system, nil
+}
+
+// readNVMLDevice reads a GPU, the readings failing as not supported or
+// unavailable are left out.
+func readNVMLDevice(index int, device nvml.Device) nvmlGPU {
+	gpu := nvmlGPU{index: index, pstate: -1, values: make(map[string]uint64)}
+	if uuid, ret := device.GetUUID(); ret == nvml.SUCCESS {
+		gpu.uuid = uuid
+	}
+	if name, ret := device.GetName(); ret == nvml.SUCCESS {
+		gpu.name = name
+	}
+	if pstate, ret := device.GetPerformanceState(); ret == nvml.SUCCESS && pstate != nvml.PSTATE_UNKNOWN {
+		gpu.pstate = int(pstate)
+	}
+
+	set32 := func(name string, value uint32, ret nvml.Return) {
+		if ret == nvml.SUCCESS {
+			gpu.values[name] = uint64(value)
+		}
+	}
+	set64 := func(name string, value uint64, ret nvml.Return) {
+		if ret == nvml.SUCCESS {
+			gpu.values[name] = value
+		}
+	}
+	setInt := func(name string, value int, ret nvml.Return) {
+		if ret == nvml.SUCCESS {
+			gpu.values[name] = uint64(value)
+		}
+	}
+
+	fan, ret := device.GetFanSpeed()
+	set32(nvmlFanSpeed, fan, ret)
+	if memory, ret := device.GetMemoryInfo(); ret == nvml.SUCCESS {
+		gpu.values[nvmlMemoryTotal] = memory.Total
+		gpu.values[nvmlMemoryUsed] = memory.Used
+		gpu.values[nvmlMemoryFree] = memory.Free
+	}
+	temperature, ret := device.GetTemperature(nvml.TEMPERATURE_GPU)
+	set32(nvmlTemperature, temperature, ret)
+	if utilization, ret := device.GetUtilizationRates(); ret == nvml.SUCCESS {
+		gpu.values[nvmlUtilizationGPU] = uint64(utilization.Gpu)
+		gpu.values[nvmlUtilizationMemory] = uint64(utilization.Memory)
+	}
+	encoder, _, ret := device.GetEncoderUtilization()
+	set32(nvmlUtilizationEncoder, encoder, ret)
+	decoder, _, ret := device.GetDecoderUtilization()
+	set32(nvmlUtilizationDecoder, decoder, ret)
+	gen, ret := device.GetCurrPcieLinkGeneration()
+	setInt(nvmlPCIeGen, gen, ret)
+	width, ret := device.GetCurrPcieLinkWidth()
+	setInt(nvmlPCIeWidth, width, ret)
+	clock, ret := device.GetClockInfo(nvml.CLOCK_GRAPHICS)
+	set32(nvmlClockGraphics, clock, ret)
+	clock, ret = device.GetClockInfo(nvml.CLOCK_SM)
+	set32(nvmlClockSM, clock, ret)
+	clock, ret = device.GetClockInfo(nvml.CLOCK_MEM)
+	set32(nvmlClockMemory, clock, ret)
+	clock, ret = device.GetClockInfo(nvml.CLOCK_VIDEO)
+	set32(nvmlClockVideo, clock, ret)
+	power, ret := device.GetPowerUsage()
+	set32(nvmlPowerUsage, power, ret)
+	energy, ret := device.GetTotalEnergyConsumption()
+	set64(nvmlEnergy, energy, ret)
+	reasons, ret := device.GetCurrentClocksThrottleReasons()
+	set64(nvmlThrottleReasons, reasons, ret)
+	ecc, ret := device.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_CORRECTED, nvml.VOLATILE_ECC)
+	set64(nvmlECCCorrected, ecc, ret)
+	ecc, ret = device.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_UNCORRECTED, nvml.VOLATILE_ECC)
+	set64(nvmlECCUncorrected, ecc, ret)
+
+	migEnabled := false
+	if current, _, ret := device.GetMigMode(); ret == nvml.SUCCESS && current == nvml.DEVICE_MIG_ENABLE {
+		migEnabled = true
+		gpu.migs = readNVMLMIGDevices(device)
+	}
+
+	if processes, ret := device.GetComputeRunningProcesses(); ret == nvml.SUCCESS {
+		for _, p := range processes {
+			process := nvmlProcess{pid: p.Pid, usedMemory: p.UsedGpuMemory, gpuInstance: -1, computeInstance: -1}
+			if migEnabled {
+				process.gpuInstance = int(p.GpuInstanceId)
+				process.computeInstance = int(p.ComputeInstanceId)
+			}
+			if name, ret := nvml.SystemGetProcessName(int(p.Pid)); ret == nvml.SUCCESS {
+				process.name = name
+			}
+			gpu.processes = append(gpu.processes, process)
+		}
+	}
+	return gpu
+}
+
+// readNVMLMIGDevices reads the MIG devices of a GPU in MIG mode.  The
+// utilization of the MIG devices is not available through NVML but through
+// the profiling metrics of DCGM.
+func readNVMLMIGDevices(device nvml.Device) []nvmlMIGDevice {
+	count, ret := device.GetMaxMigDeviceCount()
+	if ret != nvml.SUCCESS {
+		return nil
+	}
+
+	var migs []nvmlMIGDevice
+	for i := 0; i < count; i++ {
+		// The indices of the MIG devices not created are not found
+		handle, ret := device.GetMigDeviceHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			continue
+		}
+		mig := nvmlMIGDevice{index: i, gpuInstance: -1, computeInstance: -1, values: make(map[string]uint64)}
+		if uuid, ret := handle.GetUUID(); ret == nvml.SUCCESS {
+			mig.uuid = uuid
+		}
+		if id, ret := handle.GetGpuInstanceId(); ret == nvml.SUCCESS {
+			mig.gpuInstance = id
+		}
+		if id, ret := handle.GetComputeInstanceId(); ret == nvml.SUCCESS {
+			mig.computeInstance = id
+		}
+		if memory, ret := handle.GetMemoryInfo(); ret == nvml.SUCCESS {
+			mig.values[nvmlMemoryTotal] = memory.Total
+			mig.values[nvmlMemoryUsed] = memory.Used
+			mig.values[nvmlMemoryFree] = memory.Free
+		}
+		migs = append(migs, mig)
+	}
+	return migs
+}
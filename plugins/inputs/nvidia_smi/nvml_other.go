@@ -0,0 +1,10 @@
+// +build !linux !cgo
+
+package nvidia_smi
+
+import "fmt"
+
+// readNVML is used to mock the library in tests.
+var readNVML = func() (*nvmlSystem, error) {
+	return nil, fmt.Errorf("reading the GPUs through NVML requires Linux and a build with cgo")
+}
@@ -0,0 +1,220 @@
+package nvidia_smi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherNVML(t *testing.T) {
+	defer func(f func() (*nvmlSystem, error)) { readNVML = f }(readNVML)
+	readNVML = func() (*nvmlSystem, error) {
+		return &nvmlSystem{
+			driverVersion: "450.80.02",
+			cudaVersion:   11000,
+			gpus: []nvmlGPU{
+				{
+					index:  0,
+					uuid:   "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+					name:   "A100-SXM4-40GB",
+					pstate: 0,
+					values: map[string]uint64{
+						nvmlMemoryTotal:        42505273344,
+						nvmlMemoryUsed:         3145728000,
+						nvmlMemoryFree:         39359545344,
+						nvmlTemperature:        61,
+						nvmlUtilizationGPU:     98,
+						nvmlUtilizationMemory:  45,
+						nvmlUtilizationEncoder: 0,
+						nvmlUtilizationDecoder: 0,
+						nvmlPCIeGen:            4,
+						nvmlPCIeWidth:          16,
+						nvmlClockGraphics:      1410,
+						nvmlClockSM:            1410,
+						nvmlClockMemory:        1215,
+						nvmlClockVideo:         1275,
+						nvmlPowerUsage:         312500,
+						nvmlEnergy:             8021937461,
+						nvmlThrottleReasons:    0x24,
+						nvmlECCCorrected:       3,
+						nvmlECCUncorrected:     0,
+					},
+					processes: []nvmlProcess{
+						{pid: 4242, name: "python3", usedMemory: 3140485120, gpuInstance: -1, computeInstance: -1},
+						{pid: 4243, usedMemory: nvmlValueNotAvailable, gpuInstance: -1, computeInstance: -1},
+					},
+				},
+				{
+					index:  1,
+					pstate: -1,
+					values: map[string]uint64{nvmlFanSpeed: 30},
+				},
+			},
+		}, nil
+	}
+
+	smi := &NvidiaSMI{UseNVML: true}
+	var acc testutil.Accumulator
+	require.NoError(t, smi.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("nvidia_smi",
+			map[string]string{
+				"index":  "0",
+				"name":   "A100-SXM4-40GB",
+				"uuid":   "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"pstate": "P0",
+			},
+			map[string]interface{}{
+				"driver_version":                   "450.80.02",
+				"cuda_version":                     "11.0",
+				"memory_total":                     40536,
+				"memory_used":                      3000,
+				"memory_free":                      37536,
+				"temperature_gpu":                  61,
+				"utilization_gpu":                  98,
+				"utilization_memory":               45,
+				"utilization_encoder":              0,
+				"utilization_decoder":              0,
+				"pcie_link_gen_current":            4,
+				"pcie_link_width_current":          16,
+				"clocks_current_graphics":          1410,
+				"clocks_current_sm":                1410,
+				"clocks_current_memory":            1215,
+				"clocks_current_video":             1275,
+				"power_draw":                       312.5,
+				"energy_consumption_joules":        8021937.461,
+				"clocks_throttle_reasons":          int64(0x24),
+				"clocks_throttle_reasons_gpu_idle": false,
+				"clocks_throttle_reasons_applications_clocks_setting": false,
+				"clocks_throttle_reasons_sw_power_cap":                true,
+				"clocks_throttle_reasons_hw_slowdown":                 false,
+				"clocks_throttle_reasons_sync_boost":                  false,
+				"clocks_throttle_reasons_sw_thermal_slowdown":         true,
+				"clocks_throttle_reasons_hw_thermal_slowdown":         false,
+				"clocks_throttle_reasons_hw_power_brake_slowdown":     false,
+				"clocks_throttle_reasons_display_clock_setting":       false,
+				"ecc_errors_corrected_volatile":                       int64(3),
+				"ecc_errors_uncorrected_volatile":                     int64(0),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("nvidia_smi_process",
+			map[string]string{
+				"index":        "0",
+				"uuid":         "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"pid":          "4242",
+				"process_name": "python3",
+			},
+			map[string]interface{}{
+				"used_memory": 2995,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("nvidia_smi",
+			map[string]string{
+				"index": "1",
+			},
+			map[string]interface{}{
+				"driver_version": "450.80.02",
+				"cuda_version":   "11.0",
+				"fan_speed":      30,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherNVMLMIG(t *testing.T) {
+	defer func(f func() (*nvmlSystem, error)) { readNVML = f }(readNVML)
+	readNVML = func() (*nvmlSystem, error) {
+		return &nvmlSystem{
+			gpus: []nvmlGPU{
+				{
+					index:  0,
+					uuid:   "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+					name:   "A100-SXM4-40GB",
+					pstate: 0,
+					values: map[string]uint64{nvmlTemperature: 47},
+					processes: []nvmlProcess{
+						{pid: 28177, name: "python3", usedMemory: 3813670912, gpuInstance: 1, computeInstance: 0},
+					},
+					migs: []nvmlMIGDevice{
+						{
+							index:           0,
+							uuid:            "MIG-GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52/1/0",
+							gpuInstance:     1,
+							computeInstance: 0,
+							values: map[string]uint64{
+								nvmlMemoryTotal: 21072183296,
+								nvmlMemoryUsed:  3817865216,
+								nvmlMemoryFree:  17254318080,
+							},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	smi := &NvidiaSMI{UseNVML: true}
+	var acc testutil.Accumulator
+	require.NoError(t, smi.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("nvidia_smi",
+			map[string]string{
+				"index":  "0",
+				"name":   "A100-SXM4-40GB",
+				"uuid":   "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"pstate": "P0",
+			},
+			map[string]interface{}{
+				"temperature_gpu": 47,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("nvidia_smi_process",
+			map[string]string{
+				"index":            "0",
+				"uuid":             "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"pid":              "28177",
+				"process_name":     "python3",
+				"gpu_instance":     "1",
+				"compute_instance": "0",
+			},
+			map[string]interface{}{
+				"used_memory": 3637,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("nvidia_smi_mig",
+			map[string]string{
+				"index":            "0",
+				"mig_index":        "0",
+				"name":             "A100-SXM4-40GB",
+				"uuid":             "GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52",
+				"mig_uuid":         "MIG-GPU-604ac76c-d9cf-fef3-62e9-d92044ab6e52/1/0",
+				"gpu_instance":     "1",
+				"compute_instance": "0",
+			},
+			map[string]interface{}{
+				"memory_total": 20096,
+				"memory_used":  3641,
+				"memory_free":  16455,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherNVMLError(t *testing.T) {
+	defer func(f func() (*nvmlSystem, error)) { readNVML = f }(readNVML)
+	readNVML = func() (*nvmlSystem, error) {
+		return nil, errors.New("loading the NVML library failed")
+	}
+
+	smi := &NvidiaSMI{UseNVML: true, BinPath: "/nonexistent/nvidia-smi"}
+	var acc testutil.Accumulator
+	require.EqualError(t, smi.Gather(&acc), "loading the NVML library failed")
+}
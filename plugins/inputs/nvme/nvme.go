@@ -0,0 +1,232 @@
+package nvme
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Admin commands and their parameters of the NVMe specification.
+const (
+	logPageSMART = 0x02
+
+	identifyNamespace      = 0x00
+	identifyController     = 0x01
+	identifyNamespaceList  = 0x02
+	identifySize           = 4096
+	smartLogSize           = 512
+	dataUnitBytes          = 512 * 1000
+	kelvinToCelsius        = 273
+	maxNamespacesInListing = identifySize / 4
+)
+
+// controllerRegexp matches the character devices of the controllers, not
+// those of the namespaces such as nvme0n1.
+var controllerRegexp = regexp.MustCompile(`^nvme\d+$`)
+
+// controller sends admin commands to an NVMe controller.
+type controller interface {
+	identify(cns uint32, nsid uint32, buf []byte) error
+	getLogPage(lid uint32, nsid uint32, buf []byte) error
+	Close() error
+}
+
+// openController is used to mock the controllers in tests.
+var openController = openDevice
+
+type NVMe struct {
+	Devices    []string `toml:"devices"`
+	Namespaces bool     `toml:"namespaces"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## Character devices of the NVMe controllers, by default all of them.
+  # devices = ["/dev/nvme0"]
+
+  ## Report the size and utilization of the namespaces of each controller.
+  # namespaces = true
+`
+
+func (n *NVMe) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NVMe) Description() string {
+	return "Read the SMART/health log of NVMe drives through ioctls, without smartctl"
+}
+
+func (n *NVMe) Gather(acc telegraf.Accumulator) error {
+	devices := n.Devices
+	if len(devices) == 0 {
+		matches, err := filepath.Glob("/dev/nvme[0-9]*")
+		if err != nil {
+			return err
+		}
+		for _, path := range matches {
+			if controllerRegexp.MatchString(filepath.Base(path)) {
+				devices = append(devices, path)
+			}
+		}
+		sort.Strings(devices)
+	}
+
+	for _, path := range devices {
+		if err := n.gatherController(acc, path); err != nil {
+			acc.AddError(fmt.Errorf("%s: %v", path, err))
+		}
+	}
+	return nil
+}
+
+func (n *NVMe) gatherController(acc telegraf.Accumulator, path string) error {
+	c, err := openController(path)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	buf := make([]byte, identifySize)
+	if err := c.identify(identifyController, 0, buf); err != nil {
+		return fmt.Errorf("identify controller failed: %v", err)
+	}
+	tags := map[string]string{"device": filepath.Base(path)}
+	serial, model, firmware := parseIdentifyController(buf)
+	setTagIfUsed(tags, "serial", serial)
+	setTagIfUsed(tags, "model", model)
+
+	log := make([]byte, smartLogSize)
+	if err := c.getLogPage(logPageSMART, 0xffffffff, log); err != nil {
+		return fmt.Errorf("reading the SMART log failed: %v", err)
+	}
+	fields := parseSMARTLog(log)
+	if firmware != "" {
+		fields["firmware"] = firmware
+	}
+	acc.AddFields("nvme", fields, tags)
+
+	if n.Namespaces {
+		return n.gatherNamespaces(acc, c, tags)
+	}
+	return nil
+}
+
+func (n *NVMe) gatherNamespaces(acc telegraf.Accumulator, c controller, tags map[string]string) error {
+	list := make([]byte, identifySize)
+	if err := c.identify(identifyNamespaceList, 0, list); err != nil {
+		return fmt.Errorf("listing the namespaces failed: %v", err)
+	}
+	for i := 0; i < maxNamespacesInListing; i++ {
+		nsid := binary.LittleEndian.Uint32(list[4*i:])
+		// The list ends with the first unused entry
+		if nsid == 0 {
+			break
+		}
+
+		buf := make([]byte, identifySize)
+		if err := c.identify(identifyNamespace, nsid, buf); err != nil {
+			acc.AddError(fmt.Errorf("identify namespace %d failed: %v", nsid, err))
+			continue
+		}
+		fields := parseIdentifyNamespace(buf)
+		if fields == nil {
+			continue
+		}
+		nsTags := map[string]string{"nsid": strconv.FormatUint(uint64(nsid), 10)}
+		for k, v := range tags {
+			nsTags[k] = v
+		}
+		acc.AddFields("nvme_namespace", fields, nsTags)
+	}
+	return nil
+}
+
+// parseIdentifyController returns the serial number, model and firmware
+// revision of the identify controller data, padded with spaces.
+func parseIdentifyController(buf []byte) (string, string, string) {
+	text := func(b []byte) string {
+		return string(bytes.TrimRight(bytes.TrimRight(b, "\x00"), " "))
+	}
+	return text(buf[4:24]), text(buf[24:64]), text(buf[64:72])
+}
+
+// parseIdentifyNamespace returns the size of the namespace, its capacity and
+// the space used in bytes, from their number of logical blocks.
+func parseIdentifyNamespace(buf []byte) map[string]interface{} {
+	size := binary.LittleEndian.Uint64(buf[0:])
+	capacity := binary.LittleEndian.Uint64(buf[8:])
+	used := binary.LittleEndian.Uint64(buf[16:])
+	// The format in use and its LBA data size as a power of two
+	format := int(buf[26] & 0x0f)
+	lbads := buf[128+4*format+2]
+	if size == 0 || lbads < 9 {
+		return nil
+	}
+	blockSize := uint64(1) << lbads
+	return map[string]interface{}{
+		"size_bytes":     size * blockSize,
+		"capacity_bytes": capacity * blockSize,
+		"used_bytes":     used * blockSize,
+		"utilization":    float64(used) / float64(capacity) * 100,
+	}
+}
+
+// parseSMARTLog converts the SMART/health information log page.  The 128
+// bit counters are read as 64 bit, they would overflow after more than a
+// zettabyte.
+func parseSMARTLog(log []byte) map[string]interface{} {
+	u16 := func(i int) uint16 { return binary.LittleEndian.Uint16(log[i:]) }
+	u32 := func(i int) uint32 { return binary.LittleEndian.Uint32(log[i:]) }
+	u64 := func(i int) uint64 { return binary.LittleEndian.Uint64(log[i:]) }
+
+	fields := map[string]interface{}{
+		"critical_warning":                  int64(log[0]),
+		"temperature":                       int64(u16(1)) - kelvinToCelsius,
+		"available_spare":                   int64(log[3]),
+		"available_spare_threshold":         int64(log[4]),
+		"percentage_used":                   int64(log[5]),
+		"data_read_bytes":                   u64(32) * dataUnitBytes,
+		"data_written_bytes":                u64(48) * dataUnitBytes,
+		"host_read_commands":                u64(64),
+		"host_write_commands":               u64(80),
+		"controller_busy_time":              u64(96),
+		"power_cycles":                      u64(112),
+		"power_on_hours":                    u64(128),
+		"unsafe_shutdowns":                  u64(144),
+		"media_errors":                      u64(160),
+		"error_log_entries":                 u64(176),
+		"warning_temperature_time":          int64(u32(192)),
+		"critical_temperature_time":         int64(u32(196)),
+		"thermal_management_t1_transitions": int64(u32(216)),
+		"thermal_management_t2_transitions": int64(u32(220)),
+		"thermal_management_t1_time":        int64(u32(224)),
+		"thermal_management_t2_time":        int64(u32(228)),
+	}
+	// Temperature sensors, 0 when not implemented
+	for i := 0; i < 8; i++ {
+		if t := u16(200 + 2*i); t != 0 {
+			fields["temperature_sensor_"+strconv.Itoa(i+1)] = int64(t) - kelvinToCelsius
+		}
+	}
+	return fields
+}
+
+func setTagIfUsed(tags map[string]string, name, value string) {
+	if value != "" {
+		tags[name] = value
+	}
+}
+
+func init() {
+	inputs.Add("nvme", func() telegraf.Input {
+		return &NVMe{Namespaces: true}
+	})
+}
@@ -0,0 +1,83 @@
+// +build linux
+
+package nvme
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	adminGetLogPage = 0x02
+	adminIdentify   = 0x06
+
+	// _IOWR('N', 0x41, struct nvme_admin_cmd)
+	nvmeIoctlAdminCmd = 0xc0484e41
+)
+
+// passthruCmd is the struct nvme_passthru_cmd of linux/nvme_ioctl.h.
+type passthruCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+type device struct {
+	file *os.File
+}
+
+func openDevice(path string) (controller, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &device{file: file}, nil
+}
+
+func (d *device) identify(cns uint32, nsid uint32, buf []byte) error {
+	return d.adminCommand(&passthruCmd{opcode: adminIdentify, nsid: nsid, cdw10: cns}, buf)
+}
+
+func (d *device) getLogPage(lid uint32, nsid uint32, buf []byte) error {
+	// The number of dwords to read, zero based, and the log page
+	numd := uint32(len(buf)/4 - 1)
+	return d.adminCommand(&passthruCmd{opcode: adminGetLogPage, nsid: nsid, cdw10: numd<<16 | lid}, buf)
+}
+
+func (d *device) adminCommand(cmd *passthruCmd, buf []byte) error {
+	cmd.addr = uint64(uintptr(unsafe.Pointer(&buf[0])))
+	cmd.dataLen = uint32(len(buf))
+	status, _, errno := unix.Syscall(unix.SYS_IOCTL, d.file.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(cmd)))
+	runtime.KeepAlive(buf)
+	if errno != 0 {
+		return errno
+	}
+	// A positive value is the status of the command
+	if status != 0 {
+		return fmt.Errorf("command failed with status 0x%x", status)
+	}
+	return nil
+}
+
+func (d *device) Close() error {
+	return d.file.Close()
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package nvme
+
+import "fmt"
+
+func openDevice(path string) (controller, error) {
+	return nil, fmt.Errorf("reading NVMe devices is only supported on Linux")
+}
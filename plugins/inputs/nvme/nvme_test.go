@@ -0,0 +1,160 @@
+package nvme
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockController struct {
+	identifyData map[uint32][]byte
+	namespaces   map[uint32][]byte
+	smartLog     []byte
+}
+
+func (m *mockController) identify(cns uint32, nsid uint32, buf []byte) error {
+	var data []byte
+	if cns == identifyNamespace {
+		data = m.namespaces[nsid]
+	} else {
+		data = m.identifyData[cns]
+	}
+	if data == nil {
+		return fmt.Errorf("command failed with status 0x2")
+	}
+	copy(buf, data)
+	return nil
+}
+
+func (m *mockController) getLogPage(lid uint32, nsid uint32, buf []byte) error {
+	copy(buf, m.smartLog)
+	return nil
+}
+
+func (m *mockController) Close() error {
+	return nil
+}
+
+func newMockController() *mockController {
+	ctrl := make([]byte, identifySize)
+	copy(ctrl[4:], "S4EWNX0N123456      ")
+	copy(ctrl[24:], "Samsung SSD 970 EVO Plus 1TB            ")
+	copy(ctrl[64:], "2B2QEXM7")
+
+	list := make([]byte, identifySize)
+	binary.LittleEndian.PutUint32(list[0:], 1)
+
+	// 1000 blocks of 4096 bytes, 250 used, in the second LBA format
+	ns := make([]byte, identifySize)
+	binary.LittleEndian.PutUint64(ns[0:], 1000)
+	binary.LittleEndian.PutUint64(ns[8:], 1000)
+	binary.LittleEndian.PutUint64(ns[16:], 250)
+	ns[26] = 1
+	ns[128+2] = 9
+	ns[132+2] = 12
+
+	log := make([]byte, smartLogSize)
+	log[0] = 0x02
+	binary.LittleEndian.PutUint16(log[1:], 310)
+	log[3] = 100
+	log[4] = 10
+	log[5] = 3
+	binary.LittleEndian.PutUint64(log[32:], 2000)
+	binary.LittleEndian.PutUint64(log[48:], 4000)
+	binary.LittleEndian.PutUint64(log[64:], 123456)
+	binary.LittleEndian.PutUint64(log[80:], 654321)
+	binary.LittleEndian.PutUint64(log[96:], 42)
+	binary.LittleEndian.PutUint64(log[112:], 17)
+	binary.LittleEndian.PutUint64(log[128:], 1234)
+	binary.LittleEndian.PutUint64(log[144:], 5)
+	binary.LittleEndian.PutUint64(log[160:], 1)
+	binary.LittleEndian.PutUint64(log[176:], 8)
+	binary.LittleEndian.PutUint32(log[192:], 3)
+	binary.LittleEndian.PutUint32(log[196:], 0)
+	binary.LittleEndian.PutUint16(log[200:], 310)
+	binary.LittleEndian.PutUint16(log[202:], 318)
+	binary.LittleEndian.PutUint32(log[216:], 12)
+	binary.LittleEndian.PutUint32(log[220:], 2)
+	binary.LittleEndian.PutUint32(log[224:], 600)
+	binary.LittleEndian.PutUint32(log[228:], 30)
+
+	return &mockController{
+		identifyData: map[uint32][]byte{
+			identifyController:    ctrl,
+			identifyNamespaceList: list,
+		},
+		namespaces: map[uint32][]byte{1: ns},
+		smartLog:   log,
+	}
+}
+
+func TestGather(t *testing.T) {
+	openController = func(path string) (controller, error) {
+		if path != "/dev/nvme0" {
+			return nil, fmt.Errorf("open %s: no such file or directory", path)
+		}
+		return newMockController(), nil
+	}
+	defer func() { openController = openDevice }()
+
+	n := &NVMe{Devices: []string{"/dev/nvme0"}, Namespaces: true}
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	tags := map[string]string{
+		"device": "nvme0",
+		"serial": "S4EWNX0N123456",
+		"model":  "Samsung SSD 970 EVO Plus 1TB",
+	}
+	acc.AssertContainsTaggedFields(t, "nvme", map[string]interface{}{
+		"critical_warning":                  int64(2),
+		"temperature":                       int64(37),
+		"available_spare":                   int64(100),
+		"available_spare_threshold":         int64(10),
+		"percentage_used":                   int64(3),
+		"data_read_bytes":                   uint64(1024000000),
+		"data_written_bytes":                uint64(2048000000),
+		"host_read_commands":                uint64(123456),
+		"host_write_commands":               uint64(654321),
+		"controller_busy_time":              uint64(42),
+		"power_cycles":                      uint64(17),
+		"power_on_hours":                    uint64(1234),
+		"unsafe_shutdowns":                  uint64(5),
+		"media_errors":                      uint64(1),
+		"error_log_entries":                 uint64(8),
+		"warning_temperature_time":          int64(3),
+		"critical_temperature_time":         int64(0),
+		"thermal_management_t1_transitions": int64(12),
+		"thermal_management_t2_transitions": int64(2),
+		"thermal_management_t1_time":        int64(600),
+		"thermal_management_t2_time":        int64(30),
+		"temperature_sensor_1":              int64(37),
+		"temperature_sensor_2":              int64(45),
+		"firmware":                          "2B2QEXM7",
+	}, tags)
+
+	tags["nsid"] = "1"
+	acc.AssertContainsTaggedFields(t, "nvme_namespace", map[string]interface{}{
+		"size_bytes":     uint64(4096000),
+		"capacity_bytes": uint64(4096000),
+		"used_bytes":     uint64(1024000),
+		"utilization":    float64(25),
+	}, tags)
+}
+
+func TestGatherMissingDevice(t *testing.T) {
+	openController = func(path string) (controller, error) {
+		return nil, fmt.Errorf("open %s: no such file or directory", path)
+	}
+	defer func() { openController = openDevice }()
+
+	n := &NVMe{Devices: []string{"/dev/nvme1"}}
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, 0, len(acc.Metrics))
+}
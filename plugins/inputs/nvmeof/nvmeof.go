@@ -0,0 +1,265 @@
+package nvmeof
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// NVMeoF stores the configuration values for the nvmeof input plugin
+type NVMeoF struct {
+	NVMePath   string `toml:"nvme_path"`
+	TargetPath string `toml:"target_path"`
+	DebugPath  string `toml:"debug_path"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	// Last state seen of the controllers of the initiator, by name
+	controllers map[string]*controllerState
+}
+
+// controllerState tracks a host controller across intervals to count its
+// reconnections, which the kernel does not expose.
+type controllerState struct {
+	state        string
+	cntlid       string
+	stateChanges int64
+	reconnects   int64
+}
+
+var sampleConfig = `
+  ## Path of the nvme class in sysfs, listing the controllers of the
+  ## initiator.  The controllers of local PCIe drives are ignored.
+  # nvme_path = "/sys/class/nvme"
+
+  ## Path of the nvmet configfs of the target.
+  # target_path = "/sys/kernel/config/nvmet"
+
+  ## Path of the nvmet debugfs, listing the controllers of the hosts
+  ## connected to the target since Linux 6.14.
+  # debug_path = "/sys/kernel/debug/nvmet"
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (n *NVMeoF) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (n *NVMeoF) Description() string {
+	return "Read the state of the NVMe over Fabrics controllers of initiators and targets"
+}
+
+// Init sets the defaults.
+func (n *NVMeoF) Init() error {
+	if n.NVMePath == "" {
+		n.NVMePath = "/sys/class/nvme"
+	}
+	if n.TargetPath == "" {
+		n.TargetPath = "/sys/kernel/config/nvmet"
+	}
+	if n.DebugPath == "" {
+		n.DebugPath = "/sys/kernel/debug/nvmet"
+	}
+	n.controllers = make(map[string]*controllerState)
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (n *NVMeoF) Gather(acc telegraf.Accumulator) error {
+	if err := n.gatherInitiator(acc); err != nil {
+		acc.AddError(err)
+	}
+	if err := n.gatherTarget(acc); err != nil {
+		acc.AddError(err)
+	}
+	return nil
+}
+
+func (n *NVMeoF) gatherInitiator(acc telegraf.Accumulator) error {
+	dirs, err := filepath.Glob(filepath.Join(n.NVMePath, "nvme[0-9]*"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(dirs)
+
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		transport, err := readString(filepath.Join(dir, "transport"))
+		if err != nil {
+			acc.AddError(fmt.Errorf("reading %s: %v", dir, err))
+			continue
+		}
+		if transport == "pcie" {
+			continue
+		}
+
+		name := filepath.Base(dir)
+		seen[name] = true
+		tags := map[string]string{
+			"controller": name,
+			"transport":  transport,
+		}
+		if nqn, err := readString(filepath.Join(dir, "subsysnqn")); err == nil {
+			tags["subsysnqn"] = nqn
+		}
+		if address, err := readString(filepath.Join(dir, "address")); err == nil {
+			for k, v := range parseAddress(address) {
+				if k == "traddr" || k == "trsvcid" {
+					tags[k] = v
+				}
+			}
+		}
+
+		state, _ := readString(filepath.Join(dir, "state"))
+		cntlid, _ := readString(filepath.Join(dir, "cntlid"))
+		c := n.update(name, state, cntlid)
+
+		fields := map[string]interface{}{
+			"state":         state,
+			"state_changes": c.stateChanges,
+			"reconnects":    c.reconnects,
+		}
+		for _, file := range []string{"queue_count", "sqsize", "kato", "reconnect_delay", "ctrl_loss_tmo", "fast_io_fail_tmo"} {
+			// The timeouts are "off" when disabled
+			if v, err := readInt(filepath.Join(dir, file)); err == nil {
+				fields[file] = v
+			}
+		}
+		acc.AddFields("nvmeof_controller", fields, tags)
+	}
+
+	// Forget the controllers deleted, their names are reused
+	for name := range n.controllers {
+		if !seen[name] {
+			delete(n.controllers, name)
+		}
+	}
+	return nil
+}
+
+// update records the state of a controller.  Every association with the
+// target gets a new controller id, a controller going back to live or with
+// a new id has reconnected.
+func (n *NVMeoF) update(name, state, cntlid string) *controllerState {
+	c, ok := n.controllers[name]
+	if !ok {
+		c = &controllerState{state: state, cntlid: cntlid}
+		n.controllers[name] = c
+		return c
+	}
+	if state != c.state {
+		c.stateChanges++
+	}
+	if state == "live" && (c.state != "live" || (cntlid != c.cntlid && c.cntlid != "")) {
+		c.reconnects++
+	}
+	c.state = state
+	c.cntlid = cntlid
+	return c
+}
+
+func (n *NVMeoF) gatherTarget(acc telegraf.Accumulator) error {
+	subsystems, err := ioutil.ReadDir(filepath.Join(n.TargetPath, "subsystems"))
+	if err != nil {
+		// Not a target, or the nvmet module is not loaded
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Count the ports each subsystem is exported through
+	ports := make(map[string]int64)
+	portDirs, _ := filepath.Glob(filepath.Join(n.TargetPath, "ports", "*"))
+	for _, port := range portDirs {
+		links, _ := ioutil.ReadDir(filepath.Join(port, "subsystems"))
+		for _, link := range links {
+			ports[link.Name()]++
+		}
+		n.gatherPort(acc, port, int64(len(links)))
+	}
+
+	for _, subsystem := range subsystems {
+		nqn := subsystem.Name()
+		dir := filepath.Join(n.TargetPath, "subsystems", nqn)
+		fields := map[string]interface{}{
+			"ports": ports[nqn],
+		}
+
+		namespaces, _ := filepath.Glob(filepath.Join(dir, "namespaces", "*"))
+		var enabled int64
+		for _, ns := range namespaces {
+			if v, err := readInt(filepath.Join(ns, "enable")); err == nil && v == 1 {
+				enabled++
+			}
+		}
+		fields["namespaces"] = int64(len(namespaces))
+		fields["namespaces_enabled"] = enabled
+
+		hosts, _ := ioutil.ReadDir(filepath.Join(dir, "allowed_hosts"))
+		fields["allowed_hosts"] = int64(len(hosts))
+		if v, err := readInt(filepath.Join(dir, "attr_allow_any_host")); err == nil {
+			fields["allow_any_host"] = v == 1
+		}
+
+		// The controllers connected are only listed in debugfs
+		if ctrls, err := filepath.Glob(filepath.Join(n.DebugPath, nqn, "ctrl[0-9]*")); err == nil && ctrls != nil {
+			fields["controllers"] = int64(len(ctrls))
+		}
+		acc.AddFields("nvmeof_target_subsystem", fields, map[string]string{"subsysnqn": nqn})
+	}
+	return nil
+}
+
+func (n *NVMeoF) gatherPort(acc telegraf.Accumulator, dir string, subsystems int64) {
+	tags := map[string]string{"port": filepath.Base(dir)}
+	for _, name := range []string{"trtype", "traddr", "trsvcid"} {
+		if v, err := readString(filepath.Join(dir, "addr_"+name)); err == nil && v != "" {
+			tags[name] = v
+		}
+	}
+	acc.AddFields("nvmeof_target_port", map[string]interface{}{"subsystems": subsystems}, tags)
+}
+
+// parseAddress parses the address of a controller, such as
+// "traddr=192.168.0.10,trsvcid=4420,src_addr=192.168.0.1".
+func parseAddress(address string) map[string]string {
+	values := make(map[string]string)
+	for _, kv := range strings.Split(address, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return values
+}
+
+func readString(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readInt(path string) (int64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("nvmeof", func() telegraf.Input {
+		return &NVMeoF{}
+	})
+}
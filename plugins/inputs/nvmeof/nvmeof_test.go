@@ -0,0 +1,143 @@
+package nvmeof
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherInitiator(t *testing.T) {
+	n := &NVMeoF{NVMePath: "testdata/initiator", TargetPath: "testdata/initiator/nvmet"}
+	require.NoError(t, n.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "nvmeof_controller",
+		map[string]interface{}{
+			"state":           "live",
+			"state_changes":   int64(0),
+			"reconnects":      int64(0),
+			"queue_count":     int64(33),
+			"sqsize":          int64(127),
+			"kato":            int64(5),
+			"reconnect_delay": int64(10),
+			"ctrl_loss_tmo":   int64(600),
+		},
+		map[string]string{
+			"controller": "nvme1",
+			"transport":  "rdma",
+			"subsysnqn":  "nqn.2020-01.org.example:scratch",
+			"traddr":     "192.168.0.10",
+			"trsvcid":    "4420",
+		})
+}
+
+func TestGatherReconnect(t *testing.T) {
+	n := &NVMeoF{TargetPath: "testdata/reconnect/nvmet"}
+	require.NoError(t, n.Init())
+
+	// The controller loses its connection and reconnects with a new id
+	tests := []struct {
+		state         string
+		stateChanges  int64
+		reconnects    int64
+		expectedState string
+	}{
+		{"live", 0, 0, "live"},
+		{"connecting", 1, 0, "connecting"},
+		{"reconnected", 2, 1, "live"},
+	}
+	for _, tt := range tests {
+		n.NVMePath = filepath.Join("testdata", "reconnect", tt.state)
+
+		var acc testutil.Accumulator
+		require.NoError(t, n.Gather(&acc))
+		require.Empty(t, acc.Errors)
+		acc.AssertContainsTaggedFields(t, "nvmeof_controller",
+			map[string]interface{}{
+				"state":         tt.expectedState,
+				"state_changes": tt.stateChanges,
+				"reconnects":    tt.reconnects,
+			},
+			map[string]string{"controller": "nvme1", "transport": "rdma"})
+	}
+}
+
+// createTarget creates the configfs and debugfs trees of a target, they are
+// not checked in as NQNs are not valid file names on Windows.
+func createTarget(t *testing.T, dir string, nqn string) {
+	subsystem := filepath.Join(dir, "nvmet", "subsystems", nqn)
+	port := filepath.Join(dir, "nvmet", "ports", "1")
+	for _, d := range []string{
+		filepath.Join(subsystem, "namespaces", "1"),
+		filepath.Join(subsystem, "namespaces", "2"),
+		filepath.Join(subsystem, "allowed_hosts", "nqn.2020-01.org.example:node01"),
+		filepath.Join(subsystem, "allowed_hosts", "nqn.2020-01.org.example:node02"),
+		filepath.Join(port, "subsystems", nqn),
+		filepath.Join(dir, "debug", nqn, "ctrl1"),
+		filepath.Join(dir, "debug", nqn, "ctrl2"),
+	} {
+		require.NoError(t, os.MkdirAll(d, 0755))
+	}
+
+	files := map[string]string{
+		filepath.Join(subsystem, "attr_allow_any_host"):       "0",
+		filepath.Join(subsystem, "namespaces", "1", "enable"): "1",
+		filepath.Join(subsystem, "namespaces", "2", "enable"): "0",
+		filepath.Join(port, "addr_trtype"):                    "rdma",
+		filepath.Join(port, "addr_traddr"):                    "192.168.0.10",
+		filepath.Join(port, "addr_trsvcid"):                   "4420",
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(name, []byte(content+"\n"), 0644))
+	}
+}
+
+func TestGatherTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvmeof")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	nqn := "nqn.2020-01.org.example:scratch"
+	createTarget(t, dir, nqn)
+
+	n := &NVMeoF{
+		NVMePath:   filepath.Join(dir, "nvme"),
+		TargetPath: filepath.Join(dir, "nvmet"),
+		DebugPath:  filepath.Join(dir, "debug"),
+	}
+	require.NoError(t, n.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "nvmeof_target_subsystem",
+		map[string]interface{}{
+			"ports":              int64(1),
+			"namespaces":         int64(2),
+			"namespaces_enabled": int64(1),
+			"allowed_hosts":      int64(2),
+			"allow_any_host":     false,
+			"controllers":        int64(2),
+		},
+		map[string]string{"subsysnqn": nqn},
+	)
+	acc.AssertContainsTaggedFields(t, "nvmeof_target_port",
+		map[string]interface{}{"subsystems": int64(1)},
+		map[string]string{
+			"port":    "1",
+			"trtype":  "rdma",
+			"traddr":  "192.168.0.10",
+			"trsvcid": "4420",
+		},
+	)
+}
@@ -0,0 +1,405 @@
+package ome_power
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement = "ome_power"
+
+	entityDevice = "device"
+	entityGroup  = "group"
+
+	// timestampFormat is the format of the metric timestamps, in UTC.
+	timestampFormat = "2006-01-02 15:04:05.999999"
+)
+
+// metricTypes are the Power Manager metric types gathered and their fields.
+var metricTypes = map[int]string{
+	1: "max_power",
+	2: "min_power",
+	3: "average_power",
+	4: "instant_power",
+	5: "max_inlet_temperature",
+	6: "min_inlet_temperature",
+	7: "average_inlet_temperature",
+	8: "instant_inlet_temperature",
+}
+
+// durations are the history durations of the Power Manager metrics API.
+var durations = map[string]int{
+	"recent": 0,
+	"1h":     1,
+	"6h":     2,
+	"12h":    3,
+	"1d":     4,
+	"7d":     5,
+}
+
+var sampleConfig = `
+  ## URL of the OpenManage Enterprise appliance
+  url = "https://ome.example.com"
+
+  ## Credentials of a user allowed to read the Power Manager metrics
+  username = "telegraf"
+  password = ""
+
+  ## Ids of the devices and groups to gather the power and thermal history
+  ## of, as listed by /api/DeviceService/Devices and /api/GroupService/Groups
+  devices = []
+  groups = []
+
+  ## Duration of the history pulled every interval, one of recent, 1h, 6h,
+  ## 12h, 1d or 7d.  Samples already gathered are skipped.
+  # duration = "1h"
+
+  ## Id of the Power Manager plugin, as listed by /api/PluginService/Plugins
+  # plugin_id = "2F6D05BE-EC4D-4C07-8ED1-6A0A2A6C8E34"
+
+  ## Timeout for HTTP requests
+  # timeout = "20s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Power Manager aggregates the readings every few minutes, there is no
+  ## need to query it more often.
+  interval = "5m"
+`
+
+// OMEPower gathers the power and thermal history collected by the Power
+// Manager plugin of Dell EMC OpenManage Enterprise.
+type OMEPower struct {
+	URL      string `toml:"url"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	Devices  []int  `toml:"devices"`
+	Groups   []int  `toml:"groups"`
+	Duration string `toml:"duration"`
+	PluginID string `toml:"plugin_id"`
+	httpconfig.HTTPClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+
+	// loginMu serializes logins, mu guards the session token, the names of
+	// the entities and the timestamp of the last sample gathered of each.
+	loginMu sync.Mutex
+	mu      sync.Mutex
+	token   string
+	names   map[entity]string
+	last    map[entity]time.Time
+}
+
+type entity struct {
+	kind string
+	id   int
+}
+
+// metricsRequest queries the history of an entity.
+type metricsRequest struct {
+	PluginID    string `json:"PluginId"`
+	EntityType  int    `json:"EntityType"`
+	EntityID    int    `json:"EntityId"`
+	MetricTypes []int  `json:"MetricTypes"`
+	Duration    int    `json:"Duration"`
+	SortOrder   int    `json:"SortOrder"`
+}
+
+type metricsResponse struct {
+	Value []struct {
+		Type      int      `json:"Type"`
+		Value     *float64 `json:"Value"`
+		Timestamp string   `json:"Timestamp"`
+	} `json:"Value"`
+}
+
+func (o *OMEPower) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OMEPower) Description() string {
+	return "Read the power and thermal history of devices and groups from Dell OpenManage Enterprise Power Manager"
+}
+
+func (o *OMEPower) Init() error {
+	if o.URL == "" {
+		return fmt.Errorf("no url configured")
+	}
+	if len(o.Devices) == 0 && len(o.Groups) == 0 {
+		return fmt.Errorf("no devices or groups configured")
+	}
+	if _, ok := durations[o.Duration]; !ok {
+		return fmt.Errorf("invalid duration %q", o.Duration)
+	}
+	o.URL = strings.TrimRight(o.URL, "/")
+
+	client, err := o.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	o.client = client
+	o.names = make(map[entity]string)
+	o.last = make(map[entity]time.Time)
+	return nil
+}
+
+func (o *OMEPower) Gather(acc telegraf.Accumulator) error {
+	entities := make([]entity, 0, len(o.Devices)+len(o.Groups))
+	for _, id := range o.Devices {
+		entities = append(entities, entity{kind: entityDevice, id: id})
+	}
+	for _, id := range o.Groups {
+		entities = append(entities, entity{kind: entityGroup, id: id})
+	}
+
+	var wg sync.WaitGroup
+	for _, e := range entities {
+		wg.Add(1)
+		go func(e entity) {
+			defer wg.Done()
+			if err := o.gatherEntity(acc, e); err != nil {
+				acc.AddError(fmt.Errorf("%s %d: %v", e.kind, e.id, err))
+			}
+		}(e)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (o *OMEPower) gatherEntity(acc telegraf.Accumulator, e entity) error {
+	types := make([]int, 0, len(metricTypes))
+	for t := range metricTypes {
+		types = append(types, t)
+	}
+	sort.Ints(types)
+
+	req := metricsRequest{
+		PluginID:    o.PluginID,
+		EntityID:    e.id,
+		MetricTypes: types,
+		Duration:    durations[o.Duration],
+	}
+	if e.kind == entityGroup {
+		req.EntityType = 1
+	}
+
+	var resp metricsResponse
+	if err := o.do("POST", "/api/MetricService/Metrics", req, &resp); err != nil {
+		return err
+	}
+
+	// Samples of the metric types share their timestamp
+	samples := make(map[time.Time]map[string]interface{})
+	for _, v := range resp.Value {
+		field, ok := metricTypes[v.Type]
+		if !ok || v.Value == nil {
+			continue
+		}
+		t, err := time.ParseInLocation(timestampFormat, v.Timestamp, time.UTC)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", v.Timestamp)
+		}
+		if samples[t] == nil {
+			samples[t] = make(map[string]interface{})
+		}
+		samples[t][field] = *v.Value
+	}
+
+	o.mu.Lock()
+	last := o.last[e]
+	o.mu.Unlock()
+
+	tags := map[string]string{
+		"entity_type": e.kind,
+		"entity_id":   strconv.Itoa(e.id),
+	}
+	if name := o.name(e); name != "" {
+		tags["name"] = name
+	}
+
+	newest := last
+	for t, fields := range samples {
+		if !t.After(last) {
+			continue
+		}
+		acc.AddFields(measurement, fields, tags, t)
+		if t.After(newest) {
+			newest = t
+		}
+	}
+
+	o.mu.Lock()
+	o.last[e] = newest
+	o.mu.Unlock()
+	return nil
+}
+
+// name returns the name of the entity, looked up once.
+func (o *OMEPower) name(e entity) string {
+	o.mu.Lock()
+	name, ok := o.names[e]
+	o.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	var resp struct {
+		DeviceName string `json:"DeviceName"`
+		Name       string `json:"Name"`
+	}
+	path := fmt.Sprintf("/api/DeviceService/Devices(%d)", e.id)
+	if e.kind == entityGroup {
+		path = fmt.Sprintf("/api/GroupService/Groups(%d)", e.id)
+	}
+	if err := o.do("GET", path, nil, &resp); err != nil {
+		o.Log.Debugf("Looking up name of %s %d: %v", e.kind, e.id, err)
+		return ""
+	}
+	name = resp.DeviceName
+	if name == "" {
+		name = resp.Name
+	}
+
+	o.mu.Lock()
+	o.names[e] = name
+	o.mu.Unlock()
+	return name
+}
+
+// do sends the request with the session token, logging in again if the
+// session expired.
+func (o *OMEPower) do(method, path string, body, v interface{}) error {
+	o.mu.Lock()
+	token := o.token
+	o.mu.Unlock()
+
+	if token == "" {
+		var err error
+		if token, err = o.login(token); err != nil {
+			return err
+		}
+	}
+
+	status, err := o.request(method, path, token, body, v)
+	if status == http.StatusUnauthorized {
+		if token, err = o.login(token); err != nil {
+			return err
+		}
+		_, err = o.request(method, path, token, body, v)
+	}
+	return err
+}
+
+// login creates an API session and returns its token, unless another
+// request replaced the stale token meanwhile.  Sessions are limited, so
+// concurrent requests share one.
+func (o *OMEPower) login(stale string) (string, error) {
+	o.loginMu.Lock()
+	defer o.loginMu.Unlock()
+
+	o.mu.Lock()
+	token := o.token
+	o.mu.Unlock()
+	if token != stale {
+		return token, nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"UserName":    o.Username,
+		"Password":    o.Password,
+		"SessionType": "API",
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", o.URL+"/api/SessionService/Sessions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("login returned HTTP status %s", resp.Status)
+	}
+	token = resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return "", fmt.Errorf("login returned no session token")
+	}
+
+	o.mu.Lock()
+	o.token = token
+	o.mu.Unlock()
+	return token, nil
+}
+
+func (o *OMEPower) request(method, path, token string, body, v interface{}) (int, error) {
+	var buf []byte
+	if body != nil {
+		var err error
+		if buf, err = json.Marshal(body); err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequest(method, o.URL+path, bytes.NewReader(buf))
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Auth-Token", token)
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s%s returned HTTP status %s: %q", req.URL.Host, req.URL.Path, resp.Status, b)
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+func init() {
+	inputs.Add("ome_power", func() telegraf.Input {
+		return &OMEPower{
+			Duration: "1h",
+			PluginID: "2F6D05BE-EC4D-4C07-8ED1-6A0A2A6C8E34",
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 20 * time.Second},
+			},
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package ome_power
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const metricsResponseBody = `
+{
+  "Value": [
+    {"Type": 1, "Value": 412.0, "Timestamp": "2020-12-16 14:00:00.000000"},
+    {"Type": 3, "Value": 385.5, "Timestamp": "2020-12-16 14:00:00.000000"},
+    {"Type": 4, "Value": 390.0, "Timestamp": "2020-12-16 14:00:00.000000"},
+    {"Type": 7, "Value": 22.5, "Timestamp": "2020-12-16 14:00:00.000000"},
+    {"Type": 1, "Value": 420.0, "Timestamp": "2020-12-16 14:15:00.000000"},
+    {"Type": 3, "Value": 401.0, "Timestamp": "2020-12-16 14:15:00.000000"},
+    {"Type": 4, "Value": null, "Timestamp": "2020-12-16 14:15:00.000000"},
+    {"Type": 16, "Value": 123.0, "Timestamp": "2020-12-16 14:15:00.000000"}
+  ]
+}
+`
+
+// fakeOME is a fake OpenManage Enterprise appliance, sessions are expired
+// by clearing the token.
+type fakeOME struct {
+	sync.Mutex
+	token    string
+	logins   int
+	requests []metricsRequest
+}
+
+func (f *fakeOME) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.URL.Path == "/api/SessionService/Sessions" {
+		var login map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&login); err != nil || login["Password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.logins++
+		f.token = fmt.Sprintf("token-%d", f.logins)
+		w.Header().Set("X-Auth-Token", f.token)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	if f.token == "" || r.Header.Get("X-Auth-Token") != f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/MetricService/Metrics":
+		var req metricsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.requests = append(f.requests, req)
+		fmt.Fprint(w, metricsResponseBody)
+	case "/api/DeviceService/Devices(10053)":
+		fmt.Fprint(w, `{"Id": 10053, "DeviceName": "node1.example.com"}`)
+	case "/api/GroupService/Groups(1042)":
+		fmt.Fprint(w, `{"Id": 1042, "Name": "Rack A1"}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGather(t *testing.T) {
+	fake := &fakeOME{}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	o := &OMEPower{
+		URL:      ts.URL,
+		Username: "telegraf",
+		Password: "secret",
+		Devices:  []int{10053},
+		Groups:   []int{1042},
+		Duration: "1h",
+		PluginID: "2F6D05BE-EC4D-4C07-8ED1-6A0A2A6C8E34",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4)
+	require.Equal(t, 1, fake.logins)
+
+	acc.AssertContainsTaggedFields(t, "ome_power",
+		map[string]interface{}{
+			"max_power":                 412.0,
+			"average_power":             385.5,
+			"instant_power":             390.0,
+			"average_inlet_temperature": 22.5,
+		},
+		map[string]string{
+			"entity_type": "device",
+			"entity_id":   "10053",
+			"name":        "node1.example.com",
+		})
+	acc.AssertContainsTaggedFields(t, "ome_power",
+		map[string]interface{}{
+			"max_power":     420.0,
+			"average_power": 401.0,
+		},
+		map[string]string{
+			"entity_type": "group",
+			"entity_id":   "1042",
+			"name":        "Rack A1",
+		})
+	for _, m := range acc.Metrics {
+		require.Contains(t, []time.Time{
+			time.Date(2020, time.December, 16, 14, 0, 0, 0, time.UTC),
+			time.Date(2020, time.December, 16, 14, 15, 0, 0, time.UTC),
+		}, m.Time.UTC())
+	}
+
+	for _, req := range fake.requests {
+		require.Equal(t, 1, req.Duration)
+		require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, req.MetricTypes)
+		if req.EntityID == 1042 {
+			require.Equal(t, 1, req.EntityType)
+		} else {
+			require.Equal(t, 0, req.EntityType)
+		}
+	}
+
+	// Samples already gathered are skipped
+	acc.ClearMetrics()
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Empty(t, acc.Metrics)
+}
+
+func TestGatherSessionExpired(t *testing.T) {
+	fake := &fakeOME{}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	o := &OMEPower{
+		URL:      ts.URL,
+		Username: "telegraf",
+		Password: "secret",
+		Devices:  []int{10053},
+		Duration: "1h",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	fake.Lock()
+	fake.token = ""
+	fake.Unlock()
+
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Equal(t, 2, fake.logins)
+}
+
+func TestGatherLoginFailure(t *testing.T) {
+	ts := httptest.NewServer(&fakeOME{})
+	defer ts.Close()
+
+	o := &OMEPower{
+		URL:      ts.URL,
+		Username: "telegraf",
+		Password: "wrong",
+		Devices:  []int{10053},
+		Duration: "1h",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "login returned HTTP status 401")
+}
+
+func TestInit(t *testing.T) {
+	o := &OMEPower{URL: "https://ome.example.com", Duration: "1h"}
+	require.Error(t, o.Init())
+
+	o = &OMEPower{URL: "https://ome.example.com", Devices: []int{1}, Duration: "2h"}
+	require.Error(t, o.Init())
+}
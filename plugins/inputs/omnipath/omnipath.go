@@ -0,0 +1,205 @@
+package omnipath
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// OmniPath stores the configuration values for the omnipath input plugin
+type OmniPath struct {
+	Path              string   `toml:"path"`
+	HWCountersInclude []string `toml:"hw_counters_include"`
+	HWCountersExclude []string `toml:"hw_counters_exclude"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	filter filter.Filter
+}
+
+var sampleConfig = `
+  ## Path of the InfiniBand class in sysfs, the hfi1 devices are read.
+  # path = "/sys/class/infiniband"
+
+  ## Counters of the hfi1 driver to report, by their names in the
+  ## hw_counters directories, e.g. "RxDroppedPkt".  All by default.
+  # hw_counters_include = []
+  # hw_counters_exclude = []
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (o *OmniPath) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (o *OmniPath) Description() string {
+	return "Read the port and driver counters of Omni-Path HFIs from the hfi1 driver"
+}
+
+// Init sets the defaults and compiles the filter.
+func (o *OmniPath) Init() error {
+	if o.Path == "" {
+		o.Path = "/sys/class/infiniband"
+	}
+	var err error
+	o.filter, err = filter.NewIncludeExcludeFilter(o.HWCountersInclude, o.HWCountersExclude)
+	return err
+}
+
+// Gather is the main execution function for the plugin
+func (o *OmniPath) Gather(acc telegraf.Accumulator) error {
+	devices, err := filepath.Glob(filepath.Join(o.Path, "hfi1_*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no hfi1 device found in %s", o.Path)
+	}
+	sort.Strings(devices)
+
+	for _, dir := range devices {
+		if err := o.gatherDevice(acc, dir); err != nil {
+			acc.AddError(fmt.Errorf("reading %s: %v", dir, err))
+		}
+	}
+	return nil
+}
+
+// gatherDevice adds the driver counters of the device and the counters of
+// each of its ports.
+func (o *OmniPath) gatherDevice(acc telegraf.Accumulator, dir string) error {
+	device := filepath.Base(dir)
+	fields := o.readCounters(filepath.Join(dir, "hw_counters"), true)
+	// tempsense holds the current temperature then the limits
+	if s, err := readString(filepath.Join(dir, "tempsense")); err == nil {
+		if parts := strings.Fields(s); len(parts) > 0 {
+			if t, err := strconv.ParseFloat(parts[0], 64); err == nil {
+				fields["temperature"] = t
+			}
+		}
+	}
+	if len(fields) > 0 {
+		acc.AddFields("omnipath_device", fields, map[string]string{"device": device})
+	}
+
+	ports, err := filepath.Glob(filepath.Join(dir, "ports", "[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, port := range ports {
+		fields := o.readCounters(filepath.Join(port, "counters"), false)
+		for k, v := range o.readCounters(filepath.Join(port, "hw_counters"), true) {
+			fields[k] = v
+		}
+		if state, err := readString(filepath.Join(port, "state")); err == nil {
+			fields["state"] = stateName(state)
+		}
+		if state, err := readString(filepath.Join(port, "phys_state")); err == nil {
+			fields["phys_state"] = stateName(state)
+		}
+		// e.g. "100 Gb/sec (4X EDR)"
+		if rate, err := readString(filepath.Join(port, "rate")); err == nil {
+			if parts := strings.Fields(rate); len(parts) > 0 {
+				if r, err := strconv.ParseFloat(parts[0], 64); err == nil {
+					fields["rate_gbps"] = r
+				}
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		tags := map[string]string{
+			"device": device,
+			"port":   filepath.Base(port),
+		}
+		acc.AddFields("omnipath", fields, tags)
+	}
+	return nil
+}
+
+// readCounters reads the counters of a directory, the counters of the
+// driver are filtered and converted to snake case.  The lifespan of the
+// driver counters, their update period, is not a counter.
+func (o *OmniPath) readCounters(dir string, driver bool) map[string]interface{} {
+	fields := make(map[string]interface{})
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fields
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || name == "lifespan" {
+			continue
+		}
+		if driver && !o.filter.Match(name) {
+			continue
+		}
+		value, err := readUint(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if driver {
+			name = snakeCase(name)
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// stateName returns the name of a port state, e.g. "active" for
+// "4: ACTIVE" and "link_up" for "5: LinkUp".
+func stateName(state string) string {
+	if i := strings.Index(state, ":"); i >= 0 {
+		state = strings.TrimSpace(state[i+1:])
+	}
+	return snakeCase(state)
+}
+
+// snakeCase converts a CamelCase counter name, e.g. TxFlitVL0 to
+// tx_flit_vl0.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func readString(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	s, err := readString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func init() {
+	inputs.Add("omnipath", func() telegraf.Input {
+		return &OmniPath{}
+	})
+}
@@ -0,0 +1,95 @@
+package omnipath
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	o := &OmniPath{Path: "testdata/hfi1", Log: testutil.Logger{}}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "omnipath_device",
+		map[string]interface{}{
+			"rcv_overflow":      uint64(3),
+			"send_sched_fail":   uint64(0),
+			"dc_recv_err":       uint64(12),
+			"tx_wait_vl0":       uint64(4),
+			"rx_dropped_pkt":    uint64(0),
+			"rx_bad_format_err": uint64(1),
+			"temperature":       46.5,
+		},
+		map[string]string{"device": "hfi1_0"})
+	acc.AssertContainsTaggedFields(t, "omnipath",
+		map[string]interface{}{
+			"port_xmit_data":                  uint64(1024),
+			"port_rcv_data":                   uint64(2048),
+			"port_xmit_wait":                  uint64(7),
+			"symbol_error":                    uint64(0),
+			"link_downed":                     uint64(1),
+			"excessive_buffer_overrun_errors": uint64(0),
+			"tx_flit_vl0":                     uint64(512),
+			"rx_dropped_pkt":                  uint64(2),
+			"state":                           "active",
+			"phys_state":                      "link_up",
+			"rate_gbps":                       100.0,
+		},
+		map[string]string{"device": "hfi1_0", "port": "1"})
+}
+
+func TestGatherFilter(t *testing.T) {
+	o := &OmniPath{
+		Path:              "testdata/hfi1",
+		HWCountersInclude: []string{"Rx*", "Tx*"},
+		HWCountersExclude: []string{"TxWait*"},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsTaggedFields(t, "omnipath_device",
+		map[string]interface{}{
+			"rx_dropped_pkt":    uint64(0),
+			"rx_bad_format_err": uint64(1),
+			"temperature":       46.5,
+		},
+		map[string]string{"device": "hfi1_0"})
+	// The standard counters are not filtered
+	m, ok := acc.Get("omnipath")
+	require.True(t, ok)
+	require.Contains(t, m.Fields, "port_xmit_data")
+	require.Contains(t, m.Fields, "tx_flit_vl0")
+	require.Contains(t, m.Fields, "rx_dropped_pkt")
+}
+
+func TestGatherNoDevice(t *testing.T) {
+	o := &OmniPath{Path: "testdata/mlx5", Log: testutil.Logger{}}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, o.Gather(&acc))
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"TxFlitVL0":          "tx_flit_vl0",
+		"RxDroppedPkt":       "rx_dropped_pkt",
+		"DcRecvErr":          "dc_recv_err",
+		"LinkUp":             "link_up",
+		"ACTIVE":             "active",
+		"SDmaDescFetchedCnt": "s_dma_desc_fetched_cnt",
+	}
+	for in, want := range tests {
+		require.Equal(t, want, snakeCase(in))
+	}
+}
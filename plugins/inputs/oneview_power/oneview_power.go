@@ -0,0 +1,357 @@
+package oneview_power
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement = "oneview_power"
+
+	// apiVersion is the REST API version requested, supported since
+	// OneView 4.1.
+	apiVersion = "800"
+)
+
+// utilizationFields are the utilization metrics gathered and their fields,
+// not all resource types support all metrics.
+var utilizationFields = map[string]string{
+	"AveragePower":       "average_power",
+	"PeakPower":          "peak_power",
+	"PowerCap":           "power_cap",
+	"AmbientTemperature": "ambient_temperature",
+}
+
+// resourceMetrics are the utilization metrics supported by the resource
+// types.
+var resourceMetrics = map[string][]string{
+	"server-hardware": {"AveragePower", "PeakPower", "PowerCap", "AmbientTemperature"},
+	"enclosures":      {"AveragePower", "PeakPower", "PowerCap", "AmbientTemperature"},
+	"power-devices":   {"AveragePower", "PeakPower"},
+}
+
+var sampleConfig = `
+  ## URL of the OneView appliance
+  url = "https://oneview.example.com"
+
+  ## Credentials of a user allowed to read the resources, with the
+  ## directory of the user if not local
+  username = "telegraf"
+  password = ""
+  # login_domain = ""
+
+  ## Types of the resources to gather the power and thermal history of, all
+  ## resources of each type are gathered.  One or more of server-hardware,
+  ## enclosures and power-devices.
+  # resource_types = ["server-hardware", "enclosures"]
+
+  ## Timeout for HTTP requests
+  # timeout = "20s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## OneView samples the utilization every 5 minutes, there is no need to
+  ## query it more often.
+  interval = "5m"
+`
+
+// OneViewPower gathers the power and thermal history collected by HPE
+// OneView.
+type OneViewPower struct {
+	URL           string   `toml:"url"`
+	Username      string   `toml:"username"`
+	Password      string   `toml:"password"`
+	LoginDomain   string   `toml:"login_domain"`
+	ResourceTypes []string `toml:"resource_types"`
+	httpconfig.HTTPClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+
+	// loginMu serializes logins, mu guards the session and the timestamp
+	// of the last sample gathered of each resource.
+	loginMu sync.Mutex
+	mu      sync.Mutex
+	session string
+	last    map[string]time.Time
+}
+
+// resource is a member of a resource collection.
+type resource struct {
+	URI          string `json:"uri"`
+	Name         string `json:"name"`
+	SerialNumber string `json:"serialNumber"`
+}
+
+type collection struct {
+	Members     []resource `json:"members"`
+	NextPageURI string     `json:"nextPageUri"`
+}
+
+// utilization holds the samples of the metrics of a resource, as pairs of
+// the time in milliseconds since the epoch and the value.
+type utilization struct {
+	MetricList []struct {
+		MetricName    string       `json:"metricName"`
+		MetricSamples [][2]float64 `json:"metricSamples"`
+	} `json:"metricList"`
+}
+
+func (o *OneViewPower) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OneViewPower) Description() string {
+	return "Read the power and thermal history of servers, enclosures and power devices from HPE OneView"
+}
+
+func (o *OneViewPower) Init() error {
+	if o.URL == "" {
+		return fmt.Errorf("no url configured")
+	}
+	for _, t := range o.ResourceTypes {
+		if _, ok := resourceMetrics[t]; !ok {
+			return fmt.Errorf("invalid resource type %q", t)
+		}
+	}
+	o.URL = strings.TrimRight(o.URL, "/")
+
+	client, err := o.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	o.client = client
+	o.last = make(map[string]time.Time)
+	return nil
+}
+
+func (o *OneViewPower) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, t := range o.ResourceTypes {
+		resources, err := o.list(t)
+		if err != nil {
+			acc.AddError(fmt.Errorf("listing %s: %v", t, err))
+			continue
+		}
+		for _, r := range resources {
+			wg.Add(1)
+			go func(t string, r resource) {
+				defer wg.Done()
+				if err := o.gatherResource(acc, t, r); err != nil {
+					acc.AddError(fmt.Errorf("%s: %v", r.URI, err))
+				}
+			}(t, r)
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+// list returns all resources of the type, following the pages of the
+// collection.
+func (o *OneViewPower) list(resourceType string) ([]resource, error) {
+	var resources []resource
+	next := "/rest/" + resourceType
+	for next != "" {
+		var c collection
+		if err := o.do("GET", next, &c); err != nil {
+			return nil, err
+		}
+		resources = append(resources, c.Members...)
+		next = c.NextPageURI
+	}
+	return resources, nil
+}
+
+func (o *OneViewPower) gatherResource(acc telegraf.Accumulator, resourceType string, r resource) error {
+	o.mu.Lock()
+	last := o.last[r.URI]
+	o.mu.Unlock()
+
+	params := url.Values{}
+	for _, metric := range resourceMetrics[resourceType] {
+		params.Add("fields", metric)
+	}
+	if !last.IsZero() {
+		params.Set("filter", "startDate="+last.Add(time.Millisecond).UTC().Format("2006-01-02T15:04:05.000Z"))
+	}
+
+	var u utilization
+	if err := o.do("GET", r.URI+"/utilization?"+params.Encode(), &u); err != nil {
+		return err
+	}
+
+	// Samples of the metrics share their timestamp
+	samples := make(map[time.Time]map[string]interface{})
+	for _, metric := range u.MetricList {
+		field, ok := utilizationFields[metric.MetricName]
+		if !ok {
+			continue
+		}
+		for _, s := range metric.MetricSamples {
+			t := time.Unix(0, int64(s[0])*int64(time.Millisecond))
+			if samples[t] == nil {
+				samples[t] = make(map[string]interface{})
+			}
+			samples[t][field] = s[1]
+		}
+	}
+
+	tags := map[string]string{
+		"resource_type": resourceType,
+		"name":          r.Name,
+	}
+	if r.SerialNumber != "" {
+		tags["serial_number"] = r.SerialNumber
+	}
+
+	newest := last
+	for t, fields := range samples {
+		if !t.After(last) {
+			continue
+		}
+		acc.AddFields(measurement, fields, tags, t)
+		if t.After(newest) {
+			newest = t
+		}
+	}
+
+	o.mu.Lock()
+	o.last[r.URI] = newest
+	o.mu.Unlock()
+	return nil
+}
+
+// do sends the request with the session, logging in again if the session
+// expired.
+func (o *OneViewPower) do(method, path string, v interface{}) error {
+	o.mu.Lock()
+	session := o.session
+	o.mu.Unlock()
+
+	if session == "" {
+		var err error
+		if session, err = o.login(session); err != nil {
+			return err
+		}
+	}
+
+	status, err := o.request(method, path, session, v)
+	if status == http.StatusUnauthorized {
+		if session, err = o.login(session); err != nil {
+			return err
+		}
+		_, err = o.request(method, path, session, v)
+	}
+	return err
+}
+
+// login creates a session and returns its id, unless another request
+// replaced the stale session meanwhile.  Sessions are limited, so
+// concurrent requests share one.
+func (o *OneViewPower) login(stale string) (string, error) {
+	o.loginMu.Lock()
+	defer o.loginMu.Unlock()
+
+	o.mu.Lock()
+	session := o.session
+	o.mu.Unlock()
+	if session != stale {
+		return session, nil
+	}
+
+	credentials := map[string]string{
+		"userName": o.Username,
+		"password": o.Password,
+	}
+	if o.LoginDomain != "" {
+		credentials["authLoginDomain"] = o.LoginDomain
+	}
+	body, err := json.Marshal(credentials)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", o.URL+"/rest/login-sessions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Version", apiVersion)
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("login returned HTTP status %s", resp.Status)
+	}
+	var result struct {
+		SessionID string `json:"sessionID"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.SessionID == "" {
+		return "", fmt.Errorf("login returned no session")
+	}
+
+	o.mu.Lock()
+	o.session = result.SessionID
+	o.mu.Unlock()
+	return result.SessionID, nil
+}
+
+func (o *OneViewPower) request(method, path, session string, v interface{}) (int, error) {
+	req, err := http.NewRequest(method, o.URL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Auth", session)
+	req.Header.Set("X-API-Version", apiVersion)
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s%s returned HTTP status %s: %q", req.URL.Host, req.URL.Path, resp.Status, b)
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+func init() {
+	inputs.Add("oneview_power", func() telegraf.Input {
+		return &OneViewPower{
+			ResourceTypes: []string{"server-hardware", "enclosures"},
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 20 * time.Second},
+			},
+		}
+	})
+}
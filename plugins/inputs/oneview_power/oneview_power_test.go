@@ -0,0 +1,194 @@
+package oneview_power
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const serverUtilization = `
+{
+  "metricList": [
+    {"metricName": "AveragePower", "metricCapacity": 800, "metricSamples": [[1608127500000, 401], [1608127200000, 385]]},
+    {"metricName": "PeakPower", "metricCapacity": 800, "metricSamples": [[1608127500000, 420], [1608127200000, 412]]},
+    {"metricName": "AmbientTemperature", "metricCapacity": 100, "metricSamples": [[1608127500000, 23], [1608127200000, 22]]}
+  ],
+  "resolution": 300000,
+  "isFresh": true
+}
+`
+
+const enclosureUtilization = `
+{
+  "metricList": [
+    {"metricName": "AveragePower", "metricCapacity": 14000, "metricSamples": [[1608127500000, 3120]]},
+    {"metricName": "PowerCap", "metricCapacity": 14000, "metricSamples": [[1608127500000, 9000]]}
+  ]
+}
+`
+
+// fakeOneView is a fake OneView appliance, sessions are expired by clearing
+// the session.
+type fakeOneView struct {
+	sync.Mutex
+	session string
+	logins  int
+	filters []string
+}
+
+func (f *fakeOneView) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("X-API-Version") != apiVersion {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Path == "/rest/login-sessions" {
+		var login map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&login); err != nil || login["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.logins++
+		f.session = fmt.Sprintf("session-%d", f.logins)
+		fmt.Fprintf(w, `{"sessionID": %q}`, f.session)
+		return
+	}
+
+	if f.session == "" || r.Header.Get("Auth") != f.session {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/rest/server-hardware":
+		if r.URL.Query().Get("start") == "1" {
+			fmt.Fprint(w, `{"members": [{"uri": "/rest/server-hardware/2", "name": "Encl1, bay 2", "serialNumber": "CZ20150002"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"members": [{"uri": "/rest/server-hardware/1", "name": "Encl1, bay 1", "serialNumber": "CZ20150001"}], "nextPageUri": "/rest/server-hardware?start=1&count=1"}`)
+	case "/rest/enclosures":
+		fmt.Fprint(w, `{"members": [{"uri": "/rest/enclosures/1", "name": "Encl1", "serialNumber": "CN7515049C"}]}`)
+	case "/rest/server-hardware/1/utilization", "/rest/server-hardware/2/utilization":
+		f.filters = append(f.filters, r.URL.Query().Get("filter"))
+		fmt.Fprint(w, serverUtilization)
+	case "/rest/enclosures/1/utilization":
+		fmt.Fprint(w, enclosureUtilization)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGather(t *testing.T) {
+	fake := &fakeOneView{}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	o := &OneViewPower{
+		URL:           ts.URL,
+		Username:      "telegraf",
+		Password:      "secret",
+		ResourceTypes: []string{"server-hardware", "enclosures"},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 5)
+	require.Equal(t, 1, fake.logins)
+
+	acc.AssertContainsTaggedFields(t, "oneview_power",
+		map[string]interface{}{
+			"average_power":       401.0,
+			"peak_power":          420.0,
+			"ambient_temperature": 23.0,
+		},
+		map[string]string{
+			"resource_type": "server-hardware",
+			"name":          "Encl1, bay 2",
+			"serial_number": "CZ20150002",
+		})
+	acc.AssertContainsTaggedFields(t, "oneview_power",
+		map[string]interface{}{
+			"average_power": 3120.0,
+			"power_cap":     9000.0,
+		},
+		map[string]string{
+			"resource_type": "enclosures",
+			"name":          "Encl1",
+			"serial_number": "CN7515049C",
+		})
+	for _, m := range acc.Metrics {
+		require.Contains(t, []time.Time{time.Unix(1608127200, 0), time.Unix(1608127500, 0)}, m.Time)
+	}
+
+	// Only newer samples are requested and samples already gathered are
+	// skipped
+	acc.ClearMetrics()
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Empty(t, acc.Metrics)
+	require.Equal(t, []string{"", "", "startDate=2020-12-16T14:05:00.001Z", "startDate=2020-12-16T14:05:00.001Z"}, fake.filters)
+}
+
+func TestGatherSessionExpired(t *testing.T) {
+	fake := &fakeOneView{}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	o := &OneViewPower{
+		URL:           ts.URL,
+		Username:      "telegraf",
+		Password:      "secret",
+		ResourceTypes: []string{"enclosures"},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	fake.Lock()
+	fake.session = ""
+	fake.Unlock()
+
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Equal(t, 2, fake.logins)
+}
+
+func TestGatherLoginFailure(t *testing.T) {
+	ts := httptest.NewServer(&fakeOneView{})
+	defer ts.Close()
+
+	o := &OneViewPower{
+		URL:           ts.URL,
+		Username:      "telegraf",
+		Password:      "wrong",
+		ResourceTypes: []string{"enclosures"},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "login returned HTTP status 401")
+}
+
+func TestInit(t *testing.T) {
+	o := &OneViewPower{URL: "https://oneview.example.com", ResourceTypes: []string{"racks"}}
+	require.Error(t, o.Init())
+}
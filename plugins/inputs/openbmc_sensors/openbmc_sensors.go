@@ -0,0 +1,317 @@
+package openbmc_sensors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement = "openbmc_sensors"
+
+	// sensorsPath is the root of the sensor objects on D-Bus, the REST API
+	// serves the objects below it at the same path.
+	sensorsPath = "/xyz/openbmc_project/sensors"
+)
+
+// units maps the units of xyz.openbmc_project.Sensor.Value to the unit tag.
+var units = map[string]string{
+	"xyz.openbmc_project.Sensor.Value.Unit.DegreesC": "celsius",
+	"xyz.openbmc_project.Sensor.Value.Unit.RPMS":     "rpm",
+	"xyz.openbmc_project.Sensor.Value.Unit.Volts":    "volts",
+	"xyz.openbmc_project.Sensor.Value.Unit.Meters":   "meters",
+	"xyz.openbmc_project.Sensor.Value.Unit.Amperes":  "amperes",
+	"xyz.openbmc_project.Sensor.Value.Unit.Watts":    "watts",
+	"xyz.openbmc_project.Sensor.Value.Unit.Joules":   "joules",
+	"xyz.openbmc_project.Sensor.Value.Unit.Percent":  "percent",
+	"xyz.openbmc_project.Sensor.Value.Unit.CFM":      "cfm",
+	"xyz.openbmc_project.Sensor.Value.Unit.Pascals":  "pascals",
+}
+
+// thresholds are the threshold properties of the sensor objects and their
+// fields.
+var thresholds = map[string]string{
+	"WarningLow":   "warning_low",
+	"WarningHigh":  "warning_high",
+	"CriticalLow":  "critical_low",
+	"CriticalHigh": "critical_high",
+}
+
+var sampleConfig = `
+  ## URL of the OpenBMC
+  address = "https://127.0.0.1"
+
+  ## Credentials of a user allowed to read the sensors
+  username = "root"
+  password = ""
+
+  ## Types of the sensors to gather, the names of the namespaces below
+  ## /xyz/openbmc_project/sensors such as temperature, power, fan_tach,
+  ## voltage or current.  Glob patterns are supported, empty for all types.
+  # sensor_types = []
+
+  ## Timeout for HTTP requests
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// OpenBMCSensors gathers the sensors of OpenBMC based BMCs through the REST
+// API serving their D-Bus objects.
+type OpenBMCSensors struct {
+	Address     string   `toml:"address"`
+	Username    string   `toml:"username"`
+	Password    string   `toml:"password"`
+	SensorTypes []string `toml:"sensor_types"`
+	httpconfig.HTTPClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	baseURL    *url.URL
+	host       string
+	client     *http.Client
+	typeFilter filter.Filter
+	loggedIn   bool
+}
+
+// response is the envelope of the REST API responses.
+type response struct {
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+	Status  string          `json:"status"`
+}
+
+func (o *OpenBMCSensors) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenBMCSensors) Description() string {
+	return "Read the sensors of OpenBMC based BMCs through their REST API"
+}
+
+func (o *OpenBMCSensors) Init() error {
+	if o.Address == "" {
+		return fmt.Errorf("no address configured")
+	}
+
+	var err error
+	o.typeFilter, err = filter.Compile(o.SensorTypes)
+	if err != nil {
+		return err
+	}
+
+	o.baseURL, err = url.Parse(o.Address)
+	if err != nil {
+		return err
+	}
+	o.host, _, err = net.SplitHostPort(o.baseURL.Host)
+	if err != nil {
+		o.host = o.baseURL.Host
+	}
+
+	o.client, err = o.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	// The session is kept in a cookie
+	o.client.Jar, err = cookiejar.New(nil)
+	return err
+}
+
+func (o *OpenBMCSensors) Gather(acc telegraf.Accumulator) error {
+	var objects map[string]map[string]interface{}
+	if err := o.get(sensorsPath+"/enumerate", &objects); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+
+	for objectPath, properties := range objects {
+		// Objects are like /xyz/openbmc_project/sensors/temperature/ambient
+		rel := strings.TrimPrefix(objectPath, sensorsPath+"/")
+		sensorType, name := path.Split(rel)
+		sensorType = strings.TrimSuffix(sensorType, "/")
+		if sensorType == "" || strings.Contains(sensorType, "/") || name == "" {
+			continue
+		}
+		if o.typeFilter != nil && !o.typeFilter.Match(sensorType) {
+			continue
+		}
+
+		value, ok := number(properties["Value"])
+		if !ok {
+			continue
+		}
+		// Older BMCs report integers scaled by a power of ten
+		scale := 1.0
+		if s, ok := number(properties["Scale"]); ok {
+			scale = math.Pow10(int(s))
+		}
+
+		fields := map[string]interface{}{
+			"value": value * scale,
+		}
+		for property, field := range thresholds {
+			if v, ok := number(properties[property]); ok {
+				fields[field] = v * scale
+			}
+		}
+		for property, field := range map[string]string{"Functional": "functional", "Available": "available"} {
+			if v, ok := properties[property].(bool); ok {
+				fields[field] = v
+			}
+		}
+
+		tags := map[string]string{
+			"address": o.host,
+			"type":    sensorType,
+			"name":    name,
+		}
+		if unit, ok := properties["Unit"].(string); ok {
+			if u, ok := units[unit]; ok {
+				tags["unit"] = u
+			} else {
+				tags["unit"] = strings.ToLower(unit[strings.LastIndex(unit, ".")+1:])
+			}
+		}
+		acc.AddFields(measurement, fields, tags, timestamp)
+	}
+	return nil
+}
+
+// number returns the value of a numeric property, missing readings are
+// null or NaN.
+func number(v interface{}) (float64, bool) {
+	f, ok := v.(float64)
+	if !ok || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// get decodes the data of the response at the URI, logging in first and
+// again if the session expired.
+func (o *OpenBMCSensors) get(uri string, v interface{}) error {
+	if !o.loggedIn {
+		if err := o.login(); err != nil {
+			return err
+		}
+	}
+
+	status, err := o.request(uri, v)
+	if status == http.StatusUnauthorized {
+		if err := o.login(); err != nil {
+			return err
+		}
+		_, err = o.request(uri, v)
+	}
+	return err
+}
+
+func (o *OpenBMCSensors) request(uri string, v interface{}) (int, error) {
+	req, err := o.newRequest("GET", uri, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("%s received status code %d (%s), expected 200",
+			uri,
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return resp.StatusCode, err
+	}
+	if r.Status != "ok" {
+		return resp.StatusCode, fmt.Errorf("%s returned status %q: %s", uri, r.Status, r.Message)
+	}
+	return resp.StatusCode, json.Unmarshal(r.Data, v)
+}
+
+// login creates a session, kept in the cookie returned by the BMC.
+func (o *OpenBMCSensors) login() error {
+	body, err := json.Marshal(map[string][]string{
+		"data": {o.Username, o.Password},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := o.newRequest("POST", "/login", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		o.loggedIn = false
+		return fmt.Errorf("login received status code %d (%s), expected 200",
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+	o.loggedIn = true
+	return nil
+}
+
+func (o *OpenBMCSensors) newRequest(method, uri string, body *bytes.Reader) (*http.Request, error) {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	loc := o.baseURL.ResolveReference(ref).String()
+
+	var req *http.Request
+	if body != nil {
+		req, err = http.NewRequest(method, loc, body)
+	} else {
+		req, err = http.NewRequest(method, loc, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	return req, nil
+}
+
+func init() {
+	inputs.Add("openbmc_sensors", func() telegraf.Input {
+		return &OpenBMCSensors{
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+			},
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package openbmc_sensors
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T, logins *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login" && r.Method == "POST":
+			var body map[string][]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body["data"]) != 2 || body["data"][1] != "0penBmc" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"data": {"description": "Login failed"}, "message": "401 Unauthorized", "status": "error"}`))
+				return
+			}
+			*logins++
+			http.SetCookie(w, &http.Cookie{Name: "SESSION", Value: "session"})
+			w.Write([]byte(`{"data": "User 'root' logged in", "message": "200 OK", "status": "ok"}`))
+		case func() bool { c, err := r.Cookie("SESSION"); return err != nil || c.Value != "session" }():
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/xyz/openbmc_project/sensors/enumerate":
+			data, err := ioutil.ReadFile("testdata/enumerate.json")
+			require.NoError(t, err)
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestGather(t *testing.T) {
+	var logins int
+	ts := newServer(t, &logins)
+	defer ts.Close()
+
+	plugin := &OpenBMCSensors{
+		Address:  ts.URL,
+		Username: "root",
+		Password: "0penBmc",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	tags := func(sensorType, name, unit string) map[string]string {
+		return map[string]string{
+			"address": "127.0.0.1",
+			"type":    sensorType,
+			"name":    name,
+			"unit":    unit,
+		}
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("openbmc_sensors", tags("fan_tach", "fan0_0", "rpm"),
+			map[string]interface{}{
+				"value":        5280.0,
+				"critical_low": 1000.0,
+				"functional":   true,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("openbmc_sensors", tags("power", "total_power", "watts"),
+			map[string]interface{}{
+				"value":      412.5,
+				"functional": true,
+				"available":  true,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("openbmc_sensors", tags("temperature", "ambient", "celsius"),
+			map[string]interface{}{
+				"value":         23.5,
+				"warning_high":  32.0,
+				"critical_high": 35.0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("openbmc_sensors", tags("airflow", "system", "cubicfeetperminute"),
+			map[string]interface{}{
+				"value": 40.0,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(),
+		testutil.IgnoreTime(), testutil.SortMetrics())
+
+	// The session is kept between gathers
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Equal(t, 1, logins)
+}
+
+func TestSensorTypes(t *testing.T) {
+	var logins int
+	ts := newServer(t, &logins)
+	defer ts.Close()
+
+	plugin := &OpenBMCSensors{
+		Address:     ts.URL,
+		Username:    "root",
+		Password:    "0penBmc",
+		SensorTypes: []string{"power", "temp*"},
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+	for _, m := range acc.GetTelegrafMetrics() {
+		require.Contains(t, []string{"power", "temperature"}, m.Tags()["type"])
+	}
+}
+
+func TestLoginFailure(t *testing.T) {
+	var logins int
+	ts := newServer(t, &logins)
+	defer ts.Close()
+
+	plugin := &OpenBMCSensors{
+		Address:  ts.URL,
+		Username: "root",
+		Password: "wrong",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.EqualError(t, plugin.Gather(&acc), "login received status code 401 (Unauthorized), expected 200")
+}
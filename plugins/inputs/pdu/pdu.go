@@ -0,0 +1,300 @@
+package pdu
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/soniah/gosnmp"
+)
+
+var sampleConfig = `
+  ## Agent addresses of the PDUs, as in the snmp input
+  ##   ex: agents = ["udp://127.0.0.1:161", "tcp://127.0.0.1:161"]
+  agents = ["udp://127.0.0.1:161"]
+
+  ## MIB profile of the PDUs, one of apc, raritan, vertiv or servertech.
+  ## Use one plugin instance per vendor.
+  profile = "apc"
+
+  ## Read the per outlet and per phase readings
+  # outlets = true
+  # phases = true
+
+  ## Timeout for each request.
+  # timeout = "5s"
+
+  ## SNMP version; can be 1, 2, or 3.
+  # version = 2
+
+  ## SNMP community string.
+  # community = "public"
+
+  ## Number of retries to attempt.
+  # retries = 3
+
+  ## The GETBULK max-repetitions parameter.
+  # max_repetitions = 10
+
+  ## SNMPv3 authentication and encryption options.
+  ##
+  ## Security Name.
+  # sec_name = "myuser"
+  ## Authentication protocol; one of "MD5", "SHA", or "".
+  # auth_protocol = "MD5"
+  ## Authentication password.
+  # auth_password = "pass"
+  ## Security Level; one of "noAuthNoPriv", "authNoPriv", or "authPriv".
+  # sec_level = "authNoPriv"
+  ## Context Name.
+  # context_name = ""
+  ## Privacy protocol used for encrypted messages; one of "DES", "AES" or "".
+  # priv_protocol = ""
+  ## Privacy password used for encrypted messages.
+  # priv_password = ""
+`
+
+// snmpConnection is the subset of the SNMP client used, to mock it in
+// tests.
+type snmpConnection interface {
+	Host() string
+	Walk(string, gosnmp.WalkFunc) error
+}
+
+// Pdu gathers the outlet and phase readings of PDUs via SNMP.
+type Pdu struct {
+	Agents  []string `toml:"agents"`
+	Profile string   `toml:"profile"`
+	Outlets bool     `toml:"outlets"`
+	Phases  bool     `toml:"phases"`
+	snmp.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	profile profile
+	// connect returns the connection to the agent.
+	connect func(agent string) (snmpConnection, error)
+}
+
+// SampleConfig returns the documentation about the sample configuration
+func (p *Pdu) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (p *Pdu) Description() string {
+	return "Read the outlet and phase power of APC, Raritan, Vertiv and ServerTech PDUs via SNMP"
+}
+
+// Init selects the profile and checks the SNMP client configuration.
+func (p *Pdu) Init() error {
+	var ok bool
+	p.profile, ok = profiles[p.Profile]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q, must be one of %s", p.Profile, strings.Join(names, ", "))
+	}
+
+	if _, err := snmp.NewWrapper(p.ClientConfig); err != nil {
+		return fmt.Errorf("parsing SNMP client config: %v", err)
+	}
+	if p.connect == nil {
+		p.connect = p.dial
+	}
+	return nil
+}
+
+// dial connects to the agent.  Each gather uses its own connection as they
+// must not be shared between goroutines.
+func (p *Pdu) dial(agent string) (snmpConnection, error) {
+	gs, err := snmp.NewWrapper(p.ClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := gs.SetAgent(agent); err != nil {
+		return nil, err
+	}
+	if err := gs.Connect(); err != nil {
+		return nil, fmt.Errorf("setting up connection: %v", err)
+	}
+	return gs, nil
+}
+
+// Gather is the main execution function for the plugin
+func (p *Pdu) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, agent := range p.Agents {
+		wg.Add(1)
+		go func(agent string) {
+			defer wg.Done()
+			if err := p.gatherAgent(acc, agent); err != nil {
+				acc.AddError(fmt.Errorf("agent %s: %v", agent, err))
+			}
+		}(agent)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (p *Pdu) gatherAgent(acc telegraf.Accumulator, agent string) error {
+	conn, err := p.connect(agent)
+	if err != nil {
+		return err
+	}
+	w := &walker{conn: conn, cache: make(map[string]map[string]interface{})}
+
+	if p.Outlets {
+		if err := p.gatherTable(acc, w, p.profile.outlets, "pdu_outlet", "outlet"); err != nil {
+			return fmt.Errorf("outlets: %v", err)
+		}
+	}
+	if p.Phases {
+		if err := p.gatherTable(acc, w, p.profile.phases, "pdu_phase", "phase"); err != nil {
+			return fmt.Errorf("phases: %v", err)
+		}
+	}
+	return nil
+}
+
+// gatherTable adds the rows of the table tagged with their index and label.
+func (p *Pdu) gatherTable(acc telegraf.Accumulator, w *walker, t table, measurement, indexTag string) error {
+	rows := make(map[string]map[string]interface{})
+	for _, c := range t.columns {
+		values, err := w.walk(c.oid)
+		if err != nil {
+			return err
+		}
+		var digits map[string]interface{}
+		if c.digits != "" {
+			if digits, err = w.walk(c.digits); err != nil {
+				return err
+			}
+		}
+
+		for index, v := range values {
+			row := index
+			if c.suffix != "" {
+				if !strings.HasSuffix(index, "."+c.suffix) {
+					continue
+				}
+				row = strings.TrimSuffix(index, "."+c.suffix)
+			}
+			value, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			if c.digits != "" {
+				d, ok := toFloat(digits[index])
+				if !ok {
+					continue
+				}
+				value /= math.Pow10(int(d))
+			} else if c.scale < 1 {
+				// Dividing keeps decimal readings such as 12 tenths of
+				// Amps exact
+				value /= math.Round(1 / c.scale)
+			} else {
+				value *= c.scale
+			}
+
+			if rows[row] == nil {
+				rows[row] = make(map[string]interface{})
+			}
+			rows[row][c.field] = value
+		}
+	}
+
+	var labels map[string]interface{}
+	if t.label != "" {
+		var err error
+		if labels, err = w.walk(t.label); err != nil {
+			return err
+		}
+	}
+
+	timestamp := time.Now()
+	for row, fields := range rows {
+		tags := map[string]string{
+			"agent_host": w.conn.Host(),
+			indexTag:     row,
+		}
+		if label, ok := labels[row].(string); ok && label != "" {
+			tags["label"] = label
+		}
+		acc.AddFields(measurement, fields, tags, timestamp)
+	}
+	return nil
+}
+
+// walker walks the columns of an agent, each column once per gather.
+type walker struct {
+	conn  snmpConnection
+	cache map[string]map[string]interface{}
+}
+
+// walk returns the values of the column by their index.
+func (w *walker) walk(oid string) (map[string]interface{}, error) {
+	if values, ok := w.cache[oid]; ok {
+		return values, nil
+	}
+
+	values := make(map[string]interface{})
+	prefix := "." + oid + "."
+	err := w.conn.Walk(oid, func(pdu gosnmp.SnmpPDU) error {
+		if !strings.HasPrefix(pdu.Name, prefix) {
+			return nil
+		}
+		index := strings.TrimPrefix(pdu.Name, prefix)
+		switch pdu.Type {
+		case gosnmp.OctetString:
+			if b, ok := pdu.Value.([]byte); ok {
+				values[index] = string(b)
+			}
+		case gosnmp.Integer, gosnmp.Counter32, gosnmp.Gauge32, gosnmp.Counter64, gosnmp.Uinteger32:
+			values[index] = gosnmp.ToBigInt(pdu.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %v", oid, err)
+	}
+	w.cache[oid] = values
+	return values, nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	b, ok := v.(*big.Int)
+	if !ok {
+		return 0, false
+	}
+	f, _ := new(big.Float).SetInt(b).Float64()
+	return f, true
+}
+
+func init() {
+	inputs.Add("pdu", func() telegraf.Input {
+		return &Pdu{
+			Outlets: true,
+			Phases:  true,
+			ClientConfig: snmp.ClientConfig{
+				Retries:        3,
+				MaxRepetitions: 10,
+				Timeout:        internal.Duration{Duration: 5 * time.Second},
+				Version:        2,
+				Community:      "public",
+			},
+		}
+	})
+}
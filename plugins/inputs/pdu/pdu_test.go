@@ -0,0 +1,168 @@
+package pdu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent is an SNMP agent serving fixed values.
+type fakeAgent struct {
+	host   string
+	values map[string]interface{}
+	walks  int
+}
+
+func (a *fakeAgent) Host() string {
+	return a.host
+}
+
+func (a *fakeAgent) Walk(oid string, fn gosnmp.WalkFunc) error {
+	a.walks++
+	var names []string
+	for name := range a.values {
+		if strings.HasPrefix(name, "."+oid+".") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pdu := gosnmp.SnmpPDU{Name: name}
+		switch v := a.values[name].(type) {
+		case string:
+			pdu.Type = gosnmp.OctetString
+			pdu.Value = []byte(v)
+		case int:
+			pdu.Type = gosnmp.Integer
+			pdu.Value = v
+		case uint:
+			pdu.Type = gosnmp.Gauge32
+			pdu.Value = v
+		}
+		if err := fn(pdu); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newPdu(t *testing.T, profile string, agent *fakeAgent) *Pdu {
+	p := &Pdu{
+		Agents:  []string{"udp://" + agent.host + ":161"},
+		Profile: profile,
+		Outlets: true,
+		Phases:  true,
+		Log:     testutil.Logger{},
+		connect: func(string) (snmpConnection, error) { return agent, nil },
+	}
+	require.NoError(t, p.Init())
+	return p
+}
+
+func TestGatherAPC(t *testing.T) {
+	agent := &fakeAgent{
+		host: "10.0.0.1",
+		values: map[string]interface{}{
+			".1.3.6.1.4.1.318.1.1.26.9.4.3.1.3.1":  "node01",
+			".1.3.6.1.4.1.318.1.1.26.9.4.3.1.3.2":  "node02",
+			".1.3.6.1.4.1.318.1.1.26.9.4.3.1.6.1":  uint(12),
+			".1.3.6.1.4.1.318.1.1.26.9.4.3.1.6.2":  uint(0),
+			".1.3.6.1.4.1.318.1.1.26.9.4.3.1.7.1":  uint(265),
+			".1.3.6.1.4.1.318.1.1.26.9.4.3.1.7.2":  uint(0),
+			".1.3.6.1.4.1.318.1.1.26.9.4.3.1.11.1": uint(12345),
+			".1.3.6.1.4.1.318.1.1.26.9.4.3.1.11.2": uint(20),
+			".1.3.6.1.4.1.318.1.1.26.6.3.1.5.1":    uint(64),
+			".1.3.6.1.4.1.318.1.1.26.6.3.1.6.1":    uint(230),
+			".1.3.6.1.4.1.318.1.1.26.6.3.1.7.1":    uint(142),
+		},
+	}
+	p := newPdu(t, "apc", agent)
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "pdu_outlet",
+		map[string]interface{}{
+			"current_amps": 1.2,
+			"power_watts":  265.0,
+			"energy_kwh":   1234.5,
+		},
+		map[string]string{"agent_host": "10.0.0.1", "outlet": "1", "label": "node01"})
+	acc.AssertContainsTaggedFields(t, "pdu_outlet",
+		map[string]interface{}{
+			"current_amps": 0.0,
+			"power_watts":  0.0,
+			"energy_kwh":   2.0,
+		},
+		map[string]string{"agent_host": "10.0.0.1", "outlet": "2", "label": "node02"})
+	acc.AssertContainsTaggedFields(t, "pdu_phase",
+		map[string]interface{}{
+			"current_amps":  6.4,
+			"voltage_volts": 230.0,
+			"power_watts":   1420.0,
+		},
+		map[string]string{"agent_host": "10.0.0.1", "phase": "1"})
+}
+
+func TestGatherRaritan(t *testing.T) {
+	agent := &fakeAgent{
+		host: "10.0.0.2",
+		values: map[string]interface{}{
+			".1.3.6.1.4.1.13742.6.3.5.3.1.3.1.1":   "node01",
+			".1.3.6.1.4.1.13742.6.5.4.3.1.4.1.1.1": uint(1234),
+			".1.3.6.1.4.1.13742.6.5.4.3.1.4.1.1.4": uint(230),
+			".1.3.6.1.4.1.13742.6.5.4.3.1.4.1.1.5": uint(281),
+			".1.3.6.1.4.1.13742.6.5.4.3.1.4.1.1.8": uint(5432100),
+			".1.3.6.1.4.1.13742.6.3.5.4.1.7.1.1.1": uint(3),
+			".1.3.6.1.4.1.13742.6.3.5.4.1.7.1.1.4": uint(0),
+			".1.3.6.1.4.1.13742.6.3.5.4.1.7.1.1.5": uint(0),
+		},
+	}
+	p := newPdu(t, "raritan", agent)
+	p.Phases = false
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "pdu_outlet",
+		map[string]interface{}{
+			"current_amps": 1.234,
+			"power_watts":  281.0,
+			"energy_kwh":   5432.1,
+		},
+		map[string]string{"agent_host": "10.0.0.2", "outlet": "1.1", "label": "node01"})
+
+	// The sensor value column is walked once for all fields
+	require.Equal(t, 3, agent.walks)
+}
+
+func TestGatherConnectError(t *testing.T) {
+	p := &Pdu{
+		Agents:  []string{"udp://10.0.0.3:161"},
+		Profile: "servertech",
+		Outlets: true,
+		Log:     testutil.Logger{},
+		connect: func(agent string) (snmpConnection, error) {
+			return nil, fmt.Errorf("setting up connection: timeout")
+		},
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "udp://10.0.0.3:161")
+}
+
+func TestInitUnknownProfile(t *testing.T) {
+	p := &Pdu{Profile: "eaton"}
+	require.Error(t, p.Init())
+}
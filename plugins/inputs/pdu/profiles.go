@@ -0,0 +1,109 @@
+package pdu
+
+// profile holds the OIDs of the outlet and phase tables of the PDUs of a
+// vendor.
+type profile struct {
+	outlets table
+	phases  table
+}
+
+// table is a conceptual table of the MIB of a PDU.  The rows are identified
+// by the index of their OIDs, the label column optionally names them.
+type table struct {
+	label   string
+	columns []column
+}
+
+// column is a column of a table read into a field.  The raw value is
+// multiplied by scale, or divided by ten to the power of the value of the
+// digits column of the same row.  Columns of sensor tables indexed by the
+// sensor type as last component only include the rows of the sensor type
+// given as suffix.
+type column struct {
+	oid    string
+	field  string
+	scale  float64
+	digits string
+	suffix string
+}
+
+var profiles = map[string]profile{
+	// PowerNet-MIB, rPDU2OutletMeteredStatusTable and rPDU2PhaseStatusTable
+	"apc": {
+		outlets: table{
+			label: "1.3.6.1.4.1.318.1.1.26.9.4.3.1.3",
+			columns: []column{
+				{oid: "1.3.6.1.4.1.318.1.1.26.9.4.3.1.6", field: "current_amps", scale: 0.1},
+				{oid: "1.3.6.1.4.1.318.1.1.26.9.4.3.1.7", field: "power_watts", scale: 1},
+				{oid: "1.3.6.1.4.1.318.1.1.26.9.4.3.1.11", field: "energy_kwh", scale: 0.1},
+			},
+		},
+		phases: table{
+			columns: []column{
+				{oid: "1.3.6.1.4.1.318.1.1.26.6.3.1.5", field: "current_amps", scale: 0.1},
+				{oid: "1.3.6.1.4.1.318.1.1.26.6.3.1.6", field: "voltage_volts", scale: 1},
+				{oid: "1.3.6.1.4.1.318.1.1.26.6.3.1.7", field: "power_watts", scale: 10},
+			},
+		},
+	},
+	// PDU2-MIB, measurementsOutletSensorValue and measurementsInletPoleSensorValue
+	// indexed by the sensor type: rmsCurrent(1), rmsVoltage(4),
+	// activePower(5) and activeEnergy(8) in Wh.
+	"raritan": {
+		outlets: table{
+			label: "1.3.6.1.4.1.13742.6.3.5.3.1.3",
+			columns: []column{
+				{oid: "1.3.6.1.4.1.13742.6.5.4.3.1.4", field: "current_amps", digits: "1.3.6.1.4.1.13742.6.3.5.4.1.7", suffix: "1"},
+				{oid: "1.3.6.1.4.1.13742.6.5.4.3.1.4", field: "power_watts", digits: "1.3.6.1.4.1.13742.6.3.5.4.1.7", suffix: "5"},
+				{oid: "1.3.6.1.4.1.13742.6.5.4.3.1.4", field: "energy_kwh", scale: 0.001, suffix: "8"},
+			},
+		},
+		phases: table{
+			columns: []column{
+				{oid: "1.3.6.1.4.1.13742.6.5.2.4.1.4", field: "current_amps", digits: "1.3.6.1.4.1.13742.6.3.3.6.1.7", suffix: "1"},
+				{oid: "1.3.6.1.4.1.13742.6.5.2.4.1.4", field: "voltage_volts", digits: "1.3.6.1.4.1.13742.6.3.3.6.1.7", suffix: "4"},
+				{oid: "1.3.6.1.4.1.13742.6.5.2.4.1.4", field: "power_watts", digits: "1.3.6.1.4.1.13742.6.3.3.6.1.7", suffix: "5"},
+			},
+		},
+	},
+	// GEIST-V5-MIB of Vertiv Geist PDUs, outletTable and phaseTable
+	"vertiv": {
+		outlets: table{
+			label: "1.3.6.1.4.1.21239.5.2.3.5.1.3",
+			columns: []column{
+				{oid: "1.3.6.1.4.1.21239.5.2.3.5.1.8", field: "current_amps", scale: 0.01},
+				{oid: "1.3.6.1.4.1.21239.5.2.3.5.1.12", field: "power_watts", scale: 1},
+				{oid: "1.3.6.1.4.1.21239.5.2.3.5.1.6", field: "energy_kwh", scale: 0.1},
+			},
+		},
+		phases: table{
+			label: "1.3.6.1.4.1.21239.5.2.3.3.1.3",
+			columns: []column{
+				{oid: "1.3.6.1.4.1.21239.5.2.3.3.1.8", field: "current_amps", scale: 0.01},
+				{oid: "1.3.6.1.4.1.21239.5.2.3.3.1.6", field: "voltage_volts", scale: 0.1},
+				{oid: "1.3.6.1.4.1.21239.5.2.3.3.1.12", field: "power_watts", scale: 1},
+				{oid: "1.3.6.1.4.1.21239.5.2.3.3.1.4", field: "energy_kwh", scale: 0.1},
+			},
+		},
+	},
+	// Sentry4-MIB, st4OutletMonitorTable and st4PhaseMonitorTable
+	"servertech": {
+		outlets: table{
+			label: "1.3.6.1.4.1.1718.4.1.8.2.1.3",
+			columns: []column{
+				{oid: "1.3.6.1.4.1.1718.4.1.8.3.1.1", field: "current_amps", scale: 0.01},
+				{oid: "1.3.6.1.4.1.1718.4.1.8.3.1.3", field: "power_watts", scale: 1},
+				{oid: "1.3.6.1.4.1.1718.4.1.8.3.1.9", field: "energy_kwh", scale: 0.1},
+			},
+		},
+		phases: table{
+			label: "1.3.6.1.4.1.1718.4.1.6.2.1.3",
+			columns: []column{
+				{oid: "1.3.6.1.4.1.1718.4.1.6.3.1.3", field: "voltage_volts", scale: 0.1},
+				{oid: "1.3.6.1.4.1.1718.4.1.6.3.1.4", field: "current_amps", scale: 0.01},
+				{oid: "1.3.6.1.4.1.1718.4.1.6.3.1.8", field: "power_watts", scale: 1},
+				{oid: "1.3.6.1.4.1.1718.4.1.6.3.1.10", field: "energy_kwh", scale: 0.1},
+			},
+		},
+	},
+}
@@ -0,0 +1,346 @@
+package perf_event
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Event types of perf_event_open(2).
+const (
+	typeHardware = 0
+	typeHWCache  = 3
+	typeRaw      = 4
+)
+
+// hwCache returns the config of a hardware cache event, the cache id with
+// the operation and result in the following bytes.
+func hwCache(cache, op, result uint64) uint64 {
+	return cache | op<<8 | result<<16
+}
+
+// Cache ids, operations and results of hardware cache events.
+const (
+	cacheL1D  = 0
+	cacheLL   = 2
+	cacheDTLB = 3
+
+	opRead = 0
+
+	resultAccess = 0
+	resultMiss   = 1
+)
+
+// events are the named events, the generic hardware events of the kernel
+// which it maps to the events of the processor.
+var events = map[string]eventSpec{
+	"instructions":            {typ: typeHardware, config: 1},
+	"cycles":                  {typ: typeHardware, config: 0},
+	"ref_cycles":              {typ: typeHardware, config: 9},
+	"cache_references":        {typ: typeHardware, config: 2},
+	"cache_misses":            {typ: typeHardware, config: 3},
+	"branch_instructions":     {typ: typeHardware, config: 4},
+	"branch_misses":           {typ: typeHardware, config: 5},
+	"bus_cycles":              {typ: typeHardware, config: 6},
+	"stalled_cycles_frontend": {typ: typeHardware, config: 7},
+	"stalled_cycles_backend":  {typ: typeHardware, config: 8},
+	"llc_loads":               {typ: typeHWCache, config: hwCache(cacheLL, opRead, resultAccess)},
+	"llc_load_misses":         {typ: typeHWCache, config: hwCache(cacheLL, opRead, resultMiss)},
+	"l1d_loads":               {typ: typeHWCache, config: hwCache(cacheL1D, opRead, resultAccess)},
+	"l1d_load_misses":         {typ: typeHWCache, config: hwCache(cacheL1D, opRead, resultMiss)},
+	"dtlb_load_misses":        {typ: typeHWCache, config: hwCache(cacheDTLB, opRead, resultMiss)},
+}
+
+// onlinePath lists the online CPUs.
+var onlinePath = "/sys/devices/system/cpu/online"
+
+// openCounter opens a counter of the event on the CPU, restricted to the
+// tasks of the cgroup if set.  It is replaced in tests.
+var openCounter = openPerfCounter
+
+// counter is an open hardware counter.
+type counter interface {
+	// read returns the count and the times the counter was enabled and
+	// running, which differ if the counters were multiplexed.
+	read() (value, enabled, running uint64, err error)
+	close() error
+}
+
+// eventSpec is the type and config of an event passed to perf_event_open.
+type eventSpec struct {
+	name   string
+	typ    uint32
+	config uint64
+}
+
+// PerfEvent stores the configuration values for the perf_event input plugin
+type PerfEvent struct {
+	Events     []string          `toml:"events"`
+	RawEvents  map[string]string `toml:"raw_events"`
+	Cgroups    []string          `toml:"cgroups"`
+	CgroupPath string            `toml:"cgroup_path"`
+	PerCPU     bool              `toml:"per_cpu"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	specs  []eventSpec
+	scopes []*scope
+}
+
+// scope are the counters of all CPUs, restricted to a cgroup if set.
+type scope struct {
+	cgroup   string
+	counters map[int][]counter
+	// last holds the previous instructions and cycles of each metric for
+	// the instructions per cycle.
+	last map[string][2]uint64
+}
+
+var sampleConfig = `
+  ## Events counted on all CPUs, one of instructions, cycles, ref_cycles,
+  ## cache_references, cache_misses, branch_instructions, branch_misses,
+  ## bus_cycles, stalled_cycles_frontend, stalled_cycles_backend, llc_loads,
+  ## llc_load_misses, l1d_loads, l1d_load_misses or dtlb_load_misses.
+  ## Processors lack some of them, e.g. Intel processors lack the stalled
+  ## cycles.
+  # events = ["instructions", "cycles", "cache_references", "cache_misses"]
+
+  ## Processor specific events by name and raw config, see the event lists
+  ## of the processor vendor.  E.g. the cycles stalled on memory loads
+  ## missing the L3 cache of Intel Skylake servers:
+  # [inputs.perf_event.raw_events]
+  #   stalls_l3_miss = "0x06a3"
+
+  ## Count the events of the tasks of these cgroups instead of the whole
+  ## system, relative to cgroup_path.
+  # cgroups = ["slurm/uid_1000/job_42"]
+  # cgroup_path = "/sys/fs/cgroup/perf_event"
+
+  ## Emit a metric per CPU instead of the sum over all CPUs.
+  # per_cpu = false
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (p *PerfEvent) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (p *PerfEvent) Description() string {
+	return "Count hardware events of the CPUs such as instructions, cycles and cache misses with perf_event_open"
+}
+
+// Init checks the events.
+func (p *PerfEvent) Init() error {
+	if p.CgroupPath == "" {
+		p.CgroupPath = "/sys/fs/cgroup/perf_event"
+	}
+
+	p.specs = nil
+	for _, name := range p.Events {
+		spec, ok := events[name]
+		if !ok {
+			return fmt.Errorf("unknown event %q", name)
+		}
+		spec.name = name
+		p.specs = append(p.specs, spec)
+	}
+	names := make([]string, 0, len(p.RawEvents))
+	for name := range p.RawEvents {
+		if _, ok := events[name]; ok {
+			return fmt.Errorf("raw event %q shadows a named event", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		config, err := strconv.ParseUint(p.RawEvents[name], 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid config %q of raw event %q", p.RawEvents[name], name)
+		}
+		p.specs = append(p.specs, eventSpec{name: name, typ: typeRaw, config: config})
+	}
+	if len(p.specs) == 0 {
+		return fmt.Errorf("no events configured")
+	}
+	return nil
+}
+
+// Start opens the counters, they count until the plugin is stopped.
+func (p *PerfEvent) Start(_ telegraf.Accumulator) error {
+	cpus, err := onlineCPUs()
+	if err != nil {
+		return err
+	}
+
+	cgroups := []string{""}
+	if len(p.Cgroups) > 0 {
+		cgroups = p.Cgroups
+	}
+	for _, cgroup := range cgroups {
+		s := &scope{
+			cgroup:   cgroup,
+			counters: make(map[int][]counter),
+			last:     make(map[string][2]uint64),
+		}
+		p.scopes = append(p.scopes, s)
+
+		path := ""
+		if cgroup != "" {
+			path = strings.TrimSuffix(p.CgroupPath, "/") + "/" + strings.TrimPrefix(cgroup, "/")
+		}
+		for _, cpu := range cpus {
+			for _, spec := range p.specs {
+				c, err := openCounter(spec, cpu, path)
+				if err != nil {
+					p.Stop()
+					return fmt.Errorf("opening %s on cpu %d: %v", spec.name, cpu, err)
+				}
+				s.counters[cpu] = append(s.counters[cpu], c)
+			}
+		}
+	}
+	return nil
+}
+
+// Stop closes the counters.
+func (p *PerfEvent) Stop() {
+	for _, s := range p.scopes {
+		for _, counters := range s.counters {
+			for _, c := range counters {
+				c.close()
+			}
+		}
+	}
+	p.scopes = nil
+}
+
+// Gather is the main execution function for the plugin
+func (p *PerfEvent) Gather(acc telegraf.Accumulator) error {
+	for _, s := range p.scopes {
+		totals := make(map[string]map[string]uint64)
+		cpus := make([]int, 0, len(s.counters))
+		for cpu := range s.counters {
+			cpus = append(cpus, cpu)
+		}
+		sort.Ints(cpus)
+
+		for _, cpu := range cpus {
+			key := ""
+			if p.PerCPU {
+				key = strconv.Itoa(cpu)
+			}
+			if totals[key] == nil {
+				totals[key] = make(map[string]uint64)
+			}
+			for i, c := range s.counters[cpu] {
+				value, enabled, running, err := c.read()
+				if err != nil {
+					acc.AddError(fmt.Errorf("reading %s on cpu %d: %v", p.specs[i].name, cpu, err))
+					continue
+				}
+				totals[key][p.specs[i].name] += scale(value, enabled, running)
+			}
+		}
+
+		for key, counts := range totals {
+			fields := make(map[string]interface{}, len(counts)+1)
+			for name, v := range counts {
+				fields[name] = v
+			}
+			if ipc, ok := s.ipc(key, counts); ok {
+				fields["ipc"] = ipc
+			}
+
+			tags := map[string]string{}
+			if key != "" {
+				tags["cpu"] = key
+			}
+			if s.cgroup != "" {
+				tags["cgroup"] = s.cgroup
+			}
+			acc.AddFields("perf_event", fields, tags)
+		}
+	}
+	return nil
+}
+
+// ipc returns the instructions per cycle since the previous gather.
+func (s *scope) ipc(key string, counts map[string]uint64) (float64, bool) {
+	instructions, ok := counts["instructions"]
+	if !ok {
+		return 0, false
+	}
+	cycles, ok := counts["cycles"]
+	if !ok {
+		return 0, false
+	}
+	last, seen := s.last[key]
+	s.last[key] = [2]uint64{instructions, cycles}
+	if !seen || cycles <= last[1] || instructions < last[0] {
+		return 0, false
+	}
+	return float64(instructions-last[0]) / float64(cycles-last[1]), true
+}
+
+// scale estimates the count of a multiplexed counter, which only counted
+// while running, over the time it was enabled.
+func scale(value, enabled, running uint64) uint64 {
+	if running == 0 || running >= enabled {
+		return value
+	}
+	return uint64(float64(value) * float64(enabled) / float64(running))
+}
+
+// onlineCPUs returns the online CPUs.
+func onlineCPUs() ([]int, error) {
+	b, err := ioutil.ReadFile(onlinePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUList(strings.TrimSpace(string(b)))
+}
+
+// errPermission explains the lack of permission to count events of other
+// processes.
+func errPermission(err error) error {
+	return fmt.Errorf("%v: telegraf needs CAP_PERFMON or CAP_SYS_ADMIN, or kernel.perf_event_paranoid set to 0 or lower", err)
+}
+
+// parseCPUList parses a list of CPUs like "0-3,8,10-11".
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, r := range strings.Split(list, ",") {
+		if r == "" {
+			continue
+		}
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q", list)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpu list %q", list)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+func init() {
+	inputs.Add("perf_event", func() telegraf.Input {
+		return &PerfEvent{
+			Events: []string{"instructions", "cycles", "cache_references", "cache_misses"},
+		}
+	})
+}
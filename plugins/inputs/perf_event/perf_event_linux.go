@@ -0,0 +1,63 @@
+// +build linux
+
+package perf_event
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// perfCounter is a counter opened with perf_event_open.
+type perfCounter struct {
+	fd int
+}
+
+// openPerfCounter opens a counter counting the event on the CPU until it
+// is closed.  Counting the tasks of a cgroup passes the file descriptor of
+// its directory, which the kernel no longer needs once the counter is open.
+func openPerfCounter(spec eventSpec, cpu int, cgroup string) (counter, error) {
+	attr := unix.PerfEventAttr{
+		Type:        spec.typ,
+		Config:      spec.config,
+		Read_format: unix.PERF_FORMAT_TOTAL_TIME_ENABLED | unix.PERF_FORMAT_TOTAL_TIME_RUNNING,
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+
+	pid, flags := -1, unix.PERF_FLAG_FD_CLOEXEC
+	if cgroup != "" {
+		dir, err := unix.Open(cgroup, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer unix.Close(dir)
+		pid, flags = dir, flags|unix.PERF_FLAG_PID_CGROUP
+	}
+
+	fd, err := unix.PerfEventOpen(&attr, pid, cpu, -1, flags)
+	if err != nil {
+		switch err {
+		case unix.EACCES, unix.EPERM:
+			return nil, errPermission(err)
+		case unix.ENOENT, unix.EOPNOTSUPP:
+			return nil, fmt.Errorf("event not supported by the processor: %v", err)
+		}
+		return nil, err
+	}
+	return &perfCounter{fd: fd}, nil
+}
+
+func (c *perfCounter) read() (uint64, uint64, uint64, error) {
+	// value, time enabled and time running in host byte order
+	var v [3]uint64
+	buf := (*[24]byte)(unsafe.Pointer(&v))[:]
+	if _, err := unix.Read(c.fd, buf); err != nil {
+		return 0, 0, 0, err
+	}
+	return v[0], v[1], v[2], nil
+}
+
+func (c *perfCounter) close() error {
+	return unix.Close(c.fd)
+}
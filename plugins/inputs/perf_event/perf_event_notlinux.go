@@ -0,0 +1,11 @@
+// +build !linux
+
+package perf_event
+
+import (
+	"errors"
+)
+
+func openPerfCounter(spec eventSpec, cpu int, cgroup string) (counter, error) {
+	return nil, errors.New("perf_event_open is only available on Linux")
+}
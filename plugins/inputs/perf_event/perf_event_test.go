@@ -0,0 +1,188 @@
+package perf_event
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCounter counts the number of reads times the step of its event.
+type fakeCounter struct {
+	step    uint64
+	reads   uint64
+	enabled uint64
+	running uint64
+	closed  *int
+}
+
+func (c *fakeCounter) read() (uint64, uint64, uint64, error) {
+	c.reads++
+	return c.step * c.reads, c.enabled, c.running, nil
+}
+
+func (c *fakeCounter) close() error {
+	*c.closed++
+	return nil
+}
+
+// fakeCounters replaces openCounter and the online CPUs, it returns the
+// number of closed counters.
+func fakeCounters(t *testing.T, online string, steps map[string]uint64) (*int, func()) {
+	dir, err := ioutil.TempDir("", "perf_event")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "online")
+	require.NoError(t, ioutil.WriteFile(path, []byte(online+"\n"), 0644))
+
+	closed := new(int)
+	onlinePath = path
+	openCounter = func(spec eventSpec, cpu int, cgroup string) (counter, error) {
+		step, ok := steps[spec.name]
+		if !ok {
+			return nil, errors.New("no such file or directory")
+		}
+		c := &fakeCounter{step: step, enabled: 100, running: 100, closed: closed}
+		if cgroup != "" {
+			// Half of the cycles counted by the tasks of the cgroup
+			c.step /= 2
+		}
+		if spec.name == "cache_misses" {
+			// Multiplexed a quarter of the time
+			c.running = 25
+		}
+		return c, nil
+	}
+	return closed, func() {
+		os.RemoveAll(dir)
+		onlinePath = "/sys/devices/system/cpu/online"
+		openCounter = openPerfCounter
+	}
+}
+
+func TestGather(t *testing.T) {
+	closed, restore := fakeCounters(t, "0-1", map[string]uint64{
+		"instructions": 3000,
+		"cycles":       2000,
+		"cache_misses": 10,
+	})
+	defer restore()
+
+	p := &PerfEvent{
+		Events: []string{"instructions", "cycles", "cache_misses"},
+		Log:    testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, p.Start(&acc))
+
+	// The first gather has no instructions per cycle
+	require.NoError(t, p.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "perf_event",
+		map[string]interface{}{
+			"instructions": uint64(6000),
+			"cycles":       uint64(4000),
+			"cache_misses": uint64(80),
+		},
+		map[string]string{})
+
+	acc.ClearMetrics()
+	require.NoError(t, p.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "perf_event",
+		map[string]interface{}{
+			"instructions": uint64(12000),
+			"cycles":       uint64(8000),
+			"cache_misses": uint64(160),
+			"ipc":          1.5,
+		},
+		map[string]string{})
+
+	p.Stop()
+	require.Equal(t, 6, *closed)
+}
+
+func TestGatherPerCPUAndCgroups(t *testing.T) {
+	_, restore := fakeCounters(t, "0,2", map[string]uint64{
+		"instructions": 3000,
+		"cycles":       2000,
+	})
+	defer restore()
+
+	p := &PerfEvent{
+		Events:  []string{"instructions", "cycles"},
+		Cgroups: []string{"slurm/job_42"},
+		PerCPU:  true,
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, p.Start(&acc))
+	defer p.Stop()
+
+	require.NoError(t, p.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "perf_event",
+		map[string]interface{}{
+			"instructions": uint64(1500),
+			"cycles":       uint64(1000),
+		},
+		map[string]string{"cpu": "2", "cgroup": "slurm/job_42"})
+}
+
+func TestStartUnsupportedEvent(t *testing.T) {
+	closed, restore := fakeCounters(t, "0-3", map[string]uint64{
+		"instructions": 3000,
+	})
+	defer restore()
+
+	p := &PerfEvent{
+		Events: []string{"instructions", "stalled_cycles_backend"},
+		Log:    testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+	var acc testutil.Accumulator
+	err := p.Start(&acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "stalled_cycles_backend")
+	// The counters opened before are closed
+	require.Equal(t, 1, *closed)
+}
+
+func TestInit(t *testing.T) {
+	p := &PerfEvent{
+		Events:    []string{"instructions"},
+		RawEvents: map[string]string{"stalls_l3_miss": "0x06a3"},
+	}
+	require.NoError(t, p.Init())
+	require.Equal(t, []eventSpec{
+		{name: "instructions", typ: typeHardware, config: 1},
+		{name: "stalls_l3_miss", typ: typeRaw, config: 0x06a3},
+	}, p.specs)
+
+	require.Error(t, (&PerfEvent{Events: []string{"flops"}}).Init())
+	require.Error(t, (&PerfEvent{RawEvents: map[string]string{"x": "zz"}}).Init())
+	require.Error(t, (&PerfEvent{RawEvents: map[string]string{"cycles": "0x3c"}}).Init())
+	require.Error(t, (&PerfEvent{}).Init())
+}
+
+func TestScale(t *testing.T) {
+	require.Equal(t, uint64(100), scale(100, 10, 10))
+	require.Equal(t, uint64(400), scale(100, 40, 10))
+	require.Equal(t, uint64(0), scale(0, 40, 0))
+}
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,8,10-11")
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1, 2, 3, 8, 10, 11}, cpus)
+
+	_, err = parseCPUList("3-1")
+	require.Error(t, err)
+	_, err = parseCPUList("a")
+	require.Error(t, err)
+}
@@ -0,0 +1,251 @@
+package raid_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// executables are searched in this order if no path is configured.
+var executables = []string{"storcli64", "storcli", "perccli64", "perccli"}
+
+// tasks are the background tasks reported, with the storcli objects and
+// command.
+var tasks = []struct {
+	name    string
+	object  string
+	command string
+}{
+	{"bgi", "/call/vall", "bgi"},
+	{"cc", "/call/vall", "cc"},
+	{"rebuild", "/call/eall/sall", "rebuild"},
+}
+
+type RaidController struct {
+	Path    string            `toml:"path"`
+	UseSudo bool              `toml:"use_sudo"`
+	Timeout internal.Duration `toml:"timeout"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## Optionally specify the path to the storcli or perccli executable, by
+  ## default storcli64, storcli, perccli64 and perccli are searched in PATH.
+  # path = "/opt/MegaRAID/storcli/storcli64"
+
+  ## On most platforms the cli requires root access.
+  ## Setting 'use_sudo' to true will make use of sudo to run it.
+  ## Sudo must be configured to allow the telegraf user to run the cli
+  ## without a password.
+  # use_sudo = false
+
+  ## Timeout for the cli command to complete.
+  # timeout = "30s"
+`
+
+func (r *RaidController) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RaidController) Description() string {
+	return "Read RAID controller, battery and background task status via storcli or perccli"
+}
+
+func (r *RaidController) Init() error {
+	if r.Path == "" {
+		for _, name := range executables {
+			if path, err := exec.LookPath(name); err == nil {
+				r.Path = path
+				break
+			}
+		}
+		if r.Path == "" {
+			return fmt.Errorf("none of %s found: verify that storcli or perccli is installed and in your PATH (or specified in config)",
+				strings.Join(executables, ", "))
+		}
+	}
+	return nil
+}
+
+func (r *RaidController) Gather(acc telegraf.Accumulator) error {
+	out, err := r.run("/call", "show", "all")
+	if err != nil {
+		return err
+	}
+	controllers, err := parseOutput(out, false)
+	if err != nil {
+		return err
+	}
+
+	active := make(map[string]int64)
+	r.gatherTasks(acc, active)
+
+	for _, c := range controllers {
+		var info controllerInfo
+		if err := json.Unmarshal(c.data, &info); err != nil {
+			acc.AddError(fmt.Errorf("parsing status of controller %s failed: %v", c.controller, err))
+			continue
+		}
+
+		tags := map[string]string{
+			"controller": c.controller,
+			"model":      info.Basics.Model,
+			"serial_no":  info.Basics.SerialNumber,
+		}
+		fields := map[string]interface{}{
+			"status":       info.Status.ControllerStatus,
+			"active_tasks": active[c.controller],
+		}
+		if v, ok := hwTemperature(info.HwCfg, "ROC temperature(Degree Celsius)", "ROC temperature(Degree Celcius)"); ok {
+			fields["roc_temperature"] = v
+		}
+		if v, ok := hwTemperature(info.HwCfg, "Ctrl temperature(Degree Celsius)", "Ctrl temperature(Degree Celcius)"); ok {
+			fields["ctrl_temperature"] = v
+		}
+		if info.Status.MemoryCorrectableErrors != nil {
+			fields["memory_correctable_errors"] = *info.Status.MemoryCorrectableErrors
+		}
+		if info.Status.MemoryUncorrectableErrors != nil {
+			fields["memory_uncorrectable_errors"] = *info.Status.MemoryUncorrectableErrors
+		}
+		acc.AddFields("raid_controller", fields, tags)
+
+		for _, b := range info.BBUInfo {
+			fields := batteryFields(b)
+			r.gatherCharge(acc, c.controller, fields)
+			acc.AddFields("raid_controller_battery", fields, map[string]string{
+				"controller": c.controller,
+				"type":       "bbu",
+				"model":      b.Model,
+			})
+		}
+		for _, b := range info.CachevaultInfo {
+			acc.AddFields("raid_controller_battery", batteryFields(b), map[string]string{
+				"controller": c.controller,
+				"type":       "cachevault",
+				"model":      b.Model,
+			})
+		}
+	}
+	return nil
+}
+
+func batteryFields(b batteryInfo) map[string]interface{} {
+	fields := map[string]interface{}{"state": b.State}
+	if v, ok := parseTemperature(b.Temp); ok {
+		fields["temperature"] = v
+	}
+	return fields
+}
+
+// gatherCharge adds the charge of the BBU of the controller to the fields.
+func (r *RaidController) gatherCharge(acc telegraf.Accumulator, controller string, fields map[string]interface{}) {
+	out, err := r.run("/c"+controller+"/bbu", "show", "all")
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+	responses, err := parseOutput(out, true)
+	if err != nil || len(responses) == 0 {
+		return
+	}
+
+	var status bbuStatus
+	if err := json.Unmarshal(responses[0].data, &status); err != nil {
+		acc.AddError(fmt.Errorf("parsing BBU status of controller %s failed: %v", controller, err))
+		return
+	}
+	if v, ok := parsePercent(status.GasGaugeStatus["Relative State of Charge"]); ok {
+		fields["charge_percent"] = v
+	}
+	if v, ok := status.FirmwareStatus["Charging Status"].(string); ok {
+		fields["charging_status"] = v
+	}
+}
+
+// gatherTasks adds the background tasks in progress and counts them per
+// controller.
+func (r *RaidController) gatherTasks(acc telegraf.Accumulator, active map[string]int64) {
+	for _, task := range tasks {
+		out, err := r.run(task.object, "show", task.command)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		responses, err := parseOutput(out, true)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+
+		for _, c := range responses {
+			var statuses []taskStatus
+			if task.name == "rebuild" {
+				err = json.Unmarshal(c.data, &statuses)
+			} else {
+				var ops vdOperations
+				err = json.Unmarshal(c.data, &ops)
+				statuses = ops.Operations
+			}
+			if err != nil {
+				acc.AddError(fmt.Errorf("parsing %s status of controller %s failed: %v", task.name, c.controller, err))
+				continue
+			}
+
+			for _, s := range statuses {
+				if s.Status != "In progress" {
+					continue
+				}
+				target := s.DriveID
+				if target == "" {
+					target = fmt.Sprint(s.VD)
+				}
+				fields := map[string]interface{}{"status": s.Status}
+				if v, ok := parsePercent(s.Progress); ok {
+					fields["progress_percent"] = v
+				}
+				acc.AddFields("raid_controller_task", fields, map[string]string{
+					"controller": c.controller,
+					"task":       task.name,
+					"target":     target,
+				})
+				active[c.controller]++
+			}
+		}
+	}
+}
+
+func (r *RaidController) run(args ...string) ([]byte, error) {
+	args = append(args, "J")
+	out, err := runCmd(r.Timeout, r.UseSudo, r.Path, args...)
+	// storcli exits non-zero if a command fails on any controller, the
+	// details are in the JSON output
+	if err != nil && !json.Valid(out) {
+		return nil, fmt.Errorf("failed to run command '%s %s': %s - %s", r.Path, strings.Join(args, " "), err, string(out))
+	}
+	return out, nil
+}
+
+// Wrap with sudo
+var runCmd = func(timeout internal.Duration, sudo bool, command string, args ...string) ([]byte, error) {
+	cmd := exec.Command(command, args...)
+	if sudo {
+		cmd = exec.Command("sudo", append([]string{"-n", command}, args...)...)
+	}
+	return internal.CombinedOutputTimeout(cmd, timeout.Duration)
+}
+
+func init() {
+	inputs.Add("raid_controller", func() telegraf.Input {
+		return &RaidController{
+			Timeout: internal.Duration{Duration: time.Second * 30},
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package raid_controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// responses maps the storcli arguments to the files holding their output.
+var responses = map[string]string{
+	"/call show all J":               "show_all.json",
+	"/c0/bbu show all J":             "bbu_show_all.json",
+	"/call/vall show bgi J":          "bgi.json",
+	"/call/vall show cc J":           "cc.json",
+	"/call/eall/sall show rebuild J": "rebuild.json",
+}
+
+func mockRunCmd(t *testing.T) func(internal.Duration, bool, string, ...string) ([]byte, error) {
+	return func(timeout internal.Duration, sudo bool, command string, args ...string) ([]byte, error) {
+		file, ok := responses[strings.Join(args, " ")]
+		if !ok {
+			return nil, fmt.Errorf("unexpected command %v", args)
+		}
+		out, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		require.NoError(t, err)
+		return out, nil
+	}
+}
+
+func TestGather(t *testing.T) {
+	orig := runCmd
+	runCmd = mockRunCmd(t)
+	defer func() { runCmd = orig }()
+
+	r := &RaidController{Path: "storcli64", Timeout: internal.Duration{Duration: time.Second}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(r.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("raid_controller_task",
+			map[string]string{"controller": "0", "task": "bgi", "target": "0"},
+			map[string]interface{}{"status": "In progress", "progress_percent": int64(34)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("raid_controller_task",
+			map[string]string{"controller": "0", "task": "rebuild", "target": "/c0/e32/s1"},
+			map[string]interface{}{"status": "In progress", "progress_percent": int64(12)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("raid_controller",
+			map[string]string{"controller": "0", "model": "PERC H730P Mini", "serial_no": "5AT00ZD"},
+			map[string]interface{}{
+				"status":                      "Optimal",
+				"active_tasks":                int64(2),
+				"roc_temperature":             int64(64),
+				"memory_correctable_errors":   int64(0),
+				"memory_uncorrectable_errors": int64(0),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("raid_controller_battery",
+			map[string]string{"controller": "0", "type": "bbu", "model": "BBU"},
+			map[string]interface{}{
+				"state":           "Optimal",
+				"temperature":     int64(34),
+				"charge_percent":  int64(87),
+				"charging_status": "Charging",
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("raid_controller",
+			map[string]string{"controller": "1", "model": "AVAGO MegaRAID SAS 9361-8i", "serial_no": "SK71234567"},
+			map[string]interface{}{
+				"status":                      "Needs Attention",
+				"active_tasks":                int64(0),
+				"roc_temperature":             int64(71),
+				"ctrl_temperature":            int64(58),
+				"memory_correctable_errors":   int64(2),
+				"memory_uncorrectable_errors": int64(0),
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("raid_controller_battery",
+			map[string]string{"controller": "1", "type": "cachevault", "model": "CVPM02"},
+			map[string]interface{}{
+				"state":       "Optimal",
+				"temperature": int64(28),
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherControllerFailure(t *testing.T) {
+	orig := runCmd
+	runCmd = func(timeout internal.Duration, sudo bool, command string, args ...string) ([]byte, error) {
+		return []byte(`{"Controllers":[{"Command Status":{"Controller":"All","Status":"Failure","Description":"No Controller found"}}]}`),
+			fmt.Errorf("exit status 1")
+	}
+	defer func() { runCmd = orig }()
+
+	r := &RaidController{Path: "storcli64", Timeout: internal.Duration{Duration: time.Second}}
+	var acc testutil.Accumulator
+	err := r.Gather(&acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "No Controller found")
+}
+
+func TestParseTemperature(t *testing.T) {
+	v, ok := parseTemperature("34C")
+	require.True(t, ok)
+	require.Equal(t, int64(34), v)
+
+	_, ok = parseTemperature("N/A")
+	require.False(t, ok)
+}
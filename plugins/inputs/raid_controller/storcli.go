@@ -0,0 +1,121 @@
+package raid_controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// storcliOutput is the JSON output of storcli and perccli commands, holding
+// one entry per controller.
+type storcliOutput struct {
+	Controllers []struct {
+		CommandStatus struct {
+			Controller  interface{} `json:"Controller"`
+			Status      string      `json:"Status"`
+			Description string      `json:"Description"`
+		} `json:"Command Status"`
+		ResponseData json.RawMessage `json:"Response Data"`
+	} `json:"Controllers"`
+}
+
+// controllerInfo is the response of "/cX show all".
+type controllerInfo struct {
+	Basics struct {
+		Model        string `json:"Model"`
+		SerialNumber string `json:"Serial Number"`
+	} `json:"Basics"`
+	Status struct {
+		ControllerStatus          string `json:"Controller Status"`
+		MemoryCorrectableErrors   *int64 `json:"Memory Correctable Errors"`
+		MemoryUncorrectableErrors *int64 `json:"Memory Uncorrectable Errors"`
+	} `json:"Status"`
+	HwCfg          map[string]interface{} `json:"HwCfg"`
+	BBUInfo        []batteryInfo          `json:"BBU_Info"`
+	CachevaultInfo []batteryInfo          `json:"Cachevault_Info"`
+}
+
+type batteryInfo struct {
+	Model string `json:"Model"`
+	State string `json:"State"`
+	Temp  string `json:"Temp"`
+}
+
+// bbuStatus is the response of "/cX/bbu show all".
+type bbuStatus struct {
+	GasGaugeStatus map[string]interface{} `json:"GasGaugeStatus"`
+	FirmwareStatus map[string]interface{} `json:"BBU_Firmware_Status"`
+}
+
+// taskStatus is an entry of the responses of "/cX/vall show bgi|cc" and
+// "/cX/eall/sall show rebuild".
+type taskStatus struct {
+	VD       interface{} `json:"VD"`
+	DriveID  string      `json:"Drive-ID"`
+	Progress interface{} `json:"Progress%"`
+	Status   string      `json:"Status"`
+}
+
+// vdOperations is the response of "/cX/vall show bgi|cc".
+type vdOperations struct {
+	Operations []taskStatus `json:"VD Operation Status"`
+}
+
+// controllerResponse is the response data of one controller.
+type controllerResponse struct {
+	controller string
+	data       json.RawMessage
+}
+
+// parseOutput returns the response data of the controllers.  Failures of
+// single controllers are returned as error unless ignoreFailures is set, as
+// storcli reports commands without matching objects, e.g. virtual drives,
+// as failures.
+func parseOutput(out []byte, ignoreFailures bool) ([]controllerResponse, error) {
+	var output storcliOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return nil, fmt.Errorf("parsing output failed: %v", err)
+	}
+
+	var responses []controllerResponse
+	for _, c := range output.Controllers {
+		controller := fmt.Sprint(c.CommandStatus.Controller)
+		if c.CommandStatus.Status != "Success" {
+			if ignoreFailures {
+				continue
+			}
+			return nil, fmt.Errorf("controller %s: %s", controller, c.CommandStatus.Description)
+		}
+		responses = append(responses, controllerResponse{controller: controller, data: c.ResponseData})
+	}
+	return responses, nil
+}
+
+// parseTemperature parses temperatures like "34C" in degree Celsius.
+func parseTemperature(s string) (int64, bool) {
+	v, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(s), "C"), 10, 64)
+	return v, err == nil
+}
+
+// parsePercent parses numbers and strings like "98%" or "98 %".
+func parsePercent(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), true
+	case string:
+		p, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(v, "%")), 10, 64)
+		return p, err == nil
+	}
+	return 0, false
+}
+
+// hwTemperature returns the first of the temperatures reported in HwCfg.
+func hwTemperature(hwcfg map[string]interface{}, keys ...string) (int64, bool) {
+	for _, key := range keys {
+		if v, ok := hwcfg[key].(float64); ok {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
@@ -19,7 +19,8 @@ import (
 
 // Ras plugin gathers and counts errors provided by RASDaemon
 type Ras struct {
-	DBPath string `toml:"db_path"`
+	DBPath    string `toml:"db_path"`
+	MCEEvents bool   `toml:"mce_events"`
 
 	Log telegraf.Logger `toml:"-"`
 	db  *sql.DB         `toml:"-"`
@@ -35,6 +36,11 @@ type machineCheckError struct {
 	SocketID     int
 	ErrorMsg     string
 	MciStatusMsg string
+	CPU          int
+	Bank         int
+	BankName     string
+	Status       int64
+	Address      int64
 }
 
 type metricCounters map[string]int64
@@ -42,7 +48,7 @@ type metricCounters map[string]int64
 const (
 	mceQuery = `
 		SELECT 
-			id, timestamp, error_msg, mcistatus_msg, socketid
+			id, timestamp, error_msg, mcistatus_msg, socketid, cpu, bank, bank_name, status, addr
 		FROM mce_record
 		WHERE timestamp > ?
 		`
@@ -73,6 +79,10 @@ func (r *Ras) SampleConfig() string {
   ## Optional path to RASDaemon sqlite3 database.
   ## Default: /var/lib/rasdaemon/ras-mc_event.db
   # db_path = ""
+
+  ## Add each machine check as a ras_mce metric with the bank, the CPU and
+  ## the type of the error, besides the counters.
+  # mce_events = false
 `
 }
 
@@ -124,6 +134,9 @@ func (r *Ras) Gather(acc telegraf.Accumulator) error {
 			return err
 		}
 		r.updateCounters(mcError)
+		if r.MCEEvents {
+			addMCEEvent(acc, mcError)
+		}
 	}
 
 	addCPUSocketMetrics(acc, r.cpuSocketCounters)
@@ -308,9 +321,66 @@ func addServerMetrics(acc telegraf.Accumulator, counters map[string]int64) {
 	acc.AddCounter("ras", fields, map[string]string{})
 }
 
+// addMCEEvent adds a machine check at the time it was logged.
+func addMCEEvent(acc telegraf.Accumulator, mcError *machineCheckError) {
+	if strings.Contains(mcError.ErrorMsg, "No Error") {
+		return
+	}
+	timestamp, err := parseDate(mcError.Timestamp)
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+
+	tags := map[string]string{
+		"socket_id":  strconv.Itoa(mcError.SocketID),
+		"cpu":        strconv.Itoa(mcError.CPU),
+		"bank":       strconv.Itoa(mcError.Bank),
+		"error_type": errorType(mcError.ErrorMsg),
+		"corrected":  strconv.FormatBool(strings.Contains(mcError.MciStatusMsg, "Corrected_error")),
+	}
+	if mcError.BankName != "" {
+		tags["bank_name"] = mcError.BankName
+	}
+	fields := map[string]interface{}{
+		"error_msg":     mcError.ErrorMsg,
+		"mcistatus_msg": mcError.MciStatusMsg,
+		"status":        fmt.Sprintf("0x%016x", uint64(mcError.Status)),
+	}
+	if mcError.Address != 0 {
+		fields["address"] = fmt.Sprintf("0x%x", uint64(mcError.Address))
+	}
+	acc.AddFields("ras_mce", fields, tags, timestamp)
+}
+
+// errorType classifies a machine check by the unit reporting it, following
+// the counters.
+func errorType(msg string) string {
+	switch {
+	case strings.Contains(msg, "Memory read error") || strings.Contains(msg, "Memory write error") ||
+		strings.Contains(msg, "MEMORY CONTROLLER"):
+		return "memory"
+	case strings.Contains(msg, "CACHE Level-"):
+		return "cache"
+	case strings.Contains(msg, "TLB"):
+		return "tlb"
+	case strings.Contains(msg, "UPI:"):
+		return "upi"
+	case strings.Contains(msg, "BUS"):
+		return "bus"
+	case strings.Contains(msg, "Internal Timer error"), strings.Contains(msg, "SMM Handler Code Access Violation"),
+		strings.Contains(msg, "Internal parity error"), strings.Contains(msg, "FRC error"),
+		strings.Contains(msg, "External error"), strings.Contains(msg, "Microcode ROM parity error"):
+		return "processor"
+	default:
+		return "unclassified"
+	}
+}
+
 func fetchMachineCheckError(rows *sql.Rows) (*machineCheckError, error) {
 	mcError := &machineCheckError{}
-	err := rows.Scan(&mcError.ID, &mcError.Timestamp, &mcError.ErrorMsg, &mcError.MciStatusMsg, &mcError.SocketID)
+	err := rows.Scan(&mcError.ID, &mcError.Timestamp, &mcError.ErrorMsg, &mcError.MciStatusMsg, &mcError.SocketID,
+		&mcError.CPU, &mcError.Bank, &mcError.BankName, &mcError.Status, &mcError.Address)
 
 	if err != nil {
 		return nil, err
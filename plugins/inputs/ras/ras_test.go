@@ -5,11 +5,17 @@ package ras
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUpdateCounters(t *testing.T) {
@@ -253,3 +259,79 @@ var testData = []machineCheckError{
 		MciStatusMsg: "Error_overflow Corrected_error",
 	},
 }
+
+func TestGatherMCEEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ras")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "ras-mc_event.db")
+	db, err := connectToDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE mce_record (id INTEGER PRIMARY KEY, timestamp TEXT, error_msg TEXT,
+		mcistatus_msg TEXT, socketid INTEGER, cpu INTEGER, bank INTEGER, bank_name TEXT, status INTEGER, addr INTEGER)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO mce_record (timestamp, error_msg, mcistatus_msg, socketid, cpu, bank, bank_name, status, addr) VALUES
+		('2020-05-20 07:34:53 +0200', 'MEMORY CONTROLLER RD_CHANNEL0_ERR Transaction: Memory read error', 'Corrected_error', 1, 28, 13, 'Memory Controller 0', -7493989779944505200, 1178951232),
+		('2020-05-20 07:35:11 +0200', 'No Error', 'Corrected_error', 0, 0, 0, '', 0, 0),
+		('2020-05-20 08:25:55 +0200', 'Instruction CACHE Level-2 Generic Error', 'Uncorrected_error', 0, 3, 1, '', 0, 0)`)
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	ras := newRas()
+	ras.DBPath = dbPath
+	ras.MCEEvents = true
+	require.NoError(t, ras.Start(&acc))
+	defer ras.Stop()
+	require.NoError(t, ras.Gather(&acc))
+
+	var events []telegraf.Metric
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "ras_mce" {
+			events = append(events, m)
+		}
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("ras_mce",
+			map[string]string{
+				"socket_id":  "1",
+				"cpu":        "28",
+				"bank":       "13",
+				"bank_name":  "Memory Controller 0",
+				"error_type": "memory",
+				"corrected":  "true",
+			},
+			map[string]interface{}{
+				"error_msg":     "MEMORY CONTROLLER RD_CHANNEL0_ERR Transaction: Memory read error",
+				"mcistatus_msg": "Corrected_error",
+				"status":        "0x9800000000000090",
+				"address":       "0x46455e40",
+			},
+			time.Date(2020, 5, 20, 5, 34, 53, 0, time.UTC)),
+		testutil.MustMetric("ras_mce",
+			map[string]string{
+				"socket_id":  "0",
+				"cpu":        "3",
+				"bank":       "1",
+				"error_type": "cache",
+				"corrected":  "false",
+			},
+			map[string]interface{}{
+				"error_msg":     "Instruction CACHE Level-2 Generic Error",
+				"mcistatus_msg": "Uncorrected_error",
+				"status":        "0x0000000000000000",
+			},
+			time.Date(2020, 5, 20, 6, 25, 55, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, events)
+}
+
+func TestErrorType(t *testing.T) {
+	assert.Equal(t, "memory", errorType("MEMORY CONTROLLER WR_CHANNEL2_ERR Transaction: Memory write error"))
+	assert.Equal(t, "tlb", errorType("Instruction TLB Level-0 Error"))
+	assert.Equal(t, "upi", errorType("UPI: COR LL Rx detected CRC error - successful LLR without Phy Reinit"))
+	assert.Equal(t, "bus", errorType("BUS Level-3 Generic Generic IO Request-did-not-timeout Error"))
+	assert.Equal(t, "processor", errorType("Internal parity error"))
+	assert.Equal(t, "unclassified", errorType("Unclassified"))
+}
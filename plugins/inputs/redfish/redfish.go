@@ -12,6 +12,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	rfsession "github.com/influxdata/telegraf/plugins/common/redfish"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
@@ -25,12 +26,29 @@ const sampleConfig = `
   username = "root"
   password = "password123456"
 
-  ## ComputerSystemId
+  ## ComputerSystemId, the chassis of the system are gathered.  Leave empty to
+  ## gather the power and thermal readings of all chassis of the BMC.
   computer_system_id="2M220100SL"
 
+  ## Authentication method, "basic" sends the credentials with each request,
+  ## "session" logs in once and uses the session token, recommended for
+  ## current BMCs which limit or slow down basic authentication.
+  # auth = "basic"
+
+  ## Maximum number of concurrent requests to the BMC with session
+  ## authentication.  The session and the limit are shared with the other
+  ## Redfish plugins reading the BMC, the lowest limit configured applies.
+  # max_concurrent_requests = 4
+
   ## Amount of time allowed to complete the HTTP request
   # timeout = "5s"
 
+  ## Add the firmware versions listed by the UpdateService as
+  ## redfish_firmware metrics.  They only change with updates and are read
+  ## once per firmware_interval.
+  # firmware_inventory = false
+  # firmware_interval = "1h"
+
   ## Optional TLS Config
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
@@ -44,11 +62,30 @@ type Redfish struct {
 	Username         string          `toml:"username"`
 	Password         string          `toml:"password"`
 	ComputerSystemId string          `toml:"computer_system_id"`
+	Auth             string          `toml:"auth"`
 	Timeout          config.Duration `toml:"timeout"`
 
+	MaxConcurrentRequests int `toml:"max_concurrent_requests"`
+
+	FirmwareInventory bool            `toml:"firmware_inventory"`
+	FirmwareInterval  config.Duration `toml:"firmware_interval"`
+
 	client http.Client
 	tls.ClientConfig
 	baseURL *url.URL
+
+	// session is the session shared with the other Redfish plugins when
+	// using session authentication.
+	session *rfsession.Session
+
+	// firmwareGathered is when the firmware inventory was last read.
+	firmwareGathered time.Time
+}
+
+type Collection struct {
+	Members []struct {
+		Ref string `json:"@odata.id"`
+	}
 }
 
 type System struct {
@@ -61,8 +98,20 @@ type System struct {
 }
 
 type Chassis struct {
+	Id       string
+	Name     string
 	Location *Location
-	Power    struct {
+	Status   Status
+
+	// IndicatorLED is the identify LED, replaced by LocationIndicatorActive
+	// in newer schema versions.
+	IndicatorLED            string
+	LocationIndicatorActive *bool
+	PhysicalSecurity        *struct {
+		IntrusionSensor string
+	}
+
+	Power struct {
 		Ref string `json:"@odata.id"`
 	}
 	Thermal struct {
@@ -113,6 +162,17 @@ type Thermal struct {
 	}
 }
 
+// SoftwareInventory is a firmware component listed by the UpdateService.
+type SoftwareInventory struct {
+	Id           string
+	Name         string
+	Version      string
+	Manufacturer string
+	ReleaseDate  string
+	Updateable   *bool
+	Status       Status
+}
+
 type Location struct {
 	PostalAddress struct {
 		DataCenter string
@@ -146,8 +206,12 @@ func (r *Redfish) Init() error {
 		return fmt.Errorf("did not provide username and password")
 	}
 
-	if r.ComputerSystemId == "" {
-		return fmt.Errorf("did not provide the computer system ID of the resource")
+	switch r.Auth {
+	case "":
+		r.Auth = "basic"
+	case "basic", "session":
+	default:
+		return fmt.Errorf("invalid auth %q, expected basic or session", r.Auth)
 	}
 
 	var err error
@@ -156,6 +220,11 @@ func (r *Redfish) Init() error {
 		return err
 	}
 
+	if r.Auth == "session" {
+		r.session = rfsession.GetSession(r.baseURL, r.Username, r.Password)
+		r.session.SetMaxConcurrency(r.MaxConcurrentRequests)
+	}
+
 	tlsCfg, err := r.ClientConfig.TLSConfig()
 	if err != nil {
 		return err
@@ -173,16 +242,7 @@ func (r *Redfish) Init() error {
 }
 
 func (r *Redfish) getData(url string, payload interface{}) error {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	req.SetBasicAuth(r.Username, r.Password)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("OData-Version", "4.0")
-	resp, err := r.client.Do(req)
+	resp, err := r.get(url)
 	if err != nil {
 		return err
 	}
@@ -207,6 +267,37 @@ func (r *Redfish) getData(url string, payload interface{}) error {
 	return nil
 }
 
+func (r *Redfish) get(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+
+	if r.Auth == "session" {
+		return r.session.Do(&r.client, req)
+	}
+	req.SetBasicAuth(r.Username, r.Password)
+	return r.client.Do(req)
+}
+
+// getChassisRefs returns the references of all chassis of the BMC.
+func (r *Redfish) getChassisRefs() ([]string, error) {
+	loc := r.baseURL.ResolveReference(&url.URL{Path: "/redfish/v1/Chassis"})
+	collection := &Collection{}
+	err := r.getData(loc.String(), collection)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, 0, len(collection.Members))
+	for _, member := range collection.Members {
+		refs = append(refs, member.Ref)
+	}
+	return refs, nil
+}
+
 func (r *Redfish) getComputerSystem(id string) (*System, error) {
 	loc := r.baseURL.ResolveReference(&url.URL{Path: path.Join("/redfish/v1/Systems/", id)})
 	system := &System{}
@@ -253,6 +344,32 @@ func (r *Redfish) Gather(acc telegraf.Accumulator) error {
 		address = r.baseURL.Host
 	}
 
+	if r.FirmwareInventory && time.Since(r.firmwareGathered) >= time.Duration(r.FirmwareInterval) {
+		// A BMC without firmware inventory still has readings
+		if err := r.gatherFirmware(acc, address); err != nil {
+			acc.AddError(fmt.Errorf("firmware inventory: %v", err))
+		} else {
+			r.firmwareGathered = time.Now()
+		}
+	}
+
+	if r.ComputerSystemId == "" {
+		refs, err := r.getChassisRefs()
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			chassis, err := r.getChassis(ref)
+			if err != nil {
+				return err
+			}
+			if err := r.gatherChassis(acc, address, "", chassis); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	system, err := r.getComputerSystem(r.ComputerSystemId)
 	if err != nil {
 		return err
@@ -263,50 +380,62 @@ func (r *Redfish) Gather(acc telegraf.Accumulator) error {
 		if err != nil {
 			return err
 		}
+		if err := r.gatherChassis(acc, address, system.Hostname, chassis); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
+// gatherChassis gathers the thermal and power readings of the chassis.
+// Chassis not gathered through a computer system are tagged with their id
+// instead of the hostname of the system.
+func (r *Redfish) gatherChassis(acc telegraf.Accumulator, address, hostname string, chassis *Chassis) error {
+	newTags := func(name string, status Status) map[string]string {
+		tags := map[string]string{}
+		tags["address"] = address
+		tags["name"] = name
+		if r.ComputerSystemId != "" {
+			tags["source"] = hostname
+		} else {
+			tags["chassis"] = chassis.Id
+		}
+		tags["state"] = status.State
+		tags["health"] = status.Health
+		if chassis.Location != nil {
+			tags["datacenter"] = chassis.Location.PostalAddress.DataCenter
+			tags["room"] = chassis.Location.PostalAddress.Room
+			tags["rack"] = chassis.Location.Placement.Rack
+			tags["row"] = chassis.Location.Placement.Row
+		}
+		return tags
+	}
+
+	if fields := chassisStatus(chassis); len(fields) > 0 {
+		acc.AddFields("redfish_chassis", fields, newTags(chassis.Name, chassis.Status))
+	}
+
+	// Chassis of the BMC such as enclosures may have no thermal or power
+	// resources.
+	if chassis.Thermal.Ref != "" {
 		thermal, err := r.getThermal(chassis.Thermal.Ref)
 		if err != nil {
 			return err
 		}
 
 		for _, j := range thermal.Temperatures {
-			tags := map[string]string{}
-			tags["address"] = address
-			tags["name"] = j.Name
-			tags["source"] = system.Hostname
-			tags["state"] = j.Status.State
-			tags["health"] = j.Status.Health
-			if chassis.Location != nil {
-				tags["datacenter"] = chassis.Location.PostalAddress.DataCenter
-				tags["room"] = chassis.Location.PostalAddress.Room
-				tags["rack"] = chassis.Location.Placement.Rack
-				tags["row"] = chassis.Location.Placement.Row
-			}
-
 			fields := make(map[string]interface{})
 			fields["reading_celsius"] = j.ReadingCelsius
 			fields["upper_threshold_critical"] = j.UpperThresholdCritical
 			fields["upper_threshold_fatal"] = j.UpperThresholdFatal
 			fields["lower_threshold_critical"] = j.LowerThresholdCritical
 			fields["lower_threshold_fatal"] = j.LowerThresholdFatal
-			acc.AddFields("redfish_thermal_temperatures", fields, tags)
+			acc.AddFields("redfish_thermal_temperatures", fields, newTags(j.Name, j.Status))
 		}
 
 		for _, j := range thermal.Fans {
-			tags := map[string]string{}
 			fields := make(map[string]interface{})
-			tags["address"] = address
-			tags["name"] = j.Name
-			tags["source"] = system.Hostname
-			tags["state"] = j.Status.State
-			tags["health"] = j.Status.Health
-			if chassis.Location != nil {
-				tags["datacenter"] = chassis.Location.PostalAddress.DataCenter
-				tags["room"] = chassis.Location.PostalAddress.Room
-				tags["rack"] = chassis.Location.Placement.Rack
-				tags["row"] = chassis.Location.Placement.Row
-			}
-
 			if j.ReadingUnits != nil && *j.ReadingUnits == "RPM" {
 				fields["upper_threshold_critical"] = j.UpperThresholdCritical
 				fields["upper_threshold_fatal"] = j.UpperThresholdFatal
@@ -316,66 +445,105 @@ func (r *Redfish) Gather(acc telegraf.Accumulator) error {
 			} else {
 				fields["reading_percent"] = j.Reading
 			}
-			acc.AddFields("redfish_thermal_fans", fields, tags)
+			acc.AddFields("redfish_thermal_fans", fields, newTags(j.Name, j.Status))
 		}
+	}
 
+	if chassis.Power.Ref != "" {
 		power, err := r.getPower(chassis.Power.Ref)
 		if err != nil {
 			return err
 		}
 
 		for _, j := range power.PowerSupplies {
-			tags := map[string]string{}
-			tags["address"] = address
-			tags["name"] = j.Name
-			tags["source"] = system.Hostname
-			tags["state"] = j.Status.State
-			tags["health"] = j.Status.Health
-			if chassis.Location != nil {
-				tags["datacenter"] = chassis.Location.PostalAddress.DataCenter
-				tags["room"] = chassis.Location.PostalAddress.Room
-				tags["rack"] = chassis.Location.Placement.Rack
-				tags["row"] = chassis.Location.Placement.Row
-			}
-
 			fields := make(map[string]interface{})
 			fields["power_input_watts"] = j.PowerInputWatts
 			fields["power_output_watts"] = j.PowerOutputWatts
 			fields["line_input_voltage"] = j.LineInputVoltage
 			fields["last_power_output_watts"] = j.LastPowerOutputWatts
 			fields["power_capacity_watts"] = j.PowerCapacityWatts
-			acc.AddFields("redfish_power_powersupplies", fields, tags)
+			acc.AddFields("redfish_power_powersupplies", fields, newTags(j.Name, j.Status))
 		}
 
 		for _, j := range power.Voltages {
-			tags := map[string]string{}
-			tags["address"] = address
-			tags["name"] = j.Name
-			tags["source"] = system.Hostname
-			tags["state"] = j.Status.State
-			tags["health"] = j.Status.Health
-			if chassis.Location != nil {
-				tags["datacenter"] = chassis.Location.PostalAddress.DataCenter
-				tags["room"] = chassis.Location.PostalAddress.Room
-				tags["rack"] = chassis.Location.Placement.Rack
-				tags["row"] = chassis.Location.Placement.Row
-			}
-
 			fields := make(map[string]interface{})
 			fields["reading_volts"] = j.ReadingVolts
 			fields["upper_threshold_critical"] = j.UpperThresholdCritical
 			fields["upper_threshold_fatal"] = j.UpperThresholdFatal
 			fields["lower_threshold_critical"] = j.LowerThresholdCritical
 			fields["lower_threshold_fatal"] = j.LowerThresholdFatal
-			acc.AddFields("redfish_power_voltages", fields, tags)
+			acc.AddFields("redfish_power_voltages", fields, newTags(j.Name, j.Status))
 		}
 	}
 
 	return nil
 }
 
+// chassisStatus returns the state of the identify LED and the intrusion
+// sensor of the chassis, the fields are omitted if the BMC lacks them.
+func chassisStatus(chassis *Chassis) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if chassis.IndicatorLED != "" {
+		fields["indicator_led"] = chassis.IndicatorLED
+		fields["identify_led_on"] = chassis.IndicatorLED == "Lit" || chassis.IndicatorLED == "Blinking"
+	}
+	if chassis.LocationIndicatorActive != nil {
+		fields["identify_led_on"] = *chassis.LocationIndicatorActive
+	}
+	if chassis.PhysicalSecurity != nil && chassis.PhysicalSecurity.IntrusionSensor != "" {
+		fields["intrusion_sensor"] = chassis.PhysicalSecurity.IntrusionSensor
+		fields["chassis_intrusion"] = chassis.PhysicalSecurity.IntrusionSensor != "Normal"
+	}
+	return fields
+}
+
+// gatherFirmware gathers the versions of the firmware components listed in
+// the FirmwareInventory of the UpdateService.
+func (r *Redfish) gatherFirmware(acc telegraf.Accumulator, address string) error {
+	loc := r.baseURL.ResolveReference(&url.URL{Path: "/redfish/v1/UpdateService/FirmwareInventory"})
+	collection := &Collection{}
+	if err := r.getData(loc.String(), collection); err != nil {
+		return err
+	}
+
+	for _, member := range collection.Members {
+		loc := r.baseURL.ResolveReference(&url.URL{Path: member.Ref})
+		item := &SoftwareInventory{}
+		if err := r.getData(loc.String(), item); err != nil {
+			return err
+		}
+
+		tags := map[string]string{
+			"address": address,
+			"id":      item.Id,
+			"name":    item.Name,
+		}
+		if item.Status.State != "" {
+			tags["state"] = item.Status.State
+		}
+		if item.Status.Health != "" {
+			tags["health"] = item.Status.Health
+		}
+		fields := map[string]interface{}{"version": item.Version}
+		if item.Manufacturer != "" {
+			fields["manufacturer"] = item.Manufacturer
+		}
+		if item.ReleaseDate != "" {
+			fields["release_date"] = item.ReleaseDate
+		}
+		if item.Updateable != nil {
+			fields["updateable"] = *item.Updateable
+		}
+		acc.AddFields("redfish_firmware", fields, tags)
+	}
+	return nil
+}
+
 func init() {
 	inputs.Add("redfish", func() telegraf.Input {
-		return &Redfish{}
+		return &Redfish{
+			MaxConcurrentRequests: 4,
+			FirmwareInterval:      config.Duration(time.Hour),
+		}
 	})
 }
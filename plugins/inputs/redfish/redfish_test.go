@@ -1,6 +1,8 @@
 package redfish
 
 import (
+	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -46,6 +49,27 @@ func TestDellApis(t *testing.T) {
 	require.NoError(t, err)
 
 	expected_metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"redfish_chassis",
+			map[string]string{
+				"name":       "Computer System Chassis",
+				"source":     "tpa-hostname",
+				"address":    address,
+				"datacenter": "",
+				"health":     "OK",
+				"rack":       "",
+				"room":       "",
+				"row":        "",
+				"state":      "Enabled",
+			},
+			map[string]interface{}{
+				"indicator_led":     "Off",
+				"identify_led_on":   false,
+				"intrusion_sensor":  "Normal",
+				"chassis_intrusion": false,
+			},
+			time.Unix(0, 0),
+		),
 		testutil.MustMetric(
 			"redfish_thermal_temperatures",
 			map[string]string{
@@ -868,3 +892,217 @@ func TestInvalidHPJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionAuthAllChassis(t *testing.T) {
+	var logins int
+	token := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redfish/v1/SessionService/Sessions" {
+			var credentials map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil ||
+				credentials["UserName"] != "test" || credentials["Password"] != "test" {
+				http.Error(w, "Unauthorized.", 401)
+				return
+			}
+			logins++
+			token = fmt.Sprintf("token-%d", logins)
+			w.Header().Set("X-Auth-Token", token)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		if token == "" || r.Header.Get("X-Auth-Token") != token {
+			http.Error(w, "Unauthorized.", 401)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/redfish/v1/Chassis":
+			fmt.Fprint(w, `{"Members": [{"@odata.id": "/redfish/v1/Chassis/System.Embedded.1"}, {"@odata.id": "/redfish/v1/Chassis/Enclosure.Internal.0-1"}]}`)
+		case "/redfish/v1/Chassis/Enclosure.Internal.0-1":
+			fmt.Fprint(w, `{"Id": "Enclosure.Internal.0-1"}`)
+		case "/redfish/v1/Chassis/System.Embedded.1/Thermal":
+			http.ServeFile(w, r, "testdata/dell_thermal.json")
+		case "/redfish/v1/Chassis/System.Embedded.1/Power":
+			http.ServeFile(w, r, "testdata/dell_power.json")
+		case "/redfish/v1/Chassis/System.Embedded.1":
+			http.ServeFile(w, r, "testdata/dell_chassis.json")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := &Redfish{
+		Address:  ts.URL,
+		Username: "test",
+		Password: "test",
+		Auth:     "session",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, 1, logins)
+	require.True(t, acc.HasMeasurement("redfish_thermal_temperatures"))
+	require.True(t, acc.HasMeasurement("redfish_power_powersupplies"))
+	for _, m := range acc.Metrics {
+		require.Equal(t, "System.Embedded.1", m.Tags["chassis"])
+		require.NotContains(t, m.Tags, "source")
+	}
+
+	// Expired sessions are replaced
+	token = ""
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, 2, logins)
+	require.True(t, acc.HasMeasurement("redfish_thermal_temperatures"))
+}
+
+func TestSessionAuthLoginFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized.", 401)
+	}))
+	defer ts.Close()
+
+	plugin := &Redfish{
+		Address:          ts.URL,
+		Username:         "test",
+		Password:         "wrong",
+		ComputerSystemId: "System.Embedded.1",
+		Auth:             "session",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	err := plugin.Gather(&acc)
+	require.EqualError(t, err, "login received status code 401 (Unauthorized), expected 201")
+}
+
+func TestInvalidAuth(t *testing.T) {
+	plugin := &Redfish{
+		Address:  "https://127.0.0.1",
+		Username: "test",
+		Password: "test",
+		Auth:     "digest",
+	}
+	require.Error(t, plugin.Init())
+}
+
+func TestChassisStatus(t *testing.T) {
+	active := true
+	chassis := &Chassis{
+		IndicatorLED:            "Off",
+		LocationIndicatorActive: &active,
+	}
+	chassis.PhysicalSecurity = &struct{ IntrusionSensor string }{"HardwareIntrusion"}
+	require.Equal(t, map[string]interface{}{
+		"indicator_led":     "Off",
+		"identify_led_on":   true,
+		"intrusion_sensor":  "HardwareIntrusion",
+		"chassis_intrusion": true,
+	}, chassisStatus(chassis))
+
+	require.Equal(t, map[string]interface{}{
+		"indicator_led":   "Blinking",
+		"identify_led_on": true,
+	}, chassisStatus(&Chassis{IndicatorLED: "Blinking"}))
+
+	require.Empty(t, chassisStatus(&Chassis{}))
+}
+
+func TestFirmwareInventory(t *testing.T) {
+	var inventoryRequests int
+	firmwareOK := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkAuth(r, "test", "test") {
+			http.Error(w, "Unauthorized.", 401)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/redfish/v1/UpdateService/FirmwareInventory":
+			inventoryRequests++
+			if !firmwareOK {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			http.ServeFile(w, r, "testdata/dell_firmwareinventory.json")
+		case "/redfish/v1/UpdateService/FirmwareInventory/Installed-25227-4.40.00.00":
+			http.ServeFile(w, r, "testdata/dell_firmware_idrac.json")
+		case "/redfish/v1/UpdateService/FirmwareInventory/Installed-159-2.10.2":
+			http.ServeFile(w, r, "testdata/dell_firmware_bios.json")
+		case "/redfish/v1/Chassis/System.Embedded.1/Thermal":
+			http.ServeFile(w, r, "testdata/dell_thermal.json")
+		case "/redfish/v1/Chassis/System.Embedded.1/Power":
+			http.ServeFile(w, r, "testdata/dell_power.json")
+		case "/redfish/v1/Chassis/System.Embedded.1":
+			http.ServeFile(w, r, "testdata/dell_chassis.json")
+		case "/redfish/v1/Systems/System.Embedded.1":
+			http.ServeFile(w, r, "testdata/dell_systems.json")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	address, _, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+
+	plugin := &Redfish{
+		Address:           ts.URL,
+		Username:          "test",
+		Password:          "test",
+		ComputerSystemId:  "System.Embedded.1",
+		FirmwareInventory: true,
+		FirmwareInterval:  config.Duration(time.Hour),
+	}
+	require.NoError(t, plugin.Init())
+
+	// A missing inventory is reported without dropping the readings and
+	// retried on the next gather
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "firmware inventory")
+	require.True(t, acc.HasMeasurement("redfish_thermal_temperatures"))
+	require.False(t, acc.HasMeasurement("redfish_firmware"))
+
+	firmwareOK = true
+	acc = testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "redfish_firmware",
+		map[string]interface{}{
+			"version":      "4.40.00.00",
+			"release_date": "00:00:00Z",
+			"updateable":   true,
+		},
+		map[string]string{
+			"address": address,
+			"id":      "Installed-25227-4.40.00.00",
+			"name":    "Integrated Dell Remote Access Controller",
+			"state":   "Enabled",
+			"health":  "OK",
+		})
+	acc.AssertContainsTaggedFields(t, "redfish_firmware",
+		map[string]interface{}{
+			"version":      "2.10.2",
+			"manufacturer": "Dell Inc.",
+			"updateable":   true,
+		},
+		map[string]string{
+			"address": address,
+			"id":      "Installed-159-2.10.2",
+			"name":    "BIOS",
+		})
+
+	// The inventory is only read again after the interval
+	acc = testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, 2, inventoryRequests)
+	require.False(t, acc.HasMeasurement("redfish_firmware"))
+	require.True(t, acc.HasMeasurement("redfish_power_powersupplies"))
+}
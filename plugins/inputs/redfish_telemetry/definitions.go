@@ -0,0 +1,239 @@
+package redfish_telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+const definitionsURI = "/redfish/v1/TelemetryService/MetricReportDefinitions"
+
+var collectionFunctions = map[string]bool{
+	"Average":   true,
+	"Maximum":   true,
+	"Minimum":   true,
+	"Summation": true,
+}
+
+// ReportDefinition is a metric report definition kept in sync on the BMC.
+type ReportDefinition struct {
+	ID                 string            `toml:"id"`
+	MetricProperties   []string          `toml:"metric_properties"`
+	Interval           internal.Duration `toml:"interval"`
+	CollectionFunction string            `toml:"collection_function"`
+	CollectionDuration internal.Duration `toml:"collection_duration"`
+}
+
+// definition holds the properties of a MetricReportDefinition resource set
+// by the plugin.
+type definition struct {
+	ID                         string `json:"Id,omitempty"`
+	MetricReportDefinitionType string
+	ReportActions              []string
+	ReportUpdates              string
+	Schedule                   struct {
+		RecurrenceInterval string
+	}
+	Metrics []definitionMetric
+}
+
+type definitionMetric struct {
+	MetricProperties   []string
+	CollectionFunction string `json:",omitempty"`
+	CollectionDuration string `json:",omitempty"`
+}
+
+func (d *ReportDefinition) validate() error {
+	if d.ID == "" {
+		return fmt.Errorf("report definition without id")
+	}
+	if len(d.MetricProperties) == 0 {
+		return fmt.Errorf("report definition %s has no metric properties", d.ID)
+	}
+	if d.Interval.Duration <= 0 {
+		return fmt.Errorf("report definition %s has no interval", d.ID)
+	}
+	if d.CollectionFunction != "" && !collectionFunctions[d.CollectionFunction] {
+		return fmt.Errorf("report definition %s has invalid collection function %q", d.ID, d.CollectionFunction)
+	}
+	return nil
+}
+
+// resource returns the MetricReportDefinition resource of the definition,
+// its reports are pushed as events and kept in the metric reports.
+func (d *ReportDefinition) resource() *definition {
+	m := definitionMetric{
+		MetricProperties:   d.MetricProperties,
+		CollectionFunction: d.CollectionFunction,
+	}
+	if d.CollectionDuration.Duration > 0 {
+		m.CollectionDuration = formatDuration(d.CollectionDuration.Duration)
+	}
+
+	def := &definition{
+		ID:                         d.ID,
+		MetricReportDefinitionType: "Periodic",
+		ReportActions:              []string{"RedfishEvent", "LogToMetricReportsCollection"},
+		ReportUpdates:              "Overwrite",
+		Metrics:                    []definitionMetric{m},
+	}
+	def.Schedule.RecurrenceInterval = formatDuration(d.Interval.Duration)
+	return def
+}
+
+// applyDefinitions creates the report definitions missing on the BMC and
+// updates those differing from the configuration.
+func (r *RedfishTelemetry) applyDefinitions() error {
+	for _, d := range r.ReportDefinitions {
+		if err := r.applyDefinition(d); err != nil {
+			return fmt.Errorf("report definition %s: %v", d.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *RedfishTelemetry) applyDefinition(d *ReportDefinition) error {
+	want := d.resource()
+	uri := definitionsURI + "/" + d.ID
+
+	var have definition
+	status, err := r.request("GET", uri, nil, &have)
+	switch {
+	case status == http.StatusNotFound:
+		r.Log.Infof("Creating report definition %s on %s", d.ID, r.host)
+		_, err = r.request("POST", definitionsURI, want, nil)
+		return err
+	case err != nil:
+		return err
+	case equalDefinitions(want, &have):
+		return nil
+	}
+
+	r.Log.Infof("Updating report definition %s on %s", d.ID, r.host)
+	update := *want
+	update.ID = ""
+	_, err = r.request("PATCH", uri, &update, nil)
+	return err
+}
+
+// equalDefinitions compares the definitions, the durations may be formatted
+// differently by the BMC.
+func equalDefinitions(want, have *definition) bool {
+	if have.ID != want.ID ||
+		have.MetricReportDefinitionType != want.MetricReportDefinitionType ||
+		have.ReportUpdates != want.ReportUpdates ||
+		!sameStrings(have.ReportActions, want.ReportActions) ||
+		!equalDurations(have.Schedule.RecurrenceInterval, want.Schedule.RecurrenceInterval) ||
+		len(have.Metrics) != len(want.Metrics) {
+		return false
+	}
+	for i := range want.Metrics {
+		if !reflect.DeepEqual(have.Metrics[i].MetricProperties, want.Metrics[i].MetricProperties) ||
+			have.Metrics[i].CollectionFunction != want.Metrics[i].CollectionFunction ||
+			!equalDurations(have.Metrics[i].CollectionDuration, want.Metrics[i].CollectionDuration) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameStrings compares the lists ignoring their order.
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sa := append([]string(nil), a...)
+	sb := append([]string(nil), b...)
+	sort.Strings(sa)
+	sort.Strings(sb)
+	return reflect.DeepEqual(sa, sb)
+}
+
+func equalDurations(a, b string) bool {
+	if a == b {
+		return true
+	}
+	da, err := parseDuration(a)
+	if err != nil {
+		return false
+	}
+	db, err := parseDuration(b)
+	if err != nil {
+		return false
+	}
+	return da == db
+}
+
+// request sends the JSON body and decodes the response into v if not nil,
+// returning the status code of the response.
+func (r *RedfishTelemetry) request(method, uri string, body, v interface{}) (int, error) {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return 0, err
+		}
+	}
+	req, err := r.newRequest(method, uri, &buf)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		r.token, r.session = "", ""
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%s %s received status code %d (%s)",
+			method, uri,
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+	if v == nil {
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// formatDuration formats the duration as ISO 8601 duration in seconds, such
+// as "PT0.5S".
+func formatDuration(d time.Duration) string {
+	return "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+}
+
+var durationRe = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseDuration parses the ISO 8601 durations used by Redfish, such as
+// "P1DT2H" or "PT0.5S".
+func parseDuration(s string) (time.Duration, error) {
+	m := durationRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var d time.Duration
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(v * float64(unit))
+	}
+	return d, nil
+}
@@ -0,0 +1,135 @@
+package redfish_telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDefinitions(t *testing.T) {
+	type call struct {
+		method string
+		path   string
+		body   map[string]interface{}
+	}
+	var calls []call
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "root" || pass != "secret" {
+			http.Error(w, "Unauthorized.", 401)
+			return
+		}
+		if r.Method != "GET" {
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			calls = append(calls, call{r.Method, r.URL.Path, body})
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		switch r.URL.Path {
+		case definitionsURI + "/Synced":
+			// Same definition with the durations formatted by the BMC
+			fmt.Fprint(w, `{"Id": "Synced", "MetricReportDefinitionType": "Periodic",
+				"ReportActions": ["LogToMetricReportsCollection", "RedfishEvent"], "ReportUpdates": "Overwrite",
+				"Schedule": {"RecurrenceInterval": "PT0H0M1S"},
+				"Metrics": [{"MetricProperties": ["/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts"]}]}`)
+		case definitionsURI + "/Outdated":
+			fmt.Fprint(w, `{"Id": "Outdated", "MetricReportDefinitionType": "Periodic",
+				"ReportActions": ["LogToMetricReportsCollection", "RedfishEvent"], "ReportUpdates": "Overwrite",
+				"Schedule": {"RecurrenceInterval": "PT1M"},
+				"Metrics": [{"MetricProperties": ["/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts"]}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	properties := []string{"/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts"}
+	r := &RedfishTelemetry{
+		Address:       ts.URL,
+		Username:      "root",
+		Password:      "secret",
+		Auth:          "basic",
+		RetryInterval: internal.Duration{Duration: time.Second},
+		ReportDefinitions: []*ReportDefinition{
+			{ID: "Synced", MetricProperties: properties, Interval: internal.Duration{Duration: time.Second}},
+			{ID: "Outdated", MetricProperties: properties, Interval: internal.Duration{Duration: time.Second}},
+			{
+				ID:                 "Missing",
+				MetricProperties:   properties,
+				Interval:           internal.Duration{Duration: 500 * time.Millisecond},
+				CollectionFunction: "Average",
+				CollectionDuration: internal.Duration{Duration: time.Second},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+	require.NoError(t, r.applyDefinitions())
+
+	require.Len(t, calls, 2)
+
+	require.Equal(t, "PATCH", calls[0].method)
+	require.Equal(t, definitionsURI+"/Outdated", calls[0].path)
+	require.NotContains(t, calls[0].body, "Id")
+	require.Equal(t, map[string]interface{}{"RecurrenceInterval": "PT1S"}, calls[0].body["Schedule"])
+
+	require.Equal(t, "POST", calls[1].method)
+	require.Equal(t, definitionsURI, calls[1].path)
+	require.Equal(t, map[string]interface{}{
+		"Id":                         "Missing",
+		"MetricReportDefinitionType": "Periodic",
+		"ReportActions":              []interface{}{"RedfishEvent", "LogToMetricReportsCollection"},
+		"ReportUpdates":              "Overwrite",
+		"Schedule":                   map[string]interface{}{"RecurrenceInterval": "PT0.5S"},
+		"Metrics": []interface{}{
+			map[string]interface{}{
+				"MetricProperties":   []interface{}{properties[0]},
+				"CollectionFunction": "Average",
+				"CollectionDuration": "PT1S",
+			},
+		},
+	}, calls[1].body)
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Duration
+	}{
+		{"PT1S", time.Second},
+		{"PT0.5S", 500 * time.Millisecond},
+		{"PT0H0M1S", time.Second},
+		{"PT5M", 5 * time.Minute},
+		{"P1DT2H", 26 * time.Hour},
+	}
+	for _, tt := range tests {
+		d, err := parseDuration(tt.in)
+		require.NoError(t, err, tt.in)
+		require.Equal(t, tt.expected, d, tt.in)
+	}
+
+	for _, in := range []string{"", "P", "PT", "1S", "PT1X"} {
+		_, err := parseDuration(in)
+		require.Error(t, err, in)
+	}
+}
+
+func TestInvalidDefinition(t *testing.T) {
+	r := &RedfishTelemetry{
+		Address:       "https://127.0.0.1",
+		RetryInterval: internal.Duration{Duration: time.Second},
+		ReportDefinitions: []*ReportDefinition{
+			{ID: "Power", Interval: internal.Duration{Duration: time.Second}},
+		},
+	}
+	require.Error(t, r.Init())
+}
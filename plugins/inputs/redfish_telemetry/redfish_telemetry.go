@@ -0,0 +1,495 @@
+package redfish_telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = "redfish_telemetry"
+
+var sampleConfig = `
+  ## URL of the BMC
+  address = "https://127.0.0.1"
+
+  ## Credentials of a user allowed to read the event service
+  username = "root"
+  password = ""
+
+  ## Authentication method, "basic" sends the credentials with each request,
+  ## "session" logs in once and uses the session token.  The session is
+  ## deleted when Telegraf stops.
+  # auth = "session"
+
+  ## Ids of the metric reports to ingest, empty for all reports pushed by
+  ## the BMC.
+  # reports = []
+
+  ## Delay before reconnecting after the stream failed or was closed by the
+  ## BMC.
+  # retry_interval = "10s"
+
+  ## Timeout for requests other than the stream
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Metric report definitions created on the BMC, or updated if they differ,
+  ## when connecting.  The reports are pushed to the event stream.
+  # [[inputs.redfish_telemetry.report_definition]]
+  #   id = "TelegrafPower"
+  #   ## Properties of the BMC resources reported, wildcards are supported
+  #   ## by some BMCs.
+  #   metric_properties = [
+  #     "/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts",
+  #   ]
+  #   ## Interval of the reports
+  #   interval = "1s"
+  #   ## Optional function of the values collected over collection_duration
+  #   ## reported instead of the current values, one of Average, Maximum,
+  #   ## Minimum or Summation.
+  #   # collection_function = "Average"
+  #   # collection_duration = "1s"
+`
+
+// RedfishTelemetry ingests the metric reports pushed by a BMC over the
+// server-sent events stream of the Redfish event service.
+type RedfishTelemetry struct {
+	Address       string            `toml:"address"`
+	Username      string            `toml:"username"`
+	Password      string            `toml:"password"`
+	Auth          string            `toml:"auth"`
+	Reports       []string          `toml:"reports"`
+	RetryInterval internal.Duration `toml:"retry_interval"`
+	httpconfig.HTTPClientConfig
+
+	ReportDefinitions []*ReportDefinition `toml:"report_definition"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	baseURL *url.URL
+	host    string
+	client  *http.Client
+	// stream is the client of the event stream, without a timeout.
+	stream  *http.Client
+	reports map[string]bool
+
+	// token and session are the token and the URI of the session, only
+	// used by the receiver and after it stopped.
+	token   string
+	session string
+	// applied is set once the report definitions are in sync.
+	applied bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// metricReport is a MetricReport resource, the values are strings by the
+// schema but some BMCs send numbers.
+type metricReport struct {
+	Type                   string `json:"@odata.type"`
+	ID                     string `json:"Id"`
+	Timestamp              string
+	MetricReportDefinition struct {
+		Ref string `json:"@odata.id"`
+	}
+	MetricValues []struct {
+		MetricID       string `json:"MetricId"`
+		MetricValue    interface{}
+		MetricProperty string
+		Timestamp      string
+	}
+}
+
+func (r *RedfishTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RedfishTelemetry) Description() string {
+	return "Receive the metric reports pushed by BMCs over the Redfish event service stream"
+}
+
+func (r *RedfishTelemetry) Init() error {
+	if r.Address == "" {
+		return fmt.Errorf("no address configured")
+	}
+	switch r.Auth {
+	case "":
+		r.Auth = "session"
+	case "basic", "session":
+	default:
+		return fmt.Errorf("invalid auth %q, expected basic or session", r.Auth)
+	}
+	if r.RetryInterval.Duration <= 0 {
+		return fmt.Errorf("retry_interval must be positive")
+	}
+
+	var err error
+	r.baseURL, err = url.Parse(r.Address)
+	if err != nil {
+		return err
+	}
+	r.host, _, err = net.SplitHostPort(r.baseURL.Host)
+	if err != nil {
+		r.host = r.baseURL.Host
+	}
+
+	r.client, err = r.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	stream := *r.client
+	stream.Timeout = 0
+	r.stream = &stream
+
+	for _, d := range r.ReportDefinitions {
+		if err := d.validate(); err != nil {
+			return err
+		}
+	}
+
+	r.reports = make(map[string]bool, len(r.Reports))
+	for _, id := range r.Reports {
+		r.reports[id] = true
+	}
+	return nil
+}
+
+// Gather does nothing, the metric reports are added as they arrive.
+func (r *RedfishTelemetry) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+// Start connects to the event stream in the background, reconnecting after
+// failures.
+func (r *RedfishTelemetry) Start(acc telegraf.Accumulator) error {
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			err := r.receive(ctx, acc)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				acc.AddError(fmt.Errorf("event stream of %s: %v", r.host, err))
+			} else {
+				r.Log.Debugf("Event stream of %s closed by the BMC", r.host)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.RetryInterval.Duration):
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop closes the event stream and deletes the session.
+func (r *RedfishTelemetry) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+	r.cancel = nil
+
+	if r.session != "" {
+		if err := r.logout(); err != nil {
+			r.Log.Warnf("Deleting session on %s failed: %v", r.host, err)
+		}
+	}
+}
+
+// receive reads the event stream until it is closed, adding the metric
+// reports.
+func (r *RedfishTelemetry) receive(ctx context.Context, acc telegraf.Accumulator) error {
+	if r.Auth == "session" && r.token == "" {
+		if err := r.login(); err != nil {
+			return err
+		}
+	}
+
+	// Failing definitions are retried on the next connect, the reports of
+	// the BMC are received meanwhile.
+	if !r.applied {
+		if err := r.applyDefinitions(); err != nil {
+			acc.AddError(fmt.Errorf("%s: %v", r.host, err))
+		} else {
+			r.applied = true
+		}
+	}
+
+	uri, err := r.streamURI()
+	if err != nil {
+		return err
+	}
+
+	req, err := r.newRequest("GET", uri, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := r.stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Log in again on the next attempt
+		r.token, r.session = "", ""
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d (%s), expected 200",
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+
+	return readEvents(resp.Body, func(data []byte) {
+		r.addReport(acc, data)
+	})
+}
+
+// readEvents calls handle with the data of each server-sent event.
+func readEvents(body io.Reader, handle func(data []byte)) error {
+	var data bytes.Buffer
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// A blank line dispatches the event, other fields than data and
+		// comments are ignored.
+		if line == "" {
+			if data.Len() > 0 {
+				handle(data.Bytes())
+				data.Reset()
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// addReport adds the values of a metric report, other events are ignored.
+func (r *RedfishTelemetry) addReport(acc telegraf.Accumulator, data []byte) {
+	var report metricReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		acc.AddError(fmt.Errorf("parsing event of %s: %v", r.host, err))
+		return
+	}
+	if !strings.HasPrefix(report.Type, "#MetricReport.") {
+		r.Log.Debugf("Ignoring event of type %q of %s", report.Type, r.host)
+		return
+	}
+	if report.ID == "" && report.MetricReportDefinition.Ref != "" {
+		report.ID = path.Base(report.MetricReportDefinition.Ref)
+	}
+	if len(r.reports) > 0 && !r.reports[report.ID] {
+		return
+	}
+
+	reportTime, err := time.Parse(time.RFC3339Nano, report.Timestamp)
+	if err != nil {
+		reportTime = time.Now()
+	}
+
+	for _, v := range report.MetricValues {
+		var value interface{}
+		switch mv := v.MetricValue.(type) {
+		case string:
+			if f, err := strconv.ParseFloat(mv, 64); err == nil {
+				value = f
+			} else {
+				value = mv
+			}
+		case float64:
+			value = mv
+		default:
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, v.Timestamp)
+		if err != nil {
+			t = reportTime
+		}
+
+		tags := map[string]string{
+			"address":   r.host,
+			"report":    report.ID,
+			"metric_id": v.MetricID,
+		}
+		if v.MetricProperty != "" {
+			tags["metric_property"] = v.MetricProperty
+		}
+		acc.AddFields(measurement, map[string]interface{}{"value": value}, tags, t)
+	}
+}
+
+// streamURI returns the URI of the server-sent events stream announced by
+// the event service.
+func (r *RedfishTelemetry) streamURI() (string, error) {
+	req, err := r.newRequest("GET", "/redfish/v1/EventService", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		r.token, r.session = "", ""
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("event service received status code %d (%s), expected 200",
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+
+	var service struct {
+		ServerSentEventURI string `json:"ServerSentEventUri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&service); err != nil {
+		return "", fmt.Errorf("error parsing event service: %v", err)
+	}
+	if service.ServerSentEventURI == "" {
+		return "", fmt.Errorf("BMC does not support server-sent events")
+	}
+	return service.ServerSentEventURI, nil
+}
+
+// newRequest returns a request of the URI relative to the address of the
+// BMC, authenticated as configured.
+func (r *RedfishTelemetry) newRequest(method, uri string, body io.Reader) (*http.Request, error) {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, r.baseURL.ResolveReference(ref).String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Auth == "session" {
+		req.Header.Set("X-Auth-Token", r.token)
+	} else {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	return req, nil
+}
+
+// login creates a session on the BMC and keeps its token and URI.
+func (r *RedfishTelemetry) login() error {
+	body, err := json.Marshal(map[string]string{
+		"UserName": r.Username,
+		"Password": r.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := r.newRequest("POST", "/redfish/v1/SessionService/Sessions", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Del("X-Auth-Token")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login received status code %d (%s), expected 201",
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+
+	token := resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return fmt.Errorf("login returned no session token")
+	}
+	r.token = token
+	r.session = resp.Header.Get("Location")
+	return nil
+}
+
+// logout deletes the session.
+func (r *RedfishTelemetry) logout() error {
+	req, err := r.newRequest("DELETE", r.session, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	r.token, r.session = "", ""
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("received status code %d (%s)",
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("redfish_telemetry", func() telegraf.Input {
+		return &RedfishTelemetry{
+			Auth:          "session",
+			RetryInterval: internal.Duration{Duration: 10 * time.Second},
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+			},
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package redfish_telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const events = `: keep-alive
+
+id: 1
+data: {"@odata.type": "#Event.v1_4_0.Event", "Id": "1", "Events": [{"MessageId": "ResourceEvent.1.0.ResourceChanged"}]}
+
+id: 2
+data: {"@odata.type": "#MetricReport.v1_3_0.MetricReport", "Id": "PowerMetrics",
+data:  "Timestamp": "2020-12-16T14:05:00Z",
+data:  "MetricValues": [
+data:   {"MetricId": "PowerConsumedWatts", "MetricValue": "221", "Timestamp": "2020-12-16T14:05:00.250Z", "MetricProperty": "/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts"},
+data:   {"MetricId": "PowerState", "MetricValue": "On"}
+data:  ]}
+
+id: 3
+data: {"@odata.type": "#MetricReport.v1_3_0.MetricReport", "MetricReportDefinition": {"@odata.id": "/redfish/v1/TelemetryService/MetricReportDefinitions/ThermalMetrics"},
+data:  "MetricValues": [{"MetricId": "TemperatureReadingCelsius", "MetricValue": 23.5, "Timestamp": "2020-12-16T14:05:00Z"}]}
+
+`
+
+// fakeBMC is a fake BMC serving the event stream once per session.
+type fakeBMC struct {
+	sync.Mutex
+	token   string
+	deleted []string
+}
+
+func (f *fakeBMC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	token := f.token
+	f.Unlock()
+
+	switch {
+	case r.URL.Path == "/redfish/v1/SessionService/Sessions" && r.Method == "POST":
+		var credentials map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil || credentials["Password"] != "secret" {
+			http.Error(w, "Unauthorized.", 401)
+			return
+		}
+		f.Lock()
+		f.token = "token-1"
+		f.Unlock()
+		w.Header().Set("X-Auth-Token", "token-1")
+		w.Header().Set("Location", "/redfish/v1/SessionService/Sessions/1")
+		w.WriteHeader(http.StatusCreated)
+		return
+	case token == "" || r.Header.Get("X-Auth-Token") != token:
+		http.Error(w, "Unauthorized.", 401)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/redfish/v1/SessionService/Sessions/1":
+		f.Lock()
+		f.deleted = append(f.deleted, r.Method)
+		f.token = ""
+		f.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case "/redfish/v1/EventService":
+		fmt.Fprint(w, `{"ServerSentEventUri": "/redfish/v1/EventService/SSE"}`)
+	case "/redfish/v1/EventService/SSE":
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, events)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestReceiveReports(t *testing.T) {
+	fake := &fakeBMC{}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	address, _, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+
+	r := &RedfishTelemetry{
+		Address:       ts.URL,
+		Username:      "telegraf",
+		Password:      "secret",
+		RetryInterval: internal.Duration{Duration: time.Second},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Start(&acc))
+	acc.Wait(3)
+	r.Stop()
+
+	require.Empty(t, acc.Errors)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(measurement,
+			map[string]string{
+				"address":         address,
+				"report":          "PowerMetrics",
+				"metric_id":       "PowerConsumedWatts",
+				"metric_property": "/redfish/v1/Chassis/1/Power#/PowerControl/0/PowerConsumedWatts",
+			},
+			map[string]interface{}{"value": 221.0},
+			time.Date(2020, 12, 16, 14, 5, 0, 250000000, time.UTC)),
+		testutil.MustMetric(measurement,
+			map[string]string{
+				"address":   address,
+				"report":    "PowerMetrics",
+				"metric_id": "PowerState",
+			},
+			map[string]interface{}{"value": "On"},
+			time.Date(2020, 12, 16, 14, 5, 0, 0, time.UTC)),
+		testutil.MustMetric(measurement,
+			map[string]string{
+				"address":   address,
+				"report":    "ThermalMetrics",
+				"metric_id": "TemperatureReadingCelsius",
+			},
+			map[string]interface{}{"value": 23.5},
+			time.Date(2020, 12, 16, 14, 5, 0, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// The session is deleted on stop
+	require.Equal(t, []string{"DELETE"}, fake.deleted)
+}
+
+func TestReceiveSelectedReports(t *testing.T) {
+	r := &RedfishTelemetry{
+		Address:       "https://127.0.0.1",
+		Reports:       []string{"ThermalMetrics"},
+		RetryInterval: internal.Duration{Duration: time.Second},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, readEvents(strings.NewReader(events), func(data []byte) {
+		r.addReport(&acc, data)
+	}))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, "ThermalMetrics", acc.Metrics[0].Tags["report"])
+}
+
+func TestLoginFailure(t *testing.T) {
+	ts := httptest.NewServer(&fakeBMC{})
+	defer ts.Close()
+
+	r := &RedfishTelemetry{
+		Address:       ts.URL,
+		Username:      "telegraf",
+		Password:      "wrong",
+		RetryInterval: internal.Duration{Duration: time.Hour},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Start(&acc))
+	acc.WaitError(1)
+	r.Stop()
+	require.Contains(t, acc.Errors[0].Error(), "login received status code 401")
+}
+
+func TestInit(t *testing.T) {
+	r := &RedfishTelemetry{
+		Address:       "https://127.0.0.1",
+		Auth:          "digest",
+		RetryInterval: internal.Duration{Duration: time.Second},
+	}
+	require.Error(t, r.Init())
+}
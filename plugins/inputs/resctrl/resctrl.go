@@ -0,0 +1,226 @@
+package resctrl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// now is used to mock the time in tests.
+var now = time.Now
+
+// mbmEvents are the memory bandwidth monitoring counters and the fields of
+// their bandwidth.
+var mbmEvents = map[string]string{
+	"mbm_total_bytes": "total_bytes_per_second",
+	"mbm_local_bytes": "local_bytes_per_second",
+}
+
+// gauges are the monitoring values reported as read, llc_occupancy being the
+// bytes of the L3 cache occupied by the group.
+var gauges = []string{"llc_occupancy"}
+
+// Resctrl stores the configuration values for the resctrl input plugin
+type Resctrl struct {
+	Path      string   `toml:"path"`
+	MonGroups []string `toml:"mon_groups"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	monGroupFilter filter.Filter
+	// last holds the previous reading of each counter by its file.
+	last map[string]reading
+}
+
+// reading is a value of a byte counter.
+type reading struct {
+	value     uint64
+	timestamp time.Time
+}
+
+// group is a control or monitoring group of resctrl.
+type group struct {
+	ctrl string
+	mon  string
+	dir  string
+}
+
+var sampleConfig = `
+  ## Mount point of the resctrl file system.
+  # path = "/sys/fs/resctrl"
+
+  ## Monitoring groups to gather, e.g. the groups created for each job by
+  ## the workload manager, by default all.  Control groups are always
+  ## gathered.
+  # mon_groups = ["job_*"]
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (r *Resctrl) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (r *Resctrl) Description() string {
+	return "Read the memory bandwidth and cache occupancy of the resctrl groups"
+}
+
+// Init compiles the group filter.
+func (r *Resctrl) Init() error {
+	if r.Path == "" {
+		r.Path = "/sys/fs/resctrl"
+	}
+	var err error
+	if r.monGroupFilter, err = filter.Compile(r.MonGroups); err != nil {
+		return fmt.Errorf("invalid mon_groups: %v", err)
+	}
+	r.last = make(map[string]reading)
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (r *Resctrl) Gather(acc telegraf.Accumulator) error {
+	if _, err := os.Stat(filepath.Join(r.Path, "mon_data")); err != nil {
+		return fmt.Errorf("no monitoring data found in %s, is resctrl mounted on a processor supporting monitoring? %v", r.Path, err)
+	}
+
+	groups, err := r.groups()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		domains, err := filepath.Glob(filepath.Join(g.dir, "mon_data", "mon_L3_*"))
+		if err != nil {
+			return err
+		}
+		for _, domain := range domains {
+			fields := r.readDomain(domain, seen)
+			if len(fields) == 0 {
+				continue
+			}
+			tags := map[string]string{
+				"ctrl_group": g.ctrl,
+				"domain":     strings.TrimLeft(strings.TrimPrefix(filepath.Base(domain), "mon_L3_"), "0"),
+			}
+			if tags["domain"] == "" {
+				tags["domain"] = "0"
+			}
+			if g.mon != "" {
+				tags["mon_group"] = g.mon
+			}
+			acc.AddFields("resctrl", fields, tags)
+		}
+	}
+
+	// Forget the counters of removed groups, their names are often reused
+	// by later jobs
+	for path := range r.last {
+		if !seen[path] {
+			delete(r.last, path)
+		}
+	}
+	return nil
+}
+
+// groups returns the control groups, the root one named "default", and
+// their monitoring groups.
+func (r *Resctrl) groups() ([]group, error) {
+	ctrlDirs := map[string]string{"default": r.Path}
+	entries, err := ioutil.ReadDir(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "info" || e.Name() == "mon_data" || e.Name() == "mon_groups" {
+			continue
+		}
+		ctrlDirs[e.Name()] = filepath.Join(r.Path, e.Name())
+	}
+
+	names := make([]string, 0, len(ctrlDirs))
+	for name := range ctrlDirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var groups []group
+	for _, ctrl := range names {
+		dir := ctrlDirs[ctrl]
+		groups = append(groups, group{ctrl: ctrl, dir: dir})
+		monDirs, err := ioutil.ReadDir(filepath.Join(dir, "mon_groups"))
+		if err != nil {
+			continue
+		}
+		for _, m := range monDirs {
+			if !m.IsDir() || (r.monGroupFilter != nil && !r.monGroupFilter.Match(m.Name())) {
+				continue
+			}
+			groups = append(groups, group{ctrl: ctrl, mon: m.Name(), dir: filepath.Join(dir, "mon_groups", m.Name())})
+		}
+	}
+	return groups, nil
+}
+
+// readDomain reads the counters of the group in the cache domain.  The
+// counters read "Unavailable" while the hardware has no value, they are
+// skipped.
+func (r *Resctrl) readDomain(dir string, seen map[string]bool) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, event := range gauges {
+		path := filepath.Join(dir, event)
+		value, err := readUint(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				r.Log.Debugf("Skipping %s: %v", path, err)
+			}
+			continue
+		}
+		fields[event] = value
+	}
+	for event, rateField := range mbmEvents {
+		path := filepath.Join(dir, event)
+		value, err := readUint(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				r.Log.Debugf("Skipping %s: %v", path, err)
+			}
+			continue
+		}
+		seen[path] = true
+		fields[event] = value
+
+		timestamp := now()
+		last, ok := r.last[path]
+		r.last[path] = reading{value: value, timestamp: timestamp}
+		elapsed := timestamp.Sub(last.timestamp).Seconds()
+		if ok && value >= last.value && elapsed > 0 {
+			fields[rateField] = float64(value-last.value) / elapsed
+		}
+	}
+	return fields
+}
+
+func readUint(path string) (uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func init() {
+	inputs.Add("resctrl", func() telegraf.Input {
+		return &Resctrl{}
+	})
+}
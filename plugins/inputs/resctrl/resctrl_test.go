@@ -0,0 +1,132 @@
+package resctrl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCounters writes the counters of a group in the cache domain.
+func writeCounters(t *testing.T, dir, domain string, counters map[string]string) {
+	path := filepath.Join(dir, "mon_data", "mon_L3_"+domain)
+	require.NoError(t, os.MkdirAll(path, 0755))
+	for name, value := range counters {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, name), []byte(value+"\n"), 0644))
+	}
+}
+
+func TestGather(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resctrl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	start := time.Unix(1602756000, 0)
+	defer func() { now = time.Now }()
+	now = func() time.Time { return start }
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "info", "L3_MON"), 0755))
+	writeCounters(t, dir, "00", map[string]string{"mbm_total_bytes": "1000000", "mbm_local_bytes": "800000"})
+	writeCounters(t, dir, "01", map[string]string{"mbm_total_bytes": "Unavailable", "mbm_local_bytes": "0"})
+	job := filepath.Join(dir, "mon_groups", "job_42")
+	writeCounters(t, job, "00", map[string]string{"mbm_total_bytes": "5000", "mbm_local_bytes": "5000"})
+	other := filepath.Join(dir, "mon_groups", "other")
+	writeCounters(t, other, "00", map[string]string{"mbm_total_bytes": "1", "mbm_local_bytes": "1"})
+	clos := filepath.Join(dir, "low_prio")
+	writeCounters(t, clos, "00", map[string]string{"mbm_total_bytes": "70", "mbm_local_bytes": "60"})
+
+	r := &Resctrl{Path: dir, MonGroups: []string{"job_*"}, Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4)
+	acc.AssertContainsTaggedFields(t, "resctrl",
+		map[string]interface{}{"mbm_local_bytes": uint64(0)},
+		map[string]string{"ctrl_group": "default", "domain": "1"})
+	acc.AssertContainsTaggedFields(t, "resctrl",
+		map[string]interface{}{"mbm_total_bytes": uint64(70), "mbm_local_bytes": uint64(60)},
+		map[string]string{"ctrl_group": "low_prio", "domain": "0"})
+
+	now = func() time.Time { return start.Add(10 * time.Second) }
+	writeCounters(t, dir, "00", map[string]string{"mbm_total_bytes": "21000000", "mbm_local_bytes": "10800000"})
+	writeCounters(t, job, "00", map[string]string{"mbm_total_bytes": "1005000", "mbm_local_bytes": "505000"})
+
+	acc.ClearMetrics()
+	require.NoError(t, r.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	acc.AssertContainsTaggedFields(t, "resctrl",
+		map[string]interface{}{
+			"mbm_total_bytes":        uint64(21000000),
+			"mbm_local_bytes":        uint64(10800000),
+			"total_bytes_per_second": 2000000.0,
+			"local_bytes_per_second": 1000000.0,
+		},
+		map[string]string{"ctrl_group": "default", "domain": "0"})
+	acc.AssertContainsTaggedFields(t, "resctrl",
+		map[string]interface{}{
+			"mbm_total_bytes":        uint64(1005000),
+			"mbm_local_bytes":        uint64(505000),
+			"total_bytes_per_second": 100000.0,
+			"local_bytes_per_second": 50000.0,
+		},
+		map[string]string{"ctrl_group": "default", "mon_group": "job_42", "domain": "0"})
+
+	// A group recreated with the same name has no bandwidth on its first
+	// reading
+	require.NoError(t, os.RemoveAll(job))
+	acc.ClearMetrics()
+	require.NoError(t, r.Gather(&acc))
+	writeCounters(t, job, "00", map[string]string{"mbm_total_bytes": "2000000", "mbm_local_bytes": "1000000"})
+	acc.ClearMetrics()
+	now = func() time.Time { return start.Add(30 * time.Second) }
+	require.NoError(t, r.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "resctrl",
+		map[string]interface{}{
+			"mbm_total_bytes": uint64(2000000),
+			"mbm_local_bytes": uint64(1000000),
+		},
+		map[string]string{"ctrl_group": "default", "mon_group": "job_42", "domain": "0"})
+}
+
+func TestGatherOccupancy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resctrl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Processors with CMT but without MBM only have the occupancy
+	writeCounters(t, dir, "00", map[string]string{"llc_occupancy": "4718592"})
+	writeCounters(t, filepath.Join(dir, "batch"), "00", map[string]string{"llc_occupancy": "Unavailable"})
+	writeCounters(t, filepath.Join(dir, "batch", "mon_groups", "job_7"), "00", map[string]string{"llc_occupancy": "25165824"})
+
+	r := &Resctrl{Path: dir, Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "resctrl",
+		map[string]interface{}{"llc_occupancy": uint64(4718592)},
+		map[string]string{"ctrl_group": "default", "domain": "0"})
+	acc.AssertContainsTaggedFields(t, "resctrl",
+		map[string]interface{}{"llc_occupancy": uint64(25165824)},
+		map[string]string{"ctrl_group": "batch", "mon_group": "job_7", "domain": "0"})
+}
+
+func TestGatherNotMounted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resctrl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	r := &Resctrl{Path: dir, Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, r.Gather(&acc))
+}
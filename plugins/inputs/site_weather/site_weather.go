@@ -0,0 +1,329 @@
+package site_weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	measurement = "site_weather"
+
+	sourceWeatherLinkLive = "weatherlink_live"
+	sourceAmbient         = "ambient"
+
+	defaultAmbientURL = "https://api.ambientweather.net"
+)
+
+var sampleConfig = `
+  ## Source of the readings, one of
+  ##   weatherlink_live - local HTTP API of a Davis WeatherLink Live
+  ##   ambient          - Ambient Weather REST API
+  source = "weatherlink_live"
+
+  ## Addresses of the WeatherLink Live devices
+  urls = ["http://192.168.1.50"]
+
+  ## Ambient Weather API and application keys, all stations of the account
+  ## are gathered
+  # api_key = ""
+  # application_key = ""
+  # urls = ["https://api.ambientweather.net"]
+
+  ## Timeout for HTTP requests
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Stations update their readings every few seconds to minutes, there is
+  ## no need to query them more often.
+  interval = "1m"
+`
+
+// SiteWeather gathers outdoor conditions from a local weather station.
+type SiteWeather struct {
+	Source         string   `toml:"source"`
+	URLs           []string `toml:"urls"`
+	APIKey         string   `toml:"api_key"`
+	ApplicationKey string   `toml:"application_key"`
+	httpconfig.HTTPClientConfig
+
+	client *http.Client
+}
+
+// reading holds the conditions reported by a station in metric units.
+type reading struct {
+	station     string
+	name        string
+	timestamp   time.Time
+	temperature *float64 // °C
+	humidity    *float64 // %
+	dewPoint    *float64 // °C
+	wetBulb     *float64 // °C
+	pressure    *float64 // hPa
+}
+
+func (s *SiteWeather) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SiteWeather) Description() string {
+	return "Read outdoor dry-bulb and wet-bulb temperatures from a site weather station"
+}
+
+func (s *SiteWeather) Init() error {
+	switch s.Source {
+	case sourceWeatherLinkLive:
+		if len(s.URLs) == 0 {
+			return fmt.Errorf("no urls configured")
+		}
+	case sourceAmbient:
+		if s.APIKey == "" || s.ApplicationKey == "" {
+			return fmt.Errorf("api_key and application_key are required for source %q", s.Source)
+		}
+		if len(s.URLs) == 0 {
+			s.URLs = []string{defaultAmbientURL}
+		}
+	default:
+		return fmt.Errorf("invalid source %q", s.Source)
+	}
+
+	client, err := s.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	s.client = client
+	return nil
+}
+
+func (s *SiteWeather) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+	for _, u := range s.URLs {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+
+			var readings []reading
+			var err error
+			switch s.Source {
+			case sourceWeatherLinkLive:
+				readings, err = s.gatherWeatherLinkLive(u)
+			case sourceAmbient:
+				readings, err = s.gatherAmbient(u)
+			}
+			if err != nil {
+				acc.AddError(err)
+				return
+			}
+
+			for _, r := range readings {
+				s.addReading(acc, r)
+			}
+		}(u)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *SiteWeather) addReading(acc telegraf.Accumulator, r reading) {
+	if r.wetBulb == nil && r.temperature != nil && r.humidity != nil {
+		wb := wetBulb(*r.temperature, *r.humidity)
+		r.wetBulb = &wb
+	}
+
+	fields := make(map[string]interface{})
+	for k, v := range map[string]*float64{
+		"temperature":          r.temperature,
+		"humidity":             r.humidity,
+		"dew_point":            r.dewPoint,
+		"wet_bulb_temperature": r.wetBulb,
+		"pressure":             r.pressure,
+	} {
+		if v != nil {
+			fields[k] = *v
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	tags := map[string]string{
+		"source":  s.Source,
+		"station": r.station,
+	}
+	if r.name != "" {
+		tags["name"] = r.name
+	}
+	acc.AddFields(measurement, fields, tags, r.timestamp)
+}
+
+func (s *SiteWeather) get(u string, v interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s%s returned HTTP status %s: %q", req.URL.Host, req.URL.Path, resp.Status, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// weatherLinkLiveResponse is the current conditions document of a Davis
+// WeatherLink Live, temperatures are in °F and pressures in inHg.
+type weatherLinkLiveResponse struct {
+	Data *struct {
+		DID        string `json:"did"`
+		TS         int64  `json:"ts"`
+		Conditions []struct {
+			DataStructureType int      `json:"data_structure_type"`
+			Temp              *float64 `json:"temp"`
+			Hum               *float64 `json:"hum"`
+			DewPoint          *float64 `json:"dew_point"`
+			WetBulb           *float64 `json:"wet_bulb"`
+			BarSeaLevel       *float64 `json:"bar_sea_level"`
+		} `json:"conditions"`
+	} `json:"data"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (s *SiteWeather) gatherWeatherLinkLive(base string) ([]reading, error) {
+	var resp weatherLinkLiveResponse
+	if err := s.get(strings.TrimRight(base, "/")+"/v1/current_conditions", &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s: %s (%d)", base, resp.Error.Message, resp.Error.Code)
+	}
+	if resp.Data == nil {
+		return nil, fmt.Errorf("%s: no data in response", base)
+	}
+
+	r := reading{
+		station:   resp.Data.DID,
+		timestamp: time.Unix(resp.Data.TS, 0),
+	}
+	for _, c := range resp.Data.Conditions {
+		switch c.DataStructureType {
+		case 1: // ISS current conditions
+			if r.temperature != nil {
+				// only the first transmitter is reported
+				continue
+			}
+			r.temperature = fahrenheitToCelsius(c.Temp)
+			r.humidity = c.Hum
+			r.dewPoint = fahrenheitToCelsius(c.DewPoint)
+			r.wetBulb = fahrenheitToCelsius(c.WetBulb)
+		case 3: // barometer
+			r.pressure = inHgToHPa(c.BarSeaLevel)
+		}
+	}
+	return []reading{r}, nil
+}
+
+// ambientDevice is a station of the Ambient Weather REST API, temperatures
+// are in °F and pressures in inHg.
+type ambientDevice struct {
+	MacAddress string `json:"macAddress"`
+	Info       struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	LastData struct {
+		DateUTC    int64    `json:"dateutc"`
+		TempF      *float64 `json:"tempf"`
+		Humidity   *float64 `json:"humidity"`
+		DewPoint   *float64 `json:"dewPoint"`
+		BaromRelIn *float64 `json:"baromrelin"`
+	} `json:"lastData"`
+}
+
+func (s *SiteWeather) gatherAmbient(base string) ([]reading, error) {
+	params := url.Values{}
+	params.Set("apiKey", s.APIKey)
+	params.Set("applicationKey", s.ApplicationKey)
+
+	var devices []ambientDevice
+	if err := s.get(strings.TrimRight(base, "/")+"/v1/devices?"+params.Encode(), &devices); err != nil {
+		return nil, err
+	}
+
+	readings := make([]reading, 0, len(devices))
+	for _, d := range devices {
+		readings = append(readings, reading{
+			station:     d.MacAddress,
+			name:        d.Info.Name,
+			timestamp:   time.Unix(0, d.LastData.DateUTC*int64(time.Millisecond)),
+			temperature: fahrenheitToCelsius(d.LastData.TempF),
+			humidity:    d.LastData.Humidity,
+			dewPoint:    fahrenheitToCelsius(d.LastData.DewPoint),
+			pressure:    inHgToHPa(d.LastData.BaromRelIn),
+		})
+	}
+	return readings, nil
+}
+
+// wetBulb estimates the wet-bulb temperature in °C from the dry-bulb
+// temperature in °C and the relative humidity in percent at standard sea
+// level pressure, see Stull, R. (2011) "Wet-Bulb Temperature from Relative
+// Humidity and Air Temperature".
+func wetBulb(t, rh float64) float64 {
+	return t*math.Atan(0.151977*math.Sqrt(rh+8.313659)) +
+		math.Atan(t+rh) - math.Atan(rh-1.676331) +
+		0.00391838*math.Pow(rh, 1.5)*math.Atan(0.023101*rh) -
+		4.686035
+}
+
+func fahrenheitToCelsius(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	c := (*f - 32) * 5 / 9
+	return &c
+}
+
+func inHgToHPa(p *float64) *float64 {
+	if p == nil {
+		return nil
+	}
+	hpa := *p * 33.8639
+	return &hpa
+}
+
+func init() {
+	inputs.Add("site_weather", func() telegraf.Input {
+		return &SiteWeather{
+			Source: sourceWeatherLinkLive,
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+			},
+		}
+	})
+}
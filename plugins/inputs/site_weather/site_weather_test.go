@@ -0,0 +1,151 @@
+package site_weather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const weatherLinkLiveResponseBody = `
+{
+  "data": {
+    "did": "001D0A700002",
+    "ts": 1531754005,
+    "conditions": [
+      {
+        "lsid": 48308,
+        "data_structure_type": 1,
+        "txid": 1,
+        "temp": 68.0,
+        "hum": 50.0,
+        "dew_point": 50.0,
+        "wet_bulb": null,
+        "heat_index": 66.6
+      },
+      {
+        "lsid": 48307,
+        "data_structure_type": 4,
+        "temp_in": 78.0,
+        "hum_in": 41.1
+      },
+      {
+        "lsid": 48306,
+        "data_structure_type": 3,
+        "bar_sea_level": 30.0,
+        "bar_trend": null,
+        "bar_absolute": 29.8
+      }
+    ]
+  },
+  "error": null
+}
+`
+
+const ambientResponseBody = `
+[
+  {
+    "macAddress": "00:0E:C6:20:0F:7B",
+    "lastData": {
+      "dateutc": 1515436500000,
+      "tempf": 86.0,
+      "humidity": 40,
+      "baromrelin": 30.0
+    },
+    "info": {
+      "name": "Roof",
+      "location": "Datacenter"
+    }
+  }
+]
+`
+
+func TestWeatherLinkLive(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/current_conditions", r.URL.Path)
+		w.Write([]byte(weatherLinkLiveResponseBody))
+	}))
+	defer ts.Close()
+
+	s := &SiteWeather{
+		Source: sourceWeatherLinkLive,
+		URLs:   []string{ts.URL},
+	}
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(s.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("site_weather",
+			map[string]string{
+				"source":  "weatherlink_live",
+				"station": "001D0A700002",
+			},
+			map[string]interface{}{
+				"temperature":          float64(20),
+				"humidity":             float64(50),
+				"dew_point":            float64(10),
+				"wet_bulb_temperature": wetBulb(20, 50),
+				"pressure":             float64(1015.917),
+			},
+			time.Unix(1531754005, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestAmbient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/devices", r.URL.Path)
+		require.Equal(t, "secret", r.URL.Query().Get("apiKey"))
+		require.Equal(t, "app", r.URL.Query().Get("applicationKey"))
+		w.Write([]byte(ambientResponseBody))
+	}))
+	defer ts.Close()
+
+	s := &SiteWeather{
+		Source:         sourceAmbient,
+		URLs:           []string{ts.URL},
+		APIKey:         "secret",
+		ApplicationKey: "app",
+	}
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(s.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("site_weather",
+			map[string]string{
+				"source":  "ambient",
+				"station": "00:0E:C6:20:0F:7B",
+				"name":    "Roof",
+			},
+			map[string]interface{}{
+				"temperature":          float64(30),
+				"humidity":             float64(40),
+				"wet_bulb_temperature": wetBulb(30, 40),
+				"pressure":             float64(1015.917),
+			},
+			time.Unix(1515436500, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestWetBulb(t *testing.T) {
+	// reference values from Stull (2011)
+	require.InDelta(t, 13.7, wetBulb(20, 50), 0.1)
+	require.InDelta(t, 25.6, wetBulb(30, 70), 0.1)
+}
+
+func TestInitErrors(t *testing.T) {
+	require.Error(t, (&SiteWeather{Source: "davis"}).Init())
+	require.Error(t, (&SiteWeather{Source: sourceWeatherLinkLive}).Init())
+	require.Error(t, (&SiteWeather{Source: sourceAmbient, APIKey: "secret"}).Init())
+}
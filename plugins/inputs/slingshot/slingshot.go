@@ -0,0 +1,148 @@
+package slingshot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Slingshot stores the configuration values for the slingshot input plugin
+type Slingshot struct {
+	Path            string   `toml:"path"`
+	CountersInclude []string `toml:"counters_include"`
+	CountersExclude []string `toml:"counters_exclude"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	counterFilter filter.Filter
+}
+
+var sampleConfig = `
+  ## Path of the cxi class in sysfs.
+  # path = "/sys/class/cxi"
+
+  ## Telemetry counters to report, by their names in the telemetry
+  ## directory of the devices.  The NICs have over a thousand counters,
+  ## by default those of the congestion, retries and traffic are reported.
+  # counters_include = ["hni_*", "pct_*", "lpe_net_*", "parbs_*", "cq_*"]
+  # counters_exclude = []
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (s *Slingshot) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (s *Slingshot) Description() string {
+	return "Read the telemetry counters of HPE Slingshot Cassini NICs from the cxi driver"
+}
+
+// Init sets the defaults and compiles the counter filter.
+func (s *Slingshot) Init() error {
+	if s.Path == "" {
+		s.Path = "/sys/class/cxi"
+	}
+	var err error
+	if s.counterFilter, err = filter.NewIncludeExcludeFilter(s.CountersInclude, s.CountersExclude); err != nil {
+		return fmt.Errorf("invalid counter filter: %v", err)
+	}
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (s *Slingshot) Gather(acc telegraf.Accumulator) error {
+	devices, err := filepath.Glob(filepath.Join(s.Path, "cxi[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no cxi device found in %s", s.Path)
+	}
+	sort.Strings(devices)
+
+	for _, dir := range devices {
+		if err := s.gatherDevice(acc, dir); err != nil {
+			acc.AddError(fmt.Errorf("reading %s: %v", dir, err))
+		}
+	}
+	return nil
+}
+
+func (s *Slingshot) gatherDevice(acc telegraf.Accumulator, dir string) error {
+	device := filepath.Join(dir, "device")
+	files, err := ioutil.ReadDir(filepath.Join(device, "telemetry"))
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !s.counterFilter.Match(name) {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(device, "telemetry", name))
+		if err != nil {
+			// Some counters are not readable on every revision of the NIC
+			continue
+		}
+		value, err := parseCounter(string(content))
+		if err != nil {
+			s.Log.Debugf("Invalid counter %s of %s: %v", name, dir, err)
+			continue
+		}
+		fields[name] = value
+	}
+
+	// The state of the link to the switch
+	if link, err := readString(filepath.Join(device, "port", "link")); err == nil {
+		fields["link"] = link
+	}
+	if speed, err := readString(filepath.Join(device, "port", "speed")); err == nil {
+		fields["speed"] = speed
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	tags := map[string]string{"device": filepath.Base(dir)}
+	if nid, err := readString(filepath.Join(device, "properties", "nid")); err == nil {
+		tags["nid"] = nid
+	}
+	acc.AddFields("slingshot", fields, tags)
+	return nil
+}
+
+// parseCounter parses a telemetry counter, its value followed by the time it
+// was sampled at, e.g. "123456@1608026653.123456789".
+func parseCounter(content string) (uint64, error) {
+	value := strings.TrimSpace(content)
+	if i := strings.Index(value, "@"); i >= 0 {
+		value = value[:i]
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func readString(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func init() {
+	inputs.Add("slingshot", func() telegraf.Input {
+		return &Slingshot{
+			CountersInclude: []string{"hni_*", "pct_*", "lpe_net_*", "parbs_*", "cq_*"},
+		}
+	})
+}
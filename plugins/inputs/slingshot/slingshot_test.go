@@ -0,0 +1,60 @@
+package slingshot
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	s := &Slingshot{
+		Path:            "testdata",
+		CountersInclude: []string{"hni_*", "pct_*", "lpe_net_*"},
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 1)
+
+	acc.AssertContainsTaggedFields(t, "slingshot",
+		map[string]interface{}{
+			"hni_pkts_sent_by_tc_0":    uint64(1024),
+			"hni_rx_paused_0":          uint64(17),
+			"pct_retry_srb_requests":   uint64(3),
+			"lpe_net_match_priority_0": uint64(42),
+			"link":                     "up",
+			"speed":                    "BS_200G",
+		},
+		map[string]string{"device": "cxi0", "nid": "0x12a4"})
+}
+
+func TestGatherNoDevice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slingshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := &Slingshot{Path: dir, Log: testutil.Logger{}}
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, s.Gather(&acc))
+}
+
+func TestParseCounter(t *testing.T) {
+	value, err := parseCounter("123456@1608026653.123456789\n")
+	require.NoError(t, err)
+	require.Equal(t, uint64(123456), value)
+
+	value, err = parseCounter("42")
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), value)
+
+	_, err = parseCounter("@1608026653.1")
+	require.Error(t, err)
+}
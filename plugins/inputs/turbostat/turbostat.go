@@ -0,0 +1,292 @@
+package turbostat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// topologyColumns identify the CPU of a row, they are reported as tags.
+var topologyColumns = map[string]string{
+	"Package": "package",
+	"Die":     "die",
+	"Node":    "node",
+	"Core":    "core",
+	"CPU":     "cpu",
+	"APIC":    "",
+	"X2APIC":  "",
+}
+
+// packagePrefixes are the prefixes of the columns turbostat only prints on
+// the first CPU of each package.
+var packagePrefixes = []string{"Pkg", "PKG_", "RAM", "GFX", "CorWatt", "Cor_J", "Totl%", "Any%", "CPUGFX%", "SYS%", "Uncore", "UncMHz"}
+
+// fieldNames are the field names of columns not following the generic
+// naming.
+var fieldNames = map[string]string{
+	"PkgWatt": "pkg_watts",
+	"CorWatt": "core_watts",
+	"RAMWatt": "ram_watts",
+	"GFXWatt": "gfx_watts",
+	"PkgTmp":  "pkg_temp_c",
+	"CoreTmp": "core_temp_c",
+	"PKG_%":   "pkg_throttled_percent",
+	"RAM_%":   "ram_throttled_percent",
+}
+
+// Turbostat stores the configuration values for the turbostat input plugin
+type Turbostat struct {
+	Path           string            `toml:"path"`
+	UseSudo        bool              `toml:"use_sudo"`
+	SampleDuration internal.Duration `toml:"sample_duration"`
+	Show           []string          `toml:"show"`
+	PerCPU         bool              `toml:"per_cpu"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the turbostat executable
+  # path = "/usr/bin/turbostat"
+
+  ## Setting 'use_sudo' to true will make use of sudo to run turbostat,
+  ## which needs root to read the MSRs.  Sudo must be configured to allow
+  ## the telegraf user to run turbostat without a password.
+  # use_sudo = false
+
+  ## Duration turbostat measures for, the averages of each gather cover this
+  ## duration.  It must be shorter than the interval.
+  # sample_duration = "5s"
+
+  ## Columns to collect, passed to "turbostat --show", by default all.
+  # show = ["Busy%", "Bzy_MHz", "C1%", "C6%", "CoreTmp", "PkgTmp", "Pkg%pc6", "PkgWatt", "RAMWatt"]
+
+  ## Emit a metric per CPU in addition to the system and package summaries.
+  # per_cpu = false
+`
+
+// SampleConfig returns the documentation about the sample configuration
+func (t *Turbostat) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description returns a basic description for the plugin functions
+func (t *Turbostat) Description() string {
+	return "Read the C-state residency, temperatures and package power of the CPUs with turbostat"
+}
+
+// Init locates turbostat.
+func (t *Turbostat) Init() error {
+	if t.SampleDuration.Duration < time.Second {
+		return fmt.Errorf("sample_duration must be at least 1s")
+	}
+	if len(t.Path) == 0 {
+		t.Path = "turbostat"
+	}
+	path, err := exec.LookPath(t.Path)
+	if err != nil {
+		return fmt.Errorf("turbostat not found: verify that turbostat is installed and that turbostat is in your PATH (or specified in config): %v", err)
+	}
+	t.Path = path
+	return nil
+}
+
+// Gather is the main execution function for the plugin
+func (t *Turbostat) Gather(acc telegraf.Accumulator) error {
+	args := []string{
+		"--quiet",
+		"--interval", strconv.FormatFloat(t.SampleDuration.Duration.Seconds(), 'f', -1, 64),
+		"--num_iterations", "1",
+	}
+	if len(t.Show) > 0 {
+		args = append(args, "--show", strings.Join(t.Show, ","))
+	}
+	name := t.Path
+	if t.UseSudo {
+		// -n - avoid prompting the user for input of any kind
+		args = append([]string{"-n", name}, args...)
+		name = "sudo"
+	}
+
+	cmd := execCommand(name, args...)
+	// turbostat measures for the sample duration before printing
+	out, err := internal.CombinedOutputTimeout(cmd, t.SampleDuration.Duration+10*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %s - %s", strings.Join(cmd.Args, " "), err, string(out))
+	}
+	timestamp := time.Now()
+
+	rows, err := parseRows(out)
+	if err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if r.scope == "cpu" && !t.PerCPU {
+			continue
+		}
+		tags := r.tags
+		tags["scope"] = r.scope
+		acc.AddFields("turbostat", r.fields, tags, timestamp)
+	}
+	return nil
+}
+
+// row is a metric parsed from a line of the turbostat output.
+type row struct {
+	scope  string
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// parseRows parses the tab separated output of turbostat.  The first line
+// after the header summarizes the system, marked by "-" in the topology
+// columns.  The package columns of the following lines, one per CPU, are
+// only printed on the first CPU of each package and are reported as a
+// separate package row.
+func parseRows(out []byte) ([]*row, error) {
+	var header []string
+	var rows []*row
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		values := strings.Fields(scanner.Text())
+		if len(values) == 0 {
+			continue
+		}
+		if header == nil {
+			if isHeader(values) {
+				header = values
+			}
+			continue
+		}
+		if values[0] != "-" && !unicode.IsDigit(rune(values[0][0])) {
+			// Warnings printed by turbostat
+			continue
+		}
+		if len(values) > len(header) {
+			return nil, fmt.Errorf("more values than columns in line %q", scanner.Text())
+		}
+
+		summary := values[0] == "-"
+		tags := make(map[string]string)
+		cpuFields := make(map[string]interface{})
+		pkgFields := make(map[string]interface{})
+		for i, value := range values {
+			column := header[i]
+			if tag, ok := topologyColumns[column]; ok {
+				if tag != "" && !summary {
+					tags[tag] = value
+				}
+				continue
+			}
+			v, err := parseValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q of %s", value, column)
+			}
+			if isPackageColumn(column) {
+				pkgFields[fieldName(column)] = v
+			} else {
+				cpuFields[fieldName(column)] = v
+			}
+		}
+
+		if summary {
+			for k, v := range pkgFields {
+				cpuFields[k] = v
+			}
+			rows = append(rows, &row{scope: "system", tags: tags, fields: cpuFields})
+			continue
+		}
+		if len(pkgFields) > 0 {
+			pkgTags := make(map[string]string)
+			if p, ok := tags["package"]; ok {
+				pkgTags["package"] = p
+			} else {
+				// The package column is omitted on single package systems
+				pkgTags["package"] = "0"
+			}
+			rows = append(rows, &row{scope: "package", tags: pkgTags, fields: pkgFields})
+		}
+		if len(cpuFields) > 0 {
+			rows = append(rows, &row{scope: "cpu", tags: tags, fields: cpuFields})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("no header found in output: %s", string(out))
+	}
+	return rows, nil
+}
+
+// isHeader returns whether the line is the column header.
+func isHeader(values []string) bool {
+	for _, v := range values {
+		if v == "CPU" || v == "Busy%" || v == "Avg_MHz" || v == "PkgWatt" {
+			return true
+		}
+	}
+	return false
+}
+
+func isPackageColumn(column string) bool {
+	for _, p := range packagePrefixes {
+		if strings.HasPrefix(column, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldName converts a column to a field name, e.g. "Busy%" to busy_percent,
+// "CPU%c6" to cpu_c6_percent and "Avg_MHz" to avg_mhz.
+func fieldName(column string) string {
+	if name, ok := fieldNames[column]; ok {
+		return name
+	}
+	percent := strings.Contains(column, "%")
+	var b strings.Builder
+	for _, r := range column {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteRune('_')
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if percent {
+		name += "_percent"
+	}
+	return name
+}
+
+// parseValue parses counts as integers and the other values as floats.
+func parseValue(value string) (interface{}, error) {
+	if !strings.Contains(value, ".") {
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v, nil
+		}
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func init() {
+	inputs.Add("turbostat", func() telegraf.Input {
+		return &Turbostat{
+			SampleDuration: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package turbostat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	ts := &Turbostat{
+		Path:           os.Args[0],
+		SampleDuration: internal.Duration{Duration: time.Second},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, ts.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, ts.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 3)
+
+	acc.AssertContainsTaggedFields(t, "turbostat",
+		map[string]interface{}{
+			"avg_mhz":               int64(120),
+			"busy_percent":          4.5,
+			"bzy_mhz":               int64(2667),
+			"tsc_mhz":               int64(2100),
+			"irq":                   int64(4130),
+			"smi":                   int64(0),
+			"c1":                    int64(1200),
+			"c6":                    int64(3000),
+			"c1_percent":            2.1,
+			"c6_percent":            93.2,
+			"cpu_c1_percent":        5.0,
+			"cpu_c6_percent":        90.5,
+			"core_temp_c":           int64(46),
+			"pkg_temp_c":            int64(49),
+			"pkg_pc2_percent":       10.2,
+			"pkg_pc6_percent":       80.1,
+			"pkg_watts":             152.3,
+			"ram_watts":             20.1,
+			"pkg_throttled_percent": 0.0,
+			"ram_throttled_percent": 0.0,
+		},
+		map[string]string{"scope": "system"})
+	acc.AssertContainsTaggedFields(t, "turbostat",
+		map[string]interface{}{
+			"pkg_temp_c":            int64(49),
+			"pkg_pc2_percent":       10.4,
+			"pkg_pc6_percent":       80.2,
+			"pkg_watts":             77.2,
+			"ram_watts":             10.1,
+			"pkg_throttled_percent": 0.0,
+			"ram_throttled_percent": 0.0,
+		},
+		map[string]string{"scope": "package", "package": "1"})
+}
+
+func TestGatherPerCPU(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = fakeExecCommand
+
+	ts := &Turbostat{
+		Path:           os.Args[0],
+		SampleDuration: internal.Duration{Duration: time.Second},
+		Show:           []string{"Busy%", "PkgWatt"},
+		PerCPU:         true,
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, ts.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, ts.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 7)
+
+	// The second thread of a core has no core columns
+	acc.AssertContainsTaggedFields(t, "turbostat",
+		map[string]interface{}{
+			"avg_mhz":      int64(90),
+			"busy_percent": 3.4,
+			"bzy_mhz":      int64(2650),
+			"tsc_mhz":      int64(2100),
+			"irq":          int64(900),
+			"smi":          int64(0),
+			"c1":           int64(280),
+			"c6":           int64(700),
+			"c1_percent":   2.2,
+			"c6_percent":   94.1,
+		},
+		map[string]string{"scope": "cpu", "package": "0", "core": "0", "cpu": "32"})
+}
+
+func TestGatherError(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cmd := fakeExecCommand(command, args...)
+		cmd.Env = append(cmd.Env, "FAKE_TURBOSTAT_FAILURE=turbostat: no /dev/cpu/0/msr, Try \"# modprobe msr\"")
+		return cmd
+	}
+
+	ts := &Turbostat{
+		Path:           os.Args[0],
+		SampleDuration: internal.Duration{Duration: time.Second},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, ts.Init())
+
+	var acc testutil.Accumulator
+	err := ts.Gather(&acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "modprobe msr")
+}
+
+func TestParseRowsSinglePackage(t *testing.T) {
+	rows, err := parseRows([]byte("turbostat version 20.09.30 - Len Brown <lenb@kernel.org>\n" +
+		"Core\tCPU\tBusy%\tCoreTmp\tPkgWatt\n" +
+		"-\t-\t1.50\t40\t12.00\n" +
+		"0\t0\t2.00\t40\t12.00\n" +
+		"0\t1\t1.00\n"))
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	require.Equal(t, "package", rows[1].scope)
+	require.Equal(t, map[string]string{"package": "0"}, rows[1].tags)
+	require.Equal(t, map[string]interface{}{"pkg_watts": 12.0}, rows[1].fields)
+	require.Equal(t, map[string]interface{}{"busy_percent": 1.0}, rows[3].fields)
+
+	_, err = parseRows([]byte("turbostat: cpu0: msr offset 0x611 read failed\n"))
+	require.Error(t, err)
+}
+
+func TestFieldName(t *testing.T) {
+	tests := map[string]string{
+		"Busy%":   "busy_percent",
+		"Avg_MHz": "avg_mhz",
+		"CPU%c6":  "cpu_c6_percent",
+		"Pkg%pc6": "pkg_pc6_percent",
+		"C1E%":    "c1e_percent",
+		"POLL":    "poll",
+		"PkgWatt": "pkg_watts",
+		"PKG_%":   "pkg_throttled_percent",
+		"Pkg_J":   "pkg_j",
+	}
+	for column, expected := range tests {
+		require.Equal(t, expected, fieldName(column), column)
+	}
+}
+
+// fakeExecCommand is a helper function that mock
+// the exec.Command call (and call the test binary)
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// prints the capture of turbostat in testdata.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	if failure, ok := os.LookupEnv("FAKE_TURBOSTAT_FAILURE"); ok {
+		fmt.Fprint(os.Stderr, failure)
+		os.Exit(1)
+	}
+
+	if !strings.Contains(strings.Join(os.Args, " "), "--quiet --interval 1 --num_iterations 1") {
+		fmt.Fprint(os.Stderr, "invalid arguments")
+		os.Exit(1)
+	}
+	out, err := ioutil.ReadFile(filepath.Join("testdata", "turbostat.txt"))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+	// turbostat prints to stderr
+	fmt.Fprint(os.Stderr, string(out))
+	os.Exit(0)
+}
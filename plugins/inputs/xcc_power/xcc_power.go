@@ -0,0 +1,241 @@
+package xcc_power
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/common/redfish"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const measurement = "xcc_power"
+
+// components maps keywords of the power control names reported by the XCC
+// to the component tag, in order of precedence as "Other Sub-system Power"
+// also contains "system".
+var components = []struct {
+	keyword   string
+	component string
+}{
+	{"cpu", "cpu"},
+	{"processor", "cpu"},
+	{"memory", "memory"},
+	{"storage", "storage"},
+	{"drive", "storage"},
+	{"disk", "storage"},
+	{"backplane", "storage"},
+	{"fan", "fan"},
+	{"other", "other"},
+	{"server", "system"},
+	{"system", "system"},
+}
+
+var sampleConfig = `
+  ## URL of the XClarity Controller
+  address = "https://127.0.0.1"
+
+  ## Credentials of a user allowed to read the power resource
+  username = "telegraf"
+  password = ""
+
+  ## Id of the chassis
+  # chassis_id = "1"
+
+  ## Maximum number of concurrent requests to the BMC.  The session and the
+  ## limit are shared with the other Redfish plugins reading the BMC, the
+  ## lowest limit configured applies.
+  # max_concurrent_requests = 4
+
+  ## Timeout for HTTP requests
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// XccPower gathers the power consumed by the components of Lenovo servers
+// from the XClarity Controller.
+type XccPower struct {
+	Address   string `toml:"address"`
+	Username  string `toml:"username"`
+	Password  string `toml:"password"`
+	ChassisID string `toml:"chassis_id"`
+
+	MaxConcurrentRequests int `toml:"max_concurrent_requests"`
+	httpconfig.HTTPClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	baseURL *url.URL
+	host    string
+	client  *http.Client
+	session *redfish.Session
+}
+
+// power is the Power resource, besides the power control of the server the
+// XCC reports one per measured component.
+type power struct {
+	PowerControl []struct {
+		MemberID           string `json:"MemberId"`
+		Name               string
+		PowerConsumedWatts *float64
+		PowerMetrics       struct {
+			AverageConsumedWatts *float64
+			MinConsumedWatts     *float64
+			MaxConsumedWatts     *float64
+		}
+		Oem struct {
+			Lenovo struct {
+				PowerUtilization struct {
+					CapacityMaxAC *float64
+				}
+			}
+		}
+	}
+}
+
+func (x *XccPower) SampleConfig() string {
+	return sampleConfig
+}
+
+func (x *XccPower) Description() string {
+	return "Read the power of the CPU, memory and drive bays of Lenovo servers from the XClarity Controller"
+}
+
+func (x *XccPower) Init() error {
+	if x.Address == "" {
+		return fmt.Errorf("no address configured")
+	}
+	if x.ChassisID == "" {
+		x.ChassisID = "1"
+	}
+
+	var err error
+	x.baseURL, err = url.Parse(x.Address)
+	if err != nil {
+		return err
+	}
+	x.host, _, err = net.SplitHostPort(x.baseURL.Host)
+	if err != nil {
+		x.host = x.baseURL.Host
+	}
+
+	x.session = redfish.GetSession(x.baseURL, x.Username, x.Password)
+	x.session.SetMaxConcurrency(x.MaxConcurrentRequests)
+
+	x.client, err = x.HTTPClientConfig.CreateClient(context.Background())
+	return err
+}
+
+func (x *XccPower) Gather(acc telegraf.Accumulator) error {
+	var p power
+	if err := x.get(path.Join("/redfish/v1/Chassis", x.ChassisID, "Power"), &p); err != nil {
+		return err
+	}
+	timestamp := time.Now()
+
+	for _, pc := range p.PowerControl {
+		fields := make(map[string]interface{})
+		addField(fields, "power_watts", pc.PowerConsumedWatts)
+		addField(fields, "average_watts", pc.PowerMetrics.AverageConsumedWatts)
+		addField(fields, "min_watts", pc.PowerMetrics.MinConsumedWatts)
+		addField(fields, "max_watts", pc.PowerMetrics.MaxConsumedWatts)
+		addField(fields, "capacity_watts", pc.Oem.Lenovo.PowerUtilization.CapacityMaxAC)
+		if len(fields) == 0 {
+			continue
+		}
+
+		tags := map[string]string{
+			"address":   x.host,
+			"component": component(pc.Name),
+			"name":      pc.Name,
+		}
+		if pc.MemberID != "" {
+			tags["member_id"] = pc.MemberID
+		}
+		acc.AddFields(measurement, fields, tags, timestamp)
+	}
+	return nil
+}
+
+// component returns the component of the power control with the name.
+func component(name string) string {
+	lower := strings.ToLower(name)
+	for _, c := range components {
+		if strings.Contains(lower, c.keyword) {
+			return c.component
+		}
+	}
+	return "other"
+}
+
+func addField(fields map[string]interface{}, key string, value *float64) {
+	if value != nil {
+		fields[key] = *value
+	}
+}
+
+// get decodes the resource at the URI, requested with the session shared
+// with the other Redfish plugins reading the XCC.
+func (x *XccPower) get(uri string, v interface{}) error {
+	req, err := x.newRequest("GET", uri)
+	if err != nil {
+		return err
+	}
+	resp, err := x.session.Do(x.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s received status code %d (%s), expected 200",
+			uri,
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (x *XccPower) newRequest(method, uri string) (*http.Request, error) {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, x.baseURL.ResolveReference(ref).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	return req, nil
+}
+
+func init() {
+	inputs.Add("xcc_power", func() telegraf.Input {
+		return &XccPower{
+			ChassisID: "1",
+
+			MaxConcurrentRequests: 4,
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+			},
+		}
+	})
+}
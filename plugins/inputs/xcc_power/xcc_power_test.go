@@ -0,0 +1,138 @@
+package xcc_power
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/redfish/v1/SessionService/Sessions" && r.Method == "POST":
+			var credentials map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil || credentials["Password"] != "secret" {
+				http.Error(w, "Unauthorized.", 401)
+				return
+			}
+			w.Header().Set("X-Auth-Token", "token")
+			w.WriteHeader(http.StatusCreated)
+		case r.Header.Get("X-Auth-Token") != "token":
+			http.Error(w, "Unauthorized.", 401)
+		case r.URL.Path == "/redfish/v1/Chassis/1/Power":
+			data, err := ioutil.ReadFile("testdata/power.json")
+			require.NoError(t, err)
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestGather(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	plugin := &XccPower{
+		Address:  ts.URL,
+		Username: "telegraf",
+		Password: "secret",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(plugin.Gather))
+
+	tags := func(component, name, id string) map[string]string {
+		return map[string]string{
+			"address":   "127.0.0.1",
+			"component": component,
+			"name":      name,
+			"member_id": id,
+		}
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("xcc_power", tags("system", "Server Power Control", "0"),
+			map[string]interface{}{
+				"power_watts":    264.0,
+				"average_watts":  259.5,
+				"min_watts":      241.0,
+				"max_watts":      312.0,
+				"capacity_watts": 1100.0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("xcc_power", tags("cpu", "CPU Sub-system Power", "1"),
+			map[string]interface{}{
+				"power_watts":   131.0,
+				"average_watts": 128.2,
+				"min_watts":     112.0,
+				"max_watts":     170.0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("xcc_power", tags("memory", "Memory Sub-system Power", "2"),
+			map[string]interface{}{
+				"power_watts":   38.0,
+				"average_watts": 37.1,
+				"min_watts":     35.0,
+				"max_watts":     44.0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("xcc_power", tags("storage", "Drive Bays Power", "3"),
+			map[string]interface{}{
+				"power_watts": 22.0,
+			},
+			time.Unix(0, 0)),
+		testutil.MustMetric("xcc_power", tags("other", "Other Sub-system Power", "4"),
+			map[string]interface{}{
+				"power_watts": 73.0,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestLoginFailure(t *testing.T) {
+	ts := newServer(t)
+	defer ts.Close()
+
+	plugin := &XccPower{
+		Address:  ts.URL,
+		Username: "telegraf",
+		Password: "wrong",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.EqualError(t, plugin.Gather(&acc), "login received status code 401 (Unauthorized), expected 201")
+}
+
+func TestComponent(t *testing.T) {
+	tests := []struct {
+		name      string
+		component string
+	}{
+		{"Server Power Control", "system"},
+		{"CPU Sub-system Power", "cpu"},
+		{"Processor Power", "cpu"},
+		{"Memory Sub-system Power", "memory"},
+		{"Drive Bays Power", "storage"},
+		{"Storage Backplane Power", "storage"},
+		{"Fan Power", "fan"},
+		{"Other Sub-system Power", "other"},
+		{"PCIe Adapters", "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.component, component(tt.name))
+		})
+	}
+}
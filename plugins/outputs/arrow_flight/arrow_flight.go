@@ -0,0 +1,265 @@
+package arrow_flight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+var sampleConfig = `
+  ## Address of the Flight service
+  address = "localhost:8815"
+
+  ## Path of the flight descriptor of the streams, the measurement name is
+  ## appended as last element
+  # path_prefix = ["telegraf"]
+
+  ## Bearer token sent in the authorization header
+  # token = ""
+
+  ## Maximum time a write may take, including waiting for the service to
+  ## accept the data.  Slow writes fail and the metrics stay buffered.
+  # timeout = "10s"
+
+  ## Optional TLS Config
+  # enable_tls = false
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type ArrowFlight struct {
+	Address    string            `toml:"address"`
+	PathPrefix []string          `toml:"path_prefix"`
+	Token      string            `toml:"token"`
+	Timeout    internal.Duration `toml:"timeout"`
+	EnableTLS  bool              `toml:"enable_tls"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	conn    *grpc.ClientConn
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mem     memory.Allocator
+	streams map[string]*stream
+}
+
+// stream is an open DoPut call streaming the records of one measurement.
+type stream struct {
+	schema *arrow.Schema
+	put    grpc.ClientStream
+	writer *flightDataWriter
+	cancel context.CancelFunc
+
+	// done is closed once the service finished the call, err holds the
+	// reason if it did not finish cleanly.
+	done chan struct{}
+	err  error
+}
+
+func (a *ArrowFlight) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *ArrowFlight) Description() string {
+	return "Stream metrics to an Apache Arrow Flight service"
+}
+
+func (a *ArrowFlight) Connect() error {
+	if a.Address == "" {
+		return fmt.Errorf("address must be set")
+	}
+	if a.Timeout.Duration <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if a.EnableTLS {
+		tlscfg, err := a.ClientConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlscfg))}
+	}
+
+	// The connection is established in the background and re-established
+	// by grpc after failures.
+	conn, err := grpc.Dial(a.Address, opts...)
+	if err != nil {
+		return err
+	}
+	a.conn = conn
+
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	if a.Token != "" {
+		a.ctx = metadata.AppendToOutgoingContext(a.ctx, "authorization", "Bearer "+a.Token)
+	}
+	a.mem = memory.NewGoAllocator()
+	a.streams = make(map[string]*stream)
+	return nil
+}
+
+func (a *ArrowFlight) Close() error {
+	for name, s := range a.streams {
+		if err := a.closeStream(s); err != nil {
+			a.Log.Errorf("Closing stream of %q failed: %v", name, err)
+		}
+		delete(a.streams, name)
+	}
+	if a.cancel != nil {
+		a.cancel()
+	}
+	if a.conn != nil {
+		return a.conn.Close()
+	}
+	return nil
+}
+
+func (a *ArrowFlight) Write(metrics []telegraf.Metric) error {
+	var names []string
+	batches := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		if _, ok := batches[m.Name()]; !ok {
+			names = append(names, m.Name())
+		}
+		batches[m.Name()] = append(batches[m.Name()], m)
+	}
+
+	for _, name := range names {
+		if err := a.write(name, batches[name]); err != nil {
+			return fmt.Errorf("writing %q failed: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// write sends the metrics of a measurement on its stream.  A new stream is
+// opened if the metrics add columns to the schema of the current one or the
+// current one failed.
+func (a *ArrowFlight) write(name string, metrics []telegraf.Metric) error {
+	s := a.streams[name]
+	if s != nil {
+		select {
+		case <-s.done:
+			a.Log.Debugf("Reopening stream of %q: %v", name, s.err)
+			s.cancel()
+			s = nil
+		default:
+		}
+	}
+
+	var prev *arrow.Schema
+	if s != nil {
+		prev = s.schema
+	}
+	schema := buildSchema(metrics, prev)
+	if s != nil && !schema.Equal(s.schema) {
+		if err := a.closeStream(s); err != nil {
+			a.Log.Debugf("Closing stream of %q failed: %v", name, err)
+		}
+		s = nil
+	}
+	if s == nil {
+		delete(a.streams, name)
+		var err error
+		if s, err = a.openStream(name, schema); err != nil {
+			return err
+		}
+		a.streams[name] = s
+	}
+
+	record := buildRecord(a.mem, schema, metrics, a.Log)
+	defer record.Release()
+
+	// Sending blocks while the service applies flow control, cancel the
+	// call if it does not catch up in time.
+	timer := time.AfterFunc(a.Timeout.Duration, s.cancel)
+	err := s.writer.Write(record)
+	if err == nil {
+		err = s.writer.Flush()
+	}
+	if !timer.Stop() {
+		err = fmt.Errorf("timeout after %s", a.Timeout.Duration)
+	}
+	if err != nil {
+		s.cancel()
+		delete(a.streams, name)
+		return err
+	}
+	return nil
+}
+
+func (a *ArrowFlight) openStream(name string, schema *arrow.Schema) (*stream, error) {
+	ctx, cancel := context.WithCancel(a.ctx)
+	put, err := doPut(ctx, a.conn)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	path := append(append([]string{}, a.PathPrefix...), name)
+	s := &stream{
+		schema: schema,
+		put:    put,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.writer = newFlightDataWriter(put, &flightDescriptor{
+		Type: descriptorTypePath,
+		Path: path,
+	}, ipc.WithSchema(schema), ipc.WithAllocator(a.mem))
+
+	// Drain the results, the service might stall the stream otherwise
+	go func() {
+		defer close(s.done)
+		for {
+			if err := put.RecvMsg(&putResult{}); err != nil {
+				if err != io.EOF {
+					s.err = err
+				}
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// closeStream finishes the call and waits for the service to acknowledge
+// it.
+func (a *ArrowFlight) closeStream(s *stream) error {
+	defer s.cancel()
+	if err := s.put.CloseSend(); err != nil {
+		return err
+	}
+
+	select {
+	case <-s.done:
+		return s.err
+	case <-time.After(a.Timeout.Duration):
+		return fmt.Errorf("timeout after %s", a.Timeout.Duration)
+	}
+}
+
+func init() {
+	outputs.Add("arrow_flight", func() telegraf.Output {
+		return &ArrowFlight{
+			PathPrefix: []string{"telegraf"},
+			Timeout:    internal.Duration{Duration: 10 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,288 @@
+package arrow_flight
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// putCall is a DoPut call received by the test server.
+type putCall struct {
+	path    string
+	auth    []string
+	schema  *arrow.Schema
+	records []array.Record
+}
+
+type testServer struct {
+	sync.Mutex
+	server   *grpc.Server
+	listener net.Listener
+	calls    []*putCall
+
+	// failAfter ends calls with an error after the given number of records
+	failAfter int
+}
+
+func newTestServer(t *testing.T) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ts := &testServer{server: grpc.NewServer(), listener: listener}
+	ts.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "arrow.flight.protocol.FlightService",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName: "DoPut",
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				return ts.doPut(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		}},
+	}, ts)
+	go ts.server.Serve(listener)
+	return ts
+}
+
+func (ts *testServer) doPut(put grpc.ServerStream) error {
+	call := &putCall{}
+	md, _ := metadata.FromIncomingContext(put.Context())
+	call.auth = md.Get("authorization")
+
+	reader, err := ipc.NewReader(&streamReader{put: put, call: call})
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+	call.schema = reader.Schema()
+
+	ts.Lock()
+	ts.calls = append(ts.calls, call)
+	failAfter := ts.failAfter
+	ts.Unlock()
+
+	for reader.Next() {
+		rec := reader.Record()
+		rec.Retain()
+		ts.Lock()
+		call.records = append(call.records, rec)
+		ts.Unlock()
+		if failAfter > 0 && len(call.records) >= failAfter {
+			return errors.New("failure")
+		}
+		if err := put.SendMsg(&putResult{}); err != nil {
+			return err
+		}
+	}
+	return reader.Err()
+}
+
+func (ts *testServer) getCalls() []*putCall {
+	ts.Lock()
+	defer ts.Unlock()
+	return append([]*putCall{}, ts.calls...)
+}
+
+// waitRecords waits until the server received the number of records.
+func (ts *testServer) waitRecords(t *testing.T, n int) []*putCall {
+	for i := 0; i < 100; i++ {
+		var received int
+		calls := ts.getCalls()
+		ts.Lock()
+		for _, call := range calls {
+			received += len(call.records)
+		}
+		ts.Unlock()
+		if received >= n {
+			return calls
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server did not receive %d records", n)
+	return nil
+}
+
+// streamReader reassembles the IPC stream from the received FlightData and
+// records the descriptor sent with the first message.
+type streamReader struct {
+	put  grpc.ServerStream
+	call *putCall
+	buf  bytes.Buffer
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		var data flightData
+		if err := r.put.RecvMsg(&data); err != nil {
+			return 0, err
+		}
+		if data.FlightDescriptor != nil {
+			r.call.path = strings.Join(data.FlightDescriptor.Path, "/")
+		}
+		var prefix [8]byte
+		binary.LittleEndian.PutUint32(prefix[:4], 0xFFFFFFFF)
+		binary.LittleEndian.PutUint32(prefix[4:], uint32(len(data.DataHeader)))
+		r.buf.Write(prefix[:])
+		r.buf.Write(data.DataHeader)
+		r.buf.Write(data.DataBody)
+	}
+	return r.buf.Read(p)
+}
+
+func newOutput(ts *testServer) *ArrowFlight {
+	return &ArrowFlight{
+		Address:    ts.listener.Addr().String(),
+		PathPrefix: []string{"telegraf"},
+		Token:      "secret",
+		Timeout:    internal.Duration{Duration: 5 * time.Second},
+		Log:        testutil.Logger{},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.server.Stop()
+
+	a := newOutput(ts)
+	require.NoError(t, a.Connect())
+	defer a.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("ipmi_power",
+			map[string]string{"server": "node01"},
+			map[string]interface{}{"power": 220.0, "state": "activated"},
+			time.Unix(0, 1)),
+		testutil.MustMetric("gpu_job",
+			map[string]string{"index": "0"},
+			map[string]interface{}{"job_id": "1001"},
+			time.Unix(0, 1)),
+		testutil.MustMetric("ipmi_power",
+			map[string]string{"server": "node02"},
+			map[string]interface{}{"power": int64(180)},
+			time.Unix(0, 2)),
+	}
+	require.NoError(t, a.Write(metrics))
+
+	calls := ts.waitRecords(t, 2)
+	require.Len(t, calls, 2)
+	paths := map[string]*putCall{}
+	for _, call := range calls {
+		paths[call.path] = call
+		require.Equal(t, []string{"Bearer secret"}, call.auth)
+	}
+
+	call := paths["telegraf/ipmi_power"]
+	require.NotNil(t, call)
+	require.Equal(t, []string{"time", "server", "power", "state"}, columnNames(call.schema))
+	rec := call.records[0]
+	require.Equal(t, int64(2), rec.NumRows())
+	require.Equal(t, []float64{220, 180}, rec.Column(2).(*array.Float64).Float64Values())
+	require.Equal(t, "node02", rec.Column(1).(*array.String).Value(1))
+	require.True(t, rec.Column(3).IsNull(1))
+
+	require.NotNil(t, paths["telegraf/gpu_job"])
+}
+
+func TestWriteSchemaChange(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.server.Stop()
+
+	a := newOutput(ts)
+	require.NoError(t, a.Connect())
+	defer a.Close()
+
+	write := func(fields map[string]interface{}) {
+		require.NoError(t, a.Write([]telegraf.Metric{
+			testutil.MustMetric("ipmi_power", map[string]string{}, fields, time.Unix(0, 0)),
+		}))
+	}
+
+	// Metrics with a subset of the columns reuse the stream
+	write(map[string]interface{}{"power": 220.0, "state": "activated"})
+	write(map[string]interface{}{"power": 221.0})
+	calls := ts.waitRecords(t, 2)
+	require.Len(t, calls, 1)
+
+	// New columns require a new stream
+	write(map[string]interface{}{"power": 222.0, "limit": 400.0})
+	calls = ts.waitRecords(t, 3)
+	require.Len(t, calls, 2)
+	require.Equal(t, []string{"time", "power", "state", "limit"}, columnNames(calls[1].schema))
+}
+
+func TestWriteReconnect(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.server.Stop()
+	ts.failAfter = 1
+
+	a := newOutput(ts)
+	require.NoError(t, a.Connect())
+	defer a.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("ipmi_power",
+			map[string]string{},
+			map[string]interface{}{"power": 220.0},
+			time.Unix(0, 0)),
+	}
+	require.NoError(t, a.Write(metrics))
+	ts.waitRecords(t, 1)
+
+	// Wait for the failure to reach the client
+	s := a.streams["ipmi_power"]
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not fail")
+	}
+	require.Error(t, s.err)
+
+	require.NoError(t, a.Write(metrics))
+	calls := ts.waitRecords(t, 2)
+	require.Len(t, calls, 2)
+}
+
+func TestBuildRecordTypeMismatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("test",
+			map[string]string{},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("test",
+			map[string]string{},
+			map[string]interface{}{"value": "one"},
+			time.Unix(0, 0)),
+	}
+	schema := buildSchema(metrics, nil)
+	require.Equal(t, arrow.PrimitiveTypes.Int64, schema.Field(1).Type)
+
+	rec := buildRecord(memory.NewGoAllocator(), schema, metrics, testutil.Logger{})
+	defer rec.Release()
+	require.Equal(t, int64(1), rec.Column(1).(*array.Int64).Value(0))
+	require.True(t, rec.Column(1).IsNull(1))
+}
+
+func columnNames(schema *arrow.Schema) []string {
+	var names []string
+	for _, f := range schema.Fields() {
+		names = append(names, f.Name)
+	}
+	return names
+}
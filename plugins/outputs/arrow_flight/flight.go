@@ -0,0 +1,127 @@
+package arrow_flight
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// The messages of the Flight protocol used by DoPut, see Flight.proto of
+// the Arrow project.  They are declared here instead of using the Flight
+// client of the Arrow module, as its generated code requires the protobuf
+// APIv2, which conflicts with the registrations of github.com/ericchiang/k8s.
+
+const (
+	doPutMethod = "/arrow.flight.protocol.FlightService/DoPut"
+
+	descriptorTypePath = 1
+)
+
+type flightDescriptor struct {
+	Type int32    `protobuf:"varint,1,opt,name=type,proto3"`
+	Cmd  []byte   `protobuf:"bytes,2,opt,name=cmd,proto3"`
+	Path []string `protobuf:"bytes,3,rep,name=path,proto3"`
+}
+
+func (m *flightDescriptor) Reset()         { *m = flightDescriptor{} }
+func (m *flightDescriptor) String() string { return proto.CompactTextString(m) }
+func (*flightDescriptor) ProtoMessage()    {}
+
+type flightData struct {
+	FlightDescriptor *flightDescriptor `protobuf:"bytes,1,opt,name=flight_descriptor,json=flightDescriptor,proto3"`
+	DataHeader       []byte            `protobuf:"bytes,2,opt,name=data_header,json=dataHeader,proto3"`
+	AppMetadata      []byte            `protobuf:"bytes,3,opt,name=app_metadata,json=appMetadata,proto3"`
+	DataBody         []byte            `protobuf:"bytes,1000,opt,name=data_body,json=dataBody,proto3"`
+}
+
+func (m *flightData) Reset()         { *m = flightData{} }
+func (m *flightData) String() string { return proto.CompactTextString(m) }
+func (*flightData) ProtoMessage()    {}
+
+type putResult struct {
+	AppMetadata []byte `protobuf:"bytes,1,opt,name=app_metadata,json=appMetadata,proto3"`
+}
+
+func (m *putResult) Reset()         { *m = putResult{} }
+func (m *putResult) String() string { return proto.CompactTextString(m) }
+func (*putResult) ProtoMessage()    {}
+
+var doPutDesc = &grpc.StreamDesc{
+	StreamName:    "DoPut",
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// doPut starts a DoPut call on the connection.
+func doPut(ctx context.Context, conn *grpc.ClientConn) (grpc.ClientStream, error) {
+	return conn.NewStream(ctx, doPutDesc, doPutMethod)
+}
+
+// flightDataWriter sends the messages written by an IPC stream writer as
+// FlightData.  The IPC writer flushes complete messages on every write, so
+// the buffer always holds whole messages after a write returned.
+type flightDataWriter struct {
+	*ipc.Writer
+
+	buf        bytes.Buffer
+	put        grpc.ClientStream
+	descriptor *flightDescriptor
+}
+
+func newFlightDataWriter(put grpc.ClientStream, descriptor *flightDescriptor, opts ...ipc.Option) *flightDataWriter {
+	w := &flightDataWriter{put: put, descriptor: descriptor}
+	w.Writer = ipc.NewWriter(&w.buf, opts...)
+	return w
+}
+
+// Flush sends the buffered messages, the flight descriptor is set on the
+// first one as required by the protocol.
+func (w *flightDataWriter) Flush() error {
+	defer w.buf.Reset()
+
+	messages, err := splitMessages(w.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	for _, data := range messages {
+		data.FlightDescriptor = w.descriptor
+		w.descriptor = nil
+		if err := w.put.SendMsg(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitMessages splits an encapsulated IPC stream into the metadata and
+// body of its messages.
+func splitMessages(stream []byte) ([]*flightData, error) {
+	var messages []*flightData
+	r := bytes.NewReader(stream)
+	for r.Len() > 0 {
+		start := len(stream) - r.Len()
+		mr := ipc.NewMessageReader(r)
+		msg, err := mr.Message()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		bodyLen := int(msg.BodyLen())
+		mr.Release()
+
+		// The metadata follows the continuation token and its length
+		end := len(stream) - r.Len()
+		body := end - bodyLen
+		messages = append(messages, &flightData{
+			DataHeader: stream[start+8 : body],
+			DataBody:   stream[body:end],
+		})
+	}
+	return messages, nil
+}
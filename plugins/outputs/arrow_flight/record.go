@@ -0,0 +1,172 @@
+package arrow_flight
+
+import (
+	"sort"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	timeColumn = "time"
+	kindKey    = "telegraf.kind"
+	kindTag    = "tag"
+	kindField  = "field"
+)
+
+// buildSchema returns the schema of a measurement: the time column followed
+// by the tags and fields.  The columns of the previous schema are kept in
+// order so the schema only changes when the metrics add new tags or fields.
+func buildSchema(metrics []telegraf.Metric, prev *arrow.Schema) *arrow.Schema {
+	fields := []arrow.Field{{Name: timeColumn, Type: arrow.FixedWidthTypes.Timestamp_ns}}
+	if prev != nil {
+		fields = prev.Fields()
+	}
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.Name] = true
+	}
+
+	var tags, values []arrow.Field
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			if known[tag.Key] {
+				continue
+			}
+			known[tag.Key] = true
+			tags = append(tags, column(tag.Key, arrow.BinaryTypes.String, kindTag))
+		}
+		for _, field := range m.FieldList() {
+			if known[field.Key] {
+				continue
+			}
+			dtype := dataType(field.Value)
+			if dtype == nil {
+				continue
+			}
+			known[field.Key] = true
+			values = append(values, column(field.Key, dtype, kindField))
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+
+	fields = append(append(fields, tags...), values...)
+	return arrow.NewSchema(fields, nil)
+}
+
+func column(name string, dtype arrow.DataType, kind string) arrow.Field {
+	return arrow.Field{
+		Name:     name,
+		Type:     dtype,
+		Nullable: true,
+		Metadata: arrow.NewMetadata([]string{kindKey}, []string{kind}),
+	}
+}
+
+func dataType(value interface{}) arrow.DataType {
+	switch value.(type) {
+	case float64:
+		return arrow.PrimitiveTypes.Float64
+	case int64:
+		return arrow.PrimitiveTypes.Int64
+	case uint64:
+		return arrow.PrimitiveTypes.Uint64
+	case bool:
+		return arrow.FixedWidthTypes.Boolean
+	case string:
+		return arrow.BinaryTypes.String
+	}
+	return nil
+}
+
+// buildRecord converts the metrics to a record of the schema.  Missing tags
+// and fields are null, so are fields not matching the type of their column.
+func buildRecord(mem memory.Allocator, schema *arrow.Schema, metrics []telegraf.Metric, log telegraf.Logger) array.Record {
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Reserve(len(metrics))
+
+	for _, m := range metrics {
+		for i, f := range schema.Fields() {
+			builder := b.Field(i)
+			if f.Name == timeColumn && i == 0 {
+				builder.(*array.TimestampBuilder).Append(arrow.Timestamp(m.Time().UnixNano()))
+				continue
+			}
+
+			if kind(f) == kindTag {
+				if value, ok := m.GetTag(f.Name); ok {
+					builder.(*array.StringBuilder).Append(value)
+				} else {
+					builder.AppendNull()
+				}
+				continue
+			}
+
+			value, ok := m.GetField(f.Name)
+			if !ok || !appendValue(builder, value) {
+				if ok {
+					log.Debugf("Dropping field %q of %q: type %T does not match column type %s",
+						f.Name, m.Name(), value, f.Type)
+				}
+				builder.AppendNull()
+			}
+		}
+	}
+	return b.NewRecord()
+}
+
+func kind(f arrow.Field) string {
+	if i := f.Metadata.FindKey(kindKey); i >= 0 {
+		return f.Metadata.Values()[i]
+	}
+	return ""
+}
+
+// appendValue appends the value if it matches the type of the builder,
+// integers are converted to float columns.
+func appendValue(builder array.Builder, value interface{}) bool {
+	switch b := builder.(type) {
+	case *array.Float64Builder:
+		switch v := value.(type) {
+		case float64:
+			b.Append(v)
+		case int64:
+			b.Append(float64(v))
+		case uint64:
+			b.Append(float64(v))
+		default:
+			return false
+		}
+	case *array.Int64Builder:
+		v, ok := value.(int64)
+		if !ok {
+			return false
+		}
+		b.Append(v)
+	case *array.Uint64Builder:
+		v, ok := value.(uint64)
+		if !ok {
+			return false
+		}
+		b.Append(v)
+	case *array.BooleanBuilder:
+		v, ok := value.(bool)
+		if !ok {
+			return false
+		}
+		b.Append(v)
+	case *array.StringBuilder:
+		v, ok := value.(string)
+		if !ok {
+			return false
+		}
+		b.Append(v)
+	default:
+		return false
+	}
+	return true
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/oauth"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
@@ -89,6 +90,7 @@ type HTTPConfig struct {
 	Username                  string
 	Password                  string
 	TLSConfig                 *tls.Config
+	OAuth2Config              oauth.OAuth2Config
 	Proxy                     *url.URL
 	Headers                   map[string]string
 	ContentEncoding           string
@@ -177,10 +179,10 @@ func NewHTTPClient(config HTTPConfig) (*httpClient, error) {
 	}
 
 	client := &httpClient{
-		client: &http.Client{
+		client: config.OAuth2Config.CreateOauth2Client(context.Background(), &http.Client{
 			Timeout:   config.Timeout,
 			Transport: transport,
-		},
+		}),
 		createDatabaseExecuted: make(map[string]bool),
 		config:                 config,
 		log:                    config.Log,
@@ -14,12 +14,14 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/oauth"
 	"github.com/influxdata/telegraf/plugins/outputs/influxdb"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -1135,3 +1137,74 @@ func TestDBRPTagsCreateDatabaseCalledOnDatabaseNotFound(t *testing.T) {
 
 	require.True(t, handlers.Done(), "all handlers not called")
 }
+
+func TestHTTP_WriteOAuth2TokenRotation(t *testing.T) {
+	var issued int32
+	var valid atomic.Value
+	valid.Store("")
+
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/token":
+				token := fmt.Sprintf("token%d", atomic.AddInt32(&issued, 1))
+				valid.Store(token)
+				values := url.Values{}
+				values.Add("access_token", token)
+				values.Add("token_type", "bearer")
+				values.Add("expires_in", "3600")
+				w.Write([]byte(values.Encode()))
+			case "/write":
+				if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	config := influxdb.HTTPConfig{
+		URL:                  u,
+		Database:             "telegraf",
+		SkipDatabaseCreation: true,
+		OAuth2Config: oauth.OAuth2Config{
+			ClientID:     "howdy",
+			ClientSecret: "secret",
+			TokenURL:     ts.URL + "/token",
+		},
+		Log: testutil.Logger{},
+	}
+
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": 42.0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+
+	ctx := context.Background()
+	require.NoError(t, client.Write(ctx, metrics))
+	require.NoError(t, client.Write(ctx, metrics))
+	require.Equal(t, int32(1), atomic.LoadInt32(&issued))
+
+	// the streamed body cannot be replayed, the rejected write fails and
+	// the next one is sent with a fresh token
+	valid.Store("rotated")
+	require.Error(t, client.Write(ctx, metrics))
+	require.NoError(t, client.Write(ctx, metrics))
+	require.Equal(t, int32(2), atomic.LoadInt32(&issued))
+}
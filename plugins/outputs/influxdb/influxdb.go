@@ -10,6 +10,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/oauth"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
@@ -51,6 +52,7 @@ type InfluxDB struct {
 	SkipDatabaseCreation      bool              `toml:"skip_database_creation"`
 	InfluxUintSupport         bool              `toml:"influx_uint_support"`
 	tls.ClientConfig
+	oauth.OAuth2Config
 
 	Precision string // precision deprecated in 1.0; value is ignored
 
@@ -122,6 +124,14 @@ var sampleConfig = `
   ## Use TLS but skip chain & host verification
   # insecure_skip_verify = false
 
+  ## OAuth2 Client Credentials Grant, tokens are cached until they expire
+  ## and fetched again when rejected by the server.  The bearer token
+  ## replaces the basic auth credentials.
+  # client_id = "clientid"
+  # client_secret = "secret"
+  # token_url = "https://indentityprovider/oauth2/v1/token"
+  # scopes = ["urn:opc:idm:__myscopes__"]
+
   ## HTTP Proxy override, if unset values the standard proxy environment
   ## variables are consulted to determine which proxy, if any, should be used.
   # http_proxy = "http://corporate.proxy:3128"
@@ -262,6 +272,7 @@ func (i *InfluxDB) httpClient(ctx context.Context, url *url.URL, proxy *url.URL)
 		URL:                       url,
 		Timeout:                   i.Timeout.Duration,
 		TLSConfig:                 tlsConfig,
+		OAuth2Config:              i.OAuth2Config,
 		UserAgent:                 i.UserAgent,
 		Username:                  i.Username,
 		Password:                  i.Password,
@@ -18,6 +18,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/oauth"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
@@ -53,6 +54,7 @@ type HTTPConfig struct {
 	UserAgent        string
 	ContentEncoding  string
 	TLSConfig        *tls.Config
+	OAuth2Config     oauth.OAuth2Config
 
 	Serializer *influx.Serializer
 }
@@ -129,10 +131,10 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 
 	client := &httpClient{
 		serializer: serializer,
-		client: &http.Client{
+		client: config.OAuth2Config.CreateOauth2Client(context.Background(), &http.Client{
 			Timeout:   timeout,
 			Transport: transport,
-		},
+		}),
 		url:              config.URL,
 		ContentEncoding:  config.ContentEncoding,
 		Timeout:          timeout,
@@ -2,14 +2,17 @@ package influxdb_v2_test
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/oauth"
 	influxdb "github.com/influxdata/telegraf/plugins/outputs/influxdb_v2"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -111,3 +114,69 @@ func TestWriteBucketTagWorksOnRetry(t *testing.T) {
 	err = client.Write(ctx, metrics)
 	require.NoError(t, err)
 }
+
+func TestWriteOAuth2TokenRotation(t *testing.T) {
+	var issued int32
+	var valid atomic.Value
+	valid.Store("")
+
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/token":
+				token := fmt.Sprintf("token%d", atomic.AddInt32(&issued, 1))
+				valid.Store(token)
+				values := url.Values{}
+				values.Add("access_token", token)
+				values.Add("token_type", "bearer")
+				values.Add("expires_in", "3600")
+				w.Write([]byte(values.Encode()))
+			case "/api/v2/write":
+				if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer ts.Close()
+
+	config := &influxdb.HTTPConfig{
+		URL:    genURL(ts.URL),
+		Bucket: "telegraf",
+		OAuth2Config: oauth.OAuth2Config{
+			ClientID:     "howdy",
+			ClientSecret: "secret",
+			TokenURL:     ts.URL + "/token",
+		},
+	}
+
+	client, err := influxdb.NewHTTPClient(config)
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"value": 42.0,
+			},
+			time.Unix(0, 0),
+		),
+	}
+
+	ctx := context.Background()
+	require.NoError(t, client.Write(ctx, metrics))
+	require.NoError(t, client.Write(ctx, metrics))
+	require.Equal(t, int32(1), atomic.LoadInt32(&issued))
+
+	// the streamed body cannot be replayed, the rejected write fails and
+	// the next one is sent with a fresh token
+	valid.Store("rotated")
+	require.Error(t, client.Write(ctx, metrics))
+	require.NoError(t, client.Write(ctx, metrics))
+	require.Equal(t, int32(2), atomic.LoadInt32(&issued))
+}
@@ -0,0 +1,389 @@
+package ipmi_power_cap
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/ipmi"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// execCommand is used to mock commands in tests.
+var execCommand = exec.Command
+
+// exceptionActions are the actions taken when the limit cannot be kept
+// within the correction time, as named by "ipmitool dcmi power set_limit
+// action".
+var exceptionActions = map[string]bool{
+	"no_action":   true,
+	"sel_logging": true,
+	"power_off":   true,
+}
+
+var sampleConfig = `
+  ## optionally specify the path to the ipmitool executable
+  # path = "/usr/bin/ipmitool"
+  ##
+  ## Setting 'use_sudo' to true will make use of sudo to run ipmitool.
+  ## Sudo must be configured to allow the telegraf user to run ipmitool
+  ## without a password.
+  # use_sudo = false
+  ##
+  ## Command used to escalate privileges with 'use_sudo', e.g. doas or pbrun.
+  ## The ipmitool command line is appended or replaces a "{command}" element.
+  # sudo_command = ["sudo", "-n"]
+  ##
+  ## Power limits can only be set with ADMINISTRATOR privilege
+  # privilege = "ADMINISTRATOR"
+  ##
+  ## BMCs the power limit is set on via a url matching
+  ##  [username[:password]@][protocol[(address[:port])]]
+  ## the syntax is shared with the ipmi_power input, including the
+  ## 'interface' and 'password_file' parameters
+  ##  e.g.
+  ##    root:passwd@lan(127.0.0.1)
+  ##    root:@lanplus([fd00::1]:623)?password_file=/run/secrets/bmc
+  ##
+  ## if no servers are specified, the limit of the local BMC is set
+  ##
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  ## Interface used for all servers, one of lan, lanplus, open or serial,
+  ## unless they set an 'interface' parameter. By default servers use the
+  ## protocol of their url, or lan without one. Serial servers name their
+  ## device and baud rate, e.g. "root:passwd@serial(/dev/ttyS0:115200)".
+  # interface = ""
+
+  ## Timeout for each ipmitool command to complete
+  # timeout = "20s"
+
+  ## Name of the control metrics setting the power limits, other metrics
+  ## are ignored.  The BMC is selected by the address of the server in
+  ## 'server_tag', control metrics of the local BMC lack the tag.
+  # measurement = "power_cap"
+  # server_tag = "server"
+
+  ## Field holding the power limit in Watts.  An optional boolean field
+  ## 'active_field' activates or deactivates the limit, by default the
+  ## limit is activated once set.
+  # limit_field = "limit_watts"
+  # active_field = "active"
+
+  ## Limits outside of this range are rejected, as a safeguard against
+  ## faulty controllers.  0 disables the bound.
+  # min_limit_watts = 0.0
+  # max_limit_watts = 0.0
+
+  ## Action taken by the BMC if the limit cannot be kept within the
+  ## correction time, one of no_action, sel_logging or power_off, the
+  ## correction time in milliseconds and the statistics sampling period in
+  ## seconds.  Unset parameters are left as configured on the BMC.
+  # exception_action = "sel_logging"
+  # correction_time_ms = 0
+  # sampling_period_seconds = 0
+`
+
+// IpmiPowerCap sets the DCMI power limits of BMCs from control metrics.
+type IpmiPowerCap struct {
+	Path                  string            `toml:"path"`
+	UseSudo               bool              `toml:"use_sudo"`
+	SudoCommand           []string          `toml:"sudo_command"`
+	Privilege             string            `toml:"privilege"`
+	Servers               []string          `toml:"servers"`
+	Interface             string            `toml:"interface"`
+	Timeout               internal.Duration `toml:"timeout"`
+	Measurement           string            `toml:"measurement"`
+	ServerTag             string            `toml:"server_tag"`
+	LimitField            string            `toml:"limit_field"`
+	ActiveField           string            `toml:"active_field"`
+	MinLimitWatts         float64           `toml:"min_limit_watts"`
+	MaxLimitWatts         float64           `toml:"max_limit_watts"`
+	ExceptionAction       string            `toml:"exception_action"`
+	CorrectionTimeMs      int               `toml:"correction_time_ms"`
+	SamplingPeriodSeconds int               `toml:"sampling_period_seconds"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	// servers are the BMCs by hostname, the local BMC is the empty name.
+	servers map[string]*server
+}
+
+// server is a BMC and the limit last set on it.
+type server struct {
+	url        string
+	configured bool
+	limit      *uint16
+	active     *bool
+}
+
+// request is the limit and activation requested for a BMC by the newest
+// control metric.
+type request struct {
+	limit  *uint16
+	active *bool
+}
+
+// SampleConfig returns a sample configuration.
+func (p *IpmiPowerCap) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description describes the plugin.
+func (p *IpmiPowerCap) Description() string {
+	return "Set the DCMI power limits of BMCs from control metrics via IPMI"
+}
+
+// Init locates ipmitool and checks the limit parameters.
+func (p *IpmiPowerCap) Init() error {
+	if p.ExceptionAction != "" && !exceptionActions[p.ExceptionAction] {
+		return fmt.Errorf("invalid exception_action %q, expected no_action, sel_logging or power_off", p.ExceptionAction)
+	}
+	if p.MaxLimitWatts > 0 && p.MinLimitWatts > p.MaxLimitWatts {
+		return fmt.Errorf("min_limit_watts exceeds max_limit_watts")
+	}
+	if p.CorrectionTimeMs < 0 || p.SamplingPeriodSeconds < 0 {
+		return fmt.Errorf("correction_time_ms and sampling_period_seconds must not be negative")
+	}
+
+	p.servers = make(map[string]*server)
+	for _, s := range p.Servers {
+		conn := ipmi.NewConnection(s, p.Privilege)
+		p.servers[conn.Hostname] = &server{url: s}
+	}
+	if len(p.Servers) == 0 {
+		p.servers[""] = &server{}
+	}
+
+	if p.UseSudo {
+		if err := ipmi.CheckSudoCommand(p.SudoCommand); err != nil {
+			return err
+		}
+	}
+	if err := ipmi.CheckInterfaces(p.Servers, p.Interface); err != nil {
+		return err
+	}
+
+	if len(p.Path) == 0 {
+		p.Path = "ipmitool"
+	}
+	path, err := exec.LookPath(p.Path)
+	if err != nil {
+		return fmt.Errorf("ipmitool not found: verify that ipmitool is installed and that ipmitool is in your PATH (or specified in config): %v", err)
+	}
+	p.Path = path
+	return nil
+}
+
+// Connect satisfies the Output interface.
+func (p *IpmiPowerCap) Connect() error {
+	return nil
+}
+
+// Close satisfies the Output interface.
+func (p *IpmiPowerCap) Close() error {
+	return nil
+}
+
+// Write applies the newest limit requested for every BMC in the batch.
+// Limits already set are not sent again.  Invalid control metrics are
+// dropped, failures to set a limit are returned so the batch is retried.
+func (p *IpmiPowerCap) Write(metrics []telegraf.Metric) error {
+	requests := make(map[string]*request)
+	for _, m := range metrics {
+		if m.Name() != p.Measurement {
+			continue
+		}
+		hostname, _ := m.GetTag(p.ServerTag)
+		if _, ok := p.servers[hostname]; !ok {
+			p.Log.Debugf("Dropping control metric of unknown server %q", hostname)
+			continue
+		}
+		r, err := p.parseRequest(m)
+		if err != nil {
+			p.Log.Errorf("Dropping control metric of server %q: %v", hostname, err)
+			continue
+		}
+		if requests[hostname] == nil {
+			requests[hostname] = &request{}
+		}
+		if r.limit != nil {
+			requests[hostname].limit = r.limit
+		}
+		if r.active != nil {
+			requests[hostname].active = r.active
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+	for hostname, r := range requests {
+		wg.Add(1)
+		go func(hostname string, r *request) {
+			defer wg.Done()
+			if err := p.apply(hostname, p.servers[hostname], r); err != nil {
+				mu.Lock()
+				errs = append(errs, err.Error())
+				mu.Unlock()
+			}
+		}(hostname, r)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("setting power limits failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// parseRequest returns the limit and activation of the control metric.
+func (p *IpmiPowerCap) parseRequest(m telegraf.Metric) (*request, error) {
+	r := &request{}
+	if v, ok := m.GetField(p.LimitField); ok {
+		limit, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a number: %v", p.LimitField, v)
+		}
+		if limit < 1 || limit > 65535 {
+			return nil, fmt.Errorf("limit of %v Watts out of range", limit)
+		}
+		if (p.MinLimitWatts > 0 && limit < p.MinLimitWatts) || (p.MaxLimitWatts > 0 && limit > p.MaxLimitWatts) {
+			return nil, fmt.Errorf("limit of %v Watts outside of the allowed range", limit)
+		}
+		watts := uint16(limit)
+		r.limit = &watts
+	}
+	if v, ok := m.GetField(p.ActiveField); ok {
+		active, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a boolean: %v", p.ActiveField, v)
+		}
+		r.active = &active
+	}
+	if r.limit == nil && r.active == nil {
+		return nil, fmt.Errorf("neither %s nor %s set", p.LimitField, p.ActiveField)
+	}
+	return r, nil
+}
+
+// apply sets the requested limit on the BMC and activates it.  A new limit
+// is activated unless deactivation is requested.
+func (p *IpmiPowerCap) apply(hostname string, s *server, r *request) error {
+	var opts []string
+	if s.url != "" {
+		conn := ipmi.NewConnectionInterface(s.url, p.Privilege, p.Interface)
+		if err := conn.LoadPassword(); err != nil {
+			return err
+		}
+		opts = conn.Options()
+	}
+
+	if !s.configured {
+		if err := p.configure(opts); err != nil {
+			return err
+		}
+		s.configured = true
+	}
+
+	active := r.active
+	if r.limit != nil && (s.limit == nil || *s.limit != *r.limit) {
+		if err := p.setLimit(opts, "limit", strconv.Itoa(int(*r.limit))); err != nil {
+			return err
+		}
+		p.Log.Infof("Set power limit of %s to %d Watts", name(hostname), *r.limit)
+		s.limit = r.limit
+		if active == nil {
+			activate := true
+			active = &activate
+		}
+	}
+
+	if active != nil && (s.active == nil || *s.active != *active) {
+		command := "deactivate"
+		if *active {
+			command = "activate"
+		}
+		if _, err := p.run(append(append([]string{}, opts...), "dcmi", "power", command)...); err != nil {
+			return err
+		}
+		p.Log.Infof("Power limit of %s %sd", name(hostname), command)
+		s.active = active
+	}
+	return nil
+}
+
+// configure sets the exception action, correction time and sampling period.
+func (p *IpmiPowerCap) configure(opts []string) error {
+	if p.ExceptionAction != "" {
+		if err := p.setLimit(opts, "action", p.ExceptionAction); err != nil {
+			return err
+		}
+	}
+	if p.CorrectionTimeMs > 0 {
+		if err := p.setLimit(opts, "correction", strconv.Itoa(p.CorrectionTimeMs)); err != nil {
+			return err
+		}
+	}
+	if p.SamplingPeriodSeconds > 0 {
+		if err := p.setLimit(opts, "sample", strconv.Itoa(p.SamplingPeriodSeconds)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *IpmiPowerCap) setLimit(opts []string, parameter, value string) error {
+	_, err := p.run(append(append([]string{}, opts...), "dcmi", "power", "set_limit", parameter, value)...)
+	return err
+}
+
+// run runs ipmitool with the arguments.
+func (p *IpmiPowerCap) run(args ...string) ([]byte, error) {
+	r := &ipmi.Runner{
+		Path:        p.Path,
+		UseSudo:     p.UseSudo,
+		SudoCommand: p.SudoCommand,
+		Timeout:     p.Timeout.Duration,
+		ExecCommand: execCommand,
+	}
+	return r.Run(args...)
+}
+
+// name returns the name of the BMC for logging.
+func name(hostname string) string {
+	if hostname == "" {
+		return "the local BMC"
+	}
+	return hostname
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func init() {
+	outputs.Add("ipmi_power_cap", func() telegraf.Output {
+		return &IpmiPowerCap{
+			Privilege:   "ADMINISTRATOR",
+			Timeout:     internal.Duration{Duration: time.Second * 20},
+			Measurement: "power_cap",
+			ServerTag:   "server",
+			LimitField:  "limit_watts",
+			ActiveField: "active",
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package ipmi_power_cap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newPowerCap(t *testing.T, log string) *IpmiPowerCap {
+	p := &IpmiPowerCap{
+		Path:             os.Args[0],
+		Privilege:        "ADMINISTRATOR",
+		Servers:          []string{"USERID:PASSW0RD@lan(192.168.1.1)", "USERID:PASSW0RD@lan(192.168.1.2)"},
+		Timeout:          internal.Duration{Duration: time.Second * 5},
+		Measurement:      "power_cap",
+		ServerTag:        "server",
+		LimitField:       "limit_watts",
+		ActiveField:      "active",
+		MaxLimitWatts:    1000,
+		ExceptionAction:  "sel_logging",
+		CorrectionTimeMs: 6000,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+
+	execCommand = func(command string, args ...string) *exec.Cmd {
+		cs := []string{"-test.run=TestHelperProcess", "--", command}
+		cs = append(cs, args...)
+		cmd := exec.Command(os.Args[0], cs...)
+		cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "FAKE_IPMI_LOG=" + log}
+		return cmd
+	}
+	return p
+}
+
+func controlMetric(server string, fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric("power_cap",
+		map[string]string{"server": server},
+		fields,
+		time.Unix(0, 0))
+}
+
+// commands returns the ipmitool commands logged by the helper process
+// without the connection options.
+func commands(t *testing.T, log string) []string {
+	data, err := ioutil.ReadFile(log)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	var cmds []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		cmds = append(cmds, line[strings.Index(line, "dcmi"):])
+	}
+	return cmds
+}
+
+func TestWrite(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	log := t.TempDir() + "/commands"
+	p := newPowerCap(t, log)
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		controlMetric("192.168.1.1", map[string]interface{}{"limit_watts": 400.0}),
+		controlMetric("192.168.1.1", map[string]interface{}{"limit_watts": int64(450)}),
+		testutil.MustMetric("ipmi_power", map[string]string{"server": "192.168.1.1"},
+			map[string]interface{}{"instantaneous_power_reading": 220.0}, time.Unix(0, 0)),
+	}))
+	require.Equal(t, []string{
+		"dcmi power set_limit action sel_logging",
+		"dcmi power set_limit correction 6000",
+		"dcmi power set_limit limit 450",
+		"dcmi power activate",
+	}, commands(t, log))
+
+	// Unchanged limits are not set again
+	require.NoError(t, os.Remove(log))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		controlMetric("192.168.1.1", map[string]interface{}{"limit_watts": 450.0}),
+	}))
+	require.Empty(t, commands(t, log))
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		controlMetric("192.168.1.1", map[string]interface{}{"limit_watts": 500.0}),
+	}))
+	require.Equal(t, []string{"dcmi power set_limit limit 500"}, commands(t, log))
+
+	require.NoError(t, os.Remove(log))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		controlMetric("192.168.1.1", map[string]interface{}{"active": false}),
+	}))
+	require.Equal(t, []string{"dcmi power deactivate"}, commands(t, log))
+}
+
+func TestWriteInvalid(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	log := t.TempDir() + "/commands"
+	p := newPowerCap(t, log)
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		controlMetric("192.168.1.1", map[string]interface{}{"limit_watts": 1200.0}),
+		controlMetric("192.168.1.1", map[string]interface{}{"limit_watts": "high"}),
+		controlMetric("192.168.1.1", map[string]interface{}{"other": 1.0}),
+		controlMetric("192.168.1.9", map[string]interface{}{"limit_watts": 400.0}),
+	}))
+	require.Empty(t, commands(t, log))
+}
+
+func TestWriteFailure(t *testing.T) {
+	defer func() { execCommand = exec.Command }()
+	log := t.TempDir() + "/commands"
+	p := newPowerCap(t, log)
+	command := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		cmd := command(name, args...)
+		cmd.Env = append(cmd.Env, "FAKE_IPMI_FAILURE=DCMI request failed because: Invalid data field in request (cc)")
+		return cmd
+	}
+
+	err := p.Write([]telegraf.Metric{
+		controlMetric("192.168.1.2", map[string]interface{}{"limit_watts": 400.0}),
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid data field")
+	require.NotContains(t, err.Error(), "PASSW0RD")
+}
+
+func TestInitInvalidAction(t *testing.T) {
+	p := &IpmiPowerCap{
+		Path:            os.Args[0],
+		ExceptionAction: "reboot",
+	}
+	require.Error(t, p.Init())
+}
+
+// TestHelperProcess isn't a real test. It's used to mock exec.Command and
+// appends the ipmitool command line to the log file.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	if failure, ok := os.LookupEnv("FAKE_IPMI_FAILURE"); ok {
+		fmt.Fprint(os.Stdout, failure)
+		os.Exit(1)
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	f, err := os.OpenFile(os.Getenv("FAKE_IPMI_LOG"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Fprint(os.Stdout, err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(f, strings.Join(args[2:], " "))
+	f.Close()
+	os.Exit(0)
+}
@@ -0,0 +1,188 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	defaultURL           = "http://localhost:4318/v1/metrics"
+	defaultClientTimeout = 5 * time.Second
+	contentType          = "application/json"
+)
+
+var sampleConfig = `
+  ## URL of the OTLP/HTTP metrics endpoint of the receiver
+  # url = "http://localhost:4318/v1/metrics"
+
+  ## Timeout for HTTP message
+  # timeout = "5s"
+
+  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
+  ## compress body or "identity" to apply no encoding.
+  # content_encoding = "identity"
+
+  ## OAuth2 Client Credentials Grant, tokens are cached until they expire
+  ## and fetched again when rejected by the server
+  # client_id = "clientid"
+  # client_secret = "secret"
+  # token_url = "https://indentityprovider/oauth2/v1/token"
+  # scopes = ["urn:opc:idm:__myscopes__"]
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## HTTP Proxy, defaults to the proxy environment variables
+  # http_proxy_url = "http://localhost:8888"
+
+  ## Additional HTTP headers
+  # [outputs.opentelemetry.headers]
+  #   Authorization = "Bearer token"
+
+  ## Attributes of the resource all metrics are reported for
+  # [outputs.opentelemetry.resource_attributes]
+  #   "service.name" = "telegraf"
+`
+
+type OpenTelemetry struct {
+	URL                string            `toml:"url"`
+	Headers            map[string]string `toml:"headers"`
+	ContentEncoding    string            `toml:"content_encoding"`
+	ResourceAttributes map[string]string `toml:"resource_attributes"`
+	httpconfig.HTTPClientConfig
+
+	client *http.Client
+}
+
+func (o *OpenTelemetry) Connect() error {
+	if o.Timeout.Duration == 0 {
+		o.Timeout.Duration = defaultClientTimeout
+	}
+
+	client, err := o.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	o.client = client
+
+	return nil
+}
+
+func (o *OpenTelemetry) Close() error {
+	return nil
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Send metrics to an OpenTelemetry receiver over OTLP/HTTP"
+}
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
+	e := newEncoder()
+	for _, metric := range metrics {
+		if err := e.add(metric); err != nil {
+			return err
+		}
+	}
+	if len(e.metrics) == 0 {
+		return nil
+	}
+
+	reqBody, err := json.Marshal(o.request(e.metrics))
+	if err != nil {
+		return err
+	}
+
+	return o.write(reqBody)
+}
+
+// request wraps the metrics of a batch into an export request of a single
+// resource.
+func (o *OpenTelemetry) request(metrics []*otlpMetric) *exportRequest {
+	return &exportRequest{
+		ResourceMetrics: []resourceMetrics{
+			{
+				Resource: resource{Attributes: mapAttributes(o.ResourceAttributes)},
+				ScopeMetrics: []scopeMetrics{
+					{
+						Scope:   scope{Name: "telegraf", Version: internal.Version()},
+						Metrics: metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (o *OpenTelemetry) write(reqBody []byte) error {
+	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
+
+	if o.ContentEncoding == "gzip" {
+		rc, err := internal.CompressWithGzip(reqBodyBuffer)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		reqBodyBuffer = rc
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.URL, reqBodyBuffer)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Type", contentType)
+	if o.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range o.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("when writing to [%s] received status code: %d", o.URL, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func init() {
+	outputs.Add("opentelemetry", func() telegraf.Output {
+		return &OpenTelemetry{
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: defaultClientTimeout},
+			},
+			URL: defaultURL,
+		}
+	})
+}
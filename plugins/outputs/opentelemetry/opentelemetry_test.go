@@ -0,0 +1,182 @@
+package opentelemetry
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/v1/metrics", r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	plugin := &OpenTelemetry{
+		URL:                ts.URL + "/v1/metrics",
+		ResourceAttributes: map[string]string{"service.name": "telegraf"},
+	}
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"ipmi_power",
+			map[string]string{"server": "node01"},
+			map[string]interface{}{"power": 250.5, "state": "on", "capped": true},
+			time.Unix(0, 1000),
+		),
+		testutil.MustMetric(
+			"ipmi_power",
+			map[string]string{"server": "node02"},
+			map[string]interface{}{"power": 300.0},
+			time.Unix(0, 2000),
+		),
+		testutil.MustMetric(
+			"net",
+			map[string]string{},
+			map[string]interface{}{"bytes_recv": int64(42)},
+			time.Unix(0, 3000),
+			telegraf.Counter,
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	expected := `{"resourceMetrics":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"telegraf"}}]},
+		"scopeMetrics":[{"scope":{"name":"telegraf"},"metrics":[
+			{"name":"ipmi_power_capped","gauge":{"dataPoints":[
+				{"attributes":[{"key":"server","value":{"stringValue":"node01"}}],"timeUnixNano":"1000","asInt":"1"}]}},
+			{"name":"ipmi_power_power","gauge":{"dataPoints":[
+				{"attributes":[{"key":"server","value":{"stringValue":"node01"}}],"timeUnixNano":"1000","asDouble":250.5},
+				{"attributes":[{"key":"server","value":{"stringValue":"node02"}}],"timeUnixNano":"2000","asDouble":300}]}},
+			{"name":"net_bytes_recv","sum":{"dataPoints":[
+				{"timeUnixNano":"3000","asInt":"42"}],"aggregationTemporality":2,"isMonotonic":true}}
+		]}]
+	}]}`
+	require.JSONEq(t, expected, string(body))
+}
+
+func TestWriteExponentialHistogram(t *testing.T) {
+	m := testutil.MustMetric(
+		"ipmi_power",
+		map[string]string{"server": "node01"},
+		map[string]interface{}{
+			"power_count":                  int64(4),
+			"power_sum":                    1500.0,
+			"power_min":                    100.0,
+			"power_max":                    800.0,
+			"power_scale":                  int64(0),
+			"power_zero_count":             int64(0),
+			"power_positive_offset":        int64(6),
+			"power_positive_bucket_counts": "1,1,1,1",
+			"samples":                      int64(4),
+		},
+		time.Unix(1608026400, 0),
+	)
+
+	e := newEncoder()
+	require.NoError(t, e.add(m))
+	require.Len(t, e.metrics, 2)
+
+	h := e.metrics[0]
+	require.Equal(t, "ipmi_power_power", h.Name)
+	require.NotNil(t, h.ExponentialHistogram)
+	require.Equal(t, temporalityDelta, h.ExponentialHistogram.AggregationTemporality)
+
+	sum, min, max := 1500.0, 100.0, 800.0
+	require.Equal(t, []exponentialHistogramDataPoint{
+		{
+			Attributes:   []keyValue{{Key: "server", Value: anyValue{StringValue: "node01"}}},
+			TimeUnixNano: "1608026400000000000",
+			Count:        "4",
+			Sum:          &sum,
+			Scale:        0,
+			ZeroCount:    "0",
+			Positive:     &buckets{Offset: 6, BucketCounts: []string{"1", "1", "1", "1"}},
+			Min:          &min,
+			Max:          &max,
+		},
+	}, h.ExponentialHistogram.DataPoints)
+
+	require.Equal(t, "ipmi_power_samples", e.metrics[1].Name)
+	require.NotNil(t, e.metrics[1].Gauge)
+}
+
+func TestWriteInvalidHistogram(t *testing.T) {
+	m := testutil.MustMetric(
+		"ipmi_power",
+		map[string]string{},
+		map[string]interface{}{
+			"power_count":                  int64(1),
+			"power_scale":                  int64(0),
+			"power_zero_count":             int64(0),
+			"power_positive_offset":        int64(6),
+			"power_positive_bucket_counts": "1,x",
+		},
+		time.Unix(0, 0),
+	)
+
+	require.Error(t, newEncoder().add(m))
+}
+
+func TestWriteGzip(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err = ioutil.ReadAll(gz)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	plugin := &OpenTelemetry{
+		URL:             ts.URL,
+		ContentEncoding: "gzip",
+	}
+	require.NoError(t, plugin.Connect())
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{testutil.TestMetric(1.0)}))
+	require.Contains(t, string(body), `"name":"test1_value"`)
+}
+
+func TestWriteStatusCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	plugin := &OpenTelemetry{URL: ts.URL}
+	require.NoError(t, plugin.Connect())
+
+	require.Error(t, plugin.Write([]telegraf.Metric{testutil.TestMetric(1.0)}))
+}
+
+func TestWriteNothing(t *testing.T) {
+	plugin := &OpenTelemetry{URL: "http://127.0.0.1:1"}
+	require.NoError(t, plugin.Connect())
+
+	m := testutil.MustMetric(
+		"log",
+		map[string]string{},
+		map[string]interface{}{"message": "text only"},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+}
@@ -0,0 +1,365 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// The types below are the messages of an OTLP ExportMetricsServiceRequest in
+// the JSON mapping of protobuf accepted by OTLP/HTTP receivers: field names
+// are lowerCamelCase, enums are numbers and 64 bit integers are strings.
+
+const (
+	temporalityDelta      = 1
+	temporalityCumulative = 2
+)
+
+type exportRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeMetrics struct {
+	Scope   scope         `json:"scope"`
+	Metrics []*otlpMetric `json:"metrics"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpMetric struct {
+	Name                 string                `json:"name"`
+	Gauge                *gauge                `json:"gauge,omitempty"`
+	Sum                  *sum                  `json:"sum,omitempty"`
+	ExponentialHistogram *exponentialHistogram `json:"exponentialHistogram,omitempty"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type sum struct {
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type numberDataPoint struct {
+	Attributes   []keyValue `json:"attributes,omitempty"`
+	TimeUnixNano string     `json:"timeUnixNano"`
+	AsDouble     *float64   `json:"asDouble,omitempty"`
+	AsInt        string     `json:"asInt,omitempty"`
+}
+
+type exponentialHistogram struct {
+	DataPoints             []exponentialHistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                             `json:"aggregationTemporality"`
+}
+
+type exponentialHistogramDataPoint struct {
+	Attributes   []keyValue `json:"attributes,omitempty"`
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Count        string     `json:"count"`
+	Sum          *float64   `json:"sum,omitempty"`
+	Scale        int64      `json:"scale"`
+	ZeroCount    string     `json:"zeroCount"`
+	Positive     *buckets   `json:"positive,omitempty"`
+	Negative     *buckets   `json:"negative,omitempty"`
+	Min          *float64   `json:"min,omitempty"`
+	Max          *float64   `json:"max,omitempty"`
+}
+
+type buckets struct {
+	Offset       int64    `json:"offset"`
+	BucketCounts []string `json:"bucketCounts"`
+}
+
+// histogramSuffixes are the fields written by the exponential_histogram
+// processor for each summarized field.
+var histogramSuffixes = []string{
+	"_count", "_sum", "_min", "_max", "_scale", "_zero_count",
+	"_positive_offset", "_positive_bucket_counts",
+	"_negative_offset", "_negative_bucket_counts",
+}
+
+// encoder groups the data points of a batch into OTLP metrics named
+// <measurement>_<field>.
+type encoder struct {
+	metrics []*otlpMetric
+	index   map[string]*otlpMetric
+}
+
+func newEncoder() *encoder {
+	return &encoder{index: make(map[string]*otlpMetric)}
+}
+
+// metric returns the OTLP metric of the given name and kind, creating it on
+// first use.
+func (e *encoder) metric(name, kind string) *otlpMetric {
+	key := kind + "\x00" + name
+	if m, ok := e.index[key]; ok {
+		return m
+	}
+
+	m := &otlpMetric{Name: name}
+	switch kind {
+	case "sum":
+		m.Sum = &sum{AggregationTemporality: temporalityCumulative, IsMonotonic: true}
+	case "histogram":
+		m.ExponentialHistogram = &exponentialHistogram{AggregationTemporality: temporalityDelta}
+	default:
+		m.Gauge = &gauge{}
+	}
+	e.index[key] = m
+	e.metrics = append(e.metrics, m)
+	return m
+}
+
+// add encodes a telegraf metric.  The fields of exponential histograms are
+// encoded as one histogram data point, the other numeric fields as one gauge
+// or, for counters, sum data point each.  String fields are skipped.
+func (e *encoder) add(metric telegraf.Metric) error {
+	attributes := tagAttributes(metric)
+	timestamp := unixNano(metric.Time())
+	fields := metric.Fields()
+
+	consumed := make(map[string]bool)
+	for _, prefix := range histogramPrefixes(fields) {
+		point, err := histogramPoint(fields, prefix)
+		if err != nil {
+			return fmt.Errorf("encoding histogram %s of %s: %v", prefix, metric.Name(), err)
+		}
+		point.Attributes = attributes
+		point.TimeUnixNano = timestamp
+
+		h := e.metric(metric.Name()+"_"+prefix, "histogram").ExponentialHistogram
+		h.DataPoints = append(h.DataPoints, point)
+
+		for _, suffix := range histogramSuffixes {
+			consumed[prefix+suffix] = true
+		}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if !consumed[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		point, ok := numberPoint(fields[key])
+		if !ok {
+			continue
+		}
+		point.Attributes = attributes
+		point.TimeUnixNano = timestamp
+
+		name := metric.Name() + "_" + key
+		if metric.Type() == telegraf.Counter {
+			s := e.metric(name, "sum").Sum
+			s.DataPoints = append(s.DataPoints, point)
+		} else {
+			g := e.metric(name, "gauge").Gauge
+			g.DataPoints = append(g.DataPoints, point)
+		}
+	}
+	return nil
+}
+
+func tagAttributes(metric telegraf.Metric) []keyValue {
+	var attributes []keyValue
+	for _, tag := range metric.TagList() {
+		attributes = append(attributes, keyValue{Key: tag.Key, Value: anyValue{StringValue: tag.Value}})
+	}
+	return attributes
+}
+
+func mapAttributes(m map[string]string) []keyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var attributes []keyValue
+	for _, k := range keys {
+		attributes = append(attributes, keyValue{Key: k, Value: anyValue{StringValue: m[k]}})
+	}
+	return attributes
+}
+
+// numberPoint converts a field value, returning false for values without a
+// numeric representation.
+func numberPoint(value interface{}) (numberDataPoint, bool) {
+	switch v := value.(type) {
+	case int64:
+		return numberDataPoint{AsInt: strconv.FormatInt(v, 10)}, true
+	case uint64:
+		if v > math.MaxInt64 {
+			f := float64(v)
+			return numberDataPoint{AsDouble: &f}, true
+		}
+		return numberDataPoint{AsInt: strconv.FormatUint(v, 10)}, true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return numberDataPoint{}, false
+		}
+		return numberDataPoint{AsDouble: &v}, true
+	case bool:
+		if v {
+			return numberDataPoint{AsInt: "1"}, true
+		}
+		return numberDataPoint{AsInt: "0"}, true
+	}
+	return numberDataPoint{}, false
+}
+
+// histogramPrefixes returns the sorted names of the fields summarized by the
+// exponential_histogram processor, recognized by their scale, count and zero
+// count fields.
+func histogramPrefixes(fields map[string]interface{}) []string {
+	var prefixes []string
+	for key := range fields {
+		if !strings.HasSuffix(key, "_scale") {
+			continue
+		}
+		prefix := strings.TrimSuffix(key, "_scale")
+		if _, ok := fields[prefix+"_count"]; !ok {
+			continue
+		}
+		if _, ok := fields[prefix+"_zero_count"]; !ok {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+func histogramPoint(fields map[string]interface{}, prefix string) (exponentialHistogramDataPoint, error) {
+	var point exponentialHistogramDataPoint
+
+	count, err := uintField(fields, prefix+"_count")
+	if err != nil {
+		return point, err
+	}
+	point.Count = strconv.FormatUint(count, 10)
+
+	zeroCount, err := uintField(fields, prefix+"_zero_count")
+	if err != nil {
+		return point, err
+	}
+	point.ZeroCount = strconv.FormatUint(zeroCount, 10)
+
+	point.Scale, err = intField(fields, prefix+"_scale")
+	if err != nil {
+		return point, err
+	}
+
+	point.Sum = floatField(fields, prefix+"_sum")
+	point.Min = floatField(fields, prefix+"_min")
+	point.Max = floatField(fields, prefix+"_max")
+
+	point.Positive, err = histogramBuckets(fields, prefix+"_positive")
+	if err != nil {
+		return point, err
+	}
+	point.Negative, err = histogramBuckets(fields, prefix+"_negative")
+	if err != nil {
+		return point, err
+	}
+	return point, nil
+}
+
+// histogramBuckets decodes the offset and the comma separated bucket counts,
+// returning nil if the histogram has no buckets of that sign.
+func histogramBuckets(fields map[string]interface{}, prefix string) (*buckets, error) {
+	value, ok := fields[prefix+"_bucket_counts"]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s_bucket_counts is not a string", prefix)
+	}
+
+	offset, err := intField(fields, prefix+"_offset")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &buckets{Offset: offset, BucketCounts: []string{}}
+	if list == "" {
+		return b, nil
+	}
+	for _, count := range strings.Split(list, ",") {
+		if _, err := strconv.ParseUint(count, 10, 64); err != nil {
+			return nil, fmt.Errorf("%s_bucket_counts: %v", prefix, err)
+		}
+		b.BucketCounts = append(b.BucketCounts, count)
+	}
+	return b, nil
+}
+
+func intField(fields map[string]interface{}, key string) (int64, error) {
+	switch v := fields[key].(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		return int64(v), nil
+	case nil:
+		return 0, fmt.Errorf("%s is missing", key)
+	}
+	return 0, fmt.Errorf("%s is not an integer", key)
+}
+
+func uintField(fields map[string]interface{}, key string) (uint64, error) {
+	v, err := intField(fields, key)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("%s is negative", key)
+	}
+	return uint64(v), nil
+}
+
+func floatField(fields map[string]interface{}, key string) *float64 {
+	v, ok := fields[key].(float64)
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// unixNano formats a time as the string of a fixed64 field.
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
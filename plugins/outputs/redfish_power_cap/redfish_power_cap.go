@@ -0,0 +1,464 @@
+package redfish_power_cap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// limitExceptions are the actions of the BMC if the limit cannot be kept,
+// see the PowerLimitException enum of the Power schema.
+var limitExceptions = map[string]bool{
+	"NoAction":     true,
+	"HardPowerOff": true,
+	"LogEventOnly": true,
+	"Oem":          true,
+}
+
+var sampleConfig = `
+  ## URLs of the BMCs
+  servers = ["https://127.0.0.1"]
+
+  ## Credentials of a user allowed to configure the power limits
+  username = "telegraf"
+  password = ""
+
+  ## Id of the chassis and index of the PowerControl member limited
+  # chassis_id = "1"
+  # power_control_index = 0
+
+  ## Name of the control metrics setting the power limits, other metrics
+  ## are ignored.  The BMC is selected by the host of its URL in
+  ## 'server_tag'.
+  # measurement = "power_cap"
+  # server_tag = "server"
+
+  ## Field holding the power limit in Watts
+  # limit_field = "limit_watts"
+
+  ## Limits outside of this range are rejected, as a safeguard against
+  ## faulty controllers.  0 disables the bound.
+  # min_limit_watts = 0.0
+  # max_limit_watts = 0.0
+
+  ## Action taken by the BMC if the limit cannot be kept within the
+  ## correction time, one of NoAction, HardPowerOff, LogEventOnly or Oem,
+  ## and the correction time in milliseconds.  Unset parameters are left as
+  ## configured on the BMC.
+  # limit_exception = "LogEventOnly"
+  # correction_time_ms = 0
+
+  ## Only log the limit changes and count them in the audit metrics instead
+  ## of applying them.
+  # dry_run = false
+
+  ## Timeout for HTTP requests
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// RedfishPowerCap sets the power limits of chassis through the Redfish API
+// of their BMCs from control metrics.
+type RedfishPowerCap struct {
+	Servers           []string `toml:"servers"`
+	Username          string   `toml:"username"`
+	Password          string   `toml:"password"`
+	ChassisID         string   `toml:"chassis_id"`
+	PowerControlIndex int      `toml:"power_control_index"`
+	Measurement       string   `toml:"measurement"`
+	ServerTag         string   `toml:"server_tag"`
+	LimitField        string   `toml:"limit_field"`
+	MinLimitWatts     float64  `toml:"min_limit_watts"`
+	MaxLimitWatts     float64  `toml:"max_limit_watts"`
+	LimitException    string   `toml:"limit_exception"`
+	CorrectionTimeMs  int      `toml:"correction_time_ms"`
+	DryRun            bool     `toml:"dry_run"`
+	httpconfig.HTTPClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+	// bmcs are the BMCs by host.
+	bmcs map[string]*bmc
+}
+
+// bmc is a BMC, its session and the audit metrics of its limit changes.
+type bmc struct {
+	baseURL *url.URL
+	host    string
+	token   string
+
+	changes       selfstat.Stat
+	dryRunChanges selfstat.Stat
+	failures      selfstat.Stat
+	limitWatts    selfstat.Stat
+}
+
+// power is the Power resource of a chassis.
+type power struct {
+	PowerControl []struct {
+		PowerLimit struct {
+			LimitInWatts   *float64
+			LimitException string
+			CorrectionInMs *int64
+		}
+	}
+}
+
+// powerLimit is the PowerLimit object patched.
+type powerLimit struct {
+	LimitInWatts   float64
+	LimitException string `json:",omitempty"`
+	CorrectionInMs int64  `json:",omitempty"`
+}
+
+// SampleConfig returns a sample configuration.
+func (r *RedfishPowerCap) SampleConfig() string {
+	return sampleConfig
+}
+
+// Description describes the plugin.
+func (r *RedfishPowerCap) Description() string {
+	return "Set the power limits of chassis from control metrics via Redfish"
+}
+
+// Init parses the servers and checks the limit parameters.
+func (r *RedfishPowerCap) Init() error {
+	if len(r.Servers) == 0 {
+		return fmt.Errorf("no servers configured")
+	}
+	if r.ChassisID == "" {
+		r.ChassisID = "1"
+	}
+	if r.PowerControlIndex < 0 {
+		return fmt.Errorf("power_control_index must not be negative")
+	}
+	if r.LimitException != "" && !limitExceptions[r.LimitException] {
+		return fmt.Errorf("invalid limit_exception %q, expected NoAction, HardPowerOff, LogEventOnly or Oem", r.LimitException)
+	}
+	if r.MaxLimitWatts > 0 && r.MinLimitWatts > r.MaxLimitWatts {
+		return fmt.Errorf("min_limit_watts exceeds max_limit_watts")
+	}
+
+	r.bmcs = make(map[string]*bmc, len(r.Servers))
+	for _, s := range r.Servers {
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		host, _, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			host = u.Host
+		}
+		tags := map[string]string{"address": host, "chassis_id": r.ChassisID}
+		r.bmcs[host] = &bmc{
+			baseURL:       u,
+			host:          host,
+			changes:       selfstat.Register("redfish_power_cap", "changes", tags),
+			dryRunChanges: selfstat.Register("redfish_power_cap", "dry_run_changes", tags),
+			failures:      selfstat.Register("redfish_power_cap", "failures", tags),
+			limitWatts:    selfstat.Register("redfish_power_cap", "limit_watts", tags),
+		}
+	}
+
+	var err error
+	r.client, err = r.HTTPClientConfig.CreateClient(context.Background())
+	return err
+}
+
+// Connect satisfies the Output interface.
+func (r *RedfishPowerCap) Connect() error {
+	return nil
+}
+
+// Close satisfies the Output interface.
+func (r *RedfishPowerCap) Close() error {
+	return nil
+}
+
+// Write applies the newest limit requested for every BMC in the batch.
+// Limits already set on the BMC are not patched again.  Invalid control
+// metrics are dropped, failures to set a limit are returned so the batch is
+// retried.
+func (r *RedfishPowerCap) Write(metrics []telegraf.Metric) error {
+	limits := make(map[string]float64)
+	for _, m := range metrics {
+		if m.Name() != r.Measurement {
+			continue
+		}
+		host, _ := m.GetTag(r.ServerTag)
+		if _, ok := r.bmcs[host]; !ok {
+			r.Log.Debugf("Dropping control metric of unknown server %q", host)
+			continue
+		}
+		limit, err := r.parseLimit(m)
+		if err != nil {
+			r.Log.Errorf("Dropping control metric of server %q: %v", host, err)
+			continue
+		}
+		limits[host] = limit
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+	for host, limit := range limits {
+		wg.Add(1)
+		go func(b *bmc, limit float64) {
+			defer wg.Done()
+			if err := r.apply(b, limit); err != nil {
+				b.failures.Incr(1)
+				mu.Lock()
+				errs = append(errs, fmt.Sprintf("%s: %v", b.host, err))
+				mu.Unlock()
+			}
+		}(r.bmcs[host], limit)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("setting power limits failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// parseLimit returns the limit of the control metric.
+func (r *RedfishPowerCap) parseLimit(m telegraf.Metric) (float64, error) {
+	v, ok := m.GetField(r.LimitField)
+	if !ok {
+		return 0, fmt.Errorf("%s not set", r.LimitField)
+	}
+	var limit float64
+	switch v := v.(type) {
+	case float64:
+		limit = v
+	case int64:
+		limit = float64(v)
+	case uint64:
+		limit = float64(v)
+	default:
+		return 0, fmt.Errorf("%s is not a number: %v", r.LimitField, v)
+	}
+	if limit <= 0 {
+		return 0, fmt.Errorf("limit of %v Watts out of range", limit)
+	}
+	if (r.MinLimitWatts > 0 && limit < r.MinLimitWatts) || (r.MaxLimitWatts > 0 && limit > r.MaxLimitWatts) {
+		return 0, fmt.Errorf("limit of %v Watts outside of the allowed range", limit)
+	}
+	return limit, nil
+}
+
+// apply patches the power limit of the chassis if it differs from the one
+// set on the BMC.
+func (r *RedfishPowerCap) apply(b *bmc, limit float64) error {
+	uri := path.Join("/redfish/v1/Chassis", r.ChassisID, "Power")
+	var p power
+	etag, err := r.get(b, uri, &p)
+	if err != nil {
+		return err
+	}
+	if r.PowerControlIndex >= len(p.PowerControl) {
+		return fmt.Errorf("chassis %s has no PowerControl member %d", r.ChassisID, r.PowerControlIndex)
+	}
+
+	current := p.PowerControl[r.PowerControlIndex].PowerLimit
+	if current.LimitInWatts != nil && *current.LimitInWatts == limit &&
+		(r.LimitException == "" || current.LimitException == r.LimitException) &&
+		(r.CorrectionTimeMs == 0 || (current.CorrectionInMs != nil && *current.CorrectionInMs == int64(r.CorrectionTimeMs))) {
+		return nil
+	}
+	previous := "none"
+	if current.LimitInWatts != nil {
+		previous = fmt.Sprintf("%v Watts", *current.LimitInWatts)
+	}
+
+	if r.DryRun {
+		r.Log.Infof("Dry run: would change the power limit of chassis %s of %s from %s to %v Watts", r.ChassisID, b.host, previous, limit)
+		b.dryRunChanges.Incr(1)
+		return nil
+	}
+
+	// Members before the limited one are sent empty to leave them unchanged
+	members := make([]interface{}, r.PowerControlIndex+1)
+	for i := range members {
+		members[i] = struct{}{}
+	}
+	members[r.PowerControlIndex] = map[string]interface{}{
+		"PowerLimit": powerLimit{
+			LimitInWatts:   limit,
+			LimitException: r.LimitException,
+			CorrectionInMs: int64(r.CorrectionTimeMs),
+		},
+	}
+	body, err := json.Marshal(map[string]interface{}{"PowerControl": members})
+	if err != nil {
+		return err
+	}
+	if err := r.patch(b, uri, etag, body); err != nil {
+		return err
+	}
+
+	r.Log.Infof("Changed the power limit of chassis %s of %s from %s to %v Watts", r.ChassisID, b.host, previous, limit)
+	b.changes.Incr(1)
+	b.limitWatts.Set(int64(limit))
+	return nil
+}
+
+// get decodes the resource at the URI and returns its ETag, logging in
+// again if the session expired.
+func (r *RedfishPowerCap) get(b *bmc, uri string, v interface{}) (string, error) {
+	var etag string
+	err := r.do(b, func() (*http.Request, error) {
+		return r.newRequest(b, "GET", uri, nil)
+	}, func(resp *http.Response) error {
+		etag = resp.Header.Get("ETag")
+		return json.NewDecoder(resp.Body).Decode(v)
+	})
+	return etag, err
+}
+
+// patch patches the resource at the URI, conditional on its ETag if known.
+func (r *RedfishPowerCap) patch(b *bmc, uri, etag string, body []byte) error {
+	return r.do(b, func() (*http.Request, error) {
+		req, err := r.newRequest(b, "PATCH", uri, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+		return req, nil
+	}, func(*http.Response) error {
+		return nil
+	})
+}
+
+// do sends the request with the session token and handles the response,
+// logging in first and again if the session expired.
+func (r *RedfishPowerCap) do(b *bmc, newRequest func() (*http.Request, error), handle func(*http.Response) error) error {
+	if b.token == "" {
+		if err := r.login(b); err != nil {
+			return err
+		}
+	}
+
+	for retry := true; ; retry = false {
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Auth-Token", b.token)
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && retry {
+			resp.Body.Close()
+			if err := r.login(b); err != nil {
+				return err
+			}
+			continue
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s %s received status code %d (%s)",
+				req.Method, req.URL.Path,
+				resp.StatusCode,
+				http.StatusText(resp.StatusCode))
+		}
+		return handle(resp)
+	}
+}
+
+// login creates a session on the BMC and keeps its token.
+func (r *RedfishPowerCap) login(b *bmc) error {
+	body, err := json.Marshal(map[string]string{
+		"UserName": r.Username,
+		"Password": r.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := r.newRequest(b, "POST", "/redfish/v1/SessionService/Sessions", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("login received status code %d (%s), expected 201",
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode))
+	}
+
+	token := resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return fmt.Errorf("login returned no session token")
+	}
+	b.token = token
+	return nil
+}
+
+func (r *RedfishPowerCap) newRequest(b *bmc, method, uri string, body *bytes.Reader) (*http.Request, error) {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	loc := b.baseURL.ResolveReference(ref).String()
+
+	var req *http.Request
+	if body != nil {
+		req, err = http.NewRequest(method, loc, body)
+	} else {
+		req, err = http.NewRequest(method, loc, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+	req.Header.Set("User-Agent", internal.ProductToken())
+	return req, nil
+}
+
+func init() {
+	outputs.Add("redfish_power_cap", func() telegraf.Output {
+		return &RedfishPowerCap{
+			ChassisID:   "1",
+			Measurement: "power_cap",
+			ServerTag:   "server",
+			LimitField:  "limit_watts",
+			HTTPClientConfig: httpconfig.HTTPClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+			},
+		}
+	})
+}
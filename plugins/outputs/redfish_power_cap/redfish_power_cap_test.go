@@ -0,0 +1,168 @@
+package redfish_power_cap
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const powerURI = "/redfish/v1/Chassis/1/Power"
+
+// fakeBMC serves the Power resource of a chassis and records the patches.
+type fakeBMC struct {
+	limit   float64
+	patches []map[string]interface{}
+	ifMatch []string
+}
+
+func (f *fakeBMC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/redfish/v1/SessionService/Sessions":
+		w.Header().Set("X-Auth-Token", "token")
+		w.WriteHeader(http.StatusCreated)
+	case r.Header.Get("X-Auth-Token") != "token":
+		w.WriteHeader(http.StatusUnauthorized)
+	case r.Method == "GET" && r.URL.Path == powerURI:
+		w.Header().Set("ETag", `W/"1234"`)
+		w.Write([]byte(`{"PowerControl":[{"PowerLimit":{"LimitInWatts":` +
+			jsonFloat(f.limit) + `,"LimitException":"LogEventOnly"}}]}`))
+	case r.Method == "PATCH" && r.URL.Path == powerURI:
+		body, _ := ioutil.ReadAll(r.Body)
+		var patch map[string]interface{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.patches = append(f.patches, patch)
+		f.ifMatch = append(f.ifMatch, r.Header.Get("If-Match"))
+		f.limit = patch["PowerControl"].([]interface{})[0].(map[string]interface{})["PowerLimit"].(map[string]interface{})["LimitInWatts"].(float64)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func jsonFloat(v float64) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func newPowerCap(t *testing.T, url string) *RedfishPowerCap {
+	r := &RedfishPowerCap{
+		Servers:          []string{url},
+		Username:         "telegraf",
+		Password:         "secret",
+		ChassisID:        "1",
+		Measurement:      "power_cap",
+		ServerTag:        "server",
+		LimitField:       "limit_watts",
+		MaxLimitWatts:    1000,
+		LimitException:   "LogEventOnly",
+		CorrectionTimeMs: 6000,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+	return r
+}
+
+func controlMetric(limit interface{}) telegraf.Metric {
+	return testutil.MustMetric("power_cap",
+		map[string]string{"server": "127.0.0.1"},
+		map[string]interface{}{"limit_watts": limit},
+		time.Unix(0, 0))
+}
+
+func TestWrite(t *testing.T) {
+	bmc := &fakeBMC{limit: 600}
+	ts := httptest.NewServer(bmc)
+	defer ts.Close()
+
+	r := newPowerCap(t, ts.URL)
+	changes := r.bmcs["127.0.0.1"].changes.Get()
+
+	require.NoError(t, r.Write([]telegraf.Metric{
+		controlMetric(400.0),
+		controlMetric(int64(450)),
+		testutil.MustMetric("ipmi_power", map[string]string{"server": "127.0.0.1"},
+			map[string]interface{}{"limit_watts": 1.0}, time.Unix(0, 0)),
+	}))
+	require.Len(t, bmc.patches, 1)
+	require.Equal(t, map[string]interface{}{
+		"PowerControl": []interface{}{
+			map[string]interface{}{
+				"PowerLimit": map[string]interface{}{
+					"LimitInWatts":   450.0,
+					"LimitException": "LogEventOnly",
+					"CorrectionInMs": 6000.0,
+				},
+			},
+		},
+	}, bmc.patches[0])
+	require.Equal(t, []string{`W/"1234"`}, bmc.ifMatch)
+	require.Equal(t, changes+1, r.bmcs["127.0.0.1"].changes.Get())
+	require.Equal(t, int64(450), r.bmcs["127.0.0.1"].limitWatts.Get())
+
+	// Limits already set on the BMC are not patched again, the fake BMC
+	// does not report the correction time
+	r.CorrectionTimeMs = 0
+	require.NoError(t, r.Write([]telegraf.Metric{controlMetric(450.0)}))
+	require.Len(t, bmc.patches, 1)
+}
+
+func TestWriteDryRun(t *testing.T) {
+	bmc := &fakeBMC{limit: 600}
+	ts := httptest.NewServer(bmc)
+	defer ts.Close()
+
+	r := newPowerCap(t, ts.URL)
+	r.DryRun = true
+	dryRunChanges := r.bmcs["127.0.0.1"].dryRunChanges.Get()
+
+	require.NoError(t, r.Write([]telegraf.Metric{controlMetric(400.0)}))
+	require.Empty(t, bmc.patches)
+	require.Equal(t, dryRunChanges+1, r.bmcs["127.0.0.1"].dryRunChanges.Get())
+}
+
+func TestWriteInvalid(t *testing.T) {
+	bmc := &fakeBMC{limit: 600}
+	ts := httptest.NewServer(bmc)
+	defer ts.Close()
+
+	r := newPowerCap(t, ts.URL)
+	require.NoError(t, r.Write([]telegraf.Metric{
+		controlMetric(1200.0),
+		controlMetric("high"),
+		testutil.MustMetric("power_cap", map[string]string{"server": "192.168.1.9"},
+			map[string]interface{}{"limit_watts": 400.0}, time.Unix(0, 0)),
+	}))
+	require.Empty(t, bmc.patches)
+}
+
+func TestWriteFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	r := newPowerCap(t, ts.URL)
+	failures := r.bmcs["127.0.0.1"].failures.Get()
+	err := r.Write([]telegraf.Metric{controlMetric(400.0)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "login received status code 403")
+	require.Equal(t, failures+1, r.bmcs["127.0.0.1"].failures.Get())
+}
+
+func TestInitInvalidException(t *testing.T) {
+	r := &RedfishPowerCap{
+		Servers:        []string{"https://127.0.0.1"},
+		LimitException: "Reboot",
+	}
+	require.Error(t, r.Init())
+}
@@ -0,0 +1,218 @@
+package exponential_histogram
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Measurements summarized as exponential histograms, glob patterns are
+  ## supported.  The raw metrics are dropped.
+  measurements = ["ipmi_power"]
+
+  ## Fields summarized, glob patterns are supported.  Empty for all numeric
+  ## fields, other fields are dropped.
+  # fields = []
+
+  ## Length of the windows, aligned to the wall clock
+  # period = "1m"
+
+  ## Maximum number of buckets for the positive and for the negative values,
+  ## the scale is lowered until the values of a window fit.
+  # max_size = 160
+
+  ## Initial scale of the histograms, the relative error of the buckets is
+  ## at most 2^(2^-max_scale) - 1.
+  # max_scale = 20
+`
+
+type ExponentialHistogram struct {
+	Measurements []string          `toml:"measurements"`
+	Fields       []string          `toml:"fields"`
+	Period       internal.Duration `toml:"period"`
+	MaxSize      int               `toml:"max_size"`
+	MaxScale     int               `toml:"max_scale"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	measurementFilter filter.Filter
+	fieldFilter       filter.Filter
+	acc               telegraf.Accumulator
+
+	sync.Mutex
+	cache map[uint64]*series
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// series holds the histograms of the fields of a series in the current
+// window.
+type series struct {
+	name       string
+	tags       map[string]string
+	histograms map[string]*histogram
+}
+
+func (e *ExponentialHistogram) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *ExponentialHistogram) Description() string {
+	return "Summarize fields per window as mergeable exponential histograms"
+}
+
+func (e *ExponentialHistogram) Init() error {
+	if len(e.Measurements) == 0 {
+		return fmt.Errorf("no measurements configured")
+	}
+	if e.Period.Duration <= 0 {
+		return fmt.Errorf("period must be positive")
+	}
+	if e.MaxSize < 2 {
+		return fmt.Errorf("max_size must be at least 2")
+	}
+	if e.MaxScale < -10 || e.MaxScale > 20 {
+		return fmt.Errorf("max_scale must be between -10 and 20")
+	}
+
+	var err error
+	e.measurementFilter, err = filter.Compile(e.Measurements)
+	if err != nil {
+		return err
+	}
+	e.fieldFilter, err = filter.Compile(e.Fields)
+	if err != nil {
+		return err
+	}
+	e.cache = make(map[uint64]*series)
+	return nil
+}
+
+func (e *ExponentialHistogram) Start(acc telegraf.Accumulator) error {
+	e.acc = acc
+	e.done = make(chan struct{})
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for {
+			now := time.Now()
+			end := now.Truncate(e.Period.Duration).Add(e.Period.Duration)
+			select {
+			case <-e.done:
+				return
+			case <-time.After(end.Sub(now)):
+				e.push(end.Add(-e.Period.Duration))
+			}
+		}
+	}()
+	return nil
+}
+
+func (e *ExponentialHistogram) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	if !e.measurementFilter.Match(m.Name()) {
+		acc.AddMetric(m)
+		return nil
+	}
+
+	e.Lock()
+	id := m.HashID()
+	s, ok := e.cache[id]
+	if !ok {
+		s = &series{
+			name:       m.Name(),
+			tags:       m.Tags(),
+			histograms: make(map[string]*histogram),
+		}
+		e.cache[id] = s
+	}
+	for _, field := range m.FieldList() {
+		if e.fieldFilter != nil && !e.fieldFilter.Match(field.Key) {
+			continue
+		}
+		value, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+		h, ok := s.histograms[field.Key]
+		if !ok {
+			h = newHistogram(e.MaxSize, e.MaxScale)
+			s.histograms[field.Key] = h
+		}
+		h.add(value)
+	}
+	e.Unlock()
+
+	m.Drop()
+	return nil
+}
+
+// Stop emits the histograms of the incomplete window.
+func (e *ExponentialHistogram) Stop() error {
+	close(e.done)
+	e.wg.Wait()
+	e.push(time.Now().Truncate(e.Period.Duration))
+	return nil
+}
+
+// push emits the histograms of the window starting at start and resets the
+// cache.
+func (e *ExponentialHistogram) push(start time.Time) {
+	e.Lock()
+	cache := e.cache
+	e.cache = make(map[uint64]*series)
+	e.Unlock()
+
+	for _, s := range cache {
+		fields := make(map[string]interface{})
+		for key, h := range s.histograms {
+			if h.count == 0 {
+				continue
+			}
+			for k, v := range h.fields(key) {
+				fields[k] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		m, err := metric.New(s.name, s.tags, fields, start)
+		if err != nil {
+			e.Log.Errorf("Creating histogram of %s failed: %v", s.name, err)
+			continue
+		}
+		e.acc.AddMetric(m)
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.AddStreaming("exponential_histogram", func() telegraf.StreamingProcessor {
+		return &ExponentialHistogram{
+			Measurements: []string{"ipmi_power"},
+			Period:       internal.Duration{Duration: time.Minute},
+			MaxSize:      160,
+			MaxScale:     20,
+		}
+	})
+}
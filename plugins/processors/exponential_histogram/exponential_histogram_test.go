@@ -0,0 +1,138 @@
+package exponential_histogram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newExponentialHistogram(t *testing.T) *ExponentialHistogram {
+	e := &ExponentialHistogram{
+		Measurements: []string{"ipmi_*"},
+		Period:       internal.Duration{Duration: time.Hour},
+		MaxSize:      4,
+		MaxScale:     20,
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, e.Init())
+	return e
+}
+
+func TestWindowedHistograms(t *testing.T) {
+	e := newExponentialHistogram(t)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+
+	now := time.Now()
+	for i, power := range []float64{100, 200, 400, 800} {
+		require.NoError(t, e.Add(testutil.MustMetric("ipmi_power",
+			map[string]string{"server": "node01"},
+			map[string]interface{}{"power": power, "unit": "Watts"},
+			now.Add(time.Duration(i)*time.Second)), acc))
+	}
+	require.NoError(t, e.Add(testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"usage": 42.0},
+		now), acc))
+
+	// Metrics of other measurements pass through, raw ones are held back
+	require.Equal(t, uint64(1), acc.NMetrics())
+
+	start := time.Unix(0, 0)
+	e.push(start)
+	require.NoError(t, e.Stop())
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"usage": 42.0},
+			now),
+		testutil.MustMetric("ipmi_power",
+			map[string]string{"server": "node01"},
+			map[string]interface{}{
+				"power_count":                  int64(4),
+				"power_sum":                    1500.0,
+				"power_min":                    100.0,
+				"power_max":                    800.0,
+				"power_scale":                  int64(0),
+				"power_zero_count":             int64(0),
+				"power_positive_offset":        int64(6),
+				"power_positive_bucket_counts": "1,1,1,1",
+			},
+			start),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestFieldFilter(t *testing.T) {
+	e := newExponentialHistogram(t)
+	e.Fields = []string{"power"}
+	require.NoError(t, e.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+
+	require.NoError(t, e.Add(testutil.MustMetric("ipmi_power",
+		map[string]string{"server": "node01"},
+		map[string]interface{}{"power": 220.0, "current": 1.0},
+		time.Now()), acc))
+	require.NoError(t, e.Stop())
+
+	require.Len(t, acc.Metrics, 1)
+	require.Contains(t, acc.Metrics[0].Fields, "power_count")
+	require.NotContains(t, acc.Metrics[0].Fields, "current_count")
+}
+
+func TestBucketIndex(t *testing.T) {
+	tests := []struct {
+		value    float64
+		scale    int
+		expected int
+	}{
+		{1, 0, -1},
+		{2, 0, 0},
+		{3, 0, 1},
+		{4, 0, 1},
+		{2, 1, 1},
+		{3, 1, 3},
+		{4, -1, 0},
+		{5, -1, 1},
+		{0.5, 0, -2},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, bucketIndex(tt.value, tt.scale), "value %v at scale %d", tt.value, tt.scale)
+	}
+}
+
+func TestHistogramDownscale(t *testing.T) {
+	h := newHistogram(4, 20)
+	for _, v := range []float64{1, 2, 4, 8, 0, -3} {
+		h.add(v)
+	}
+
+	require.Equal(t, map[string]interface{}{
+		"power_count":                  int64(6),
+		"power_sum":                    12.0,
+		"power_min":                    -3.0,
+		"power_max":                    8.0,
+		"power_scale":                  int64(0),
+		"power_zero_count":             int64(1),
+		"power_positive_offset":        int64(-1),
+		"power_positive_bucket_counts": "1,1,1,1",
+		"power_negative_offset":        int64(1),
+		"power_negative_bucket_counts": "1",
+	}, h.fields("power"))
+}
+
+func TestInit(t *testing.T) {
+	e := &ExponentialHistogram{
+		Measurements: []string{"ipmi_power"},
+		Period:       internal.Duration{Duration: time.Minute},
+		MaxSize:      1,
+		MaxScale:     20,
+	}
+	require.Error(t, e.Init())
+}
@@ -0,0 +1,168 @@
+package exponential_histogram
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// histogram is a base-2 exponential histogram as defined by the OpenTelemetry
+// metrics data model.  Bucket i covers the values in (base^i, base^(i+1)],
+// with base = 2^(2^-scale).  The scale is lowered whenever the buckets would
+// exceed maxSize, halving the resolution each step.
+type histogram struct {
+	maxSize int
+
+	scale     int
+	count     uint64
+	zeroCount uint64
+	sum       float64
+	min       float64
+	max       float64
+	positive  buckets
+	negative  buckets
+}
+
+// buckets holds the counts of the populated bucket indexes.
+type buckets struct {
+	counts map[int]uint64
+	low    int
+	high   int
+}
+
+func newHistogram(maxSize, maxScale int) *histogram {
+	return &histogram{
+		maxSize:  maxSize,
+		scale:    maxScale,
+		positive: buckets{counts: make(map[int]uint64)},
+		negative: buckets{counts: make(map[int]uint64)},
+	}
+}
+
+func (h *histogram) add(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if h.count == 0 || v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += v
+
+	if v == 0 {
+		h.zeroCount++
+		return
+	}
+	b := &h.positive
+	if v < 0 {
+		b = &h.negative
+		v = -v
+	}
+
+	index := bucketIndex(v, h.scale)
+	for b.span(index) > h.maxSize {
+		h.downscale(1)
+		index = bucketIndex(v, h.scale)
+	}
+	b.add(index)
+}
+
+// downscale lowers the scale by change, merging 2^change adjacent buckets.
+func (h *histogram) downscale(change int) {
+	h.scale -= change
+	h.positive.downscale(change)
+	h.negative.downscale(change)
+}
+
+// bucketIndex returns the index of the bucket holding the positive value at
+// the scale.
+func bucketIndex(v float64, scale int) int {
+	if scale <= 0 {
+		// Exact computation using the binary exponent, powers of two are
+		// the upper bound of the bucket below.
+		frac, exp := math.Frexp(v)
+		exp--
+		if frac == 0.5 {
+			exp--
+		}
+		return exp >> uint(-scale)
+	}
+	return int(math.Ceil(math.Log2(v)*math.Ldexp(1, scale))) - 1
+}
+
+// span returns the number of buckets needed to cover the populated buckets
+// and index.
+func (b *buckets) span(index int) int {
+	if len(b.counts) == 0 {
+		return 1
+	}
+	low, high := b.low, b.high
+	if index < low {
+		low = index
+	}
+	if index > high {
+		high = index
+	}
+	return high - low + 1
+}
+
+func (b *buckets) add(index int) {
+	if len(b.counts) == 0 || index < b.low {
+		b.low = index
+	}
+	if len(b.counts) == 0 || index > b.high {
+		b.high = index
+	}
+	b.counts[index]++
+}
+
+func (b *buckets) downscale(change int) {
+	if len(b.counts) == 0 {
+		return
+	}
+	counts := make(map[int]uint64, len(b.counts))
+	for index, count := range b.counts {
+		counts[index>>uint(change)] += count
+	}
+	b.counts = counts
+	b.low >>= uint(change)
+	b.high >>= uint(change)
+}
+
+// encode returns the offset of the first bucket and the counts of the
+// contiguous buckets from there as a comma separated list, matching the
+// offset and bucket_counts of an OTLP ExponentialHistogramDataPoint.
+func (b *buckets) encode() (int64, string) {
+	counts := make([]string, 0, b.high-b.low+1)
+	for index := b.low; index <= b.high; index++ {
+		counts = append(counts, strconv.FormatUint(b.counts[index], 10))
+	}
+	return int64(b.low), strings.Join(counts, ",")
+}
+
+// fields returns the fields of the histogram prefixed by the name of the
+// field it was built of.
+func (h *histogram) fields(prefix string) map[string]interface{} {
+	fields := map[string]interface{}{
+		prefix + "_count":      int64(h.count),
+		prefix + "_sum":        h.sum,
+		prefix + "_min":        h.min,
+		prefix + "_max":        h.max,
+		prefix + "_scale":      int64(h.scale),
+		prefix + "_zero_count": int64(h.zeroCount),
+	}
+	if len(h.positive.counts) > 0 {
+		offset, counts := h.positive.encode()
+		fields[prefix+"_positive_offset"] = offset
+		fields[prefix+"_positive_bucket_counts"] = counts
+	}
+	if len(h.negative.counts) > 0 {
+		offset, counts := h.negative.encode()
+		fields[prefix+"_negative_offset"] = offset
+		fields[prefix+"_negative_bucket_counts"] = counts
+	}
+	return fields
+}
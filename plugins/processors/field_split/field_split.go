@@ -0,0 +1,198 @@
+package fieldsplit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Split rules are applied in order.  Each rule matches a string field or
+  ## a tag against a regular expression with named groups; the groups listed
+  ## in 'fields' are added as numeric fields, the groups listed in 'tags'
+  ## are added as tags.  Values not matching the pattern are left alone.
+  [[processors.field_split.rule]]
+    ## Measurements the rule applies to, supports globs.  Applies to all
+    ## measurements if empty.
+    measurements = ["ipmi_sensor"]
+
+    ## Field holding the combined string, e.g. "142 Watts"
+    field = "reading"
+    ## Alternatively the tag holding the combined string
+    # tag = ""
+
+    ## Regular expression with named groups
+    pattern = '^(?P<value>[-+]?[0-9.]+(?:[eE][-+]?[0-9]+)?)\s+(?P<unit>\S.*)$'
+
+    ## Groups converted to numeric fields, integers are kept as integers
+    fields = ["value"]
+    ## Groups added as tags
+    tags = ["unit"]
+
+    ## Remove the original field or tag if the pattern matched
+    # drop_original = false
+
+  ## Split a sensor name such as "CPU1_VR_Pwr" into the device, its index
+  ## and the sensor.
+  # [[processors.field_split.rule]]
+  #   measurements = ["ipmi_sensor"]
+  #   tag = "name"
+  #   pattern = '^(?P<device>[A-Za-z]+)(?P<index>\d+)_(?P<sensor>.+)$'
+  #   tags = ["device", "index", "sensor"]
+  #   drop_original = true
+`
+
+type FieldSplit struct {
+	Rules []*Rule `toml:"rule"`
+
+	Log telegraf.Logger `toml:"-"`
+}
+
+// Rule describes how to split a single field or tag.
+type Rule struct {
+	Measurements []string `toml:"measurements"`
+	Field        string   `toml:"field"`
+	Tag          string   `toml:"tag"`
+	Pattern      string   `toml:"pattern"`
+	Fields       []string `toml:"fields"`
+	Tags         []string `toml:"tags"`
+	DropOriginal bool     `toml:"drop_original"`
+
+	filter filter.Filter
+	regex  *regexp.Regexp
+	groups map[string]int
+}
+
+func (s *FieldSplit) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *FieldSplit) Description() string {
+	return "Split combined strings such as value and unit into fields and tags."
+}
+
+func (s *FieldSplit) Init() error {
+	for i, rule := range s.Rules {
+		if err := rule.init(); err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) init() error {
+	if (r.Field == "") == (r.Tag == "") {
+		return fmt.Errorf("exactly one of field or tag must be set")
+	}
+	if len(r.Fields) == 0 && len(r.Tags) == 0 {
+		return fmt.Errorf("no fields or tags to extract")
+	}
+
+	var err error
+	r.filter, err = filter.Compile(r.Measurements)
+	if err != nil {
+		return err
+	}
+	r.regex, err = regexp.Compile(r.Pattern)
+	if err != nil {
+		return err
+	}
+
+	r.groups = make(map[string]int)
+	for i, name := range r.regex.SubexpNames() {
+		if name != "" {
+			r.groups[name] = i
+		}
+	}
+	for _, name := range append(r.Fields, r.Tags...) {
+		if _, ok := r.groups[name]; !ok {
+			return fmt.Errorf("pattern has no group named %q", name)
+		}
+	}
+	return nil
+}
+
+func (s *FieldSplit) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		for _, rule := range s.Rules {
+			if err := rule.apply(metric); err != nil {
+				s.Log.Debugf("Skipping rule for %q: %v", metric.Name(), err)
+			}
+		}
+	}
+	return in
+}
+
+// apply splits the value of the rule's field or tag, the metric is only
+// modified if all groups could be converted.
+func (r *Rule) apply(metric telegraf.Metric) error {
+	if r.filter != nil && !r.filter.Match(metric.Name()) {
+		return nil
+	}
+
+	var value string
+	if r.Field != "" {
+		v, ok := metric.GetField(r.Field)
+		if !ok {
+			return nil
+		}
+		if value, ok = v.(string); !ok {
+			return nil
+		}
+	} else {
+		var ok bool
+		if value, ok = metric.GetTag(r.Tag); !ok {
+			return nil
+		}
+	}
+
+	match := r.regex.FindStringSubmatch(value)
+	if match == nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(r.Fields))
+	for _, name := range r.Fields {
+		v, err := parseNumber(match[r.groups[name]])
+		if err != nil {
+			return fmt.Errorf("group %q: %v", name, err)
+		}
+		fields[name] = v
+	}
+
+	if r.DropOriginal {
+		if r.Field != "" {
+			metric.RemoveField(r.Field)
+		} else {
+			metric.RemoveTag(r.Tag)
+		}
+	}
+	for name, v := range fields {
+		metric.AddField(name, v)
+	}
+	for _, name := range r.Tags {
+		if v := match[r.groups[name]]; v != "" {
+			metric.AddTag(name, v)
+		}
+	}
+	return nil
+}
+
+// parseNumber returns an integer if the string holds one and a float
+// otherwise.
+func parseNumber(s string) (interface{}, error) {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func init() {
+	processors.Add("field_split", func() telegraf.Processor {
+		return &FieldSplit{}
+	})
+}
@@ -0,0 +1,157 @@
+package fieldsplit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const valueUnitPattern = `^(?P<value>[-+]?[0-9.]+(?:[eE][-+]?[0-9]+)?)\s+(?P<unit>\S.*)$`
+
+func TestSplitValueUnit(t *testing.T) {
+	plugin := &FieldSplit{
+		Rules: []*Rule{
+			{
+				Measurements: []string{"ipmi_*"},
+				Field:        "reading",
+				Pattern:      valueUnitPattern,
+				Fields:       []string{"value"},
+				Tags:         []string{"unit"},
+				DropOriginal: true,
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	input := []telegraf.Metric{
+		testutil.MustMetric("ipmi_sensor",
+			map[string]string{"name": "PSU1"},
+			map[string]interface{}{"reading": "142 Watts"},
+			now),
+		testutil.MustMetric("ipmi_sensor",
+			map[string]string{"name": "Planar VBAT"},
+			map[string]interface{}{"reading": "3.05 Volts"},
+			now),
+		testutil.MustMetric("ipmi_sensor",
+			map[string]string{"name": "Fan 1"},
+			map[string]interface{}{"reading": "0x00"},
+			now),
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"reading": "42 percent"},
+			now),
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("ipmi_sensor",
+			map[string]string{"name": "PSU1", "unit": "Watts"},
+			map[string]interface{}{"value": int64(142)},
+			now),
+		testutil.MustMetric("ipmi_sensor",
+			map[string]string{"name": "Planar VBAT", "unit": "Volts"},
+			map[string]interface{}{"value": 3.05},
+			now),
+		testutil.MustMetric("ipmi_sensor",
+			map[string]string{"name": "Fan 1"},
+			map[string]interface{}{"reading": "0x00"},
+			now),
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"reading": "42 percent"},
+			now),
+	}
+
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestSplitNameIndex(t *testing.T) {
+	plugin := &FieldSplit{
+		Rules: []*Rule{
+			{
+				Tag:     "name",
+				Pattern: `^(?P<device>[A-Za-z]+)(?P<index>\d+)_(?P<sensor>.+)$`,
+				Tags:    []string{"device", "index", "sensor"},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	input := testutil.MustMetric("ipmi_sensor",
+		map[string]string{"name": "CPU1_VR_Pwr"},
+		map[string]interface{}{"value": 12.0},
+		now)
+	expected := testutil.MustMetric("ipmi_sensor",
+		map[string]string{"name": "CPU1_VR_Pwr", "device": "CPU", "index": "1", "sensor": "VR_Pwr"},
+		map[string]interface{}{"value": 12.0},
+		now)
+
+	actual := plugin.Apply(input)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, actual)
+}
+
+func TestSplitConversionFailureLeavesMetric(t *testing.T) {
+	plugin := &FieldSplit{
+		Rules: []*Rule{
+			{
+				Field:        "reading",
+				Pattern:      `^(?P<value>\S+)\s+(?P<unit>\S+)$`,
+				Fields:       []string{"value"},
+				Tags:         []string{"unit"},
+				DropOriginal: true,
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	input := testutil.MustMetric("ipmi_sensor",
+		map[string]string{},
+		map[string]interface{}{"reading": "na Watts"},
+		now)
+	expected := input.Copy()
+
+	actual := plugin.Apply(input)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, actual)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule *Rule
+	}{
+		{
+			name: "no source",
+			rule: &Rule{Pattern: `(?P<value>\d+)`, Fields: []string{"value"}},
+		},
+		{
+			name: "field and tag",
+			rule: &Rule{Field: "a", Tag: "b", Pattern: `(?P<value>\d+)`, Fields: []string{"value"}},
+		},
+		{
+			name: "nothing extracted",
+			rule: &Rule{Field: "a", Pattern: `(?P<value>\d+)`},
+		},
+		{
+			name: "invalid pattern",
+			rule: &Rule{Field: "a", Pattern: `(?P<value>\d+`, Fields: []string{"value"}},
+		},
+		{
+			name: "unknown group",
+			rule: &Rule{Field: "a", Pattern: `(?P<value>\d+)`, Tags: []string{"unit"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &FieldSplit{Rules: []*Rule{tt.rule}}
+			require.Error(t, plugin.Init())
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package privacy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Measurements only leaving the node as windowed aggregates, glob
+  ## patterns are supported.  The raw metrics are dropped.
+  measurements = []
+
+  ## Length of the windows, aligned to the wall clock
+  # period = "5m"
+
+  ## Statistics emitted per numeric field as "<field>_<stat>", supported
+  ## are count, min, max, mean and sum.  Non-numeric fields are dropped.
+  # stats = ["count", "min", "max", "mean"]
+
+  ## Windows with fewer samples of a series are dropped, so sparse series do
+  ## not reveal their raw values.
+  # min_count = 1
+`
+
+var validStats = map[string]bool{
+	"count": true,
+	"min":   true,
+	"max":   true,
+	"mean":  true,
+	"sum":   true,
+}
+
+type Privacy struct {
+	Measurements []string          `toml:"measurements"`
+	Period       internal.Duration `toml:"period"`
+	Stats        []string          `toml:"stats"`
+	MinCount     int               `toml:"min_count"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	filter filter.Filter
+	acc    telegraf.Accumulator
+
+	sync.Mutex
+	cache map[uint64]*series
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// series holds the statistics of a series in the current window.
+type series struct {
+	name   string
+	tags   map[string]string
+	count  int
+	fields map[string]*stats
+}
+
+type stats struct {
+	count int
+	min   float64
+	max   float64
+	sum   float64
+}
+
+func (p *Privacy) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Privacy) Description() string {
+	return "Forward designated measurements only as windowed aggregates"
+}
+
+func (p *Privacy) Init() error {
+	if len(p.Measurements) == 0 {
+		return fmt.Errorf("no measurements configured")
+	}
+	if p.Period.Duration <= 0 {
+		return fmt.Errorf("period must be positive")
+	}
+	for _, stat := range p.Stats {
+		if !validStats[stat] {
+			return fmt.Errorf("unsupported stat %q", stat)
+		}
+	}
+
+	var err error
+	p.filter, err = filter.Compile(p.Measurements)
+	if err != nil {
+		return err
+	}
+	p.cache = make(map[uint64]*series)
+	return nil
+}
+
+func (p *Privacy) Start(acc telegraf.Accumulator) error {
+	p.acc = acc
+	p.done = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			now := time.Now()
+			end := now.Truncate(p.Period.Duration).Add(p.Period.Duration)
+			select {
+			case <-p.done:
+				return
+			case <-time.After(end.Sub(now)):
+				p.push(end.Add(-p.Period.Duration))
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *Privacy) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	if !p.filter.Match(m.Name()) {
+		acc.AddMetric(m)
+		return nil
+	}
+
+	p.Lock()
+	id := m.HashID()
+	s, ok := p.cache[id]
+	if !ok {
+		s = &series{
+			name:   m.Name(),
+			tags:   m.Tags(),
+			fields: make(map[string]*stats),
+		}
+		p.cache[id] = s
+	}
+	s.count++
+	for _, field := range m.FieldList() {
+		value, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+		st, ok := s.fields[field.Key]
+		if !ok {
+			s.fields[field.Key] = &stats{count: 1, min: value, max: value, sum: value}
+			continue
+		}
+		st.count++
+		st.sum += value
+		if value < st.min {
+			st.min = value
+		}
+		if value > st.max {
+			st.max = value
+		}
+	}
+	p.Unlock()
+
+	m.Drop()
+	return nil
+}
+
+// Stop emits the aggregates of the incomplete window.
+func (p *Privacy) Stop() error {
+	close(p.done)
+	p.wg.Wait()
+	p.push(time.Now().Truncate(p.Period.Duration))
+	return nil
+}
+
+// push emits the aggregates of the window starting at start and resets the
+// cache.
+func (p *Privacy) push(start time.Time) {
+	p.Lock()
+	cache := p.cache
+	p.cache = make(map[uint64]*series)
+	p.Unlock()
+
+	for _, s := range cache {
+		if s.count < p.MinCount {
+			p.Log.Debugf("Dropping window of %s with %d samples", s.name, s.count)
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		for key, st := range s.fields {
+			for _, stat := range p.Stats {
+				switch stat {
+				case "count":
+					fields[key+"_count"] = int64(st.count)
+				case "min":
+					fields[key+"_min"] = st.min
+				case "max":
+					fields[key+"_max"] = st.max
+				case "mean":
+					fields[key+"_mean"] = st.sum / float64(st.count)
+				case "sum":
+					fields[key+"_sum"] = st.sum
+				}
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		m, err := metric.New(s.name, s.tags, fields, start)
+		if err != nil {
+			p.Log.Errorf("Creating aggregate of %s failed: %v", s.name, err)
+			continue
+		}
+		p.acc.AddMetric(m)
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	processors.AddStreaming("privacy", func() telegraf.StreamingProcessor {
+		return &Privacy{
+			Period:   internal.Duration{Duration: 5 * time.Minute},
+			Stats:    []string{"count", "min", "max", "mean"},
+			MinCount: 1,
+		}
+	})
+}
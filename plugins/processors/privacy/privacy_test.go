@@ -0,0 +1,105 @@
+package privacy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newPrivacy(t *testing.T) *Privacy {
+	p := &Privacy{
+		Measurements: []string{"ipmi_*"},
+		Period:       internal.Duration{Duration: time.Hour},
+		Stats:        []string{"count", "min", "max", "mean"},
+		MinCount:     1,
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+	return p
+}
+
+func TestWindowedAggregates(t *testing.T) {
+	p := newPrivacy(t)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+
+	now := time.Now()
+	for i, power := range []float64{200, 220, 240} {
+		require.NoError(t, p.Add(testutil.MustMetric("ipmi_power",
+			map[string]string{"server": "node01"},
+			map[string]interface{}{"power": power, "unit": "Watts"},
+			now.Add(time.Duration(i)*time.Second)), acc))
+	}
+	require.NoError(t, p.Add(testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"usage": 42.0},
+		now), acc))
+
+	// Metrics of other measurements pass through, raw ones are held back
+	require.Equal(t, uint64(1), acc.NMetrics())
+
+	start := time.Unix(0, 0)
+	p.push(start)
+	require.NoError(t, p.Stop())
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"usage": 42.0},
+			now),
+		testutil.MustMetric("ipmi_power",
+			map[string]string{"server": "node01"},
+			map[string]interface{}{
+				"power_count": int64(3),
+				"power_min":   200.0,
+				"power_max":   240.0,
+				"power_mean":  220.0,
+			},
+			start),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestMinCount(t *testing.T) {
+	p := newPrivacy(t)
+	p.MinCount = 2
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+
+	require.NoError(t, p.Add(testutil.MustMetric("ipmi_power",
+		map[string]string{"server": "node01"},
+		map[string]interface{}{"power": 220.0},
+		time.Now()), acc))
+	require.NoError(t, p.Stop())
+	require.Zero(t, acc.NMetrics())
+}
+
+func TestStopFlushesWindow(t *testing.T) {
+	p := newPrivacy(t)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+
+	require.NoError(t, p.Add(testutil.MustMetric("ipmi_power",
+		map[string]string{"server": "node01"},
+		map[string]interface{}{"power": int64(220)},
+		time.Now()), acc))
+	require.NoError(t, p.Stop())
+
+	require.Equal(t, uint64(1), acc.NMetrics())
+	m := acc.GetTelegrafMetrics()[0]
+	require.Equal(t, 220.0, m.Fields()["power_mean"])
+	require.NotContains(t, m.Fields(), "power")
+}
+
+func TestInitInvalidStat(t *testing.T) {
+	p := &Privacy{
+		Measurements: []string{"ipmi_power"},
+		Period:       internal.Duration{Duration: time.Minute},
+		Stats:        []string{"stdev"},
+	}
+	require.Error(t, p.Init())
+}
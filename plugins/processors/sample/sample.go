@@ -0,0 +1,155 @@
+package sample
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Keep one of every 'ratio' series, all metrics of a series are either
+  ## kept or dropped.  Use the metric filters, e.g. namepass, to only sample
+  ## high-rate sources.
+  ratio = 10
+
+  ## Tags identifying a series together with the measurement name, all tags
+  ## are used if empty.
+  # tags = ["host", "cpu"]
+
+  ## Hash function choosing the kept series, one of fnv1a, fnv1, crc32 or
+  ## md5.
+  # hash = "fnv1a"
+
+  ## Seed of the hash, changing it keeps a different subset of the series.
+  # seed = ""
+
+  ## Field added to kept metrics holding the number of series each kept
+  ## series stands for, multiply by it to estimate fleet totals.  Set to
+  ## empty to not annotate the metrics.
+  # weight_field = "sample_weight"
+`
+
+// hashes are the hash functions series can be chosen by.
+var hashes = map[string]func() hash.Hash{
+	"fnv1a": func() hash.Hash { return fnv.New64a() },
+	"fnv1":  func() hash.Hash { return fnv.New64() },
+	"crc32": func() hash.Hash { return crc32.NewIEEE() },
+	"md5":   md5.New,
+}
+
+type Sample struct {
+	Ratio       int      `toml:"ratio"`
+	Tags        []string `toml:"tags"`
+	Hash        string   `toml:"hash"`
+	Seed        string   `toml:"seed"`
+	WeightField string   `toml:"weight_field"`
+
+	newHash func() hash.Hash
+}
+
+func (s *Sample) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Sample) Description() string {
+	return "Keep a deterministic sample of series chosen by their hash."
+}
+
+func (s *Sample) Init() error {
+	if s.Ratio < 1 {
+		return fmt.Errorf("ratio must be at least 1")
+	}
+	newHash, ok := hashes[s.Hash]
+	if !ok {
+		return fmt.Errorf("unknown hash %q, must be one of %s", s.Hash, strings.Join(hashNames(), ", "))
+	}
+	s.newHash = newHash
+	return nil
+}
+
+func (s *Sample) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, metric := range in {
+		if !s.keep(metric) {
+			metric.Drop()
+			continue
+		}
+		if s.WeightField != "" {
+			metric.AddField(s.WeightField, int64(s.Ratio))
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+// keep reports whether the series of the metric is in the sample.
+func (s *Sample) keep(metric telegraf.Metric) bool {
+	if s.Ratio == 1 {
+		return true
+	}
+
+	h := s.newHash()
+	h.Write([]byte(s.Seed))
+	h.Write([]byte{0})
+	h.Write([]byte(metric.Name()))
+	for _, tag := range s.seriesTags(metric) {
+		h.Write([]byte{0})
+		h.Write([]byte(tag.Key))
+		h.Write([]byte{0})
+		h.Write([]byte(tag.Value))
+	}
+	return sum64(h.Sum(nil))%uint64(s.Ratio) == 0
+}
+
+// seriesTags returns the tags identifying the series of the metric, sorted
+// by key.
+func (s *Sample) seriesTags(metric telegraf.Metric) []*telegraf.Tag {
+	if len(s.Tags) == 0 {
+		return metric.TagList()
+	}
+	tags := make([]*telegraf.Tag, 0, len(s.Tags))
+	for _, key := range s.Tags {
+		if value, ok := metric.GetTag(key); ok {
+			tags = append(tags, &telegraf.Tag{Key: key, Value: value})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags
+}
+
+// sum64 folds the sum of a hash into an integer.
+func sum64(sum []byte) uint64 {
+	if len(sum) < 8 {
+		var buf [8]byte
+		copy(buf[8-len(sum):], sum)
+		sum = buf[:]
+	}
+	return binary.BigEndian.Uint64(sum)
+}
+
+func hashNames() []string {
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	processors.Add("sample", func() telegraf.Processor {
+		return &Sample{
+			Ratio:       10,
+			Hash:        "fnv1a",
+			WeightField: "sample_weight",
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package sample
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSample(t *testing.T, ratio int) *Sample {
+	s := &Sample{
+		Ratio:       ratio,
+		Hash:        "fnv1a",
+		WeightField: "sample_weight",
+	}
+	require.NoError(t, s.Init())
+	return s
+}
+
+func cores(n int) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, n)
+	for i := 0; i < n; i++ {
+		metrics = append(metrics, testutil.MustMetric(
+			"core_power",
+			map[string]string{
+				"host": fmt.Sprintf("node%d", i/64),
+				"cpu":  fmt.Sprintf("cpu%d", i%64),
+			},
+			map[string]interface{}{"watts": 4.2},
+			time.Unix(0, 0),
+		))
+	}
+	return metrics
+}
+
+func series(metrics []telegraf.Metric) map[string]bool {
+	keys := make(map[string]bool)
+	for _, m := range metrics {
+		keys[m.Tags()["host"]+"/"+m.Tags()["cpu"]] = true
+	}
+	return keys
+}
+
+func TestSample(t *testing.T) {
+	s := newSample(t, 10)
+
+	kept := s.Apply(cores(6400)...)
+	require.InDelta(t, 640, len(kept), 80)
+	for _, m := range kept {
+		require.Equal(t, int64(10), m.Fields()["sample_weight"])
+	}
+
+	// The same series are kept every time
+	require.Equal(t, series(kept), series(s.Apply(cores(6400)...)))
+}
+
+func TestSampleSeed(t *testing.T) {
+	s := newSample(t, 10)
+	seeded := newSample(t, 10)
+	seeded.Seed = "rack-a"
+
+	require.NotEqual(t, series(s.Apply(cores(640)...)), series(seeded.Apply(cores(640)...)))
+}
+
+func TestSampleTags(t *testing.T) {
+	s := newSample(t, 4)
+	s.Tags = []string{"host"}
+
+	// Series are chosen by host only, so whole hosts are kept
+	kept := s.Apply(cores(64 * 16)...)
+	require.NotEmpty(t, kept)
+	require.Equal(t, 0, len(kept)%64)
+}
+
+func TestSampleHashes(t *testing.T) {
+	for _, name := range hashNames() {
+		t.Run(name, func(t *testing.T) {
+			s := &Sample{Ratio: 10, Hash: name}
+			require.NoError(t, s.Init())
+
+			kept := s.Apply(cores(6400)...)
+			require.InDelta(t, 640, len(kept), 80)
+			for _, m := range kept {
+				require.NotContains(t, m.Fields(), "sample_weight")
+			}
+		})
+	}
+}
+
+func TestSampleRatioOne(t *testing.T) {
+	s := newSample(t, 1)
+	require.Len(t, s.Apply(cores(100)...), 100)
+}
+
+func TestSampleInit(t *testing.T) {
+	s := &Sample{Ratio: 10, Hash: "sha3"}
+	require.Error(t, s.Init())
+
+	s = &Sample{Ratio: 0, Hash: "fnv1a"}
+	require.Error(t, s.Init())
+}
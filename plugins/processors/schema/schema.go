@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tag added to metrics violating their schema, holding the reasons
+  ## separated by "; ".  Route violations to a quarantine output using
+  ## tagpass/tagdrop on this tag.
+  # violation_tag = "schema_violation"
+
+  ## If set, metrics violating their schema are renamed to this measurement
+  ## so they can also be routed using namepass/namedrop.
+  # quarantine_measurement = ""
+
+  ## If true, metrics without a declared schema are treated as violations.
+  # reject_undeclared = false
+
+  [[processors.schema.measurement]]
+    ## Name of the measurement the schema applies to
+    name = "ipmi_power"
+
+    ## Tags and fields that must be present
+    required_tags = ["server"]
+    required_fields = ["instantaneous_power_reading"]
+
+    ## Types of the fields, checked when the field is present.  Valid types
+    ## are "float", "integer", "unsigned", "string" and "boolean".
+    [processors.schema.measurement.field_types]
+      instantaneous_power_reading = "float"
+      instantaneous_power_reading_unit = "string"
+
+    ## Allowed values of tags or string fields holding units
+    [processors.schema.measurement.units]
+      instantaneous_power_reading_unit = ["Watts"]
+`
+
+type Schema struct {
+	ViolationTag          string              `toml:"violation_tag"`
+	QuarantineMeasurement string              `toml:"quarantine_measurement"`
+	RejectUndeclared      bool                `toml:"reject_undeclared"`
+	Measurements          []MeasurementSchema `toml:"measurement"`
+
+	schemas map[string]*MeasurementSchema
+}
+
+// MeasurementSchema is the contract a single measurement has to fulfill.
+type MeasurementSchema struct {
+	Name           string              `toml:"name"`
+	RequiredTags   []string            `toml:"required_tags"`
+	RequiredFields []string            `toml:"required_fields"`
+	FieldTypes     map[string]string   `toml:"field_types"`
+	Units          map[string][]string `toml:"units"`
+}
+
+func (s *Schema) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Schema) Description() string {
+	return "Validate metrics against declared schemas and mark violations."
+}
+
+func (s *Schema) Init() error {
+	if s.ViolationTag == "" {
+		return fmt.Errorf("violation_tag must not be empty")
+	}
+
+	s.schemas = make(map[string]*MeasurementSchema, len(s.Measurements))
+	for i := range s.Measurements {
+		ms := &s.Measurements[i]
+		if ms.Name == "" {
+			return fmt.Errorf("measurement schema without name")
+		}
+		if _, ok := s.schemas[ms.Name]; ok {
+			return fmt.Errorf("duplicate schema for measurement %q", ms.Name)
+		}
+		for field, typ := range ms.FieldTypes {
+			switch typ {
+			case "float", "integer", "unsigned", "string", "boolean":
+			default:
+				return fmt.Errorf("invalid type %q for field %q of measurement %q", typ, field, ms.Name)
+			}
+		}
+		s.schemas[ms.Name] = ms
+	}
+	return nil
+}
+
+func (s *Schema) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		var violations []string
+		if ms, ok := s.schemas[metric.Name()]; ok {
+			violations = ms.validate(metric)
+		} else if s.RejectUndeclared {
+			violations = []string{"no schema declared"}
+		}
+
+		if len(violations) == 0 {
+			continue
+		}
+		metric.AddTag(s.ViolationTag, strings.Join(violations, "; "))
+		if s.QuarantineMeasurement != "" {
+			metric.SetName(s.QuarantineMeasurement)
+		}
+	}
+	return in
+}
+
+// validate returns the reasons the metric violates the schema, if any.
+func (ms *MeasurementSchema) validate(metric telegraf.Metric) []string {
+	var violations []string
+	for _, tag := range ms.RequiredTags {
+		if !metric.HasTag(tag) {
+			violations = append(violations, fmt.Sprintf("missing tag %s", tag))
+		}
+	}
+	for _, field := range ms.RequiredFields {
+		if !metric.HasField(field) {
+			violations = append(violations, fmt.Sprintf("missing field %s", field))
+		}
+	}
+
+	fields := make([]string, 0, len(ms.FieldTypes))
+	for field := range ms.FieldTypes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		v, ok := metric.GetField(field)
+		if !ok {
+			continue
+		}
+		if typ := typeName(v); typ != ms.FieldTypes[field] {
+			violations = append(violations,
+				fmt.Sprintf("field %s has type %s, expected %s", field, typ, ms.FieldTypes[field]))
+		}
+	}
+
+	keys := make([]string, 0, len(ms.Units))
+	for key := range ms.Units {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		unit, ok := metric.GetTag(key)
+		if !ok {
+			v, ok := metric.GetField(key)
+			if !ok {
+				continue
+			}
+			unit = fmt.Sprintf("%v", v)
+		}
+		if !contains(ms.Units[key], unit) {
+			violations = append(violations, fmt.Sprintf("%s has unit %q, expected one of %q", key, unit, ms.Units[key]))
+		}
+	}
+	return violations
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "float"
+	case int64:
+		return "integer"
+	case uint64:
+		return "unsigned"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func contains(choices []string, s string) bool {
+	for _, choice := range choices {
+		if choice == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	processors.Add("schema", func() telegraf.Processor {
+		return &Schema{
+			ViolationTag: "schema_violation",
+		}
+	})
+}
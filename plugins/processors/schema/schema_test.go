@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSchema() *Schema {
+	return &Schema{
+		ViolationTag: "schema_violation",
+		Measurements: []MeasurementSchema{
+			{
+				Name:           "ipmi_power",
+				RequiredTags:   []string{"server"},
+				RequiredFields: []string{"instantaneous_power_reading"},
+				FieldTypes: map[string]string{
+					"instantaneous_power_reading":      "float",
+					"instantaneous_power_reading_unit": "string",
+				},
+				Units: map[string][]string{
+					"instantaneous_power_reading_unit": {"Watts"},
+				},
+			},
+		},
+	}
+}
+
+func TestValid(t *testing.T) {
+	s := newSchema()
+	require.NoError(t, s.Init())
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("ipmi_power",
+			map[string]string{"server": "192.168.1.1"},
+			map[string]interface{}{
+				"instantaneous_power_reading":      float64(220),
+				"instantaneous_power_reading_unit": "Watts",
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"usage_idle": float64(42)},
+			time.Unix(0, 0),
+		),
+	}
+	expected := []telegraf.Metric{input[0].Copy(), input[1].Copy()}
+
+	actual := s.Apply(input...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestViolations(t *testing.T) {
+	s := newSchema()
+	s.QuarantineMeasurement = "quarantine"
+	require.NoError(t, s.Init())
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("ipmi_power",
+			map[string]string{},
+			map[string]interface{}{
+				"instantaneous_power_reading_unit": "kW",
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("ipmi_power",
+			map[string]string{"server": "192.168.1.1"},
+			map[string]interface{}{
+				"instantaneous_power_reading": int64(220),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("quarantine",
+			map[string]string{
+				"schema_violation": `missing tag server; missing field instantaneous_power_reading; instantaneous_power_reading_unit has unit "kW", expected one of ["Watts"]`,
+			},
+			map[string]interface{}{
+				"instantaneous_power_reading_unit": "kW",
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("quarantine",
+			map[string]string{
+				"server":           "192.168.1.1",
+				"schema_violation": "field instantaneous_power_reading has type integer, expected float",
+			},
+			map[string]interface{}{
+				"instantaneous_power_reading": int64(220),
+			},
+			time.Unix(0, 0),
+		),
+	}
+
+	actual := s.Apply(input...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestRejectUndeclared(t *testing.T) {
+	s := newSchema()
+	s.RejectUndeclared = true
+	require.NoError(t, s.Init())
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"usage_idle": float64(42)},
+		time.Unix(0, 0),
+	)
+	actual := s.Apply(m)
+	require.Equal(t, "no schema declared", actual[0].Tags()["schema_violation"])
+}
+
+func TestInitErrors(t *testing.T) {
+	s := newSchema()
+	s.Measurements[0].FieldTypes["instantaneous_power_reading"] = "double"
+	require.Error(t, s.Init())
+
+	s = newSchema()
+	s.Measurements = append(s.Measurements, MeasurementSchema{Name: "ipmi_power"})
+	require.Error(t, s.Init())
+}
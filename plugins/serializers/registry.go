@@ -105,6 +105,21 @@ type Config struct {
 	PrometheusStringAsLabel bool `toml:"prometheus_string_as_label"`
 }
 
+// TimestampResolution returns the resolution of the timestamps written by
+// the serializer, finer timestamps are truncated.
+func (config *Config) TimestampResolution() time.Duration {
+	switch config.DataFormat {
+	case "json":
+		return config.TimestampUnits
+	case "graphite", "carbon2", "wavefront":
+		return time.Second
+	case "nowmetric", "prometheus":
+		return time.Millisecond
+	default:
+		return time.Nanosecond
+	}
+}
+
 // NewSerializer a Serializer interface based on the given config.
 func NewSerializer(config *Config) (Serializer, error) {
 	var err error
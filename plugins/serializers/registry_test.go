@@ -0,0 +1,23 @@
+package serializers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestampResolution(t *testing.T) {
+	tests := []struct {
+		config   Config
+		expected time.Duration
+	}{
+		{Config{DataFormat: "influx"}, time.Nanosecond},
+		{Config{DataFormat: "json", TimestampUnits: time.Millisecond}, time.Millisecond},
+		{Config{DataFormat: "graphite"}, time.Second},
+		{Config{DataFormat: "prometheus"}, time.Millisecond},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, tt.config.TimestampResolution(), tt.config.DataFormat)
+	}
+}